@@ -13,8 +13,13 @@ import (
 	"strings"
 	"time"
 
+	vaultkeystore "github.com/ethsana/sana/pkg/keystore/vault"
 	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/postage/postagemanager"
+	"github.com/ethsana/sana/pkg/pss/mailbox"
+	"github.com/ethsana/sana/pkg/repair"
 	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/tracing"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -34,8 +39,14 @@ const (
 	optionNameNATAddr                   = "nat-addr"
 	optionNameP2PWSEnable               = "p2p-ws-enable"
 	optionNameP2PQUICEnable             = "p2p-quic-enable"
+	optionNameP2PKeepaliveTCP           = "p2p-keepalive-tcp"
+	optionNameP2PKeepaliveWS            = "p2p-keepalive-ws"
+	optionNameP2PKeepaliveQUIC          = "p2p-keepalive-quic"
+	optionNameP2PKeepaliveTimeout       = "p2p-keepalive-timeout"
+	optionNameP2PKeepaliveMaxFailures   = "p2p-keepalive-max-failures"
 	optionNameDebugAPIEnable            = "debug-api-enable"
 	optionNameDebugAPIAddr              = "debug-api-addr"
+	optionNameDebugAPIKeysBackup        = "debug-api-keys-backup"
 	optionNameBootnodes                 = "bootnode"
 	optionNameNetworkID                 = "network-id"
 	optionWelcomeMessage                = "welcome-message"
@@ -74,6 +85,82 @@ const (
 	optionNameUniswapEnable             = "uniswap-enable"
 	optionNameUniswapEndpoint           = "uniswap-endpoint"
 	optionNameUniswapValidTime          = "uniswap-valid-time"
+	optionNameRetrievalCaching          = "forward-cache"
+	optionNameRetrievalHopTimeout       = "retrieval-hop-timeout"
+	optionNameRetrievalRequestTimeout   = "retrieval-request-timeout"
+	optionNameRetrievalRetryInterval    = "retrieval-retry-interval"
+	optionNameUploadDedup               = "upload-dedup"
+	optionNameS3Enable                  = "s3-enable"
+	optionNameS3PostageBatch            = "s3-postage-batch"
+	optionNameUploadScanEndpoint        = "upload-scan-endpoint"
+	optionNameUploadScanTimeout         = "upload-scan-timeout"
+	optionNameManifestCacheCapacity     = "manifest-cache-capacity"
+	optionNameStampingClients           = "stamping-clients"
+	optionNameStampingBatch             = "stamping-batch"
+	optionNameNetwork                   = "network"
+	optionNameStaticPostageBatch        = "static-postage-batch"
+	optionNameStaticPostageDepth        = "static-postage-depth"
+	optionNameUploadPolicies            = "upload-policies"
+	optionNameStandbyAddr               = "standby-addr"
+	optionNameStandbyPeer               = "standby-peer"
+	optionNameStandbyToken              = "standby-token"
+	optionNameReportPeriods             = "report-periods"
+	optionNameReportWebhooks            = "report-webhooks"
+	optionNameReportSMTPAddr            = "report-smtp-addr"
+	optionNameReportSMTPUsername        = "report-smtp-username"
+	optionNameReportSMTPPassword        = "report-smtp-password"
+	optionNameReportSMTPFrom            = "report-smtp-from"
+	optionNameReportSMTPTo              = "report-smtp-to"
+	optionNameCommitmentInterval        = "commitment-interval"
+	optionNameCommitmentPostageBatch    = "commitment-postage-batch"
+	optionNameTakedownSigners           = "takedown-signers"
+	optionNameKeystore                  = "keystore"
+	optionNameKeystoreEndpoint          = "keystore-endpoint"
+	optionNameKeystoreToken             = "keystore-token"
+	optionNameKeystoreMount             = "keystore-mount"
+	optionNameKeystorePrefix            = "keystore-prefix"
+	optionNameShutdownTimeout           = "shutdown-timeout"
+	optionNameNetworkProfiles           = "network-profiles"
+	optionNameGatewayRateLimit          = "gateway-rate-limit"
+	optionNameGatewayMaxRequestSize     = "gateway-max-request-size"
+	optionNameGatewayAllowedEndpoints   = "gateway-allowed-endpoints"
+	optionNameGatewayDeniedEndpoints    = "gateway-denied-endpoints"
+	optionNameP2PProxy                  = "p2p-proxy"
+	optionNameP2POnionAddr              = "p2p-onion-addr"
+	optionNameRepairInterval            = "repair-interval"
+	optionNameRepairBudget              = "repair-budget"
+	optionNameRepairPostageBatch        = "repair-postage-batch"
+	optionNameClefSignerTimeout         = "clef-signer-timeout"
+	optionNameLogFormat                 = "log-format"
+	optionNameLogLevel                  = "log-level"
+	optionNamePostageAutoTopUpLimit     = "postage-auto-topup-limit"
+	optionNamePostageExpiryWarning      = "postage-expiry-warning"
+	optionNamePostageTopUpTTL           = "postage-topup-ttl"
+	optionNamePostageDiluteUtilization  = "postage-dilute-utilization"
+	optionNameSwapLatencySelection      = "swap-endpoint-latency-selection"
+	optionNamePssMailbox                = "pss-mailbox"
+	optionNamePssMailboxCapacity        = "pss-mailbox-capacity"
+	optionNamePssMailboxMaxTTL          = "pss-mailbox-max-ttl"
+	optionNameMode                      = "mode"
+	optionNameRemoteAPIURL              = "remote-api-url"
+	optionNameDBCapacityGB              = "db-capacity-gb"
+	optionNameDBReserveCapacity         = "db-reserve-capacity"
+	optionNameDBPinCapacity             = "db-pin-capacity"
+	optionNameDBEvictionPolicy          = "db-eviction-policy"
+	optionNameS3APIAddr                 = "s3-api-addr"
+	optionNameS3Credentials             = "s3-credentials"
+	optionNameReputationBanThreshold    = "reputation-ban-threshold"
+	optionNameReputationBanDuration     = "reputation-ban-duration"
+	optionNameReputationPreferredPeers  = "reputation-preferred-peers"
+	optionNameReputationBlockedCIDRs    = "reputation-blocked-cidrs"
+	optionNameRequireTEE                = "require-tee"
+	optionNameTracingExporter           = "tracing-exporter"
+	optionNameTracingInsecure           = "tracing-insecure"
+	optionNameSnapshotSource            = "snapshot-source"
+	optionNameSnapshotSigners           = "snapshot-signers"
+	optionNameAPIURL                    = "api-url"
+	optionNamePostageBatch              = "postage-batch"
+	optionNameWriteback                 = "writeback"
 )
 
 func init() {
@@ -132,6 +219,13 @@ func newCommand(opts ...option) (c *command, err error) {
 	c.initVersionCmd()
 	c.initDBCmd()
 	c.initTeeCmd()
+	c.initMountCmd()
+	c.initSyncCmd()
+	c.initSupportBundleCmd()
+	c.initStandbyCmd()
+	c.initServiceCmd()
+	c.initKeysCmd()
+	c.initConfigCmd()
 
 	if err := c.initConfigurateOptionsCmd(); err != nil {
 		return nil, err
@@ -216,15 +310,21 @@ func (c *command) setAllFlags(cmd *cobra.Command) {
 	cmd.Flags().String(optionNameNATAddr, "", "NAT exposed address")
 	cmd.Flags().Bool(optionNameP2PWSEnable, false, "enable P2P WebSocket transport")
 	cmd.Flags().Bool(optionNameP2PQUICEnable, false, "enable P2P QUIC transport")
+	cmd.Flags().Duration(optionNameP2PKeepaliveTCP, time.Minute, "interval between keepalive pings of peers connected over TCP, 0 disables them")
+	cmd.Flags().Duration(optionNameP2PKeepaliveWS, time.Minute, "interval between keepalive pings of peers connected over WebSocket, 0 disables them")
+	cmd.Flags().Duration(optionNameP2PKeepaliveQUIC, 15*time.Second, "interval between keepalive pings of peers connected over QUIC, 0 disables them")
+	cmd.Flags().Duration(optionNameP2PKeepaliveTimeout, 10*time.Second, "timeout of a single keepalive ping")
+	cmd.Flags().Int(optionNameP2PKeepaliveMaxFailures, 2, "number of consecutive failed keepalive pings after which a connection is closed as half-open")
 	cmd.Flags().StringSlice(optionNameBootnodes, []string{"/dnsaddr/mainnet.ethsana.org"}, "initial nodes to connect to")
 	cmd.Flags().Bool(optionNameDebugAPIEnable, false, "enable debug HTTP API")
 	cmd.Flags().String(optionNameDebugAPIAddr, ":1635", "debug HTTP API listen address")
+	cmd.Flags().Bool(optionNameDebugAPIKeysBackup, false, "enable the backups of the node keys by the debug HTTP API")
 	cmd.Flags().Uint64(optionNameNetworkID, 100, "ID of the Sana network")
 	cmd.Flags().StringSlice(optionCORSAllowedOrigins, []string{}, "origins with CORS headers enabled")
 	cmd.Flags().String(optionDashboardAuthorization, "", "debug api and api authorization token")
 	cmd.Flags().Bool(optionNameStandalone, false, "whether we want the node to start with no listen addresses for p2p")
 	cmd.Flags().Bool(optionNameTracingEnabled, false, "enable tracing")
-	cmd.Flags().String(optionNameTracingEndpoint, "127.0.0.1:6831", "endpoint to send tracing data")
+	cmd.Flags().String(optionNameTracingEndpoint, "127.0.0.1:6831", "endpoint to send tracing data, the Jaeger agent or, if not set, the OTLP collector on localhost")
 	cmd.Flags().String(optionNameTracingServiceName, "bee", "service name identifier for tracing")
 	cmd.Flags().String(optionNameVerbosity, "info", "log verbosity level 0=silent, 1=error, 2=warn, 3=info, 4=debug, 5=trace")
 	cmd.Flags().String(optionWelcomeMessage, "", "send a welcome message string during handshakes")
@@ -238,7 +338,7 @@ func (c *command) setAllFlags(cmd *cobra.Command) {
 	cmd.Flags().Bool(optionNameClefSignerEnable, false, "enable clef signer")
 	cmd.Flags().String(optionNameClefSignerEndpoint, "", "clef signer endpoint")
 	cmd.Flags().String(optionNameClefSignerEthereumAddress, "", "ethereum address to use from clef signer")
-	cmd.Flags().String(optionNameSwapEndpoint, "ws://localhost:8546", "swap ethereum blockchain endpoint")
+	cmd.Flags().StringSlice(optionNameSwapEndpoint, []string{"ws://localhost:8546"}, "swap ethereum blockchain endpoint, can be repeated to fail over to the next endpoint when one is unavailable")
 	cmd.Flags().String(optionNameSwapFactoryAddress, "", "swap factory addresses")
 	cmd.Flags().String(optionNameSwapInitialDeposit, "10000000000000000", "initial deposit if deploying a new chequebook")
 	cmd.Flags().Bool(optionNameSwapEnable, true, "enable swap")
@@ -256,25 +356,179 @@ func (c *command) setAllFlags(cmd *cobra.Command) {
 	cmd.Flags().Bool(optionNameUniswapEnable, false, "enable uniswap oracle")
 	cmd.Flags().String(optionNameUniswapEndpoint, "", "uniswap ethereum blockchain endpoint")
 	cmd.Flags().Duration(optionNameUniswapValidTime, time.Minute*30, "uniswap valid time")
+	cmd.Flags().Bool(optionNameRetrievalCaching, false, "cache chunks forwarded for other peers")
+	cmd.Flags().Duration(optionNameRetrievalHopTimeout, 0, "maximal duration of a chunk request to a single peer, 0 adapts it to the measured round trip times of the peers")
+	cmd.Flags().Duration(optionNameRetrievalRequestTimeout, 0, "maximal duration of retrieving a chunk from multiple peers, 0 means no limit")
+	cmd.Flags().Duration(optionNameRetrievalRetryInterval, 5*time.Second, "duration after which a chunk is requested from another peer while a request is in flight")
+	cmd.Flags().Bool(optionNameUploadDedup, false, "skip re-uploading content already uploaded with the same postage batch")
+	cmd.Flags().Bool(optionNameS3Enable, false, "enable the S3-compatible API under /s3")
+	cmd.Flags().String(optionNameS3PostageBatch, "", "postage batch id used for S3 uploads without a batch header")
+	cmd.Flags().String(optionNameUploadScanEndpoint, "", "icap:// or http(s):// endpoint of a service which scans uploaded content")
+	cmd.Flags().Duration(optionNameUploadScanTimeout, 30*time.Second, "maximal duration of a single upload scan")
+	cmd.Flags().Uint64(optionNameManifestCacheCapacity, 16*1024*1024, "size in bytes of the in-memory cache of parsed manifest nodes, 0 disables it")
+	cmd.Flags().String(optionNameStampingClients, "", "path to a JSON file with the light clients allowed to have chunks stamped under /stamping")
+	cmd.Flags().String(optionNameStampingBatch, "", "postage batch id used to stamp the chunks of the stamping clients")
+	cmd.Flags().String(optionNameNetwork, "", "network profile to join, mainnet, testnet, dev or one of the network profile files, dev-static runs without a chain backend using a static postage batch")
+	cmd.Flags().String(optionNameStaticPostageBatch, "", "postage batch id accepted by all nodes of the dev-static network")
+	cmd.Flags().Uint(optionNameStaticPostageDepth, 24, "depth of the static postage batch of the dev-static network")
+	cmd.Flags().String(optionNameUploadPolicies, "", "path to a JSON file with the upload policies of the origins served by a gateway")
+	cmd.Flags().String(optionNameStandbyAddr, "", "pairing endpoint listen address replicated by a standby node")
+	cmd.Flags().String(optionNameStandbyPeer, "", "pairing endpoint URL of the standby node, checked to never run both nodes as active")
+	cmd.Flags().String(optionNameStandbyToken, "", "token shared by the node and its standby")
+	cmd.Flags().StringSlice(optionNameReportPeriods, []string{}, "periods of the generated reports, daily or weekly")
+	cmd.Flags().StringSlice(optionNameReportWebhooks, []string{}, "URLs to which the reports are posted as JSON")
+	cmd.Flags().String(optionNameReportSMTPAddr, "", "host:port of the SMTP server through which the reports are sent by email")
+	cmd.Flags().String(optionNameReportSMTPUsername, "", "SMTP username")
+	cmd.Flags().String(optionNameReportSMTPPassword, "", "SMTP password")
+	cmd.Flags().String(optionNameReportSMTPFrom, "", "sender of the report emails")
+	cmd.Flags().StringSlice(optionNameReportSMTPTo, []string{}, "recipients of the report emails")
+	cmd.Flags().Duration(optionNameCommitmentInterval, 0, "interval of the commitments to the reserve contents, disabled if zero")
+	cmd.Flags().String(optionNameCommitmentPostageBatch, "", "postage batch id which stamps the published reserve commitments")
+	cmd.Flags().StringSlice(optionNameTakedownSigners, []string{}, "ethereum addresses whose signed takedown notices and counter-notices are accepted")
+	cmd.Flags().String(optionNameKeystore, keystoreFile, "keystore backend of the private keys, file or vault")
+	cmd.Flags().String(optionNameKeystoreEndpoint, "", "address of the vault server")
+	cmd.Flags().String(optionNameKeystoreToken, "", "vault token, the VAULT_TOKEN environment variable if not set")
+	cmd.Flags().String(optionNameKeystoreMount, vaultkeystore.DefaultMount, "mount path of the vault key/value version 2 secrets engine")
+	cmd.Flags().String(optionNameKeystorePrefix, "sana", "vault secret path under which the keys are stored")
+	cmd.Flags().Duration(optionNameShutdownTimeout, 15*time.Second, "maximal duration of draining the pending uploads and settlements and stopping the node")
+	cmd.Flags().StringSlice(optionNameNetworkProfiles, []string{}, "paths to YAML files with network profiles, mapping the network names to their network id, chain id, bootnodes, block time and contract addresses")
+	cmd.Flags().String(optionNameGatewayRateLimit, "", "requests allowed per client IP address in the gateway mode, as REQUESTS/PERIOD like 100/m, unlimited if empty")
+	cmd.Flags().Int64(optionNameGatewayMaxRequestSize, 0, "maximal size of the request bodies in bytes in the gateway mode, unlimited if zero")
+	cmd.Flags().StringSlice(optionNameGatewayAllowedEndpoints, []string{}, "api endpoints allowed in the gateway mode as [METHOD] PATH, where a PATH ending with * matches the prefix, all endpoints if empty")
+	cmd.Flags().StringSlice(optionNameGatewayDeniedEndpoints, []string{}, "api endpoints denied in the gateway mode as [METHOD] PATH, where a PATH ending with * matches the prefix")
+	cmd.Flags().String(optionNameP2PProxy, "", "SOCKS5 proxy url through which the P2P connections are dialed, like socks5://127.0.0.1:9050 of a Tor client, only with the TCP transport")
+	cmd.Flags().String(optionNameP2POnionAddr, "", "Tor onion service address advertised to the peers, like <onion service>.onion:1634")
+	cmd.Flags().Duration(optionNameRepairInterval, 0, "interval of the rounds probing the network for sampled chunks of the pinned content and repairing the missing ones, only on demand if zero")
+	cmd.Flags().Int(optionNameRepairBudget, repair.DefaultBudget, "maximal number of chunks probed in a repair round")
+	cmd.Flags().String(optionNameRepairPostageBatch, "", "postage batch id which stamps the repaired chunks with fresh stamps, the stored stamps are used if empty")
+	cmd.Flags().Duration(optionNameClefSignerTimeout, time.Minute, "maximal duration a signing request waits for the clef signer to reconnect")
+	cmd.Flags().String(optionNameLogFormat, logFormatText, "format of the log lines, text or json")
+	cmd.Flags().String(optionNameLogLevel, "", "log verbosity levels of the components overriding the verbosity, like p2p=debug,api=warn")
+	cmd.Flags().String(optionNamePostageAutoTopUpLimit, "", "total amount of tokens spent on the automatic top-ups of the owned postage batches, the batches are only watched if empty or zero")
+	cmd.Flags().Duration(optionNamePostageExpiryWarning, postagemanager.DefaultWarnTTL, "remaining time to live under which the owned postage batches are reported as expiring and topped up")
+	cmd.Flags().Duration(optionNamePostageTopUpTTL, 0, "time to live the expiring postage batches are topped up to, twice the expiry warning if zero")
+	cmd.Flags().Float64(optionNamePostageDiluteUtilization, 0, "utilization of the fullest bucket of a mutable postage batch, between 0 and 1, from which it is diluted to the next depth, never if zero")
+	cmd.Flags().Bool(optionNameSwapLatencySelection, false, "prefer the healthy swap endpoint with the lowest latency instead of the order of the endpoints")
+	cmd.Flags().Bool(optionNamePssMailbox, false, "hold the pss mailboxes of the offline recipients, full nodes only")
+	cmd.Flags().Int(optionNamePssMailboxCapacity, mailbox.DefaultCapacity, "maximal number of held pss mailbox messages")
+	cmd.Flags().Duration(optionNamePssMailboxMaxTTL, mailbox.DefaultMaxTTL, "maximal time the pss mailbox messages are held for")
+	cmd.Flags().String(optionNameMode, "", "mode of the node, light-remote to proxy the api chunks to the node of the remote api url")
+	cmd.Flags().String(optionNameRemoteAPIURL, "", "api url of the trusted full node used in the light-remote mode")
+	cmd.Flags().Uint64(optionNameDBCapacityGB, 0, "size of the database in gigabytes shared by the cache, the reserve and the pinned chunks, overrides the cache capacity")
+	cmd.Flags().Uint64(optionNameDBReserveCapacity, 0, "reserve capacity in chunks, 0 is the capacity of the reserve of the network")
+	cmd.Flags().Uint64(optionNameDBPinCapacity, 0, "maximal number of pinned chunks, 0 is unlimited")
+	cmd.Flags().String(optionNameDBEvictionPolicy, "lru", "eviction policy of the cache, lru or proximity to keep the chunks of the neighbourhood longer")
+	cmd.Flags().String(optionNameS3APIAddr, "", "S3-compatible API listen address, disabled if empty")
+	cmd.Flags().String(optionNameS3Credentials, "", "path to a JSON file with the access and secret keys which sign the S3 API requests")
+	cmd.Flags().Float64(optionNameReputationBanThreshold, 0, "negative peer score at or below which the peers are banned, never if zero")
+	cmd.Flags().Duration(optionNameReputationBanDuration, time.Hour, "duration of the peer bans, forever if zero")
+	cmd.Flags().StringSlice(optionNameReputationPreferredPeers, []string{}, "overlay addresses of the peers which are never banned and whose connections are always accepted")
+	cmd.Flags().StringSlice(optionNameReputationBlockedCIDRs, []string{}, "networks in CIDR notation whose underlay addresses are not connected with")
+	cmd.Flags().Bool(optionNameRequireTEE, false, "refuse to start on the main network without a TEE device verified by its vendor")
+	cmd.Flags().String(optionNameTracingExporter, tracing.ExporterJaeger, "exporter of the tracing data, jaeger, otlp-grpc or otlp-http")
+	cmd.Flags().Bool(optionNameTracingInsecure, false, "disable TLS of the connections to the OTLP collector")
+	cmd.Flags().StringSlice(optionNameSnapshotSource, []string{}, "HTTPS urls of the neighborhood snapshots or overlay addresses of the trusted peers serving them, imported by a fresh full node before the pull sync, disabled if empty")
+	cmd.Flags().StringSlice(optionNameSnapshotSigners, []string{}, "ethereum addresses whose signed snapshots are imported from the HTTPS urls")
 }
 
-func newLogger(cmd *cobra.Command, verbosity string) (logging.Logger, error) {
-	var logger logging.Logger
-	switch verbosity {
-	case "0", "silent":
-		logger = logging.New(ioutil.Discard, 0)
-	case "1", "error":
-		logger = logging.New(cmd.OutOrStdout(), logrus.ErrorLevel)
-	case "2", "warn":
-		logger = logging.New(cmd.OutOrStdout(), logrus.WarnLevel)
-	case "3", "info":
-		logger = logging.New(cmd.OutOrStdout(), logrus.InfoLevel)
-	case "4", "debug":
-		logger = logging.New(cmd.OutOrStdout(), logrus.DebugLevel)
-	case "5", "trace":
-		logger = logging.New(cmd.OutOrStdout(), logrus.TraceLevel)
-	default:
+// verbosityLevels maps the verbosity values, except silent, to the log levels.
+var verbosityLevels = map[string]logrus.Level{
+	"1": logrus.ErrorLevel, "error": logrus.ErrorLevel,
+	"2": logrus.WarnLevel, "warn": logrus.WarnLevel,
+	"3": logrus.InfoLevel, "info": logrus.InfoLevel,
+	"4": logrus.DebugLevel, "debug": logrus.DebugLevel,
+	"5": logrus.TraceLevel, "trace": logrus.TraceLevel,
+}
+
+func isSilentVerbosity(verbosity string) bool {
+	return verbosity == "0" || verbosity == "silent"
+}
+
+func newLogger(cmd *cobra.Command, verbosity string, opts ...logging.Option) (logging.Logger, error) {
+	if isSilentVerbosity(verbosity) {
+		return logging.New(ioutil.Discard, 0), nil
+	}
+	level, ok := verbosityLevels[verbosity]
+	if !ok {
 		return nil, fmt.Errorf("unknown verbosity level %q", verbosity)
 	}
-	return logger, nil
+	return logging.New(cmd.OutOrStdout(), level, opts...), nil
+}
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// logFormatOptions returns the logger options of the log format.
+func logFormatOptions(format string) ([]logging.Option, error) {
+	switch format {
+	case logFormatText, "":
+		return nil, nil
+	case logFormatJSON:
+		return []logging.Option{logging.WithJSONFormat()}, nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+// parseLogLevels parses the component levels in the
+// component=verbosity[,component=verbosity...] format.
+func parseLogLevels(s string) (map[string]logrus.Level, error) {
+	levels := make(map[string]logrus.Level)
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		i := strings.Index(v, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid log level %q", v)
+		}
+		component, verbosity := strings.TrimSpace(v[:i]), strings.ToLower(strings.TrimSpace(v[i+1:]))
+		level, ok := verbosityLevels[verbosity]
+		if !ok {
+			return nil, fmt.Errorf("unknown verbosity level %q of component %q", verbosity, component)
+		}
+		levels[component] = level
+	}
+	return levels, nil
+}
+
+// setLogLevels sets the component levels of a logger created by newLogger.
+// The levels are ignored by the silent logger.
+func setLogLevels(logger logging.Logger, verbosity, logLevels string) error {
+	levels, err := parseLogLevels(logLevels)
+	if err != nil {
+		return err
+	}
+	if isSilentVerbosity(verbosity) {
+		return nil
+	}
+	l, ok := logger.(interface {
+		SetComponentLevels(map[string]logrus.Level)
+	})
+	if !ok {
+		return errors.New("logger does not support the component levels")
+	}
+	l.SetComponentLevels(levels)
+	return nil
+}
+
+// setVerbosity changes the log level of a logger created by newLogger. The
+// silent logger discards the output, so switching from or to the silent
+// verbosity requires a restart.
+func setVerbosity(logger logging.Logger, old, verbosity string) error {
+	if isSilentVerbosity(old) || isSilentVerbosity(verbosity) {
+		return errors.New("silent verbosity requires a restart")
+	}
+	level, ok := verbosityLevels[verbosity]
+	if !ok {
+		return fmt.Errorf("unknown verbosity level %q", verbosity)
+	}
+	l, ok := logger.(interface{ SetLevel(logrus.Level) })
+	if !ok {
+		return errors.New("logger does not support changing the verbosity")
+	}
+	l.SetLevel(level)
+	return nil
 }
@@ -0,0 +1,436 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/config"
+	"github.com/ethsana/sana/pkg/node"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/tracing"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// envPrefix is the prefix of the environment variables of the options.
+const envPrefix = "BEE_"
+
+// configMigration rewrites an option of the former versions to the current
+// ones. It returns the note on the option which is empty if the option does
+// not need to be rewritten, and the key and value which replace it, no
+// option replaces it if the key is empty.
+type configMigration func(v interface{}) (key string, value interface{}, note string)
+
+// configMigrations are the migrations of the options of the former versions
+// by their keys.
+var configMigrations = map[string]configMigration{
+	"mainnet": func(v interface{}) (string, interface{}, string) {
+		if b, ok := v.(bool); ok && b {
+			return optionNameNetwork, "mainnet", "replaced by the network option"
+		}
+		return "", nil, "replaced by the network option"
+	},
+	"swap-legacy-factory-addresses": func(v interface{}) (string, interface{}, string) {
+		return "", nil, "no longer used"
+	},
+	optionNameNetworkID: func(v interface{}) (string, interface{}, string) {
+		if id, ok := v.(int); ok && id == 1 {
+			return optionNameNetworkID, 100, "the main network id changed from 1 to 100"
+		}
+		return "", nil, ""
+	},
+}
+
+// configIssue is a problem of the configuration, an error which prevents the
+// node from starting or a warning.
+type configIssue struct {
+	key     string
+	message string
+	warning bool
+}
+
+func (i configIssue) String() string {
+	if i.key == "" {
+		return i.message
+	}
+	return fmt.Sprintf("%s: %s", i.key, i.message)
+}
+
+type configIssues []configIssue
+
+// err returns the first error of the issues, nil if there are only warnings.
+func (is configIssues) err() error {
+	for _, i := range is {
+		if !i.warning {
+			return errors.New(i.String())
+		}
+	}
+	return nil
+}
+
+func (c *command) initConfigCmd() {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Check and migrate the configuration",
+		Long: `Check and migrate the configuration.
+
+The check reports the unknown, deprecated and invalid options of the
+configuration file, the environment and the flags, and the combinations of
+options which prevent the node from starting or which are dangerous. The
+migration rewrites the options of the former versions in the configuration
+file to the current ones.`,
+	}
+
+	c.initConfigCheckCmd(cmd)
+	c.initConfigMigrateCmd(cmd)
+
+	c.root.AddCommand(cmd)
+}
+
+func (c *command) initConfigCheckCmd(configCmd *cobra.Command) {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check the configuration, the flags set as for the start command are checked too",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if len(args) > 0 {
+				return cmd.Help()
+			}
+
+			issues, err := c.checkConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			var errs int
+			for _, i := range issues {
+				level := "error"
+				if i.warning {
+					level = "warning"
+				} else {
+					errs++
+				}
+				cmd.Printf("%s: %s\n", level, i)
+			}
+			if errs > 0 {
+				return fmt.Errorf("%d configuration errors", errs)
+			}
+			cmd.Println("configuration ok")
+			return nil
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.config.BindPFlags(cmd.Flags())
+		},
+	}
+
+	c.setAllFlags(cmd)
+
+	configCmd.AddCommand(cmd)
+}
+
+func (c *command) initConfigMigrateCmd(configCmd *cobra.Command) {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Rewrite the options of the former versions in the configuration file",
+		Long: `Rewrite the options of the former versions in the configuration file.
+
+The original file is kept with the .bak suffix, the comments of the file are
+not preserved.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if len(args) > 0 {
+				return cmd.Help()
+			}
+
+			path := c.config.ConfigFileUsed()
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("read config: %w", err)
+			}
+			var settings yaml.MapSlice
+			if err := yaml.Unmarshal(data, &settings); err != nil {
+				return fmt.Errorf("parse config %s: %w", path, err)
+			}
+
+			migrated, notes := migrateConfig(settings)
+			if len(notes) == 0 {
+				cmd.Println("configuration is up to date")
+				return nil
+			}
+
+			out, err := yaml.Marshal(migrated)
+			if err != nil {
+				return err
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(path+".bak", data, fi.Mode()); err != nil {
+				return fmt.Errorf("back up config: %w", err)
+			}
+			if err := ioutil.WriteFile(path, out, fi.Mode()); err != nil {
+				return fmt.Errorf("write config: %w", err)
+			}
+			for _, n := range notes {
+				cmd.Println(n)
+			}
+			cmd.Printf("configuration %s migrated, the original is kept in %s.bak\n", path, path)
+			return nil
+		},
+	}
+
+	configCmd.AddCommand(cmd)
+}
+
+// migrateConfig applies the migrations to the settings of a configuration
+// file and returns the migrated settings with the notes on the changes.
+func migrateConfig(settings yaml.MapSlice) (yaml.MapSlice, []string) {
+	migrated := make(yaml.MapSlice, 0, len(settings))
+	var notes []string
+	for _, item := range settings {
+		key := fmt.Sprint(item.Key)
+		m, ok := configMigrations[key]
+		if !ok {
+			migrated = append(migrated, item)
+			continue
+		}
+		newKey, value, note := m(item.Value)
+		if note == "" {
+			migrated = append(migrated, item)
+			continue
+		}
+		if newKey == "" {
+			notes = append(notes, fmt.Sprintf("%s: removed, %s", key, note))
+			continue
+		}
+		if newKey != key && hasKey(settings, newKey) {
+			// the current option takes precedence
+			notes = append(notes, fmt.Sprintf("%s: removed, %s which is set", key, note))
+			continue
+		}
+		migrated = append(migrated, yaml.MapItem{Key: newKey, Value: value})
+		notes = append(notes, fmt.Sprintf("%s: %s, set %s to %v", key, note, newKey, value))
+	}
+	return migrated, notes
+}
+
+func hasKey(settings yaml.MapSlice, key string) bool {
+	for _, item := range settings {
+		if fmt.Sprint(item.Key) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConfig returns the issues of the configuration file, the environment
+// and the options with the flags of the start command.
+func (c *command) checkConfig(flags *pflag.FlagSet) (configIssues, error) {
+	var issues configIssues
+
+	if path := c.config.ConfigFileUsed(); path != "" {
+		fi, err := c.checkConfigFile(path, flags)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, fi...)
+	}
+	issues = append(issues, checkConfigEnv(os.Environ(), flags)...)
+	issues = append(issues, c.checkConfigOptions()...)
+	return issues, nil
+}
+
+// checkConfigFile returns the unknown, deprecated and invalid options of the
+// configuration file.
+func (c *command) checkConfigFile(path string, flags *pflag.FlagSet) (configIssues, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	var settings yaml.MapSlice
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return configIssues{{message: fmt.Sprintf("parse config %s: %v", path, err)}}, nil
+	}
+
+	// the values are parsed with the flags of a separate command so that
+	// the flags of the command are not changed
+	values := &cobra.Command{}
+	c.setAllFlags(values)
+
+	var issues configIssues
+	for _, item := range settings {
+		key := fmt.Sprint(item.Key)
+		if m, ok := configMigrations[key]; ok {
+			if _, _, note := m(item.Value); note != "" {
+				issues = append(issues, configIssue{key: key, message: note + ", run the config migrate command", warning: true})
+				continue
+			}
+		}
+		f := values.Flags().Lookup(key)
+		if f == nil || flags.Lookup(key) == nil {
+			issues = append(issues, configIssue{key: key, message: "unknown option", warning: true})
+			continue
+		}
+		if err := setFlagValue(f, item.Value); err != nil {
+			issues = append(issues, configIssue{key: key, message: fmt.Sprintf("invalid value %v: %v", item.Value, err)})
+		}
+	}
+	return issues, nil
+}
+
+// setFlagValue sets the flag to the value of the configuration file.
+func setFlagValue(f *pflag.Flag, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			values = append(values, fmt.Sprint(e))
+		}
+		return f.Value.Set(strings.Join(values, ","))
+	default:
+		return f.Value.Set(fmt.Sprint(v))
+	}
+}
+
+// checkConfigEnv returns the environment variables with the prefix of the
+// options which do not set any of the options.
+func checkConfigEnv(environ []string, flags *pflag.FlagSet) configIssues {
+	var issues configIssues
+	for _, e := range environ {
+		name := strings.SplitN(e, "=", 2)[0]
+		if !strings.HasPrefix(name, envPrefix) {
+			continue
+		}
+		key := strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(name, envPrefix)), "_", "-")
+		if _, ok := configMigrations[key]; ok {
+			issues = append(issues, configIssue{key: name, message: "option of a former version, see the config migrate command", warning: true})
+			continue
+		}
+		if flags.Lookup(key) == nil {
+			issues = append(issues, configIssue{key: name, message: "unknown option", warning: true})
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].key < issues[j].key })
+	return issues
+}
+
+// checkConfigOptions returns the combinations of the options which prevent
+// the node from starting and the dangerous ones.
+func (c *command) checkConfigOptions() configIssues {
+	var issues configIssues
+	fail := func(key, message string) {
+		issues = append(issues, configIssue{key: key, message: message})
+	}
+	warn := func(key, message string) {
+		issues = append(issues, configIssue{key: key, message: message, warning: true})
+	}
+
+	fullNode := c.config.GetBool(optionNameFullNode)
+	if c.config.GetBool(optionNameBootnodeMode) && !fullNode {
+		fail(optionNameBootnodeMode, "boot node must be started as a full node")
+	}
+	if c.config.GetBool(optionNamePssMailbox) && !fullNode {
+		fail(optionNamePssMailbox, "pss mailbox must be held by a full node")
+	}
+	switch mode := c.config.GetString(optionNameMode); mode {
+	case "":
+	case node.ModeLightRemote:
+		if fullNode || c.config.GetBool(optionNameSwapEnable) || c.config.GetBool(optionNameMine) {
+			fail(optionNameMode, "full node, swap and mining require the p2p network, which is not used in the light-remote mode")
+		}
+		if c.config.GetString(optionNameRemoteAPIURL) == "" {
+			fail(optionNameMode, "light-remote node requires the remote api url")
+		}
+	default:
+		fail(optionNameMode, fmt.Sprintf("unknown mode %q", mode))
+	}
+
+	network := c.config.GetString(optionNameNetwork)
+	networkConfig, err := c.getNetworkConfig(network)
+	if err != nil {
+		fail(optionNameNetwork, err.Error())
+	}
+	if c.config.GetBool(optionNameRequireTEE) && networkConfig != nil && networkConfig.networkID != config.DefaultNetworkProfiles()["mainnet"].NetworkID {
+		warn(optionNameRequireTEE, "has no effect outside of the main network")
+	}
+
+	if c.config.GetString(optionNameS3APIAddr) != "" && c.config.GetString(optionNameS3Credentials) == "" {
+		fail(optionNameS3APIAddr, "S3 api requires the credentials")
+	}
+	if c.config.GetFloat64(optionNameReputationBanThreshold) > 0 {
+		fail(optionNameReputationBanThreshold, "ban threshold must not be positive")
+	}
+	switch e := c.config.GetString(optionNameTracingExporter); e {
+	case tracing.ExporterJaeger, tracing.ExporterOTLPGRPC, tracing.ExporterOTLPHTTP:
+	default:
+		fail(optionNameTracingExporter, fmt.Sprintf("unknown tracing exporter %q", e))
+	}
+
+	if c.config.GetBool(optionNameGatewayMode) {
+		if c.config.GetString(optionNameGatewayRateLimit) == "" {
+			warn(optionNameGatewayMode, "gateway without a rate limit of the requests")
+		}
+		if c.config.GetInt64(optionNameGatewayMaxRequestSize) == 0 {
+			warn(optionNameGatewayMode, "gateway without a maximal size of the requests")
+		}
+	}
+	if c.config.GetBool(optionNameDebugAPIEnable) && !loopbackAddr(c.config.GetString(optionNameDebugAPIAddr)) {
+		warn(optionNameDebugAPIAddr, "debug api is reachable from other hosts, it is not authenticated")
+	}
+
+	if sources := c.config.GetStringSlice(optionNameSnapshotSource); len(sources) > 0 {
+		if !fullNode {
+			fail(optionNameSnapshotSource, "snapshots are imported only by full nodes")
+		}
+		signers := c.config.GetStringSlice(optionNameSnapshotSigners)
+		for _, source := range sources {
+			switch {
+			case strings.HasPrefix(source, "https://"):
+			case strings.HasPrefix(source, "http://"):
+				warn(optionNameSnapshotSource, fmt.Sprintf("snapshot %s downloaded without TLS", source))
+			default:
+				if _, err := swarm.ParseHexAddress(source); err != nil {
+					fail(optionNameSnapshotSource, fmt.Sprintf("source %q is neither an url nor an overlay address", source))
+				}
+				continue
+			}
+			if len(signers) == 0 {
+				fail(optionNameSnapshotSigners, fmt.Sprintf("snapshot %s requires the trusted signers", source))
+			}
+		}
+		for _, a := range signers {
+			if !common.IsHexAddress(a) {
+				fail(optionNameSnapshotSigners, fmt.Sprintf("malformed snapshot signer address %q", a))
+			}
+		}
+	}
+	return issues
+}
+
+// loopbackAddr reports whether the listen address is bound to the loopback
+// interface.
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethsana/sana/cmd/ant/cmd"
+)
+
+func TestConfigCheckCmd(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		config   string
+		wantErr  bool
+		wantOuts []string
+	}{
+		{
+			name:     "ok",
+			config:   "full-node: true\nbootnode-mode: true\n",
+			wantOuts: []string{"configuration ok"},
+		},
+		{
+			name:     "unknown option",
+			config:   "full-nodes: true\n",
+			wantOuts: []string{"warning: full-nodes: unknown option"},
+		},
+		{
+			name:     "deprecated option",
+			config:   "mainnet: true\n",
+			wantOuts: []string{"warning: mainnet: replaced by the network option"},
+		},
+		{
+			name:     "invalid value",
+			config:   "cache-capacity: many\n",
+			wantErr:  true,
+			wantOuts: []string{"error: cache-capacity: invalid value many"},
+		},
+		{
+			name:     "bootnode without full node",
+			config:   "bootnode-mode: true\n",
+			wantErr:  true,
+			wantOuts: []string{"error: bootnode-mode: boot node must be started as a full node"},
+		},
+		{
+			name:     "snapshot without signers",
+			config:   "full-node: true\nsnapshot-source: https://snapshots.example.com/snapshot.gz\n",
+			wantErr:  true,
+			wantOuts: []string{"error: snapshot-signers: snapshot https://snapshots.example.com/snapshot.gz requires the trusted signers"},
+		},
+		{
+			name:   "gateway without limits",
+			config: "gateway-mode: true\n",
+			wantOuts: []string{
+				"warning: gateway-mode: gateway without a rate limit of the requests",
+				"warning: gateway-mode: gateway without a maximal size of the requests",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfgFile := filepath.Join(t.TempDir(), "config.yaml")
+			if err := ioutil.WriteFile(cfgFile, []byte(tc.config), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			var outputBuf bytes.Buffer
+			err := newCommand(t,
+				cmd.WithCfgFile(cfgFile),
+				cmd.WithArgs("config", "check"),
+				cmd.WithOutput(&outputBuf),
+				cmd.WithErrorOutput(ioutil.Discard),
+			).Execute()
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			got := outputBuf.String()
+			for _, want := range tc.wantOuts {
+				if !strings.Contains(got, want) {
+					t.Errorf("got output %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigMigrateCmd(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "config.yaml")
+	config := "full-node: true\nmainnet: true\nswap-legacy-factory-addresses: 0x1\nnetwork-id: 1\n"
+	if err := ioutil.WriteFile(cfgFile, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var outputBuf bytes.Buffer
+	if err := newCommand(t,
+		cmd.WithCfgFile(cfgFile),
+		cmd.WithArgs("config", "migrate"),
+		cmd.WithOutput(&outputBuf),
+	).Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadFile(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "full-node: true\nnetwork: mainnet\nnetwork-id: 100\n"
+	if string(got) != want {
+		t.Errorf("got config %q, want %q", got, want)
+	}
+
+	backup, err := ioutil.ReadFile(cfgFile + ".bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(backup) != config {
+		t.Errorf("got backup %q, want %q", backup, config)
+	}
+}
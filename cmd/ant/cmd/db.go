@@ -13,9 +13,12 @@ import (
 	"strings"
 
 	"github.com/ethsana/sana/pkg/localstore"
+	"github.com/ethsana/sana/pkg/logging"
 	"github.com/spf13/cobra"
 )
 
+const optionNameRemoveCorrupt = "remove-corrupt"
+
 func (c *command) initDBCmd() {
 	cmd := &cobra.Command{
 		Use:   "db",
@@ -24,6 +27,8 @@ func (c *command) initDBCmd() {
 
 	dbExportCmd(cmd)
 	dbImportCmd(cmd)
+	dbCompactCmd(cmd)
+	dbVerifyCmd(cmd)
 
 	c.root.AddCommand(cmd)
 }
@@ -62,6 +67,7 @@ func dbExportCmd(cmd *cobra.Command) {
 			if err != nil {
 				return fmt.Errorf("localstore: %w", err)
 			}
+			defer storer.Close()
 
 			var out io.Writer
 			if args[0] == "-" {
@@ -122,6 +128,7 @@ func dbImportCmd(cmd *cobra.Command) {
 			if err != nil {
 				return fmt.Errorf("localstore: %w", err)
 			}
+			defer storer.Close()
 
 			var in io.Reader
 			if args[0] == "-" {
@@ -148,3 +155,96 @@ func dbImportCmd(cmd *cobra.Command) {
 	c.Flags().String(optionNameVerbosity, "info", "verbosity level")
 	cmd.AddCommand(c)
 }
+
+func dbCompactCmd(cmd *cobra.Command) {
+	c := &cobra.Command{
+		Use:   "compact",
+		Short: "Compact the DB to reclaim the space of removed chunks. The node must be stopped",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			storer, logger, err := openLocalstore(cmd)
+			if err != nil {
+				return err
+			}
+			defer storer.Close()
+
+			logger.Info("compacting database")
+			if err := storer.Compact(); err != nil {
+				return fmt.Errorf("error compacting database: %v", err)
+			}
+			logger.Info("database compacted successfully")
+
+			return nil
+		},
+	}
+	c.Flags().String(optionNameDataDir, "", "data directory")
+	c.Flags().String(optionNameVerbosity, "info", "verbosity level")
+	cmd.AddCommand(c)
+}
+
+func dbVerifyCmd(cmd *cobra.Command) {
+	c := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify that the stored chunks match their addresses and report the corrupt ones. The node must be stopped",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			remove, err := cmd.Flags().GetBool(optionNameRemoveCorrupt)
+			if err != nil {
+				return fmt.Errorf("get remove-corrupt: %v", err)
+			}
+			storer, logger, err := openLocalstore(cmd)
+			if err != nil {
+				return err
+			}
+			defer storer.Close()
+
+			logger.Info("verifying database")
+			count, corrupt, err := storer.Verify(cmd.Context(), remove)
+			for _, c := range corrupt {
+				logger.Warningf("corrupt chunk %s: %s", c.Address, c.Reason)
+			}
+			if err != nil {
+				return fmt.Errorf("error verifying database: %v", err)
+			}
+
+			switch {
+			case len(corrupt) == 0:
+				logger.Infof("database verified %d chunks successfully", count)
+			case remove:
+				logger.Infof("database verified %d chunks, removed %d corrupt chunks", count, len(corrupt))
+			default:
+				return fmt.Errorf("database verified %d chunks, found %d corrupt chunks", count, len(corrupt))
+			}
+
+			return nil
+		},
+	}
+	c.Flags().String(optionNameDataDir, "", "data directory")
+	c.Flags().String(optionNameVerbosity, "info", "verbosity level")
+	c.Flags().Bool(optionNameRemoveCorrupt, false, "remove the corrupt chunks so that they can be synced again")
+	cmd.AddCommand(c)
+}
+
+// openLocalstore opens the localstore in the data-dir of the command. It fails
+// while the node is running as the database is locked.
+func openLocalstore(cmd *cobra.Command) (*localstore.DB, logging.Logger, error) {
+	v, err := cmd.Flags().GetString(optionNameVerbosity)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get verbosity: %v", err)
+	}
+	logger, err := newLogger(cmd, strings.ToLower(v))
+	if err != nil {
+		return nil, nil, fmt.Errorf("new logger: %v", err)
+	}
+	dataDir, err := cmd.Flags().GetString(optionNameDataDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get data-dir: %v", err)
+	}
+	if dataDir == "" {
+		return nil, nil, errors.New("no data-dir provided")
+	}
+
+	storer, err := localstore.New(filepath.Join(dataDir, "localstore"), nil, nil, nil, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("localstore: %w", err)
+	}
+	return storer, logger, nil
+}
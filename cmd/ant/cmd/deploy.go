@@ -31,12 +31,17 @@ func (c *command) initDeployCmd() error {
 				return fmt.Errorf("new logger: %v", err)
 			}
 
+			networkConfig, err := c.getNetworkConfig(c.config.GetString(optionNameNetwork))
+			if err != nil {
+				return err
+			}
+
 			dataDir := c.config.GetString(optionNameDataDir)
-			factoryAddress := c.config.GetString(optionNameSwapFactoryAddress)
+			factoryAddress := networkConfig.swapFactoryAddress
 			swapInitialDeposit := c.config.GetString(optionNameSwapInitialDeposit)
-			swapEndpoint := c.config.GetString(optionNameSwapEndpoint)
+			swapEndpoints := c.config.GetStringSlice(optionNameSwapEndpoint)
 			deployGasPrice := c.config.GetString(optionNameSwapDeploymentGasPrice)
-			networkID := c.config.GetUint64(optionNameNetworkID)
+			networkID := networkConfig.networkID
 
 			stateStore, err := node.InitStateStore(logger, dataDir)
 			if err != nil {
@@ -57,7 +62,8 @@ func (c *command) initDeployCmd() error {
 				ctx,
 				logger,
 				stateStore,
-				swapEndpoint,
+				swapEndpoints,
+				c.config.GetBool(optionNameSwapLatencySelection),
 				signer,
 				blocktime,
 			)
@@ -13,7 +13,10 @@ type (
 )
 
 var (
-	NewCommand = newCommand
+	NewCommand         = newCommand
+	ShutdownExitCode   = shutdownExitCode
+	ServiceConfig      = serviceConfig
+	HasServiceArgument = hasServiceArgument
 
 	// avoid unused lint errors until the functions are used
 	_ = WithCfgFile
@@ -0,0 +1,222 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ethsana/sana/pkg/keystore/backup"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/node"
+	"github.com/spf13/cobra"
+)
+
+const optionNameBackupPasswordFile = "backup-password-file"
+
+func (c *command) initKeysCmd() {
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Back up and restore the node keys",
+		Long: `Back up and restore the node keys.
+
+The sana, libp2p and pss keys, the clef signer configuration and the
+chequebook deployment transaction are exported into a single archive which
+is encrypted with a backup password. Importing the archive into the data
+directory of another machine restores the node with the same overlay
+address. The keys of clef are not part of the archive and have to be backed
+up with clef.`,
+	}
+
+	c.initKeysExportCmd(cmd)
+	c.initKeysImportCmd(cmd)
+
+	c.root.AddCommand(cmd)
+}
+
+func (c *command) initKeysExportCmd(keysCmd *cobra.Command) {
+	cmd := &cobra.Command{
+		Use:   "export <filename>",
+		Short: "Export the node keys into an encrypted archive. Use \"-\" as filename in order to write to STDOUT",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			logger, err := c.keysLogger(cmd)
+			if err != nil {
+				return err
+			}
+
+			ks, err := c.newKeystore(logger)
+			if err != nil {
+				return err
+			}
+			exists, err := ks.Exists("libp2p")
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return errors.New("no keys found in the keystore")
+			}
+			password, err := c.keystorePassword(cmd, ks)
+			if err != nil {
+				return err
+			}
+
+			stateStore, err := node.InitStateStore(logger, c.config.GetString(optionNameDataDir))
+			if err != nil {
+				return err
+			}
+			defer stateStore.Close()
+
+			var clef *backup.Clef
+			if c.config.GetBool(optionNameClefSignerEnable) {
+				clef = &backup.Clef{
+					Endpoint:        c.config.GetString(optionNameClefSignerEndpoint),
+					EthereumAddress: c.config.GetString(optionNameClefSignerEthereumAddress),
+				}
+			}
+
+			backupPassword, err := c.backupPassword(cmd, true)
+			if err != nil {
+				return err
+			}
+			data, err := backup.NewExporter(ks, stateStore, clef).Export(password, backupPassword)
+			if err != nil {
+				return fmt.Errorf("export keys: %w", err)
+			}
+
+			if args[0] == "-" {
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+			if err := ioutil.WriteFile(args[0], data, 0600); err != nil {
+				return fmt.Errorf("write backup: %w", err)
+			}
+			logger.Infof("keys exported to %s", args[0])
+			return nil
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.config.BindPFlags(cmd.Flags())
+		},
+	}
+
+	c.setAllFlags(cmd)
+	cmd.Flags().String(optionNameBackupPasswordFile, "", "path to a file that contains the backup password")
+	keysCmd.AddCommand(cmd)
+}
+
+func (c *command) initKeysImportCmd(keysCmd *cobra.Command) {
+	cmd := &cobra.Command{
+		Use:   "import <filename>",
+		Short: "Import the node keys from an encrypted archive",
+		Long: `Import the node keys from an encrypted archive.
+
+The keys are encrypted with the password of the keystore, provided with
+--password or --password-file or prompted for. Keys which already exist are
+kept if they are the same as the imported ones, otherwise nothing is
+imported.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			logger, err := c.keysLogger(cmd)
+			if err != nil {
+				return err
+			}
+
+			data, err := ioutil.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("read backup: %w", err)
+			}
+			backupPassword, err := c.backupPassword(cmd, false)
+			if err != nil {
+				return err
+			}
+			b, err := backup.Decrypt(data, backupPassword)
+			if err != nil {
+				return fmt.Errorf("decrypt backup: %w", err)
+			}
+
+			ks, err := c.newKeystore(logger)
+			if err != nil {
+				return err
+			}
+			password, err := c.keystorePassword(cmd, ks)
+			if err != nil {
+				return err
+			}
+
+			stateStore, err := node.InitStateStore(logger, c.config.GetString(optionNameDataDir))
+			if err != nil {
+				return err
+			}
+			defer stateStore.Close()
+
+			if err := b.Restore(ks, password, stateStore); err != nil {
+				return fmt.Errorf("import keys: %w", err)
+			}
+			logger.Infof("keys imported from backup created at %s", b.CreatedAt)
+			if b.Clef != nil {
+				logger.Infof("the sana key is managed by clef, start the node with --%s --%s=%s --%s=%s", optionNameClefSignerEnable, optionNameClefSignerEndpoint, b.Clef.Endpoint, optionNameClefSignerEthereumAddress, b.Clef.EthereumAddress)
+			}
+			if b.ChequebookDeployment == nil {
+				logger.Warning("the backup has no chequebook deployment, the overlay address is derived again on the first start")
+			}
+			return nil
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.config.BindPFlags(cmd.Flags())
+		},
+	}
+
+	c.setAllFlags(cmd)
+	cmd.Flags().String(optionNameBackupPasswordFile, "", "path to a file that contains the backup password")
+	keysCmd.AddCommand(cmd)
+}
+
+func (c *command) keysLogger(cmd *cobra.Command) (logging.Logger, error) {
+	if c.config.GetString(optionNameDataDir) == "" {
+		return nil, errors.New("no data-dir provided")
+	}
+	logger, err := newLogger(cmd, strings.ToLower(c.config.GetString(optionNameVerbosity)))
+	if err != nil {
+		return nil, fmt.Errorf("new logger: %v", err)
+	}
+	return logger, nil
+}
+
+// backupPassword returns the password of the backup archive from the backup
+// password file or prompts for it, with a confirmation if it is a new one.
+func (c *command) backupPassword(cmd *cobra.Command, confirm bool) (string, error) {
+	if pf := c.config.GetString(optionNameBackupPasswordFile); pf != "" {
+		b, err := ioutil.ReadFile(pf)
+		if err != nil {
+			return "", err
+		}
+		return string(bytes.Trim(b, "\n")), nil
+	}
+	password, err := terminalPromptPassword(cmd, c.passwordReader, "Backup password")
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		return "", errors.New("backup password not provided")
+	}
+	if confirm {
+		p, err := terminalPromptPassword(cmd, c.passwordReader, "Confirm backup password")
+		if err != nil {
+			return "", err
+		}
+		if p != password {
+			return "", errors.New("passwords are not the same")
+		}
+	}
+	return password, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ethsana/sana/pkg/apiclient"
+	"github.com/ethsana/sana/pkg/manifestfs"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/spf13/cobra"
+)
+
+func (c *command) initMountCmd() {
+	cmd := &cobra.Command{
+		Use:   "mount <reference> <mountpoint>",
+		Short: "Mount a manifest of a running node as a FUSE filesystem",
+		Long: `Mount a manifest of a running node as a FUSE filesystem.
+
+The filesystem is read-only unless writeback is enabled. With writeback,
+modified files are kept locally and published as a new manifest when the
+filesystem is unmounted. The new manifest reference is printed.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if len(args) != 2 {
+				return cmd.Help()
+			}
+			v, err := cmd.Flags().GetString(optionNameVerbosity)
+			if err != nil {
+				return fmt.Errorf("get verbosity: %v", err)
+			}
+			logger, err := newLogger(cmd, strings.ToLower(v))
+			if err != nil {
+				return fmt.Errorf("new logger: %v", err)
+			}
+
+			reference, err := swarm.ParseHexAddress(args[0])
+			if err != nil {
+				return fmt.Errorf("parse reference: %w", err)
+			}
+			mountpoint := args[1]
+
+			apiURL, err := cmd.Flags().GetString(optionNameAPIURL)
+			if err != nil {
+				return fmt.Errorf("get api-url: %v", err)
+			}
+			writeback, err := cmd.Flags().GetBool(optionNameWriteback)
+			if err != nil {
+				return fmt.Errorf("get writeback: %v", err)
+			}
+			batchHex, err := cmd.Flags().GetString(optionNamePostageBatch)
+			if err != nil {
+				return fmt.Errorf("get postage-batch: %v", err)
+			}
+			batch, err := parsePostageBatch(batchHex)
+			if err != nil {
+				return err
+			}
+			if writeback && batch == nil {
+				return errors.New("writeback requires a postage batch")
+			}
+
+			client := apiclient.New(apiURL, apiclient.Options{PostageBatch: batch})
+			fs, err := manifestfs.New(cmd.Context(), client, reference, manifestfs.Options{Writeback: writeback})
+			if err != nil {
+				return err
+			}
+			defer fs.Close()
+
+			server, err := manifestfs.Mount(mountpoint, fs, logger)
+			if err != nil {
+				return fmt.Errorf("mount: %w", err)
+			}
+
+			logger.Infof("mounted %s on %s", reference, mountpoint)
+
+			unmountedC := make(chan struct{})
+			go func() {
+				server.Wait()
+				close(unmountedC)
+			}()
+
+			interruptChannel := make(chan os.Signal, 1)
+			signal.Notify(interruptChannel, syscall.SIGINT, syscall.SIGTERM)
+			defer signal.Stop(interruptChannel)
+
+			select {
+			case sig := <-interruptChannel:
+				logger.Debugf("received signal: %v", sig)
+				if err := server.Unmount(); err != nil {
+					return fmt.Errorf("unmount: %w", err)
+				}
+				<-unmountedC
+			case <-unmountedC:
+			}
+			logger.Infof("unmounted %s", mountpoint)
+
+			if !fs.Modified() {
+				return nil
+			}
+			published, err := fs.Publish(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("publish: %w", err)
+			}
+			cmd.Println(published.String())
+			return nil
+		},
+	}
+	cmd.Flags().String(optionNameAPIURL, "http://localhost:1633", "HTTP API URL of the node")
+	cmd.Flags().Bool(optionNameWriteback, false, "publish modifications as a new manifest on unmount")
+	cmd.Flags().String(optionNamePostageBatch, "", "postage batch ID used to publish modifications")
+	cmd.Flags().String(optionNameVerbosity, "info", "verbosity level")
+	cmd.SetOut(c.root.OutOrStdout())
+	c.root.AddCommand(cmd)
+}
+
+// parsePostageBatch decodes the optional hex encoded postage batch ID.
+func parsePostageBatch(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	batch, err := hex.DecodeString(s)
+	if err != nil || len(batch) != 32 {
+		return nil, errors.New("invalid postage batch")
+	}
+	return batch, nil
+}
@@ -0,0 +1,334 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kardianos/service"
+	"github.com/spf13/cobra"
+)
+
+const optionNameServiceRestartDelay = "restart-delay"
+
+// serviceSystemdScript is the systemd unit of the service. In difference to
+// the default unit of the service package, the node is only restarted when
+// it fails, with a configurable delay.
+const serviceSystemdScript = `[Unit]
+Description={{.Description}}
+ConditionFileIsExecutable={{.Path|cmdEscape}}
+After=network-online.target
+Wants=network-online.target
+StartLimitIntervalSec=600
+StartLimitBurst=5
+
+[Service]
+ExecStart={{.Path|cmdEscape}}{{range .Arguments}} {{.|cmd}}{{end}}
+{{if .WorkingDirectory}}WorkingDirectory={{.WorkingDirectory|cmdEscape}}{{end}}
+{{if .UserName}}User={{.UserName}}{{end}}
+{{if gt .LimitNOFILE -1 }}LimitNOFILE={{.LimitNOFILE}}{{end}}
+Restart={{.Restart}}
+{{if .SuccessExitStatus}}SuccessExitStatus={{.SuccessExitStatus}}{{end}}
+RestartSec=%d
+TimeoutStopSec=60
+EnvironmentFile=-/etc/sysconfig/{{.Name}}
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// serviceLaunchdScript is the launchd property list of the service. The node
+// is restarted when it exits with an error, throttled by the restart delay.
+// In difference to systemd, launchd can not tell the shutdowns for
+// maintenance and by the timebomb from failures.
+const serviceLaunchdScript = `<?xml version='1.0' encoding='UTF-8'?>
+<!DOCTYPE plist PUBLIC "-//Apple Computer//DTD PLIST 1.0//EN"
+"http://www.apple.com/DTDs/PropertyList-1.0.dtd" >
+<plist version='1.0'>
+  <dict>
+    <key>Label</key>
+    <string>{{html .Name}}</string>
+    <key>ProgramArguments</key>
+    <array>
+      <string>{{html .Path}}</string>
+    {{range .Config.Arguments}}
+      <string>{{html .}}</string>
+    {{end}}
+    </array>
+    {{if .UserName}}<key>UserName</key>
+    <string>{{html .UserName}}</string>{{end}}
+    {{if .WorkingDirectory}}<key>WorkingDirectory</key>
+    <string>{{html .WorkingDirectory}}</string>{{end}}
+    <key>KeepAlive</key>
+    <dict>
+      <key>SuccessfulExit</key>
+      <false/>
+    </dict>
+    <key>ThrottleInterval</key>
+    <integer>%d</integer>
+    <key>RunAtLoad</key>
+    <true/>
+    <key>Disabled</key>
+    <false/>
+    <key>StandardOutPath</key>
+    <string>/usr/local/var/log/{{html .Name}}.out.log</string>
+    <key>StandardErrorPath</key>
+    <string>/usr/local/var/log/{{html .Name}}.err.log</string>
+  </dict>
+</plist>
+`
+
+func (c *command) initServiceCmd() {
+	cmd := &cobra.Command{
+		Use:   "service",
+		Short: "Manage the node as a service of the operating system",
+	}
+
+	c.serviceInstallCmd(cmd)
+	c.serviceUninstallCmd(cmd)
+	c.serviceStartCmd(cmd)
+	c.serviceStopCmd(cmd)
+	c.serviceStatusCmd(cmd)
+
+	c.root.AddCommand(cmd)
+}
+
+func (c *command) serviceInstallCmd(cmd *cobra.Command) {
+	installCmd := &cobra.Command{
+		Use:   "install [-- start flags]",
+		Short: "Install the node as a service which is restarted on failure",
+		Long: `Install the node as a service which is restarted on failure.
+
+The service runs "ant start" with the flags given after "--", the data
+directory and the config file, if it is found, so that the service node uses
+the same configuration as the command. It is a systemd unit on Linux, a
+launchd daemon on macOS and a service of the service control manager on
+Windows.
+
+The service is restarted by the service manager when the node fails, after
+the restart delay. On Windows the delay doubles for the second and further
+failures within a day. Shutdowns for maintenance and by the timebomb are not
+failures, so that the node is not restarted after them, except on macOS
+where launchd restarts the node after every shutdown with an error.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			delay, err := cmd.Flags().GetDuration(optionNameServiceRestartDelay)
+			if err != nil {
+				return fmt.Errorf("get restart-delay: %v", err)
+			}
+			if delay < time.Second {
+				return errors.New("restart delay must be at least one second")
+			}
+
+			dataDir, err := filepath.Abs(c.config.GetString(optionNameDataDir))
+			if err != nil {
+				return fmt.Errorf("data directory: %w", err)
+			}
+			if !hasServiceArgument(args, optionNameDataDir) {
+				args = append([]string{"--" + optionNameDataDir + "=" + dataDir}, args...)
+			}
+			if f := c.config.ConfigFileUsed(); f != "" && !hasServiceArgument(args, "config") {
+				if f, err = filepath.Abs(f); err != nil {
+					return fmt.Errorf("config file: %w", err)
+				}
+				args = append([]string{"--config=" + f}, args...)
+			}
+
+			s, err := service.New(&program{}, serviceConfig(args, delay))
+			if err != nil {
+				return err
+			}
+			if err := s.Install(); err != nil {
+				return fmt.Errorf("install service: %w", err)
+			}
+			if err := setServiceRecoveryActions(serviceName, delay); err != nil {
+				return fmt.Errorf("set recovery actions: %w", err)
+			}
+
+			cmd.Printf("service %s installed on %s\n", serviceName, s.Platform())
+			cmd.Printf("service arguments: %s\n", strings.Join(serviceConfig(args, delay).Arguments, " "))
+			return nil
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.config.BindPFlags(cmd.Flags())
+		},
+	}
+	installCmd.Flags().Duration(optionNameServiceRestartDelay, 10*time.Second, "delay before the node is restarted after a failure")
+	installCmd.Flags().String(optionNameDataDir, filepath.Join(c.homeDir, ".sana"), "data directory of the service node")
+
+	cmd.AddCommand(installCmd)
+}
+
+func (c *command) serviceUninstallCmd(cmd *cobra.Command) {
+	cmd.AddCommand(&cobra.Command{
+		Use:   "uninstall",
+		Short: "Stop and uninstall the node service",
+		Long: `Stop and uninstall the node service.
+
+The data directory and the config file of the node are kept.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if len(args) > 0 {
+				return cmd.Help()
+			}
+
+			s, err := service.New(&program{}, serviceConfig(nil, 0))
+			if err != nil {
+				return err
+			}
+			if err := stopService(s); err != nil {
+				return err
+			}
+			if err := s.Uninstall(); err != nil {
+				return fmt.Errorf("uninstall service: %w", err)
+			}
+			cmd.Printf("service %s uninstalled\n", serviceName)
+			return nil
+		},
+	})
+}
+
+func (c *command) serviceStartCmd(cmd *cobra.Command) {
+	cmd.AddCommand(&cobra.Command{
+		Use:   "start",
+		Short: "Start the node service",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if len(args) > 0 {
+				return cmd.Help()
+			}
+
+			s, err := service.New(&program{}, serviceConfig(nil, 0))
+			if err != nil {
+				return err
+			}
+			status, err := s.Status()
+			if err != nil {
+				return fmt.Errorf("service status: %w", err)
+			}
+			if status == service.StatusRunning {
+				cmd.Printf("service %s is already running\n", serviceName)
+				return nil
+			}
+			if err := s.Start(); err != nil {
+				return fmt.Errorf("start service: %w", err)
+			}
+			cmd.Printf("service %s started\n", serviceName)
+			return nil
+		},
+	})
+}
+
+func (c *command) serviceStopCmd(cmd *cobra.Command) {
+	cmd.AddCommand(&cobra.Command{
+		Use:   "stop",
+		Short: "Stop the node service",
+		Long: `Stop the node service.
+
+The node is not restarted by the service manager until the service is started
+again, or the machine is restarted.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if len(args) > 0 {
+				return cmd.Help()
+			}
+
+			s, err := service.New(&program{}, serviceConfig(nil, 0))
+			if err != nil {
+				return err
+			}
+			if _, err := s.Status(); err != nil {
+				return fmt.Errorf("service status: %w", err)
+			}
+			if err := stopService(s); err != nil {
+				return err
+			}
+			cmd.Printf("service %s stopped\n", serviceName)
+			return nil
+		},
+	})
+}
+
+func (c *command) serviceStatusCmd(cmd *cobra.Command) {
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Print the status of the node service",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if len(args) > 0 {
+				return cmd.Help()
+			}
+
+			s, err := service.New(&program{}, serviceConfig(nil, 0))
+			if err != nil {
+				return err
+			}
+			status, err := s.Status()
+			if err != nil {
+				if errors.Is(err, service.ErrNotInstalled) {
+					cmd.Printf("service %s is not installed\n", serviceName)
+					return nil
+				}
+				return fmt.Errorf("service status: %w", err)
+			}
+
+			switch status {
+			case service.StatusRunning:
+				cmd.Printf("service %s is running\n", serviceName)
+			case service.StatusStopped:
+				cmd.Printf("service %s is stopped\n", serviceName)
+			default:
+				cmd.Printf("service %s status is unknown\n", serviceName)
+			}
+			return nil
+		},
+	})
+}
+
+// stopService stops the service if it is running.
+func stopService(s service.Service) error {
+	status, err := s.Status()
+	if err != nil {
+		if errors.Is(err, service.ErrNotInstalled) {
+			return err
+		}
+		return fmt.Errorf("service status: %w", err)
+	}
+	if status != service.StatusRunning {
+		return nil
+	}
+	if err := s.Stop(); err != nil {
+		return fmt.Errorf("stop service: %w", err)
+	}
+	return nil
+}
+
+// hasServiceArgument returns true if the flag with the name is in the
+// arguments of the service.
+func hasServiceArgument(args []string, name string) bool {
+	for _, a := range args {
+		if a == "--"+name || strings.HasPrefix(a, "--"+name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceConfig returns the configuration of the node service which runs the
+// start command with the arguments.
+func serviceConfig(args []string, restartDelay time.Duration) *service.Config {
+	return &service.Config{
+		Name:        serviceName,
+		DisplayName: "Ant",
+		Description: "Ant, Sana client.",
+		Arguments:   append([]string{"start"}, args...),
+		Option: service.KeyValue{
+			"Restart":           "on-failure",
+			"SuccessExitStatus": strconv.Itoa(ExitCodeTimebomb) + " " + strconv.Itoa(ExitCodeMaintenance),
+			"SystemdScript":     fmt.Sprintf(serviceSystemdScript, int(restartDelay.Seconds())),
+			"LaunchdConfig":     fmt.Sprintf(serviceLaunchdScript, int(restartDelay.Seconds())),
+		},
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd_test
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/ethsana/sana/cmd/ant/cmd"
+	"github.com/kardianos/service"
+)
+
+func TestServiceConfig(t *testing.T) {
+	cfg := cmd.ServiceConfig([]string{"--data-dir=/var/lib/sana", "--full-node"}, 10*time.Second)
+
+	wantArgs := []string{"start", "--data-dir=/var/lib/sana", "--full-node"}
+	if !reflect.DeepEqual(cfg.Arguments, wantArgs) {
+		t.Errorf("got arguments %v, want %v", cfg.Arguments, wantArgs)
+	}
+	if got := cfg.Option["Restart"]; got != "on-failure" {
+		t.Errorf("got restart %v, want on-failure", got)
+	}
+
+	script, ok := cfg.Option["SystemdScript"].(string)
+	if !ok {
+		t.Fatalf("got systemd script %T, want string", cfg.Option["SystemdScript"])
+	}
+	i := strings.Index(script, "[Service]")
+	if i < 0 {
+		t.Fatal("systemd script without the service section")
+	}
+	unit, svc := script[:i], script[i:]
+	for _, want := range []string{"StartLimitIntervalSec=600\n", "StartLimitBurst=5\n"} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("unit section without %q", want)
+		}
+	}
+	if strings.Contains(svc, "StartLimit") {
+		t.Error("service section with the start limits")
+	}
+	if !strings.Contains(svc, "RestartSec=10\n") {
+		t.Errorf("service section without the restart delay")
+	}
+}
+
+func TestHasServiceArgument(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+		want bool
+	}{
+		{
+			name: "no arguments",
+		},
+		{
+			name: "flag",
+			args: []string{"--full-node", "--data-dir"},
+			want: true,
+		},
+		{
+			name: "flag with value",
+			args: []string{"--data-dir=/var/lib/sana"},
+			want: true,
+		},
+		{
+			name: "flag with the name as prefix",
+			args: []string{"--data-dir-other=/var/lib/sana", "--data-directory"},
+		},
+		{
+			name: "value",
+			args: []string{"--config", "data-dir"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := cmd.HasServiceArgument(tc.args, "data-dir"); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// serviceTemplateFuncs are the template functions of the systemd and launchd
+// services of the service package.
+var serviceTemplateFuncs = template.FuncMap{
+	"cmd": func(s string) string {
+		return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+	},
+	"cmdEscape": func(s string) string {
+		return strings.Replace(s, " ", `\x20`, -1)
+	},
+	"bool": func(v bool) string {
+		return strconv.FormatBool(v)
+	},
+}
+
+func TestServiceTemplates(t *testing.T) {
+	cfg := cmd.ServiceConfig([]string{"--data-dir=/var/lib/sana"}, 30*time.Second)
+	cfg.UserName = "sana"
+
+	wantExitStatus := strconv.Itoa(cmd.ExitCodeTimebomb) + " " + strconv.Itoa(cmd.ExitCodeMaintenance)
+	if got := cfg.Option["SuccessExitStatus"]; got != wantExitStatus {
+		t.Errorf("got success exit status %v, want %v", got, wantExitStatus)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		option   string
+		data     interface{}
+		wantOuts []string
+	}{
+		{
+			name:   "systemd",
+			option: "SystemdScript",
+			// the data of the systemd unit of the service package
+			data: &struct {
+				*service.Config
+				Path              string
+				LimitNOFILE       int
+				Restart           string
+				SuccessExitStatus string
+			}{
+				Config:            cfg,
+				Path:              "/usr/local/bin/ant",
+				LimitNOFILE:       -1,
+				Restart:           cfg.Option["Restart"].(string),
+				SuccessExitStatus: cfg.Option["SuccessExitStatus"].(string),
+			},
+			wantOuts: []string{
+				"After=network-online.target\nWants=network-online.target\nStartLimitIntervalSec=600\nStartLimitBurst=5\n\n[Service]\n",
+				"ExecStart=/usr/local/bin/ant \"start\" \"--data-dir=/var/lib/sana\"\n",
+				"User=sana\n",
+				"Restart=on-failure\n",
+				"SuccessExitStatus=" + wantExitStatus + "\n",
+				"RestartSec=30\n",
+			},
+		},
+		{
+			name:   "launchd",
+			option: "LaunchdConfig",
+			// the data of the launchd property list of the service package
+			data: &struct {
+				*service.Config
+				Path string
+			}{
+				Config: cfg,
+				Path:   "/usr/local/bin/ant",
+			},
+			wantOuts: []string{
+				"<string>/usr/local/bin/ant</string>",
+				"<string>start</string>",
+				"<string>--data-dir=/var/lib/sana</string>",
+				"<key>UserName</key>\n    <string>sana</string>",
+				"<key>SuccessfulExit</key>\n      <false/>",
+				"<key>ThrottleInterval</key>\n    <integer>30</integer>",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			script, ok := cfg.Option[tc.option].(string)
+			if !ok {
+				t.Fatalf("got %s %T, want string", tc.option, cfg.Option[tc.option])
+			}
+			tmpl, err := template.New(tc.name).Funcs(serviceTemplateFuncs).Parse(script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, tc.data); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+			for _, want := range tc.wantOuts {
+				if !strings.Contains(got, want) {
+					t.Errorf("got %s %q, want %q", tc.option, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package cmd
+
+import "time"
+
+// setServiceRecoveryActions is a no-op as the restarts on failure are
+// configured in the service definition.
+func setServiceRecoveryActions(name string, delay time.Duration) error {
+	return nil
+}
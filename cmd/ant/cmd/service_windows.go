@@ -0,0 +1,49 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package cmd
+
+import (
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// serviceRecoveryResetPeriod is the duration without failures, in seconds,
+// after which the failure count of the service is reset.
+const serviceRecoveryResetPeriod = 24 * 60 * 60
+
+// setServiceRecoveryActions configures the service control manager to
+// restart the service when it fails, waiting for the delay after the first
+// failure and twice as long after further failures.
+func setServiceRecoveryActions(name string, delay time.Duration) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err := s.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: delay},
+		{Type: mgr.ServiceRestart, Delay: 2 * delay},
+		{Type: mgr.ServiceRestart, Delay: 2 * delay},
+	}, serviceRecoveryResetPeriod); err != nil {
+		return err
+	}
+
+	// apply the actions also when the service stops with an error, not only
+	// when the process crashes
+	flag := struct{ failureActionsOnNonCrashFailures int32 }{1}
+	return windows.ChangeServiceConfig2(s.Handle, windows.SERVICE_CONFIG_FAILURE_ACTIONS_FLAG, (*byte)(unsafe.Pointer(&flag)))
+}
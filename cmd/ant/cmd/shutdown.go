@@ -0,0 +1,116 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/node"
+	"github.com/sirupsen/logrus"
+)
+
+// Process exit codes which are used depending on the shutdown reason, so
+// that process supervisors can distinguish between clean exits and crashes.
+const (
+	ExitCodeOK          = 0
+	ExitCodeFatal       = 1
+	ExitCodeForced      = 2
+	ExitCodeTimebomb    = 3
+	ExitCodeMaintenance = 4
+)
+
+const shutdownReportFilename = "shutdown-report.json"
+
+// ExitError is returned by Execute when the process needs to exit with a
+// specific exit code.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit code %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// shutdownExitCode returns the process exit code for the shutdown reason.
+func shutdownExitCode(reason node.ShutdownReason, forced bool) int {
+	if forced {
+		return ExitCodeForced
+	}
+	switch reason {
+	case node.ShutdownReasonFatal:
+		return ExitCodeFatal
+	case node.ShutdownReasonTimebomb:
+		return ExitCodeTimebomb
+	case node.ShutdownReasonMaintenance:
+		return ExitCodeMaintenance
+	default:
+		return ExitCodeOK
+	}
+}
+
+// shutdownState holds the reason of the shutdown as it is determined by
+// the start and stop functions of the program which may be called from
+// different goroutines.
+type shutdownState struct {
+	mu     sync.Mutex
+	reason node.ShutdownReason
+	err    error
+}
+
+// set records the shutdown reason, only the first reason is kept.
+func (s *shutdownState) set(reason node.ShutdownReason, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.reason != "" {
+		return
+	}
+	s.reason = reason
+	s.err = err
+}
+
+func (s *shutdownState) get() (node.ShutdownReason, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.reason, s.err
+}
+
+// reportShutdown logs the shutdown report and writes it to the data
+// directory, if one is configured.
+func reportShutdown(logger logging.Logger, dataDir string, report *node.ShutdownReport) {
+	for _, c := range report.Components {
+		logger.WithFields(logrus.Fields{
+			"component": c.Name,
+			"duration":  c.Duration.String(),
+			"error":     c.Error,
+		}).Debug("shutdown: component stopped")
+	}
+	logger.WithFields(logrus.Fields{
+		"reason":   report.Reason,
+		"duration": report.Duration.String(),
+		"forced":   report.Forced,
+		"error":    report.Error,
+	}).Info("shutdown report")
+
+	if dataDir == "" {
+		return
+	}
+	if err := report.WriteFile(filepath.Join(dataDir, shutdownReportFilename)); err != nil {
+		logger.Debugf("shutdown: write report: %v", err)
+		logger.Warning("unable to write shutdown report")
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethsana/sana/cmd/ant/cmd"
+	"github.com/ethsana/sana/pkg/node"
+)
+
+func TestShutdownExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		reason node.ShutdownReason
+		forced bool
+		want   int
+	}{
+		{reason: node.ShutdownReasonSignal, want: cmd.ExitCodeOK},
+		{reason: node.ShutdownReasonFatal, want: cmd.ExitCodeFatal},
+		{reason: node.ShutdownReasonTimebomb, want: cmd.ExitCodeTimebomb},
+		{reason: node.ShutdownReasonMaintenance, want: cmd.ExitCodeMaintenance},
+		{reason: node.ShutdownReasonFatal, forced: true, want: cmd.ExitCodeForced},
+		{reason: node.ShutdownReasonSignal, forced: true, want: cmd.ExitCodeForced},
+	} {
+		if got := cmd.ShutdownExitCode(tc.reason, tc.forced); got != tc.want {
+			t.Errorf("reason %q forced %v: got exit code %d, want %d", tc.reason, tc.forced, got, tc.want)
+		}
+	}
+}
+
+func TestExitError(t *testing.T) {
+	err := errors.New("fatal error")
+	e := &cmd.ExitError{Code: cmd.ExitCodeFatal, Err: err}
+	if e.Error() != "fatal error" {
+		t.Errorf("got message %q, want %q", e.Error(), "fatal error")
+	}
+	if !errors.Is(e, err) {
+		t.Error("exit error does not wrap the error")
+	}
+
+	e = &cmd.ExitError{Code: cmd.ExitCodeForced}
+	if e.Error() != "exit code 2" {
+		t.Errorf("got message %q, want %q", e.Error(), "exit code 2")
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ethsana/sana/pkg/node"
+	"github.com/ethsana/sana/pkg/standby"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	optionNameStandbyInterval        = "standby-interval"
+	optionNameStandbyFailoverTimeout = "standby-failover-timeout"
+	optionNameStandbyAutoPromote     = "standby-auto-promote"
+)
+
+func (c *command) initStandbyCmd() {
+	cmd := &cobra.Command{
+		Use:   "standby",
+		Short: "Replicate an active node to be able to take over when it is lost",
+		Long: `Replicate an active node to be able to take over when it is lost.
+
+The pins, postage batches, chequebook and transaction metadata are copied
+from the pairing endpoint of the active node, given by --standby-peer, into
+the state store of the data directory. The keys of the active node are not
+replicated and need to be provisioned in the data directory separately.
+
+The standby is promoted with a POST request to /standby/promote on its own
+pairing endpoint, or automatically with --standby-auto-promote. Promotion is
+refused while the active node answers or was replicated within the failover
+timeout. After promotion the command exits and the node can be started with
+the same data directory. A promoted node does not start while the former
+active node is running, and the former active node stops once it sees the
+promoted one.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if len(args) > 0 {
+				return cmd.Help()
+			}
+			v, err := cmd.Flags().GetString(optionNameVerbosity)
+			if err != nil {
+				return fmt.Errorf("get verbosity: %v", err)
+			}
+			logger, err := newLogger(cmd, strings.ToLower(v))
+			if err != nil {
+				return fmt.Errorf("new logger: %v", err)
+			}
+
+			dataDir, err := cmd.Flags().GetString(optionNameDataDir)
+			if err != nil {
+				return fmt.Errorf("get data-dir: %v", err)
+			}
+			if dataDir == "" {
+				return errors.New("no data-dir provided")
+			}
+			addr, err := cmd.Flags().GetString(optionNameStandbyAddr)
+			if err != nil {
+				return fmt.Errorf("get standby-addr: %v", err)
+			}
+			peerURL, err := cmd.Flags().GetString(optionNameStandbyPeer)
+			if err != nil {
+				return fmt.Errorf("get standby-peer: %v", err)
+			}
+			if peerURL == "" {
+				return errors.New("no standby-peer provided")
+			}
+			token, err := cmd.Flags().GetString(optionNameStandbyToken)
+			if err != nil {
+				return fmt.Errorf("get standby-token: %v", err)
+			}
+			interval, err := cmd.Flags().GetDuration(optionNameStandbyInterval)
+			if err != nil {
+				return fmt.Errorf("get standby-interval: %v", err)
+			}
+			var o standby.Options
+			if o.FailoverTimeout, err = cmd.Flags().GetDuration(optionNameStandbyFailoverTimeout); err != nil {
+				return fmt.Errorf("get standby-failover-timeout: %v", err)
+			}
+			if o.AutoPromote, err = cmd.Flags().GetBool(optionNameStandbyAutoPromote); err != nil {
+				return fmt.Errorf("get standby-auto-promote: %v", err)
+			}
+
+			stateStore, err := node.InitStateStore(logger, dataDir)
+			if err != nil {
+				return err
+			}
+			defer stateStore.Close()
+
+			s, err := standby.New(stateStore, logger, token, o)
+			if err != nil {
+				return err
+			}
+			peer := standby.NewClient(peerURL, token)
+
+			if addr != "" {
+				l, err := net.Listen("tcp", addr)
+				if err != nil {
+					return fmt.Errorf("standby listener: %w", err)
+				}
+				errorLogWriter := logger.WriterLevel(logrus.ErrorLevel)
+				defer errorLogWriter.Close()
+				server := &http.Server{
+					IdleTimeout:       30 * time.Second,
+					ReadHeaderTimeout: 3 * time.Second,
+					Handler:           s.Handler(peer),
+					ErrorLog:          log.New(errorLogWriter, "", 0),
+				}
+				go func() {
+					logger.Infof("standby address: %s", l.Addr())
+					if err := server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+						logger.Errorf("standby server: %v", err)
+					}
+				}()
+				defer server.Close()
+			}
+
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+			interruptChannel := make(chan os.Signal, 1)
+			signal.Notify(interruptChannel, syscall.SIGINT, syscall.SIGTERM)
+			go func() {
+				select {
+				case <-interruptChannel:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+
+			logger.Infof("replicating %s into %s", peerURL, dataDir)
+			s.Run(ctx, peer, interval)
+
+			select {
+			case <-s.Promoted():
+				logger.Infof("promoted in standby epoch %d, the node can be started with data-dir %s", s.State().Epoch, dataDir)
+			default:
+				logger.Info("standby stopped")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String(optionNameDataDir, filepath.Join(c.homeDir, ".sana"), "data directory")
+	cmd.Flags().String(optionNameVerbosity, "info", "verbosity level")
+	cmd.Flags().String(optionNameStandbyAddr, ":1640", "pairing endpoint listen address")
+	cmd.Flags().String(optionNameStandbyPeer, "", "pairing endpoint URL of the active node")
+	cmd.Flags().String(optionNameStandbyToken, "", "token shared by the node and its standby")
+	cmd.Flags().Duration(optionNameStandbyInterval, 30*time.Second, "interval between replications")
+	cmd.Flags().Duration(optionNameStandbyFailoverTimeout, 5*time.Minute, "minimal time since the last replication before promotion")
+	cmd.Flags().Bool(optionNameStandbyAutoPromote, false, "promote once the active node can not be replicated for the failover timeout")
+
+	c.root.AddCommand(cmd)
+}
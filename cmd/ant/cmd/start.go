@@ -8,8 +8,8 @@ import (
 	"bytes"
 	"context"
 	"crypto/ecdsa"
-	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 
 	"os"
@@ -24,14 +24,19 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethsana/sana"
 	tee "github.com/ethsana/sana-tee"
+	"github.com/ethsana/sana/pkg/config"
 	"github.com/ethsana/sana/pkg/crypto"
 	"github.com/ethsana/sana/pkg/crypto/clef"
 	"github.com/ethsana/sana/pkg/keystore"
+	"github.com/ethsana/sana/pkg/keystore/backup"
 	filekeystore "github.com/ethsana/sana/pkg/keystore/file"
 	memkeystore "github.com/ethsana/sana/pkg/keystore/mem"
+	vaultkeystore "github.com/ethsana/sana/pkg/keystore/vault"
 	"github.com/ethsana/sana/pkg/logging"
 	"github.com/ethsana/sana/pkg/node"
+	"github.com/ethsana/sana/pkg/reload"
 	"github.com/ethsana/sana/pkg/resolver/multiresolver"
+	"github.com/ethsana/sana/pkg/tracing"
 	"github.com/kardianos/service"
 	"github.com/spf13/cobra"
 )
@@ -51,10 +56,30 @@ func (c *command) initStartCmd() (err error) {
 			}
 
 			v := strings.ToLower(c.config.GetString(optionNameVerbosity))
-			logger, err := newLogger(cmd, v)
+			logOptions, err := logFormatOptions(strings.ToLower(c.config.GetString(optionNameLogFormat)))
 			if err != nil {
 				return fmt.Errorf("new logger: %v", err)
 			}
+			logger, err := newLogger(cmd, v, logOptions...)
+			if err != nil {
+				return fmt.Errorf("new logger: %v", err)
+			}
+			if err := setLogLevels(logger, v, c.config.GetString(optionNameLogLevel)); err != nil {
+				return fmt.Errorf("new logger: %v", err)
+			}
+
+			issues, err := c.checkConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			for _, i := range issues {
+				if i.warning {
+					logger.Warningf("config: %s", i)
+				}
+			}
+			if err := issues.err(); err != nil {
+				return fmt.Errorf("config: %w", err)
+			}
 
 			go startTimeBomb(logger)
 
@@ -110,28 +135,55 @@ func (c *command) initStartCmd() (err error) {
 			if err != nil {
 				return err
 			}
+			if closer, ok := signerConfig.signer.(io.Closer); ok {
+				defer closer.Close()
+			}
 
 			logger.Infof("version: %v", sana.Version)
 
 			bootNode := c.config.GetBool(optionNameBootnodeMode)
 			fullNode := c.config.GetBool(optionNameFullNode)
 
-			if bootNode && !fullNode {
-				return errors.New("boot node must be started as a full node")
+			network := c.config.GetString(optionNameNetwork)
+			networkConfig, err := c.getNetworkConfig(network)
+			if err != nil {
+				return err
+			}
+			networkID := networkConfig.networkID
+			if c.config.GetBool(optionNameRequireTEE) && networkID == config.DefaultNetworkProfiles()["mainnet"].NetworkID {
+				if err := checkTEE(); err != nil {
+					return fmt.Errorf("require tee: %w", err)
+				}
 			}
 
-			networkID := c.config.GetUint64(optionNameNetworkID)
-			bootnodes := c.config.GetStringSlice(optionNameBootnodes)
-			blockTime := c.config.GetUint64(optionNameBlockTime)
+			swapEnable := c.config.GetBool(optionNameSwapEnable)
+			mineEnable := c.config.GetBool(optionNameMine)
+			if network == node.NetworkDevStatic {
+				// there is no chain to settle on or to mine with and the
+				// public bootnodes are not part of the network
+				swapEnable, mineEnable = false, false
+				if !c.config.IsSet(optionNameBootnodes) {
+					networkConfig.bootNodes = nil
+				}
+			}
 
-			networkConfig := getConfigByNetworkID(networkID, blockTime)
+			// Reload the configuration on signals, the changes are applied
+			// once the node is started.
+			reloadChannel := make(chan os.Signal, 1)
+			signal.Notify(reloadChannel, reloadSignals...)
+			defer signal.Stop(reloadChannel)
 
-			if c.config.IsSet(optionNameBootnodes) {
-				networkConfig.bootNodes = bootnodes
+			reloader, err := reload.New(c.loadSettings, logger)
+			if err != nil {
+				return fmt.Errorf("reloader: %w", err)
 			}
 
-			if c.config.IsSet(optionNameBlockTime) && blockTime != 0 {
-				networkConfig.blockTime = blockTime
+			// the default endpoint is the one of the Jaeger agent, the OTLP
+			// exporters default to the collector on localhost
+			tracingExporter := c.config.GetString(optionNameTracingExporter)
+			tracingEndpoint := c.config.GetString(optionNameTracingEndpoint)
+			if tracingExporter != tracing.ExporterJaeger && !c.config.IsSet(optionNameTracingEndpoint) {
+				tracingEndpoint = ""
 			}
 
 			a, err := node.NewAnt(c.config.GetString(optionNameP2PAddr), signerConfig.publicKey, signerConfig.signer, networkID, logger, signerConfig.libp2pPrivateKey, signerConfig.pssPrivateKey, &node.Options{
@@ -147,14 +199,23 @@ func (c *command) initStartCmd() (err error) {
 				NATAddr:                  c.config.GetString(optionNameNATAddr),
 				EnableWS:                 c.config.GetBool(optionNameP2PWSEnable),
 				EnableQUIC:               c.config.GetBool(optionNameP2PQUICEnable),
+				P2PProxy:                 c.config.GetString(optionNameP2PProxy),
+				P2POnionAddr:             c.config.GetString(optionNameP2POnionAddr),
+				KeepaliveTCP:             c.config.GetDuration(optionNameP2PKeepaliveTCP),
+				KeepaliveWS:              c.config.GetDuration(optionNameP2PKeepaliveWS),
+				KeepaliveQUIC:            c.config.GetDuration(optionNameP2PKeepaliveQUIC),
+				KeepaliveTimeout:         c.config.GetDuration(optionNameP2PKeepaliveTimeout),
+				KeepaliveMaxFailures:     c.config.GetInt(optionNameP2PKeepaliveMaxFailures),
 				WelcomeMessage:           c.config.GetString(optionWelcomeMessage),
 				Bootnodes:                networkConfig.bootNodes,
 				CORSAllowedOrigins:       c.config.GetStringSlice(optionCORSAllowedOrigins),
 				DashboardAuthorization:   c.config.GetString(optionDashboardAuthorization),
 				Standalone:               c.config.GetBool(optionNameStandalone),
 				TracingEnabled:           c.config.GetBool(optionNameTracingEnabled),
-				TracingEndpoint:          c.config.GetString(optionNameTracingEndpoint),
+				TracingEndpoint:          tracingEndpoint,
 				TracingServiceName:       c.config.GetString(optionNameTracingServiceName),
+				TracingExporter:          tracingExporter,
+				TracingInsecure:          c.config.GetBool(optionNameTracingInsecure),
 				Logger:                   logger,
 				GlobalPinningEnabled:     c.config.GetBool(optionNameGlobalPinningEnabled),
 				PaymentThreshold:         c.config.GetString(optionNamePaymentThreshold),
@@ -162,51 +223,174 @@ func (c *command) initStartCmd() (err error) {
 				PaymentEarly:             c.config.GetString(optionNamePaymentEarly),
 				ResolverConnectionCfgs:   resolverCfgs,
 				GatewayMode:              c.config.GetBool(optionNameGatewayMode),
+				GatewayRateLimit:         c.config.GetString(optionNameGatewayRateLimit),
+				GatewayMaxRequestSize:    c.config.GetInt64(optionNameGatewayMaxRequestSize),
+				GatewayAllowedEndpoints:  c.config.GetStringSlice(optionNameGatewayAllowedEndpoints),
+				GatewayDeniedEndpoints:   c.config.GetStringSlice(optionNameGatewayDeniedEndpoints),
 				BootnodeMode:             bootNode,
-				SwapEndpoint:             c.config.GetString(optionNameSwapEndpoint),
-				SwapFactoryAddress:       c.config.GetString(optionNameSwapFactoryAddress),
+				SwapEndpoints:            c.config.GetStringSlice(optionNameSwapEndpoint),
+				SwapLatencySelection:     c.config.GetBool(optionNameSwapLatencySelection),
+				SwapFactoryAddress:       networkConfig.swapFactoryAddress,
 				SwapInitialDeposit:       c.config.GetString(optionNameSwapInitialDeposit),
-				SwapEnable:               c.config.GetBool(optionNameSwapEnable),
+				SwapEnable:               swapEnable,
 				FullNodeMode:             fullNode,
 				Transaction:              c.config.GetString(optionNameTransactionHash),
 				BlockHash:                c.config.GetString(optionNameBlockHash),
-				PostageContractAddress:   c.config.GetString(optionNamePostageContractAddress),
-				PriceOracleAddress:       c.config.GetString(optionNamePriceOracleAddress),
+				PostageContractAddress:   networkConfig.postageStampAddress,
+				PriceOracleAddress:       networkConfig.priceOracleAddress,
 				BlockTime:                networkConfig.blockTime,
 				DeployGasPrice:           c.config.GetString(optionNameSwapDeploymentGasPrice),
 				WarmupTime:               c.config.GetDuration(optionWarmUpTime),
 				ChainID:                  networkConfig.chainID,
-				MineEnabled:              c.config.GetBool(optionNameMine),
+				MineEnabled:              mineEnable,
 				MineTrust:                c.config.GetBool(optionNameMineTrust),
-				MineContractAddress:      c.config.GetString(optionNameMineContractAddress),
+				MineContractAddress:      networkConfig.mineAddress,
 				UniswapEnable:            c.config.GetBool(optionNameUniswapEnable),
 				UniswapEndpoint:          c.config.GetString(optionNameUniswapEndpoint),
 				UniswapValidTime:         c.config.GetDuration(optionNameUniswapValidTime),
+				RetrievalCaching:         c.config.GetBool(optionNameRetrievalCaching),
+				RetrievalHopTimeout:      c.config.GetDuration(optionNameRetrievalHopTimeout),
+				RetrievalRequestTimeout:  c.config.GetDuration(optionNameRetrievalRequestTimeout),
+				RetrievalRetryInterval:   c.config.GetDuration(optionNameRetrievalRetryInterval),
+				UploadDedup:              c.config.GetBool(optionNameUploadDedup),
+				S3Enable:                 c.config.GetBool(optionNameS3Enable),
+				S3PostageBatch:           c.config.GetString(optionNameS3PostageBatch),
+				UploadScanEndpoint:       c.config.GetString(optionNameUploadScanEndpoint),
+				UploadScanTimeout:        c.config.GetDuration(optionNameUploadScanTimeout),
+				ManifestCacheCapacity:    c.config.GetUint64(optionNameManifestCacheCapacity),
+				StampingClients:          c.config.GetString(optionNameStampingClients),
+				StampingBatch:            c.config.GetString(optionNameStampingBatch),
+				Network:                  networkConfig.mode,
+				StaticPostageBatch:       c.config.GetString(optionNameStaticPostageBatch),
+				StaticPostageDepth:       uint8(c.config.GetUint(optionNameStaticPostageDepth)),
+				UploadPolicies:           c.config.GetString(optionNameUploadPolicies),
+				StandbyAddr:              c.config.GetString(optionNameStandbyAddr),
+				StandbyPeer:              c.config.GetString(optionNameStandbyPeer),
+				StandbyToken:             c.config.GetString(optionNameStandbyToken),
+				ReportPeriods:            c.config.GetStringSlice(optionNameReportPeriods),
+				ReportWebhooks:           c.config.GetStringSlice(optionNameReportWebhooks),
+				ReportSMTPAddr:           c.config.GetString(optionNameReportSMTPAddr),
+				ReportSMTPUsername:       c.config.GetString(optionNameReportSMTPUsername),
+				ReportSMTPPassword:       c.config.GetString(optionNameReportSMTPPassword),
+				ReportSMTPFrom:           c.config.GetString(optionNameReportSMTPFrom),
+				ReportSMTPTo:             c.config.GetStringSlice(optionNameReportSMTPTo),
+				CommitmentInterval:       c.config.GetDuration(optionNameCommitmentInterval),
+				CommitmentPostageBatch:   c.config.GetString(optionNameCommitmentPostageBatch),
+				TakedownSigners:          c.config.GetStringSlice(optionNameTakedownSigners),
+				RepairInterval:           c.config.GetDuration(optionNameRepairInterval),
+				RepairBudget:             c.config.GetInt(optionNameRepairBudget),
+				RepairPostageBatch:       c.config.GetString(optionNameRepairPostageBatch),
+				PostageAutoTopUpLimit:    c.config.GetString(optionNamePostageAutoTopUpLimit),
+				PostageExpiryWarning:     c.config.GetDuration(optionNamePostageExpiryWarning),
+				PostageTopUpTTL:          c.config.GetDuration(optionNamePostageTopUpTTL),
+				PostageDiluteUtilization: c.config.GetFloat64(optionNamePostageDiluteUtilization),
+				PssMailbox:               c.config.GetBool(optionNamePssMailbox),
+				PssMailboxCapacity:       c.config.GetInt(optionNamePssMailboxCapacity),
+				PssMailboxMaxTTL:         c.config.GetDuration(optionNamePssMailboxMaxTTL),
+				Mode:                     c.config.GetString(optionNameMode),
+				RemoteAPIURL:             c.config.GetString(optionNameRemoteAPIURL),
+				DBCapacityGB:             c.config.GetUint64(optionNameDBCapacityGB),
+				DBReserveCapacity:        c.config.GetUint64(optionNameDBReserveCapacity),
+				DBPinCapacity:            c.config.GetUint64(optionNameDBPinCapacity),
+				DBEvictionPolicy:         c.config.GetString(optionNameDBEvictionPolicy),
+				S3APIAddr:                c.config.GetString(optionNameS3APIAddr),
+				S3Credentials:            c.config.GetString(optionNameS3Credentials),
+				ReputationBanThreshold:   c.config.GetFloat64(optionNameReputationBanThreshold),
+				ReputationBanDuration:    c.config.GetDuration(optionNameReputationBanDuration),
+				ReputationPreferredPeers: c.config.GetStringSlice(optionNameReputationPreferredPeers),
+				ReputationBlockedCIDRs:   c.config.GetStringSlice(optionNameReputationBlockedCIDRs),
+				SnapshotSources:          c.config.GetStringSlice(optionNameSnapshotSource),
+				SnapshotSigners:          c.config.GetStringSlice(optionNameSnapshotSigners),
+				Reloader:                 reloader,
+				Keystore:                 signerConfig.keystore,
+				KeysBackupEnable:         c.config.GetBool(optionNameDebugAPIKeysBackup),
+				Clef:                     signerConfig.clef,
 			})
 			if err != nil {
 				return err
 			}
 
+			reloader.Handle(func() error {
+				return setVerbosity(logger, v, strings.ToLower(c.config.GetString(optionNameVerbosity)))
+			}, optionNameVerbosity)
+			reloader.Handle(func() error {
+				return setLogLevels(logger, v, c.config.GetString(optionNameLogLevel))
+			}, optionNameLogLevel)
+			reloader.Handle(func() error {
+				return a.SetPaymentThresholds(
+					c.config.GetString(optionNamePaymentThreshold),
+					c.config.GetString(optionNamePaymentTolerance),
+					c.config.GetString(optionNamePaymentEarly),
+				)
+			}, optionNamePaymentThreshold, optionNamePaymentTolerance, optionNamePaymentEarly)
+			reloader.Handle(func() error {
+				a.SetCORSAllowedOrigins(c.config.GetStringSlice(optionCORSAllowedOrigins))
+				return nil
+			}, optionCORSAllowedOrigins)
+			reloader.Handle(func() error {
+				cfgs, err := multiresolver.ParseConnectionStrings(c.config.GetStringSlice(optionNameResolverEndpoints))
+				if err != nil {
+					return err
+				}
+				return a.SetResolverConnectionConfigs(cfgs)
+			}, optionNameResolverEndpoints)
+
+			reloadDone := make(chan struct{})
+			defer close(reloadDone)
+			go func() {
+				for {
+					select {
+					case sig := <-reloadChannel:
+						logger.Debugf("received signal: %v", sig)
+						if _, err := reloader.Reload(); err != nil {
+							logger.Debugf("reload: %v", err)
+							logger.Error("unable to reload configuration")
+						}
+					case <-reloadDone:
+						return
+					}
+				}
+			}()
+
 			// Wait for termination or interrupt signals.
 			// We want to clean up things at the end.
 			interruptChannel := make(chan os.Signal, 1)
-			signal.Notify(interruptChannel, syscall.SIGINT, syscall.SIGTERM)
+			signal.Notify(interruptChannel, append([]os.Signal{syscall.SIGINT, syscall.SIGTERM}, maintenanceSignals...)...)
+
+			var (
+				state  shutdownState
+				forced bool
+			)
 
 			p := &program{
 				start: func() {
 					// Block main goroutine until it is interrupted
-					sig := <-interruptChannel
+					select {
+					case sig := <-interruptChannel:
+						logger.Debugf("received signal: %v", sig)
+						if isMaintenanceSignal(sig) {
+							state.set(node.ShutdownReasonMaintenance, nil)
+						} else {
+							state.set(node.ShutdownReasonSignal, nil)
+						}
+					case err := <-a.Fatal():
+						logger.Debugf("fatal: %v", err)
+						state.set(node.ShutdownReasonFatal, err)
+					}
 
-					logger.Debugf("received signal: %v", sig)
 					logger.Info("shutting down")
 				},
 				stop: func() {
+					// the service manager stops the node without
+					// a signal being received
+					state.set(node.ShutdownReasonMaintenance, nil)
+
 					// Shutdown
 					done := make(chan struct{})
 					go func() {
 						defer close(done)
 
-						ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+						ctx, cancel := context.WithTimeout(context.Background(), c.config.GetDuration(optionNameShutdownTimeout))
 						defer cancel()
 
 						if err := a.Shutdown(ctx); err != nil {
@@ -219,21 +403,38 @@ func (c *command) initStartCmd() (err error) {
 					select {
 					case sig := <-interruptChannel:
 						logger.Debugf("received signal: %v", sig)
+						forced = true
 					case <-done:
 					}
+
+					if report := a.ShutdownReport(); report != nil {
+						reason, err := state.get()
+						report.Reason = reason
+						if err != nil {
+							report.Error = err.Error()
+						}
+						reportShutdown(logger, c.config.GetString(optionNameDataDir), report)
+					}
 				},
 			}
 
 			if isWindowsService {
-				s, err := service.New(p, &service.Config{
-					Name:        serviceName,
-					DisplayName: "Ant",
-					Description: "Ant, Sana client.",
-				})
+				s, err := service.New(p, serviceConfig(nil, 0))
 				if err != nil {
 					return err
 				}
 
+				start := p.start
+				p.start = func() {
+					start()
+					// the service control manager applies the recovery
+					// actions only if the service did not stop by itself
+					if reason, _ := state.get(); reason == node.ShutdownReasonFatal {
+						p.stop()
+						os.Exit(ExitCodeFatal)
+					}
+				}
+
 				if err = s.Run(); err != nil {
 					return err
 				}
@@ -243,6 +444,10 @@ func (c *command) initStartCmd() (err error) {
 				p.stop()
 			}
 
+			reason, err := state.get()
+			if code := shutdownExitCode(reason, forced); code != ExitCodeOK {
+				return &ExitError{Code: code, Err: err}
+			}
 			return nil
 		},
 		PreRunE: func(cmd *cobra.Command, args []string) error {
@@ -276,6 +481,10 @@ type signerConfig struct {
 	publicKey        *ecdsa.PublicKey
 	libp2pPrivateKey *ecdsa.PrivateKey
 	pssPrivateKey    *ecdsa.PrivateKey
+	keystore         keystore.Service
+	password         string
+	// clef is the configuration of the clef signer, if it is enabled
+	clef *backup.Clef
 }
 
 func waitForClef(logger logging.Logger, maxRetries uint64, endpoint string) (externalSigner *external.ExternalSigner, err error) {
@@ -294,46 +503,56 @@ func waitForClef(logger logging.Logger, maxRetries uint64, endpoint string) (ext
 	}
 }
 
-func (c *command) configureSigner(cmd *cobra.Command, logger logging.Logger) (config *signerConfig, err error) {
-	var keystore keystore.Service
-	if c.config.GetString(optionNameDataDir) == "" {
-		keystore = memkeystore.New()
-		logger.Warning("data directory not provided, keys are not persisted")
-	} else {
-		keystore = filekeystore.New(filepath.Join(c.config.GetString(optionNameDataDir), "keys"))
-	}
+const (
+	keystoreFile  = "file"
+	keystoreVault = "vault"
+)
 
-	var signer crypto.Signer
-	var password string
-	var publicKey *ecdsa.PublicKey
-	if p := c.config.GetString(optionNamePassword); p != "" {
-		password = p
-	} else if pf := c.config.GetString(optionNamePasswordFile); pf != "" {
-		b, err := ioutil.ReadFile(pf)
-		if err != nil {
-			return nil, err
+// newKeystore returns the keystore of the private keys selected by the
+// keystore option.
+func (c *command) newKeystore(logger logging.Logger) (keystore.Service, error) {
+	switch k := c.config.GetString(optionNameKeystore); k {
+	case keystoreFile, "":
+		if c.config.GetString(optionNameDataDir) == "" {
+			logger.Warning("data directory not provided, keys are not persisted")
+			return memkeystore.New(), nil
 		}
-		password = string(bytes.Trim(b, "\n"))
-	} else {
-		// if libp2p key exists we can assume all required keys exist
-		// so prompt for a password to unlock them
-		// otherwise prompt for new password with confirmation to create them
-		exists, err := keystore.Exists("libp2p")
-		if err != nil {
-			return nil, err
+		return filekeystore.New(filepath.Join(c.config.GetString(optionNameDataDir), "keys")), nil
+	case keystoreVault:
+		token := c.config.GetString(optionNameKeystoreToken)
+		if token == "" {
+			token = os.Getenv("VAULT_TOKEN")
 		}
-		if exists {
-			password, err = terminalPromptPassword(cmd, c.passwordReader, "Password")
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			password, err = terminalPromptCreatePassword(cmd, c.passwordReader)
-			if err != nil {
-				return nil, err
-			}
+		ks, err := vaultkeystore.New(vaultkeystore.Options{
+			Endpoint: c.config.GetString(optionNameKeystoreEndpoint),
+			Token:    token,
+			Mount:    c.config.GetString(optionNameKeystoreMount),
+			Prefix:   c.config.GetString(optionNameKeystorePrefix),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("vault keystore: %w", err)
 		}
+		logger.Infof("using vault keystore %s", c.config.GetString(optionNameKeystoreEndpoint))
+		return ks, nil
+	default:
+		return nil, fmt.Errorf("unknown keystore %q", k)
 	}
+}
+
+func (c *command) configureSigner(cmd *cobra.Command, logger logging.Logger) (config *signerConfig, err error) {
+	keystore, err := c.newKeystore(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := c.keystorePassword(cmd, keystore)
+	if err != nil {
+		return nil, err
+	}
+
+	var signer crypto.Signer
+	var publicKey *ecdsa.PublicKey
+	var clefConfig *backup.Clef
 
 	if c.config.GetBool(optionNameClefSignerEnable) {
 		endpoint := c.config.GetString(optionNameClefSignerEndpoint)
@@ -354,6 +573,25 @@ func (c *command) configureSigner(cmd *cobra.Command, logger logging.Logger) (co
 			return nil, err
 		}
 
+		// the signer starts with the established connection and dials
+		// clef again after it is restarted
+		connected := true
+		dial := func() (clef.ExternalSignerInterface, clef.Client, error) {
+			if connected {
+				connected = false
+				return externalSigner, clefRPC, nil
+			}
+			externalSigner, err := external.NewExternalSigner(endpoint)
+			if err != nil {
+				return nil, nil, err
+			}
+			clefRPC, err := rpc.Dial(endpoint)
+			if err != nil {
+				return nil, nil, err
+			}
+			return externalSigner, clefRPC, nil
+		}
+
 		wantedAddress := c.config.GetString(optionNameClefSignerEthereumAddress)
 		var overlayEthAddress *common.Address = nil
 		// if wantedAddress was specified use that, otherwise clef account 0 will be selected.
@@ -362,7 +600,9 @@ func (c *command) configureSigner(cmd *cobra.Command, logger logging.Logger) (co
 			overlayEthAddress = &ethAddress
 		}
 
-		signer, err = clef.NewSigner(externalSigner, clefRPC, crypto.Recover, overlayEthAddress)
+		signer, err = clef.NewResilientSigner(dial, crypto.Recover, overlayEthAddress, logger, clef.ResilientOptions{
+			RequestTimeout: c.config.GetDuration(optionNameClefSignerTimeout),
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -371,6 +611,15 @@ func (c *command) configureSigner(cmd *cobra.Command, logger logging.Logger) (co
 		if err != nil {
 			return nil, err
 		}
+
+		ethAddress, err := signer.EthereumAddress()
+		if err != nil {
+			return nil, err
+		}
+		clefConfig = &backup.Clef{
+			Endpoint:        endpoint,
+			EthereumAddress: ethAddress.Hex(),
+		}
 	} else {
 		logger.Warning("clef is not enabled; portability and security of your keys is sub optimal")
 		swarmPrivateKey, _, err := keystore.Key("sana", password)
@@ -417,38 +666,129 @@ func (c *command) configureSigner(cmd *cobra.Command, logger logging.Logger) (co
 		publicKey:        publicKey,
 		libp2pPrivateKey: libp2pPrivateKey,
 		pssPrivateKey:    pssPrivateKey,
+		keystore:         keystore,
+		password:         password,
+		clef:             clefConfig,
 	}, nil
 }
 
+// keystorePassword returns the password of the keys from the password options
+// or prompts for it.
+func (c *command) keystorePassword(cmd *cobra.Command, keystore keystore.Service) (password string, err error) {
+	if p := c.config.GetString(optionNamePassword); p != "" {
+		return p, nil
+	}
+	if pf := c.config.GetString(optionNamePasswordFile); pf != "" {
+		b, err := ioutil.ReadFile(pf)
+		if err != nil {
+			return "", err
+		}
+		return string(bytes.Trim(b, "\n")), nil
+	}
+	// if libp2p key exists we can assume all required keys exist
+	// so prompt for a password to unlock them
+	// otherwise prompt for new password with confirmation to create them
+	exists, err := keystore.Exists("libp2p")
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return terminalPromptPassword(cmd, c.passwordReader, "Password")
+	}
+	return terminalPromptCreatePassword(cmd, c.passwordReader)
+}
+
 type networkConfig struct {
-	bootNodes []string
-	blockTime uint64
-	chainID   int64
+	// mode is the network option passed to the node, set only for the
+	// networks which are not described by a profile
+	mode                string
+	networkID           uint64
+	bootNodes           []string
+	blockTime           uint64
+	chainID             int64
+	postageStampAddress string
+	priceOracleAddress  string
+	swapFactoryAddress  string
+	mineAddress         string
 }
 
-func getConfigByNetworkID(networkID uint64, defaultBlockTime uint64) *networkConfig {
-	var config = networkConfig{
-		blockTime: uint64(time.Duration(defaultBlockTime) * time.Second),
+// getNetworkConfig returns the configuration of the network profile selected
+// by the network option, or of the profile with the configured network id if
+// no profile is selected. The explicitly set options take precedence over the
+// profile.
+func (c *command) getNetworkConfig(network string) (*networkConfig, error) {
+	profiles := config.DefaultNetworkProfiles()
+	for _, path := range c.config.GetStringSlice(optionNameNetworkProfiles) {
+		if err := profiles.Load(path); err != nil {
+			return nil, fmt.Errorf("network profiles: %w", err)
+		}
+	}
+
+	cfg := &networkConfig{
+		networkID: c.config.GetUint64(optionNameNetworkID),
+		blockTime: c.config.GetUint64(optionNameBlockTime),
+		chainID:   -1, // will use the value provided by the chain
+	}
+
+	var (
+		profile config.NetworkProfile
+		found   bool
+	)
+	switch network {
+	case "", node.NetworkDevStatic:
+		cfg.mode = network
+		id := cfg.networkID
+		if id == 1 {
+			// the former id of the main network
+			id = 100
+		}
+		profile, found = profiles.ByNetworkID(id)
+	default:
+		profile, found = profiles[network]
+		if !found {
+			return nil, fmt.Errorf("unknown network %q, use one of %s or %s", network, strings.Join(profiles.Names(), ", "), node.NetworkDevStatic)
+		}
+		if !c.config.IsSet(optionNameNetworkID) {
+			cfg.networkID = profile.NetworkID
+		}
+	}
+
+	if found {
+		cfg.bootNodes = profile.Bootnodes
+		if profile.ChainID != 0 {
+			cfg.chainID = profile.ChainID
+		}
+		if profile.BlockTime != 0 && !c.config.IsSet(optionNameBlockTime) {
+			cfg.blockTime = profile.BlockTime
+		}
 	}
-	switch networkID {
-	case 1:
-		config.bootNodes = []string{"/dnsaddr/mainnet.ethsana.org"}
-		config.chainID = 100
-
-	case 5: //staging
-		config.bootNodes = []string{"/dnsaddr/testnet.ethsana.org"}
-		config.chainID = 5
-
-	case 100:
-		config.bootNodes = []string{"/dnsaddr/mainnet.ethsana.org"}
-		config.chainID = 100
-
-	case 31337:
-		config.bootNodes = []string{}
-		config.chainID = 31337
-	default: //will use the value provided by the chain
-		config.chainID = -1
+	if c.config.IsSet(optionNameBootnodes) {
+		cfg.bootNodes = c.config.GetStringSlice(optionNameBootnodes)
 	}
 
-	return &config
+	// the contract addresses of the profile are used only if the options
+	// are not set
+	address := func(option, profileAddress string) string {
+		if !c.config.IsSet(option) && profileAddress != "" {
+			return profileAddress
+		}
+		return c.config.GetString(option)
+	}
+	cfg.postageStampAddress = address(optionNamePostageContractAddress, profile.PostageStampAddress)
+	cfg.priceOracleAddress = address(optionNamePriceOracleAddress, profile.PriceOracleAddress)
+	cfg.swapFactoryAddress = address(optionNameSwapFactoryAddress, profile.SwapFactoryAddress)
+	cfg.mineAddress = address(optionNameMineContractAddress, profile.MineAddress)
+
+	return cfg, nil
+}
+
+// loadSettings reads the configuration file again, if there is one, and
+// returns all settings.
+func (c *command) loadSettings() (map[string]interface{}, error) {
+	if c.config.ConfigFileUsed() != "" {
+		if err := c.config.ReadInConfig(); err != nil {
+			return nil, err
+		}
+	}
+	return c.config.AllSettings(), nil
 }
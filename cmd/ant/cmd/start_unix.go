@@ -8,6 +8,8 @@ package cmd
 
 import (
 	"errors"
+	"os"
+	"syscall"
 
 	"github.com/ethsana/sana/pkg/logging"
 )
@@ -19,3 +21,13 @@ func isWindowsService() (bool, error) {
 func createWindowsEventLogger(svcName string, logger logging.Logger) (logging.Logger, error) {
 	return nil, errors.New("cannot create Windows event logger")
 }
+
+// maintenanceSignals are signals which shut down the node for maintenance.
+var maintenanceSignals = []os.Signal{syscall.SIGUSR1}
+
+func isMaintenanceSignal(sig os.Signal) bool {
+	return sig == syscall.SIGUSR1
+}
+
+// reloadSignals are signals which reload the configuration.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
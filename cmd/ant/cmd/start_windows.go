@@ -9,6 +9,7 @@ package cmd
 import (
 	"fmt"
 	"io"
+	"os"
 
 	"golang.org/x/sys/windows/svc"
 	"golang.org/x/sys/windows/svc/debug"
@@ -96,3 +97,15 @@ func (l *windowsEventLogger) WriterLevel(level logrus.Level) *io.PipeWriter {
 func (l *windowsEventLogger) NewEntry() *logrus.Entry {
 	return l.logger.NewEntry()
 }
+
+// maintenanceSignals are signals which shut down the node for maintenance.
+// On Windows, maintenance shutdowns are issued by the service manager.
+var maintenanceSignals []os.Signal
+
+func isMaintenanceSignal(sig os.Signal) bool {
+	return false
+}
+
+// reloadSignals are signals which reload the configuration.
+// On Windows, the configuration is reloaded with the debug api.
+var reloadSignals []os.Signal
@@ -0,0 +1,119 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/ethsana/sana"
+	"github.com/ethsana/sana/pkg/supportbundle"
+	"github.com/spf13/cobra"
+)
+
+const (
+	optionNameOutput      = "output"
+	optionNameLogFile     = "log-file"
+	optionNameLogLines    = "log-lines"
+	optionNameDebugAPIURL = "debug-api-url"
+)
+
+func (c *command) initSupportBundleCmd() {
+	cmd := &cobra.Command{
+		Use:   "support-bundle",
+		Short: "Collect diagnostic information into an archive for bug reports",
+		Long: `Collect diagnostic information into an archive for bug reports.
+
+The archive contains the version information, the effective configuration,
+the last lines of the node log and snapshots of the metrics, the peer topology
+and the chain state from the debug API of the running node. Passwords,
+authorization values and credentials in endpoint URLs are redacted in all
+collected files. Parts which can not be collected are listed in errors.txt.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if len(args) > 0 {
+				return cmd.Help()
+			}
+
+			output, err := cmd.Flags().GetString(optionNameOutput)
+			if err != nil {
+				return fmt.Errorf("get output: %v", err)
+			}
+			logFile, err := cmd.Flags().GetString(optionNameLogFile)
+			if err != nil {
+				return fmt.Errorf("get log-file: %v", err)
+			}
+			logLines, err := cmd.Flags().GetInt(optionNameLogLines)
+			if err != nil {
+				return fmt.Errorf("get log-lines: %v", err)
+			}
+			debugAPIURL, err := cmd.Flags().GetString(optionNameDebugAPIURL)
+			if err != nil {
+				return fmt.Errorf("get debug-api-url: %v", err)
+			}
+			if debugAPIURL == "" && c.config.GetBool(optionNameDebugAPIEnable) {
+				debugAPIURL = localURL(c.config.GetString(optionNameDebugAPIAddr))
+			}
+
+			now := time.Now()
+			if output == "" {
+				output = supportbundle.Name(now) + ".tar.gz"
+			}
+			f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+			if err != nil {
+				return err
+			}
+
+			files, err := supportbundle.Write(cmd.Context(), f, supportbundle.Options{
+				Version:     sana.Version,
+				Config:      c.config.AllSettings(),
+				DebugAPIURL: debugAPIURL,
+				LogFile:     logFile,
+				LogLines:    logLines,
+				Now: func() time.Time {
+					return now
+				},
+			})
+			if err != nil {
+				_ = f.Close()
+				_ = os.Remove(output)
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+
+			for _, file := range files {
+				cmd.Printf("%s (%d bytes)\n", file.Name, file.Size)
+			}
+			cmd.Printf("support bundle written to %s\n", output)
+			return nil
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.config.BindPFlags(cmd.Flags())
+		},
+	}
+
+	c.setAllFlags(cmd)
+	cmd.Flags().String(optionNameOutput, "", "path of the created archive (default sana-support-<time>.tar.gz)")
+	cmd.Flags().String(optionNameLogFile, "", "path of the node log file")
+	cmd.Flags().Int(optionNameLogLines, 1000, "number of the last log lines to collect")
+	cmd.Flags().String(optionNameDebugAPIURL, "", "debug HTTP API URL of the node (default derived from debug-api-addr)")
+	cmd.SetOut(c.root.OutOrStdout())
+	c.root.AddCommand(cmd)
+}
+
+// localURL returns the HTTP URL of the listen address on the local host.
+func localURL(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
@@ -0,0 +1,214 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethsana/sana/pkg/apiclient"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/dirsync"
+	"github.com/ethsana/sana/pkg/feeds"
+	filekeystore "github.com/ethsana/sana/pkg/keystore/file"
+	"github.com/spf13/cobra"
+)
+
+const (
+	optionNameFeed          = "feed"
+	optionNamePrune         = "prune"
+	optionNameIndexDocument = "index-document"
+	optionNameErrorDocument = "error-document"
+)
+
+func (c *command) initSyncCmd() {
+	cmd := &cobra.Command{
+		Use:   "sync <dir>",
+		Short: "Publish the changes of a directory to a feed",
+		Long: `Publish the changes of a directory to a feed.
+
+Local files are compared with the manifest published by the latest feed
+update and only changed files are uploaded through the node HTTP API. The
+updated manifest is published as a new feed update signed with the sana key
+from the data directory. The printed feed manifest reference always resolves
+to the latest version of the directory.`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+			v, err := cmd.Flags().GetString(optionNameVerbosity)
+			if err != nil {
+				return fmt.Errorf("get verbosity: %v", err)
+			}
+			logger, err := newLogger(cmd, strings.ToLower(v))
+			if err != nil {
+				return fmt.Errorf("new logger: %v", err)
+			}
+
+			topicName, err := cmd.Flags().GetString(optionNameFeed)
+			if err != nil {
+				return fmt.Errorf("get feed: %v", err)
+			}
+			if topicName == "" {
+				return errors.New("no feed topic provided")
+			}
+			topic, err := feedTopic(topicName)
+			if err != nil {
+				return err
+			}
+
+			apiURL, err := cmd.Flags().GetString(optionNameAPIURL)
+			if err != nil {
+				return fmt.Errorf("get api-url: %v", err)
+			}
+			batchHex, err := cmd.Flags().GetString(optionNamePostageBatch)
+			if err != nil {
+				return fmt.Errorf("get postage-batch: %v", err)
+			}
+			batch, err := parsePostageBatch(batchHex)
+			if err != nil {
+				return err
+			}
+			if batch == nil {
+				return errors.New("no postage batch provided")
+			}
+
+			var o dirsync.Options
+			if o.Prune, err = cmd.Flags().GetBool(optionNamePrune); err != nil {
+				return fmt.Errorf("get prune: %v", err)
+			}
+			if o.IndexDocument, err = cmd.Flags().GetString(optionNameIndexDocument); err != nil {
+				return fmt.Errorf("get index-document: %v", err)
+			}
+			if o.ErrorDocument, err = cmd.Flags().GetString(optionNameErrorDocument); err != nil {
+				return fmt.Errorf("get error-document: %v", err)
+			}
+
+			signer, err := c.syncSigner(cmd)
+			if err != nil {
+				return err
+			}
+			owner, err := signer.EthereumAddress()
+			if err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			client := apiclient.New(apiURL, apiclient.Options{PostageBatch: batch})
+			feed := feeds.New(topic, owner)
+
+			previous, next, err := dirsync.Latest(ctx, client, feed)
+			if err != nil {
+				return err
+			}
+			if previous.IsZero() {
+				logger.Infof("feed has no updates, creating a new manifest")
+			} else {
+				logger.Infof("comparing with manifest %s", previous)
+			}
+
+			result, err := dirsync.Sync(ctx, client, args[0], previous, o)
+			if err != nil {
+				return err
+			}
+			for _, p := range result.Uploaded {
+				logger.Debugf("uploaded %s", p)
+			}
+			for _, p := range result.Removed {
+				logger.Debugf("removed %s", p)
+			}
+			logger.Infof("uploaded %d, removed %d, unchanged %d files", len(result.Uploaded), len(result.Removed), result.Unchanged)
+
+			if result.Reference.Equal(previous) {
+				logger.Info("directory is up to date")
+			} else {
+				if err := dirsync.Publish(ctx, client, signer, topic, next, result.Reference); err != nil {
+					return err
+				}
+				logger.Infof("published manifest %s as feed update %s", result.Reference, next)
+			}
+
+			feedManifest, err := client.CreateFeedManifest(ctx, owner, topic)
+			if err != nil {
+				return err
+			}
+			cmd.Println(feedManifest.String())
+			return nil
+		},
+	}
+	cmd.Flags().String(optionNameFeed, "", "feed topic, hashed unless it is a 32 byte hex value")
+	cmd.Flags().String(optionNameAPIURL, "http://localhost:1633", "HTTP API URL of the node")
+	cmd.Flags().String(optionNamePostageBatch, "", "postage batch ID used for uploads")
+	cmd.Flags().Bool(optionNamePrune, false, "remove manifest entries of deleted files")
+	cmd.Flags().String(optionNameIndexDocument, "", "website index document")
+	cmd.Flags().String(optionNameErrorDocument, "", "website error document")
+	cmd.Flags().String(optionNameDataDir, filepath.Join(c.homeDir, ".sana"), "data directory")
+	cmd.Flags().String(optionNamePassword, "", "password for decrypting keys")
+	cmd.Flags().String(optionNamePasswordFile, "", "path to a file that contains password for decrypting keys")
+	cmd.Flags().String(optionNameVerbosity, "info", "verbosity level")
+	cmd.SetOut(c.root.OutOrStdout())
+	c.root.AddCommand(cmd)
+}
+
+// syncSigner unlocks the sana key which signs the feed updates.
+func (c *command) syncSigner(cmd *cobra.Command) (crypto.Signer, error) {
+	dataDir, err := cmd.Flags().GetString(optionNameDataDir)
+	if err != nil {
+		return nil, fmt.Errorf("get data-dir: %v", err)
+	}
+	if dataDir == "" {
+		return nil, errors.New("no data-dir provided")
+	}
+	keystore := filekeystore.New(filepath.Join(dataDir, "keys"))
+
+	password, err := cmd.Flags().GetString(optionNamePassword)
+	if err != nil {
+		return nil, fmt.Errorf("get password: %v", err)
+	}
+	passwordFile, err := cmd.Flags().GetString(optionNamePasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("get password-file: %v", err)
+	}
+	if password == "" && passwordFile != "" {
+		b, err := ioutil.ReadFile(passwordFile)
+		if err != nil {
+			return nil, err
+		}
+		password = string(bytes.Trim(b, "\n"))
+	}
+	if password == "" {
+		exists, err := keystore.Exists("sana")
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("no sana key in %s", dataDir)
+		}
+		password, err = terminalPromptPassword(cmd, c.passwordReader, "Password")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pk, _, err := keystore.Key("sana", password)
+	if err != nil {
+		return nil, fmt.Errorf("sana key: %w", err)
+	}
+	return crypto.NewDefaultSigner(pk), nil
+}
+
+// feedTopic returns the topic for the name, which is used as it is when it is
+// a 32 byte hex value.
+func feedTopic(name string) ([]byte, error) {
+	if b, err := hex.DecodeString(name); err == nil && len(b) == 32 {
+		return b, nil
+	}
+	return crypto.LegacyKeccak256([]byte(name))
+}
@@ -5,6 +5,9 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+
 	tee "github.com/ethsana/sana-tee"
 	"github.com/spf13/cobra"
 )
@@ -20,3 +23,24 @@ func (c *command) initTeeCmd() {
 	v.SetOut(c.root.OutOrStdout())
 	c.root.AddCommand(v)
 }
+
+// checkTEE returns an error if the node does not run in a TEE with a device
+// which is verified by its vendor, as the miners verify the devices of the
+// nodes which request a trust signature.
+func checkTEE() error {
+	if !tee.Ok() {
+		return errors.New("the operating environment of TEE is not prepared")
+	}
+	device, err := tee.DeviceID()
+	if err != nil {
+		return fmt.Errorf("tee device: %w", err)
+	}
+	ok, err := device.Verify()
+	if err != nil {
+		return fmt.Errorf("verify tee device: %w", err)
+	}
+	if !ok {
+		return errors.New("tee device not verified by its vendor")
+	}
+	return nil
+}
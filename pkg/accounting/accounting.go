@@ -13,11 +13,13 @@ import (
 	"math/big"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethsana/sana/pkg/logging"
 	"github.com/ethsana/sana/pkg/p2p"
 	"github.com/ethsana/sana/pkg/pricing"
+	"github.com/ethsana/sana/pkg/reputation"
 	"github.com/ethsana/sana/pkg/storage"
 	"github.com/ethsana/sana/pkg/swarm"
 )
@@ -97,11 +99,17 @@ type accountingPeer struct {
 
 // Accounting is the main implementation of the accounting interface.
 type Accounting struct {
+	// number of monetary settlements in progress, first for 64-bit alignment
+	pendingPayments int64
+
 	// Mutex for accessing the accountingPeers map.
 	accountingPeersMu sync.Mutex
 	accountingPeers   map[string]*accountingPeer
 	logger            logging.Logger
 	store             storage.StateStorer
+	// Mutex for accessing the payment thresholds, which are replaced
+	// and not modified when they change.
+	thresholdsMu sync.RWMutex
 	// The payment threshold in BZZ we communicate to our peers.
 	paymentThreshold *big.Int
 	// The amount in BZZ we let peers exceed the payment threshold before we
@@ -124,6 +132,7 @@ type Accounting struct {
 	wg             sync.WaitGroup
 	p2p            p2p.Service
 	timeNow        func() time.Time
+	reputation     reputation.Recorder
 }
 
 var (
@@ -206,8 +215,9 @@ func (a *Accounting) Reserve(ctx context.Context, peer swarm.Address, price uint
 	bigPrice := new(big.Int).SetUint64(price)
 
 	threshold := new(big.Int).Set(accountingPeer.paymentThreshold)
-	if threshold.Cmp(a.earlyPayment) > 0 {
-		threshold.Sub(threshold, a.earlyPayment)
+	earlyPayment := a.getEarlyPayment()
+	if threshold.Cmp(earlyPayment) > 0 {
+		threshold.Sub(threshold, earlyPayment)
 	} else {
 		threshold.SetInt64(0)
 	}
@@ -404,6 +414,7 @@ func (a *Accounting) settle(peer swarm.Address, balance *accountingPeer) error {
 				// add settled amount to shadow reserve before sending it
 				balance.shadowReservedBalance.Add(balance.shadowReservedBalance, paymentAmount)
 				a.wg.Add(1)
+				atomic.AddInt64(&a.pendingPayments, 1)
 				go a.payFunction(context.Background(), peer, paymentAmount)
 			}
 		}
@@ -509,7 +520,7 @@ func (a *Accounting) getAccountingPeer(peer swarm.Address) *accountingPeer {
 			shadowReservedBalance: big.NewInt(0),
 			ghostBalance:          big.NewInt(0),
 			// initially assume the peer has the same threshold as us
-			paymentThreshold: new(big.Int).Set(a.paymentThreshold),
+			paymentThreshold: new(big.Int).Set(a.getPaymentThreshold()),
 			connected:        false,
 		}
 		a.accountingPeers[peer.String()] = peerData
@@ -727,6 +738,7 @@ func (a *Accounting) shadowBalance(peer swarm.Address) (shadowBalance *big.Int,
 // NotifyPaymentSent is triggered by async monetary settlement to update our balance and remove it's price from the shadow reserve
 func (a *Accounting) NotifyPaymentSent(peer swarm.Address, amount *big.Int, receivedError error) {
 	defer a.wg.Done()
+	defer atomic.AddInt64(&a.pendingPayments, -1)
 	accountingPeer := a.getAccountingPeer(peer)
 
 	accountingPeer.lock.Lock()
@@ -995,9 +1007,12 @@ func (d *debitAction) Apply() error {
 	a.metrics.TotalDebitedAmount.Add(tot)
 	a.metrics.DebitEventsCount.Inc()
 
-	if nextBalance.Cmp(a.disconnectLimit) >= 0 {
+	if nextBalance.Cmp(a.getDisconnectLimit()) >= 0 {
 		// peer too much in debt
 		a.metrics.AccountingDisconnectsOverdrawCount.Inc()
+		if a.reputation != nil {
+			a.reputation.RecordFailure(d.peer, reputation.FailureSettlement)
+		}
 
 		disconnectFor, err := a.blocklistUntil(d.peer, 1)
 		if err != nil {
@@ -1018,7 +1033,7 @@ func (d *debitAction) Cleanup() {
 		a := d.accounting
 		d.accountingPeer.shadowReservedBalance = new(big.Int).Sub(d.accountingPeer.shadowReservedBalance, d.price)
 		d.accountingPeer.ghostBalance = new(big.Int).Add(d.accountingPeer.ghostBalance, d.price)
-		if d.accountingPeer.ghostBalance.Cmp(a.disconnectLimit) > 0 {
+		if d.accountingPeer.ghostBalance.Cmp(a.getDisconnectLimit()) > 0 {
 			a.metrics.AccountingDisconnectsGhostOverdrawCount.Inc()
 			_ = a.blocklist(d.peer, 1)
 		}
@@ -1036,7 +1051,7 @@ func (a *Accounting) blocklistUntil(peer swarm.Address, multiplier int64) (int64
 		debt.Set(a.refreshRate)
 	}
 
-	additionalDebt := new(big.Int).Add(debt, a.paymentThreshold)
+	additionalDebt := new(big.Int).Add(debt, a.getPaymentThreshold())
 
 	multiplyDebt := new(big.Int).Mul(additionalDebt, big.NewInt(multiplier))
 
@@ -1048,6 +1063,9 @@ func (a *Accounting) blocklistUntil(peer swarm.Address, multiplier int64) (int64
 }
 
 func (a *Accounting) blocklist(peer swarm.Address, multiplier int64) error {
+	if a.reputation != nil {
+		a.reputation.RecordFailure(peer, reputation.FailureSettlement)
+	}
 
 	disconnectFor, err := a.blocklistUntil(peer, multiplier)
 	if err != nil {
@@ -1144,6 +1162,36 @@ func (a *Accounting) Disconnect(peer swarm.Address) {
 	}
 }
 
+// SetPaymentThresholds changes the payment threshold, the tolerance and the
+// early payment. The new threshold is assumed for the peers which did not
+// announce theirs yet.
+func (a *Accounting) SetPaymentThresholds(paymentThreshold, paymentTolerance, earlyPayment *big.Int) {
+	a.thresholdsMu.Lock()
+	defer a.thresholdsMu.Unlock()
+	a.paymentThreshold = new(big.Int).Set(paymentThreshold)
+	a.paymentTolerance = new(big.Int).Set(paymentTolerance)
+	a.earlyPayment = new(big.Int).Set(earlyPayment)
+	a.disconnectLimit = new(big.Int).Add(paymentThreshold, paymentTolerance)
+}
+
+func (a *Accounting) getPaymentThreshold() *big.Int {
+	a.thresholdsMu.RLock()
+	defer a.thresholdsMu.RUnlock()
+	return a.paymentThreshold
+}
+
+func (a *Accounting) getEarlyPayment() *big.Int {
+	a.thresholdsMu.RLock()
+	defer a.thresholdsMu.RUnlock()
+	return a.earlyPayment
+}
+
+func (a *Accounting) getDisconnectLimit() *big.Int {
+	a.thresholdsMu.RLock()
+	defer a.thresholdsMu.RUnlock()
+	return a.disconnectLimit
+}
+
 func (a *Accounting) SetRefreshFunc(f RefreshFunc) {
 	a.refreshFunction = f
 }
@@ -1152,6 +1200,16 @@ func (a *Accounting) SetPayFunc(f PayFunc) {
 	a.payFunction = f
 }
 
+// SetReputation sets the recorder of the settlement misbehavior of the peers.
+func (a *Accounting) SetReputation(r reputation.Recorder) {
+	a.reputation = r
+}
+
+// PendingPayments returns the number of monetary settlements in progress.
+func (a *Accounting) PendingPayments() int {
+	return int(atomic.LoadInt64(&a.pendingPayments))
+}
+
 // Close hangs up running websockets on shutdown.
 func (a *Accounting) Close() error {
 	a.wg.Wait()
@@ -735,7 +735,13 @@ func TestAccountingCallSettlementTooSoon(t *testing.T) {
 
 	acc.Release(peer1Addr, 1)
 
+	if got := acc.PendingPayments(); got != 1 {
+		t.Fatalf("got %d pending payments, want 1", got)
+	}
 	acc.NotifyPaymentSent(peer1Addr, big.NewInt(int64(requestPrice)), errors.New("error"))
+	if got := acc.PendingPayments(); got != 0 {
+		t.Fatalf("got %d pending payments, want 0", got)
+	}
 	acc.SetTime(ts + 1)
 
 	// try another request
@@ -21,21 +21,33 @@ import (
 	"unicode/utf8"
 
 	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/dedup"
 	"github.com/ethsana/sana/pkg/feeds"
+	"github.com/ethsana/sana/pkg/file"
+	"github.com/ethsana/sana/pkg/file/loadsave"
 	"github.com/ethsana/sana/pkg/file/pipeline/builder"
 	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/manifest/nodecache"
 	m "github.com/ethsana/sana/pkg/metrics"
 	"github.com/ethsana/sana/pkg/pinning"
 	"github.com/ethsana/sana/pkg/postage"
 	"github.com/ethsana/sana/pkg/postage/postagecontract"
 	"github.com/ethsana/sana/pkg/pss"
+	"github.com/ethsana/sana/pkg/pss/mailbox"
+	"github.com/ethsana/sana/pkg/repair"
 	"github.com/ethsana/sana/pkg/resolver"
+	"github.com/ethsana/sana/pkg/s3"
+	"github.com/ethsana/sana/pkg/stamping"
 	"github.com/ethsana/sana/pkg/steward"
 	"github.com/ethsana/sana/pkg/storage"
 	"github.com/ethsana/sana/pkg/swarm"
 	"github.com/ethsana/sana/pkg/tags"
+	"github.com/ethsana/sana/pkg/takedown"
 	"github.com/ethsana/sana/pkg/tracing"
 	"github.com/ethsana/sana/pkg/traversal"
+	"github.com/ethsana/sana/pkg/uploadpolicy"
+	"github.com/ethsana/sana/pkg/uploadscan"
+	"github.com/ethsana/sana/pkg/uploadsession"
 )
 
 const (
@@ -48,6 +60,7 @@ const (
 	SwarmFeedIndexNextHeader  = "Swarm-Feed-Index-Next"
 	SwarmCollectionHeader     = "Swarm-Collection"
 	SwarmPostageBatchIdHeader = "Swarm-Postage-Batch-Id"
+	SwarmPostageStampHeader   = "Swarm-Postage-Stamp"
 )
 
 // The size of buffer used for prefetching content with Langos.
@@ -76,6 +89,8 @@ var (
 	errDirectoryStore       = errors.New("could not store directory")
 	errFileStore            = errors.New("could not store file")
 	errInvalidPostageBatch  = errors.New("invalid postage batch id")
+	errInvalidPostageStamp  = errors.New("invalid postage stamp")
+	errStampsNotAccepted    = errors.New("stamped chunks not accepted")
 )
 
 // Service is the API service interface.
@@ -83,6 +98,13 @@ type Service interface {
 	http.Handler
 	m.Collector
 	io.Closer
+	// SetCORSAllowedOrigins changes the origins which are allowed to make
+	// cross-origin requests.
+	SetCORSAllowedOrigins(origins []string)
+	// S3Handler returns the handler of the S3 gateway which serves the
+	// S3-compatible API at the root path with the requests authenticated by
+	// the S3Credentials, or nil if the S3Buckets are not set.
+	S3Handler() http.Handler
 }
 
 type server struct {
@@ -102,6 +124,12 @@ type server struct {
 	Options
 	http.Handler
 	metrics metrics
+	gateway *gatewayLimiter
+
+	s3Mu      sync.Mutex // serializes S3 bucket manifest updates
+	s3Gateway http.Handler
+
+	corsMu sync.RWMutex // protects CORSAllowedOrigins which can be changed at runtime
 
 	wsWg sync.WaitGroup // wait for all websockets to close on exit
 	quit chan struct{}
@@ -112,6 +140,55 @@ type Options struct {
 	Authorization      string
 	GatewayMode        bool
 	WsPingPeriod       time.Duration
+	// DedupIndex, if set, is used to skip re-uploading content which was
+	// already uploaded with the same postage batch.
+	DedupIndex dedup.Index
+	// S3Buckets, if set, enables the S3-compatible API which stores objects
+	// in bucket manifests.
+	S3Buckets s3.Buckets
+	// S3PostageBatch is used to stamp S3 uploads which do not specify a
+	// postage batch.
+	S3PostageBatch []byte
+	// S3Credentials are the access keys which sign the requests of the S3
+	// gateway.
+	S3Credentials s3.Credentials
+	// UploadScanner, if set, scans the content of uploads before they are
+	// stored and rejects uploads which it flags. The content of upload
+	// sessions is scanned once it is stored, before the session is completed.
+	UploadScanner uploadscan.Scanner
+	// UploadScanAudit records the uploads rejected by the UploadScanner.
+	UploadScanAudit uploadscan.Audit
+	// ManifestCache, if set, keeps the manifest nodes parsed while serving
+	// downloads in memory.
+	ManifestCache *nodecache.Cache
+	// Stamping, if set, enables the endpoints under /stamping where trusted
+	// light clients have their chunks stamped with the StampingBatch.
+	Stamping stamping.Service
+	// StampingBatch is the postage batch used to stamp the chunks of the
+	// Stamping clients.
+	StampingBatch []byte
+	// UploadPolicies, if set, are enforced on uploads depending on the
+	// Origin of the request.
+	UploadPolicies *uploadpolicy.Policies
+	// Takedown, if set, denies access to the content which is taken down and
+	// enables the endpoints under /takedown which accept the notices.
+	Takedown *takedown.Service
+	// GatewayLimits, if set, are enforced on the requests in the gateway
+	// mode.
+	GatewayLimits *GatewayLimits
+	// Repair, if set, enables the endpoint which repairs the content on
+	// demand.
+	Repair *repair.Service
+	// UploadSessions, if set, enables the endpoints under /uploads of the
+	// resumable uploads.
+	UploadSessions *uploadsession.Manager
+	// PssMailbox, if set, enables the endpoints under /pss/mailbox which
+	// deposit the pss messages for and fetch them from the mailboxes.
+	PssMailbox *mailbox.Service
+	// ValidStamp, if set, validates the stamps of the chunks which are
+	// stamped by the clients, the nodes in the light-remote mode, and
+	// uploaded with the stamp in the Swarm-Postage-Stamp header.
+	ValidStamp func(swarm.Chunk, []byte) (swarm.Chunk, error)
 }
 
 const (
@@ -139,12 +216,21 @@ func New(tags *tags.Tags, storer storage.Storer, resolver resolver.Interface, ps
 		metrics:         newMetrics(),
 		quit:            make(chan struct{}),
 	}
+	if o.GatewayMode && o.GatewayLimits != nil {
+		s.gateway = newGatewayLimiter(*o.GatewayLimits)
+	}
 
 	s.setupRouting()
 
 	return s
 }
 
+func (s *server) SetCORSAllowedOrigins(origins []string) {
+	s.corsMu.Lock()
+	defer s.corsMu.Unlock()
+	s.CORSAllowedOrigins = origins
+}
+
 // Close hangs up running websockets on shutdown.
 func (s *server) Close() error {
 	s.logger.Info("api shutting down")
@@ -214,6 +300,16 @@ func (s *server) resolveNameOrAddress(str string) (swarm.Address, error) {
 	return swarm.ZeroAddress, fmt.Errorf("%w: %v", errInvalidNameOrAddress, err)
 }
 
+// manifestLoadSaver returns the load saver for reading manifests. The parsed
+// manifest nodes are kept in the manifest cache when one is configured.
+func (s *server) manifestLoadSaver() file.LoadSaver {
+	ls := loadsave.New(s.storer, storage.ModePutRequest, false)
+	if s.ManifestCache == nil {
+		return ls
+	}
+	return s.ManifestCache.LoadSaver(ls)
+}
+
 // requestModePut returns the desired storage.ModePut for this request based on the request headers.
 func requestModePut(r *http.Request) storage.ModePut {
 	if h := strings.ToLower(r.Header.Get(SwarmPinHeader)); h == "true" {
@@ -241,6 +337,36 @@ func requestPostageBatchId(r *http.Request) ([]byte, error) {
 	return nil, errInvalidPostageBatch
 }
 
+// requestStampedChunk returns the chunk with the stamp in the
+// Swarm-Postage-Stamp header, or nil if the request has none.
+func (s *server) requestStampedChunk(r *http.Request, ch swarm.Chunk) (swarm.Chunk, error) {
+	h := r.Header.Get(SwarmPostageStampHeader)
+	if h == "" {
+		return nil, nil
+	}
+	if s.ValidStamp == nil {
+		return nil, errStampsNotAccepted
+	}
+	stamp, err := hex.DecodeString(h)
+	if err != nil {
+		return nil, errInvalidPostageStamp
+	}
+	ch, err = s.ValidStamp(ch, stamp)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, errInvalidPostageStamp)
+	}
+	return ch, nil
+}
+
+// postageStampErrorMessage returns the response message of the error of
+// requestStampedChunk.
+func postageStampErrorMessage(err error) string {
+	if errors.Is(err, errStampsNotAccepted) {
+		return errStampsNotAccepted.Error()
+	}
+	return errInvalidPostageStamp.Error()
+}
+
 func (s *server) newTracingHandler(spanName string) func(h http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -251,7 +377,7 @@ func (s *server) newTracingHandler(spanName string) func(h http.Handler) http.Ha
 			}
 
 			span, _, ctx := s.tracer.StartSpanFromContext(ctx, spanName, s.logger)
-			defer span.Finish()
+			defer span.End()
 
 			err = s.tracer.AddContextHTTPHeader(ctx, r.Header)
 			if err != nil {
@@ -281,7 +407,9 @@ func (s *server) checkOrigin(r *http.Request) bool {
 	if r.TLS != nil {
 		scheme = "https"
 	}
-	hosts := append(s.CORSAllowedOrigins, scheme+"://"+r.Host)
+	s.corsMu.RLock()
+	hosts := append(append(make([]string, 0, len(s.CORSAllowedOrigins)+1), s.CORSAllowedOrigins...), scheme+"://"+r.Host)
+	s.corsMu.RUnlock()
 	for _, v := range hosts {
 		if equalASCIIFold(origin[0], v) || v == "*" {
 			return true
@@ -20,23 +20,33 @@ import (
 
 	"github.com/ethsana/sana/pkg/api"
 	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/dedup"
 	"github.com/ethsana/sana/pkg/feeds"
 	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
 	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/manifest/nodecache"
 	"github.com/ethsana/sana/pkg/pinning"
 	"github.com/ethsana/sana/pkg/postage"
 	mockpost "github.com/ethsana/sana/pkg/postage/mock"
 	"github.com/ethsana/sana/pkg/postage/postagecontract"
 	"github.com/ethsana/sana/pkg/pss"
+	"github.com/ethsana/sana/pkg/pss/mailbox"
+	"github.com/ethsana/sana/pkg/repair"
 	"github.com/ethsana/sana/pkg/resolver"
 	resolverMock "github.com/ethsana/sana/pkg/resolver/mock"
+	"github.com/ethsana/sana/pkg/s3"
+	"github.com/ethsana/sana/pkg/stamping"
 	statestore "github.com/ethsana/sana/pkg/statestore/mock"
 	"github.com/ethsana/sana/pkg/steward"
 	"github.com/ethsana/sana/pkg/storage"
 	"github.com/ethsana/sana/pkg/storage/mock"
 	"github.com/ethsana/sana/pkg/swarm"
 	"github.com/ethsana/sana/pkg/tags"
+	"github.com/ethsana/sana/pkg/takedown"
 	"github.com/ethsana/sana/pkg/traversal"
+	"github.com/ethsana/sana/pkg/uploadpolicy"
+	"github.com/ethsana/sana/pkg/uploadscan"
+	"github.com/ethsana/sana/pkg/uploadsession"
 	"github.com/gorilla/websocket"
 	"resenje.org/web"
 )
@@ -71,6 +81,21 @@ type testServerOptions struct {
 	PostageContract    postagecontract.Interface
 	Post               postage.Service
 	Steward            steward.Reuploader
+	DedupIndex         dedup.Index
+	S3Buckets          s3.Buckets
+	S3PostageBatch     []byte
+	UploadScanner      uploadscan.Scanner
+	UploadScanAudit    uploadscan.Audit
+	ManifestCache      *nodecache.Cache
+	Stamping           stamping.Service
+	StampingBatch      []byte
+	UploadPolicies     *uploadpolicy.Policies
+	Takedown           *takedown.Service
+	GatewayLimits      *api.GatewayLimits
+	Repair             *repair.Service
+	UploadSessions     *uploadsession.Manager
+	PssMailbox         *mailbox.Service
+	ValidStamp         func(swarm.Chunk, []byte) (swarm.Chunk, error)
 }
 
 func newTestServer(t *testing.T, o testServerOptions) (*http.Client, *websocket.Conn, string) {
@@ -94,6 +119,21 @@ func newTestServer(t *testing.T, o testServerOptions) (*http.Client, *websocket.
 		CORSAllowedOrigins: o.CORSAllowedOrigins,
 		GatewayMode:        o.GatewayMode,
 		WsPingPeriod:       o.WsPingPeriod,
+		DedupIndex:         o.DedupIndex,
+		S3Buckets:          o.S3Buckets,
+		S3PostageBatch:     o.S3PostageBatch,
+		UploadScanner:      o.UploadScanner,
+		UploadScanAudit:    o.UploadScanAudit,
+		ManifestCache:      o.ManifestCache,
+		Stamping:           o.Stamping,
+		StampingBatch:      o.StampingBatch,
+		UploadPolicies:     o.UploadPolicies,
+		Takedown:           o.Takedown,
+		GatewayLimits:      o.GatewayLimits,
+		Repair:             o.Repair,
+		UploadSessions:     o.UploadSessions,
+		PssMailbox:         o.PssMailbox,
+		ValidStamp:         o.ValidStamp,
 	})
 	ts := httptest.NewServer(s)
 	t.Cleanup(ts.Close)
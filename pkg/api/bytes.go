@@ -7,6 +7,7 @@ package api
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 
@@ -67,19 +68,42 @@ func (s *server) bytesUploadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	p := requestPipelineFn(putter, r)
-	address, err := p(ctx, r.Body)
+	var (
+		address      swarm.Address
+		deduplicated bool
+		body         io.Reader = r.Body
+	)
+	du, err := s.newDedupUpload(r, batch)
 	if err != nil {
-		logger.Debugf("bytes upload: split write all: %v", err)
-		logger.Error("bytes upload: split write all")
-		switch {
-		case errors.Is(err, postage.ErrBucketFull):
-			jsonhttp.PaymentRequired(w, "batch is overissued")
-		default:
-			jsonhttp.InternalServerError(w, nil)
-		}
+		logger.Debugf("bytes upload: dedup: %v", err)
+		logger.Error("bytes upload: dedup")
+		jsonhttp.InternalServerError(w, nil)
 		return
 	}
+	if du != nil {
+		defer du.Close()
+		body = du.body
+		address, deduplicated = s.dedupLookup(ctx, du)
+	}
+
+	if !deduplicated {
+		p := requestPipelineFn(putter, r)
+		address, err = p(ctx, body)
+		if err != nil {
+			logger.Debugf("bytes upload: split write all: %v", err)
+			logger.Error("bytes upload: split write all")
+			switch {
+			case errors.Is(err, postage.ErrBucketFull):
+				jsonhttp.PaymentRequired(w, "batch is overissued")
+			default:
+				jsonhttp.InternalServerError(w, nil)
+			}
+			return
+		}
+		if du != nil {
+			s.dedupStore(du, address)
+		}
+	}
 
 	if created {
 		_, err = tag.DoneSplit(address)
@@ -102,6 +126,10 @@ func (s *server) bytesUploadHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set(SwarmTagHeader, fmt.Sprint(tag.Uid))
 	w.Header().Set("Access-Control-Expose-Headers", SwarmTagHeader)
+	if deduplicated {
+		w.Header().Set(SwarmDeduplicatedHeader, "true")
+		w.Header().Add("Access-Control-Expose-Headers", SwarmDeduplicatedHeader)
+	}
 	jsonhttp.Created(w, bytesPostResponse{
 		Reference: address,
 	})
@@ -119,6 +147,9 @@ func (s *server) bytesGetHandler(w http.ResponseWriter, r *http.Request) {
 		jsonhttp.NotFound(w, nil)
 		return
 	}
+	if s.takenDown(w, address) {
+		return
+	}
 
 	additionalHeaders := http.Header{
 		"Content-Type": {"application/octet-stream"},
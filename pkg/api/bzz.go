@@ -120,22 +120,47 @@ func (s *server) fileUploadHandler(w http.ResponseWriter, r *http.Request, store
 	fileName = r.URL.Query().Get("name")
 	reader = r.Body
 
-	p := requestPipelineFn(storer, r)
-
-	// first store the file and get its reference
-	fr, err := p(ctx, reader)
+	// batch id has already been validated by this time
+	batch, _ := requestPostageBatchId(r)
+	du, err := s.newDedupUpload(r, batch)
 	if err != nil {
-		logger.Debugf("bzz upload file: file store, file %q: %v", fileName, err)
-		logger.Errorf("bzz upload file: file store, file %q", fileName)
-		switch {
-		case errors.Is(err, postage.ErrBucketFull):
-			jsonhttp.PaymentRequired(w, "batch is overissued")
-		default:
-			jsonhttp.InternalServerError(w, errFileStore)
-		}
+		logger.Debugf("bzz upload file: dedup: %v", err)
+		logger.Error("bzz upload file: dedup")
+		jsonhttp.InternalServerError(w, nil)
 		return
 	}
 
+	var (
+		fr           swarm.Address
+		deduplicated bool
+	)
+	if du != nil {
+		defer du.Close()
+		reader = du.body
+		fr, deduplicated = s.dedupLookup(ctx, du)
+	}
+
+	if !deduplicated {
+		p := requestPipelineFn(storer, r)
+
+		// first store the file and get its reference
+		fr, err = p(ctx, reader)
+		if err != nil {
+			logger.Debugf("bzz upload file: file store, file %q: %v", fileName, err)
+			logger.Errorf("bzz upload file: file store, file %q", fileName)
+			switch {
+			case errors.Is(err, postage.ErrBucketFull):
+				jsonhttp.PaymentRequired(w, "batch is overissued")
+			default:
+				jsonhttp.InternalServerError(w, errFileStore)
+			}
+			return
+		}
+		if du != nil {
+			s.dedupStore(du, fr)
+		}
+	}
+
 	// If filename is still empty, use the file hash as the filename
 	if fileName == "" {
 		fileName = fr.String()
@@ -231,6 +256,10 @@ func (s *server) fileUploadHandler(w http.ResponseWriter, r *http.Request, store
 	w.Header().Set("ETag", fmt.Sprintf("%q", manifestReference.String()))
 	w.Header().Set(SwarmTagHeader, fmt.Sprint(tag.Uid))
 	w.Header().Set("Access-Control-Expose-Headers", SwarmTagHeader)
+	if deduplicated {
+		w.Header().Set(SwarmDeduplicatedHeader, "true")
+		w.Header().Add("Access-Control-Expose-Headers", SwarmDeduplicatedHeader)
+	}
 	jsonhttp.Created(w, bzzUploadResponse{
 		Reference: manifestReference,
 	})
@@ -238,7 +267,7 @@ func (s *server) fileUploadHandler(w http.ResponseWriter, r *http.Request, store
 
 func (s *server) bzzDownloadHandler(w http.ResponseWriter, r *http.Request) {
 	logger := tracing.NewLoggerWithTraceID(r.Context(), s.logger)
-	ls := loadsave.New(s.storer, storage.ModePutRequest, false)
+	ls := s.manifestLoadSaver()
 	feedDereferenced := false
 
 	targets := r.URL.Query().Get("targets")
@@ -264,6 +293,10 @@ func (s *server) bzzDownloadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 FETCH:
+	if s.takenDown(w, address) {
+		return
+	}
+
 	// read manifest entry
 	m, err := manifest.NewDefaultManifestReference(
 		address,
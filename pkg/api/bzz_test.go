@@ -23,6 +23,7 @@ import (
 	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
 	"github.com/ethsana/sana/pkg/logging"
 	"github.com/ethsana/sana/pkg/manifest"
+	"github.com/ethsana/sana/pkg/manifest/nodecache"
 	pinning "github.com/ethsana/sana/pkg/pinning/mock"
 	mockpost "github.com/ethsana/sana/pkg/postage/mock"
 	statestore "github.com/ethsana/sana/pkg/statestore/mock"
@@ -634,3 +635,65 @@ func (m *mockSteward) Reupload(_ context.Context, addr swarm.Address) error {
 	m.addr = addr
 	return nil
 }
+
+func TestBzzManifestCache(t *testing.T) {
+	var (
+		logger         = logging.New(ioutil.Discard, 0)
+		mockStatestore = statestore.NewStateStore()
+		storer         = smock.NewStorer()
+		cache          = nodecache.New(1 << 20)
+		client, _, _   = newTestServer(t, testServerOptions{
+			Storer:        storer,
+			Tags:          tags.NewTags(mockStatestore, logger),
+			Logger:        logger,
+			Post:          mockpost.New(mockpost.WithAcceptAll()),
+			ManifestCache: cache,
+		})
+	)
+
+	tr := tarFiles(t, []f{
+		{
+			data: []byte("robots text"),
+			name: "robots.txt",
+			header: http.Header{
+				"Content-Type": {"text/plain; charset=utf-8"},
+			},
+		},
+		{
+			data: []byte("image 1"),
+			name: "1.png",
+			dir:  "img",
+			header: http.Header{
+				"Content-Type": {"image/png"},
+			},
+		},
+	})
+	var resp api.BzzUploadResponse
+	jsonhttptest.Request(t, client, http.MethodPost, "/bzz", http.StatusCreated,
+		jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+		jsonhttptest.WithRequestBody(tr),
+		jsonhttptest.WithRequestHeader(api.SwarmCollectionHeader, "true"),
+		jsonhttptest.WithRequestHeader("Content-Type", api.ContentTypeTar),
+		jsonhttptest.WithUnmarshalJSONResponse(&resp),
+	)
+	if cache.Len() != 0 {
+		t.Fatalf("got %d cached nodes after upload, want none", cache.Len())
+	}
+
+	download := func() {
+		t.Helper()
+		jsonhttptest.Request(t, client, http.MethodGet, "/bzz/"+resp.Reference.String()+"/img/1.png", http.StatusOK,
+			jsonhttptest.WithExpectedResponse([]byte("image 1")),
+		)
+	}
+
+	download()
+	cached := cache.Len()
+	if cached == 0 {
+		t.Fatal("expected cached manifest nodes")
+	}
+	download()
+	if cache.Len() != cached {
+		t.Fatalf("got %d cached nodes, want %d", cache.Len(), cached)
+	}
+}
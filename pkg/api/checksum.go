@@ -0,0 +1,153 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/ethsana/sana/pkg/file/joiner"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/manifest"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/tracing"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// checksumFormatTSV lists path, size, swarm reference and sha256 digest
+	// of every file separated by tabs, preceded by a header line.
+	checksumFormatTSV = "tsv"
+	// checksumFormatSha256sum produces output which can be verified
+	// directly with `sha256sum -c`.
+	checksumFormatSha256sum = "sha256sum"
+
+	contentTypeTextPlain = "text/plain; charset=utf-8"
+)
+
+var errInvalidChecksumFormat = errors.New("invalid checksum format")
+
+// checksumEntry holds the checksum information of a single manifest file.
+type checksumEntry struct {
+	Path      string
+	Size      int64
+	Reference swarm.Address
+	Sha256    string
+}
+
+// checksumsHandler walks the manifest referenced by the address and responds
+// with a checksum listing of all files it contains, as a downloadable
+// attachment.
+func (s *server) checksumsHandler(w http.ResponseWriter, r *http.Request) {
+	logger := tracing.NewLoggerWithTraceID(r.Context(), s.logger)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = checksumFormatTSV
+	}
+	if format != checksumFormatTSV && format != checksumFormatSha256sum {
+		logger.Debugf("checksums: unknown format %q", format)
+		logger.Error("checksums: unknown format")
+		jsonhttp.BadRequest(w, errInvalidChecksumFormat)
+		return
+	}
+
+	nameOrHex := mux.Vars(r)["address"]
+	address, err := s.resolveNameOrAddress(nameOrHex)
+	if err != nil {
+		logger.Debugf("checksums: parse address %s: %v", nameOrHex, err)
+		logger.Error("checksums: parse address")
+		jsonhttp.NotFound(w, nil)
+		return
+	}
+	if s.takenDown(w, address) {
+		return
+	}
+
+	ls := s.manifestLoadSaver()
+	m, err := manifest.NewDefaultManifestReference(address, ls)
+	if err != nil {
+		logger.Debugf("checksums: not manifest %s: %v", address, err)
+		logger.Error("checksums: not manifest")
+		jsonhttp.NotFound(w, nil)
+		return
+	}
+
+	entries, err := s.manifestChecksums(r.Context(), m)
+	if err != nil {
+		logger.Debugf("checksums: manifest %s: %v", address, err)
+		logger.Error("checksums: manifest")
+		if errors.Is(err, storage.ErrNotFound) {
+			jsonhttp.NotFound(w, nil)
+			return
+		}
+		jsonhttp.InternalServerError(w, nil)
+		return
+	}
+
+	var buf bytes.Buffer
+	if format == checksumFormatTSV {
+		fmt.Fprintln(&buf, "path\tsize\treference\tsha256")
+	}
+	for _, e := range entries {
+		switch format {
+		case checksumFormatSha256sum:
+			fmt.Fprintf(&buf, "%s  %s\n", e.Sha256, e.Path)
+		default:
+			fmt.Fprintf(&buf, "%s\t%d\t%s\t%s\n", e.Path, e.Size, e.Reference, e.Sha256)
+		}
+	}
+
+	w.Header().Set(contentTypeHeader, contentTypeTextPlain)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", address, format))
+	w.Header().Set("Content-Length", fmt.Sprint(buf.Len()))
+	w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition")
+	w.WriteHeader(http.StatusOK)
+	_, _ = buf.WriteTo(w)
+}
+
+// manifestChecksums returns checksum entries for all files in the manifest
+// sorted by their paths.
+func (s *server) manifestChecksums(ctx context.Context, m manifest.Interface) ([]checksumEntry, error) {
+	var entries []checksumEntry
+	err := m.IterateEntries(ctx, func(path string, e manifest.Entry) error {
+		entries = append(entries, checksumEntry{
+			Path:      path,
+			Reference: e.Reference(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	for i := range entries {
+		reader, _, err := joiner.New(ctx, s.storer, entries[i].Reference)
+		if err != nil {
+			return nil, fmt.Errorf("join %s: %w", entries[i].Path, err)
+		}
+		h := sha256.New()
+		n, err := io.Copy(h, reader)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", entries[i].Path, err)
+		}
+		entries[i].Size = n
+		entries[i].Sha256 = hex.EncodeToString(h.Sum(nil))
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/api"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
+	mockpost "github.com/ethsana/sana/pkg/postage/mock"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/tags"
+)
+
+func TestChecksums(t *testing.T) {
+	var (
+		checksumsResource = func(addr, format string) string {
+			if format == "" {
+				return "/checksums/" + addr
+			}
+			return "/checksums/" + addr + "?format=" + format
+		}
+		storer         = mock.NewStorer()
+		mockStatestore = statestore.NewStateStore()
+		logger         = logging.New(ioutil.Discard, 0)
+		client, _, _   = newTestServer(t, testServerOptions{
+			Storer: storer,
+			Tags:   tags.NewTags(mockStatestore, logger),
+			Logger: logger,
+			Post:   mockpost.New(mockpost.WithAcceptAll()),
+		})
+		files = []f{
+			{data: []byte("second file data"), name: "b.txt", dir: "docs"},
+			{data: []byte("first file data"), name: "a.txt"},
+		}
+	)
+
+	var resp api.BzzUploadResponse
+	jsonhttptest.Request(t, client, http.MethodPost, "/bzz", http.StatusCreated,
+		jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+		jsonhttptest.WithRequestBody(tarFiles(t, files)),
+		jsonhttptest.WithRequestHeader(api.SwarmCollectionHeader, "True"),
+		jsonhttptest.WithRequestHeader("Content-Type", api.ContentTypeTar),
+		jsonhttptest.WithUnmarshalJSONResponse(&resp),
+	)
+
+	sum := func(b []byte) string {
+		h := sha256.Sum256(b)
+		return hex.EncodeToString(h[:])
+	}
+
+	t.Run("sha256sum", func(t *testing.T) {
+		want := fmt.Sprintf("%s  a.txt\n%s  docs/b.txt\n", sum(files[1].data), sum(files[0].data))
+		rcvdHeader := jsonhttptest.Request(t, client, http.MethodGet, checksumsResource(resp.Reference.String(), "sha256sum"), http.StatusOK,
+			jsonhttptest.WithExpectedResponse([]byte(want)),
+		)
+		if cd := rcvdHeader.Get("Content-Disposition"); cd != fmt.Sprintf("attachment; filename=\"%s.sha256sum\"", resp.Reference) {
+			t.Fatalf("unexpected content disposition %q", cd)
+		}
+	})
+
+	t.Run("tsv", func(t *testing.T) {
+		var b []byte
+		jsonhttptest.Request(t, client, http.MethodGet, checksumsResource(resp.Reference.String(), ""), http.StatusOK,
+			jsonhttptest.WithPutResponseBody(&b),
+		)
+		lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("got %d lines, want 3", len(lines))
+		}
+		if lines[0] != "path\tsize\treference\tsha256" {
+			t.Fatalf("unexpected header %q", lines[0])
+		}
+		for i, file := range []f{files[1], files[0]} {
+			cols := strings.Split(lines[i+1], "\t")
+			if len(cols) != 4 {
+				t.Fatalf("got %d columns, want 4", len(cols))
+			}
+			if cols[1] != fmt.Sprint(len(file.data)) {
+				t.Fatalf("got size %s, want %d", cols[1], len(file.data))
+			}
+			if cols[3] != sum(file.data) {
+				t.Fatalf("got sha256 %s, want %s", cols[3], sum(file.data))
+			}
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodGet, checksumsResource(resp.Reference.String(), "md5"), http.StatusBadRequest,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "invalid checksum format",
+				Code:    http.StatusBadRequest,
+			}),
+		)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodGet, checksumsResource("f30c0aa7e9e2a0ef4c9b1b750ebfeaeb7c7c24da700bb089da19a46e3677824b", ""), http.StatusNotFound)
+	})
+}
@@ -85,27 +85,40 @@ func (s *server) chunkUploadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	batch, err := requestPostageBatchId(r)
-	if err != nil {
-		s.logger.Debugf("chunk upload: postage batch id: %v", err)
-		s.logger.Error("chunk upload: postage batch id")
-		jsonhttp.BadRequest(w, "invalid postage batch id")
+	var putter storage.Putter
+	stamped, err := s.requestStampedChunk(r, chunk)
+	switch {
+	case err != nil:
+		s.logger.Debugf("chunk upload: postage stamp: %v", err)
+		s.logger.Error("chunk upload: postage stamp")
+		jsonhttp.BadRequest(w, postageStampErrorMessage(err))
 		return
-	}
+	case stamped != nil:
+		// the chunk is stamped by the client
+		chunk, putter = stamped, s.storer
+	default:
+		batch, err := requestPostageBatchId(r)
+		if err != nil {
+			s.logger.Debugf("chunk upload: postage batch id: %v", err)
+			s.logger.Error("chunk upload: postage batch id")
+			jsonhttp.BadRequest(w, "invalid postage batch id")
+			return
+		}
 
-	putter, err := newStamperPutter(s.storer, s.post, s.signer, batch)
-	if err != nil {
-		s.logger.Debugf("chunk upload: putter:%v", err)
-		s.logger.Error("chunk upload: putter")
-		switch {
-		case errors.Is(err, postage.ErrNotFound):
-			jsonhttp.BadRequest(w, "batch not found")
-		case errors.Is(err, postage.ErrNotUsable):
-			jsonhttp.BadRequest(w, "batch not usable yet")
-		default:
-			jsonhttp.BadRequest(w, nil)
+		putter, err = newStamperPutter(s.storer, s.post, s.signer, batch)
+		if err != nil {
+			s.logger.Debugf("chunk upload: putter:%v", err)
+			s.logger.Error("chunk upload: putter")
+			switch {
+			case errors.Is(err, postage.ErrNotFound):
+				jsonhttp.BadRequest(w, "batch not found")
+			case errors.Is(err, postage.ErrNotUsable):
+				jsonhttp.BadRequest(w, "batch not usable yet")
+			default:
+				jsonhttp.BadRequest(w, nil)
+			}
+			return
 		}
-		return
 	}
 
 	seen, err := putter.Put(ctx, requestModePut(r), chunk)
@@ -170,6 +183,9 @@ func (s *server) chunkGetHandler(w http.ResponseWriter, r *http.Request) {
 		jsonhttp.NotFound(w, nil)
 		return
 	}
+	if s.takenDown(w, address) {
+		return
+	}
 
 	chunk, err := s.storer.Get(ctx, storage.ModeGetRequest, address)
 	if err != nil {
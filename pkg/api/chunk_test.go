@@ -7,6 +7,8 @@ package api_test
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"testing"
@@ -142,3 +144,73 @@ func TestChunkUploadDownload(t *testing.T) {
 		}
 	})
 }
+
+// TestChunkUploadStamped uploads the chunks stamped by the clients, as the
+// nodes in the light-remote mode do.
+func TestChunkUploadStamped(t *testing.T) {
+	var (
+		chunksEndpoint = "/chunks"
+		chunk          = testingc.GenerateTestRandomChunk()
+		storerMock     = mock.NewStorer()
+		validStamp     = func(ch swarm.Chunk, stamp []byte) (swarm.Chunk, error) {
+			want, err := chunk.Stamp().MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(stamp, want) {
+				return nil, errors.New("unknown stamp")
+			}
+			return ch, nil
+		}
+	)
+	stamp, err := chunk.Stamp().MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		client, _, _ := newTestServer(t, testServerOptions{
+			Storer:     storerMock,
+			Tags:       tags.NewTags(statestore.NewStateStore(), logging.New(ioutil.Discard, 0)),
+			Post:       mockpost.New(),
+			ValidStamp: validStamp,
+		})
+		jsonhttptest.Request(t, client, http.MethodPost, chunksEndpoint, http.StatusCreated,
+			jsonhttptest.WithRequestHeader(api.SwarmPostageStampHeader, hex.EncodeToString(stamp)),
+			jsonhttptest.WithRequestBody(bytes.NewReader(chunk.Data())),
+			jsonhttptest.WithExpectedJSONResponse(api.ChunkAddressResponse{Reference: chunk.Address()}),
+		)
+		has, err := storerMock.Has(context.Background(), chunk.Address())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !has {
+			t.Fatal("stamped chunk not stored")
+		}
+
+		jsonhttptest.Request(t, client, http.MethodPost, chunksEndpoint, http.StatusBadRequest,
+			jsonhttptest.WithRequestHeader(api.SwarmPostageStampHeader, hex.EncodeToString(stamp[1:])),
+			jsonhttptest.WithRequestBody(bytes.NewReader(chunk.Data())),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "invalid postage stamp",
+				Code:    http.StatusBadRequest,
+			}),
+		)
+	})
+
+	t.Run("not accepted", func(t *testing.T) {
+		client, _, _ := newTestServer(t, testServerOptions{
+			Storer: mock.NewStorer(),
+			Tags:   tags.NewTags(statestore.NewStateStore(), logging.New(ioutil.Discard, 0)),
+			Post:   mockpost.New(),
+		})
+		jsonhttptest.Request(t, client, http.MethodPost, chunksEndpoint, http.StatusBadRequest,
+			jsonhttptest.WithRequestHeader(api.SwarmPostageStampHeader, hex.EncodeToString(stamp)),
+			jsonhttptest.WithRequestBody(bytes.NewReader(chunk.Data())),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "stamped chunks not accepted",
+				Code:    http.StatusBadRequest,
+			}),
+		)
+	})
+}
@@ -0,0 +1,134 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/ethsana/sana/pkg/dedup"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+// SwarmDeduplicatedHeader is set on upload responses when the reference was
+// found in the deduplication index and the content was not uploaded again.
+const SwarmDeduplicatedHeader = "Swarm-Deduplicated"
+
+// dedupMaxSpoolSize is the maximal size of the request bodies which are
+// spooled to be deduplicated. The larger ones are uploaded without the
+// deduplication.
+var dedupMaxSpoolSize int64 = 64 * 1024 * 1024
+
+// dedupUpload holds the state of a single upload when the deduplication
+// index is enabled. The key is nil if the upload is not deduplicated.
+type dedupUpload struct {
+	index dedup.Index
+	key   []byte
+	body  io.Reader
+	file  *os.File
+}
+
+// newDedupUpload spools the request body into a temporary file while hashing
+// it, so that the content hash is known before the upload is started. It
+// returns nil if deduplication is not enabled or not applicable for the
+// request. At most dedupMaxSpoolSize bytes, or the maximal request size of the
+// gateway if it is smaller, are spooled, the larger bodies are read from the
+// spooled part and the rest of the request without the deduplication.
+func (s *server) newDedupUpload(r *http.Request, batch []byte) (*dedupUpload, error) {
+	if s.DedupIndex == nil || requestEncrypt(r) {
+		// encrypted uploads never produce the same reference
+		return nil, nil
+	}
+
+	f, err := ioutil.TempFile("", "sana-upload-")
+	if err != nil {
+		return nil, fmt.Errorf("create spool file: %w", err)
+	}
+
+	limit := dedupMaxSpoolSize
+	if s.gateway != nil && s.gateway.limits.MaxRequestSize > 0 && s.gateway.limits.MaxRequestSize < limit {
+		limit = s.gateway.limits.MaxRequestSize
+	}
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		closeSpool(f)
+		return nil, fmt.Errorf("spool body: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		closeSpool(f)
+		return nil, fmt.Errorf("rewind spool file: %w", err)
+	}
+
+	if n > limit {
+		return &dedupUpload{
+			index: s.DedupIndex,
+			body:  io.MultiReader(f, r.Body),
+			file:  f,
+		}, nil
+	}
+	return &dedupUpload{
+		index: s.DedupIndex,
+		key:   dedup.Key(h.Sum(nil), batch),
+		body:  f,
+		file:  f,
+	}, nil
+}
+
+// lookup returns the reference of a previous identical upload if one exists
+// and its root chunk is still present in the local store. Stale index
+// entries are removed.
+func (s *server) dedupLookup(ctx context.Context, u *dedupUpload) (swarm.Address, bool) {
+	if u.key == nil {
+		return swarm.ZeroAddress, false
+	}
+	ref, err := u.index.Get(u.key)
+	if err != nil {
+		if !errors.Is(err, dedup.ErrNotFound) {
+			s.logger.Debugf("dedup: get: %v", err)
+		}
+		return swarm.ZeroAddress, false
+	}
+
+	has, err := s.storer.Has(ctx, ref)
+	if err != nil {
+		s.logger.Debugf("dedup: has %s: %v", ref, err)
+		return swarm.ZeroAddress, false
+	}
+	if !has {
+		if err := u.index.Delete(u.key); err != nil {
+			s.logger.Debugf("dedup: delete stale %s: %v", ref, err)
+		}
+		return swarm.ZeroAddress, false
+	}
+	return ref, true
+}
+
+// dedupStore records the reference of a completed upload.
+func (s *server) dedupStore(u *dedupUpload, ref swarm.Address) {
+	if u.key == nil {
+		return
+	}
+	if err := u.index.Put(u.key, ref); err != nil {
+		s.logger.Debugf("dedup: put %s: %v", ref, err)
+	}
+}
+
+// Close removes the spooled request body.
+func (u *dedupUpload) Close() {
+	closeSpool(u.file)
+}
+
+func closeSpool(f *os.File) {
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+}
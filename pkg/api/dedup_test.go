@@ -0,0 +1,95 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/api"
+	"github.com/ethsana/sana/pkg/dedup"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
+	mockpost "github.com/ethsana/sana/pkg/postage/mock"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/tags"
+)
+
+func TestUploadDedup(t *testing.T) {
+	var (
+		storer         = mock.NewStorer()
+		mockStatestore = statestore.NewStateStore()
+		logger         = logging.New(ioutil.Discard, 0)
+		client, _, _   = newTestServer(t, testServerOptions{
+			Storer:     storer,
+			Tags:       tags.NewTags(mockStatestore, logger),
+			Logger:     logger,
+			Post:       mockpost.New(mockpost.WithAcceptAll()),
+			DedupIndex: dedup.New(mockStatestore),
+		})
+		data = []byte("deduplicated content")
+	)
+
+	upload := func(t *testing.T, resource string, wantDedup bool, headers ...jsonhttptest.Option) api.BytesPostResponse {
+		t.Helper()
+
+		var resp api.BytesPostResponse
+		opts := append([]jsonhttptest.Option{
+			jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+			jsonhttptest.WithRequestBody(bytes.NewReader(data)),
+			jsonhttptest.WithUnmarshalJSONResponse(&resp),
+		}, headers...)
+		h := jsonhttptest.Request(t, client, http.MethodPost, resource, http.StatusCreated, opts...)
+		if got := h.Get(api.SwarmDeduplicatedHeader) == "true"; got != wantDedup {
+			t.Fatalf("got deduplicated %v, want %v", got, wantDedup)
+		}
+		return resp
+	}
+
+	t.Run("bytes", func(t *testing.T) {
+		first := upload(t, "/bytes", false)
+		second := upload(t, "/bytes", true)
+		if !first.Reference.Equal(second.Reference) {
+			t.Fatalf("got reference %s, want %s", second.Reference, first.Reference)
+		}
+	})
+
+	t.Run("bzz", func(t *testing.T) {
+		contentType := jsonhttptest.WithRequestHeader("Content-Type", "text/plain")
+		first := upload(t, "/bzz?name=file.txt", true, contentType)
+		second := upload(t, "/bzz?name=file.txt", true, contentType)
+		if !first.Reference.Equal(second.Reference) {
+			t.Fatalf("got reference %s, want %s", second.Reference, first.Reference)
+		}
+	})
+
+	t.Run("stale entry", func(t *testing.T) {
+		ref := upload(t, "/bytes", true).Reference
+		if err := storer.Set(context.Background(), storage.ModeSetRemove, ref); err != nil {
+			t.Fatal(err)
+		}
+		upload(t, "/bytes", false)
+	})
+
+	t.Run("over spool size", func(t *testing.T) {
+		api.SetDedupMaxSpoolSize(t, int64(len(data)-1))
+		first := upload(t, "/bytes", false)
+		second := upload(t, "/bytes", false)
+		if !first.Reference.Equal(second.Reference) {
+			t.Fatalf("got reference %s, want %s", second.Reference, first.Reference)
+		}
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		encrypt := jsonhttptest.WithRequestHeader(api.SwarmEncryptHeader, "true")
+		upload(t, "/bytes", false, encrypt)
+		upload(t, "/bytes", false, encrypt)
+	})
+}
@@ -4,7 +4,11 @@
 
 package api
 
-import "github.com/ethsana/sana/pkg/swarm"
+import (
+	"testing"
+
+	"github.com/ethsana/sana/pkg/swarm"
+)
 
 type Server = server
 
@@ -17,6 +21,9 @@ type (
 	TagResponse           = tagResponse
 	TagRequest            = tagRequest
 	ListTagsResponse      = listTagsResponse
+	TakedownNoticeRequest = takedownNoticeRequest
+	RepairResponse        = repairResponse
+	UploadSessionResponse = uploadSessionResponse
 )
 
 var (
@@ -48,3 +55,10 @@ func (s *Server) ResolveNameOrAddress(str string) (swarm.Address, error) {
 func CalculateNumberOfChunks(contentLength int64, isEncrypted bool) int64 {
 	return calculateNumberOfChunks(contentLength, isEncrypted)
 }
+
+func SetDedupMaxSpoolSize(t *testing.T, size int64) {
+	t.Helper()
+	old := dedupMaxSpoolSize
+	dedupMaxSpoolSize = size
+	t.Cleanup(func() { dedupMaxSpoolSize = old })
+}
@@ -0,0 +1,232 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/ratelimit"
+)
+
+// GatewayLimits are enforced on the requests in the gateway mode, in which
+// the api is exposed to the public.
+type GatewayLimits struct {
+	// RateLimit is the number of requests which a client IP address can make
+	// in the RateLimitPeriod, in bursts of up to RateLimit requests. Zero
+	// disables the rate limit.
+	RateLimit       int
+	RateLimitPeriod time.Duration
+	// MaxRequestSize is the maximal size of the request bodies in bytes.
+	// Zero disables the limit.
+	MaxRequestSize int64
+	// AllowedEndpoints, if not empty, are the only endpoints which can be
+	// requested.
+	AllowedEndpoints []EndpointRule
+	// DeniedEndpoints can not be requested even if they are allowed.
+	DeniedEndpoints []EndpointRule
+}
+
+// EndpointRule matches the requests of api endpoints. The path is matched
+// without the api version prefix.
+type EndpointRule struct {
+	// Method matches the request method, all methods if it is empty.
+	Method string
+	// Path matches the request path exactly or, if it ends with "*", the
+	// paths with the prefix before the "*".
+	Path string
+}
+
+// ParseEndpointRule parses the rule in the "[METHOD] PATH" format, for
+// example "GET /bzz/*" or "/bytes".
+func ParseEndpointRule(s string) (EndpointRule, error) {
+	fields := strings.Fields(s)
+	var r EndpointRule
+	switch len(fields) {
+	case 1:
+		r.Path = fields[0]
+	case 2:
+		r.Method = strings.ToUpper(fields[0])
+		r.Path = fields[1]
+	default:
+		return EndpointRule{}, fmt.Errorf("invalid endpoint rule %q", s)
+	}
+	if !strings.HasPrefix(r.Path, "/") {
+		return EndpointRule{}, fmt.Errorf("invalid endpoint rule %q: path must start with /", s)
+	}
+	if i := strings.Index(r.Path, "*"); i >= 0 && i != len(r.Path)-1 {
+		return EndpointRule{}, fmt.Errorf("invalid endpoint rule %q: * is allowed only at the end of the path", s)
+	}
+	for _, c := range r.Method {
+		if c < 'A' || c > 'Z' {
+			return EndpointRule{}, fmt.Errorf("invalid endpoint rule %q: invalid method", s)
+		}
+	}
+	return r, nil
+}
+
+// ParseEndpointRules parses the rules with ParseEndpointRule.
+func ParseEndpointRules(rules []string) ([]EndpointRule, error) {
+	var rs []EndpointRule
+	for _, s := range rules {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		r, err := ParseEndpointRule(s)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+// ParseRateLimit parses the rate limit in the "REQUESTS/PERIOD" format, where
+// the period is a duration or one of the units s, m and h, for example
+// "100/m" or "1000/10m". The empty string disables the rate limit.
+func ParseRateLimit(s string) (requests int, period time.Duration, err error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	i := strings.Index(s, "/")
+	if i < 0 {
+		return 0, 0, fmt.Errorf("invalid rate limit %q", s)
+	}
+	requests, err = strconv.Atoi(s[:i])
+	if err != nil || requests <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate limit %q: invalid number of requests", s)
+	}
+	p := s[i+1:]
+	if p == "s" || p == "m" || p == "h" {
+		p = "1" + p
+	}
+	period, err = time.ParseDuration(p)
+	if err != nil || period <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate limit %q: invalid period", s)
+	}
+	return requests, period, nil
+}
+
+func (r EndpointRule) matches(method, path string) bool {
+	if r.Method != "" && r.Method != method {
+		return false
+	}
+	if strings.HasSuffix(r.Path, "*") {
+		return strings.HasPrefix(path, strings.TrimSuffix(r.Path, "*"))
+	}
+	return path == r.Path
+}
+
+// gatewayLimiter enforces the GatewayLimits.
+type gatewayLimiter struct {
+	limits  GatewayLimits
+	limiter *ratelimit.Limiter
+	// idle is the time after which the rate limit of a client is reset
+	idle time.Duration
+
+	mu      sync.Mutex
+	cleared time.Time
+}
+
+func newGatewayLimiter(limits GatewayLimits) *gatewayLimiter {
+	l := &gatewayLimiter{
+		limits:  limits,
+		cleared: time.Now(),
+	}
+	if limits.RateLimit > 0 && limits.RateLimitPeriod > 0 {
+		l.limiter = ratelimit.New(limits.RateLimitPeriod/time.Duration(limits.RateLimit), limits.RateLimit)
+		l.idle = limits.RateLimitPeriod
+	}
+	return l
+}
+
+// allowsEndpoint returns true if the endpoint of the request is allowed and
+// not denied.
+func (l *gatewayLimiter) allowsEndpoint(r *http.Request) bool {
+	path := r.URL.Path
+	if path == "/v1" || strings.HasPrefix(path, "/v1/") {
+		path = strings.TrimPrefix(path, "/v1")
+	}
+	for _, rule := range l.limits.DeniedEndpoints {
+		if rule.matches(r.Method, path) {
+			return false
+		}
+	}
+	if len(l.limits.AllowedEndpoints) == 0 {
+		return true
+	}
+	for _, rule := range l.limits.AllowedEndpoints {
+		if rule.matches(r.Method, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsRequest returns true if the client has not exceeded the rate limit.
+func (l *gatewayLimiter) allowsRequest(client string) bool {
+	if l.limiter == nil {
+		return true
+	}
+
+	// the limiters of the clients which did not make requests for a while
+	// are full and can be dropped
+	l.mu.Lock()
+	if time.Since(l.cleared) >= l.idle {
+		l.limiter.ClearIdle(l.idle)
+		l.cleared = time.Now()
+	}
+	l.mu.Unlock()
+
+	return l.limiter.Allow(client, 1)
+}
+
+// retryAfter returns the number of seconds in which a token is refilled.
+func (l *gatewayLimiter) retryAfter() int {
+	d := l.limits.RateLimitPeriod / time.Duration(l.limits.RateLimit)
+	return int(math.Ceil(d.Seconds()))
+}
+
+// gatewayLimitsHandler enforces the gateway limits on the requests in the
+// gateway mode.
+func (s *server) gatewayLimitsHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.gateway == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if !s.gateway.allowsEndpoint(r) {
+			s.metrics.GatewayForbidden.Inc()
+			s.logger.Tracef("gateway mode: endpoint not allowed %s %s", r.Method, r.URL.String())
+			jsonhttp.Forbidden(w, "endpoint not allowed")
+			return
+		}
+
+		client := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			client = host
+		}
+		if !s.gateway.allowsRequest(client) {
+			s.metrics.GatewayRateLimited.Inc()
+			s.logger.Tracef("gateway mode: rate limit exceeded by %s", client)
+			w.Header().Set("Retry-After", strconv.Itoa(s.gateway.retryAfter()))
+			jsonhttp.TooManyRequests(w, nil)
+			return
+		}
+
+		if s.gateway.limits.MaxRequestSize > 0 {
+			h = jsonhttp.NewMaxBodyBytesHandler(s.gateway.limits.MaxRequestSize)(h)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
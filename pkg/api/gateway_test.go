@@ -0,0 +1,174 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/api"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
+	mockpost "github.com/ethsana/sana/pkg/postage/mock"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/tags"
+)
+
+func TestGatewayLimits(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+
+	newClient := func(t *testing.T, gatewayMode bool, limits api.GatewayLimits) *http.Client {
+		t.Helper()
+		client, _, _ := newTestServer(t, testServerOptions{
+			Storer:        mock.NewStorer(),
+			Tags:          tags.NewTags(statestore.NewStateStore(), logger),
+			Logger:        logger,
+			GatewayMode:   gatewayMode,
+			Post:          mockpost.New(mockpost.WithAcceptAll()),
+			GatewayLimits: &limits,
+		})
+		return client
+	}
+
+	t.Run("endpoints", func(t *testing.T) {
+		allowed, err := api.ParseEndpointRules([]string{"GET /bytes/*", "/chunks*"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		denied, err := api.ParseEndpointRules([]string{"POST /chunks"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := newClient(t, true, api.GatewayLimits{
+			AllowedEndpoints: allowed,
+			DeniedEndpoints:  denied,
+		})
+
+		forbidden := jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+			Message: "endpoint not allowed",
+			Code:    http.StatusForbidden,
+		})
+		address := "/0773a91efd6547c754fc1d95fb1c62c7d1b47f959c2caa685dfec8736da95c1c"
+
+		jsonhttptest.Request(t, client, http.MethodGet, "/bytes"+address, http.StatusNotFound)
+		jsonhttptest.Request(t, client, http.MethodGet, "/v1/bytes"+address, http.StatusNotFound)
+		jsonhttptest.Request(t, client, http.MethodGet, "/chunks"+address, http.StatusNotFound)
+		jsonhttptest.Request(t, client, http.MethodPost, "/bytes", http.StatusForbidden, forbidden)
+		jsonhttptest.Request(t, client, http.MethodPost, "/chunks", http.StatusForbidden, forbidden)
+		jsonhttptest.Request(t, client, http.MethodPost, "/v1/chunks", http.StatusForbidden, forbidden)
+		jsonhttptest.Request(t, client, http.MethodGet, "/bzz"+address+"/", http.StatusForbidden, forbidden)
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		client := newClient(t, true, api.GatewayLimits{
+			RateLimit:       2,
+			RateLimitPeriod: time.Hour,
+		})
+
+		jsonhttptest.Request(t, client, http.MethodGet, "/", http.StatusOK)
+		jsonhttptest.Request(t, client, http.MethodGet, "/", http.StatusOK)
+		header := jsonhttptest.Request(t, client, http.MethodGet, "/", http.StatusTooManyRequests,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: http.StatusText(http.StatusTooManyRequests),
+				Code:    http.StatusTooManyRequests,
+			}),
+		)
+		if got := header.Get("Retry-After"); got != "1800" {
+			t.Fatalf("got retry after %q, want %q", got, "1800")
+		}
+	})
+
+	t.Run("request size", func(t *testing.T) {
+		client := newClient(t, true, api.GatewayLimits{
+			MaxRequestSize: 10,
+		})
+
+		jsonhttptest.Request(t, client, http.MethodPost, "/bytes", http.StatusRequestEntityTooLarge,
+			jsonhttptest.WithRequestBody(bytes.NewReader(make([]byte, 11))),
+			jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+		)
+		jsonhttptest.Request(t, client, http.MethodPost, "/bytes", http.StatusCreated,
+			jsonhttptest.WithRequestBody(bytes.NewReader(make([]byte, 10))),
+			jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+		)
+	})
+
+	t.Run("not in gateway mode", func(t *testing.T) {
+		client := newClient(t, false, api.GatewayLimits{
+			RateLimit:       1,
+			RateLimitPeriod: time.Hour,
+		})
+
+		jsonhttptest.Request(t, client, http.MethodGet, "/", http.StatusOK)
+		jsonhttptest.Request(t, client, http.MethodGet, "/", http.StatusOK)
+	})
+}
+
+func TestParseRateLimit(t *testing.T) {
+	for _, tc := range []struct {
+		limit    string
+		requests int
+		period   time.Duration
+		wantErr  bool
+	}{
+		{limit: "", requests: 0, period: 0},
+		{limit: "100/m", requests: 100, period: time.Minute},
+		{limit: "1000/10m", requests: 1000, period: 10 * time.Minute},
+		{limit: "5/s", requests: 5, period: time.Second},
+		{limit: "100", wantErr: true},
+		{limit: "0/m", wantErr: true},
+		{limit: "10/d", wantErr: true},
+	} {
+		requests, period, err := api.ParseRateLimit(tc.limit)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected error", tc.limit)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.limit, err)
+			continue
+		}
+		if requests != tc.requests || period != tc.period {
+			t.Errorf("%q: got %d/%s, want %d/%s", tc.limit, requests, period, tc.requests, tc.period)
+		}
+	}
+}
+
+func TestParseEndpointRule(t *testing.T) {
+	for _, tc := range []struct {
+		rule    string
+		want    api.EndpointRule
+		wantErr bool
+	}{
+		{rule: "/bytes", want: api.EndpointRule{Path: "/bytes"}},
+		{rule: "get /bzz/*", want: api.EndpointRule{Method: "GET", Path: "/bzz/*"}},
+		{rule: "bytes", wantErr: true},
+		{rule: "/bzz/*/index", wantErr: true},
+		{rule: "GET /bzz POST", wantErr: true},
+		{rule: "G3T /bzz", wantErr: true},
+	} {
+		got, err := api.ParseEndpointRule(tc.rule)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected error", tc.rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.rule, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q: got %+v, want %+v", tc.rule, got, tc.want)
+		}
+	}
+}
@@ -21,6 +21,10 @@ type metrics struct {
 	ResponseDuration   prometheus.Histogram
 	PingRequestCount   prometheus.Counter
 	ResponseCodeCounts *prometheus.CounterVec
+	UploadScanRejected prometheus.Counter
+	UploadScanFailed   prometheus.Counter
+	GatewayForbidden   prometheus.Counter
+	GatewayRateLimited prometheus.Counter
 }
 
 func newMetrics() metrics {
@@ -49,6 +53,30 @@ func newMetrics() metrics {
 			},
 			[]string{"code", "method"},
 		),
+		UploadScanRejected: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "upload_scan_rejected_count",
+			Help:      "Number of uploads rejected by the content scanner.",
+		}),
+		UploadScanFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "upload_scan_failed_count",
+			Help:      "Number of uploads refused because the content scanner failed.",
+		}),
+		GatewayForbidden: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "gateway_forbidden_count",
+			Help:      "Number of requests to endpoints which are not allowed in the gateway mode.",
+		}),
+		GatewayRateLimited: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "gateway_rate_limited_count",
+			Help:      "Number of requests refused because the client exceeded the gateway rate limit.",
+		}),
 	}
 }
 
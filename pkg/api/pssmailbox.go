@@ -0,0 +1,176 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/pss"
+	"github.com/ethsana/sana/pkg/pss/mailbox"
+	"github.com/gorilla/mux"
+)
+
+type pssMailboxMessage struct {
+	Payload []byte `json:"payload"`
+	Stored  int64  `json:"stored"`
+}
+
+type pssMailboxResponse struct {
+	Messages []pssMailboxMessage `json:"messages"`
+	// Next is the since parameter of the fetch of the later messages.
+	Next int64 `json:"next"`
+}
+
+// pssMailboxPostHandler deposits the pss message in the mailbox of one of
+// the targets, to be fetched by the recipient within the ttl in seconds.
+func (s *server) pssMailboxPostHandler(w http.ResponseWriter, r *http.Request) {
+	topicVar := mux.Vars(r)["topic"]
+	topic := pss.NewTopic(topicVar)
+
+	targets, err := mailbox.ParseTargets(mux.Vars(r)["targets"])
+	if err != nil {
+		s.logger.Debugf("pss mailbox send: bad targets: %v", err)
+		s.logger.Error("pss mailbox send: bad targets")
+		jsonhttp.BadRequest(w, "invalid targets")
+		return
+	}
+
+	var recipient *ecdsa.PublicKey
+	if v := r.URL.Query().Get("recipient"); v == "" {
+		// use topic-based encryption
+		privkey := crypto.Secp256k1PrivateKeyFromBytes(topic[:])
+		recipient = &privkey.PublicKey
+	} else {
+		recipient, err = pss.ParseRecipient(v)
+		if err != nil {
+			s.logger.Debugf("pss mailbox send: recipient: %v", err)
+			s.logger.Error("pss mailbox send: recipient")
+			jsonhttp.BadRequest(w, "invalid recipient")
+			return
+		}
+	}
+
+	var ttl time.Duration
+	if v := r.URL.Query().Get("ttl"); v != "" {
+		seconds, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			s.logger.Debugf("pss mailbox send: ttl: %v", err)
+			s.logger.Error("pss mailbox send: ttl")
+			jsonhttp.BadRequest(w, "invalid ttl")
+			return
+		}
+		ttl = time.Duration(seconds) * time.Second
+	}
+
+	payload, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Debugf("pss mailbox send: read payload: %v", err)
+		s.logger.Error("pss mailbox send: read payload")
+		jsonhttp.InternalServerError(w, nil)
+		return
+	}
+	batch, err := requestPostageBatchId(r)
+	if err != nil {
+		s.logger.Debugf("pss mailbox send: postage batch id: %v", err)
+		s.logger.Error("pss mailbox send: postage batch id")
+		jsonhttp.BadRequest(w, "invalid postage batch id")
+		return
+	}
+	i, err := s.post.GetStampIssuer(batch)
+	if err != nil {
+		s.logger.Debugf("pss mailbox send: postage batch issuer: %v", err)
+		s.logger.Error("pss mailbox send: postage batch issuer")
+		switch {
+		case errors.Is(err, postage.ErrNotFound):
+			jsonhttp.BadRequest(w, "batch not found")
+		case errors.Is(err, postage.ErrNotUsable):
+			jsonhttp.BadRequest(w, "batch not usable yet")
+		default:
+			jsonhttp.BadRequest(w, "postage stamp issuer")
+		}
+		return
+	}
+	stamper := postage.NewStamper(i, s.signer)
+
+	err = s.PssMailbox.Send(r.Context(), topic, payload, stamper, recipient, targets, ttl)
+	if err != nil {
+		s.logger.Debugf("pss mailbox send: topic %s: %v", topicVar, err)
+		s.logger.Error("pss mailbox send")
+		switch {
+		case errors.Is(err, postage.ErrBucketFull):
+			jsonhttp.PaymentRequired(w, "batch is overissued")
+		case errors.Is(err, mailbox.ErrFull):
+			jsonhttp.ServiceUnavailable(w, "mailbox full")
+		case errors.Is(err, mailbox.ErrNoMailbox):
+			jsonhttp.ServiceUnavailable(w, "no mailbox")
+		default:
+			jsonhttp.InternalServerError(w, nil)
+		}
+		return
+	}
+
+	jsonhttp.Created(w, nil)
+}
+
+// pssMailboxGetHandler returns the messages with the topic to the node held
+// in the mailboxes of the targets since the time in unix nanoseconds.
+func (s *server) pssMailboxGetHandler(w http.ResponseWriter, r *http.Request) {
+	topicVar := mux.Vars(r)["topic"]
+	topic := pss.NewTopic(topicVar)
+
+	targets, err := mailbox.ParseTargets(mux.Vars(r)["targets"])
+	if err != nil {
+		s.logger.Debugf("pss mailbox fetch: bad targets: %v", err)
+		s.logger.Error("pss mailbox fetch: bad targets")
+		jsonhttp.BadRequest(w, "invalid targets")
+		return
+	}
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		nanos, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			s.logger.Debugf("pss mailbox fetch: since: %v", err)
+			s.logger.Error("pss mailbox fetch: since")
+			jsonhttp.BadRequest(w, "invalid since")
+			return
+		}
+		since = time.Unix(0, nanos)
+	}
+
+	messages, next, err := s.PssMailbox.Fetch(r.Context(), topic, targets, since)
+	if err != nil {
+		s.logger.Debugf("pss mailbox fetch: topic %s: %v", topicVar, err)
+		s.logger.Error("pss mailbox fetch")
+		if errors.Is(err, mailbox.ErrNoMailbox) {
+			jsonhttp.ServiceUnavailable(w, "no mailbox")
+			return
+		}
+		jsonhttp.InternalServerError(w, nil)
+		return
+	}
+
+	resp := pssMailboxResponse{
+		Messages: make([]pssMailboxMessage, 0, len(messages)),
+	}
+	if !next.IsZero() {
+		resp.Next = next.UnixNano()
+	}
+	for _, m := range messages {
+		resp.Messages = append(resp.Messages, pssMailboxMessage{
+			Payload: m.Payload,
+			Stored:  m.Stored.UnixNano(),
+		})
+	}
+	jsonhttp.OK(w, resp)
+}
@@ -0,0 +1,117 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/api"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/p2p/streamtest"
+	"github.com/ethsana/sana/pkg/postage"
+	mockpost "github.com/ethsana/sana/pkg/postage/mock"
+	"github.com/ethsana/sana/pkg/pss/mailbox"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/topology"
+	"github.com/ethsana/sana/pkg/topology/mock"
+)
+
+type pssMailboxMessage struct {
+	Payload []byte `json:"payload"`
+	Stored  int64  `json:"stored"`
+}
+
+type pssMailboxResponse struct {
+	Messages []pssMailboxMessage `json:"messages"`
+	Next     int64               `json:"next"`
+}
+
+func TestPssMailbox(t *testing.T) {
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validStamp := func(ch swarm.Chunk, _ []byte) (swarm.Chunk, error) { return ch, nil }
+	// the node holds the mailboxes
+	mb, err := mailbox.New(streamtest.New(), mock.NewTopologyDriver(mock.WithClosestPeerErr(topology.ErrWantSelf)), statestore.NewStateStore(), key, validStamp, logging.New(ioutil.Discard, 0), mailbox.Options{Holder: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mb.Close()
+
+	client, _, _ := newTestServer(t, testServerOptions{
+		Post:       mockpost.New(mockpost.WithIssuer(postage.NewStampIssuer("", "", batchOk, big.NewInt(3), 11, 10, 1000, true))),
+		PssMailbox: mb,
+	})
+
+	send := func(t *testing.T, message string) {
+		t.Helper()
+		jsonhttptest.Request(t, client, http.MethodPost, "/pss/mailbox/mailtopic/12?ttl=3600", http.StatusCreated,
+			jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+			jsonhttptest.WithRequestBody(bytes.NewReader([]byte(message))),
+		)
+	}
+	fetch := func(t *testing.T, since int64) (resp pssMailboxResponse) {
+		t.Helper()
+		jsonhttptest.Request(t, client, http.MethodGet, "/pss/mailbox/mailtopic/12?since="+strconv.FormatInt(since, 10), http.StatusOK,
+			jsonhttptest.WithUnmarshalJSONResponse(&resp),
+		)
+		return resp
+	}
+
+	send(t, "first")
+	resp := fetch(t, 0)
+	if len(resp.Messages) != 1 || string(resp.Messages[0].Payload) != "first" || resp.Next != resp.Messages[0].Stored {
+		t.Fatalf("got response %+v, want the first message", resp)
+	}
+
+	send(t, "second")
+	resp = fetch(t, resp.Next)
+	if len(resp.Messages) != 1 || string(resp.Messages[0].Payload) != "second" {
+		t.Fatalf("got response %+v, want the second message", resp)
+	}
+
+	// no messages are returned as an empty list
+	next := resp.Next
+	resp = fetch(t, next)
+	if resp.Next != next {
+		t.Fatalf("got next %d, want %d", resp.Next, next)
+	}
+	if b, err := json.Marshal(resp.Messages); err != nil || string(b) != "[]" {
+		t.Fatalf("got messages %s, want []", b)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		url    string
+		error  string
+	}{
+		{name: "invalid targets", method: http.MethodGet, url: "/pss/mailbox/mailtopic/123456", error: "invalid targets"},
+		{name: "invalid since", method: http.MethodGet, url: "/pss/mailbox/mailtopic/12?since=x", error: "invalid since"},
+		{name: "invalid ttl", method: http.MethodPost, url: "/pss/mailbox/mailtopic/12?ttl=-1", error: "invalid ttl"},
+		{name: "invalid recipient", method: http.MethodPost, url: "/pss/mailbox/mailtopic/12?recipient=zz", error: "invalid recipient"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			jsonhttptest.Request(t, client, tc.method, tc.url, http.StatusBadRequest,
+				jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+				jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+					Message: tc.error,
+					Code:    http.StatusBadRequest,
+				}),
+			)
+		})
+	}
+}
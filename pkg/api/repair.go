@@ -0,0 +1,50 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/repair"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/gorilla/mux"
+)
+
+type repairResponse struct {
+	Reference swarm.Address `json:"reference"`
+	repair.Report
+}
+
+// repairHandler probes the network for all chunks of the content with the
+// reference and pushes the local copies of the missing ones.
+func (s *server) repairHandler(w http.ResponseWriter, r *http.Request) {
+	nameOrHex := mux.Vars(r)["address"]
+	address, err := s.resolveNameOrAddress(nameOrHex)
+	if err != nil {
+		s.logger.Debugf("repair: parse address %s: %v", nameOrHex, err)
+		s.logger.Error("repair: parse address")
+		jsonhttp.NotFound(w, nil)
+		return
+	}
+
+	report, err := s.Repair.Repair(r.Context(), address)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		jsonhttp.NotFound(w, "content not stored locally")
+		return
+	case err != nil:
+		s.logger.Debugf("repair: repair %s: %v", address, err)
+		s.logger.Error("repair: repair")
+		jsonhttp.InternalServerError(w, nil)
+		return
+	}
+	jsonhttp.OK(w, repairResponse{
+		Reference: address,
+		Report:    *report,
+	})
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/api"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
+	pinning "github.com/ethsana/sana/pkg/pinning/mock"
+	mockpost "github.com/ethsana/sana/pkg/postage/mock"
+	"github.com/ethsana/sana/pkg/pushsync"
+	pushsyncmock "github.com/ethsana/sana/pkg/pushsync/mock"
+	"github.com/ethsana/sana/pkg/repair"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/swarm/test"
+	"github.com/ethsana/sana/pkg/tags"
+	"github.com/ethsana/sana/pkg/traversal"
+)
+
+// lostNetwork retrieves no chunks.
+type lostNetwork struct{}
+
+func (lostNetwork) RetrieveChunk(context.Context, swarm.Address, bool) (swarm.Chunk, error) {
+	return nil, storage.ErrNotFound
+}
+
+func TestRepair(t *testing.T) {
+	var (
+		storer = mock.NewStorer()
+		logger = logging.New(ioutil.Discard, 0)
+		pushed = make(chan swarm.Address, 1)
+	)
+	push := pushsyncmock.New(func(_ context.Context, ch swarm.Chunk) (*pushsync.Receipt, error) {
+		pushed <- ch.Address()
+		return &pushsync.Receipt{Address: ch.Address()}, nil
+	})
+	service := repair.New(pinning.NewServiceMock(), storer, traversal.New(storer), lostNetwork{}, push, nil, nil, logger, repair.Options{})
+	client, _, _ := newTestServer(t, testServerOptions{
+		Storer: storer,
+		Tags:   tags.NewTags(statestore.NewStateStore(), logger),
+		Logger: logger,
+		Post:   mockpost.New(mockpost.WithAcceptAll()),
+		Repair: service,
+	})
+
+	var resp api.BytesPostResponse
+	jsonhttptest.Request(t, client, http.MethodPost, "/bytes", http.StatusCreated,
+		jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+		jsonhttptest.WithRequestBody(bytes.NewReader([]byte("lost content"))),
+		jsonhttptest.WithUnmarshalJSONResponse(&resp),
+	)
+
+	jsonhttptest.Request(t, client, http.MethodPost, "/repair/"+resp.Reference.String(), http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(api.RepairResponse{
+			Reference: resp.Reference,
+			Report:    repair.Report{Chunks: 1, Missing: 1, Repaired: 1},
+		}),
+	)
+	if got := <-pushed; !got.Equal(resp.Reference) {
+		t.Fatalf("got pushed chunk %s, want %s", got, resp.Reference)
+	}
+
+	jsonhttptest.Request(t, client, http.MethodPost, "/repair/"+test.RandomAddress().String(), http.StatusNotFound,
+		jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+			Message: "content not stored locally",
+			Code:    http.StatusNotFound,
+		}),
+	)
+}
@@ -46,6 +46,8 @@ func (s *server) setupRouting() {
 	handle("/bytes", jsonhttp.MethodHandler{
 		"POST": web.ChainHandlers(
 			s.newTracingHandler("bytes-upload"),
+			s.uploadPolicyHandler,
+			s.uploadScanHandler,
 			web.FinalHandlerFunc(s.bytesUploadHandler),
 		),
 	})
@@ -56,9 +58,28 @@ func (s *server) setupRouting() {
 		),
 	})
 
+	if s.UploadSessions != nil {
+		handle("/uploads", jsonhttp.MethodHandler{
+			"POST": web.ChainHandlers(
+				s.uploadPolicyHandler,
+				web.FinalHandlerFunc(s.uploadSessionCreateHandler),
+			),
+		})
+		handle("/uploads/{id}", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.uploadSessionGetHandler),
+			"PATCH": web.ChainHandlers(
+				s.newTracingHandler("upload-session-patch"),
+				s.uploadPolicyHandler,
+				web.FinalHandlerFunc(s.uploadSessionPatchHandler),
+			),
+			"DELETE": http.HandlerFunc(s.uploadSessionDeleteHandler),
+		})
+	}
+
 	handle("/chunks", jsonhttp.MethodHandler{
 		"POST": web.ChainHandlers(
 			jsonhttp.NewMaxBodyBytesHandler(swarm.ChunkWithSpanSize),
+			s.uploadPolicyHandler,
 			web.FinalHandlerFunc(s.chunkUploadHandler),
 		),
 	})
@@ -67,9 +88,23 @@ func (s *server) setupRouting() {
 		"GET": http.HandlerFunc(s.chunkGetHandler),
 	})
 
+	if s.Stamping != nil {
+		handle("/stamping/chunks", jsonhttp.MethodHandler{
+			"POST": web.ChainHandlers(
+				jsonhttp.NewMaxBodyBytesHandler(swarm.ChunkWithSpanSize),
+				s.uploadPolicyHandler,
+				web.FinalHandlerFunc(s.stampingChunkUploadHandler),
+			),
+		})
+		handle("/stamping/quota", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.stampingQuotaHandler),
+		})
+	}
+
 	handle("/soc/{owner}/{id}", jsonhttp.MethodHandler{
 		"POST": web.ChainHandlers(
 			jsonhttp.NewMaxBodyBytesHandler(swarm.ChunkWithSpanSize),
+			s.uploadPolicyHandler,
 			web.FinalHandlerFunc(s.socUploadHandler),
 		),
 	})
@@ -78,6 +113,7 @@ func (s *server) setupRouting() {
 		"GET": http.HandlerFunc(s.feedGetHandler),
 		"POST": web.ChainHandlers(
 			jsonhttp.NewMaxBodyBytesHandler(swarm.ChunkWithSpanSize),
+			s.uploadPolicyHandler,
 			web.FinalHandlerFunc(s.feedPostHandler),
 		),
 	})
@@ -85,6 +121,8 @@ func (s *server) setupRouting() {
 	handle("/bzz", jsonhttp.MethodHandler{
 		"POST": web.ChainHandlers(
 			s.newTracingHandler("bzz-upload"),
+			s.uploadPolicyHandler,
+			s.uploadScanHandler,
 			web.FinalHandlerFunc(s.bzzUploadHandler),
 		),
 	})
@@ -100,10 +138,48 @@ func (s *server) setupRouting() {
 		),
 		"PATCH": web.ChainHandlers(
 			s.newTracingHandler("bzz-patch"),
+			s.uploadPolicyHandler,
+			s.uploadScanHandler,
 			web.FinalHandlerFunc(s.bzzPatchHandler),
 		),
 	})
 
+	handle("/checksums/{address}", jsonhttp.MethodHandler{
+		"GET": web.ChainHandlers(
+			s.newTracingHandler("checksums-download"),
+			web.FinalHandlerFunc(s.checksumsHandler),
+		),
+	})
+
+	if s.S3Buckets != nil {
+		s.s3Routes(handle, "/s3")
+		s.setupS3Gateway()
+	}
+
+	if s.Takedown != nil {
+		handle("/takedown/notices", jsonhttp.MethodHandler{
+			"POST": web.ChainHandlers(
+				jsonhttp.NewMaxBodyBytesHandler(takedownNoticeMaxRequestSize),
+				web.FinalHandlerFunc(s.takedownNoticeHandler),
+			),
+		})
+		handle("/takedown/counter-notices", jsonhttp.MethodHandler{
+			"POST": web.ChainHandlers(
+				jsonhttp.NewMaxBodyBytesHandler(takedownNoticeMaxRequestSize),
+				web.FinalHandlerFunc(s.takedownCounterNoticeHandler),
+			),
+		})
+	}
+
+	if s.Repair != nil {
+		handle("/repair/{address}", web.ChainHandlers(
+			s.gatewayModeForbidEndpointHandler,
+			web.FinalHandler(jsonhttp.MethodHandler{
+				"POST": http.HandlerFunc(s.repairHandler),
+			})),
+		)
+	}
+
 	handle("/pss/send/{topic}/{targets}", web.ChainHandlers(
 		s.gatewayModeForbidEndpointHandler,
 		web.FinalHandler(jsonhttp.MethodHandler{
@@ -119,6 +195,19 @@ func (s *server) setupRouting() {
 		web.FinalHandlerFunc(s.pssWsHandler),
 	))
 
+	if s.PssMailbox != nil {
+		handle("/pss/mailbox/{topic}/{targets}", web.ChainHandlers(
+			s.gatewayModeForbidEndpointHandler,
+			web.FinalHandler(jsonhttp.MethodHandler{
+				"GET": http.HandlerFunc(s.pssMailboxGetHandler),
+				"POST": web.ChainHandlers(
+					jsonhttp.NewMaxBodyBytesHandler(swarm.ChunkSize),
+					web.FinalHandlerFunc(s.pssMailboxPostHandler),
+				),
+			})),
+		)
+	}
+
 	handle("/tags", web.ChainHandlers(
 		s.gatewayModeForbidEndpointHandler,
 		web.FinalHandler(jsonhttp.MethodHandler{
@@ -167,7 +256,7 @@ func (s *server) setupRouting() {
 				if o := r.Header.Get("Origin"); o != "" && s.checkOrigin(r) {
 					w.Header().Set("Access-Control-Allow-Credentials", "true")
 					w.Header().Set("Access-Control-Allow-Origin", o)
-					w.Header().Set("Access-Control-Allow-Headers", "Origin, Accept, Authorization, Content-Type, X-Requested-With, Access-Control-Request-Headers, Access-Control-Request-Method, Swarm-Tag, Swarm-Pin, Swarm-Encrypt, Swarm-Index-Document, Swarm-Error-Document, Swarm-Collection, Swarm-Postage-Batch-Id, Gas-Price")
+					w.Header().Set("Access-Control-Allow-Headers", "Origin, Accept, Authorization, Content-Type, X-Requested-With, Access-Control-Request-Headers, Access-Control-Request-Method, Swarm-Tag, Swarm-Pin, Swarm-Encrypt, Swarm-Index-Document, Swarm-Error-Document, Swarm-Collection, Swarm-Postage-Batch-Id, Swarm-Stamping-Token, Swarm-Api-Key, Gas-Price")
 					w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS, POST, PUT, DELETE")
 					w.Header().Set("Access-Control-Max-Age", "3600")
 				}
@@ -188,6 +277,7 @@ func (s *server) setupRouting() {
 				h.ServeHTTP(w, r)
 			})
 		},
+		s.gatewayLimitsHandler,
 		s.gatewayModeForbidHeadersHandler,
 		web.FinalHandler(router),
 	)
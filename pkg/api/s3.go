@@ -0,0 +1,547 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethersphere/langos"
+	"github.com/ethsana/sana/pkg/file"
+	"github.com/ethsana/sana/pkg/file/joiner"
+	"github.com/ethsana/sana/pkg/file/loadsave"
+	"github.com/ethsana/sana/pkg/file/pipeline/builder"
+	"github.com/ethsana/sana/pkg/manifest"
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/s3"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/tracing"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// SwarmBucketReferenceHeader is set on S3 responses which modify a bucket
+	// to the new root reference of the bucket manifest.
+	SwarmBucketReferenceHeader = "Swarm-Bucket-Reference"
+
+	s3Owner          = "sana"
+	contentTypeXML   = "application/xml"
+	s3DefaultObjType = "application/octet-stream"
+)
+
+// s3Respond writes the value as an XML response.
+func s3Respond(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set(contentTypeHeader, contentTypeXML)
+	w.WriteHeader(statusCode)
+	_, _ = io.WriteString(w, xml.Header)
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+// s3Error writes an S3 error response.
+func s3Error(w http.ResponseWriter, r *http.Request, code, message string) {
+	e := s3.Error{
+		Code:     code,
+		Message:  message,
+		Resource: r.URL.Path,
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(e.StatusCode())
+		return
+	}
+	s3Respond(w, e.StatusCode(), e)
+}
+
+// s3Putter returns the putter which stamps chunks with the postage batch
+// from the request header or the configured default batch.
+func (s *server) s3Putter(r *http.Request) (storage.Storer, string, error) {
+	batch := s.S3PostageBatch
+	if r.Header.Get(SwarmPostageBatchIdHeader) != "" || batch == nil {
+		var err error
+		if batch, err = requestPostageBatchId(r); err != nil {
+			return nil, s3.ErrCodeInvalidArgument, err
+		}
+	}
+
+	putter, err := newStamperPutter(s.storer, s.post, s.signer, batch)
+	if err != nil {
+		switch {
+		case errors.Is(err, postage.ErrNotFound):
+			return nil, s3.ErrCodeInvalidRequest, errors.New("batch not found")
+		case errors.Is(err, postage.ErrNotUsable):
+			return nil, s3.ErrCodeInvalidRequest, errors.New("batch not usable yet")
+		default:
+			return nil, s3.ErrCodeInternalError, err
+		}
+	}
+	return putter, "", nil
+}
+
+// s3Manifest loads the manifest of the bucket, or creates a new one if the
+// bucket has no objects.
+func s3Manifest(ls file.LoadSaver, b s3.Bucket) (manifest.Interface, error) {
+	if b.Reference.IsZero() {
+		return manifest.NewDefaultManifest(ls, false)
+	}
+	return manifest.NewDefaultManifestReference(b.Reference, ls)
+}
+
+// s3UpdateBucket applies fn to the bucket manifest and stores the resulting
+// root reference. Bucket updates are serialized.
+func (s *server) s3UpdateBucket(ctx context.Context, name string, putter storage.Storer, fn func(manifest.Interface) error) (s3.Bucket, error) {
+	s.s3Mu.Lock()
+	defer s.s3Mu.Unlock()
+
+	b, err := s.S3Buckets.Get(name)
+	if err != nil {
+		return s3.Bucket{}, err
+	}
+
+	m, err := s3Manifest(loadsave.New(putter, storage.ModePutUpload, false), b)
+	if err != nil {
+		return s3.Bucket{}, fmt.Errorf("load manifest: %w", err)
+	}
+	if err := fn(m); err != nil {
+		return s3.Bucket{}, err
+	}
+	b.Reference, err = m.Store(ctx)
+	if err != nil {
+		return s3.Bucket{}, fmt.Errorf("store manifest: %w", err)
+	}
+	if err := s.S3Buckets.Put(b); err != nil {
+		return s3.Bucket{}, fmt.Errorf("put bucket: %w", err)
+	}
+	return b, nil
+}
+
+// s3BucketError writes the error response for errors of bucket operations.
+func (s *server) s3BucketError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	logger := tracing.NewLoggerWithTraceID(r.Context(), s.logger)
+
+	switch {
+	case errors.Is(err, s3.ErrBucketNotFound):
+		s3Error(w, r, s3.ErrCodeNoSuchBucket, "The specified bucket does not exist")
+	case errors.Is(err, manifest.ErrNotFound):
+		s3Error(w, r, s3.ErrCodeNoSuchKey, "The specified key does not exist")
+	case errors.Is(err, postage.ErrBucketFull):
+		s3Error(w, r, s3.ErrCodeInvalidRequest, "batch is overissued")
+	case errors.Is(err, s3.ErrContentSHA256Mismatch):
+		s3Error(w, r, s3.ErrCodeContentSHA256Mismatch, "The provided x-amz-content-sha256 header does not match what was computed")
+	default:
+		logger.Debugf("s3 %s: %v", op, err)
+		logger.Errorf("s3 %s", op)
+		s3Error(w, r, s3.ErrCodeInternalError, "internal error")
+	}
+}
+
+func (s *server) s3ListBucketsHandler(w http.ResponseWriter, r *http.Request) {
+	buckets, err := s.S3Buckets.List()
+	if err != nil {
+		s.s3BucketError(w, r, "list buckets", err)
+		return
+	}
+	s3Respond(w, http.StatusOK, s3.NewListAllMyBucketsResult(s3Owner, buckets))
+}
+
+func (s *server) s3CreateBucketHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["bucket"]
+	if !s3.ValidBucketName(name) {
+		s3Error(w, r, s3.ErrCodeInvalidBucketName, "The specified bucket is not valid")
+		return
+	}
+
+	s.s3Mu.Lock()
+	defer s.s3Mu.Unlock()
+
+	if _, err := s.S3Buckets.Get(name); err == nil {
+		s3Error(w, r, s3.ErrCodeBucketAlreadyExists, "The bucket already exists")
+		return
+	} else if !errors.Is(err, s3.ErrBucketNotFound) {
+		s.s3BucketError(w, r, "create bucket", err)
+		return
+	}
+
+	err := s.S3Buckets.Put(s3.Bucket{
+		Name:      name,
+		Reference: swarm.ZeroAddress,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		s.s3BucketError(w, r, "create bucket", err)
+		return
+	}
+	w.Header().Set("Location", "/"+name)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *server) s3HeadBucketHandler(w http.ResponseWriter, r *http.Request) {
+	b, err := s.S3Buckets.Get(mux.Vars(r)["bucket"])
+	if err != nil {
+		s.s3BucketError(w, r, "head bucket", err)
+		return
+	}
+	if !b.Reference.IsZero() {
+		w.Header().Set(SwarmBucketReferenceHeader, b.Reference.String())
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *server) s3DeleteBucketHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["bucket"]
+
+	s.s3Mu.Lock()
+	defer s.s3Mu.Unlock()
+
+	b, err := s.S3Buckets.Get(name)
+	if err != nil {
+		s.s3BucketError(w, r, "delete bucket", err)
+		return
+	}
+	objects, err := s.s3Objects(r.Context(), b)
+	if err != nil {
+		s.s3BucketError(w, r, "delete bucket", err)
+		return
+	}
+	if len(objects) > 0 {
+		s3Error(w, r, s3.ErrCodeBucketNotEmpty, "The bucket you tried to delete is not empty")
+		return
+	}
+	if err := s.S3Buckets.Delete(name); err != nil {
+		s.s3BucketError(w, r, "delete bucket", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// s3Objects returns all objects of the bucket sorted by key.
+func (s *server) s3Objects(ctx context.Context, b s3.Bucket) ([]s3.Object, error) {
+	if b.Reference.IsZero() {
+		return nil, nil
+	}
+
+	m, err := s3Manifest(s.manifestLoadSaver(), b)
+	if err != nil {
+		return nil, fmt.Errorf("load manifest: %w", err)
+	}
+
+	var objects []s3.Object
+	err = m.IterateEntries(ctx, func(key string, e manifest.Entry) error {
+		mtdt := e.Metadata()
+		size, _ := strconv.ParseInt(mtdt[s3.EntryMetadataSizeKey], 10, 64)
+		objects = append(objects, s3.Object{
+			Key:          key,
+			LastModified: mtdt[s3.EntryMetadataLastModifiedKey],
+			ETag:         strconv.Quote(mtdt[s3.EntryMetadataETagKey]),
+			Size:         size,
+			StorageClass: "STANDARD",
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	return objects, nil
+}
+
+// s3Listing is a page of the objects of a bucket listing.
+type s3Listing struct {
+	contents       []s3.Object
+	commonPrefixes []s3.CommonPrefix
+	truncated      bool
+	// next is the key or common prefix after which the next page starts
+	next string
+}
+
+// s3List returns the page of at most maxKeys objects with the prefix and
+// keys after the marker. The keys containing the delimiter after the prefix
+// are rolled up into a single common prefix.
+func s3List(objects []s3.Object, prefix, delimiter, marker string, maxKeys int) (l s3Listing) {
+	var last string
+	for _, o := range objects {
+		if !strings.HasPrefix(o.Key, prefix) {
+			continue
+		}
+
+		commonPrefix := ""
+		if delimiter != "" {
+			if i := strings.Index(o.Key[len(prefix):], delimiter); i >= 0 {
+				commonPrefix = o.Key[:len(prefix)+i+len(delimiter)]
+			}
+		}
+
+		name := o.Key
+		if commonPrefix != "" {
+			name = commonPrefix
+		}
+		if name <= marker || (commonPrefix != "" && commonPrefix == last) {
+			continue
+		}
+		if len(l.contents)+len(l.commonPrefixes) == maxKeys {
+			l.truncated = true
+			l.next = last
+			break
+		}
+
+		if commonPrefix != "" {
+			l.commonPrefixes = append(l.commonPrefixes, s3.CommonPrefix{Prefix: commonPrefix})
+		} else {
+			l.contents = append(l.contents, o)
+		}
+		last = name
+	}
+	return l
+}
+
+func (s *server) s3ListObjectsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	maxKeys := s3.MaxKeys
+	if v := query.Get("max-keys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			s3Error(w, r, s3.ErrCodeInvalidArgument, "invalid max-keys")
+			return
+		}
+		if n < maxKeys {
+			maxKeys = n
+		}
+	}
+
+	// the continuation tokens of ListObjectsV2 are the encoded keys after
+	// which the listing continues
+	v2 := query.Get("list-type") == "2"
+	marker := query.Get("marker")
+	if v2 {
+		marker = query.Get("start-after")
+		if token := query.Get("continuation-token"); token != "" {
+			b, err := base64.RawURLEncoding.DecodeString(token)
+			if err != nil {
+				s3Error(w, r, s3.ErrCodeInvalidArgument, "The continuation token provided is incorrect")
+				return
+			}
+			marker = string(b)
+		}
+	}
+
+	b, err := s.S3Buckets.Get(mux.Vars(r)["bucket"])
+	if err != nil {
+		s.s3BucketError(w, r, "list objects", err)
+		return
+	}
+	objects, err := s.s3Objects(r.Context(), b)
+	if err != nil {
+		s.s3BucketError(w, r, "list objects", err)
+		return
+	}
+
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	l := s3List(objects, prefix, delimiter, marker, maxKeys)
+
+	if v2 {
+		result := s3.ListBucketResultV2{
+			Xmlns:             s3.XMLNamespace,
+			Name:              b.Name,
+			Prefix:            prefix,
+			StartAfter:        query.Get("start-after"),
+			ContinuationToken: query.Get("continuation-token"),
+			KeyCount:          len(l.contents) + len(l.commonPrefixes),
+			MaxKeys:           maxKeys,
+			Delimiter:         delimiter,
+			IsTruncated:       l.truncated,
+			Contents:          l.contents,
+			CommonPrefixes:    l.commonPrefixes,
+		}
+		if l.truncated {
+			result.NextContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(l.next))
+		}
+		s3Respond(w, http.StatusOK, result)
+		return
+	}
+
+	s3Respond(w, http.StatusOK, s3.ListBucketResult{
+		Xmlns:          s3.XMLNamespace,
+		Name:           b.Name,
+		Prefix:         prefix,
+		Marker:         marker,
+		NextMarker:     l.next,
+		MaxKeys:        maxKeys,
+		Delimiter:      delimiter,
+		IsTruncated:    l.truncated,
+		Contents:       l.contents,
+		CommonPrefixes: l.commonPrefixes,
+	})
+}
+
+// s3CountWriter counts the bytes written to it.
+type s3CountWriter int64
+
+func (c *s3CountWriter) Write(p []byte) (int, error) {
+	*c += s3CountWriter(len(p))
+	return len(p), nil
+}
+
+func (s *server) s3PutObjectHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name, key := mux.Vars(r)["bucket"], mux.Vars(r)["key"]
+
+	if r.Header.Get("X-Amz-Copy-Source") != "" {
+		s3Error(w, r, s3.ErrCodeNotImplemented, "CopyObject is not implemented")
+		return
+	}
+	if _, err := s.S3Buckets.Get(name); err != nil {
+		s.s3BucketError(w, r, "put object", err)
+		return
+	}
+
+	putter, code, err := s.s3Putter(r)
+	if err != nil {
+		s3Error(w, r, code, err.Error())
+		return
+	}
+
+	// the object data is stored before the bucket is locked
+	var (
+		h    = md5.New()
+		size s3CountWriter
+	)
+	pipe := builder.NewPipelineBuilder(ctx, putter, storage.ModePutUpload, false)
+	reference, err := builder.FeedPipeline(ctx, pipe, io.TeeReader(r.Body, io.MultiWriter(h, &size)))
+	if err != nil {
+		s.s3BucketError(w, r, "put object", err)
+		return
+	}
+	etag := hex.EncodeToString(h.Sum(nil))
+
+	contentType := r.Header.Get(contentTypeHeader)
+	if contentType == "" {
+		contentType = s3DefaultObjType
+	}
+	mtdt := map[string]string{
+		manifest.EntryMetadataContentTypeKey: contentType,
+		manifest.EntryMetadataFilenameKey:    path.Base(key),
+		s3.EntryMetadataETagKey:              etag,
+		s3.EntryMetadataSizeKey:              strconv.FormatInt(int64(size), 10),
+		s3.EntryMetadataLastModifiedKey:      s3.FormatTime(time.Now()),
+	}
+
+	b, err := s.s3UpdateBucket(ctx, name, putter, func(m manifest.Interface) error {
+		return m.Add(ctx, key, manifest.NewEntry(reference, mtdt))
+	})
+	if err != nil {
+		s.s3BucketError(w, r, "put object", err)
+		return
+	}
+
+	w.Header().Set("ETag", strconv.Quote(etag))
+	w.Header().Set(SwarmBucketReferenceHeader, b.Reference.String())
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *server) s3GetObjectHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name, key := mux.Vars(r)["bucket"], mux.Vars(r)["key"]
+
+	b, err := s.S3Buckets.Get(name)
+	if err != nil {
+		s.s3BucketError(w, r, "get object", err)
+		return
+	}
+	if b.Reference.IsZero() {
+		s.s3BucketError(w, r, "get object", manifest.ErrNotFound)
+		return
+	}
+
+	m, err := s3Manifest(s.manifestLoadSaver(), b)
+	if err != nil {
+		s.s3BucketError(w, r, "get object", err)
+		return
+	}
+	e, err := m.Lookup(ctx, key)
+	if err != nil {
+		s.s3BucketError(w, r, "get object", err)
+		return
+	}
+	if e.Reference().IsZero() {
+		s.s3BucketError(w, r, "get object", manifest.ErrNotFound)
+		return
+	}
+	if s.takenDown(w, e.Reference()) {
+		return
+	}
+
+	reader, l, err := joiner.New(ctx, s.storer, e.Reference())
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			err = manifest.ErrNotFound
+		}
+		s.s3BucketError(w, r, "get object", err)
+		return
+	}
+
+	mtdt := e.Metadata()
+	modTime, _ := time.Parse(s3.TimeFormat, mtdt[s3.EntryMetadataLastModifiedKey])
+	if ct, ok := mtdt[manifest.EntryMetadataContentTypeKey]; ok {
+		w.Header().Set(contentTypeHeader, ct)
+	}
+	if etag, ok := mtdt[s3.EntryMetadataETagKey]; ok {
+		w.Header().Set("ETag", strconv.Quote(etag))
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(l, 10))
+	w.Header().Set(SwarmBucketReferenceHeader, b.Reference.String())
+	http.ServeContent(w, r, "", modTime, langos.NewBufferedLangos(reader, lookaheadBufferSize(l)))
+}
+
+func (s *server) s3DeleteObjectHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name, key := mux.Vars(r)["bucket"], mux.Vars(r)["key"]
+
+	b, err := s.S3Buckets.Get(name)
+	if err != nil {
+		s.s3BucketError(w, r, "delete object", err)
+		return
+	}
+	if b.Reference.IsZero() {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	putter, code, err := s.s3Putter(r)
+	if err != nil {
+		s3Error(w, r, code, err.Error())
+		return
+	}
+
+	b, err = s.s3UpdateBucket(ctx, name, putter, func(m manifest.Interface) error {
+		return m.Remove(ctx, key)
+	})
+	if err != nil {
+		// deleting a missing object is not an error in S3
+		if errors.Is(err, manifest.ErrNotFound) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		s.s3BucketError(w, r, "delete object", err)
+		return
+	}
+
+	w.Header().Set(SwarmBucketReferenceHeader, b.Reference.String())
+	w.WriteHeader(http.StatusNoContent)
+}
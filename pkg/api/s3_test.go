@@ -0,0 +1,301 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/api"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
+	mockpost "github.com/ethsana/sana/pkg/postage/mock"
+	"github.com/ethsana/sana/pkg/s3"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/tags"
+	"resenje.org/web"
+)
+
+func TestS3(t *testing.T) {
+	var (
+		storer         = mock.NewStorer()
+		mockStatestore = statestore.NewStateStore()
+		logger         = logging.New(ioutil.Discard, 0)
+		client, _, _   = newTestServer(t, testServerOptions{
+			Storer:         storer,
+			Tags:           tags.NewTags(mockStatestore, logger),
+			Logger:         logger,
+			Post:           mockpost.New(mockpost.WithAcceptAll()),
+			S3Buckets:      s3.NewBuckets(mockStatestore),
+			S3PostageBatch: batchOk,
+		})
+		objects = map[string][]byte{
+			"a.txt":          []byte("first object"),
+			"docs/b.txt":     []byte("second object"),
+			"docs/sub/c.txt": []byte("third object"),
+		}
+	)
+
+	unmarshalXML := func(t *testing.T, b []byte, v interface{}) {
+		t.Helper()
+		if err := xml.Unmarshal(b, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectError := func(t *testing.T, method, url string, statusCode int, code string) {
+		t.Helper()
+		var b []byte
+		jsonhttptest.Request(t, client, method, url, statusCode,
+			jsonhttptest.WithPutResponseBody(&b),
+		)
+		var e s3.Error
+		unmarshalXML(t, b, &e)
+		if e.Code != code {
+			t.Fatalf("got error code %q, want %q", e.Code, code)
+		}
+	}
+
+	t.Run("no such bucket", func(t *testing.T) {
+		expectError(t, http.MethodGet, "/s3/photos", http.StatusNotFound, s3.ErrCodeNoSuchBucket)
+		expectError(t, http.MethodPut, "/s3/photos/a.txt", http.StatusNotFound, s3.ErrCodeNoSuchBucket)
+	})
+
+	t.Run("invalid bucket name", func(t *testing.T) {
+		expectError(t, http.MethodPut, "/s3/Photos", http.StatusBadRequest, s3.ErrCodeInvalidBucketName)
+	})
+
+	t.Run("create bucket", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodPut, "/s3/photos", http.StatusOK)
+		expectError(t, http.MethodPut, "/s3/photos", http.StatusConflict, s3.ErrCodeBucketAlreadyExists)
+
+		var b []byte
+		jsonhttptest.Request(t, client, http.MethodGet, "/s3", http.StatusOK,
+			jsonhttptest.WithPutResponseBody(&b),
+		)
+		var result s3.ListAllMyBucketsResult
+		unmarshalXML(t, b, &result)
+		if len(result.Buckets) != 1 || result.Buckets[0].Name != "photos" {
+			t.Fatalf("unexpected buckets %+v", result.Buckets)
+		}
+	})
+
+	var reference swarm.Address
+	t.Run("put object", func(t *testing.T) {
+		for key, data := range objects {
+			h := jsonhttptest.Request(t, client, http.MethodPut, "/s3/photos/"+key, http.StatusOK,
+				jsonhttptest.WithRequestBody(bytes.NewReader(data)),
+				jsonhttptest.WithRequestHeader("Content-Type", "text/plain"),
+			)
+			sum := md5.Sum(data)
+			if got, want := h.Get("ETag"), strconv.Quote(hex.EncodeToString(sum[:])); got != want {
+				t.Fatalf("got etag %s, want %s", got, want)
+			}
+			var err error
+			reference, err = swarm.ParseHexAddress(h.Get(api.SwarmBucketReferenceHeader))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("get object", func(t *testing.T) {
+		h := jsonhttptest.Request(t, client, http.MethodGet, "/s3/photos/docs/b.txt", http.StatusOK,
+			jsonhttptest.WithExpectedResponse(objects["docs/b.txt"]),
+		)
+		if ct := h.Get("Content-Type"); ct != "text/plain" {
+			t.Fatalf("got content type %q", ct)
+		}
+		expectError(t, http.MethodGet, "/s3/photos/missing.txt", http.StatusNotFound, s3.ErrCodeNoSuchKey)
+	})
+
+	t.Run("bucket manifest", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodGet, "/bzz/"+reference.String()+"/a.txt", http.StatusOK,
+			jsonhttptest.WithExpectedResponse(objects["a.txt"]),
+		)
+	})
+
+	t.Run("list objects", func(t *testing.T) {
+		var b []byte
+		jsonhttptest.Request(t, client, http.MethodGet, "/s3/photos?delimiter=/", http.StatusOK,
+			jsonhttptest.WithPutResponseBody(&b),
+		)
+		var result s3.ListBucketResult
+		unmarshalXML(t, b, &result)
+		if len(result.Contents) != 1 || result.Contents[0].Key != "a.txt" {
+			t.Fatalf("unexpected contents %+v", result.Contents)
+		}
+		if result.Contents[0].Size != int64(len(objects["a.txt"])) {
+			t.Fatalf("got size %d, want %d", result.Contents[0].Size, len(objects["a.txt"]))
+		}
+		if len(result.CommonPrefixes) != 1 || result.CommonPrefixes[0].Prefix != "docs/" {
+			t.Fatalf("unexpected common prefixes %+v", result.CommonPrefixes)
+		}
+
+		jsonhttptest.Request(t, client, http.MethodGet, "/s3/photos?prefix=docs/&max-keys=1", http.StatusOK,
+			jsonhttptest.WithPutResponseBody(&b),
+		)
+		result = s3.ListBucketResult{}
+		unmarshalXML(t, b, &result)
+		if len(result.Contents) != 1 || result.Contents[0].Key != "docs/b.txt" || !result.IsTruncated {
+			t.Fatalf("unexpected truncated listing %+v", result)
+		}
+
+		jsonhttptest.Request(t, client, http.MethodGet, "/s3/photos?prefix=docs/&marker="+result.NextMarker, http.StatusOK,
+			jsonhttptest.WithPutResponseBody(&b),
+		)
+		result = s3.ListBucketResult{}
+		unmarshalXML(t, b, &result)
+		if len(result.Contents) != 1 || result.Contents[0].Key != "docs/sub/c.txt" || result.IsTruncated {
+			t.Fatalf("unexpected listing after marker %+v", result)
+		}
+	})
+
+	t.Run("list objects v2", func(t *testing.T) {
+		var b []byte
+		jsonhttptest.Request(t, client, http.MethodGet, "/s3/photos?list-type=2&max-keys=2", http.StatusOK,
+			jsonhttptest.WithPutResponseBody(&b),
+		)
+		var result s3.ListBucketResultV2
+		unmarshalXML(t, b, &result)
+		if result.KeyCount != 2 || result.Contents[1].Key != "docs/b.txt" || !result.IsTruncated || result.NextContinuationToken == "" {
+			t.Fatalf("unexpected truncated listing %+v", result)
+		}
+
+		jsonhttptest.Request(t, client, http.MethodGet, "/s3/photos?list-type=2&continuation-token="+result.NextContinuationToken, http.StatusOK,
+			jsonhttptest.WithPutResponseBody(&b),
+		)
+		result = s3.ListBucketResultV2{}
+		unmarshalXML(t, b, &result)
+		if result.KeyCount != 1 || result.Contents[0].Key != "docs/sub/c.txt" || result.IsTruncated {
+			t.Fatalf("unexpected listing after continuation token %+v", result)
+		}
+
+		jsonhttptest.Request(t, client, http.MethodGet, "/s3/photos?list-type=2&start-after=a.txt&delimiter=/", http.StatusOK,
+			jsonhttptest.WithPutResponseBody(&b),
+		)
+		result = s3.ListBucketResultV2{}
+		unmarshalXML(t, b, &result)
+		if result.KeyCount != 1 || len(result.CommonPrefixes) != 1 || result.CommonPrefixes[0].Prefix != "docs/" {
+			t.Fatalf("unexpected listing after start key %+v", result)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		expectError(t, http.MethodDelete, "/s3/photos", http.StatusConflict, s3.ErrCodeBucketNotEmpty)
+
+		for key := range objects {
+			jsonhttptest.Request(t, client, http.MethodDelete, "/s3/photos/"+key, http.StatusNoContent)
+		}
+		// deleting a missing object succeeds
+		jsonhttptest.Request(t, client, http.MethodDelete, "/s3/photos/a.txt", http.StatusNoContent)
+
+		jsonhttptest.Request(t, client, http.MethodDelete, "/s3/photos", http.StatusNoContent)
+		expectError(t, http.MethodGet, "/s3/photos", http.StatusNotFound, s3.ErrCodeNoSuchBucket)
+	})
+}
+
+func TestS3Gateway(t *testing.T) {
+	var (
+		mockStatestore = statestore.NewStateStore()
+		logger         = logging.New(ioutil.Discard, 0)
+		credential     = s3.Credential{AccessKey: "backup", SecretKey: "secret"}
+		pk, _          = crypto.GenerateSecp256k1Key()
+		signer         = crypto.NewDefaultSigner(pk)
+		s              = api.New(tags.NewTags(mockStatestore, logger), mock.NewStorer(), nil, nil, nil, nil, nil, mockpost.New(mockpost.WithAcceptAll()), nil, nil, signer, logger, nil, api.Options{
+			S3Buckets:      s3.NewBuckets(mockStatestore),
+			S3PostageBatch: batchOk,
+			S3Credentials:  s3.Credentials{credential.AccessKey: credential.SecretKey},
+		})
+		ts = httptest.NewServer(s.S3Handler())
+	)
+	t.Cleanup(ts.Close)
+
+	newClient := func(c s3.Credential) *http.Client {
+		return &http.Client{
+			Transport: web.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				u, err := url.Parse(ts.URL + r.URL.String())
+				if err != nil {
+					return nil, err
+				}
+				r.URL = u
+				r.Host = u.Host
+				s3.SignV4(r, c, "us-east-1", time.Now())
+				return ts.Client().Transport.RoundTrip(r)
+			}),
+		}
+	}
+	client := newClient(credential)
+
+	expectError := func(t *testing.T, client *http.Client, method, url string, statusCode int, code string, opts ...jsonhttptest.Option) {
+		t.Helper()
+		var b []byte
+		jsonhttptest.Request(t, client, method, url, statusCode,
+			append(opts, jsonhttptest.WithPutResponseBody(&b))...,
+		)
+		var e s3.Error
+		if err := xml.Unmarshal(b, &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Code != code {
+			t.Fatalf("got error code %q, want %q", e.Code, code)
+		}
+	}
+
+	t.Run("unsigned", func(t *testing.T) {
+		expectError(t, ts.Client(), http.MethodGet, ts.URL+"/", http.StatusForbidden, s3.ErrCodeAccessDenied)
+	})
+
+	t.Run("wrong secret key", func(t *testing.T) {
+		client := newClient(s3.Credential{AccessKey: credential.AccessKey, SecretKey: "other"})
+		expectError(t, client, http.MethodGet, "/", http.StatusForbidden, s3.ErrCodeSignatureDoesNotMatch)
+	})
+
+	data := []byte("backup archive")
+	sum := sha256.Sum256(data)
+
+	t.Run("put and get object", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodPut, "/backups", http.StatusOK)
+		jsonhttptest.Request(t, client, http.MethodPut, "/backups/2021/archive.tar", http.StatusOK,
+			jsonhttptest.WithRequestBody(bytes.NewReader(data)),
+			jsonhttptest.WithRequestHeader("X-Amz-Content-Sha256", hex.EncodeToString(sum[:])),
+		)
+		jsonhttptest.Request(t, client, http.MethodGet, "/backups/2021/archive.tar", http.StatusOK,
+			jsonhttptest.WithExpectedResponse(data),
+		)
+
+		var b []byte
+		jsonhttptest.Request(t, client, http.MethodGet, "/backups?list-type=2", http.StatusOK,
+			jsonhttptest.WithPutResponseBody(&b),
+		)
+		var result s3.ListBucketResultV2
+		if err := xml.Unmarshal(b, &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.KeyCount != 1 || result.Contents[0].Key != "2021/archive.tar" {
+			t.Fatalf("unexpected listing %+v", result)
+		}
+	})
+
+	t.Run("payload mismatch", func(t *testing.T) {
+		expectError(t, client, http.MethodPut, "/backups/2021/other.tar", http.StatusBadRequest, s3.ErrCodeContentSHA256Mismatch,
+			jsonhttptest.WithRequestBody(bytes.NewReader([]byte("other archive"))),
+			jsonhttptest.WithRequestHeader("X-Amz-Content-Sha256", hex.EncodeToString(sum[:])),
+		)
+	})
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/logging/httpaccess"
+	"github.com/ethsana/sana/pkg/s3"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"resenje.org/web"
+)
+
+func (s *server) S3Handler() http.Handler {
+	return s.s3Gateway
+}
+
+// s3Routes registers the handlers of the S3-compatible API under the root
+// path. The buckets are listed at the root path itself.
+func (s *server) s3Routes(handle func(path string, handler http.Handler), root string) {
+	listPath := root
+	if listPath == "" {
+		listPath = "/"
+	}
+	handle(listPath, web.ChainHandlers(
+		s.gatewayModeForbidEndpointHandler,
+		web.FinalHandler(jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.s3ListBucketsHandler),
+		})),
+	)
+	s3BucketHandler := web.ChainHandlers(
+		s.gatewayModeForbidEndpointHandler,
+		web.FinalHandler(jsonhttp.MethodHandler{
+			"GET":    http.HandlerFunc(s.s3ListObjectsHandler),
+			"HEAD":   http.HandlerFunc(s.s3HeadBucketHandler),
+			"PUT":    http.HandlerFunc(s.s3CreateBucketHandler),
+			"DELETE": http.HandlerFunc(s.s3DeleteBucketHandler),
+		}),
+	)
+	handle(root+"/{bucket}", s3BucketHandler)
+	handle(root+"/{bucket}/", s3BucketHandler)
+	handle(root+"/{bucket}/{key:.+}", web.ChainHandlers(
+		s.gatewayModeForbidEndpointHandler,
+		web.FinalHandler(jsonhttp.MethodHandler{
+			"GET": web.ChainHandlers(
+				s.newTracingHandler("s3-get-object"),
+				web.FinalHandlerFunc(s.s3GetObjectHandler),
+			),
+			"HEAD": http.HandlerFunc(s.s3GetObjectHandler),
+			"PUT": web.ChainHandlers(
+				s.newTracingHandler("s3-put-object"),
+				s.uploadPolicyHandler,
+				s.uploadScanHandler,
+				web.FinalHandlerFunc(s.s3PutObjectHandler),
+			),
+			"DELETE": http.HandlerFunc(s.s3DeleteObjectHandler),
+		})),
+	)
+}
+
+// setupS3Gateway sets up the handler of the S3 gateway which serves the
+// S3-compatible API at the root path to the S3 clients, with path-style
+// bucket addressing and the requests signed with the S3Credentials.
+func (s *server) setupS3Gateway() {
+	router := mux.NewRouter()
+	s.s3Routes(func(path string, handler http.Handler) {
+		router.Handle(path, handler)
+	}, "")
+
+	s.s3Gateway = web.ChainHandlers(
+		httpaccess.NewHTTPAccessLogHandler(s.logger, logrus.InfoLevel, s.tracer, "s3 gateway access"),
+		s.responseCodeMetricsHandler,
+		s.s3AuthHandler,
+		web.FinalHandler(router),
+	)
+}
+
+// s3AuthHandler rejects the requests which are not signed with the AWS
+// Signature Version 4 of one of the S3Credentials.
+func (s *server) s3AuthHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessKey, err := s.S3Credentials.Verify(r, time.Now())
+		if err != nil {
+			s.logger.Debugf("s3 gateway: authenticate %s %s: %v", r.Method, r.URL.Path, err)
+			switch {
+			case errors.Is(err, s3.ErrInvalidAccessKeyID):
+				s3Error(w, r, s3.ErrCodeInvalidAccessKeyID, "The access key id you provided does not exist in our records")
+			case errors.Is(err, s3.ErrSignatureMismatch):
+				s3Error(w, r, s3.ErrCodeSignatureDoesNotMatch, "The request signature we calculated does not match the signature you provided")
+			case errors.Is(err, s3.ErrRequestTimeSkewed):
+				s3Error(w, r, s3.ErrCodeRequestTimeTooSkewed, "The difference between the request time and the current time is too large")
+			case errors.Is(err, s3.ErrMalformedAuthorization):
+				s3Error(w, r, s3.ErrCodeAuthorizationHeaderMalformed, "The authorization of the request is malformed")
+			case errors.Is(err, s3.ErrUnsupportedPayload):
+				s3Error(w, r, s3.ErrCodeNotImplemented, "Streaming payloads are not implemented")
+			case errors.Is(err, s3.ErrUnsignedHeader):
+				s3Error(w, r, s3.ErrCodeAccessDenied, "There were headers present in the request which were not signed")
+			case errors.Is(err, s3.ErrRequestExpired):
+				s3Error(w, r, s3.ErrCodeAccessDenied, "Request has expired")
+			default:
+				s3Error(w, r, s3.ErrCodeAccessDenied, "Access Denied")
+			}
+			return
+		}
+		s.logger.Tracef("s3 gateway: %s %s signed by %s", r.Method, r.URL.Path, accessKey)
+		h.ServeHTTP(w, r)
+	})
+}
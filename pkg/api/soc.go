@@ -127,42 +127,54 @@ func (s *server) socUploadHandler(w http.ResponseWriter, r *http.Request) {
 		jsonhttp.Conflict(w, "chunk already exists")
 		return
 	}
-	batch, err := requestPostageBatchId(r)
-	if err != nil {
-		s.logger.Debugf("soc upload: postage batch id: %v", err)
-		s.logger.Error("soc upload: postage batch id")
-		jsonhttp.BadRequest(w, "invalid postage batch id")
-		return
-	}
+	stamped, err := s.requestStampedChunk(r, sch)
+	switch {
+	case err != nil:
+		s.logger.Debugf("soc upload: postage stamp: %v", err)
+		s.logger.Error("soc upload: postage stamp")
+		jsonhttp.BadRequest(w, postageStampErrorMessage(err))
+		return
+	case stamped != nil:
+		// the chunk is stamped by the client
+		sch = stamped
+	default:
+		batch, err := requestPostageBatchId(r)
+		if err != nil {
+			s.logger.Debugf("soc upload: postage batch id: %v", err)
+			s.logger.Error("soc upload: postage batch id")
+			jsonhttp.BadRequest(w, "invalid postage batch id")
+			return
+		}
 
-	i, err := s.post.GetStampIssuer(batch)
-	if err != nil {
-		s.logger.Debugf("soc upload: postage batch issuer: %v", err)
-		s.logger.Error("soc upload: postage batch issue")
-		switch {
-		case errors.Is(err, postage.ErrNotFound):
-			jsonhttp.BadRequest(w, "batch not found")
-		case errors.Is(err, postage.ErrNotUsable):
-			jsonhttp.BadRequest(w, "batch not usable yet")
-		default:
-			jsonhttp.BadRequest(w, "postage stamp issuer")
+		i, err := s.post.GetStampIssuer(batch)
+		if err != nil {
+			s.logger.Debugf("soc upload: postage batch issuer: %v", err)
+			s.logger.Error("soc upload: postage batch issue")
+			switch {
+			case errors.Is(err, postage.ErrNotFound):
+				jsonhttp.BadRequest(w, "batch not found")
+			case errors.Is(err, postage.ErrNotUsable):
+				jsonhttp.BadRequest(w, "batch not usable yet")
+			default:
+				jsonhttp.BadRequest(w, "postage stamp issuer")
+			}
+			return
 		}
-		return
-	}
-	stamper := postage.NewStamper(i, s.signer)
-	stamp, err := stamper.Stamp(sch.Address())
-	if err != nil {
-		s.logger.Debugf("soc upload: stamp: %v", err)
-		s.logger.Error("soc upload: stamp error")
-		switch {
-		case errors.Is(err, postage.ErrBucketFull):
-			jsonhttp.PaymentRequired(w, "batch is overissued")
-		default:
-			jsonhttp.InternalServerError(w, "stamp error")
+		stamper := postage.NewStamper(i, s.signer)
+		stamp, err := stamper.Stamp(sch.Address())
+		if err != nil {
+			s.logger.Debugf("soc upload: stamp: %v", err)
+			s.logger.Error("soc upload: stamp error")
+			switch {
+			case errors.Is(err, postage.ErrBucketFull):
+				jsonhttp.PaymentRequired(w, "batch is overissued")
+			default:
+				jsonhttp.InternalServerError(w, "stamp error")
+			}
+			return
 		}
-		return
+		sch = sch.WithStamp(stamp)
 	}
-	sch = sch.WithStamp(stamp)
 	_, err = s.storer.Put(ctx, requestModePut(r), sch)
 	if err != nil {
 		s.logger.Debugf("soc upload: chunk write error: %v", err)
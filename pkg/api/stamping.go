@@ -0,0 +1,126 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/ethsana/sana/pkg/cac"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/stamping"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+// SwarmStampingTokenHeader carries the token of a delegated stamping client.
+const SwarmStampingTokenHeader = "Swarm-Stamping-Token"
+
+// stampingClient authenticates the delegated stamping client of the request.
+func (s *server) stampingClient(w http.ResponseWriter, r *http.Request) (stamping.Client, bool) {
+	c, err := s.Stamping.Authenticate(r.Header.Get(SwarmStampingTokenHeader))
+	if err != nil {
+		s.logger.Debugf("stamping: authenticate %s: %v", r.RemoteAddr, err)
+		s.logger.Error("stamping: authenticate")
+		jsonhttp.Unauthorized(w, "invalid stamping token")
+		return stamping.Client{}, false
+	}
+	return c, true
+}
+
+// stampingChunkUploadHandler stamps the chunk of a delegated stamping client
+// with the stamping batch of the node and stores it.
+func (s *server) stampingChunkUploadHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := s.stampingClient(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		if jsonhttp.HandleBodyReadError(err, w) {
+			return
+		}
+		s.logger.Debugf("stamping chunk: read chunk data error: %v", err)
+		s.logger.Error("stamping chunk: read chunk data error")
+		jsonhttp.InternalServerError(w, "cannot read chunk data")
+		return
+	}
+
+	if len(data) < swarm.SpanSize {
+		s.logger.Debug("stamping chunk: not enough data")
+		s.logger.Error("stamping chunk: data length")
+		jsonhttp.BadRequest(w, "data length")
+		return
+	}
+
+	chunk, err := cac.NewWithDataSpan(data)
+	if err != nil {
+		s.logger.Debugf("stamping chunk: create chunk error: %v", err)
+		s.logger.Error("stamping chunk: create chunk error")
+		jsonhttp.InternalServerError(w, "create chunk error")
+		return
+	}
+
+	putter, err := newStamperPutter(s.storer, s.post, s.signer, s.StampingBatch)
+	if err != nil {
+		s.logger.Debugf("stamping chunk: putter: %v", err)
+		s.logger.Error("stamping chunk: putter")
+		jsonhttp.ServiceUnavailable(w, "stamping batch not usable")
+		return
+	}
+
+	if err := s.Stamping.Reserve(client, 1); err != nil {
+		s.logger.Debugf("stamping chunk: client %s: reserve: %v", client.Name, err)
+		s.logger.Error("stamping chunk: reserve")
+		if errors.Is(err, stamping.ErrQuotaExceeded) {
+			jsonhttp.TooManyRequests(w, "stamping quota exceeded")
+			return
+		}
+		jsonhttp.InternalServerError(w, nil)
+		return
+	}
+
+	seen, err := putter.Put(r.Context(), storage.ModePutUpload, chunk)
+	if err != nil || seen[0] {
+		// chunks which were not stamped are not accounted to the client
+		if err := s.Stamping.Release(client, 1); err != nil {
+			s.logger.Debugf("stamping chunk: client %s: release: %v", client.Name, err)
+			s.logger.Error("stamping chunk: release")
+		}
+	}
+	if err != nil {
+		s.logger.Debugf("stamping chunk: chunk write error: %v, addr %s", err, chunk.Address())
+		s.logger.Error("stamping chunk: chunk write error")
+		switch {
+		case errors.Is(err, postage.ErrBucketFull):
+			jsonhttp.PaymentRequired(w, "batch is overissued")
+		default:
+			jsonhttp.InternalServerError(w, "chunk write error")
+		}
+		return
+	}
+
+	jsonhttp.Created(w, chunkAddressResponse{Reference: chunk.Address()})
+}
+
+// stampingQuotaHandler returns the usage of the delegated stamping client.
+func (s *server) stampingQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := s.stampingClient(w, r)
+	if !ok {
+		return
+	}
+
+	usage, err := s.Stamping.Usage(client)
+	if err != nil {
+		s.logger.Debugf("stamping quota: client %s: %v", client.Name, err)
+		s.logger.Error("stamping quota")
+		jsonhttp.InternalServerError(w, nil)
+		return
+	}
+	jsonhttp.OK(w, usage)
+}
@@ -0,0 +1,170 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/api"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
+	mockpost "github.com/ethsana/sana/pkg/postage/mock"
+	"github.com/ethsana/sana/pkg/stamping"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	testingc "github.com/ethsana/sana/pkg/storage/testing"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/tags"
+	"github.com/ethsana/sana/pkg/uploadpolicy"
+)
+
+func TestStamping(t *testing.T) {
+	var (
+		token          = "client-token"
+		storer         = mock.NewStorer()
+		mockStatestore = statestore.NewStateStore()
+		logger         = logging.New(ioutil.Discard, 0)
+	)
+	service, err := stamping.New(mockStatestore, []stamping.Client{
+		{Name: "mobile", TokenHash: stamping.TokenHash(token), Quota: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _, _ := newTestServer(t, testServerOptions{
+		Storer:        storer,
+		Tags:          tags.NewTags(mockStatestore, logger),
+		Logger:        logger,
+		Post:          mockpost.New(mockpost.WithAcceptAll()),
+		Stamping:      service,
+		StampingBatch: batchOk,
+	})
+
+	upload := func(t *testing.T, ch swarm.Chunk, status int) {
+		t.Helper()
+		jsonhttptest.Request(t, client, http.MethodPost, "/stamping/chunks", status,
+			jsonhttptest.WithRequestHeader(api.SwarmStampingTokenHeader, token),
+			jsonhttptest.WithRequestBody(bytes.NewReader(ch.Data())),
+		)
+	}
+	quota := func(t *testing.T, stamped uint64) {
+		t.Helper()
+		jsonhttptest.Request(t, client, http.MethodGet, "/stamping/quota", http.StatusOK,
+			jsonhttptest.WithRequestHeader(api.SwarmStampingTokenHeader, token),
+			jsonhttptest.WithExpectedJSONResponse(stamping.Usage{
+				Client:  "mobile",
+				Stamped: stamped,
+				Quota:   2,
+			}),
+		)
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodPost, "/stamping/chunks", http.StatusUnauthorized,
+			jsonhttptest.WithRequestHeader(api.SwarmStampingTokenHeader, "wrong-token"),
+			jsonhttptest.WithRequestBody(bytes.NewReader(testingc.GenerateTestRandomChunk().Data())),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "invalid stamping token",
+				Code:    http.StatusUnauthorized,
+			}),
+		)
+		jsonhttptest.Request(t, client, http.MethodGet, "/stamping/quota", http.StatusUnauthorized)
+	})
+
+	t.Run("stamp", func(t *testing.T) {
+		ch := testingc.GenerateTestRandomChunk()
+		jsonhttptest.Request(t, client, http.MethodPost, "/stamping/chunks", http.StatusCreated,
+			jsonhttptest.WithRequestHeader(api.SwarmStampingTokenHeader, token),
+			jsonhttptest.WithRequestBody(bytes.NewReader(ch.Data())),
+			jsonhttptest.WithExpectedJSONResponse(api.BytesPostResponse{
+				Reference: ch.Address(),
+			}),
+		)
+		stored, err := storer.Get(context.Background(), storage.ModeGetRequest, ch.Address())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(stored.Data(), ch.Data()) {
+			t.Fatal("stored chunk data mismatch")
+		}
+		quota(t, 1)
+
+		// chunks which are already stored are not accounted
+		upload(t, ch, http.StatusCreated)
+		quota(t, 1)
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		upload(t, testingc.GenerateTestRandomChunk(), http.StatusCreated)
+		jsonhttptest.Request(t, client, http.MethodPost, "/stamping/chunks", http.StatusTooManyRequests,
+			jsonhttptest.WithRequestHeader(api.SwarmStampingTokenHeader, token),
+			jsonhttptest.WithRequestBody(bytes.NewReader(testingc.GenerateTestRandomChunk().Data())),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "stamping quota exceeded",
+				Code:    http.StatusTooManyRequests,
+			}),
+		)
+		quota(t, 2)
+	})
+}
+
+func TestStampingUploadPolicies(t *testing.T) {
+	var (
+		token          = "client-token"
+		appOrigin      = "https://app.example.org"
+		mockStatestore = statestore.NewStateStore()
+		logger         = logging.New(ioutil.Discard, 0)
+	)
+	service, err := stamping.New(mockStatestore, []stamping.Client{
+		{Name: "mobile", TokenHash: stamping.TokenHash(token), Quota: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies, err := uploadpolicy.New([]uploadpolicy.Policy{{Origin: appOrigin}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _, _ := newTestServer(t, testServerOptions{
+		Storer:         mock.NewStorer(),
+		Tags:           tags.NewTags(mockStatestore, logger),
+		Logger:         logger,
+		Post:           mockpost.New(mockpost.WithAcceptAll()),
+		Stamping:       service,
+		StampingBatch:  batchOk,
+		UploadPolicies: policies,
+	})
+
+	jsonhttptest.Request(t, client, http.MethodPost, "/stamping/chunks", http.StatusForbidden,
+		jsonhttptest.WithRequestHeader("Origin", "https://other.example.org"),
+		jsonhttptest.WithRequestHeader(api.SwarmStampingTokenHeader, token),
+		jsonhttptest.WithRequestBody(bytes.NewReader(testingc.GenerateTestRandomChunk().Data())),
+		jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+			Message: "uploads not allowed for origin",
+			Code:    http.StatusForbidden,
+		}),
+	)
+	jsonhttptest.Request(t, client, http.MethodPost, "/stamping/chunks", http.StatusCreated,
+		jsonhttptest.WithRequestHeader("Origin", appOrigin),
+		jsonhttptest.WithRequestHeader(api.SwarmStampingTokenHeader, token),
+		jsonhttptest.WithRequestBody(bytes.NewReader(testingc.GenerateTestRandomChunk().Data())),
+	)
+
+	// the refused upload does not count against the quota
+	jsonhttptest.Request(t, client, http.MethodGet, "/stamping/quota", http.StatusOK,
+		jsonhttptest.WithRequestHeader(api.SwarmStampingTokenHeader, token),
+		jsonhttptest.WithExpectedJSONResponse(stamping.Usage{
+			Client:  "mobile",
+			Stamped: 1,
+			Quota:   2,
+		}),
+	)
+}
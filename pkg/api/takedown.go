@@ -0,0 +1,100 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/takedown"
+)
+
+const takedownNoticeMaxRequestSize = 16 * 1024
+
+type takedownNoticeRequest struct {
+	Reference swarm.Address `json:"reference"`
+	Claimant  string        `json:"claimant"`
+	Reason    string        `json:"reason"`
+	Timestamp int64         `json:"timestamp"`
+	Signature string        `json:"signature"`
+}
+
+// takenDown responds with the status 451 if the content with the address is
+// taken down.
+func (s *server) takenDown(w http.ResponseWriter, address swarm.Address) bool {
+	if s.Takedown == nil || !s.Takedown.Denied(address) {
+		return false
+	}
+	s.logger.Tracef("takedown: denied access to %s", address)
+	jsonhttp.UnavailableForLegalReasons(w, "content taken down")
+	return true
+}
+
+func (s *server) takedownNoticeHandler(w http.ResponseWriter, r *http.Request) {
+	s.handleTakedownNotice(w, r, takedown.KindNotice)
+}
+
+func (s *server) takedownCounterNoticeHandler(w http.ResponseWriter, r *http.Request) {
+	s.handleTakedownNotice(w, r, takedown.KindCounterNotice)
+}
+
+func (s *server) handleTakedownNotice(w http.ResponseWriter, r *http.Request, kind takedown.Kind) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		if jsonhttp.HandleBodyReadError(err, w) {
+			return
+		}
+		s.logger.Debugf("takedown %s: read request body: %v", kind, err)
+		s.logger.Error("takedown: read request body")
+		jsonhttp.InternalServerError(w, "cannot read request")
+		return
+	}
+	var req takedownNoticeRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		s.logger.Debugf("takedown %s: unmarshal request: %v", kind, err)
+		jsonhttp.BadRequest(w, "invalid notice")
+		return
+	}
+	signature, err := hex.DecodeString(req.Signature)
+	if err != nil || req.Reference.IsZero() {
+		jsonhttp.BadRequest(w, "invalid notice")
+		return
+	}
+
+	n := takedown.Notice{
+		Reference: req.Reference,
+		Claimant:  req.Claimant,
+		Reason:    req.Reason,
+		Timestamp: req.Timestamp,
+	}
+	var e *takedown.Entry
+	if kind == takedown.KindNotice {
+		e, err = s.Takedown.Notice(r.Context(), n, signature)
+	} else {
+		e, err = s.Takedown.CounterNotice(r.Context(), n, signature)
+	}
+	switch {
+	case errors.Is(err, takedown.ErrInvalidSignature), errors.Is(err, takedown.ErrStaleNotice):
+		jsonhttp.BadRequest(w, err.Error())
+	case errors.Is(err, takedown.ErrUntrustedSigner):
+		jsonhttp.Forbidden(w, err.Error())
+	case errors.Is(err, takedown.ErrTakenDown):
+		jsonhttp.Conflict(w, err.Error())
+	case errors.Is(err, takedown.ErrNotTakenDown):
+		jsonhttp.NotFound(w, err.Error())
+	case err != nil:
+		s.logger.Debugf("takedown %s: %s: %v", kind, req.Reference, err)
+		s.logger.Error("takedown: unable to process notice")
+		jsonhttp.InternalServerError(w, nil)
+	default:
+		s.logger.Infof("takedown: %s for %s by %s processed: %v", kind, req.Reference, e.Signer, e.Actions)
+		jsonhttp.Created(w, e)
+	}
+}
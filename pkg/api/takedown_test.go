@@ -0,0 +1,195 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/api"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
+	pinning "github.com/ethsana/sana/pkg/pinning/mock"
+	mockpost "github.com/ethsana/sana/pkg/postage/mock"
+	"github.com/ethsana/sana/pkg/s3"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/tags"
+	"github.com/ethsana/sana/pkg/takedown"
+)
+
+func TestTakedown(t *testing.T) {
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.NewDefaultSigner(key)
+	owner, err := signer.EthereumAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mockStatestore = statestore.NewStateStore()
+		logger         = logging.New(ioutil.Discard, 0)
+	)
+	service, err := takedown.New(mockStatestore, pinning.NewServiceMock(), logger, []common.Address{owner})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _, _ := newTestServer(t, testServerOptions{
+		Storer:   mock.NewStorer(),
+		Tags:     tags.NewTags(mockStatestore, logger),
+		Logger:   logger,
+		Post:     mockpost.New(mockpost.WithAcceptAll()),
+		Takedown: service,
+	})
+
+	var resp api.BytesPostResponse
+	jsonhttptest.Request(t, client, http.MethodPost, "/bytes", http.StatusCreated,
+		jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+		jsonhttptest.WithRequestBody(bytes.NewReader([]byte("infringing content"))),
+		jsonhttptest.WithUnmarshalJSONResponse(&resp),
+	)
+	ref := resp.Reference
+
+	notice := func(t *testing.T, path string, kind takedown.Kind, ts int64, signer crypto.Signer, status int) {
+		t.Helper()
+		n := takedown.Notice{Kind: kind, Reference: ref, Claimant: "rights holder", Reason: "infringement", Timestamp: ts}
+		sig, err := n.Sign(signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jsonhttptest.Request(t, client, http.MethodPost, path, status,
+			jsonhttptest.WithJSONRequestBody(api.TakedownNoticeRequest{
+				Reference: ref,
+				Claimant:  n.Claimant,
+				Reason:    n.Reason,
+				Timestamp: n.Timestamp,
+				Signature: hex.EncodeToString(sig),
+			}),
+		)
+	}
+	download := func(t *testing.T, status int) {
+		t.Helper()
+		jsonhttptest.Request(t, client, http.MethodGet, "/bytes/"+ref.String(), status)
+		jsonhttptest.Request(t, client, http.MethodGet, "/chunks/"+ref.String(), status)
+	}
+
+	ts := time.Now().Unix()
+	download(t, http.StatusOK)
+
+	untrusted, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	notice(t, "/takedown/notices", takedown.KindNotice, ts, crypto.NewDefaultSigner(untrusted), http.StatusForbidden)
+	download(t, http.StatusOK)
+
+	notice(t, "/takedown/counter-notices", takedown.KindCounterNotice, ts, signer, http.StatusNotFound)
+	notice(t, "/takedown/notices", takedown.KindNotice, ts, signer, http.StatusCreated)
+	download(t, http.StatusUnavailableForLegalReasons)
+	notice(t, "/takedown/notices", takedown.KindNotice, ts, signer, http.StatusBadRequest)
+	notice(t, "/takedown/notices", takedown.KindNotice, ts+1, signer, http.StatusConflict)
+
+	notice(t, "/takedown/counter-notices", takedown.KindCounterNotice, ts+2, signer, http.StatusCreated)
+	download(t, http.StatusOK)
+}
+
+// TestTakedownManifests validates that the content which is taken down is
+// neither served as an s3 object nor listed by its checksums.
+func TestTakedownManifests(t *testing.T) {
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.NewDefaultSigner(key)
+	owner, err := signer.EthereumAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mockStatestore = statestore.NewStateStore()
+		logger         = logging.New(ioutil.Discard, 0)
+		data           = []byte("infringing content")
+	)
+	service, err := takedown.New(mockStatestore, pinning.NewServiceMock(), logger, []common.Address{owner})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _, _ := newTestServer(t, testServerOptions{
+		Storer:         mock.NewStorer(),
+		Tags:           tags.NewTags(mockStatestore, logger),
+		Logger:         logger,
+		Post:           mockpost.New(mockpost.WithAcceptAll()),
+		Takedown:       service,
+		S3Buckets:      s3.NewBuckets(mockStatestore),
+		S3PostageBatch: batchOk,
+	})
+
+	takeDown := func(t *testing.T, ref swarm.Address) {
+		t.Helper()
+		n := takedown.Notice{Kind: takedown.KindNotice, Reference: ref, Claimant: "rights holder", Reason: "infringement", Timestamp: time.Now().Unix()}
+		sig, err := n.Sign(signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jsonhttptest.Request(t, client, http.MethodPost, "/takedown/notices", http.StatusCreated,
+			jsonhttptest.WithJSONRequestBody(api.TakedownNoticeRequest{
+				Reference: ref,
+				Claimant:  n.Claimant,
+				Reason:    n.Reason,
+				Timestamp: n.Timestamp,
+				Signature: hex.EncodeToString(sig),
+			}),
+		)
+	}
+
+	t.Run("checksums", func(t *testing.T) {
+		var resp api.BzzUploadResponse
+		jsonhttptest.Request(t, client, http.MethodPost, "/bzz", http.StatusCreated,
+			jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+			jsonhttptest.WithRequestBody(tarFiles(t, []f{{data: data, name: "a.txt"}})),
+			jsonhttptest.WithRequestHeader(api.SwarmCollectionHeader, "True"),
+			jsonhttptest.WithRequestHeader("Content-Type", api.ContentTypeTar),
+			jsonhttptest.WithUnmarshalJSONResponse(&resp),
+		)
+		resource := "/checksums/" + resp.Reference.String()
+
+		jsonhttptest.Request(t, client, http.MethodGet, resource, http.StatusOK)
+		takeDown(t, resp.Reference)
+		jsonhttptest.Request(t, client, http.MethodGet, resource, http.StatusUnavailableForLegalReasons)
+	})
+
+	t.Run("s3 object", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodPut, "/s3/docs", http.StatusOK)
+		jsonhttptest.Request(t, client, http.MethodPut, "/s3/docs/a.txt", http.StatusOK,
+			jsonhttptest.WithRequestBody(bytes.NewReader(data)),
+		)
+		jsonhttptest.Request(t, client, http.MethodGet, "/s3/docs/a.txt", http.StatusOK,
+			jsonhttptest.WithExpectedResponse(data),
+		)
+
+		// the object is the content of the same data uploaded as bytes
+		var resp api.BytesPostResponse
+		jsonhttptest.Request(t, client, http.MethodPost, "/bytes", http.StatusCreated,
+			jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+			jsonhttptest.WithRequestBody(bytes.NewReader(data)),
+			jsonhttptest.WithUnmarshalJSONResponse(&resp),
+		)
+		takeDown(t, resp.Reference)
+
+		jsonhttptest.Request(t, client, http.MethodGet, "/s3/docs/a.txt", http.StatusUnavailableForLegalReasons)
+		jsonhttptest.Request(t, client, http.MethodHead, "/s3/docs/a.txt", http.StatusUnavailableForLegalReasons)
+	})
+}
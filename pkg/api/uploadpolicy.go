@@ -0,0 +1,59 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/hex"
+	"net/http"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+)
+
+// SwarmAPIKeyHeader carries the API key required by the upload policy of the
+// request origin.
+const SwarmAPIKeyHeader = "Swarm-Api-Key"
+
+// uploadPolicyHandler enforces the upload policy of the request origin before
+// the upload handler is called.
+func (s *server) uploadPolicyHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.UploadPolicies == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		origin := r.Header.Get("Origin")
+		policy, ok := s.UploadPolicies.Match(origin)
+		if !ok {
+			s.logger.Debugf("upload policy: no policy for origin %q", origin)
+			s.logger.Error("upload policy: origin not allowed")
+			jsonhttp.Forbidden(w, "uploads not allowed for origin")
+			return
+		}
+
+		if !policy.Authorized(r.Header.Get(SwarmAPIKeyHeader)) {
+			s.logger.Debugf("upload policy: origin %q: invalid api key", origin)
+			s.logger.Error("upload policy: invalid api key")
+			jsonhttp.Unauthorized(w, "invalid api key")
+			return
+		}
+
+		if ct := r.Header.Get(contentTypeHeader); !policy.AllowsContentType(ct) {
+			s.logger.Debugf("upload policy: origin %q: content type %q not allowed", origin, ct)
+			s.logger.Error("upload policy: content type not allowed")
+			jsonhttp.UnsupportedMediaType(w, "content type not allowed")
+			return
+		}
+
+		if batch := policy.Batch(); batch != nil {
+			r.Header.Set(SwarmPostageBatchIdHeader, hex.EncodeToString(batch))
+		}
+
+		if policy.MaxUploadSize > 0 {
+			h = jsonhttp.NewMaxBodyBytesHandler(policy.MaxUploadSize)(h)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
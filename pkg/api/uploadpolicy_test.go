@@ -0,0 +1,116 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/api"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
+	mockpost "github.com/ethsana/sana/pkg/postage/mock"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/tags"
+	"github.com/ethsana/sana/pkg/uploadpolicy"
+)
+
+func TestUploadPolicies(t *testing.T) {
+	var (
+		appOrigin      = "https://app.example.org"
+		policyBatch    = bytes.Repeat([]byte{7}, 32)
+		storer         = mock.NewStorer()
+		mockStatestore = statestore.NewStateStore()
+		logger         = logging.New(ioutil.Discard, 0)
+	)
+	policies, err := uploadpolicy.New([]uploadpolicy.Policy{
+		{
+			Origin:        appOrigin,
+			MaxUploadSize: 16,
+			ContentTypes:  []string{"text/*"},
+			APIKeyHash:    uploadpolicy.KeyHash("secret"),
+			PostageBatch:  hex.EncodeToString(policyBatch),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _, _ := newTestServer(t, testServerOptions{
+		Storer:         storer,
+		Tags:           tags.NewTags(mockStatestore, logger),
+		Logger:         logger,
+		Post:           mockpost.New(mockpost.WithAcceptAll()),
+		UploadPolicies: policies,
+	})
+
+	upload := func(t *testing.T, status int, content []byte, header http.Header, opts ...jsonhttptest.Option) {
+		t.Helper()
+		h := http.Header{
+			"Origin":                      {appOrigin},
+			api.SwarmAPIKeyHeader:         {"secret"},
+			api.SwarmPostageBatchIdHeader: {batchOkStr},
+			"Content-Type":                {"text/plain"},
+		}
+		for k, v := range header {
+			h[k] = v
+		}
+		for k := range h {
+			opts = append(opts, jsonhttptest.WithRequestHeader(k, h.Get(k)))
+		}
+		opts = append(opts, jsonhttptest.WithRequestBody(bytes.NewReader(content)))
+		jsonhttptest.Request(t, client, http.MethodPost, "/bytes", status, opts...)
+	}
+
+	t.Run("accepted", func(t *testing.T) {
+		var resp api.BytesPostResponse
+		upload(t, http.StatusCreated, []byte("hello"), nil, jsonhttptest.WithUnmarshalJSONResponse(&resp))
+
+		ch, err := storer.Get(context.Background(), storage.ModeGetRequest, resp.Reference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ch.Stamp().BatchID(), policyBatch) {
+			t.Fatalf("got batch %x, want %x", ch.Stamp().BatchID(), policyBatch)
+		}
+	})
+
+	t.Run("unknown origin", func(t *testing.T) {
+		upload(t, http.StatusForbidden, []byte("hello"), http.Header{"Origin": {"https://other.example.org"}},
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "uploads not allowed for origin",
+				Code:    http.StatusForbidden,
+			}),
+		)
+	})
+
+	t.Run("invalid api key", func(t *testing.T) {
+		upload(t, http.StatusUnauthorized, []byte("hello"), http.Header{api.SwarmAPIKeyHeader: {"wrong"}},
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "invalid api key",
+				Code:    http.StatusUnauthorized,
+			}),
+		)
+	})
+
+	t.Run("content type", func(t *testing.T) {
+		upload(t, http.StatusUnsupportedMediaType, []byte("hello"), http.Header{"Content-Type": {"application/octet-stream"}},
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "content type not allowed",
+				Code:    http.StatusUnsupportedMediaType,
+			}),
+		)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		upload(t, http.StatusRequestEntityTooLarge, bytes.Repeat([]byte("a"), 17), nil)
+	})
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/uploadscan"
+	"github.com/gorilla/mux"
+)
+
+// uploadScanHandler passes the request body to the upload scanner before the
+// upload handler is called. The body is spooled to a temporary file so that
+// nothing is stored before the scanner accepts the content. Rejected uploads
+// are recorded in the audit.
+func (s *server) uploadScanHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.UploadScanner == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		f, err := ioutil.TempFile("", "sana-scan-")
+		if err != nil {
+			s.logger.Debugf("upload scan: create spool file: %v", err)
+			s.logger.Error("upload scan: create spool file")
+			jsonhttp.InternalServerError(w, nil)
+			return
+		}
+		defer closeSpool(f)
+
+		size, err := io.Copy(f, r.Body)
+		if err != nil {
+			if jsonhttp.HandleBodyReadError(err, w) {
+				return
+			}
+			s.logger.Debugf("upload scan: spool body: %v", err)
+			s.logger.Error("upload scan: spool body")
+			jsonhttp.InternalServerError(w, "cannot read request body")
+			return
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			s.logger.Debugf("upload scan: rewind spool file: %v", err)
+			s.logger.Error("upload scan: rewind spool file")
+			jsonhttp.InternalServerError(w, nil)
+			return
+		}
+
+		info := uploadscan.Info{
+			ContentType: r.Header.Get("Content-Type"),
+			Filename:    r.URL.Query().Get("name"),
+			Size:        size,
+		}
+		if key := mux.Vars(r)["key"]; info.Filename == "" && key != "" {
+			info.Filename = path.Base(key)
+		}
+
+		if err := s.scanUpload(r, f, info); err != nil {
+			respondUploadScanError(w, err)
+			return
+		}
+
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			s.logger.Debugf("upload scan: rewind spool file: %v", err)
+			s.logger.Error("upload scan: rewind spool file")
+			jsonhttp.InternalServerError(w, nil)
+			return
+		}
+		r.Body = ioutil.NopCloser(f)
+		r.ContentLength = size
+		h.ServeHTTP(w, r)
+	})
+}
+
+// errUploadScanFailed is returned by scanUpload if the content scanner failed.
+var errUploadScanFailed = errors.New("content scanner failed")
+
+// uploadRejectedError is returned by scanUpload if the content scanner
+// rejected the upload.
+type uploadRejectedError struct {
+	reason string
+}
+
+func (e *uploadRejectedError) Error() string {
+	return "upload rejected: " + e.reason
+}
+
+// scanUpload passes the content of the upload request to the upload scanner
+// and records the rejected uploads in the audit.
+func (s *server) scanUpload(r *http.Request, content io.Reader, info uploadscan.Info) error {
+	hash := sha256.New()
+	content = io.TeeReader(content, hash)
+
+	result, err := s.UploadScanner.Scan(r.Context(), content, info)
+	if err != nil {
+		s.metrics.UploadScanFailed.Inc()
+		s.logger.Debugf("upload scan: scan: %v", err)
+		s.logger.Error("upload scan: content scanner failed")
+		return errUploadScanFailed
+	}
+	if !result.Rejected {
+		return nil
+	}
+
+	s.metrics.UploadScanRejected.Inc()
+	s.logger.Debugf("upload scan: %s from %s rejected: %s", r.URL.Path, r.RemoteAddr, result.Reason)
+	s.logger.Error("upload scan: upload rejected")
+	if s.UploadScanAudit != nil {
+		// the hash is of the whole content, also if the scanner did not read
+		// all of it
+		if _, err := io.Copy(ioutil.Discard, content); err != nil {
+			s.logger.Debugf("upload scan: hash content: %v", err)
+		}
+		err := s.UploadScanAudit.Record(uploadscan.Rejection{
+			Time:        time.Now().UTC(),
+			Path:        r.URL.Path,
+			RemoteAddr:  r.RemoteAddr,
+			ContentType: info.ContentType,
+			Filename:    info.Filename,
+			Size:        info.Size,
+			SHA256:      hex.EncodeToString(hash.Sum(nil)),
+			Reason:      result.Reason,
+		})
+		if err != nil {
+			s.logger.Debugf("upload scan: record rejection: %v", err)
+			s.logger.Error("upload scan: record rejection")
+		}
+	}
+	return &uploadRejectedError{reason: result.Reason}
+}
+
+// respondUploadScanError responds with the error of scanUpload.
+func respondUploadScanError(w http.ResponseWriter, err error) {
+	var rejected *uploadRejectedError
+	if errors.As(err, &rejected) {
+		jsonhttp.Forbidden(w, rejected.Error())
+		return
+	}
+	jsonhttp.ServiceUnavailable(w, "content scanner unavailable")
+}
@@ -0,0 +1,171 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/api"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
+	mockpost "github.com/ethsana/sana/pkg/postage/mock"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/tags"
+	"github.com/ethsana/sana/pkg/uploadscan"
+	"github.com/ethsana/sana/pkg/uploadsession"
+)
+
+// scannerFunc adapts a function to the uploadscan.Scanner interface.
+type scannerFunc func(content []byte, info uploadscan.Info) (uploadscan.Result, error)
+
+func (f scannerFunc) Scan(_ context.Context, r io.Reader, info uploadscan.Info) (uploadscan.Result, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return uploadscan.Result{}, err
+	}
+	return f(content, info)
+}
+
+func TestUploadScan(t *testing.T) {
+	var (
+		flagged        = []byte("flagged content")
+		failing        = []byte("failing content")
+		storer         = mock.NewStorer()
+		mockStatestore = statestore.NewStateStore()
+		logger         = logging.New(ioutil.Discard, 0)
+		audit          = uploadscan.NewAudit(mockStatestore)
+		scanner        = scannerFunc(func(content []byte, info uploadscan.Info) (uploadscan.Result, error) {
+			if int64(len(content)) != info.Size {
+				return uploadscan.Result{}, errors.New("size mismatch")
+			}
+			switch {
+			case bytes.Equal(content, flagged):
+				return uploadscan.Result{Rejected: true, Reason: "test signature"}, nil
+			case bytes.Equal(content, failing):
+				return uploadscan.Result{}, errors.New("scanner failure")
+			}
+			return uploadscan.Result{}, nil
+		})
+		client, _, _ = newTestServer(t, testServerOptions{
+			Storer:          storer,
+			Tags:            tags.NewTags(mockStatestore, logger),
+			Logger:          logger,
+			Post:            mockpost.New(mockpost.WithAcceptAll()),
+			UploadScanner:   scanner,
+			UploadScanAudit: audit,
+			UploadSessions:  uploadsession.New(mockStatestore, storer),
+		})
+	)
+
+	t.Run("accepted", func(t *testing.T) {
+		content := []byte("clean content")
+		var resp api.BytesPostResponse
+		jsonhttptest.Request(t, client, http.MethodPost, "/bytes", http.StatusCreated,
+			jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+			jsonhttptest.WithRequestBody(bytes.NewReader(content)),
+			jsonhttptest.WithUnmarshalJSONResponse(&resp),
+		)
+		jsonhttptest.Request(t, client, http.MethodGet, "/bytes/"+resp.Reference.String(), http.StatusOK,
+			jsonhttptest.WithExpectedResponse(content),
+		)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodPost, "/bzz?name=file.txt", http.StatusForbidden,
+			jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+			jsonhttptest.WithRequestHeader("Content-Type", "text/plain"),
+			jsonhttptest.WithRequestBody(bytes.NewReader(flagged)),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "upload rejected: test signature",
+				Code:    http.StatusForbidden,
+			}),
+		)
+
+		rejections, err := audit.Rejections()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rejections) != 1 {
+			t.Fatalf("got %d rejections, want 1", len(rejections))
+		}
+		r := rejections[0]
+		if r.Path != "/bzz" || r.Filename != "file.txt" || r.ContentType != "text/plain" || r.Size != int64(len(flagged)) || r.Reason != "test signature" {
+			t.Fatalf("unexpected rejection %+v", r)
+		}
+	})
+
+	t.Run("rejected upload session", func(t *testing.T) {
+		var session api.UploadSessionResponse
+		jsonhttptest.Request(t, client, http.MethodPost, "/uploads", http.StatusCreated,
+			jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+			jsonhttptest.WithRequestHeader(api.UploadLengthHeader, strconv.Itoa(len(flagged))),
+			jsonhttptest.WithUnmarshalJSONResponse(&session),
+		)
+		resource := "/uploads/" + session.ID
+
+		// the ranges are scanned together once the content is joined
+		jsonhttptest.Request(t, client, http.MethodPatch, resource, http.StatusOK,
+			jsonhttptest.WithRequestHeader(api.UploadOffsetHeader, "0"),
+			jsonhttptest.WithRequestBody(bytes.NewReader(flagged[:5])),
+		)
+		jsonhttptest.Request(t, client, http.MethodPatch, resource, http.StatusForbidden,
+			jsonhttptest.WithRequestHeader(api.UploadOffsetHeader, "5"),
+			jsonhttptest.WithRequestBody(bytes.NewReader(flagged[5:])),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "upload rejected: test signature",
+				Code:    http.StatusForbidden,
+			}),
+		)
+		jsonhttptest.Request(t, client, http.MethodGet, resource, http.StatusOK,
+			jsonhttptest.WithUnmarshalJSONResponse(&session),
+		)
+		if session.Reference != nil {
+			t.Fatal("rejected upload session completed")
+		}
+
+		rejections, err := audit.Rejections()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rejections) != 2 {
+			t.Fatalf("got %d rejections, want 2", len(rejections))
+		}
+		sum := sha256.Sum256(flagged)
+		var found bool
+		for _, r := range rejections {
+			if r.Path == resource {
+				found = true
+				if r.Size != int64(len(flagged)) || r.SHA256 != hex.EncodeToString(sum[:]) || r.Reason != "test signature" {
+					t.Fatalf("unexpected rejection %+v", r)
+				}
+			}
+		}
+		if !found {
+			t.Fatal("upload session rejection not recorded")
+		}
+	})
+
+	t.Run("scanner failure", func(t *testing.T) {
+		jsonhttptest.Request(t, client, http.MethodPost, "/bytes", http.StatusServiceUnavailable,
+			jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+			jsonhttptest.WithRequestBody(bytes.NewReader(failing)),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Message: "content scanner unavailable",
+				Code:    http.StatusServiceUnavailable,
+			}),
+		)
+	})
+}
@@ -0,0 +1,227 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/uploadscan"
+	"github.com/ethsana/sana/pkg/uploadsession"
+	"github.com/gorilla/mux"
+)
+
+// Headers of the resumable uploads.
+const (
+	UploadLengthHeader = "Upload-Length"
+	UploadOffsetHeader = "Upload-Offset"
+)
+
+type uploadSessionResponse struct {
+	ID            string         `json:"id"`
+	Length        int64          `json:"length"`
+	Offset        int64          `json:"offset"`
+	Stored        int64          `json:"stored"`
+	StampedChunks int64          `json:"stampedChunks"`
+	Reference     *swarm.Address `json:"reference,omitempty"`
+}
+
+func newUploadSessionResponse(s *uploadsession.Session) uploadSessionResponse {
+	r := uploadSessionResponse{
+		ID:            s.ID,
+		Length:        s.Length,
+		Offset:        s.Offset,
+		Stored:        s.Stored(),
+		StampedChunks: s.StampedChunks,
+	}
+	if s.Completed() {
+		r.Reference = &s.Reference
+	}
+	return r
+}
+
+// uploadSessionCreateHandler creates the session of a resumable upload of
+// the content with the length in the Upload-Length header.
+func (s *server) uploadSessionCreateHandler(w http.ResponseWriter, r *http.Request) {
+	length, err := strconv.ParseInt(r.Header.Get(UploadLengthHeader), 10, 64)
+	if err != nil || length < 0 {
+		s.logger.Debugf("upload session: invalid upload length %q: %v", r.Header.Get(UploadLengthHeader), err)
+		s.logger.Error("upload session: invalid upload length")
+		jsonhttp.BadRequest(w, "invalid upload length")
+		return
+	}
+
+	batch, err := requestPostageBatchId(r)
+	if err != nil {
+		s.logger.Debugf("upload session: postage batch id: %v", err)
+		s.logger.Error("upload session: postage batch id")
+		jsonhttp.BadRequest(w, "invalid postage batch id")
+		return
+	}
+	if _, err := s.post.GetStampIssuer(batch); err != nil {
+		s.logger.Debugf("upload session: stamp issuer %x: %v", batch, err)
+		s.logger.Error("upload session: stamp issuer")
+		jsonhttp.BadRequest(w, "invalid postage batch id")
+		return
+	}
+
+	pin := strings.ToLower(r.Header.Get(SwarmPinHeader)) == "true"
+	session, err := s.UploadSessions.Create(length, batch, pin)
+	if err != nil {
+		s.logger.Debugf("upload session: create: %v", err)
+		s.logger.Error("upload session: create")
+		jsonhttp.InternalServerError(w, nil)
+		return
+	}
+
+	w.Header().Set("Location", "/uploads/"+session.ID)
+	w.Header().Set(UploadOffsetHeader, "0")
+	w.Header().Set("Access-Control-Expose-Headers", "Location, "+UploadOffsetHeader)
+	jsonhttp.Created(w, newUploadSessionResponse(session))
+}
+
+// uploadSessionGetHandler returns the progress of the upload session.
+func (s *server) uploadSessionGetHandler(w http.ResponseWriter, r *http.Request) {
+	session, err := s.UploadSessions.Get(mux.Vars(r)["id"])
+	if err != nil {
+		if errors.Is(err, uploadsession.ErrNotFound) {
+			jsonhttp.NotFound(w, "upload session not found")
+			return
+		}
+		s.logger.Debugf("upload session: get: %v", err)
+		s.logger.Error("upload session: get")
+		jsonhttp.InternalServerError(w, nil)
+		return
+	}
+
+	w.Header().Set(UploadOffsetHeader, strconv.FormatInt(session.Offset, 10))
+	w.Header().Set("Access-Control-Expose-Headers", UploadOffsetHeader)
+	jsonhttp.OK(w, newUploadSessionResponse(session))
+}
+
+// uploadSessionPatchHandler stores the range of the data of the upload
+// session which starts at the offset in the Upload-Offset header. The content
+// is stored once all data is received.
+func (s *server) uploadSessionPatchHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	offset, err := strconv.ParseInt(r.Header.Get(UploadOffsetHeader), 10, 64)
+	if err != nil {
+		s.logger.Debugf("upload session: invalid upload offset %q: %v", r.Header.Get(UploadOffsetHeader), err)
+		s.logger.Error("upload session: invalid upload offset")
+		jsonhttp.BadRequest(w, "invalid upload offset")
+		return
+	}
+
+	session, err := s.UploadSessions.Get(id)
+	if err != nil {
+		if errors.Is(err, uploadsession.ErrNotFound) {
+			jsonhttp.NotFound(w, "upload session not found")
+			return
+		}
+		s.logger.Debugf("upload session: get: %v", err)
+		s.logger.Error("upload session: get")
+		jsonhttp.InternalServerError(w, nil)
+		return
+	}
+	if r.ContentLength > session.Length-offset {
+		jsonhttp.RequestEntityTooLarge(w, "data exceeds the upload length")
+		return
+	}
+
+	putter, err := newStamperPutter(s.storer, s.post, s.signer, session.Batch)
+	if err != nil {
+		s.logger.Debugf("upload session: get putter: %v", err)
+		s.logger.Error("upload session: putter")
+		jsonhttp.BadRequest(w, nil)
+		return
+	}
+
+	// the content is scanned once it is joined, as the ranges of the
+	// sessions are not scanned by the upload scan handler
+	var scan uploadsession.Scan
+	if s.UploadScanner != nil {
+		scan = func(_ context.Context, content io.Reader, length int64) error {
+			return s.scanUpload(r, content, uploadscan.Info{Size: length})
+		}
+	}
+
+	var rejected *uploadRejectedError
+	session, err = s.UploadSessions.Write(r.Context(), id, offset, putter, r.Body, scan)
+	switch {
+	case errors.Is(err, uploadsession.ErrNotFound):
+		jsonhttp.NotFound(w, "upload session not found")
+		return
+	case errors.Is(err, uploadsession.ErrBusy):
+		jsonhttp.Conflict(w, "upload session busy")
+		return
+	case errors.Is(err, uploadsession.ErrCompleted):
+		jsonhttp.Conflict(w, "upload session completed")
+		return
+	case errors.Is(err, uploadsession.ErrOffsetMismatch):
+		w.Header().Set(UploadOffsetHeader, strconv.FormatInt(session.Offset, 10))
+		w.Header().Set("Access-Control-Expose-Headers", UploadOffsetHeader)
+		jsonhttp.Conflict(w, fmt.Sprintf("upload offset mismatch, want %d", session.Offset))
+		return
+	case errors.Is(err, uploadsession.ErrInterrupted):
+		s.logger.Debugf("upload session: write %s: %v", id, err)
+		w.Header().Set(UploadOffsetHeader, strconv.FormatInt(session.Offset, 10))
+		w.Header().Set("Access-Control-Expose-Headers", UploadOffsetHeader)
+		jsonhttp.BadRequest(w, "upload interrupted")
+		return
+	case errors.Is(err, postage.ErrBucketFull):
+		jsonhttp.PaymentRequired(w, "batch is overissued")
+		return
+	case errors.Is(err, errUploadScanFailed), errors.As(err, &rejected):
+		respondUploadScanError(w, err)
+		return
+	case err != nil:
+		s.logger.Debugf("upload session: write %s: %v", id, err)
+		s.logger.Error("upload session: write")
+		jsonhttp.InternalServerError(w, nil)
+		return
+	}
+
+	// the chunks are stored unpinned, as the trees of the parts are not the
+	// one of the content, so the pin of the content pins its chunks
+	if session.Completed() && session.Pin {
+		if err := s.pinning.CreatePin(r.Context(), session.Reference, true); err != nil {
+			s.logger.Debugf("upload session: creation of pin for %q failed: %v", session.Reference, err)
+			s.logger.Error("upload session: creation of pin failed")
+			jsonhttp.InternalServerError(w, nil)
+			return
+		}
+	}
+
+	w.Header().Set(UploadOffsetHeader, strconv.FormatInt(session.Offset, 10))
+	w.Header().Set("Access-Control-Expose-Headers", UploadOffsetHeader)
+	jsonhttp.OK(w, newUploadSessionResponse(session))
+}
+
+// uploadSessionDeleteHandler removes the upload session.
+func (s *server) uploadSessionDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	err := s.UploadSessions.Delete(mux.Vars(r)["id"])
+	switch {
+	case errors.Is(err, uploadsession.ErrNotFound):
+		jsonhttp.NotFound(w, "upload session not found")
+		return
+	case errors.Is(err, uploadsession.ErrBusy):
+		jsonhttp.Conflict(w, "upload session busy")
+		return
+	case err != nil:
+		s.logger.Debugf("upload session: delete: %v", err)
+		s.logger.Error("upload session: delete")
+		jsonhttp.InternalServerError(w, nil)
+		return
+	}
+	jsonhttp.OK(w, nil)
+}
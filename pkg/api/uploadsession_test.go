@@ -0,0 +1,183 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/api"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/pinning"
+	mockpost "github.com/ethsana/sana/pkg/postage/mock"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/tags"
+	"github.com/ethsana/sana/pkg/traversal"
+	"github.com/ethsana/sana/pkg/uploadsession"
+)
+
+func TestUploadSession(t *testing.T) {
+	var (
+		storer       = mock.NewStorer()
+		logger       = logging.New(ioutil.Discard, 0)
+		client, _, _ = newTestServer(t, testServerOptions{
+			Storer:         storer,
+			Tags:           tags.NewTags(statestore.NewStateStore(), logger),
+			Logger:         logger,
+			Post:           mockpost.New(mockpost.WithAcceptAll()),
+			UploadSessions: uploadsession.New(statestore.NewStateStore(), storer),
+		})
+	)
+
+	data := make([]byte, 3*swarm.ChunkSize+100)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	length := strconv.Itoa(len(data))
+	first := swarm.ChunkSize + 10
+
+	var session api.UploadSessionResponse
+	jsonhttptest.Request(t, client, http.MethodPost, "/uploads", http.StatusCreated,
+		jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+		jsonhttptest.WithRequestHeader(api.UploadLengthHeader, length),
+		jsonhttptest.WithUnmarshalJSONResponse(&session),
+	)
+	resource := "/uploads/" + session.ID
+
+	header := jsonhttptest.Request(t, client, http.MethodPatch, resource, http.StatusOK,
+		jsonhttptest.WithRequestHeader(api.UploadOffsetHeader, "0"),
+		jsonhttptest.WithRequestBody(bytes.NewReader(data[:first])),
+		jsonhttptest.WithUnmarshalJSONResponse(&session),
+	)
+	if got := header.Get(api.UploadOffsetHeader); got != strconv.Itoa(first) {
+		t.Fatalf("got upload offset %s, want %d", got, first)
+	}
+
+	jsonhttptest.Request(t, client, http.MethodGet, resource, http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(api.UploadSessionResponse{
+			ID:            session.ID,
+			Length:        int64(len(data)),
+			Offset:        int64(first),
+			Stored:        swarm.ChunkSize,
+			StampedChunks: 1,
+		}),
+	)
+
+	jsonhttptest.Request(t, client, http.MethodPatch, resource, http.StatusConflict,
+		jsonhttptest.WithRequestHeader(api.UploadOffsetHeader, "0"),
+		jsonhttptest.WithRequestBody(bytes.NewReader(data[:first])),
+		jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+			Message: "upload offset mismatch, want " + strconv.Itoa(first),
+			Code:    http.StatusConflict,
+		}),
+	)
+
+	jsonhttptest.Request(t, client, http.MethodPatch, resource, http.StatusOK,
+		jsonhttptest.WithRequestHeader(api.UploadOffsetHeader, strconv.Itoa(first)),
+		jsonhttptest.WithRequestBody(bytes.NewReader(data[first:])),
+		jsonhttptest.WithUnmarshalJSONResponse(&session),
+	)
+	if session.Reference == nil {
+		t.Fatal("upload session not completed")
+	}
+
+	var upload api.BytesPostResponse
+	jsonhttptest.Request(t, client, http.MethodPost, "/bytes", http.StatusCreated,
+		jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+		jsonhttptest.WithRequestBody(bytes.NewReader(data)),
+		jsonhttptest.WithUnmarshalJSONResponse(&upload),
+	)
+	if !session.Reference.Equal(upload.Reference) {
+		t.Fatalf("got reference %s, want %s", session.Reference, upload.Reference)
+	}
+
+	jsonhttptest.Request(t, client, http.MethodDelete, resource, http.StatusOK)
+	jsonhttptest.Request(t, client, http.MethodGet, resource, http.StatusNotFound,
+		jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+			Message: "upload session not found",
+			Code:    http.StatusNotFound,
+		}),
+	)
+}
+
+func TestUploadSessionPin(t *testing.T) {
+	var (
+		storer       = mock.NewStorer()
+		logger       = logging.New(ioutil.Discard, 0)
+		traverser    = traversal.New(storer)
+		pins         = pinning.NewService(storer, statestore.NewStateStore(), traverser)
+		client, _, _ = newTestServer(t, testServerOptions{
+			Storer:         storer,
+			Tags:           tags.NewTags(statestore.NewStateStore(), logger),
+			Logger:         logger,
+			Post:           mockpost.New(mockpost.WithAcceptAll()),
+			Traversal:      traverser,
+			Pinning:        pins,
+			UploadSessions: uploadsession.New(statestore.NewStateStore(), storer),
+		})
+	)
+
+	data := make([]byte, 3*swarm.ChunkSize+100)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	var session api.UploadSessionResponse
+	jsonhttptest.Request(t, client, http.MethodPost, "/uploads", http.StatusCreated,
+		jsonhttptest.WithRequestHeader(api.SwarmPostageBatchIdHeader, batchOkStr),
+		jsonhttptest.WithRequestHeader(api.SwarmPinHeader, "true"),
+		jsonhttptest.WithRequestHeader(api.UploadLengthHeader, strconv.Itoa(len(data))),
+		jsonhttptest.WithUnmarshalJSONResponse(&session),
+	)
+	resource := "/uploads/" + session.ID
+
+	// the content is written in two parts, so that it is joined from their
+	// trees
+	first := swarm.ChunkSize + 10
+	jsonhttptest.Request(t, client, http.MethodPatch, resource, http.StatusOK,
+		jsonhttptest.WithRequestHeader(api.UploadOffsetHeader, "0"),
+		jsonhttptest.WithRequestBody(bytes.NewReader(data[:first])),
+	)
+	jsonhttptest.Request(t, client, http.MethodPatch, resource, http.StatusOK,
+		jsonhttptest.WithRequestHeader(api.UploadOffsetHeader, strconv.Itoa(first)),
+		jsonhttptest.WithRequestBody(bytes.NewReader(data[first:])),
+		jsonhttptest.WithUnmarshalJSONResponse(&session),
+	)
+	if session.Reference == nil {
+		t.Fatal("upload session not completed")
+	}
+
+	has, err := pins.HasPin(*session.Reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !has {
+		t.Fatal("content not pinned")
+	}
+	var count int
+	if err := traverser.Traverse(context.Background(), *session.Reference, func(addr swarm.Address) error {
+		count++
+		if mode := storer.GetModeSet(addr); mode != storage.ModeSetPin {
+			t.Errorf("chunk %s not pinned", addr)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// the four data chunks and the root
+	if count != 5 {
+		t.Fatalf("got %d chunks, want 5", count)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package apiclient provides access to the chunks of a running node through
+// its HTTP API, so that files and manifests can be processed by command line
+// tools and by the nodes in the light-remote mode with the same packages that
+// the node uses.
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/cac"
+	"github.com/ethsana/sana/pkg/pushsync"
+	"github.com/ethsana/sana/pkg/soc"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+const (
+	postageBatchIdHeader = "Swarm-Postage-Batch-Id"
+	postageStampHeader   = "Swarm-Postage-Stamp"
+)
+
+// ErrNoPostageBatch is returned when chunks are uploaded by a client without
+// a postage batch.
+var ErrNoPostageBatch = errors.New("apiclient: no postage batch")
+
+// ErrInvalidChunk is returned when the node responds with a chunk which is
+// neither content addressed nor single owner chunk with the address.
+var ErrInvalidChunk = errors.New("apiclient: invalid chunk")
+
+var errChunkTooLarge = errors.New("chunk data too large")
+
+// Options configure the Client.
+type Options struct {
+	// HTTPClient is used for requests, http.DefaultClient if nil.
+	HTTPClient *http.Client
+	// PostageBatch is used to stamp uploaded chunks.
+	PostageBatch []byte
+}
+
+// Client gets and puts chunks using the node HTTP API. It implements the
+// storage.Getter and storage.Putter interfaces.
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	postageBatch string
+}
+
+// New creates a new Client for the node API at the base URL.
+func New(baseURL string, o Options) *Client {
+	if o.HTTPClient == nil {
+		o.HTTPClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		httpClient:   o.HTTPClient,
+		postageBatch: hex.EncodeToString(o.PostageBatch),
+	}
+}
+
+// Get returns the chunk with the address. The chunks are validated, so that
+// the node can not respond with the data of the other chunks.
+func (c *Client) Get(ctx context.Context, _ storage.ModeGet, addr swarm.Address) (swarm.Chunk, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/chunks/"+addr.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, storage.ErrNotFound
+	default:
+		return nil, responseError(resp)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, swarm.ChunkWithSpanSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read chunk %s: %w", addr, err)
+	}
+	if len(data) > swarm.ChunkWithSpanSize {
+		return nil, fmt.Errorf("chunk %s: %w", addr, errChunkTooLarge)
+	}
+	ch := swarm.NewChunk(addr, data)
+	if !cac.Valid(ch) && !soc.Valid(ch) {
+		return nil, fmt.Errorf("chunk %s: %w", addr, ErrInvalidChunk)
+	}
+	return ch, nil
+}
+
+// Put uploads the chunks with their stamps, the chunks without one are
+// stamped by the node with the configured postage batch. Single owner chunks
+// are uploaded with their signature, all other chunks must be content
+// addressed.
+func (c *Client) Put(ctx context.Context, _ storage.ModePut, chs ...swarm.Chunk) ([]bool, error) {
+	exists := make([]bool, len(chs))
+	for _, ch := range chs {
+		var stamp string
+		if ch.Stamp() != nil {
+			b, err := ch.Stamp().MarshalBinary()
+			if err != nil {
+				return nil, fmt.Errorf("put chunk %s: %w", ch.Address(), err)
+			}
+			stamp = hex.EncodeToString(b)
+		} else if c.postageBatch == "" {
+			return nil, ErrNoPostageBatch
+		}
+
+		url, data := c.baseURL+"/chunks", ch.Data()
+		if !cac.Valid(ch) && soc.Valid(ch) {
+			s, err := soc.FromChunk(ch)
+			if err != nil {
+				return nil, fmt.Errorf("put chunk %s: %w", ch.Address(), err)
+			}
+			url = fmt.Sprintf("%s/soc/%x/%x?sig=%x", c.baseURL, s.OwnerAddress(), s.ID(), s.Signature())
+			data = s.WrappedChunk().Data()
+		}
+
+		var ref swarm.Address
+		if err := c.post(ctx, url, bytes.NewReader(data), stamp, &ref); err != nil {
+			return nil, fmt.Errorf("put chunk %s: %w", ch.Address(), err)
+		}
+		if !ref.Equal(ch.Address()) {
+			return nil, fmt.Errorf("chunk %s stored as %s", ch.Address(), ref)
+		}
+	}
+	return exists, nil
+}
+
+// CreateFeedManifest stores the manifest of the sequence feed and returns its
+// reference which resolves to the latest feed update.
+func (c *Client) CreateFeedManifest(ctx context.Context, owner common.Address, topic []byte) (swarm.Address, error) {
+	if c.postageBatch == "" {
+		return swarm.ZeroAddress, ErrNoPostageBatch
+	}
+
+	var ref swarm.Address
+	url := fmt.Sprintf("%s/feeds/%x/%x", c.baseURL, owner.Bytes(), topic)
+	if err := c.post(ctx, url, nil, "", &ref); err != nil {
+		return swarm.ZeroAddress, fmt.Errorf("create feed manifest: %w", err)
+	}
+	return ref, nil
+}
+
+// Reupload makes the node push the content with the reference to the
+// network again.
+func (c *Client) Reupload(ctx context.Context, ref swarm.Address) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPatch, c.baseURL+"/bzz/"+ref.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return storage.ErrNotFound
+	default:
+		return fmt.Errorf("reupload %s: %w", ref, responseError(resp))
+	}
+}
+
+// PushChunkToClosest uploads the stamped chunk to be pushed by the node. It
+// implements the pushsync.PushSyncer interface, for the pss messages sent
+// through the node.
+func (c *Client) PushChunkToClosest(ctx context.Context, ch swarm.Chunk) (*pushsync.Receipt, error) {
+	if _, err := c.Put(ctx, storage.ModePutUpload, ch); err != nil {
+		return nil, err
+	}
+	return &pushsync.Receipt{Address: ch.Address()}, nil
+}
+
+// post sends the request stamped with the stamp, or with the configured
+// postage batch if it is empty, and decodes the returned reference.
+func (c *Client) post(ctx context.Context, url string, body io.Reader, stamp string, ref *swarm.Address) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/octet-stream")
+	if stamp != "" {
+		r.Header.Set(postageStampHeader, stamp)
+	} else {
+		r.Header.Set(postageBatchIdHeader, c.postageBatch)
+	}
+
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return responseError(resp)
+	}
+	var v struct {
+		Reference swarm.Address `json:"reference"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	*ref = v.Reference
+	return nil
+}
+
+// responseError returns the error from the API response.
+func responseError(resp *http.Response) error {
+	var r struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&r); err != nil || r.Message == "" {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return fmt.Errorf("unexpected response status %s: %s", resp.Status, r.Message)
+}
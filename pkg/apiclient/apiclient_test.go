@@ -0,0 +1,174 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package apiclient_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/api"
+	"github.com/ethsana/sana/pkg/apiclient"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/feeds"
+	"github.com/ethsana/sana/pkg/feeds/sequence"
+	"github.com/ethsana/sana/pkg/file/loadsave"
+	"github.com/ethsana/sana/pkg/logging"
+	mockpost "github.com/ethsana/sana/pkg/postage/mock"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	testingc "github.com/ethsana/sana/pkg/storage/testing"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/tags"
+)
+
+func newTestServer(t *testing.T, o api.Options) string {
+	t.Helper()
+
+	pk, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logging.New(ioutil.Discard, 0)
+	s := api.New(tags.NewTags(statestore.NewStateStore(), logger), mock.NewStorer(), nil, nil, nil, nil, nil, mockpost.New(mockpost.WithAcceptAll()), nil, nil, crypto.NewDefaultSigner(pk), logger, nil, o)
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func TestClient(t *testing.T) {
+	url := newTestServer(t, api.Options{})
+	ctx := context.Background()
+
+	batch := make([]byte, 32)
+	rand.Read(batch)
+	client := apiclient.New(url, apiclient.Options{PostageBatch: batch})
+
+	data := make([]byte, 3*swarm.ChunkSize+17)
+	rand.Read(data)
+
+	ls := loadsave.New(client, storage.ModePutUpload, false)
+	ref, err := ls.Save(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ls.Load(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("loaded data does not match saved data")
+	}
+
+	_, err = client.Get(ctx, storage.ModeGetRequest, swarm.MustParseHexAddress("aabbccddeeff00112233445566778899aabbccddeeff00112233445566778899"))
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+	}
+
+	_, err = apiclient.New(url, apiclient.Options{}).Put(ctx, storage.ModePutUpload, swarm.NewChunk(swarm.ZeroAddress, nil))
+	if !errors.Is(err, apiclient.ErrNoPostageBatch) {
+		t.Fatalf("got error %v, want %v", err, apiclient.ErrNoPostageBatch)
+	}
+
+	t.Run("feed", func(t *testing.T) {
+		pk, err := crypto.GenerateSecp256k1Key()
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer := crypto.NewDefaultSigner(pk)
+		topic := make([]byte, 32)
+
+		updater, err := sequence.NewUpdater(client, signer, topic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := updater.Update(ctx, time.Now().Unix(), ref); err != nil {
+			t.Fatal(err)
+		}
+
+		ch, err := feeds.Latest(ctx, sequence.NewFinder(client, updater.Feed()), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, payload, err := feeds.FromChunk(ch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(payload, ref) {
+			t.Fatalf("got feed payload %x, want %x", payload, ref)
+		}
+
+		if _, err := client.CreateFeedManifest(ctx, updater.Feed().Owner, topic); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestClientInvalidChunk(t *testing.T) {
+	valid := testingc.GenerateTestRandomChunk()
+	other := testingc.GenerateTestRandomChunk()
+
+	// the node responds with the data of the other chunk
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(other.Data())
+	}))
+	t.Cleanup(ts.Close)
+
+	_, err := apiclient.New(ts.URL, apiclient.Options{}).Get(context.Background(), storage.ModeGetRequest, valid.Address())
+	if !errors.Is(err, apiclient.ErrInvalidChunk) {
+		t.Fatalf("got error %v, want %v", err, apiclient.ErrInvalidChunk)
+	}
+}
+
+func TestStore(t *testing.T) {
+	var stamps [][]byte
+	url := newTestServer(t, api.Options{
+		ValidStamp: func(ch swarm.Chunk, stamp []byte) (swarm.Chunk, error) {
+			stamps = append(stamps, stamp)
+			return ch, nil
+		},
+	})
+	ctx := context.Background()
+
+	// the chunks are uploaded with their stamps without a postage batch
+	store := apiclient.NewStore(apiclient.New(url, apiclient.Options{}))
+	ch := testingc.GenerateTestRandomChunk()
+	if _, err := store.Put(ctx, storage.ModePutUpload, ch); err != nil {
+		t.Fatal(err)
+	}
+	want, err := ch.Stamp().MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stamps) != 1 || !bytes.Equal(stamps[0], want) {
+		t.Fatalf("got stamps %x, want %x", stamps, want)
+	}
+
+	got, err := store.GetMulti(ctx, storage.ModeGetRequest, ch.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !bytes.Equal(got[0].Data(), ch.Data()) {
+		t.Fatal("got chunk data does not match put chunk data")
+	}
+
+	has, err := store.Has(ctx, ch.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if has {
+		t.Fatal("remote store has the chunk locally")
+	}
+	if err := store.Set(ctx, storage.ModeSetPin, ch.Address()); !errors.Is(err, apiclient.ErrNotSupported) {
+		t.Fatalf("got error %v, want %v", err, apiclient.ErrNotSupported)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package apiclient
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+// ErrNotSupported is returned by the Store for the operations on the chunks
+// stored locally, which it has none of.
+var ErrNotSupported = errors.New("apiclient: not supported by the remote store")
+
+var _ storage.Storer = (*Store)(nil)
+
+// Store is the storage.Storer which keeps no chunks but gets and puts them
+// through the API of the node of the Client, so that the API of another node
+// is served without a local store.
+type Store struct {
+	*Client
+}
+
+// NewStore returns the Store of the chunks of the node of the client.
+func NewStore(c *Client) *Store {
+	return &Store{Client: c}
+}
+
+// GetMulti returns the chunks with the addresses.
+func (s *Store) GetMulti(ctx context.Context, mode storage.ModeGet, addrs ...swarm.Address) ([]swarm.Chunk, error) {
+	chs := make([]swarm.Chunk, 0, len(addrs))
+	for _, addr := range addrs {
+		ch, err := s.Get(ctx, mode, addr)
+		if err != nil {
+			return nil, err
+		}
+		chs = append(chs, ch)
+	}
+	return chs, nil
+}
+
+// Has reports that no chunk is stored locally, so that every chunk is put.
+func (s *Store) Has(context.Context, swarm.Address) (bool, error) {
+	return false, nil
+}
+
+// HasMulti reports that no chunk is stored locally.
+func (s *Store) HasMulti(_ context.Context, addrs ...swarm.Address) ([]bool, error) {
+	return make([]bool, len(addrs)), nil
+}
+
+// Set returns ErrNotSupported, the chunks can not be pinned locally.
+func (s *Store) Set(context.Context, storage.ModeSet, ...swarm.Address) error {
+	return ErrNotSupported
+}
+
+// LastPullSubscriptionBinID returns ErrNotSupported.
+func (s *Store) LastPullSubscriptionBinID(uint8) (uint64, error) {
+	return 0, ErrNotSupported
+}
+
+// SubscribePull returns a closed channel, there are no chunks to sync.
+func (s *Store) SubscribePull(context.Context, uint8, uint64, uint64) (<-chan storage.Descriptor, <-chan struct{}, func()) {
+	c, closed := make(chan storage.Descriptor), make(chan struct{})
+	close(c)
+	close(closed)
+	return c, closed, func() {}
+}
+
+// SubscribePush returns a closed channel, the chunks are pushed by the node
+// of the client.
+func (s *Store) SubscribePush(context.Context) (<-chan swarm.Chunk, func()) {
+	c := make(chan swarm.Chunk)
+	close(c)
+	return c, func() {}
+}
+
+// Close does nothing.
+func (s *Store) Close() error {
+	return nil
+}
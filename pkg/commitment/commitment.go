@@ -0,0 +1,242 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package commitment periodically commits to the contents of the reserve of
+// the node. The commitment is the root of a Merkle tree over the addresses of
+// the reserved chunks and is published as an update of a feed of the node, so
+// that anybody can request inclusion proofs for chunks and verify that the
+// node stored them at the time of the commitment.
+package commitment
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/feeds"
+	"github.com/ethsana/sana/pkg/feeds/sequence"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+	"golang.org/x/crypto/sha3"
+)
+
+const feedIndexKey = "commitment_feed_index"
+
+// Topic is the topic of the feed of the node which the commitments are
+// published to. The payload of an update is the root followed by the number
+// of committed chunks as a big endian uint64.
+var Topic = func() []byte {
+	h := sha3.NewLegacyKeccak256()
+	_, _ = h.Write([]byte("sana-reserve-commitment"))
+	return h.Sum(nil)
+}()
+
+// ErrNoCommitment is returned if no commitment was computed yet.
+var ErrNoCommitment = errors.New("commitment: no commitment")
+
+// Reserve iterates the chunks in the reserve of the node.
+type Reserve interface {
+	IterateReserve(fn func(addr swarm.Address) (stop bool, err error)) error
+}
+
+// Commitment is a commitment to the contents of the reserve.
+type Commitment struct {
+	Root      swarm.Address `json:"root"`
+	Chunks    uint64        `json:"chunks"`
+	Timestamp int64         `json:"timestamp"`
+	// Published reports whether the commitment was published to the feed,
+	// with the sequence number FeedIndex.
+	Published bool   `json:"published"`
+	FeedIndex uint64 `json:"feedIndex"`
+}
+
+// Options configure the Service.
+type Options struct {
+	// Interval is the time between the commitments.
+	Interval time.Duration
+	// PostageBatch is the batch which stamps the feed updates. The
+	// commitments are not published without a batch.
+	PostageBatch []byte
+}
+
+// Service computes and publishes the commitments. The tree of the latest
+// commitment is held in memory to answer requests for proofs.
+type Service struct {
+	reserve  Reserve
+	putter   storage.Putter
+	post     postage.Service
+	signer   crypto.Signer
+	store    storage.StateStorer
+	logger   logging.Logger
+	interval time.Duration
+	batch    []byte
+
+	mu         sync.Mutex
+	tree       *tree
+	commitment *Commitment
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New returns a new Service.
+func New(reserve Reserve, putter storage.Putter, post postage.Service, signer crypto.Signer, store storage.StateStorer, logger logging.Logger, o Options) *Service {
+	return &Service{
+		reserve:  reserve,
+		putter:   putter,
+		post:     post,
+		signer:   signer,
+		store:    store,
+		logger:   logger,
+		interval: o.Interval,
+		batch:    o.PostageBatch,
+		quit:     make(chan struct{}),
+	}
+}
+
+// Start begins the periodic commitments.
+func (s *Service) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			<-s.quit
+			cancel()
+		}()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.Commit(ctx); err != nil {
+				s.logger.Debugf("commitment: %v", err)
+				s.logger.Error("commitment: unable to commit to the reserve")
+			}
+			select {
+			case <-s.quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Commit computes a commitment to the current reserve and publishes it.
+func (s *Service) Commit(ctx context.Context) (*Commitment, error) {
+	var addrs []swarm.Address
+	err := s.reserve.IterateReserve(func(addr swarm.Address) (bool, error) {
+		addrs = append(addrs, addr)
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("iterate reserve: %w", err)
+	}
+
+	t := newTree(addrs)
+	c := &Commitment{
+		Root:      t.root(),
+		Chunks:    uint64(len(addrs)),
+		Timestamp: time.Now().Unix(),
+	}
+
+	s.mu.Lock()
+	s.tree, s.commitment = t, c
+	s.mu.Unlock()
+
+	if s.batch == nil {
+		return c, nil
+	}
+	if err := s.publish(ctx, c); err != nil {
+		return c, fmt.Errorf("publish: %w", err)
+	}
+	s.logger.Debugf("commitment: published root %s of %d chunks", c.Root, c.Chunks)
+	return c, nil
+}
+
+func (s *Service) publish(ctx context.Context, c *Commitment) error {
+	var index uint64
+	if err := s.store.Get(feedIndexKey, &index); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	issuer, err := s.post.GetStampIssuer(s.batch)
+	if err != nil {
+		return fmt.Errorf("stamp issuer: %w", err)
+	}
+	putter, err := feeds.NewPutter(&stamperPutter{Putter: s.putter, stamper: postage.NewStamper(issuer, s.signer)}, s.signer, Topic)
+	if err != nil {
+		return err
+	}
+
+	payload := make([]byte, swarm.HashSize+8)
+	copy(payload, c.Root.Bytes())
+	binary.BigEndian.PutUint64(payload[swarm.HashSize:], c.Chunks)
+	if err := putter.Put(ctx, sequence.NewIndex(index), c.Timestamp, payload); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	c.Published, c.FeedIndex = true, index
+	s.mu.Unlock()
+	return s.store.Put(feedIndexKey, index+1)
+}
+
+// Latest returns the latest commitment.
+func (s *Service) Latest() (*Commitment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.commitment == nil {
+		return nil, ErrNoCommitment
+	}
+	c := *s.commitment
+	return &c, nil
+}
+
+// Proof returns the proof of inclusion of the chunk in the latest commitment.
+func (s *Service) Proof(addr swarm.Address) (*Commitment, *Proof, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.commitment == nil {
+		return nil, nil, ErrNoCommitment
+	}
+	p, err := s.tree.proof(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := *s.commitment
+	return &c, p, nil
+}
+
+// Close stops the periodic commitments.
+func (s *Service) Close() error {
+	close(s.quit)
+	s.wg.Wait()
+	return nil
+}
+
+// stamperPutter stamps the chunks with the postage batch of the commitments
+// before they are stored.
+type stamperPutter struct {
+	storage.Putter
+	stamper postage.Stamper
+}
+
+func (p *stamperPutter) Put(ctx context.Context, mode storage.ModePut, chs ...swarm.Chunk) ([]bool, error) {
+	for i, ch := range chs {
+		stamp, err := p.stamper.Stamp(ch.Address())
+		if err != nil {
+			return nil, err
+		}
+		chs[i] = ch.WithStamp(stamp)
+	}
+	return p.Putter.Put(ctx, mode, chs...)
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commitment_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/commitment"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/feeds"
+	"github.com/ethsana/sana/pkg/feeds/sequence"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/postage"
+	mockpost "github.com/ethsana/sana/pkg/postage/mock"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/swarm/test"
+)
+
+type reserve []swarm.Address
+
+func (r reserve) IterateReserve(fn func(swarm.Address) (bool, error)) error {
+	for _, a := range r {
+		if stop, err := fn(a); err != nil || stop {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestProof(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	for _, n := range []int{0, 1, 2, 3, 5, 8, 13} {
+		r := make(reserve, n)
+		for i := range r {
+			r[i] = test.RandomAddress()
+		}
+		s := commitment.New(r, nil, nil, nil, statestore.NewStateStore(), logger, commitment.Options{})
+		if _, _, err := s.Proof(test.RandomAddress()); !errors.Is(err, commitment.ErrNoCommitment) {
+			t.Fatalf("got error %v, want %v", err, commitment.ErrNoCommitment)
+		}
+		c, err := s.Commit(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Chunks != uint64(n) || c.Published {
+			t.Fatalf("got commitment %+v", c)
+		}
+
+		for _, a := range r {
+			_, p, err := s.Proof(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !commitment.Verify(c.Root, p) {
+				t.Fatalf("%d chunks: proof of %s not verified", n, a)
+			}
+			forged := *p
+			forged.Address = test.RandomAddress()
+			if commitment.Verify(c.Root, &forged) {
+				t.Fatalf("%d chunks: forged proof verified", n)
+			}
+		}
+		if _, _, err := s.Proof(test.RandomAddress()); !errors.Is(err, commitment.ErrNotCommitted) {
+			t.Fatalf("got error %v, want %v", err, commitment.ErrNotCommitted)
+		}
+	}
+}
+
+func TestPublish(t *testing.T) {
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.NewDefaultSigner(key)
+	owner, err := signer.EthereumAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch := make([]byte, 32)
+	issuer := postage.NewStampIssuer("label", "", batch, big.NewInt(1), 17, 16, 0, false)
+	storer := mock.NewStorer()
+	r := reserve{test.RandomAddress(), test.RandomAddress()}
+	s := commitment.New(r, storer, mockpost.New(mockpost.WithIssuer(issuer)), signer, statestore.NewStateStore(), logging.New(ioutil.Discard, 0), commitment.Options{
+		Interval:     time.Hour,
+		PostageBatch: batch,
+	})
+
+	for i := uint64(0); i < 2; i++ {
+		c, err := s.Commit(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !c.Published || c.FeedIndex != i {
+			t.Fatalf("got commitment %+v", c)
+		}
+
+		ch, err := feeds.New(commitment.Topic, owner).Update(sequence.NewIndex(i)).Address()
+		if err != nil {
+			t.Fatal(err)
+		}
+		update, err := storer.Get(context.Background(), 0, ch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if update.Stamp() == nil {
+			t.Fatal("update not stamped")
+		}
+		_, payload, err := feeds.FromChunk(update)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !swarm.NewAddress(payload[:swarm.HashSize]).Equal(c.Root) || binary.BigEndian.Uint64(payload[swarm.HashSize:]) != 2 {
+			t.Fatalf("got payload %x", payload)
+		}
+	}
+}
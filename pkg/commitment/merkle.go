@@ -0,0 +1,140 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commitment
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/ethsana/sana/pkg/swarm"
+	"golang.org/x/crypto/sha3"
+)
+
+// Prefixes of the hashed values which separate the leaves of the tree from
+// its inner nodes, so that an inner node can not be presented as a leaf.
+const (
+	leafPrefix byte = 0
+	nodePrefix byte = 1
+)
+
+// ErrNotCommitted is returned if a proof is requested for a chunk which is
+// not included in the commitment.
+var ErrNotCommitted = errors.New("commitment: chunk not committed")
+
+// Proof proves the inclusion of a chunk in a commitment. The siblings are the
+// hashes of the nodes next to the path from the leaf to the root, from the
+// bottom up. A node without a sibling at its level is moved up unchanged, so
+// the proof may be shorter than the height of the tree.
+type Proof struct {
+	Address  swarm.Address   `json:"address"`
+	Index    uint64          `json:"index"`
+	Count    uint64          `json:"count"`
+	Siblings []swarm.Address `json:"siblings"`
+}
+
+// tree is a binary Merkle tree over the addresses of the committed chunks
+// sorted in ascending order.
+type tree struct {
+	addrs  []swarm.Address
+	levels [][][]byte
+}
+
+func newTree(addrs []swarm.Address) *tree {
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i].Bytes(), addrs[j].Bytes()) < 0
+	})
+	level := make([][]byte, len(addrs))
+	for i, a := range addrs {
+		level[i] = hash(leafPrefix, a.Bytes())
+	}
+	t := &tree{addrs: addrs, levels: [][][]byte{level}}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, hash(nodePrefix, level[i], level[i+1]))
+		}
+		t.levels = append(t.levels, next)
+		level = next
+	}
+	return t
+}
+
+// root returns the root hash of the tree, which is the zero address if no
+// chunks are committed.
+func (t *tree) root() swarm.Address {
+	top := t.levels[len(t.levels)-1]
+	if len(top) == 0 {
+		return swarm.NewAddress(make([]byte, swarm.HashSize))
+	}
+	return swarm.NewAddress(top[0])
+}
+
+func (t *tree) proof(addr swarm.Address) (*Proof, error) {
+	i := sort.Search(len(t.addrs), func(i int) bool {
+		return bytes.Compare(t.addrs[i].Bytes(), addr.Bytes()) >= 0
+	})
+	if i == len(t.addrs) || !t.addrs[i].Equal(addr) {
+		return nil, ErrNotCommitted
+	}
+
+	p := &Proof{
+		Address:  addr,
+		Index:    uint64(i),
+		Count:    uint64(len(t.addrs)),
+		Siblings: make([]swarm.Address, 0),
+	}
+	for _, level := range t.levels[:len(t.levels)-1] {
+		sibling := i ^ 1
+		if sibling < len(level) {
+			p.Siblings = append(p.Siblings, swarm.NewAddress(level[sibling]))
+		}
+		i /= 2
+	}
+	return p, nil
+}
+
+// Verify reports whether the proof shows the inclusion of its chunk in the
+// commitment with the root.
+func Verify(root swarm.Address, p *Proof) bool {
+	if p.Index >= p.Count {
+		return false
+	}
+	h := hash(leafPrefix, p.Address.Bytes())
+	i, n := p.Index, p.Count
+	siblings := p.Siblings
+	for n > 1 {
+		switch {
+		case i%2 == 1:
+			if len(siblings) == 0 {
+				return false
+			}
+			h = hash(nodePrefix, siblings[0].Bytes(), h)
+			siblings = siblings[1:]
+		case i+1 < n:
+			if len(siblings) == 0 {
+				return false
+			}
+			h = hash(nodePrefix, h, siblings[0].Bytes())
+			siblings = siblings[1:]
+		}
+		i /= 2
+		n = (n + 1) / 2
+	}
+	return len(siblings) == 0 && bytes.Equal(h, root.Bytes())
+}
+
+func hash(prefix byte, data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	_, _ = h.Write([]byte{prefix})
+	for _, d := range data {
+		_, _ = h.Write(d)
+	}
+	return h.Sum(nil)
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// defaultNetworkProfiles are the profiles of the public networks. The
+// contract addresses which are not set default to the deployments known for
+// the chain.
+const defaultNetworkProfiles = `
+mainnet:
+  network-id: 100
+  chain-id: 100
+  block-time: 15
+  bootnodes:
+    - /dnsaddr/mainnet.ethsana.org
+testnet:
+  network-id: 5
+  chain-id: 5
+  block-time: 15
+  bootnodes:
+    - /dnsaddr/testnet.ethsana.org
+dev:
+  network-id: 31337
+  chain-id: 31337
+  block-time: 15
+  bootnodes: []
+`
+
+// NetworkProfile holds the settings which have to be consistent across the
+// nodes of a network.
+type NetworkProfile struct {
+	NetworkID uint64 `yaml:"network-id"`
+	// ChainID is the id of the chain backend, it is provided by the chain
+	// if it is zero.
+	ChainID   int64    `yaml:"chain-id"`
+	Bootnodes []string `yaml:"bootnodes"`
+	// BlockTime is the block time of the chain in seconds.
+	BlockTime           uint64 `yaml:"block-time"`
+	PostageStampAddress string `yaml:"postage-stamp-address"`
+	PriceOracleAddress  string `yaml:"price-oracle-address"`
+	SwapFactoryAddress  string `yaml:"swap-factory-address"`
+	MineAddress         string `yaml:"mine-address"`
+}
+
+func (p NetworkProfile) validate() error {
+	if p.NetworkID == 0 {
+		return errors.New("network id not set")
+	}
+	if p.ChainID < 0 {
+		return fmt.Errorf("invalid chain id %d", p.ChainID)
+	}
+	for name, a := range map[string]string{
+		"postage stamp": p.PostageStampAddress,
+		"price oracle":  p.PriceOracleAddress,
+		"swap factory":  p.SwapFactoryAddress,
+		"mine contract": p.MineAddress,
+	} {
+		if a != "" && !common.IsHexAddress(a) {
+			return fmt.Errorf("malformed %s address %q", name, a)
+		}
+	}
+	return nil
+}
+
+// NetworkProfiles maps the network names to their profiles.
+type NetworkProfiles map[string]NetworkProfile
+
+// DefaultNetworkProfiles returns the profiles of the public networks.
+func DefaultNetworkProfiles() NetworkProfiles {
+	p, err := ParseNetworkProfiles([]byte(defaultNetworkProfiles))
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// ParseNetworkProfiles parses the profiles from YAML, a mapping of the
+// network names to their profiles.
+func ParseNetworkProfiles(data []byte) (NetworkProfiles, error) {
+	var p NetworkProfiles
+	if err := yaml.UnmarshalStrict(data, &p); err != nil {
+		return nil, err
+	}
+	for name, profile := range p {
+		if err := profile.validate(); err != nil {
+			return nil, fmt.Errorf("network %s: %w", name, err)
+		}
+	}
+	return p, nil
+}
+
+// Load adds the profiles of the YAML file, replacing the ones with the same
+// names.
+func (p NetworkProfiles) Load(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	profiles, err := ParseNetworkProfiles(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for name, profile := range profiles {
+		p[name] = profile
+	}
+	return nil
+}
+
+// Names returns the sorted network names.
+func (p NetworkProfiles) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ByNetworkID returns the profile of the network with the id. If multiple
+// profiles have the id, the first one by name is returned.
+func (p NetworkProfiles) ByNetworkID(id uint64) (NetworkProfile, bool) {
+	for _, name := range p.Names() {
+		if p[name].NetworkID == id {
+			return p[name], true
+		}
+	}
+	return NetworkProfile{}, false
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/config"
+)
+
+func TestNetworkProfiles(t *testing.T) {
+	profiles := config.DefaultNetworkProfiles()
+	if got, want := profiles.Names(), []string{"dev", "mainnet", "testnet"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got networks %v, want %v", got, want)
+	}
+	mainnet, ok := profiles.ByNetworkID(100)
+	if !ok {
+		t.Fatal("main network not found")
+	}
+	if mainnet.ChainID != 100 || !reflect.DeepEqual(mainnet.Bootnodes, []string{"/dnsaddr/mainnet.ethsana.org"}) {
+		t.Fatalf("got main network profile %+v", mainnet)
+	}
+	if _, ok := profiles.ByNetworkID(42); ok {
+		t.Fatal("unexpected profile")
+	}
+
+	dir, err := ioutil.TempDir("", "sana-network-profiles-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "networks.yaml")
+	if err := ioutil.WriteFile(path, []byte(`
+private:
+  network-id: 4242
+  chain-id: 1337
+  block-time: 2
+  bootnodes:
+    - /ip4/10.0.0.1/tcp/1634/p2p/16Uiu2HAmTm17toLDaPYzRyjKn27iCB76yjKnJ5DjQXneFmifFvaX
+  postage-stamp-address: 0x9fE46736679d2D9a65F0992F2272dE9f3c7fa6e0
+dev:
+  network-id: 31338
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := profiles.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	private, ok := profiles["private"]
+	if !ok {
+		t.Fatal("private network not loaded")
+	}
+	if private.NetworkID != 4242 || private.ChainID != 1337 || private.BlockTime != 2 || private.PostageStampAddress != "0x9fE46736679d2D9a65F0992F2272dE9f3c7fa6e0" {
+		t.Fatalf("got private network profile %+v", private)
+	}
+	if profiles["dev"].NetworkID != 31338 {
+		t.Fatalf("dev network is not replaced: %+v", profiles["dev"])
+	}
+
+	for _, tc := range []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "missing network id", data: "net:\n  chain-id: 1\n", err: "network id not set"},
+		{name: "malformed address", data: "net:\n  network-id: 1\n  mine-address: 0x1\n", err: "malformed mine contract address"},
+		{name: "unknown field", data: "net:\n  network-id: 1\n  bootnode: []\n", err: "not found"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := config.ParseNetworkProfiles([]byte(tc.data))
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("got error %v, want %q", err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clef
+
+import (
+	m "github.com/ethsana/sana/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	Available           prometheus.Gauge
+	Disconnections      prometheus.Counter
+	Reconnections       prometheus.Counter
+	ReconnectFailures   prometheus.Counter
+	UnavailableRequests prometheus.Counter
+}
+
+func newMetrics() metrics {
+	subsystem := "clef"
+
+	return metrics{
+		Available: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "available",
+			Help:      "Whether the clef signer is connected.",
+		}),
+		Disconnections: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "disconnections",
+			Help:      "Total dropped connections to the clef signer.",
+		}),
+		Reconnections: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "reconnections",
+			Help:      "Total reconnections to the clef signer.",
+		}),
+		ReconnectFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "reconnect_failures",
+			Help:      "Total failed attempts to reconnect to the clef signer.",
+		}),
+		UnavailableRequests: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "unavailable_requests",
+			Help:      "Total signing requests which failed as the clef signer was unavailable.",
+		}),
+	}
+}
+
+func (s *ResilientSigner) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(s.metrics)
+}
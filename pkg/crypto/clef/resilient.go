@@ -0,0 +1,300 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clef
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/crypto/eip712"
+	"github.com/ethsana/sana/pkg/logging"
+)
+
+// ErrSignerUnavailable is returned if clef did not become available within
+// the timeout of the signing request.
+var ErrSignerUnavailable = errors.New("clef signer unavailable")
+
+const (
+	defaultRequestTimeout = time.Minute
+	defaultMinBackoff     = time.Second
+	defaultMaxBackoff     = time.Minute
+)
+
+// Dialer connects to clef.
+type Dialer func() (ExternalSignerInterface, Client, error)
+
+// ResilientOptions configure the ResilientSigner.
+type ResilientOptions struct {
+	// RequestTimeout is the maximal time a signing request waits for clef to
+	// become available again, one minute if it is zero.
+	RequestTimeout time.Duration
+	// MinBackoff and MaxBackoff bound the exponentially growing delays
+	// between the reconnection attempts, one second and one minute if they
+	// are zero.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// ResilientSigner is the clef signer which survives the restarts of clef.
+// When a request fails because the connection to clef was dropped, clef is
+// dialed again with exponential backoff while the signing requests wait for
+// the reconnection up to the request timeout.
+type ResilientSigner struct {
+	dial       Dialer
+	account    common.Address
+	pubKey     *ecdsa.PublicKey
+	timeout    time.Duration
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	logger     logging.Logger
+	metrics    metrics
+
+	mu     sync.Mutex
+	signer *clefSigner   // nil while clef is unavailable
+	ready  chan struct{} // closed when clef is available again
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewResilientSigner connects to clef with the dialer and returns the signer
+// of the account with the ethAddress, or of the first one if it is nil. It
+// fails if clef is not available.
+func NewResilientSigner(dial Dialer, recoverFunc crypto.RecoverFunc, ethAddress *common.Address, logger logging.Logger, o ResilientOptions) (*ResilientSigner, error) {
+	clef, client, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	signer, err := NewSigner(clef, client, recoverFunc, ethAddress)
+	if err != nil {
+		return nil, err
+	}
+	cs := signer.(*clefSigner)
+
+	s := &ResilientSigner{
+		dial:       dial,
+		account:    cs.account.Address,
+		pubKey:     cs.pubKey,
+		timeout:    o.RequestTimeout,
+		minBackoff: o.MinBackoff,
+		maxBackoff: o.MaxBackoff,
+		logger:     logger,
+		metrics:    newMetrics(),
+		signer:     cs,
+		quit:       make(chan struct{}),
+	}
+	if s.timeout <= 0 {
+		s.timeout = defaultRequestTimeout
+	}
+	if s.minBackoff <= 0 {
+		s.minBackoff = defaultMinBackoff
+	}
+	if s.maxBackoff <= 0 {
+		s.maxBackoff = defaultMaxBackoff
+	}
+	if s.maxBackoff < s.minBackoff {
+		s.maxBackoff = s.minBackoff
+	}
+	s.metrics.Available.Set(1)
+	return s, nil
+}
+
+// PublicKey returns the public key recovered during creation.
+func (s *ResilientSigner) PublicKey() (*ecdsa.PublicKey, error) {
+	return s.pubKey, nil
+}
+
+// EthereumAddress returns the ethereum address this signer uses.
+func (s *ResilientSigner) EthereumAddress() (common.Address, error) {
+	return s.account, nil
+}
+
+// Sign signs with the text/plain type which is the standard Ethereum prefix method.
+func (s *ResilientSigner) Sign(data []byte) (sig []byte, err error) {
+	err = s.do(func(c *clefSigner) (err error) {
+		sig, err = c.Sign(data)
+		return err
+	})
+	return sig, err
+}
+
+// SignTx signs an ethereum transaction.
+func (s *ResilientSigner) SignTx(transaction *types.Transaction, chainID *big.Int) (tx *types.Transaction, err error) {
+	err = s.do(func(c *clefSigner) (err error) {
+		// chainId is nil here because it is set on the clef side
+		tx, err = c.clef.SignTx(c.account, transaction, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if chainID.Cmp(tx.ChainId()) != 0 {
+		return nil, fmt.Errorf("misconfigured signer: wrong chain id %d; wanted %d", tx.ChainId(), chainID)
+	}
+	return tx, nil
+}
+
+// SignTypedData signs data according to eip712.
+func (s *ResilientSigner) SignTypedData(typedData *eip712.TypedData) (sig []byte, err error) {
+	err = s.do(func(c *clefSigner) (err error) {
+		sig, err = c.SignTypedData(typedData)
+		return err
+	})
+	return sig, err
+}
+
+// Available returns true if clef is connected.
+func (s *ResilientSigner) Available() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.signer != nil
+}
+
+// Close stops the reconnection attempts and fails the waiting requests.
+func (s *ResilientSigner) Close() error {
+	close(s.quit)
+	s.wg.Wait()
+	return nil
+}
+
+// do calls the function with the connected signer. If the connection is
+// dropped, the call is repeated once after the reconnection.
+func (s *ResilientSigner) do(fn func(*clefSigner) error) error {
+	timeout := time.NewTimer(s.timeout)
+	defer timeout.Stop()
+
+	for retried := false; ; retried = true {
+		s.mu.Lock()
+		signer, ready := s.signer, s.ready
+		s.mu.Unlock()
+
+		if signer == nil {
+			select {
+			case <-ready:
+				continue
+			case <-timeout.C:
+			case <-s.quit:
+			}
+			s.metrics.UnavailableRequests.Inc()
+			return ErrSignerUnavailable
+		}
+
+		err := fn(signer)
+		if err == nil || !isConnectionError(err) {
+			return err
+		}
+		s.disconnected(signer, err)
+		if retried {
+			return err
+		}
+	}
+}
+
+// disconnected marks clef unavailable after the connection of the signer
+// was dropped and starts the reconnection.
+func (s *ResilientSigner) disconnected(signer *clefSigner, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the signer may be already replaced after a failure of a concurrent
+	// request
+	if s.signer != signer {
+		return
+	}
+	select {
+	case <-s.quit:
+		return
+	default:
+	}
+
+	s.logger.Debugf("clef signer: connection dropped: %v", err)
+	s.logger.Warning("clef signer unavailable, reconnecting")
+	s.metrics.Available.Set(0)
+	s.metrics.Disconnections.Inc()
+
+	s.signer = nil
+	s.ready = make(chan struct{})
+	if c, ok := signer.client.(interface{ Close() }); ok {
+		c.Close()
+	}
+
+	s.wg.Add(1)
+	go s.reconnect()
+}
+
+func (s *ResilientSigner) reconnect() {
+	defer s.wg.Done()
+
+	backoff := s.minBackoff
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-time.After(backoff):
+		}
+
+		signer, err := s.connect()
+		if err == nil {
+			s.mu.Lock()
+			s.signer = signer
+			close(s.ready)
+			s.mu.Unlock()
+
+			s.logger.Info("clef signer reconnected")
+			s.metrics.Available.Set(1)
+			s.metrics.Reconnections.Inc()
+			return
+		}
+		s.logger.Debugf("clef signer: reconnect: %v", err)
+		s.metrics.ReconnectFailures.Inc()
+
+		if backoff *= 2; backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}
+
+// connect dials clef and checks that the account is still available.
+func (s *ResilientSigner) connect() (*clefSigner, error) {
+	clef, client, err := s.dial()
+	if err != nil {
+		return nil, err
+	}
+	account, err := selectAccount(clef, &s.account)
+	if err != nil {
+		if c, ok := client.(interface{ Close() }); ok {
+			c.Close()
+		}
+		return nil, err
+	}
+	return &clefSigner{
+		client:  client,
+		clef:    clef,
+		account: account,
+		pubKey:  s.pubKey,
+	}, nil
+}
+
+// rpcError is the error returned by clef, in difference to the errors of the
+// connection.
+type rpcError interface {
+	error
+	ErrorCode() int
+}
+
+// isConnectionError returns true if the error is not returned by clef, like
+// the rejection of a request, and the connection to clef may be dropped.
+func isConnectionError(err error) bool {
+	var e rpcError
+	return !errors.As(err, &e)
+}
@@ -0,0 +1,154 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clef_test
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/crypto/clef"
+	"github.com/ethsana/sana/pkg/logging"
+)
+
+// rejectedError is the error returned by clef when a request is rejected.
+type rejectedError struct{}
+
+func (rejectedError) Error() string  { return "request denied" }
+func (rejectedError) ErrorCode() int { return -32000 }
+
+// flakyClef is clef which can be stopped.
+type flakyClef struct {
+	mu      sync.Mutex
+	down    bool
+	reject  bool
+	dials   int
+	account accounts.Account
+}
+
+func (c *flakyClef) setDown(down bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.down = down
+}
+
+func (c *flakyClef) dial() (clef.ExternalSignerInterface, clef.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dials++
+	if c.down {
+		return nil, nil, errors.New("connection refused")
+	}
+	return c, nil, nil
+}
+
+func (c *flakyClef) SignData(_ accounts.Account, _ string, _ []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return nil, io.EOF
+	}
+	if c.reject {
+		return nil, rejectedError{}
+	}
+	return make([]byte, 65), nil
+}
+
+func (c *flakyClef) Accounts() []accounts.Account {
+	return []accounts.Account{c.account}
+}
+
+func (c *flakyClef) SignTx(_ accounts.Account, _ *types.Transaction, _ *big.Int) (*types.Transaction, error) {
+	return nil, nil
+}
+
+func newResilientSigner(t *testing.T, c *flakyClef, timeout time.Duration) *clef.ResilientSigner {
+	t.Helper()
+
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := clef.NewResilientSigner(c.dial, func(_, _ []byte) (*ecdsa.PublicKey, error) {
+		return &key.PublicKey, nil
+	}, nil, logging.New(ioutil.Discard, 0), clef.ResilientOptions{
+		RequestTimeout: timeout,
+		MinBackoff:     10 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestResilientSignerReconnect(t *testing.T) {
+	c := &flakyClef{account: accounts.Account{Address: common.HexToAddress("0x31415b599f636129AD03c196cef9f8f8b184D5C7")}}
+	s := newResilientSigner(t, c, 5*time.Second)
+
+	c.setDown(true)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		c.setDown(false)
+	}()
+
+	if _, err := s.Sign([]byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Available() {
+		t.Fatal("signer not available after the reconnection")
+	}
+	c.mu.Lock()
+	dials := c.dials
+	c.mu.Unlock()
+	if dials < 2 {
+		t.Fatalf("got %d dials, want at least 2", dials)
+	}
+}
+
+func TestResilientSignerUnavailable(t *testing.T) {
+	c := &flakyClef{account: accounts.Account{Address: common.HexToAddress("0x31415b599f636129AD03c196cef9f8f8b184D5C7")}}
+	s := newResilientSigner(t, c, 100*time.Millisecond)
+
+	c.setDown(true)
+	if _, err := s.Sign([]byte("data")); !errors.Is(err, clef.ErrSignerUnavailable) {
+		t.Fatalf("got error %v, want %v", err, clef.ErrSignerUnavailable)
+	}
+	if s.Available() {
+		t.Fatal("signer available while clef is down")
+	}
+}
+
+func TestResilientSignerRejected(t *testing.T) {
+	c := &flakyClef{account: accounts.Account{Address: common.HexToAddress("0x31415b599f636129AD03c196cef9f8f8b184D5C7")}}
+	s := newResilientSigner(t, c, 5*time.Second)
+
+	c.mu.Lock()
+	c.reject = true
+	c.mu.Unlock()
+
+	if _, err := s.Sign([]byte("data")); !errors.As(err, new(rejectedError)) {
+		t.Fatalf("got error %v, want %v", err, rejectedError{})
+	}
+	if !s.Available() {
+		t.Fatal("signer unavailable after the rejected request")
+	}
+	c.mu.Lock()
+	dials := c.dials
+	c.mu.Unlock()
+	if dials != 1 {
+		t.Fatalf("got %d dials, want 1", dials)
+	}
+}
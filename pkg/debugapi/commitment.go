@@ -0,0 +1,63 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ethsana/sana/pkg/commitment"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/gorilla/mux"
+)
+
+type commitmentProofResponse struct {
+	Commitment *commitment.Commitment `json:"commitment"`
+	Proof      *commitment.Proof      `json:"proof"`
+}
+
+func (s *Service) commitmentHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := s.commitment.Latest()
+	if err != nil {
+		if errors.Is(err, commitment.ErrNoCommitment) {
+			jsonhttp.NotFound(w, "no commitment")
+			return
+		}
+		s.logger.Debugf("debug api: commitment: %v", err)
+		s.logger.Error("debug api: commitment")
+		jsonhttp.InternalServerError(w, "cannot get commitment")
+		return
+	}
+	jsonhttp.OK(w, c)
+}
+
+func (s *Service) commitmentProofHandler(w http.ResponseWriter, r *http.Request) {
+	addr, err := swarm.ParseHexAddress(mux.Vars(r)["address"])
+	if err != nil {
+		s.logger.Debugf("debug api: commitment proof: parse address: %v", err)
+		jsonhttp.BadRequest(w, "invalid address")
+		return
+	}
+
+	c, p, err := s.commitment.Proof(addr)
+	if err != nil {
+		switch {
+		case errors.Is(err, commitment.ErrNoCommitment):
+			jsonhttp.NotFound(w, "no commitment")
+		case errors.Is(err, commitment.ErrNotCommitted):
+			jsonhttp.NotFound(w, "chunk not committed")
+		default:
+			s.logger.Debugf("debug api: commitment proof %s: %v", addr, err)
+			s.logger.Error("debug api: commitment proof")
+			jsonhttp.InternalServerError(w, "cannot get proof")
+		}
+		return
+	}
+	jsonhttp.OK(w, commitmentProofResponse{
+		Commitment: c,
+		Proof:      p,
+	})
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/commitment"
+	"github.com/ethsana/sana/pkg/debugapi"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/swarm/test"
+)
+
+type reserveMock []swarm.Address
+
+func (r reserveMock) IterateReserve(fn func(swarm.Address) (bool, error)) error {
+	for _, a := range r {
+		if stop, err := fn(a); err != nil || stop {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestCommitment(t *testing.T) {
+	reserve := reserveMock{test.RandomAddress(), test.RandomAddress(), test.RandomAddress()}
+	s := commitment.New(reserve, nil, nil, nil, statestore.NewStateStore(), logging.New(ioutil.Discard, 0), commitment.Options{})
+	testServer := newTestServer(t, testServerOptions{
+		Commitment: s,
+	})
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/commitment", http.StatusNotFound,
+		jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+			Code:    http.StatusNotFound,
+			Message: "no commitment",
+		}),
+	)
+
+	c, err := s.Commit(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/commitment", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(c),
+	)
+
+	var resp debugapi.CommitmentProofResponse
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/commitment/proof/"+reserve[1].String(), http.StatusOK,
+		jsonhttptest.WithUnmarshalJSONResponse(&resp),
+	)
+	if !resp.Commitment.Root.Equal(c.Root) || !commitment.Verify(c.Root, resp.Proof) {
+		t.Fatalf("got invalid proof %+v", resp.Proof)
+	}
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/commitment/proof/"+test.RandomAddress().String(), http.StatusNotFound,
+		jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+			Code:    http.StatusNotFound,
+			Message: "chunk not committed",
+		}),
+	)
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/commitment/proof/zz", http.StatusBadRequest)
+}
@@ -0,0 +1,24 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"net/http"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+)
+
+// configReloadHandler reloads the configuration and responds with the options
+// which were applied and the ones which require a restart.
+func (s *Service) configReloadHandler(w http.ResponseWriter, r *http.Request) {
+	result, err := s.reloader.Reload()
+	if err != nil {
+		s.logger.Debugf("debug api: config reload: %v", err)
+		s.logger.Error("debug api: config reload")
+		jsonhttp.InternalServerError(w, "cannot reload config")
+		return
+	}
+	jsonhttp.OK(w, result)
+}
@@ -0,0 +1,46 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/reload"
+)
+
+type reloaderFunc func() (*reload.Result, error)
+
+func (f reloaderFunc) Reload() (*reload.Result, error) { return f() }
+
+func TestConfigReload(t *testing.T) {
+	result := &reload.Result{
+		Applied: []string{"verbosity"},
+		Restart: []string{"data-dir"},
+		Failed:  map[string]string{"payment-threshold": "invalid payment threshold: x"},
+	}
+	var err error
+	testServer := newTestServer(t, testServerOptions{
+		Reloader: reloaderFunc(func() (*reload.Result, error) {
+			return result, err
+		}),
+	})
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/config/reload", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(result),
+	)
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/config/reload", http.StatusMethodNotAllowed)
+
+	err = errors.New("config file not found")
+	jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/config/reload", http.StatusInternalServerError,
+		jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "cannot reload config",
+		}),
+	)
+}
@@ -15,22 +15,32 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethsana/sana/pkg/accounting"
 	"github.com/ethsana/sana/pkg/addressbook"
+	"github.com/ethsana/sana/pkg/commitment"
+	"github.com/ethsana/sana/pkg/events"
+	"github.com/ethsana/sana/pkg/identityclaim"
+	"github.com/ethsana/sana/pkg/keystore/backup"
 	"github.com/ethsana/sana/pkg/logging"
 	"github.com/ethsana/sana/pkg/mine"
 	"github.com/ethsana/sana/pkg/p2p"
 	"github.com/ethsana/sana/pkg/pingpong"
 	"github.com/ethsana/sana/pkg/postage"
 	"github.com/ethsana/sana/pkg/postage/postagecontract"
+	"github.com/ethsana/sana/pkg/postage/postagemanager"
+	"github.com/ethsana/sana/pkg/ratelimit"
+	"github.com/ethsana/sana/pkg/reload"
+	"github.com/ethsana/sana/pkg/scheduler"
 	"github.com/ethsana/sana/pkg/settlement"
 	"github.com/ethsana/sana/pkg/settlement/swap"
 	"github.com/ethsana/sana/pkg/settlement/swap/chequebook"
 	"github.com/ethsana/sana/pkg/storage"
 	"github.com/ethsana/sana/pkg/swarm"
 	"github.com/ethsana/sana/pkg/tags"
+	"github.com/ethsana/sana/pkg/takedown"
 	"github.com/ethsana/sana/pkg/topology"
 	"github.com/ethsana/sana/pkg/topology/lightnode"
 	"github.com/ethsana/sana/pkg/tracing"
 	"github.com/ethsana/sana/pkg/transaction"
+	"github.com/ethsana/sana/pkg/uploadscan"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -57,12 +67,28 @@ type Service struct {
 	postageContract    postagecontract.Interface
 	logger             logging.Logger
 	corsAllowedOrigins []string
+	corsMu             sync.RWMutex
 	metricsRegistry    *prometheus.Registry
 	lightNodes         *lightnode.Container
 	minerEnabled       bool
 	mine               mine.Service
 	authorization      string
 	addressbook        addressbook.Interface
+	uploadScanAudit    uploadscan.Audit
+	scheduler          *scheduler.Scheduler
+	commitment         *commitment.Service
+	takedown           *takedown.Service
+	reloader           reload.Interface
+	drainer            Drainer
+	keyBackup          *backup.Exporter
+	postageManager     *postagemanager.Service
+	storagePools       StoragePools
+	peerReputation     PeerReputation
+	identityClaim      *identityclaim.Service
+	snapshots          Snapshots
+	pullSync           PullSyncStatus
+	events             *events.Bus
+	peerSampleLimiter  *ratelimit.Limiter
 	// handler is changed in the Configure method
 	handler   http.Handler
 	handlerMu sync.RWMutex
@@ -72,7 +98,7 @@ type Service struct {
 // to expose /addresses, /health endpoints, Go metrics and pprof. It is useful to expose
 // these endpoints before all dependencies are configured and injected to have
 // access to basic debugging tools and /health endpoint.
-func New(publicKey, pssPublicKey ecdsa.PublicKey, ethereumAddress common.Address, addressbook addressbook.Interface, logger logging.Logger, tracer *tracing.Tracer, corsAllowedOrigins []string, authorization string, transaction transaction.Service) *Service {
+func New(publicKey, pssPublicKey ecdsa.PublicKey, ethereumAddress common.Address, addressbook addressbook.Interface, logger logging.Logger, tracer *tracing.Tracer, corsAllowedOrigins []string, authorization string, transaction transaction.Service, events *events.Bus) *Service {
 	s := new(Service)
 	s.publicKey = publicKey
 	s.pssPublicKey = pssPublicKey
@@ -84,16 +110,37 @@ func New(publicKey, pssPublicKey ecdsa.PublicKey, ethereumAddress common.Address
 	s.metricsRegistry = newMetricsRegistry()
 	s.transaction = transaction
 	s.authorization = authorization
+	s.events = events
+	s.peerSampleLimiter = ratelimit.New(peerSampleRate, peerSampleBurst)
 
 	s.setRouter(s.newBasicRouter())
 
 	return s
 }
 
+// Options are the dependencies of the optional features of the node. The
+// endpoints of a feature are exposed by Configure only if its dependency is
+// set.
+type Options struct {
+	UploadScanAudit uploadscan.Audit
+	Scheduler       *scheduler.Scheduler
+	Commitment      *commitment.Service
+	Takedown        *takedown.Service
+	Reloader        reload.Interface
+	Drainer         Drainer
+	KeyBackup       *backup.Exporter
+	PostageManager  *postagemanager.Service
+	PeerReputation  PeerReputation
+	IdentityClaim   *identityclaim.Service
+	Snapshots       Snapshots
+	PullSync        PullSyncStatus
+}
+
 // Configure injects required dependencies and configuration parameters and
-// constructs HTTP routes that depend on them. It is intended and safe to call
-// this method only once.
-func (s *Service) Configure(overlay swarm.Address, p2p p2p.DebugService, pingpong pingpong.Interface, topologyDriver topology.Driver, lightNodes *lightnode.Container, storer storage.Storer, tags *tags.Tags, accounting accounting.Interface, pseudosettle settlement.Interface, chequebookEnabled bool, swap swap.Interface, chequebook chequebook.Service, batchStore postage.Storer, post postage.Service, postageContract postagecontract.Interface, minerEnabled bool, miner mine.Service) {
+// constructs HTTP routes that depend on them. The usage of the storage pools
+// is exposed if the storer reports it. It is intended and safe to call this
+// method only once.
+func (s *Service) Configure(overlay swarm.Address, p2p p2p.DebugService, pingpong pingpong.Interface, topologyDriver topology.Driver, lightNodes *lightnode.Container, storer storage.Storer, tags *tags.Tags, accounting accounting.Interface, pseudosettle settlement.Interface, chequebookEnabled bool, swap swap.Interface, chequebook chequebook.Service, batchStore postage.Storer, post postage.Service, postageContract postagecontract.Interface, minerEnabled bool, miner mine.Service, o Options) {
 	s.p2p = p2p
 	s.pingpong = pingpong
 	s.topologyDriver = topologyDriver
@@ -111,10 +158,38 @@ func (s *Service) Configure(overlay swarm.Address, p2p p2p.DebugService, pingpon
 	s.postageContract = postageContract
 	s.minerEnabled = minerEnabled
 	s.mine = miner
+	s.uploadScanAudit = o.UploadScanAudit
+	s.scheduler = o.Scheduler
+	s.commitment = o.Commitment
+	s.takedown = o.Takedown
+	s.reloader = o.Reloader
+	s.drainer = o.Drainer
+	s.keyBackup = o.KeyBackup
+	s.postageManager = o.PostageManager
+	s.peerReputation = o.PeerReputation
+	s.identityClaim = o.IdentityClaim
+	s.snapshots = o.Snapshots
+	s.pullSync = o.PullSync
+	if pools, ok := storer.(StoragePools); ok {
+		s.storagePools = pools
+	}
 
 	s.setRouter(s.newRouter())
 }
 
+// ConfigurePostage injects the postage dependencies of the nodes which are
+// not part of the network, the ones in the light-remote mode, and exposes the
+// endpoints of the chain state and the postage stamps in addition to the
+// basic ones.
+func (s *Service) ConfigurePostage(batchStore postage.Storer, post postage.Service, postageContract postagecontract.Interface, postageManager *postagemanager.Service) {
+	s.batchStore = batchStore
+	s.post = post
+	s.postageContract = postageContract
+	s.postageManager = postageManager
+
+	s.setRouter(s.newPostageRouter())
+}
+
 // ServeHTTP implements http.Handler interface.
 func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// protect handler as it is changed by the Configure method
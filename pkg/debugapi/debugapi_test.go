@@ -9,6 +9,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -17,25 +18,37 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethsana/sana"
 	accountingmock "github.com/ethsana/sana/pkg/accounting/mock"
+	"github.com/ethsana/sana/pkg/bigint"
+	"github.com/ethsana/sana/pkg/commitment"
 	"github.com/ethsana/sana/pkg/crypto"
 	"github.com/ethsana/sana/pkg/debugapi"
+	"github.com/ethsana/sana/pkg/events"
+	"github.com/ethsana/sana/pkg/identityclaim"
 	"github.com/ethsana/sana/pkg/jsonhttp"
 	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/keystore/backup"
 	"github.com/ethsana/sana/pkg/logging"
 	p2pmock "github.com/ethsana/sana/pkg/p2p/mock"
 	"github.com/ethsana/sana/pkg/pingpong"
 	"github.com/ethsana/sana/pkg/postage"
+	batchstoremock "github.com/ethsana/sana/pkg/postage/batchstore/mock"
 	mockpost "github.com/ethsana/sana/pkg/postage/mock"
 	"github.com/ethsana/sana/pkg/postage/postagecontract"
+	"github.com/ethsana/sana/pkg/postage/postagemanager"
+	"github.com/ethsana/sana/pkg/reload"
 	"github.com/ethsana/sana/pkg/resolver"
+	"github.com/ethsana/sana/pkg/scheduler"
 	chequebookmock "github.com/ethsana/sana/pkg/settlement/swap/chequebook/mock"
 	swapmock "github.com/ethsana/sana/pkg/settlement/swap/mock"
 	"github.com/ethsana/sana/pkg/storage"
 	"github.com/ethsana/sana/pkg/swarm"
 	"github.com/ethsana/sana/pkg/tags"
+	"github.com/ethsana/sana/pkg/takedown"
 	"github.com/ethsana/sana/pkg/topology/lightnode"
 	topologymock "github.com/ethsana/sana/pkg/topology/mock"
 	transactionmock "github.com/ethsana/sana/pkg/transaction/mock"
+	"github.com/ethsana/sana/pkg/uploadscan"
+	"github.com/gorilla/websocket"
 	"github.com/multiformats/go-multiaddr"
 	"resenje.org/web"
 )
@@ -71,11 +84,26 @@ type testServerOptions struct {
 	TransactionOpts    []transactionmock.Option
 	PostageContract    postagecontract.Interface
 	Post               postage.Service
+	UploadScanAudit    uploadscan.Audit
+	Scheduler          *scheduler.Scheduler
+	Commitment         *commitment.Service
+	Takedown           *takedown.Service
+	Reloader           reload.Interface
+	Drainer            debugapi.Drainer
+	PeerReputation     debugapi.PeerReputation
+	IdentityClaim      *identityclaim.Service
+	Snapshots          debugapi.Snapshots
+	PullSync           debugapi.PullSyncStatus
+	KeyBackup          *backup.Exporter
+	PostageManager     *postagemanager.Service
+	Events             *events.Bus
+	WsPath             string
 }
 
 type testServer struct {
 	Client  *http.Client
 	P2PMock *p2pmock.Service
+	WsConn  *websocket.Conn
 }
 
 func newTestServer(t *testing.T, o testServerOptions) *testServer {
@@ -86,8 +114,21 @@ func newTestServer(t *testing.T, o testServerOptions) *testServer {
 	swapserv := swapmock.New(o.SwapOpts...)
 	transaction := transactionmock.New(o.TransactionOpts...)
 	ln := lightnode.NewContainer(o.Overlay)
-	s := debugapi.New(o.PublicKey, o.PSSPublicKey, o.EthereumAddress, nil, logging.New(ioutil.Discard, 0), nil, o.CORSAllowedOrigins, ``, transaction)
-	s.Configure(o.Overlay, o.P2P, o.Pingpong, topologyDriver, ln, o.Storer, o.Tags, acc, settlement, true, swapserv, chequebook, o.BatchStore, o.Post, o.PostageContract, false, nil)
+	s := debugapi.New(o.PublicKey, o.PSSPublicKey, o.EthereumAddress, nil, logging.New(ioutil.Discard, 0), nil, o.CORSAllowedOrigins, ``, transaction, o.Events)
+	s.Configure(o.Overlay, o.P2P, o.Pingpong, topologyDriver, ln, o.Storer, o.Tags, acc, settlement, true, swapserv, chequebook, o.BatchStore, o.Post, o.PostageContract, false, nil, debugapi.Options{
+		UploadScanAudit: o.UploadScanAudit,
+		Scheduler:       o.Scheduler,
+		Commitment:      o.Commitment,
+		Takedown:        o.Takedown,
+		Reloader:        o.Reloader,
+		Drainer:         o.Drainer,
+		KeyBackup:       o.KeyBackup,
+		PostageManager:  o.PostageManager,
+		PeerReputation:  o.PeerReputation,
+		IdentityClaim:   o.IdentityClaim,
+		Snapshots:       o.Snapshots,
+		PullSync:        o.PullSync,
+	})
 	ts := httptest.NewServer(s)
 	t.Cleanup(ts.Close)
 
@@ -101,9 +142,21 @@ func newTestServer(t *testing.T, o testServerOptions) *testServer {
 			return ts.Client().Transport.RoundTrip(r)
 		}),
 	}
+
+	var conn *websocket.Conn
+	if o.WsPath != "" {
+		u := "ws://" + ts.Listener.Addr().String() + o.WsPath
+		var err error
+		conn, _, err = websocket.DefaultDialer.Dial(u, nil)
+		if err != nil {
+			t.Fatalf("dial: %v. url %v", err, u)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+	}
 	return &testServer{
 		Client:  client,
 		P2PMock: o.P2P,
+		WsConn:  conn,
 	}
 }
 
@@ -154,7 +207,7 @@ func TestServer_Configure(t *testing.T) {
 	swapserv := swapmock.New(o.SwapOpts...)
 	ln := lightnode.NewContainer(o.Overlay)
 	transaction := transactionmock.New(o.TransactionOpts...)
-	s := debugapi.New(o.PublicKey, o.PSSPublicKey, o.EthereumAddress, nil, logging.New(ioutil.Discard, 0), nil, nil, ``, transaction)
+	s := debugapi.New(o.PublicKey, o.PSSPublicKey, o.EthereumAddress, nil, logging.New(ioutil.Discard, 0), nil, nil, ``, transaction, nil)
 	ts := httptest.NewServer(s)
 	t.Cleanup(ts.Close)
 
@@ -185,7 +238,7 @@ func TestServer_Configure(t *testing.T) {
 		}),
 	)
 
-	s.Configure(o.Overlay, o.P2P, o.Pingpong, topologyDriver, ln, o.Storer, o.Tags, acc, settlement, true, swapserv, chequebook, nil, mockpost.New(), nil, false, nil)
+	s.Configure(o.Overlay, o.P2P, o.Pingpong, topologyDriver, ln, o.Storer, o.Tags, acc, settlement, true, swapserv, chequebook, nil, mockpost.New(), nil, false, nil, debugapi.Options{})
 
 	testBasicRouter(t, client)
 	jsonhttptest.Request(t, client, http.MethodGet, "/readiness", http.StatusOK,
@@ -205,6 +258,44 @@ func TestServer_Configure(t *testing.T) {
 	)
 }
 
+func TestServer_ConfigurePostage(t *testing.T) {
+	privateKey, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := debugapi.New(privateKey.PublicKey, privateKey.PublicKey, common.HexToAddress("abcd"), nil, logging.New(ioutil.Discard, 0), nil, nil, ``, transactionmock.New(), nil)
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+
+	client := &http.Client{
+		Transport: web.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			u, err := url.Parse(ts.URL + r.URL.String())
+			if err != nil {
+				return nil, err
+			}
+			r.URL = u
+			return ts.Client().Transport.RoundTrip(r)
+		}),
+	}
+
+	cs := &postage.ChainState{Block: 10, TotalAmount: big.NewInt(5), CurrentPrice: big.NewInt(1)}
+	post := mockpost.New(mockpost.WithIssuer(postage.NewStampIssuer("label", "", batchOk, big.NewInt(3), 11, 10, 1000, true)))
+	s.ConfigurePostage(batchstoremock.New(batchstoremock.WithChainState(cs)), post, nil, nil)
+
+	testBasicRouter(t, client)
+	jsonhttptest.Request(t, client, http.MethodGet, "/readiness", http.StatusOK)
+	jsonhttptest.Request(t, client, http.MethodGet, "/chainstate", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(debugapi.ChainStateResponse{
+			Block:        10,
+			TotalAmount:  bigint.Wrap(big.NewInt(5)),
+			CurrentPrice: bigint.Wrap(big.NewInt(1)),
+		}),
+	)
+	jsonhttptest.Request(t, client, http.MethodGet, "/stamps", http.StatusOK)
+	// the endpoints of the network are not exposed
+	jsonhttptest.Request(t, client, http.MethodGet, "/peers", http.StatusNotFound)
+}
+
 func testBasicRouter(t *testing.T, client *http.Client) {
 	t.Helper()
 
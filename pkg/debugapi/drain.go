@@ -0,0 +1,32 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+)
+
+// DrainStatus reports the progress of the drain phase of the node shutdown,
+// in which no new work is accepted and the pending one is completed.
+type DrainStatus struct {
+	Draining        bool       `json:"draining"`
+	Done            bool       `json:"done"`
+	StartedAt       *time.Time `json:"startedAt,omitempty"`
+	PendingChunks   int        `json:"pendingChunks"`
+	PendingPayments int        `json:"pendingPayments"`
+	Error           string     `json:"error,omitempty"`
+}
+
+// Drainer reports the progress of draining the node.
+type Drainer interface {
+	DrainStatus() DrainStatus
+}
+
+func (s *Service) drainStatusHandler(w http.ResponseWriter, r *http.Request) {
+	jsonhttp.OK(w, s.drainer.DrainStatus())
+}
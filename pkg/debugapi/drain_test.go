@@ -0,0 +1,42 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/debugapi"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+)
+
+type drainerFunc func() debugapi.DrainStatus
+
+func (f drainerFunc) DrainStatus() debugapi.DrainStatus { return f() }
+
+func TestDrainStatus(t *testing.T) {
+	var status debugapi.DrainStatus
+	testServer := newTestServer(t, testServerOptions{
+		Drainer: drainerFunc(func() debugapi.DrainStatus {
+			return status
+		}),
+	})
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/health/drain", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(debugapi.DrainStatus{}),
+	)
+
+	startedAt := time.Unix(1600000000, 0).UTC()
+	status = debugapi.DrainStatus{
+		Draining:        true,
+		StartedAt:       &startedAt,
+		PendingChunks:   12,
+		PendingPayments: 1,
+	}
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/health/drain", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(status),
+	)
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethsana/sana/pkg/events"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	eventsWriteDeadline = 4 * time.Second
+	eventsPingPeriod    = 60 * time.Second
+)
+
+// eventsHandler streams the node events as JSON messages over a websocket.
+// The types query parameter is an optional comma separated list of the event
+// types to stream.
+func (s *Service) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	var types map[events.Type]bool
+	if t := r.URL.Query().Get("types"); t != "" {
+		types = make(map[events.Type]bool)
+		for _, v := range strings.Split(t, ",") {
+			types[events.Type(strings.TrimSpace(v))] = true
+		}
+	}
+
+	s.corsMu.RLock()
+	allowed := s.corsAllowedOrigins
+	s.corsMu.RUnlock()
+
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			return checkOrigin(r, allowed)
+		},
+	}
+	// the upgrader responds with the error
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.Debugf("debugapi: events: upgrade: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	c, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+
+	// read the connection to process the control messages and to notice
+	// when the client is gone
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(eventsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-c:
+			if err := conn.SetWriteDeadline(time.Now().Add(eventsWriteDeadline)); err != nil {
+				s.logger.Debugf("debugapi: events: set write deadline: %v", err)
+				return
+			}
+			if !ok {
+				// shutdown
+				if err := conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "")); err != nil {
+					s.logger.Debugf("debugapi: events: write close message: %v", err)
+				}
+				return
+			}
+			if types != nil && !types[e.Type] {
+				continue
+			}
+			if err := conn.WriteJSON(e); err != nil {
+				s.logger.Debugf("debugapi: events: write: %v", err)
+				return
+			}
+		case <-gone:
+			return
+		case <-ticker.C:
+			if err := conn.SetWriteDeadline(time.Now().Add(eventsWriteDeadline)); err != nil {
+				s.logger.Debugf("debugapi: events: set write deadline: %v", err)
+				return
+			}
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				// client probably gone
+				return
+			}
+		}
+	}
+}
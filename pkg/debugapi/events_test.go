@@ -0,0 +1,87 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/events"
+	"github.com/gorilla/websocket"
+)
+
+// publishUntilReceived publishes the event until the websocket, which
+// subscribes to the events asynchronously after the upgrade, receives it.
+func publishUntilReceived(t *testing.T, bus *events.Bus, conn *websocket.Conn, typ events.Type, data interface{}) map[string]interface{} {
+	t.Helper()
+
+	received := make(chan map[string]interface{}, 1)
+	go func() {
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err == nil {
+			received <- msg
+		}
+	}()
+	timeout := time.After(5 * time.Second)
+	for {
+		bus.Publish(typ, data)
+		select {
+		case msg := <-received:
+			return msg
+		case <-time.After(50 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("event not received")
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	bus := events.New()
+	testServer := newTestServer(t, testServerOptions{
+		Events: bus,
+		WsPath: "/events",
+	})
+
+	msg := publishUntilReceived(t, bus, testServer.WsConn, events.PeerConnected, events.PeerData{Address: "abcd", FullNode: true})
+	if msg["type"] != string(events.PeerConnected) {
+		t.Fatalf("got event type %v, want %v", msg["type"], events.PeerConnected)
+	}
+	data, ok := msg["data"].(map[string]interface{})
+	if !ok || data["address"] != "abcd" || data["fullNode"] != true {
+		t.Fatalf("got event data %v", msg["data"])
+	}
+	if _, err := time.Parse(time.RFC3339Nano, msg["time"].(string)); err != nil {
+		t.Fatalf("event time: %v", err)
+	}
+
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// drain the duplicates of the published event
+	_ = testServer.WsConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := testServer.WsConn.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+				t.Fatalf("got error %v, want close going away", err)
+			}
+			break
+		}
+	}
+}
+
+func TestEventsTypes(t *testing.T) {
+	bus := events.New()
+	testServer := newTestServer(t, testServerOptions{
+		Events: bus,
+		WsPath: "/events?types=" + string(events.MineReward) + "," + string(events.Shutdown),
+	})
+
+	// the filtered out events are published first and are never received
+	bus.Publish(events.PeerConnected, events.PeerData{Address: "abcd"})
+	msg := publishUntilReceived(t, bus, testServer.WsConn, events.Shutdown, events.ShutdownData{Phase: events.ShutdownStarted})
+	if msg["type"] != string(events.Shutdown) {
+		t.Fatalf("got event type %v, want %v", msg["type"], events.Shutdown)
+	}
+}
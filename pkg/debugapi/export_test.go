@@ -16,6 +16,8 @@ type (
 	BalanceResponse                   = balanceResponse
 	SettlementResponse                = settlementResponse
 	SettlementsResponse               = settlementsResponse
+	SettlementHistoryResponse         = settlementHistoryResponse
+	SettlementHistoryBucket           = settlementHistoryBucket
 	ChequebookBalanceResponse         = chequebookBalanceResponse
 	ChequebookAddressResponse         = chequebookAddressResponse
 	ChequebookLastChequePeerResponse  = chequebookLastChequePeerResponse
@@ -34,6 +36,21 @@ type (
 	PostageCreateResponse             = postageCreateResponse
 	PostageStampResponse              = postageStampResponse
 	PostageStampsResponse             = postageStampsResponse
+	PostageExpiryResponse             = postageExpiryResponse
+	UploadScanRejectionsResponse      = uploadScanRejectionsResponse
+	CommitmentProofResponse           = commitmentProofResponse
+	PeerSampleResponse                = peerSampleResponse
+	ScheduleRequest                   = scheduleRequest
+	SchedulesResponse                 = schedulesResponse
+	ScheduleHistoryResponse           = scheduleHistoryResponse
+	TakedownsResponse                 = takedownsResponse
+	TakedownAuditResponse             = takedownAuditResponse
+	StoragePoolsResponse              = storagePoolsResponse
+	StoragePoolResponse               = storagePoolResponse
+	PeerPolicy                        = peerPolicy
+	PeerReputationResponse            = peerReputationResponse
+	SyncProgressResponse              = syncProgressResponse
+	PullSyncStatusResponse            = pullSyncStatusResponse
 )
 
 var (
@@ -0,0 +1,41 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"github.com/ethsana/sana/pkg/identityclaim"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+)
+
+// identityClaimHandler returns the identity claim of the node with the hex
+// encoded nonce of the verifier. The claim is not a remote attestation.
+func (s *Service) identityClaimHandler(w http.ResponseWriter, r *http.Request) {
+	nonce, err := hex.DecodeString(r.URL.Query().Get("nonce"))
+	if err != nil {
+		s.logger.Debugf("debug api: identity claim: decode nonce: %v", err)
+		jsonhttp.BadRequest(w, "invalid nonce")
+		return
+	}
+
+	claim, err := s.identityClaim.Claim(nonce)
+	if err != nil {
+		s.logger.Debugf("debug api: identity claim: %v", err)
+		switch {
+		case errors.Is(err, identityclaim.ErrNonceTooLarge):
+			jsonhttp.BadRequest(w, "nonce too large")
+		case errors.Is(err, identityclaim.ErrUnavailable):
+			jsonhttp.ServiceUnavailable(w, "tee device unavailable")
+		default:
+			s.logger.Error("debug api: identity claim")
+			jsonhttp.InternalServerError(w, "cannot create identity claim")
+		}
+		return
+	}
+	jsonhttp.OK(w, claim)
+}
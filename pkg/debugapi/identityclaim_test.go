@@ -0,0 +1,72 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/identityclaim"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/swarm/test"
+)
+
+func TestIdentityClaim(t *testing.T) {
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newIdentityClaim := func(device identityclaim.DeviceFunc) *identityclaim.Service {
+		s, err := identityclaim.New(test.RandomAddress(), crypto.NewDefaultSigner(key), device)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		testServer := newTestServer(t, testServerOptions{
+			IdentityClaim: newIdentityClaim(func() (identityclaim.Device, error) {
+				return identityclaim.Device{Platform: "amd", ID: []byte{0, 0, 0, 0, 1}}, nil
+			}),
+		})
+
+		var claim identityclaim.Claim
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/node/identity-claim?nonce=0102", http.StatusOK,
+			jsonhttptest.WithUnmarshalJSONResponse(&claim),
+		)
+		if claim.Nonce != "0102" || claim.Device != "0000000001" {
+			t.Fatalf("got claim %+v", claim)
+		}
+		if err := identityclaim.Verify(&claim); err != nil {
+			t.Fatal(err)
+		}
+
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/node/identity-claim?nonce=zz", http.StatusBadRequest,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid nonce",
+			}),
+		)
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		testServer := newTestServer(t, testServerOptions{
+			IdentityClaim: newIdentityClaim(func() (identityclaim.Device, error) {
+				return identityclaim.Device{}, errors.New("no device")
+			}),
+		})
+
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/node/identity-claim", http.StatusServiceUnavailable,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusServiceUnavailable,
+				Message: "tee device unavailable",
+			}),
+		)
+	})
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/keystore"
+)
+
+const keysBackupMaxRequestSize = 1024
+
+type keysBackupRequest struct {
+	NodePassword string `json:"nodePassword"`
+	Password     string `json:"password"`
+}
+
+// keysBackupHandler responds with the backup of the node keys, encrypted with
+// the password of the request. The keys are decrypted with the node password
+// of the request, so that only the owners of the keystore can back it up.
+func (s *Service) keysBackupHandler(w http.ResponseWriter, r *http.Request) {
+	var req keysBackupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.logger.Debugf("debugapi: keys backup: failed to read request: %v", err)
+		jsonhttp.BadRequest(w, err)
+		return
+	}
+	if req.NodePassword == "" {
+		jsonhttp.BadRequest(w, "node password not provided")
+		return
+	}
+	if req.Password == "" {
+		jsonhttp.BadRequest(w, "password not provided")
+		return
+	}
+
+	data, err := s.keyBackup.Export(req.NodePassword, req.Password)
+	if errors.Is(err, keystore.ErrInvalidPassword) {
+		jsonhttp.Forbidden(w, "invalid node password")
+		return
+	}
+	if err != nil {
+		s.logger.Debugf("debugapi: keys backup: %v", err)
+		s.logger.Error("debugapi: keys backup failed")
+		jsonhttp.InternalServerError(w, "keys backup failed")
+		return
+	}
+
+	name := fmt.Sprintf("sana-keys-%s.json", time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/keystore/backup"
+	"github.com/ethsana/sana/pkg/keystore/mem"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+)
+
+func TestKeysBackup(t *testing.T) {
+	ks := mem.New()
+	want, _, err := ks.Key("sana", "keystore pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testServer := newTestServer(t, testServerOptions{
+		KeyBackup: backup.NewExporter(ks, statestore.NewStateStore(), nil),
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		var data []byte
+		header := jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/keys/backup", http.StatusOK,
+			jsonhttptest.WithJSONRequestBody(map[string]string{"nodePassword": "keystore pass", "password": "backup pass"}),
+			jsonhttptest.WithPutResponseBody(&data),
+		)
+		if d := header.Get("Content-Disposition"); !strings.HasPrefix(d, "attachment") {
+			t.Fatalf("got content disposition %q", d)
+		}
+
+		b, err := backup.Decrypt(data, "backup pass")
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored := mem.New()
+		if err := b.Restore(restored, "pass", statestore.NewStateStore()); err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := restored.Key("sana", "pass")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(want) {
+			t.Fatal("restored key is not equal to the node key")
+		}
+	})
+
+	t.Run("invalid node password", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/keys/backup", http.StatusForbidden,
+			jsonhttptest.WithJSONRequestBody(map[string]string{"nodePassword": "other pass", "password": "backup pass"}),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusForbidden,
+				Message: "invalid node password",
+			}),
+		)
+	})
+
+	t.Run("no node password", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/keys/backup", http.StatusBadRequest,
+			jsonhttptest.WithJSONRequestBody(map[string]string{"password": "backup pass"}),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusBadRequest,
+				Message: "node password not provided",
+			}),
+		)
+	})
+
+	t.Run("no password", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/keys/backup", http.StatusBadRequest,
+			jsonhttptest.WithJSONRequestBody(map[string]string{"nodePassword": "keystore pass"}),
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusBadRequest,
+				Message: "password not provided",
+			}),
+		)
+	})
+}
+
+func TestKeysBackupDisabled(t *testing.T) {
+	testServer := newTestServer(t, testServerOptions{})
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/keys/backup", http.StatusNotFound,
+		jsonhttptest.WithJSONRequestBody(map[string]string{"nodePassword": "keystore pass", "password": "backup pass"}),
+	)
+}
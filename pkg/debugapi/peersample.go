@@ -0,0 +1,157 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/p2p"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/topology"
+)
+
+const (
+	defaultPeerSampleSize = 20
+	maxPeerSampleSize     = 100
+	// peerSampleRate and peerSampleBurst limit the samples taken by a client.
+	peerSampleRate  = 10 * time.Second
+	peerSampleBurst = 6
+	// lastSeenPrecision is the precision of the reported last seen times, so
+	// that the connection times of peers can not be correlated.
+	lastSeenPrecision = 10 * time.Minute
+)
+
+const (
+	peerSampleFieldBin      = "bin"
+	peerSampleFieldMode     = "mode"
+	peerSampleFieldVersion  = "version"
+	peerSampleFieldLastSeen = "lastSeen"
+)
+
+var defaultPeerSampleFields = []string{peerSampleFieldBin, peerSampleFieldMode, peerSampleFieldVersion, peerSampleFieldLastSeen}
+
+// peerSample holds the selected attributes of a sampled peer. Addresses of
+// the peers are never included.
+type peerSample struct {
+	Bin      *uint8 `json:"bin,omitempty"`
+	Mode     string `json:"mode,omitempty"`
+	Version  string `json:"version,omitempty"`
+	LastSeen *int64 `json:"lastSeen,omitempty"`
+}
+
+type peerSampleResponse struct {
+	Population int          `json:"population"`
+	Peers      []peerSample `json:"peers"`
+}
+
+type knownPeer struct {
+	address  swarm.Address
+	bin      uint8
+	light    bool
+	lastSeen int64
+}
+
+// peerSampleHandler returns a uniform random sample of the known peers with
+// the attributes selected by the comma separated fields query parameter.
+func (s *Service) peerSampleHandler(w http.ResponseWriter, r *http.Request) {
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+	if !s.peerSampleLimiter.Allow(client, 1) {
+		jsonhttp.TooManyRequests(w, "peer sample rate exceeded")
+		return
+	}
+
+	size := defaultPeerSampleSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		size, err = strconv.Atoi(v)
+		if err != nil || size <= 0 || size > maxPeerSampleSize {
+			jsonhttp.BadRequest(w, "invalid sample size")
+			return
+		}
+	}
+	fields := defaultPeerSampleFields
+	if v := r.URL.Query().Get("fields"); v != "" {
+		fields = strings.Split(v, ",")
+	}
+	selected := make(map[string]bool)
+	for _, f := range fields {
+		switch f {
+		case peerSampleFieldBin, peerSampleFieldMode, peerSampleFieldVersion, peerSampleFieldLastSeen:
+			selected[f] = true
+		default:
+			jsonhttp.BadRequest(w, "invalid field "+f)
+			return
+		}
+	}
+
+	peers := knownPeers(s.topologyDriver.Snapshot())
+	population := len(peers)
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	rnd.Shuffle(len(peers), func(i, j int) {
+		peers[i], peers[j] = peers[j], peers[i]
+	})
+	if len(peers) > size {
+		peers = peers[:size]
+	}
+
+	versioner, _ := s.p2p.(p2p.Versioner)
+	precision := int64(lastSeenPrecision / time.Second)
+	sample := make([]peerSample, 0, len(peers))
+	for _, p := range peers {
+		var ps peerSample
+		if selected[peerSampleFieldBin] && !p.light {
+			bin := p.bin
+			ps.Bin = &bin
+		}
+		if selected[peerSampleFieldMode] {
+			ps.Mode = "full"
+			if p.light {
+				ps.Mode = "light"
+			}
+		}
+		if selected[peerSampleFieldVersion] && versioner != nil {
+			ps.Version, _ = versioner.PeerVersion(p.address)
+		}
+		if selected[peerSampleFieldLastSeen] && p.lastSeen > 0 {
+			lastSeen := p.lastSeen - p.lastSeen%precision
+			ps.LastSeen = &lastSeen
+		}
+		sample = append(sample, ps)
+	}
+
+	jsonhttp.OK(w, peerSampleResponse{
+		Population: population,
+		Peers:      sample,
+	})
+}
+
+// knownPeers returns the connected and disconnected peers of the topology.
+func knownPeers(params *topology.KadParams) []knownPeer {
+	var peers []knownPeer
+	add := func(bin uint8, light bool, infos []*topology.PeerInfo) {
+		for _, info := range infos {
+			p := knownPeer{address: info.Address, bin: bin, light: light}
+			if info.Metrics != nil {
+				p.lastSeen = info.Metrics.LastSeenTimestamp
+			}
+			peers = append(peers, p)
+		}
+	}
+	for i, b := range params.Bins.List() {
+		add(uint8(i), false, b.ConnectedPeers)
+		add(uint8(i), false, b.DisconnectedPeers)
+	}
+	add(0, true, params.LightNodes.ConnectedPeers)
+	add(0, true, params.LightNodes.DisconnectedPeers)
+	return peers
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/debugapi"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/swarm/test"
+	"github.com/ethsana/sana/pkg/topology"
+	topologymock "github.com/ethsana/sana/pkg/topology/mock"
+)
+
+func TestPeerSample(t *testing.T) {
+	peer := func(lastSeen int64) *topology.PeerInfo {
+		return &topology.PeerInfo{
+			Address: test.RandomAddress(),
+			Metrics: &topology.MetricSnapshotView{LastSeenTimestamp: lastSeen},
+		}
+	}
+	snapshot := &topology.KadParams{
+		Bins: topology.KadBins{
+			Bin1: topology.BinInfo{ConnectedPeers: []*topology.PeerInfo{peer(1620000123)}},
+			Bin3: topology.BinInfo{DisconnectedPeers: []*topology.PeerInfo{peer(1620000123), peer(1620000123)}},
+		},
+		LightNodes: topology.BinInfo{ConnectedPeers: []*topology.PeerInfo{peer(1620000123)}},
+	}
+	testServer := newTestServer(t, testServerOptions{
+		TopologyOpts: []topologymock.Option{topologymock.WithSnapshot(snapshot)},
+	})
+
+	var resp debugapi.PeerSampleResponse
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers/sample?size=10&fields=mode,lastSeen", http.StatusOK,
+		jsonhttptest.WithUnmarshalJSONResponse(&resp),
+	)
+	if resp.Population != 4 || len(resp.Peers) != 4 {
+		t.Fatalf("got population %d and %d peers", resp.Population, len(resp.Peers))
+	}
+	light := 0
+	for _, p := range resp.Peers {
+		if p.Bin != nil || p.Version != "" {
+			t.Fatalf("got unselected fields %+v", p)
+		}
+		if p.LastSeen == nil || *p.LastSeen != 1620000000 {
+			t.Fatalf("got last seen %v, want rounded time", p.LastSeen)
+		}
+		if p.Mode == "light" {
+			light++
+		}
+	}
+	if light != 1 {
+		t.Fatalf("got %d light nodes, want 1", light)
+	}
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers/sample?size=2", http.StatusOK,
+		jsonhttptest.WithUnmarshalJSONResponse(&resp),
+	)
+	if resp.Population != 4 || len(resp.Peers) != 2 {
+		t.Fatalf("got population %d and %d peers", resp.Population, len(resp.Peers))
+	}
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers/sample?size=1000", http.StatusBadRequest)
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers/sample?fields=underlay", http.StatusBadRequest)
+
+	// the burst of the rate limit is exhausted
+	for i := 0; i < 2; i++ {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers/sample", http.StatusOK)
+	}
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers/sample", http.StatusTooManyRequests)
+}
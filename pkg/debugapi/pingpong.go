@@ -23,7 +23,7 @@ func (s *Service) pingpongHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	span, logger, ctx := s.tracer.StartSpanFromContext(ctx, "pingpong-api", s.logger)
-	defer span.Finish()
+	defer span.End()
 
 	address, err := swarm.ParseHexAddress(peerID)
 	if err != nil {
@@ -15,6 +15,7 @@ import (
 	"github.com/ethsana/sana/pkg/bigint"
 	"github.com/ethsana/sana/pkg/jsonhttp"
 	"github.com/ethsana/sana/pkg/postage/postagecontract"
+	"github.com/ethsana/sana/pkg/postage/postagemanager"
 	"github.com/ethsana/sana/pkg/sctx"
 	"github.com/gorilla/mux"
 )
@@ -161,6 +162,30 @@ func (s *Service) postageGetStampHandler(w http.ResponseWriter, r *http.Request)
 	jsonhttp.OK(w, &resp)
 }
 
+type postageExpiryResponse struct {
+	Batches    []postagemanager.BatchStatus `json:"batches"`
+	TopUpLimit *bigint.BigInt               `json:"topUpLimit"`
+	TopUpSpent *bigint.BigInt               `json:"topUpSpent"`
+}
+
+// postageExpiryHandler returns the estimated time to live of the owned
+// batches and the spent part of the automatic top-up budget.
+func (s *Service) postageExpiryHandler(w http.ResponseWriter, _ *http.Request) {
+	status, err := s.postageManager.Status()
+	if err != nil {
+		s.logger.Debugf("postage expiry: status: %v", err)
+		s.logger.Error("postage expiry: status")
+		jsonhttp.InternalServerError(w, nil)
+		return
+	}
+
+	jsonhttp.OK(w, postageExpiryResponse{
+		Batches:    status.Batches,
+		TopUpLimit: bigint.Wrap(status.Limit),
+		TopUpSpent: bigint.Wrap(status.Spent),
+	})
+}
+
 type reserveStateResponse struct {
 	Radius        uint8          `json:"radius"`
 	StorageRadius uint8          `json:"storageRadius"`
@@ -9,20 +9,25 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/ethsana/sana/pkg/bigint"
 	"github.com/ethsana/sana/pkg/debugapi"
 	"github.com/ethsana/sana/pkg/jsonhttp"
 	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
 	"github.com/ethsana/sana/pkg/postage"
 	"github.com/ethsana/sana/pkg/postage/batchstore/mock"
 	mockpost "github.com/ethsana/sana/pkg/postage/mock"
 	"github.com/ethsana/sana/pkg/postage/postagecontract"
 	contractMock "github.com/ethsana/sana/pkg/postage/postagecontract/mock"
+	"github.com/ethsana/sana/pkg/postage/postagemanager"
 	"github.com/ethsana/sana/pkg/sctx"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
 )
 
 func TestPostageCreateStamp(t *testing.T) {
@@ -314,3 +319,32 @@ func TestChainState(t *testing.T) {
 		)
 	})
 }
+
+func TestPostageExpiry(t *testing.T) {
+	si := postage.NewStampIssuer("label", "", batchOk, big.NewInt(3), 11, 10, 1000, true)
+	batchStore := mock.New(
+		mock.WithBatch(&postage.Batch{ID: batchOk, Value: big.NewInt(100), Depth: 11, BucketDepth: 10}),
+		mock.WithChainState(&postage.ChainState{TotalAmount: big.NewInt(50), CurrentPrice: big.NewInt(5)}),
+	)
+	manager := postagemanager.New(mockpost.New(mockpost.WithIssuer(si)), batchStore, contractMock.New(), statestore.NewStateStore(), logging.New(ioutil.Discard, 0), postagemanager.Options{
+		TopUpLimit: big.NewInt(1000),
+		BlockTime:  time.Second,
+	})
+	ts := newTestServer(t, testServerOptions{PostageManager: manager})
+
+	jsonhttptest.Request(t, ts.Client, http.MethodGet, "/stamps/expiry", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(&debugapi.PostageExpiryResponse{
+			Batches: []postagemanager.BatchStatus{
+				{
+					BatchID:  batchOkStr,
+					Label:    "label",
+					Depth:    11,
+					TTL:      10,
+					Expiring: true,
+				},
+			},
+			TopUpLimit: bigint.Wrap(big.NewInt(1000)),
+			TopUpSpent: bigint.Wrap(big.NewInt(0)),
+		}),
+	)
+}
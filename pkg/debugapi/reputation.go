@@ -0,0 +1,91 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/reputation"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+const peerPolicyMaxRequestSize = 64 * 1024
+
+// PeerReputation keeps the scores of the peers and the connection policy of
+// the node.
+type PeerReputation interface {
+	Scores() map[string]reputation.Score
+	Policy() reputation.Policy
+	SetPolicy(reputation.Policy)
+}
+
+// peerPolicy is the connection policy with the ban duration in seconds.
+type peerPolicy struct {
+	BanThreshold   float64         `json:"banThreshold"`
+	BanDuration    int64           `json:"banDuration"`
+	PreferredPeers []swarm.Address `json:"preferredPeers"`
+	BlockedCIDRs   []string        `json:"blockedCIDRs"`
+}
+
+type peerReputationResponse struct {
+	Peers map[string]reputation.Score `json:"peers"`
+}
+
+func (s *Service) peerReputationHandler(w http.ResponseWriter, r *http.Request) {
+	jsonhttp.OK(w, peerReputationResponse{Peers: s.peerReputation.Scores()})
+}
+
+func (s *Service) peerPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	p := s.peerReputation.Policy()
+	resp := peerPolicy{
+		BanThreshold:   p.BanThreshold,
+		BanDuration:    int64(p.BanDuration / time.Second),
+		PreferredPeers: p.PreferredPeers,
+		BlockedCIDRs:   make([]string, 0, len(p.BlockedNetworks)),
+	}
+	if resp.PreferredPeers == nil {
+		resp.PreferredPeers = []swarm.Address{}
+	}
+	for _, n := range p.BlockedNetworks {
+		resp.BlockedCIDRs = append(resp.BlockedCIDRs, n.String())
+	}
+	jsonhttp.OK(w, resp)
+}
+
+// peerPolicyUpdateHandler replaces the connection policy until the node is
+// restarted with the one of its configuration.
+func (s *Service) peerPolicyUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	var req peerPolicy
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.logger.Debugf("debug api: peer policy: decode request: %v", err)
+		jsonhttp.BadRequest(w, "invalid request")
+		return
+	}
+	if req.BanThreshold > 0 {
+		jsonhttp.BadRequest(w, "ban threshold must not be positive")
+		return
+	}
+	if req.BanDuration < 0 {
+		jsonhttp.BadRequest(w, "ban duration must not be negative")
+		return
+	}
+	networks, err := reputation.ParseCIDRs(req.BlockedCIDRs)
+	if err != nil {
+		s.logger.Debugf("debug api: peer policy: %v", err)
+		jsonhttp.BadRequest(w, "invalid blocked cidr")
+		return
+	}
+
+	s.peerReputation.SetPolicy(reputation.Policy{
+		BanThreshold:    req.BanThreshold,
+		BanDuration:     time.Duration(req.BanDuration) * time.Second,
+		PreferredPeers:  req.PreferredPeers,
+		BlockedNetworks: networks,
+	})
+	s.peerPolicyHandler(w, r)
+}
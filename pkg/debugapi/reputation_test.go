@@ -0,0 +1,103 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/debugapi"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/reputation"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/swarm/test"
+)
+
+func TestPeerPolicy(t *testing.T) {
+	rep, err := reputation.New(statestore.NewStateStore(), logging.New(ioutil.Discard, 0), reputation.Policy{
+		BanDuration: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rep.Close()
+	testServer := newTestServer(t, testServerOptions{
+		PeerReputation: rep,
+	})
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers/policy", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(debugapi.PeerPolicy{
+			BanDuration:    3600,
+			PreferredPeers: []swarm.Address{},
+			BlockedCIDRs:   []string{},
+		}),
+	)
+
+	preferred := test.RandomAddress()
+	policy := debugapi.PeerPolicy{
+		BanThreshold:   -20,
+		BanDuration:    60,
+		PreferredPeers: []swarm.Address{preferred},
+		BlockedCIDRs:   []string{"10.0.0.0/8"},
+	}
+	jsonhttptest.Request(t, testServer.Client, http.MethodPut, "/peers/policy", http.StatusOK,
+		jsonhttptest.WithJSONRequestBody(policy),
+		jsonhttptest.WithExpectedJSONResponse(policy),
+	)
+	if p := rep.Policy(); p.BanThreshold != -20 || p.BanDuration != time.Minute || !rep.Preferred(preferred) {
+		t.Fatalf("got policy %+v", p)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		policy  debugapi.PeerPolicy
+		message string
+	}{
+		{name: "positive threshold", policy: debugapi.PeerPolicy{BanThreshold: 1}, message: "ban threshold must not be positive"},
+		{name: "negative duration", policy: debugapi.PeerPolicy{BanDuration: -1}, message: "ban duration must not be negative"},
+		{name: "invalid cidr", policy: debugapi.PeerPolicy{BlockedCIDRs: []string{"10.0.0.0"}}, message: "invalid blocked cidr"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			jsonhttptest.Request(t, testServer.Client, http.MethodPut, "/peers/policy", http.StatusBadRequest,
+				jsonhttptest.WithJSONRequestBody(tc.policy),
+				jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+					Code:    http.StatusBadRequest,
+					Message: tc.message,
+				}),
+			)
+		})
+	}
+}
+
+func TestPeerReputation(t *testing.T) {
+	rep, err := reputation.New(statestore.NewStateStore(), logging.New(ioutil.Discard, 0), reputation.Policy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rep.Close()
+	peer := test.RandomAddress()
+	rep.RecordFailure(peer, reputation.FailureProtocol)
+
+	testServer := newTestServer(t, testServerOptions{
+		PeerReputation: rep,
+	})
+
+	var resp debugapi.PeerReputationResponse
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers/reputation", http.StatusOK,
+		jsonhttptest.WithUnmarshalJSONResponse(&resp),
+	)
+	sc, ok := resp.Peers[peer.String()]
+	if !ok {
+		t.Fatalf("peer %s not in the response", peer)
+	}
+	if sc.Score != -2 || sc.ProtocolErrors != 1 {
+		t.Fatalf("got score %v and %d protocol errors, want -2 and 1 error", sc.Score, sc.ProtocolErrors)
+	}
+}
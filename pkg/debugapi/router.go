@@ -59,6 +59,12 @@ func (s *Service) newBasicRouter() *mux.Router {
 		"GET": http.HandlerFunc(s.addressesHandler),
 	})
 
+	if s.events != nil {
+		router.Handle("/events", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.eventsHandler),
+		})
+	}
+
 	if s.transaction != nil {
 		router.Handle("/transactions", jsonhttp.MethodHandler{
 			"GET": http.HandlerFunc(s.transactionListHandler),
@@ -92,10 +98,6 @@ func (s *Service) newRouter() *mux.Router {
 		"GET": http.HandlerFunc(s.reserveStateHandler),
 	})
 
-	router.Handle("/chainstate", jsonhttp.MethodHandler{
-		"GET": http.HandlerFunc(s.chainStateHandler),
-	})
-
 	router.Handle("/connect/{multi-address:.+}", jsonhttp.MethodHandler{
 		"POST": http.HandlerFunc(s.peerConnectHandler),
 	})
@@ -109,6 +111,21 @@ func (s *Service) newRouter() *mux.Router {
 		"GET": http.HandlerFunc(s.underlaysHandler),
 	})
 
+	router.Handle("/peers/sample", jsonhttp.MethodHandler{
+		"GET": http.HandlerFunc(s.peerSampleHandler),
+	})
+	if s.peerReputation != nil {
+		router.Handle("/peers/policy", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.peerPolicyHandler),
+			"PUT": web.ChainHandlers(
+				jsonhttp.NewMaxBodyBytesHandler(peerPolicyMaxRequestSize),
+				web.FinalHandlerFunc(s.peerPolicyUpdateHandler),
+			),
+		})
+		router.Handle("/peers/reputation", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.peerReputationHandler),
+		})
+	}
 	router.Handle("/peers/{address}", jsonhttp.MethodHandler{
 		"DELETE": http.HandlerFunc(s.peerDisconnectHandler),
 	})
@@ -116,6 +133,85 @@ func (s *Service) newRouter() *mux.Router {
 		"GET":    http.HandlerFunc(s.hasChunkHandler),
 		"DELETE": http.HandlerFunc(s.removeChunk),
 	})
+	if s.uploadScanAudit != nil {
+		router.Handle("/uploadscan/rejections", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.uploadScanRejectionsHandler),
+		})
+	}
+	if s.scheduler != nil {
+		router.Handle("/schedules", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.schedulesHandler),
+			"POST": web.ChainHandlers(
+				jsonhttp.NewMaxBodyBytesHandler(scheduleMaxRequestSize),
+				web.FinalHandlerFunc(s.scheduleCreateHandler),
+			),
+		})
+		router.Handle("/schedules/{id}", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.scheduleGetHandler),
+			"PATCH": web.ChainHandlers(
+				jsonhttp.NewMaxBodyBytesHandler(scheduleMaxRequestSize),
+				web.FinalHandlerFunc(s.scheduleUpdateHandler),
+			),
+			"DELETE": http.HandlerFunc(s.scheduleDeleteHandler),
+		})
+		router.Handle("/schedules/{id}/history", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.scheduleHistoryHandler),
+		})
+	}
+	if s.commitment != nil {
+		router.Handle("/commitment", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.commitmentHandler),
+		})
+		router.Handle("/commitment/proof/{address}", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.commitmentProofHandler),
+		})
+	}
+	if s.drainer != nil {
+		router.Handle("/health/drain", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.drainStatusHandler),
+		})
+	}
+	if s.keyBackup != nil {
+		router.Handle("/keys/backup", jsonhttp.MethodHandler{
+			"POST": web.ChainHandlers(
+				jsonhttp.NewMaxBodyBytesHandler(keysBackupMaxRequestSize),
+				web.FinalHandlerFunc(s.keysBackupHandler),
+			),
+		})
+	}
+	if s.identityClaim != nil {
+		router.Handle("/node/identity-claim", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.identityClaimHandler),
+		})
+	}
+	if s.snapshots != nil || s.pullSync != nil {
+		router.Handle("/sync/progress", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.syncProgressHandler),
+		})
+	}
+	if s.snapshots != nil {
+		router.Handle("/snapshot", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.snapshotHandler),
+		})
+	}
+	if s.storagePools != nil {
+		router.Handle("/storage/pools", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.storagePoolsHandler),
+		})
+	}
+	if s.reloader != nil {
+		router.Handle("/config/reload", jsonhttp.MethodHandler{
+			"POST": http.HandlerFunc(s.configReloadHandler),
+		})
+	}
+	if s.takedown != nil {
+		router.Handle("/takedowns", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.takedownsHandler),
+		})
+		router.Handle("/takedowns/audit", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.takedownAuditHandler),
+		})
+	}
 	router.Handle("/topology", jsonhttp.MethodHandler{
 		"GET": http.HandlerFunc(s.topologyHandler),
 	})
@@ -151,6 +247,12 @@ func (s *Service) newRouter() *mux.Router {
 		router.Handle("/settlements", jsonhttp.MethodHandler{
 			"GET": http.HandlerFunc(s.settlementsHandler),
 		})
+		router.Handle("/settlements/history", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.settlementHistoryHandler),
+		})
+		router.Handle("/settlements/export", jsonhttp.MethodHandler{
+			"GET": http.HandlerFunc(s.settlementExportHandler),
+		})
 		router.Handle("/settlements/{peer}", jsonhttp.MethodHandler{
 			"GET": http.HandlerFunc(s.peerSettlementsHandler),
 		})
@@ -205,12 +307,46 @@ func (s *Service) newRouter() *mux.Router {
 		"GET": http.HandlerFunc(s.getTagHandler),
 	})
 
+	s.handlePostage(router)
+
+	return router
+}
+
+// newPostageRouter constructs the basic routes and those of the postage,
+// for the nodes which are configured with the postage dependencies only.
+func (s *Service) newPostageRouter() *mux.Router {
+	router := s.newBasicRouter()
+
+	router.Handle("/readiness", web.ChainHandlers(
+		httpaccess.SetAccessLogLevelHandler(0), // suppress access log messages
+		web.FinalHandlerFunc(statusHandler),
+	))
+
+	s.handlePostage(router)
+
+	return router
+}
+
+// handlePostage adds the routes of the chain state and the postage stamps.
+func (s *Service) handlePostage(router *mux.Router) {
+	router.Handle("/chainstate", jsonhttp.MethodHandler{
+		"GET": http.HandlerFunc(s.chainStateHandler),
+	})
+
 	router.Handle("/stamps", web.ChainHandlers(
 		web.FinalHandler(jsonhttp.MethodHandler{
 			"GET": http.HandlerFunc(s.postageGetStampsHandler),
 		})),
 	)
 
+	if s.postageManager != nil {
+		router.Handle("/stamps/expiry", web.ChainHandlers(
+			web.FinalHandler(jsonhttp.MethodHandler{
+				"GET": http.HandlerFunc(s.postageExpiryHandler),
+			})),
+		)
+	}
+
 	router.Handle("/stamps/{id}", web.ChainHandlers(
 		web.FinalHandler(jsonhttp.MethodHandler{
 			"GET": http.HandlerFunc(s.postageGetStampHandler),
@@ -222,8 +358,6 @@ func (s *Service) newRouter() *mux.Router {
 			"POST": http.HandlerFunc(s.postageCreateHandler),
 		})),
 	)
-
-	return router
 }
 
 // setRouter sets the base Debug API handler with common middlewares.
@@ -0,0 +1,116 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/scheduler"
+	"github.com/gorilla/mux"
+)
+
+const scheduleMaxRequestSize = 16 * 1024
+
+type scheduleRequest struct {
+	Type       string          `json:"type"`
+	Expression string          `json:"expression"`
+	Params     json.RawMessage `json:"params"`
+}
+
+type schedulesResponse struct {
+	Schedules []scheduler.Schedule `json:"schedules"`
+	Types     []string             `json:"types"`
+}
+
+type scheduleHistoryResponse struct {
+	Executions []scheduler.Execution `json:"executions"`
+}
+
+func (s *Service) schedulesHandler(w http.ResponseWriter, r *http.Request) {
+	jsonhttp.OK(w, schedulesResponse{
+		Schedules: s.scheduler.List(),
+		Types:     s.scheduler.Types(),
+	})
+}
+
+func (s *Service) scheduleCreateHandler(w http.ResponseWriter, r *http.Request) {
+	var req scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.logger.Debugf("debug api: schedules: decode request: %v", err)
+		jsonhttp.BadRequest(w, "invalid request")
+		return
+	}
+	sc, err := s.scheduler.Create(req.Type, req.Expression, req.Params)
+	if err != nil {
+		s.scheduleError(w, "create", err)
+		return
+	}
+	jsonhttp.Created(w, sc)
+}
+
+func (s *Service) scheduleGetHandler(w http.ResponseWriter, r *http.Request) {
+	sc, err := s.scheduler.Get(mux.Vars(r)["id"])
+	if err != nil {
+		s.scheduleError(w, "get", err)
+		return
+	}
+	jsonhttp.OK(w, sc)
+}
+
+func (s *Service) scheduleUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	var u scheduler.Update
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		s.logger.Debugf("debug api: schedules: decode request: %v", err)
+		jsonhttp.BadRequest(w, "invalid request")
+		return
+	}
+	sc, err := s.scheduler.Update(mux.Vars(r)["id"], u)
+	if err != nil {
+		s.scheduleError(w, "update", err)
+		return
+	}
+	jsonhttp.OK(w, sc)
+}
+
+func (s *Service) scheduleDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if err := s.scheduler.Delete(mux.Vars(r)["id"]); err != nil {
+		s.scheduleError(w, "delete", err)
+		return
+	}
+	jsonhttp.OK(w, nil)
+}
+
+func (s *Service) scheduleHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	h, err := s.scheduler.History(mux.Vars(r)["id"])
+	if err != nil {
+		s.scheduleError(w, "history", err)
+		return
+	}
+	if h == nil {
+		h = make([]scheduler.Execution, 0)
+	}
+	jsonhttp.OK(w, scheduleHistoryResponse{
+		Executions: h,
+	})
+}
+
+func (s *Service) scheduleError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, scheduler.ErrNotFound):
+		jsonhttp.NotFound(w, "schedule not found")
+	case errors.Is(err, scheduler.ErrUnknownType):
+		jsonhttp.BadRequest(w, "unknown task type")
+	case errors.Is(err, scheduler.ErrInvalid):
+		s.logger.Debugf("debug api: schedules: %s: %v", op, err)
+		jsonhttp.BadRequest(w, err.Error())
+	default:
+		s.logger.Debugf("debug api: schedules: %s: %v", op, err)
+		s.logger.Errorf("debug api: schedules: %s", op)
+		jsonhttp.InternalServerError(w, "cannot "+op+" schedule")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/debugapi"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/scheduler"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+)
+
+func TestSchedules(t *testing.T) {
+	s, err := scheduler.New(statestore.NewStateStore(), logging.New(ioutil.Discard, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Register("noop", scheduler.TaskFunc(func(context.Context, json.RawMessage) error { return nil }))
+
+	testServer := newTestServer(t, testServerOptions{
+		Scheduler: s,
+	})
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/schedules", http.StatusBadRequest,
+		jsonhttptest.WithJSONRequestBody(debugapi.ScheduleRequest{Type: "unknown", Expression: "@daily"}),
+		jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+			Code:    http.StatusBadRequest,
+			Message: "unknown task type",
+		}),
+	)
+	jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/schedules", http.StatusBadRequest,
+		jsonhttptest.WithJSONRequestBody(debugapi.ScheduleRequest{Type: "noop", Expression: "61 * * * *"}),
+	)
+
+	var sc scheduler.Schedule
+	jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/schedules", http.StatusCreated,
+		jsonhttptest.WithJSONRequestBody(debugapi.ScheduleRequest{Type: "noop", Expression: "@daily"}),
+		jsonhttptest.WithUnmarshalJSONResponse(&sc),
+	)
+	if sc.ID == "" || sc.Type != "noop" || !sc.Enabled {
+		t.Fatalf("got schedule %+v", sc)
+	}
+
+	var list debugapi.SchedulesResponse
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/schedules", http.StatusOK,
+		jsonhttptest.WithUnmarshalJSONResponse(&list),
+	)
+	if len(list.Schedules) != 1 || list.Schedules[0].ID != sc.ID || len(list.Types) != 1 || list.Types[0] != "noop" {
+		t.Fatalf("got schedules %+v", list)
+	}
+
+	expression := "0 3 * * *"
+	var updated scheduler.Schedule
+	jsonhttptest.Request(t, testServer.Client, http.MethodPatch, "/schedules/"+sc.ID, http.StatusOK,
+		jsonhttptest.WithJSONRequestBody(scheduler.Update{Expression: &expression}),
+		jsonhttptest.WithUnmarshalJSONResponse(&updated),
+	)
+	if updated.Expression != expression || updated.NextRun.Minute() != 0 {
+		t.Fatalf("got schedule %+v", updated)
+	}
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/schedules/"+sc.ID+"/history", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(debugapi.ScheduleHistoryResponse{
+			Executions: []scheduler.Execution{},
+		}),
+	)
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodDelete, "/schedules/"+sc.ID, http.StatusOK)
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/schedules/"+sc.ID, http.StatusNotFound,
+		jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+			Code:    http.StatusNotFound,
+			Message: "schedule not found",
+		}),
+	)
+}
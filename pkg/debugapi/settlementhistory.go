@@ -0,0 +1,211 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ethsana/sana/pkg/bigint"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/settlement/swap"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+const (
+	errCantSettlementHistory = "can not get settlement history"
+
+	// defaultSettlementHistoryRange is the range of the settlement history
+	// returned if the from parameter is not set.
+	defaultSettlementHistoryRange = 7 * 24 * time.Hour
+)
+
+// settlementHistoryPeriods are the periods of the buckets of the settlement
+// history by the value of the period parameter.
+var settlementHistoryPeriods = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+	"week": 7 * 24 * time.Hour,
+}
+
+var settlementHistoryCSVHeader = []string{"peer", "start", "chequesSent", "sent", "chequesReceived", "received", "cashouts", "uncashed"}
+
+type settlementHistoryBucket struct {
+	Peer            string         `json:"peer"`
+	Start           int64          `json:"start"`
+	ChequesSent     uint64         `json:"chequesSent"`
+	Sent            *bigint.BigInt `json:"sent"`
+	ChequesReceived uint64         `json:"chequesReceived"`
+	Received        *bigint.BigInt `json:"received"`
+	Cashouts        uint64         `json:"cashouts"`
+	Uncashed        *bigint.BigInt `json:"uncashed"`
+}
+
+type settlementHistoryResponse struct {
+	From    int64                     `json:"from"`
+	To      int64                     `json:"to"`
+	Period  string                    `json:"period"`
+	Buckets []settlementHistoryBucket `json:"buckets"`
+}
+
+// settlementHistoryHandler returns the time bucketed settlement history of
+// the peer in the peer parameter, or of all peers.
+func (s *Service) settlementHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.settlementHistory(r)
+	if err != nil {
+		var badRequest *settlementHistoryRequestError
+		if errors.As(err, &badRequest) {
+			jsonhttp.BadRequest(w, badRequest.msg)
+			return
+		}
+		s.logger.Debugf("debug api: settlement history: %v", err)
+		s.logger.Error("debug api: can not get settlement history")
+		jsonhttp.InternalServerError(w, errCantSettlementHistory)
+		return
+	}
+	jsonhttp.OK(w, resp)
+}
+
+// settlementExportHandler returns the settlement history as an attachment
+// in the json or csv format by the format parameter, for the import into the
+// accounting.
+func (s *Service) settlementExportHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		jsonhttp.BadRequest(w, "invalid format")
+		return
+	}
+
+	resp, err := s.settlementHistory(r)
+	if err != nil {
+		var badRequest *settlementHistoryRequestError
+		if errors.As(err, &badRequest) {
+			jsonhttp.BadRequest(w, badRequest.msg)
+			return
+		}
+		s.logger.Debugf("debug api: settlement export: %v", err)
+		s.logger.Error("debug api: can not export settlement history")
+		jsonhttp.InternalServerError(w, errCantSettlementHistory)
+		return
+	}
+
+	filename := "settlements-" + strconv.FormatInt(resp.From, 10) + "-" + strconv.FormatInt(resp.To, 10) + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	if format == "json" {
+		w.Header().Set("Content-Type", jsonhttp.DefaultContentTypeHeader)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			s.logger.Debugf("debug api: settlement export: write: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	cw := csv.NewWriter(w)
+	records := [][]string{settlementHistoryCSVHeader}
+	for _, b := range resp.Buckets {
+		records = append(records, []string{
+			b.Peer,
+			time.Unix(b.Start, 0).UTC().Format(time.RFC3339),
+			strconv.FormatUint(b.ChequesSent, 10),
+			b.Sent.String(),
+			strconv.FormatUint(b.ChequesReceived, 10),
+			b.Received.String(),
+			strconv.FormatUint(b.Cashouts, 10),
+			b.Uncashed.String(),
+		})
+	}
+	if err := cw.WriteAll(records); err != nil {
+		s.logger.Debugf("debug api: settlement export: write: %v", err)
+	}
+}
+
+type settlementHistoryRequestError struct {
+	msg string
+}
+
+func (e *settlementHistoryRequestError) Error() string {
+	return e.msg
+}
+
+// settlementHistory returns the settlement history by the peer, from, to and
+// period parameters of the request. The from and to parameters are unix
+// times, to defaults to now and from to a week before to.
+func (s *Service) settlementHistory(r *http.Request) (*settlementHistoryResponse, error) {
+	query := r.URL.Query()
+
+	peer := swarm.ZeroAddress
+	if v := query.Get("peer"); v != "" {
+		var err error
+		if peer, err = swarm.ParseHexAddress(v); err != nil {
+			return nil, &settlementHistoryRequestError{msg: errInvalidAddress}
+		}
+	}
+
+	to := time.Now()
+	if v := query.Get("to"); v != "" {
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, &settlementHistoryRequestError{msg: "invalid to"}
+		}
+		to = time.Unix(sec, 0)
+	}
+	from := to.Add(-defaultSettlementHistoryRange)
+	if v := query.Get("from"); v != "" {
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, &settlementHistoryRequestError{msg: "invalid from"}
+		}
+		from = time.Unix(sec, 0)
+	}
+	if !from.Before(to) {
+		return nil, &settlementHistoryRequestError{msg: "from must be before to"}
+	}
+
+	periodName := query.Get("period")
+	if periodName == "" {
+		periodName = "hour"
+	}
+	period, ok := settlementHistoryPeriods[periodName]
+	if !ok {
+		return nil, &settlementHistoryRequestError{msg: "invalid period"}
+	}
+
+	history, err := s.swap.SettlementHistory(peer, from, to, period)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &settlementHistoryResponse{
+		From:    from.Unix(),
+		To:      to.Unix(),
+		Period:  periodName,
+		Buckets: make([]settlementHistoryBucket, 0, len(history)),
+	}
+	for _, b := range history {
+		resp.Buckets = append(resp.Buckets, newSettlementHistoryBucket(b))
+	}
+	return resp, nil
+}
+
+func newSettlementHistoryBucket(b swap.HistoryBucket) settlementHistoryBucket {
+	return settlementHistoryBucket{
+		Peer:            b.Peer.String(),
+		Start:           b.Start.Unix(),
+		ChequesSent:     b.ChequesSent,
+		Sent:            bigint.Wrap(b.Sent),
+		ChequesReceived: b.ChequesReceived,
+		Received:        bigint.Wrap(b.Received),
+		Cashouts:        b.Cashouts,
+		Uncashed:        bigint.Wrap(b.Uncashed),
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/bigint"
+	"github.com/ethsana/sana/pkg/debugapi"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/settlement/swap"
+	"github.com/ethsana/sana/pkg/settlement/swap/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+func TestSettlementHistory(t *testing.T) {
+	peer := swarm.MustParseHexAddress("abcd")
+	start := time.Unix(1622505600, 0).UTC()
+
+	var (
+		gotPeer   swarm.Address
+		gotFrom   time.Time
+		gotTo     time.Time
+		gotPeriod time.Duration
+	)
+	testServer := newTestServer(t, testServerOptions{
+		SwapOpts: []mock.Option{mock.WithSettlementHistoryFunc(func(p swarm.Address, from, to time.Time, period time.Duration) ([]swap.HistoryBucket, error) {
+			gotPeer, gotFrom, gotTo, gotPeriod = p, from, to, period
+			return []swap.HistoryBucket{{
+				Peer:            peer,
+				Start:           start,
+				ChequesSent:     1,
+				Sent:            big.NewInt(70),
+				ChequesReceived: 2,
+				Received:        big.NewInt(150),
+				Cashouts:        1,
+				Uncashed:        big.NewInt(50),
+			}}, nil
+		})},
+	})
+
+	from, to := start.Unix(), start.Add(24*time.Hour).Unix()
+	want := debugapi.SettlementHistoryResponse{
+		From:   from,
+		To:     to,
+		Period: "day",
+		Buckets: []debugapi.SettlementHistoryBucket{{
+			Peer:            peer.String(),
+			Start:           start.Unix(),
+			ChequesSent:     1,
+			Sent:            bigint.Wrap(big.NewInt(70)),
+			ChequesReceived: 2,
+			Received:        bigint.Wrap(big.NewInt(150)),
+			Cashouts:        1,
+			Uncashed:        bigint.Wrap(big.NewInt(50)),
+		}},
+	}
+	query := "?peer=abcd&from=1622505600&to=1622592000&period=day"
+
+	t.Run("history", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/settlements/history"+query, http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(want),
+		)
+		if !gotPeer.Equal(peer) || gotFrom.Unix() != from || gotTo.Unix() != to || gotPeriod != 24*time.Hour {
+			t.Fatalf("got peer %s, from %v, to %v, period %v", gotPeer, gotFrom, gotTo, gotPeriod)
+		}
+	})
+
+	t.Run("history of all peers", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/settlements/history", http.StatusOK)
+		if !gotPeer.IsZero() || gotPeriod != time.Hour || gotTo.Sub(gotFrom) != 7*24*time.Hour {
+			t.Fatalf("got peer %s, from %v, to %v, period %v", gotPeer, gotFrom, gotTo, gotPeriod)
+		}
+	})
+
+	t.Run("export csv", func(t *testing.T) {
+		header := jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/settlements/export"+query, http.StatusOK,
+			jsonhttptest.WithExpectedResponse([]byte(
+				"peer,start,chequesSent,sent,chequesReceived,received,cashouts,uncashed\n"+
+					peer.String()+",2021-06-01T00:00:00Z,1,70,2,150,1,50\n",
+			)),
+		)
+		if got := header.Get("Content-Disposition"); got != `attachment; filename="settlements-1622505600-1622592000.csv"` {
+			t.Fatalf("got content disposition %q", got)
+		}
+	})
+
+	t.Run("export json", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/settlements/export"+query+"&format=json", http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(want),
+		)
+	})
+
+	for _, tc := range []struct {
+		name  string
+		url   string
+		error string
+	}{
+		{name: "invalid peer", url: "/settlements/history?peer=zz", error: debugapi.ErrInvalidAddress},
+		{name: "invalid from", url: "/settlements/history?from=x", error: "invalid from"},
+		{name: "invalid interval", url: "/settlements/history?from=10&to=5", error: "from must be before to"},
+		{name: "invalid period", url: "/settlements/history?period=minute", error: "invalid period"},
+		{name: "invalid format", url: "/settlements/export?format=xml", error: "invalid format"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			jsonhttptest.Request(t, testServer.Client, http.MethodGet, tc.url, http.StatusBadRequest,
+				jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+					Message: tc.error,
+					Code:    http.StatusBadRequest,
+				}),
+			)
+		})
+	}
+}
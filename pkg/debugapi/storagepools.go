@@ -0,0 +1,45 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"net/http"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/localstore"
+)
+
+// StoragePools reports the usage of the storage pools of the localstore.
+type StoragePools interface {
+	Pools() (localstore.Pools, error)
+}
+
+type storagePoolResponse struct {
+	Size     uint64 `json:"size"`
+	Capacity uint64 `json:"capacity"`
+}
+
+type storagePoolsResponse struct {
+	Cache          storagePoolResponse `json:"cache"`
+	Reserve        storagePoolResponse `json:"reserve"`
+	Pinned         storagePoolResponse `json:"pinned"`
+	EvictionPolicy string              `json:"evictionPolicy"`
+}
+
+func (s *Service) storagePoolsHandler(w http.ResponseWriter, r *http.Request) {
+	pools, err := s.storagePools.Pools()
+	if err != nil {
+		s.logger.Debugf("debug api: storage pools: %v", err)
+		s.logger.Error("debug api: storage pools")
+		jsonhttp.InternalServerError(w, "cannot get storage pools")
+		return
+	}
+	jsonhttp.OK(w, storagePoolsResponse{
+		Cache:          storagePoolResponse(pools.Cache),
+		Reserve:        storagePoolResponse(pools.Reserve),
+		Pinned:         storagePoolResponse(pools.Pinned),
+		EvictionPolicy: pools.EvictionPolicy.String(),
+	})
+}
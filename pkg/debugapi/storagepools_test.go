@@ -0,0 +1,46 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/debugapi"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/localstore"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/storage/mock"
+)
+
+// poolsStorer is a storer which reports the usage of its storage pools.
+type poolsStorer struct {
+	storage.Storer
+	pools func() (localstore.Pools, error)
+}
+
+func (s poolsStorer) Pools() (localstore.Pools, error) { return s.pools() }
+
+func TestStoragePools(t *testing.T) {
+	testServer := newTestServer(t, testServerOptions{
+		Storer: poolsStorer{Storer: mock.NewStorer(), pools: func() (localstore.Pools, error) {
+			return localstore.Pools{
+				Cache:          localstore.Pool{Size: 90, Capacity: 100},
+				Reserve:        localstore.Pool{Size: 20, Capacity: 50},
+				Pinned:         localstore.Pool{Size: 3},
+				EvictionPolicy: localstore.EvictionProximity,
+			}, nil
+		}},
+	})
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/storage/pools", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(debugapi.StoragePoolsResponse{
+			Cache:          debugapi.StoragePoolResponse{Size: 90, Capacity: 100},
+			Reserve:        debugapi.StoragePoolResponse{Size: 20, Capacity: 50},
+			Pinned:         debugapi.StoragePoolResponse{Size: 3},
+			EvictionPolicy: "proximity",
+		}),
+	)
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/puller"
+	"github.com/ethsana/sana/pkg/snapshot"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+// Snapshots import the snapshot of the neighborhood of the node and export
+// the snapshots of the neighborhoods.
+type Snapshots interface {
+	Progress() snapshot.Progress
+	Export(ctx context.Context, w io.Writer, overlay swarm.Address, depth uint8) (uint64, error)
+}
+
+// PullSyncStatus reports the status of the pull syncing.
+type PullSyncStatus interface {
+	Status() puller.Status
+}
+
+type pullSyncStatusResponse struct {
+	Started bool `json:"started"`
+	Peers   int  `json:"peers"`
+}
+
+type syncProgressResponse struct {
+	Snapshot *snapshot.Progress      `json:"snapshot,omitempty"`
+	PullSync *pullSyncStatusResponse `json:"pullSync,omitempty"`
+}
+
+func (s *Service) syncProgressHandler(w http.ResponseWriter, r *http.Request) {
+	var resp syncProgressResponse
+	if s.snapshots != nil {
+		p := s.snapshots.Progress()
+		resp.Snapshot = &p
+	}
+	if s.pullSync != nil {
+		st := s.pullSync.Status()
+		resp.PullSync = &pullSyncStatusResponse{
+			Started: st.Started,
+			Peers:   st.Peers,
+		}
+	}
+	jsonhttp.OK(w, resp)
+}
+
+// snapshotHandler writes the snapshot of the chunks within the depth of the
+// overlay address, the one of the node by default.
+func (s *Service) snapshotHandler(w http.ResponseWriter, r *http.Request) {
+	overlay := *s.overlay
+	if v := r.URL.Query().Get("overlay"); v != "" {
+		a, err := swarm.ParseHexAddress(v)
+		if err != nil {
+			s.logger.Debugf("debug api: snapshot: parse overlay %s: %v", v, err)
+			jsonhttp.BadRequest(w, "invalid overlay")
+			return
+		}
+		overlay = a
+	}
+	depth, err := strconv.ParseUint(r.URL.Query().Get("depth"), 10, 8)
+	if err != nil || depth > uint64(swarm.MaxPO) {
+		s.logger.Debugf("debug api: snapshot: parse depth: %v", err)
+		jsonhttp.BadRequest(w, "invalid depth")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="snapshot-`+overlay.String()+`-`+strconv.Itoa(int(depth))+`.gz"`)
+	sw := &startedWriter{w: w}
+	count, err := s.snapshots.Export(r.Context(), sw, overlay, uint8(depth))
+	if err != nil {
+		s.logger.Debugf("debug api: snapshot: %v", err)
+		if sw.started {
+			// the response is incomplete and fails the verification
+			s.logger.Error("debug api: snapshot: incomplete response")
+			return
+		}
+		w.Header().Del("Content-Type")
+		w.Header().Del("Content-Disposition")
+		if errors.Is(err, snapshot.ErrBusy) {
+			jsonhttp.ServiceUnavailable(w, "snapshot export in progress")
+			return
+		}
+		s.logger.Error("debug api: snapshot")
+		jsonhttp.InternalServerError(w, "cannot export snapshot")
+		return
+	}
+	s.logger.Debugf("debug api: snapshot: exported %d chunks of %s at depth %d", count, overlay, depth)
+}
+
+// startedWriter records whether the response was started.
+type startedWriter struct {
+	w       io.Writer
+	started bool
+}
+
+func (w *startedWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.w.Write(p)
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/debugapi"
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/puller"
+	"github.com/ethsana/sana/pkg/snapshot"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+type snapshotsMock struct {
+	progress snapshot.Progress
+	export   func(w io.Writer, overlay swarm.Address, depth uint8) (uint64, error)
+}
+
+func (m *snapshotsMock) Progress() snapshot.Progress { return m.progress }
+
+func (m *snapshotsMock) Export(_ context.Context, w io.Writer, overlay swarm.Address, depth uint8) (uint64, error) {
+	return m.export(w, overlay, depth)
+}
+
+type pullSyncStatusFunc func() puller.Status
+
+func (f pullSyncStatusFunc) Status() puller.Status { return f() }
+
+func TestSyncProgress(t *testing.T) {
+	progress := snapshot.Progress{
+		State:   snapshot.StateImporting,
+		Source:  "https://snapshots.example.com/snapshot.gz",
+		Chunks:  1000,
+		Bytes:   4000000,
+		Started: time.Unix(1634000000, 0).UTC(),
+	}
+	testServer := newTestServer(t, testServerOptions{
+		Snapshots: &snapshotsMock{progress: progress},
+		PullSync: pullSyncStatusFunc(func() puller.Status {
+			return puller.Status{Started: true, Peers: 3}
+		}),
+	})
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/sync/progress", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(debugapi.SyncProgressResponse{
+			Snapshot: &progress,
+			PullSync: &debugapi.PullSyncStatusResponse{Started: true, Peers: 3},
+		}),
+	)
+}
+
+func TestSnapshot(t *testing.T) {
+	overlay := swarm.MustParseHexAddress("ca1e000000000000000000000000000000000000000000000000000000000000")
+	other := swarm.MustParseHexAddress("be11000000000000000000000000000000000000000000000000000000000000")
+
+	var (
+		gotOverlay swarm.Address
+		gotDepth   uint8
+		exportErr  error
+	)
+	testServer := newTestServer(t, testServerOptions{
+		Overlay: overlay,
+		Snapshots: &snapshotsMock{export: func(w io.Writer, overlay swarm.Address, depth uint8) (uint64, error) {
+			if exportErr != nil {
+				return 0, exportErr
+			}
+			gotOverlay, gotDepth = overlay, depth
+			_, err := w.Write([]byte("snapshot"))
+			return 1, err
+		}},
+	})
+
+	t.Run("node overlay", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/snapshot?depth=3", http.StatusOK,
+			jsonhttptest.WithExpectedResponse([]byte("snapshot")),
+		)
+		if !gotOverlay.Equal(overlay) || gotDepth != 3 {
+			t.Errorf("got overlay %s depth %d, want %s depth 3", gotOverlay, gotDepth, overlay)
+		}
+	})
+
+	t.Run("other overlay", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/snapshot?depth=2&overlay="+other.String(), http.StatusOK,
+			jsonhttptest.WithExpectedResponse([]byte("snapshot")),
+		)
+		if !gotOverlay.Equal(other) || gotDepth != 2 {
+			t.Errorf("got overlay %s depth %d, want %s depth 2", gotOverlay, gotDepth, other)
+		}
+	})
+
+	t.Run("invalid depth", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/snapshot?depth=32", http.StatusBadRequest,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid depth",
+			}),
+		)
+	})
+
+	t.Run("busy", func(t *testing.T) {
+		exportErr = snapshot.ErrBusy
+		defer func() { exportErr = nil }()
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/snapshot?depth=1", http.StatusServiceUnavailable,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusServiceUnavailable,
+				Message: "snapshot export in progress",
+			}),
+		)
+	})
+}
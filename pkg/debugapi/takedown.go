@@ -0,0 +1,72 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/takedown"
+)
+
+type takedownsResponse struct {
+	Takedowns []takedown.Takedown `json:"takedowns"`
+}
+
+type takedownAuditResponse struct {
+	Entries []takedown.Entry `json:"entries"`
+}
+
+func (s *Service) takedownsHandler(w http.ResponseWriter, r *http.Request) {
+	takedowns, err := s.takedown.Takedowns()
+	if err != nil {
+		s.logger.Debugf("debug api: takedowns: %v", err)
+		s.logger.Error("debug api: takedowns")
+		jsonhttp.InternalServerError(w, "cannot get takedowns")
+		return
+	}
+	if takedowns == nil {
+		takedowns = make([]takedown.Takedown, 0)
+	}
+	jsonhttp.OK(w, takedownsResponse{Takedowns: takedowns})
+}
+
+// takedownAuditHandler returns the audit log of the notices, optionally
+// filtered by the reference and by the unix time since which the entries are
+// returned.
+func (s *Service) takedownAuditHandler(w http.ResponseWriter, r *http.Request) {
+	reference := swarm.ZeroAddress
+	if v := r.URL.Query().Get("reference"); v != "" {
+		var err error
+		if reference, err = swarm.ParseHexAddress(v); err != nil {
+			jsonhttp.BadRequest(w, "invalid reference")
+			return
+		}
+	}
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			jsonhttp.BadRequest(w, "invalid since")
+			return
+		}
+		since = time.Unix(sec, 0)
+	}
+
+	entries, err := s.takedown.Audit(reference, since)
+	if err != nil {
+		s.logger.Debugf("debug api: takedown audit: %v", err)
+		s.logger.Error("debug api: takedown audit")
+		jsonhttp.InternalServerError(w, "cannot get takedown audit")
+		return
+	}
+	if entries == nil {
+		entries = make([]takedown.Entry, 0)
+	}
+	jsonhttp.OK(w, takedownAuditResponse{Entries: entries})
+}
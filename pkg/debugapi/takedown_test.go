@@ -0,0 +1,98 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/debugapi"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethsana/sana/pkg/logging"
+	pinning "github.com/ethsana/sana/pkg/pinning/mock"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/swarm/test"
+	"github.com/ethsana/sana/pkg/takedown"
+)
+
+func TestTakedowns(t *testing.T) {
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.NewDefaultSigner(key)
+	owner, err := signer.EthereumAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := takedown.New(statestore.NewStateStore(), pinning.NewServiceMock(), logging.New(ioutil.Discard, 0), []common.Address{owner})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testServer := newTestServer(t, testServerOptions{
+		Takedown: s,
+	})
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/takedowns", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(debugapi.TakedownsResponse{
+			Takedowns: []takedown.Takedown{},
+		}),
+	)
+
+	ts := time.Now().Unix()
+	process := func(kind takedown.Kind, n takedown.Notice) {
+		t.Helper()
+		n.Kind = kind
+		sig, err := n.Sign(signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kind == takedown.KindNotice {
+			_, err = s.Notice(context.Background(), n, sig)
+		} else {
+			_, err = s.CounterNotice(context.Background(), n, sig)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	restored, denied := test.RandomAddress(), test.RandomAddress()
+	process(takedown.KindNotice, takedown.Notice{Reference: restored, Timestamp: ts})
+	process(takedown.KindCounterNotice, takedown.Notice{Reference: restored, Timestamp: ts + 1})
+	process(takedown.KindNotice, takedown.Notice{Reference: denied, Timestamp: ts})
+
+	var takedowns debugapi.TakedownsResponse
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/takedowns", http.StatusOK,
+		jsonhttptest.WithUnmarshalJSONResponse(&takedowns),
+	)
+	if len(takedowns.Takedowns) != 1 || !takedowns.Takedowns[0].Reference.Equal(denied) || takedowns.Takedowns[0].Signer != owner {
+		t.Fatalf("got takedowns %+v", takedowns.Takedowns)
+	}
+
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{"", 3},
+		{"?reference=" + restored.String(), 2},
+		{"?reference=" + denied.String(), 1},
+		{fmt.Sprintf("?since=%d", time.Now().Add(time.Hour).Unix()), 0},
+	} {
+		var audit debugapi.TakedownAuditResponse
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/takedowns/audit"+tc.query, http.StatusOK,
+			jsonhttptest.WithUnmarshalJSONResponse(&audit),
+		)
+		if len(audit.Entries) != tc.want {
+			t.Fatalf("%q: got %d entries, want %d", tc.query, len(audit.Entries), tc.want)
+		}
+	}
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/takedowns/audit?reference=invalid", http.StatusBadRequest)
+}
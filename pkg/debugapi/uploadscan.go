@@ -0,0 +1,32 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"net/http"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/ethsana/sana/pkg/uploadscan"
+)
+
+type uploadScanRejectionsResponse struct {
+	Rejections []uploadscan.Rejection `json:"rejections"`
+}
+
+func (s *Service) uploadScanRejectionsHandler(w http.ResponseWriter, r *http.Request) {
+	rejections, err := s.uploadScanAudit.Rejections()
+	if err != nil {
+		s.logger.Debugf("debug api: upload scan rejections: %v", err)
+		s.logger.Error("debug api: upload scan rejections")
+		jsonhttp.InternalServerError(w, "cannot get upload scan rejections")
+		return
+	}
+	if rejections == nil {
+		rejections = make([]uploadscan.Rejection, 0)
+	}
+	jsonhttp.OK(w, uploadScanRejectionsResponse{
+		Rejections: rejections,
+	})
+}
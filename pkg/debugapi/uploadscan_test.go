@@ -0,0 +1,41 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/debugapi"
+	"github.com/ethsana/sana/pkg/jsonhttp/jsonhttptest"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/uploadscan"
+)
+
+func TestUploadScanRejections(t *testing.T) {
+	audit := uploadscan.NewAudit(statestore.NewStateStore())
+	rejection := uploadscan.Rejection{
+		Time:       time.Unix(1620000000, 0).UTC(),
+		Path:       "/bytes",
+		RemoteAddr: "127.0.0.1:40000",
+		Size:       68,
+		SHA256:     "275a021bbfb6489e54d471899f7db9d1663fc695ec2fe2a2c4538aabf651fd0f",
+		Reason:     "Eicar-Test-Signature",
+	}
+	if err := audit.Record(rejection); err != nil {
+		t.Fatal(err)
+	}
+
+	testServer := newTestServer(t, testServerOptions{
+		UploadScanAudit: audit,
+	})
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/uploadscan/rejections", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(debugapi.UploadScanRejectionsResponse{
+			Rejections: []uploadscan.Rejection{rejection},
+		}),
+	)
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dedup provides a persistent index which maps hashes of uploaded
+// content to swarm references produced by previous uploads, so that
+// identical content does not need to be split and stamped again.
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+const keyPrefix = "dedup_"
+
+// ErrNotFound is returned when there is no reference for the key.
+var ErrNotFound = errors.New("dedup: not found")
+
+// Index maps upload keys to previously produced swarm references.
+type Index interface {
+	// Get returns the reference stored for the key.
+	Get(key []byte) (swarm.Address, error)
+	// Put stores the reference for the key.
+	Put(key []byte, ref swarm.Address) error
+	// Delete removes the reference for the key.
+	Delete(key []byte) error
+}
+
+type index struct {
+	store storage.StateStorer
+}
+
+// New creates a new Index persisted in the state store.
+func New(store storage.StateStorer) Index {
+	return &index{store: store}
+}
+
+// Key constructs an index key from the hash of the uploaded content and the
+// postage batch used to stamp it.
+func Key(contentHash, batch []byte) []byte {
+	h := sha256.New()
+	_, _ = h.Write(contentHash)
+	_, _ = h.Write(batch)
+	return h.Sum(nil)
+}
+
+func (i *index) Get(key []byte) (swarm.Address, error) {
+	var ref swarm.Address
+	if err := i.store.Get(storeKey(key), &ref); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return swarm.ZeroAddress, ErrNotFound
+		}
+		return swarm.ZeroAddress, err
+	}
+	return ref, nil
+}
+
+func (i *index) Put(key []byte, ref swarm.Address) error {
+	return i.store.Put(storeKey(key), ref)
+}
+
+func (i *index) Delete(key []byte) error {
+	return i.store.Delete(storeKey(key))
+}
+
+func storeKey(key []byte) string {
+	return keyPrefix + hex.EncodeToString(key)
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dedup_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/dedup"
+	"github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+func TestIndex(t *testing.T) {
+	idx := dedup.New(mock.NewStateStore())
+
+	key := dedup.Key([]byte("hash"), []byte("batch"))
+	ref := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+
+	if _, err := idx.Get(key); !errors.Is(err, dedup.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, dedup.ErrNotFound)
+	}
+
+	if err := idx.Put(key, ref); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := idx.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(ref) {
+		t.Fatalf("got reference %s, want %s", got, ref)
+	}
+
+	if err := idx.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idx.Get(key); !errors.Is(err, dedup.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, dedup.ErrNotFound)
+	}
+}
+
+func TestKey(t *testing.T) {
+	base := dedup.Key([]byte("hash"), []byte("batch"))
+
+	for _, tc := range []struct {
+		name string
+		key  []byte
+	}{
+		{"content", dedup.Key([]byte("other"), []byte("batch"))},
+		{"batch", dedup.Key([]byte("hash"), []byte("other"))},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if bytes.Equal(base, tc.key) {
+				t.Fatal("keys are equal")
+			}
+		})
+	}
+
+	if !bytes.Equal(base, dedup.Key([]byte("hash"), []byte("batch"))) {
+		t.Fatal("keys are not deterministic")
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dirsync incrementally publishes a local directory as a manifest.
+// Files are compared with the entries of the previously published manifest
+// and only the changed files are uploaded. The manifest reference is
+// published as an update of a sequence feed so that a single feed manifest
+// always resolves to the latest version of the directory.
+package dirsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/feeds"
+	"github.com/ethsana/sana/pkg/feeds/sequence"
+	"github.com/ethsana/sana/pkg/file/loadsave"
+	"github.com/ethsana/sana/pkg/file/pipeline/builder"
+	"github.com/ethsana/sana/pkg/manifest"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+// ErrInvalidFeedUpdate is returned when the latest feed update does not
+// hold a reference.
+var ErrInvalidFeedUpdate = errors.New("dirsync: invalid feed update")
+
+// Options configure Sync.
+type Options struct {
+	// Prune removes the manifest entries of files which no longer exist in
+	// the directory.
+	Prune bool
+	// IndexDocument and ErrorDocument set the website documents of the
+	// manifest when not empty.
+	IndexDocument string
+	ErrorDocument string
+}
+
+// Result describes the changes made by Sync.
+type Result struct {
+	// Reference of the new manifest, the same as the previous one when
+	// nothing changed.
+	Reference swarm.Address
+	Uploaded  []string
+	Removed   []string
+	Unchanged int
+}
+
+// Changed reports whether a new manifest was stored.
+func (r Result) Changed() bool {
+	return len(r.Uploaded) > 0 || len(r.Removed) > 0
+}
+
+// Sync uploads the files in the directory which differ from the entries of
+// the previous manifest and stores the updated manifest. If previous is the
+// zero address, a new manifest is created.
+func Sync(ctx context.Context, storer loadsave.PutGetter, dir string, previous swarm.Address, o Options) (Result, error) {
+	ls := loadsave.New(storer, storage.ModePutUpload, false)
+
+	var (
+		m   manifest.Interface
+		err error
+	)
+	if previous.IsZero() {
+		m, err = manifest.NewDefaultManifest(ls, false)
+	} else {
+		m, err = manifest.NewDefaultManifestReference(previous, ls)
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("load manifest: %w", err)
+	}
+
+	remote := make(map[string]swarm.Address)
+	if !previous.IsZero() {
+		err = m.IterateEntries(ctx, func(p string, entry manifest.Entry) error {
+			remote[p] = entry.Reference()
+			return nil
+		})
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	result := Result{Reference: previous}
+	local := make(map[string]struct{})
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		p := filepath.ToSlash(rel)
+		local[p] = struct{}{}
+
+		if reference, ok := remote[p]; ok {
+			sum, err := fileReference(ctx, discardPutter{}, path)
+			if err != nil {
+				return err
+			}
+			if sum.Equal(reference) {
+				result.Unchanged++
+				return nil
+			}
+		}
+
+		reference, err := fileReference(ctx, storer, path)
+		if err != nil {
+			return fmt.Errorf("upload %s: %w", p, err)
+		}
+		metadata := map[string]string{
+			manifest.EntryMetadataFilenameKey: info.Name(),
+		}
+		if ct := mime.TypeByExtension(filepath.Ext(path)); ct != "" {
+			metadata[manifest.EntryMetadataContentTypeKey] = ct
+		}
+		if err := m.Add(ctx, p, manifest.NewEntry(reference, metadata)); err != nil {
+			return fmt.Errorf("add %s: %w", p, err)
+		}
+		result.Uploaded = append(result.Uploaded, p)
+		return nil
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	if o.Prune {
+		for p := range remote {
+			if _, ok := local[p]; ok {
+				continue
+			}
+			if err := m.Remove(ctx, p); err != nil {
+				return Result{}, fmt.Errorf("remove %s: %w", p, err)
+			}
+			result.Removed = append(result.Removed, p)
+		}
+	}
+
+	updated, err := updateRootMetadata(ctx, m, o)
+	if err != nil {
+		return Result{}, err
+	}
+	if !result.Changed() && !updated {
+		return result, nil
+	}
+
+	result.Reference, err = m.Store(ctx)
+	if err != nil {
+		return Result{}, fmt.Errorf("store manifest: %w", err)
+	}
+	return result, nil
+}
+
+// updateRootMetadata sets the website documents of the manifest and reports
+// whether they were changed.
+func updateRootMetadata(ctx context.Context, m manifest.Interface, o Options) (bool, error) {
+	if o.IndexDocument == "" && o.ErrorDocument == "" {
+		return false, nil
+	}
+
+	metadata := make(map[string]string)
+	entry, err := m.Lookup(ctx, manifest.RootPath)
+	switch {
+	case err == nil:
+		for k, v := range entry.Metadata() {
+			metadata[k] = v
+		}
+	case errors.Is(err, manifest.ErrNotFound):
+	default:
+		return false, fmt.Errorf("lookup root metadata: %w", err)
+	}
+
+	updated := false
+	set := func(key, value string) {
+		if value != "" && metadata[key] != value {
+			metadata[key] = value
+			updated = true
+		}
+	}
+	set(manifest.WebsiteIndexDocumentSuffixKey, o.IndexDocument)
+	set(manifest.WebsiteErrorDocumentPathKey, o.ErrorDocument)
+	if !updated {
+		return false, nil
+	}
+
+	if err := m.Add(ctx, manifest.RootPath, manifest.NewEntry(swarm.ZeroAddress, metadata)); err != nil {
+		return false, fmt.Errorf("add root metadata: %w", err)
+	}
+	return true, nil
+}
+
+// fileReference splits the file into chunks which are put to the putter and
+// returns the file reference.
+func fileReference(ctx context.Context, putter storage.Putter, path string) (swarm.Address, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return swarm.ZeroAddress, err
+	}
+	defer f.Close()
+
+	pipe := builder.NewPipelineBuilder(ctx, putter, storage.ModePutUpload, false)
+	return builder.FeedPipeline(ctx, pipe, f)
+}
+
+// discardPutter is used to compute file references without storing chunks.
+type discardPutter struct{}
+
+func (discardPutter) Put(_ context.Context, _ storage.ModePut, chs ...swarm.Chunk) ([]bool, error) {
+	return make([]bool, len(chs)), nil
+}
+
+// Latest returns the reference published by the latest update of the
+// sequence feed and the index of the next update. The zero address is
+// returned if the feed has no updates.
+func Latest(ctx context.Context, getter storage.Getter, feed *feeds.Feed) (swarm.Address, feeds.Index, error) {
+	ch, _, next, err := sequence.NewFinder(getter, feed).At(ctx, time.Now().Unix(), 0)
+	if err != nil {
+		return swarm.ZeroAddress, nil, fmt.Errorf("lookup feed: %w", err)
+	}
+	if ch == nil {
+		return swarm.ZeroAddress, next, nil
+	}
+	_, payload, err := feeds.FromChunk(ch)
+	if err != nil {
+		return swarm.ZeroAddress, nil, err
+	}
+	if len(payload) != swarm.HashSize && len(payload) != swarm.HashSize*2 {
+		return swarm.ZeroAddress, nil, ErrInvalidFeedUpdate
+	}
+	return swarm.NewAddress(payload), next, nil
+}
+
+// Publish stores the reference as the feed update at the index.
+func Publish(ctx context.Context, putter storage.Putter, signer crypto.Signer, topic []byte, index feeds.Index, reference swarm.Address) error {
+	p, err := feeds.NewPutter(putter, signer, topic)
+	if err != nil {
+		return err
+	}
+	if err := p.Put(ctx, index, time.Now().Unix(), reference.Bytes()); err != nil {
+		return fmt.Errorf("put feed update: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dirsync_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/dirsync"
+	"github.com/ethsana/sana/pkg/feeds"
+	"github.com/ethsana/sana/pkg/file/loadsave"
+	"github.com/ethsana/sana/pkg/manifest"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	storer := mock.NewStorer()
+	dir := t.TempDir()
+
+	writeFile(t, dir, "index.html", "<html></html>")
+	writeFile(t, dir, "css/style.css", "body {}")
+	writeFile(t, dir, "old.txt", "old")
+
+	first, err := dirsync.Sync(ctx, storer, dir, swarm.ZeroAddress, dirsync.Options{IndexDocument: "index.html"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectPaths(t, first.Uploaded, "css/style.css", "index.html", "old.txt")
+
+	t.Run("unchanged", func(t *testing.T) {
+		r, err := dirsync.Sync(ctx, storer, dir, first.Reference, dirsync.Options{IndexDocument: "index.html"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Changed() || r.Unchanged != 3 {
+			t.Fatalf("unexpected changes %+v", r)
+		}
+		if !r.Reference.Equal(first.Reference) {
+			t.Fatal("unchanged directory stored a new manifest")
+		}
+	})
+
+	writeFile(t, dir, "css/style.css", "body { color: red; }")
+	if err := os.Remove(filepath.Join(dir, "old.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("keep removed", func(t *testing.T) {
+		r, err := dirsync.Sync(ctx, storer, dir, first.Reference, dirsync.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectPaths(t, r.Uploaded, "css/style.css")
+		expectPaths(t, r.Removed)
+		expectEntries(t, storer, r.Reference, "css/style.css", "index.html", "old.txt")
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		r, err := dirsync.Sync(ctx, storer, dir, first.Reference, dirsync.Options{Prune: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectPaths(t, r.Uploaded, "css/style.css")
+		expectPaths(t, r.Removed, "old.txt")
+		m := expectEntries(t, storer, r.Reference, "css/style.css", "index.html")
+
+		root, err := m.Lookup(ctx, manifest.RootPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc := root.Metadata()[manifest.WebsiteIndexDocumentSuffixKey]; doc != "index.html" {
+			t.Fatalf("got index document %q", doc)
+		}
+	})
+}
+
+func TestFeed(t *testing.T) {
+	ctx := context.Background()
+	storer := mock.NewStorer()
+
+	pk, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.NewDefaultSigner(pk)
+	owner, err := signer.EthereumAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic := make([]byte, 32)
+	feed := feeds.New(topic, owner)
+
+	ref, next, err := dirsync.Latest(ctx, storer, feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ref.IsZero() {
+		t.Fatalf("got reference %s for an empty feed", ref)
+	}
+
+	for _, want := range []swarm.Address{
+		swarm.MustParseHexAddress("aabbccddeeff00112233445566778899aabbccddeeff00112233445566778899"),
+		swarm.MustParseHexAddress("00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff"),
+	} {
+		if err := dirsync.Publish(ctx, storer, signer, topic, next, want); err != nil {
+			t.Fatal(err)
+		}
+		ref, next, err = dirsync.Latest(ctx, storer, feed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ref.Equal(want) {
+			t.Fatalf("got reference %s, want %s", ref, want)
+		}
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectPaths(t *testing.T, got []string, want ...string) {
+	t.Helper()
+
+	sort.Strings(got)
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got paths %v, want %v", got, want)
+	}
+}
+
+func expectEntries(t *testing.T, storer storage.Storer, reference swarm.Address, want ...string) manifest.Interface {
+	t.Helper()
+
+	m, err := manifest.NewDefaultManifestReference(reference, loadsave.New(storer, storage.ModePutUpload, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	err = m.IterateEntries(context.Background(), func(p string, _ manifest.Entry) error {
+		got = append(got, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectPaths(t, got, want...)
+	return m
+}
@@ -0,0 +1,193 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package events distributes the structured events of the node lifecycle and
+// the chain to the subscribers, such as the event stream of the debug api.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type is the type of an event.
+type Type string
+
+const (
+	// PeerConnected is published when a peer is connected. The data is a
+	// PeerData.
+	PeerConnected Type = "peer.connected"
+	// PeerDisconnected is published when a peer is disconnected. The data is
+	// a PeerData.
+	PeerDisconnected Type = "peer.disconnected"
+	// ChequebookDeployed is published when the chequebook of the node is
+	// deployed. The data is a ChequebookData.
+	ChequebookDeployed Type = "chequebook.deployed"
+	// BatchTopUp is published when a postage batch of the node is topped up.
+	// The data is a BatchData.
+	BatchTopUp Type = "postage.batch.topup"
+	// MineReward is published when the mining reward of the node increases.
+	// The data is a RewardData.
+	MineReward Type = "mine.reward"
+	// SyncContracts is published when the contract data is synced with the
+	// chain. The data is a ChainSyncData.
+	SyncContracts Type = "sync.contracts"
+	// SyncDepth is published when the neighbourhood depth of the node
+	// changes. The data is a DepthData.
+	SyncDepth Type = "sync.depth"
+	// Shutdown is published on the progress of the node shutdown. The data is
+	// a ShutdownData.
+	Shutdown Type = "shutdown"
+)
+
+// Event is a structured event.
+type Event struct {
+	Type Type        `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// PeerData is the data of the peer events.
+type PeerData struct {
+	Address string `json:"address"`
+	// FullNode is set on PeerConnected only.
+	FullNode bool `json:"fullNode,omitempty"`
+}
+
+// ChequebookData is the data of the ChequebookDeployed event.
+type ChequebookData struct {
+	Address         string `json:"address"`
+	TransactionHash string `json:"transactionHash"`
+}
+
+// BatchData is the data of the BatchTopUp event.
+type BatchData struct {
+	BatchID         string `json:"batchID"`
+	Value           string `json:"value"`
+	TransactionHash string `json:"transactionHash"`
+}
+
+// RewardData is the data of the MineReward event.
+type RewardData struct {
+	Reward   string `json:"reward"`
+	Increase string `json:"increase"`
+}
+
+// ChainSyncData is the data of the SyncContracts event.
+type ChainSyncData struct {
+	Duration string `json:"duration"`
+}
+
+// DepthData is the data of the SyncDepth event.
+type DepthData struct {
+	Depth uint8 `json:"depth"`
+}
+
+// ShutdownData is the data of the Shutdown event.
+type ShutdownData struct {
+	Phase           string `json:"phase"`
+	PendingChunks   int    `json:"pendingChunks"`
+	PendingPayments int    `json:"pendingPayments"`
+	Error           string `json:"error,omitempty"`
+}
+
+// The phases of the Shutdown event.
+const (
+	ShutdownStarted  = "started"
+	ShutdownDraining = "draining"
+	ShutdownDrained  = "drained"
+)
+
+// Publisher publishes events.
+type Publisher interface {
+	Publish(t Type, data interface{})
+}
+
+// subscriberBuffer is the number of events buffered for every subscriber.
+// Events are dropped for the subscribers which fall behind.
+const subscriberBuffer = 64
+
+// Bus is the Publisher which delivers the events to its subscribers.
+type Bus struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+	dropped     uint64
+	closed      bool
+}
+
+var _ Publisher = (*Bus)(nil)
+
+// New returns a new Bus.
+func New() *Bus {
+	return &Bus{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish delivers the event to all subscribers without blocking. It is a
+// no-op on the nil Bus.
+func (b *Bus) Publish(t Type, data interface{}) {
+	if b == nil {
+		return
+	}
+	e := Event{
+		Type: t,
+		Time: time.Now().UTC(),
+		Data: data,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.subscribers {
+		select {
+		case c <- e:
+		default:
+			b.dropped++
+		}
+	}
+}
+
+// Subscribe returns the channel of the events published until unsubscribe is
+// called. The channel is closed on unsubscribe or when the Bus is closed.
+func (b *Bus) Subscribe() (c <-chan Event, unsubscribe func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	b.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close closes the channels of all subscribers. Events published after Close
+// are dropped.
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+	return nil
+}
+
+// Dropped returns the number of events which were not delivered because the
+// subscribers fell behind.
+func (b *Bus) Dropped() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package events_test
+
+import (
+	"testing"
+
+	"github.com/ethsana/sana/pkg/events"
+)
+
+func TestBus(t *testing.T) {
+	b := events.New()
+
+	c1, unsubscribe1 := b.Subscribe()
+	c2, unsubscribe2 := b.Subscribe()
+	defer unsubscribe2()
+
+	data := events.PeerData{Address: "abcd"}
+	b.Publish(events.PeerConnected, data)
+
+	for _, c := range []<-chan events.Event{c1, c2} {
+		e := <-c
+		if e.Type != events.PeerConnected {
+			t.Fatalf("got event type %q, want %q", e.Type, events.PeerConnected)
+		}
+		if e.Data != data {
+			t.Fatalf("got event data %v, want %v", e.Data, data)
+		}
+		if e.Time.IsZero() {
+			t.Fatal("event time not set")
+		}
+	}
+
+	unsubscribe1()
+	// unsubscribing twice is safe
+	unsubscribe1()
+	if _, ok := <-c1; ok {
+		t.Fatal("channel not closed after unsubscribe")
+	}
+
+	b.Publish(events.PeerDisconnected, data)
+	if e := <-c2; e.Type != events.PeerDisconnected {
+		t.Fatalf("got event type %q, want %q", e.Type, events.PeerDisconnected)
+	}
+}
+
+func TestBusDropsEventsOfSlowSubscribers(t *testing.T) {
+	b := events.New()
+	c, unsubscribe := b.Subscribe()
+	defer unsubscribe()
+
+	const published = 100
+	for i := 0; i < published; i++ {
+		b.Publish(events.SyncDepth, events.DepthData{Depth: uint8(i)})
+	}
+
+	received := len(c)
+	if received == 0 || received == published {
+		t.Fatalf("got %d buffered events", received)
+	}
+	if got := b.Dropped(); got != uint64(published-received) {
+		t.Fatalf("got %d dropped events, want %d", got, published-received)
+	}
+	if e := <-c; e.Data != (events.DepthData{Depth: 0}) {
+		t.Fatalf("got first event data %v", e.Data)
+	}
+}
+
+func TestBusClose(t *testing.T) {
+	b := events.New()
+	c, unsubscribe := b.Subscribe()
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("channel not closed on close")
+	}
+	unsubscribe()
+
+	b.Publish(events.Shutdown, nil)
+	c, _ = b.Subscribe()
+	if _, ok := <-c; ok {
+		t.Fatal("channel of a closed bus not closed")
+	}
+}
+
+func TestNilBus(t *testing.T) {
+	var b *events.Bus
+	b.Publish(events.Shutdown, nil)
+}
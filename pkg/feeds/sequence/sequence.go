@@ -40,6 +40,11 @@ type index struct {
 	index uint64
 }
 
+// NewIndex returns the index of the update with the sequence number.
+func NewIndex(i uint64) feeds.Index {
+	return &index{i}
+}
+
 func (i *index) String() string {
 	return fmt.Sprintf("%d", i.index)
 }
@@ -38,6 +38,14 @@ func newPipeline(ctx context.Context, s storage.Putter, mode storage.ModePut) pi
 	return feeder.NewChunkFeederWriter(swarm.ChunkSize, b)
 }
 
+// NewTrieWriter returns the writer of the intermediate chunks of the tree over
+// the references and the spans of the data chunks, which have to be stored
+// already. The data chunks have to be written in order and all of them except
+// the last one have to be full.
+func NewTrieWriter(ctx context.Context, s storage.Putter, mode storage.ModePut) pipeline.ChainWriter {
+	return hashtrie.NewHashTrieWriter(swarm.ChunkSize, swarm.Branches, swarm.HashSize, newShortPipelineFunc(ctx, s, mode))
+}
+
 // newShortPipelineFunc returns a constructor function for an ephemeral hashing pipeline
 // needed by the hashTrieWriter.
 func newShortPipelineFunc(ctx context.Context, s storage.Putter, mode storage.ModePut) func() pipeline.ChainWriter {
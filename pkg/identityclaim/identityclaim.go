@@ -0,0 +1,163 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package identityclaim creates the identity claims of the node. A claim
+// binds the TEE device ID of the node to its overlay address with the
+// signature of the node key, so that the verifiers can check the signature
+// with Verify.
+//
+// A claim is not a remote attestation. The device IDs are public, as the
+// miners send them to their peers, and any key can sign them, so a claim does
+// not prove that the node runs in an enclave.
+package identityclaim
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+// MaxNonceSize is the maximum size of the nonces of the claims.
+const MaxNonceSize = 32
+
+var (
+	// ErrUnavailable is returned if the TEE device of the node is not found.
+	ErrUnavailable = errors.New("identity claim: tee device unavailable")
+	// ErrNonceTooLarge is returned for the nonces larger than MaxNonceSize.
+	ErrNonceTooLarge = errors.New("identity claim: nonce too large")
+	// ErrInvalidSignature is returned if the signature of a claim is not
+	// the one of its signer.
+	ErrInvalidSignature = errors.New("identity claim: invalid signature")
+)
+
+// Device is the TEE device of the node.
+type Device struct {
+	Platform string
+	// ID is the encoding of the device in the trust signature requests of
+	// the miners.
+	ID []byte
+}
+
+// DeviceFunc returns the TEE device of the node.
+type DeviceFunc func() (Device, error)
+
+// Claim is the identity claim of the node. The device, the nonce and the
+// signature are hex encoded.
+type Claim struct {
+	Overlay   swarm.Address  `json:"overlay"`
+	Signer    common.Address `json:"signer"`
+	Platform  string         `json:"platform"`
+	Device    string         `json:"device"`
+	Nonce     string         `json:"nonce"`
+	Timestamp int64          `json:"timestamp"`
+	Signature string         `json:"signature"`
+}
+
+// Data returns the data which is signed by the node.
+func (c *Claim) Data() ([]byte, error) {
+	device, err := hex.DecodeString(c.Device)
+	if err != nil {
+		return nil, fmt.Errorf("identity claim: device: %w", err)
+	}
+	nonce, err := hex.DecodeString(c.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("identity claim: nonce: %w", err)
+	}
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(c.Timestamp))
+	h, err := crypto.LegacyKeccak256(append(append([]byte(c.Platform), 0), device...))
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, 0, swarm.HashSize+len(h)+len(nonce)+len(ts))
+	data = append(data, c.Overlay.Bytes()...)
+	data = append(data, h...)
+	data = append(data, ts[:]...)
+	return append(data, nonce...), nil
+}
+
+// Verify checks that the claim is signed by its signer.
+func Verify(c *Claim) error {
+	data, err := c.Data()
+	if err != nil {
+		return err
+	}
+	signature, err := hex.DecodeString(c.Signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	pub, err := crypto.Recover(signature, data)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	a, err := crypto.NewEthereumAddress(*pub)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if common.BytesToAddress(a) != c.Signer {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Service creates the claims of the node.
+type Service struct {
+	overlay swarm.Address
+	signer  crypto.Signer
+	address common.Address
+	device  DeviceFunc
+	now     func() time.Time
+}
+
+// New returns a new Service which claims the device with the node key.
+func New(overlay swarm.Address, signer crypto.Signer, device DeviceFunc) (*Service, error) {
+	address, err := signer.EthereumAddress()
+	if err != nil {
+		return nil, fmt.Errorf("identity claim: %w", err)
+	}
+	return &Service{
+		overlay: overlay,
+		signer:  signer,
+		address: address,
+		device:  device,
+		now:     time.Now,
+	}, nil
+}
+
+// Claim returns the claim of the node with the nonce of the verifier, which
+// proves that the claim is not replayed.
+func (s *Service) Claim(nonce []byte) (*Claim, error) {
+	if len(nonce) > MaxNonceSize {
+		return nil, ErrNonceTooLarge
+	}
+	device, err := s.device()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	c := &Claim{
+		Overlay:   s.overlay,
+		Signer:    s.address,
+		Platform:  device.Platform,
+		Device:    hex.EncodeToString(device.ID),
+		Nonce:     hex.EncodeToString(nonce),
+		Timestamp: s.now().Unix(),
+	}
+	data, err := c.Data()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := s.signer.Sign(data)
+	if err != nil {
+		return nil, fmt.Errorf("identity claim: sign: %w", err)
+	}
+	c.Signature = hex.EncodeToString(signature)
+	return c, nil
+}
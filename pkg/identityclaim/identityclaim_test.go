@@ -0,0 +1,70 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package identityclaim_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/identityclaim"
+	"github.com/ethsana/sana/pkg/swarm/test"
+)
+
+func newService(t *testing.T, device identityclaim.DeviceFunc) *identityclaim.Service {
+	t.Helper()
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := identityclaim.New(test.RandomAddress(), crypto.NewDefaultSigner(key), device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestClaim(t *testing.T) {
+	s := newService(t, func() (identityclaim.Device, error) {
+		return identityclaim.Device{Platform: "amd", ID: []byte{0, 0, 0, 0, 1, 2, 3}}, nil
+	})
+
+	c, err := s.Claim([]byte("nonce"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Platform != "amd" || c.Device != "00000000010203" || c.Nonce != "6e6f6e6365" {
+		t.Fatalf("got claim %+v", c)
+	}
+	if err := identityclaim.Verify(c); err != nil {
+		t.Fatal(err)
+	}
+
+	// the signature does not cover a modified claim
+	tampered := *c
+	tampered.Nonce = "00"
+	if err := identityclaim.Verify(&tampered); !errors.Is(err, identityclaim.ErrInvalidSignature) {
+		t.Fatalf("got error %v, want %v", err, identityclaim.ErrInvalidSignature)
+	}
+	tampered = *c
+	tampered.Signer[0]++
+	if err := identityclaim.Verify(&tampered); !errors.Is(err, identityclaim.ErrInvalidSignature) {
+		t.Fatalf("got error %v, want %v", err, identityclaim.ErrInvalidSignature)
+	}
+
+	if _, err := s.Claim(bytes.Repeat([]byte{1}, identityclaim.MaxNonceSize+1)); !errors.Is(err, identityclaim.ErrNonceTooLarge) {
+		t.Fatalf("got error %v, want %v", err, identityclaim.ErrNonceTooLarge)
+	}
+}
+
+func TestClaimUnavailable(t *testing.T) {
+	s := newService(t, func() (identityclaim.Device, error) {
+		return identityclaim.Device{}, errors.New("no device")
+	})
+	if _, err := s.Claim(nil); !errors.Is(err, identityclaim.ErrUnavailable) {
+		t.Fatalf("got error %v, want %v", err, identityclaim.ErrUnavailable)
+	}
+}
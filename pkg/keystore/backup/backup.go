@@ -0,0 +1,241 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package backup creates password encrypted backups of the node identity and
+// restores them on another machine.
+//
+// The identity consists of the sana, libp2p and pss keys, the configuration of
+// the clef signer if the sana key is managed by clef, and the hash of the
+// chequebook deployment transaction. The overlay address is derived from the
+// sana key and the block following that transaction, so restoring both
+// preserves the overlay address.
+package backup
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/keystore"
+	filekeystore "github.com/ethsana/sana/pkg/keystore/file"
+	"github.com/ethsana/sana/pkg/settlement/swap/chequebook"
+	"github.com/ethsana/sana/pkg/storage"
+)
+
+// Version is the version of the backup format.
+const Version = 1
+
+// KeyNames are the names of the node keys in the keystore.
+var KeyNames = []string{"sana", "libp2p", "pss"}
+
+var (
+	// ErrUnsupportedVersion is returned if the backup is of an unknown
+	// version.
+	ErrUnsupportedVersion = errors.New("unsupported backup version")
+	// ErrConflict is returned if a restored value differs from the one of
+	// the target node.
+	ErrConflict = errors.New("backup conflicts with the node state")
+)
+
+// Clef is the configuration of the clef signer which manages the sana key.
+type Clef struct {
+	Endpoint        string `json:"endpoint,omitempty"`
+	EthereumAddress string `json:"ethereumAddress,omitempty"`
+}
+
+// Backup is the node identity.
+type Backup struct {
+	CreatedAt time.Time `json:"createdAt"`
+	// Keys are the hex encoded private keys by their names.
+	Keys map[string]string `json:"keys"`
+	Clef *Clef             `json:"clef,omitempty"`
+	// ChequebookDeployment is the hash of the chequebook deployment
+	// transaction, if the chequebook is deployed.
+	ChequebookDeployment *common.Hash `json:"chequebookDeployment,omitempty"`
+}
+
+// archive is the encoding of the encrypted backups.
+type archive struct {
+	Version int             `json:"version"`
+	Crypto  json.RawMessage `json:"crypto"`
+}
+
+// Create returns the backup of the node keys which exist in the keystore,
+// decrypted with the password, and of the chequebook deployment transaction
+// in the state store. The clef configuration is optional.
+func Create(ks keystore.Service, password string, store storage.StateStorer, clef *Clef) (*Backup, error) {
+	b := &Backup{
+		CreatedAt: time.Now().UTC(),
+		Keys:      make(map[string]string),
+		Clef:      clef,
+	}
+	for _, name := range KeyNames {
+		exists, err := ks.Exists(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s key: %w", name, err)
+		}
+		if !exists {
+			continue
+		}
+		k, _, err := ks.Key(name, password)
+		if err != nil {
+			return nil, fmt.Errorf("%s key: %w", name, err)
+		}
+		b.Keys[name] = hex.EncodeToString(crypto.EncodeSecp256k1PrivateKey(k))
+	}
+	if len(b.Keys) == 0 {
+		return nil, errors.New("no keys found")
+	}
+
+	var txHash common.Hash
+	switch err := store.Get(chequebook.ChequebookDeploymentKey, &txHash); {
+	case err == nil:
+		b.ChequebookDeployment = &txHash
+	case !errors.Is(err, storage.ErrNotFound):
+		return nil, fmt.Errorf("chequebook deployment: %w", err)
+	}
+	return b, nil
+}
+
+// Encrypt returns the backup encrypted with the password.
+func (b *Backup) Encrypt(password string) ([]byte, error) {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	c, err := filekeystore.EncryptData(data, password)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(archive{Version: Version, Crypto: c}, "", "  ")
+}
+
+// Decrypt returns the backup encrypted with the password. It returns
+// keystore.ErrInvalidPassword if the password is not valid.
+func Decrypt(data []byte, password string) (*Backup, error) {
+	var a archive
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("decode archive: %w", err)
+	}
+	if a.Version != Version {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, a.Version)
+	}
+	d, err := filekeystore.DecryptData(a.Crypto, password)
+	if err != nil {
+		return nil, err
+	}
+	var b Backup
+	if err := json.Unmarshal(d, &b); err != nil {
+		return nil, fmt.Errorf("decode backup: %w", err)
+	}
+	return &b, nil
+}
+
+// Restore imports the keys into the keystore, encrypted with the password, and
+// stores the chequebook deployment transaction in the state store. Keys and
+// values which already exist are kept if they are equal to the restored ones.
+// Otherwise ErrConflict is returned before anything is restored, so that no
+// identity is overwritten or mixed with another one.
+func (b *Backup) Restore(ks keystore.Service, password string, store storage.StateStorer) error {
+	imp, ok := ks.(keystore.Importer)
+	if !ok {
+		return errors.New("keystore does not support importing keys")
+	}
+
+	keys := make(map[string]*ecdsa.PrivateKey)
+	for _, name := range KeyNames {
+		h, ok := b.Keys[name]
+		if !ok {
+			continue
+		}
+		d, err := hex.DecodeString(h)
+		if err != nil {
+			return fmt.Errorf("%s key: %w", name, err)
+		}
+		k, err := crypto.DecodeSecp256k1PrivateKey(d)
+		if err != nil {
+			return fmt.Errorf("%s key: %w", name, err)
+		}
+
+		exists, err := ks.Exists(name)
+		if err != nil {
+			return fmt.Errorf("%s key: %w", name, err)
+		}
+		if !exists {
+			keys[name] = k
+			continue
+		}
+		existing, _, err := ks.Key(name, password)
+		if err != nil {
+			return fmt.Errorf("%s key: %w", name, err)
+		}
+		if !bytes.Equal(crypto.EncodeSecp256k1PrivateKey(existing), d) {
+			return fmt.Errorf("%w: different %s key exists", ErrConflict, name)
+		}
+	}
+
+	storeDeployment := false
+	if b.ChequebookDeployment != nil {
+		var txHash common.Hash
+		switch err := store.Get(chequebook.ChequebookDeploymentKey, &txHash); {
+		case err == nil:
+			if txHash != *b.ChequebookDeployment {
+				return fmt.Errorf("%w: different chequebook deployment transaction %x exists", ErrConflict, txHash)
+			}
+		case errors.Is(err, storage.ErrNotFound):
+			storeDeployment = true
+		default:
+			return fmt.Errorf("chequebook deployment: %w", err)
+		}
+	}
+
+	for _, name := range KeyNames {
+		k, ok := keys[name]
+		if !ok {
+			continue
+		}
+		if err := imp.Import(name, password, k); err != nil {
+			return fmt.Errorf("%s key: %w", name, err)
+		}
+	}
+	if storeDeployment {
+		if err := store.Put(chequebook.ChequebookDeploymentKey, *b.ChequebookDeployment); err != nil {
+			return fmt.Errorf("chequebook deployment: %w", err)
+		}
+	}
+	return nil
+}
+
+// Exporter creates encrypted backups of the identity of a running node.
+type Exporter struct {
+	keystore keystore.Service
+	store    storage.StateStorer
+	clef     *Clef
+}
+
+// NewExporter returns a new Exporter of the keys in the keystore.
+func NewExporter(ks keystore.Service, store storage.StateStorer, clef *Clef) *Exporter {
+	return &Exporter{
+		keystore: ks,
+		store:    store,
+		clef:     clef,
+	}
+}
+
+// Export returns the backup of the keys, decrypted with the password of the
+// keystore, encrypted with the password. It returns
+// keystore.ErrInvalidPassword if the keystore password is not valid.
+func (e *Exporter) Export(keystorePassword, password string) ([]byte, error) {
+	b, err := Create(e.keystore, keystorePassword, e.store, e.clef)
+	if err != nil {
+		return nil, err
+	}
+	return b.Encrypt(password)
+}
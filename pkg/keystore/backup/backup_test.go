@@ -0,0 +1,97 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package backup_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/keystore"
+	"github.com/ethsana/sana/pkg/keystore/backup"
+	"github.com/ethsana/sana/pkg/keystore/mem"
+	"github.com/ethsana/sana/pkg/settlement/swap/chequebook"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+)
+
+func TestBackup(t *testing.T) {
+	const password = "keystore pass"
+
+	ks := mem.New()
+	for _, name := range backup.KeyNames {
+		if _, _, err := ks.Key(name, password); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := statestore.NewStateStore()
+	txHash := common.HexToHash("0x1234")
+	if err := store.Put(chequebook.ChequebookDeploymentKey, txHash); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := backup.NewExporter(ks, store, &backup.Clef{Endpoint: "http://clef:8550"}).Export(password, "backup pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := backup.Decrypt(data, "invalid"); !errors.Is(err, keystore.ErrInvalidPassword) {
+		t.Fatalf("got error %v, want %v", err, keystore.ErrInvalidPassword)
+	}
+	b, err := backup.Decrypt(data, "backup pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Clef == nil || b.Clef.Endpoint != "http://clef:8550" {
+		t.Fatalf("got clef configuration %+v", b.Clef)
+	}
+
+	t.Run("restore", func(t *testing.T) {
+		restored := mem.New()
+		restoredStore := statestore.NewStateStore()
+		if err := b.Restore(restored, "new pass", restoredStore); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range backup.KeyNames {
+			want, _, err := ks.Key(name, password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, created, err := restored.Key(name, "new pass")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created || !bytes.Equal(got.D.Bytes(), want.D.Bytes()) {
+				t.Fatalf("%s key is not restored", name)
+			}
+		}
+		var got common.Hash
+		if err := restoredStore.Get(chequebook.ChequebookDeploymentKey, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != txHash {
+			t.Fatalf("got chequebook deployment %x, want %x", got, txHash)
+		}
+
+		// restoring again is a no-op
+		if err := b.Restore(restored, "new pass", restoredStore); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		other := mem.New()
+		if _, _, err := other.Key("pss", password); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Restore(other, password, statestore.NewStateStore()); !errors.Is(err, backup.ErrConflict) {
+			t.Fatalf("got error %v, want %v", err, backup.ErrConflict)
+		}
+		// nothing is restored
+		if exists, err := other.Exists("sana"); err != nil || exists {
+			t.Fatalf("sana key exists %v, error %v", exists, err)
+		}
+	})
+}
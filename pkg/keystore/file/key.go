@@ -21,7 +21,10 @@ import (
 	"golang.org/x/crypto/sha3"
 )
 
-var _ keystore.Service = (*Service)(nil)
+var (
+	_ keystore.Service  = (*Service)(nil)
+	_ keystore.Importer = (*Service)(nil)
+)
 
 const (
 	keyHeaderKDF = "scrypt"
@@ -61,7 +64,9 @@ type kdfParams struct {
 	Salt  string `json:"salt"`
 }
 
-func encryptKey(k *ecdsa.PrivateKey, password string) ([]byte, error) {
+// EncryptKey encrypts the private key with the password in the Ethereum JSON
+// v3 key file format.
+func EncryptKey(k *ecdsa.PrivateKey, password string) ([]byte, error) {
 	data := crypto.EncodeSecp256k1PrivateKey(k)
 	kc, err := encryptData(data, []byte(password))
 	if err != nil {
@@ -78,7 +83,9 @@ func encryptKey(k *ecdsa.PrivateKey, password string) ([]byte, error) {
 	})
 }
 
-func decryptKey(data []byte, password string) (*ecdsa.PrivateKey, error) {
+// DecryptKey decrypts the private key in the Ethereum JSON v3 key file format.
+// It returns keystore.ErrInvalidPassword if the password is not valid.
+func DecryptKey(data []byte, password string) (*ecdsa.PrivateKey, error) {
 	var k encryptedKey
 	if err := json.Unmarshal(data, &k); err != nil {
 		return nil, err
@@ -93,6 +100,26 @@ func decryptKey(data []byte, password string) (*ecdsa.PrivateKey, error) {
 	return crypto.DecodeSecp256k1PrivateKey(d)
 }
 
+// EncryptData encrypts the data with the password, in the JSON format of the
+// crypto section of the Ethereum JSON v3 key files.
+func EncryptData(data []byte, password string) ([]byte, error) {
+	kc, err := encryptData(data, []byte(password))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(kc)
+}
+
+// DecryptData decrypts the data encrypted by EncryptData. It returns
+// keystore.ErrInvalidPassword if the password is not valid.
+func DecryptData(data []byte, password string) ([]byte, error) {
+	var kc keyCripto
+	if err := json.Unmarshal(data, &kc); err != nil {
+		return nil, err
+	}
+	return decryptData(kc, password)
+}
+
 func encryptData(data, password []byte) (*keyCripto, error) {
 	salt := make([]byte, 32)
 	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 
 	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/keystore"
 )
 
 // Service is the file-based keystore.Service implementation.
@@ -55,7 +56,7 @@ func (s *Service) Key(name, password string) (pk *ecdsa.PrivateKey, created bool
 			return nil, false, fmt.Errorf("generate secp256k1 key: %w", err)
 		}
 
-		d, err := encryptKey(pk, password)
+		d, err := EncryptKey(pk, password)
 		if err != nil {
 			return nil, false, err
 		}
@@ -69,13 +70,34 @@ func (s *Service) Key(name, password string) (pk *ecdsa.PrivateKey, created bool
 		return pk, true, nil
 	}
 
-	pk, err = decryptKey(data, password)
+	pk, err = DecryptKey(data, password)
 	if err != nil {
 		return nil, false, err
 	}
 	return pk, false, nil
 }
 
+func (s *Service) Import(name, password string, pk *ecdsa.PrivateKey) error {
+	exists, err := s.Exists(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return keystore.ErrKeyExists
+	}
+
+	d, err := EncryptKey(pk, password)
+	if err != nil {
+		return err
+	}
+
+	filename := s.keyFilename(name)
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, d, 0600)
+}
+
 func (s *Service) keyFilename(name string) string {
 	return filepath.Join(s.dir, fmt.Sprintf("%s.key", name))
 }
@@ -13,6 +13,10 @@ import (
 // private key is stored is not valid.
 var ErrInvalidPassword = errors.New("invalid password")
 
+// ErrKeyExists is returned when a private key is imported under the name of an
+// existing one.
+var ErrKeyExists = errors.New("key already exists")
+
 // Service for managing keystore private keys.
 type Service interface {
 	// Key returns the private key for a specified name that was encrypted with
@@ -23,3 +27,12 @@ type Service interface {
 	// Exists returns true if the key with specified name exists.
 	Exists(name string) (bool, error)
 }
+
+// Importer is implemented by the keystores to which existing private keys can
+// be added.
+type Importer interface {
+	// Import stores the private key with a specified name, encrypted with
+	// the provided password. It returns ErrKeyExists if there is a key with
+	// the name.
+	Import(name, password string, k *ecdsa.PrivateKey) error
+}
@@ -13,7 +13,10 @@ import (
 	"github.com/ethsana/sana/pkg/keystore"
 )
 
-var _ keystore.Service = (*Service)(nil)
+var (
+	_ keystore.Service  = (*Service)(nil)
+	_ keystore.Importer = (*Service)(nil)
+)
 
 // Service is the memory-based keystore.Service implementation.
 //
@@ -64,6 +67,20 @@ func (s *Service) Key(name, password string) (pk *ecdsa.PrivateKey, created bool
 	return k.pk, created, nil
 }
 
+func (s *Service) Import(name, password string, pk *ecdsa.PrivateKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.m[name]; ok {
+		return keystore.ErrKeyExists
+	}
+	s.m[name] = key{
+		pk:       pk,
+		password: password,
+	}
+	return nil
+}
+
 type key struct {
 	pk       *ecdsa.PrivateKey
 	password string
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/ethsana/sana/pkg/crypto"
 	"github.com/ethsana/sana/pkg/keystore"
 )
 
@@ -82,4 +83,33 @@ func Service(t *testing.T, s keystore.Service) {
 	if !bytes.Equal(k3.D.Bytes(), k4.D.Bytes()) {
 		t.Fatal("two keys are not equal")
 	}
+
+	imp, ok := s.(keystore.Importer)
+	if !ok {
+		return
+	}
+
+	// import a new pss key
+	k5, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := imp.Import("pss", "pss pass", k5); err != nil {
+		t.Fatal(err)
+	}
+	k6, created, err := s.Key("pss", "pss pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created {
+		t.Fatal("key is created, but should not be")
+	}
+	if !bytes.Equal(k5.D.Bytes(), k6.D.Bytes()) {
+		t.Fatal("imported and stored keys are not equal")
+	}
+
+	// existing keys are not replaced
+	if err := imp.Import("swarm", "pass123456", k5); !errors.Is(err, keystore.ErrKeyExists) {
+		t.Fatalf("got error %v, want %v", err, keystore.ErrKeyExists)
+	}
 }
@@ -0,0 +1,251 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package vault provides the keystore.Service implementation which stores the
+// keys in the key/value secrets engine of HashiCorp Vault.
+package vault
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/keystore"
+	filekeystore "github.com/ethsana/sana/pkg/keystore/file"
+)
+
+var (
+	_ keystore.Service  = (*Service)(nil)
+	_ keystore.Importer = (*Service)(nil)
+)
+
+// DefaultMount is the mount path of the key/value secrets engine of a Vault
+// server in development mode.
+const DefaultMount = "secret"
+
+// Options are the options of the Vault keystore.
+type Options struct {
+	// Endpoint is the address of the Vault server.
+	Endpoint string
+	// Token authenticates the requests to the Vault server.
+	Token string
+	// Mount is the mount path of the version 2 key/value secrets engine.
+	// DefaultMount is used if it is empty.
+	Mount string
+	// Prefix is the secret path under which the keys are stored.
+	Prefix string
+	// HTTPClient is used for the requests, a client with a timeout if it is nil.
+	HTTPClient *http.Client
+}
+
+// Service is the Vault keystore.Service implementation.
+//
+// Every private key is stored in a secret named after the key, encrypted with
+// the password in the same format as the file keystore, so the keys never
+// touch the local disk and the secrets are of no use without the password.
+type Service struct {
+	endpoint *url.URL
+	token    string
+	mount    string
+	prefix   string
+	client   *http.Client
+}
+
+// New creates a new Vault keystore.Service implementation.
+func New(o Options) (*Service, error) {
+	if o.Endpoint == "" {
+		return nil, errors.New("vault endpoint not provided")
+	}
+	endpoint, err := url.Parse(o.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse vault endpoint: %w", err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported vault endpoint scheme %q", endpoint.Scheme)
+	}
+	mount := strings.Trim(o.Mount, "/")
+	if mount == "" {
+		mount = DefaultMount
+	}
+	client := o.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Service{
+		endpoint: endpoint,
+		token:    o.Token,
+		mount:    mount,
+		prefix:   strings.Trim(o.Prefix, "/"),
+		client:   client,
+	}, nil
+}
+
+// secret is the data of a version 2 key/value secret.
+type secret struct {
+	Key json.RawMessage `json:"key"`
+}
+
+type readResponse struct {
+	Data struct {
+		Data secret `json:"data"`
+	} `json:"data"`
+}
+
+type writeRequest struct {
+	Options writeOptions `json:"options"`
+	Data    secret       `json:"data"`
+}
+
+type writeOptions struct {
+	CAS int `json:"cas"`
+}
+
+type errorResponse struct {
+	Errors []string `json:"errors"`
+}
+
+func (s *Service) Exists(name string) (bool, error) {
+	data, err := s.read(name)
+	if err != nil {
+		return false, err
+	}
+	return data != nil, nil
+}
+
+func (s *Service) Key(name, password string) (pk *ecdsa.PrivateKey, created bool, err error) {
+	data, err := s.read(name)
+	if err != nil {
+		return nil, false, err
+	}
+	if data == nil {
+		pk, err = crypto.GenerateSecp256k1Key()
+		if err != nil {
+			return nil, false, fmt.Errorf("generate secp256k1 key: %w", err)
+		}
+
+		d, err := filekeystore.EncryptKey(pk, password)
+		if err != nil {
+			return nil, false, err
+		}
+		err = s.write(name, d)
+		if err == nil {
+			return pk, true, nil
+		}
+		if !errors.Is(err, keystore.ErrKeyExists) {
+			return nil, false, err
+		}
+		// use the key which was created in the meantime
+		if data, err = s.read(name); err != nil {
+			return nil, false, err
+		}
+		if data == nil {
+			return nil, false, keystore.ErrKeyExists
+		}
+	}
+
+	pk, err = filekeystore.DecryptKey(data, password)
+	if err != nil {
+		return nil, false, err
+	}
+	return pk, false, nil
+}
+
+func (s *Service) Import(name, password string, pk *ecdsa.PrivateKey) error {
+	d, err := filekeystore.EncryptKey(pk, password)
+	if err != nil {
+		return err
+	}
+	return s.write(name, d)
+}
+
+// read returns the encrypted key, or nil if the secret does not exist.
+func (s *Service) read(name string) ([]byte, error) {
+	resp, err := s.request(http.MethodGet, name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("read private key: %w", responseError(resp))
+	}
+	var r readResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("read private key: decode response: %w", err)
+	}
+	if len(r.Data.Data.Key) == 0 {
+		return nil, nil
+	}
+	return r.Data.Data.Key, nil
+}
+
+// write stores the encrypted key only if the secret does not exist, so that
+// the key of another node is never overwritten.
+func (s *Service) write(name string, key []byte) error {
+	body, err := json.Marshal(writeRequest{
+		Options: writeOptions{CAS: 0},
+		Data:    secret{Key: key},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := s.request(http.MethodPost, name, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("write private key: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusBadRequest:
+		// the check-and-set failure is reported as a bad request
+		err := responseError(resp)
+		if strings.Contains(err.Error(), "check-and-set") {
+			return keystore.ErrKeyExists
+		}
+		return fmt.Errorf("write private key: %w", err)
+	default:
+		return fmt.Errorf("write private key: %w", responseError(resp))
+	}
+}
+
+func (s *Service) request(method, name string, body io.Reader) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = path.Join("/", u.Path, "v1", s.mount, "data", s.prefix, name)
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set("X-Vault-Token", s.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return s.client.Do(req)
+}
+
+func responseError(resp *http.Response) error {
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var r errorResponse
+	if err := json.Unmarshal(data, &r); err == nil && len(r.Errors) > 0 {
+		return fmt.Errorf("vault: %s: %s", resp.Status, strings.Join(r.Errors, "; "))
+	}
+	return fmt.Errorf("vault: %s", resp.Status)
+}
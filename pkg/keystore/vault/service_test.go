@@ -0,0 +1,114 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vault_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/keystore/test"
+	"github.com/ethsana/sana/pkg/keystore/vault"
+)
+
+const token = "vault-token"
+
+// newVault returns a server with the endpoints of the version 2 key/value
+// secrets engine used by the keystore.
+func newVault(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var (
+		mu      sync.Mutex
+		secrets = make(map[string]json.RawMessage)
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, "/v1/kv/data/sana/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			data, ok := secrets[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[]}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"data": data},
+			})
+		case http.MethodPost:
+			var req struct {
+				Options struct {
+					CAS *int `json:"cas"`
+				} `json:"options"`
+				Data json.RawMessage `json:"data"`
+			}
+			b, _ := ioutil.ReadAll(r.Body)
+			if err := json.Unmarshal(b, &req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if _, ok := secrets[r.URL.Path]; ok && req.Options.CAS != nil && *req.Options.CAS == 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["check-and-set parameter did not match the current version"]}`))
+				return
+			}
+			secrets[r.URL.Path] = req.Data
+			_, _ = w.Write([]byte(`{"data":{"version":1}}`))
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestService(t *testing.T) {
+	s, err := vault.New(vault.Options{
+		Endpoint: newVault(t).URL,
+		Token:    token,
+		Mount:    "kv",
+		Prefix:   "sana",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test.Service(t, s)
+}
+
+func TestServiceUnauthorized(t *testing.T) {
+	s, err := vault.New(vault.Options{
+		Endpoint: newVault(t).URL,
+		Token:    "invalid",
+		Mount:    "kv",
+		Prefix:   "sana",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Exists("sana"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("got error %v, want permission denied", err)
+	}
+	if _, _, err := s.Key("sana", "pass"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -124,30 +124,43 @@ func (db *DB) collectGarbage() (collectedCount uint64, done bool, err error) {
 	done = true
 	first := true
 	start := time.Now()
+
+	// the number of chunks to collect is limited by the batch size,
+	// however we don't know whether another gc run is needed until
+	// we weed out the dirty entries below
+	var count uint64
+	if gcSize > target {
+		count = gcSize - target
+	}
+	if count > gcBatchSize {
+		count = gcBatchSize
+	}
+	window := count
+	if db.evictionPolicy == EvictionProximity {
+		window *= gcProximityWindow
+	}
 	candidates := make([]shed.Item, 0)
 	err = db.gcIndex.Iterate(func(item shed.Item) (stop bool, err error) {
 		if first {
 			totalTimeMetric(db.metrics.TotalTimeGCFirstItem, start)
 			first = false
 		}
-		if gcSize-collectedCount <= target {
+		if uint64(len(candidates)) >= window {
 			return true, nil
 		}
-
 		candidates = append(candidates, item)
-
-		collectedCount++
-		if collectedCount >= gcBatchSize {
-			// batch size limit reached, however we don't
-			// know whether another gc run is needed until
-			// we weed out the dirty entries below
-			return true, nil
-		}
 		return false, nil
 	}, nil)
 	if err != nil {
 		return 0, false, err
 	}
+	if db.evictionPolicy == EvictionProximity {
+		db.sortByProximity(candidates)
+	}
+	if uint64(len(candidates)) > count {
+		candidates = candidates[:count]
+	}
+	collectedCount = uint64(len(candidates))
 	db.metrics.GCCollectedCounter.Add(float64(collectedCount))
 	if testHookGCIteratorDone != nil {
 		testHookGCIteratorDone()
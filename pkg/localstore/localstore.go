@@ -109,6 +109,9 @@ type DB struct {
 	// field that stores the size of the reserve
 	reserveSize shed.Uint64Field
 
+	// field that stores the number of pins by the users
+	pinSize shed.Uint64Field
+
 	// garbage collection is triggered when gcSize exceeds
 	// the cacheCapacity value
 	cacheCapacity uint64
@@ -116,6 +119,12 @@ type DB struct {
 	// the size of the reserve in chunks
 	reserveCapacity uint64
 
+	// the limit of the pins by the users, zero is unlimited
+	pinCapacity uint64
+
+	// evictionPolicy selects the garbage collected chunks
+	evictionPolicy EvictionPolicy
+
 	unreserveFunc func(postage.UnreserveIteratorFn) error
 
 	// triggers garbage collection event loop
@@ -176,6 +185,12 @@ type Options struct {
 	// UnreserveFunc is an iterator needed to facilitate reserve
 	// eviction once ReserveCapacity is reached.
 	UnreserveFunc func(postage.UnreserveIteratorFn) error
+	// PinCapacity limits the number of pins of the chunks by the users,
+	// further pins fail with ErrPinCapacity. Zero is unlimited.
+	PinCapacity uint64
+	// EvictionPolicy selects the cached chunks which are garbage
+	// collected first.
+	EvictionPolicy EvictionPolicy
 	// OpenFilesLimit defines the upper bound of open files that the
 	// the localstore should maintain at any point of time. It is
 	// passed on to the shed constructor.
@@ -210,6 +225,8 @@ func New(path string, baseKey []byte, ss storage.StateStorer, o *Options, logger
 		stateStore:      ss,
 		cacheCapacity:   o.Capacity,
 		reserveCapacity: o.ReserveCapacity,
+		pinCapacity:     o.PinCapacity,
+		evictionPolicy:  o.EvictionPolicy,
 		unreserveFunc:   o.UnreserveFunc,
 		baseKey:         baseKey,
 		tags:            o.Tags,
@@ -292,6 +309,12 @@ func New(path string, baseKey []byte, ss storage.StateStorer, o *Options, logger
 		return nil, err
 	}
 
+	// number of pins by the users
+	db.pinSize, err = db.shed.NewUint64Field("pin-size")
+	if err != nil {
+		return nil, err
+	}
+
 	// Index storing actual chunk address, data and bin id.
 	headerSize := 16 + postage.StampSize
 	db.retrievalDataIndex, err = db.shed.NewIndex("Address->StoreTimestamp|BinID|BatchID|BatchIndex|Sig|Data", shed.IndexFuncs{
@@ -591,6 +614,12 @@ func (db *DB) po(addr swarm.Address) (bin uint8) {
 	return swarm.Proximity(db.baseKey, addr.Bytes())
 }
 
+// PushIndexSize returns the number of chunks which are not yet synced to the
+// network.
+func (db *DB) PushIndexSize() (int, error) {
+	return db.pushIndex.Count()
+}
+
 // DebugIndices returns the index sizes for all indexes in localstore
 // the returned map keys are the index name, values are the number of elements in the index
 func (db *DB) DebugIndices() (indexInfo map[string]int, err error) {
@@ -61,6 +61,7 @@ type metrics struct {
 	GCStoreAccessTimeStamps prometheus.Gauge
 
 	ReserveSize              prometheus.Gauge
+	PinSize                  prometheus.Gauge
 	EvictReserveCounter      prometheus.Counter
 	EvictReserveErrorCounter prometheus.Counter
 	TotalTimeEvictReserve    prometheus.Counter
@@ -354,6 +355,12 @@ func newMetrics() metrics {
 			Name:      "reserve_size",
 			Help:      "Number of elements in reserve.",
 		}),
+		PinSize: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "pin_size",
+			Help:      "Number of pins of chunks by the users.",
+		}),
 		EvictReserveCounter: prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: m.Namespace,
 			Subsystem: subsystem,
@@ -97,23 +97,42 @@ func (db *DB) put(mode storage.ModePut, chs ...swarm.Chunk) (exist []bool, err e
 
 	switch mode {
 	case storage.ModePutRequest, storage.ModePutRequestPin, storage.ModePutRequestCache:
+		pin := mode == storage.ModePutRequestPin     // force pin in this mode
+		cache := mode == storage.ModePutRequestCache // force cache
+		if pin {
+			if err := db.checkPinCapacity(len(chs)); err != nil {
+				return nil, err
+			}
+		}
+		var pins int64
 		for i, ch := range chs {
 			if containsChunk(ch.Address(), chs[:i]...) {
 				exist[i] = true
 				continue
 			}
 			item := chunkToItem(ch)
-			pin := mode == storage.ModePutRequestPin     // force pin in this mode
-			cache := mode == storage.ModePutRequestCache // force cache
 			exists, c, err := db.putRequest(batch, binIDs, item, pin, cache)
 			if err != nil {
 				return nil, err
 			}
 			exist[i] = exists
 			gcSizeChange += c
+			// the chunks which are already stored are not pinned
+			if pin && !exists {
+				pins++
+			}
+		}
+		if err := db.incPinSizeInBatch(batch, pins); err != nil {
+			return nil, err
 		}
 
 	case storage.ModePutUpload, storage.ModePutUploadPin:
+		if mode == storage.ModePutUploadPin {
+			if err := db.checkPinCapacity(len(chs)); err != nil {
+				return nil, err
+			}
+		}
+		var pins int64
 		for i, ch := range chs {
 			if containsChunk(ch.Address(), chs[:i]...) {
 				exist[i] = true
@@ -138,8 +157,12 @@ func (db *DB) put(mode storage.ModePut, chs ...swarm.Chunk) (exist []bool, err e
 					return nil, err
 				}
 				gcSizeChange += c
+				pins++
 			}
 		}
+		if err := db.incPinSizeInBatch(batch, pins); err != nil {
+			return nil, err
+		}
 
 	case storage.ModePutSync:
 		for i, ch := range chs {
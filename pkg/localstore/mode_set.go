@@ -81,6 +81,9 @@ func (db *DB) set(mode storage.ModeSet, addrs ...swarm.Address) (err error) {
 		}
 
 	case storage.ModeSetPin:
+		if err := db.checkPinCapacity(len(addrs)); err != nil {
+			return err
+		}
 		for _, addr := range addrs {
 			item := addressToItem(addr)
 			c, err := db.setPin(batch, item)
@@ -89,6 +92,9 @@ func (db *DB) set(mode storage.ModeSet, addrs ...swarm.Address) (err error) {
 			}
 			gcSizeChange += c
 		}
+		if err := db.incPinSizeInBatch(batch, int64(len(addrs))); err != nil {
+			return err
+		}
 	case storage.ModeSetUnpin:
 		for _, addr := range addrs {
 			c, err := db.setUnpin(batch, addr)
@@ -97,6 +103,9 @@ func (db *DB) set(mode storage.ModeSet, addrs ...swarm.Address) (err error) {
 			}
 			gcSizeChange += c
 		}
+		if err := db.incPinSizeInBatch(batch, -int64(len(addrs))); err != nil {
+			return err
+		}
 	default:
 		return ErrInvalidMode
 	}
@@ -0,0 +1,167 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package localstore
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/shed"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// EvictionPolicy selects the cached chunks which are garbage collected first.
+type EvictionPolicy int
+
+const (
+	// EvictionLRU collects the least recently accessed chunks first.
+	EvictionLRU EvictionPolicy = iota
+	// EvictionProximity collects the chunks farthest from the overlay of
+	// the node first among the least recently accessed ones, so that the
+	// chunks of its neighbourhood are cached for longer.
+	EvictionProximity
+)
+
+// gcProximityWindow is the number of times more least recently accessed
+// chunks than collected ones which are ordered by proximity with the
+// EvictionProximity policy.
+const gcProximityWindow = 4
+
+// chunkDiskSize is the approximate number of bytes a stored chunk takes on
+// disk with its stamp and the entries of the indexes.
+const chunkDiskSize = swarm.ChunkWithSpanSize + postage.StampSize + 256
+
+// ErrPinCapacity is returned when chunks are pinned over the pin capacity.
+var ErrPinCapacity = errors.New("localstore: pin capacity reached")
+
+func (p EvictionPolicy) String() string {
+	switch p {
+	case EvictionLRU:
+		return "lru"
+	case EvictionProximity:
+		return "proximity"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseEvictionPolicy returns the eviction policy with the name.
+func ParseEvictionPolicy(s string) (EvictionPolicy, error) {
+	switch s {
+	case "", "lru":
+		return EvictionLRU, nil
+	case "proximity":
+		return EvictionProximity, nil
+	default:
+		return 0, fmt.Errorf("unknown eviction policy %q", s)
+	}
+}
+
+// CacheCapacity returns the capacity of the cache in chunks of a database of
+// the size in bytes which is left by the reserve and pinned chunks.
+func CacheCapacity(size, reserveCapacity, pinCapacity uint64) (uint64, error) {
+	total := size / chunkDiskSize
+	if total <= reserveCapacity+pinCapacity {
+		return 0, fmt.Errorf("database of %d chunks is too small for the reserve of %d and %d pinned chunks", total, reserveCapacity, pinCapacity)
+	}
+	return total - reserveCapacity - pinCapacity, nil
+}
+
+// Pool is the number of chunks in a storage pool and its capacity. The zero
+// capacity is unlimited.
+type Pool struct {
+	Size     uint64
+	Capacity uint64
+}
+
+// Pools reports the usage of the storage pools of the database. The cache is
+// garbage collected with the eviction policy, the reserve holds the chunks of
+// the neighbourhood which are mined for and the pinned pool counts the pins
+// of the chunks by the users.
+type Pools struct {
+	Cache          Pool
+	Reserve        Pool
+	Pinned         Pool
+	EvictionPolicy EvictionPolicy
+}
+
+// Pools returns the usage of the storage pools.
+func (db *DB) Pools() (p Pools, err error) {
+	p.Cache.Capacity = db.cacheCapacity
+	p.Reserve.Capacity = db.reserveCapacity
+	p.Pinned.Capacity = db.pinCapacity
+	p.EvictionPolicy = db.evictionPolicy
+
+	for _, f := range []struct {
+		field shed.Uint64Field
+		size  *uint64
+	}{
+		{field: db.gcSize, size: &p.Cache.Size},
+		{field: db.reserveSize, size: &p.Reserve.Size},
+		{field: db.pinSize, size: &p.Pinned.Size},
+	} {
+		*f.size, err = f.field.Get()
+		if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+			return Pools{}, err
+		}
+	}
+	return p, nil
+}
+
+// checkPinCapacity returns ErrPinCapacity if the number of pins can not be
+// added to the pinned pool. This function must be called under batchMu lock.
+func (db *DB) checkPinCapacity(count int) error {
+	if db.pinCapacity == 0 {
+		return nil
+	}
+	pinSize, err := db.pinSize.Get()
+	if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+		return err
+	}
+	if pinSize+uint64(count) > db.pinCapacity {
+		return ErrPinCapacity
+	}
+	return nil
+}
+
+// incPinSizeInBatch changes pinSize field value by change which can be
+// negative. This function must be called under batchMu lock.
+func (db *DB) incPinSizeInBatch(batch *leveldb.Batch, change int64) (err error) {
+	if change == 0 {
+		return nil
+	}
+	pinSize, err := db.pinSize.Get()
+	if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+		return err
+	}
+
+	var newSize uint64
+	if change > 0 {
+		newSize = pinSize + uint64(change)
+	} else {
+		c := uint64(-change)
+		if c > pinSize {
+			// the pins of the databases created before the pin size
+			// was counted are not included
+			c = pinSize
+		}
+		newSize = pinSize - c
+	}
+	db.pinSize.PutInBatch(batch, newSize)
+	db.metrics.PinSize.Set(float64(newSize))
+	return nil
+}
+
+// sortByProximity orders the garbage collection candidates from the
+// farthest to the closest to the overlay of the node, keeping the access
+// order of the chunks in the same bin.
+func (db *DB) sortByProximity(items []shed.Item) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return db.po(swarm.NewAddress(items[i].Address)) < db.po(swarm.NewAddress(items[j].Address))
+	})
+}
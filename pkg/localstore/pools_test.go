@@ -0,0 +1,186 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package localstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/shed"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+// TestEvictionPolicy tests that the garbage collection collects the least
+// recently accessed chunks first with the lru policy and the farthest of
+// them with the proximity policy.
+func TestEvictionPolicy(t *testing.T) {
+	t.Cleanup(setWithinRadiusFunc(func(_ *DB, _ shed.Item) bool { return false }))
+
+	for _, tc := range []struct {
+		policy EvictionPolicy
+		// collectedNear reports whether the near chunks are collected
+		collectedNear bool
+	}{
+		{policy: EvictionLRU, collectedNear: true},
+		{policy: EvictionProximity, collectedNear: false},
+	} {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			db := newTestDB(t, &Options{
+				Capacity:       100,
+				EvictionPolicy: tc.policy,
+			})
+			ctx := context.Background()
+
+			// the near chunks are accessed before the far ones
+			var near, far []swarm.Chunk
+			for len(near) < 5 {
+				if ch := generateTestRandomChunk(); db.po(ch.Address()) > 0 {
+					near = append(near, ch)
+				}
+			}
+			for len(far) < 6 {
+				if ch := generateTestRandomChunk(); db.po(ch.Address()) == 0 {
+					far = append(far, ch)
+				}
+			}
+			for _, ch := range append(near, far...) {
+				unreserveChunkBatch(t, db, 0, ch)
+				if _, err := db.Put(ctx, storage.ModePutUpload, ch); err != nil {
+					t.Fatal(err)
+				}
+				if err := db.Set(ctx, storage.ModeSetSync, ch.Address()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// collect two of the chunks
+			db.cacheCapacity = 10
+			collected, _, err := db.collectGarbage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if collected != 2 {
+				t.Fatalf("got %d collected chunks, want 2", collected)
+			}
+
+			want := far
+			if tc.collectedNear {
+				want = near
+			}
+			for i, ch := range want {
+				_, err := db.Get(ctx, storage.ModeGetRequest, ch.Address())
+				if i < 2 && !errors.Is(err, storage.ErrNotFound) {
+					t.Errorf("chunk %d: got error %v, want %v", i, err, storage.ErrNotFound)
+				}
+				if i >= 2 && err != nil {
+					t.Errorf("chunk %d: %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPinCapacity(t *testing.T) {
+	db := newTestDB(t, &Options{PinCapacity: 2})
+	ctx := context.Background()
+
+	chs := generateTestRandomChunks(3)
+	if _, err := db.Put(ctx, storage.ModePutUploadPin, chs[0]); err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range chs[1:] {
+		if _, err := db.Put(ctx, storage.ModePutUpload, ch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Set(ctx, storage.ModeSetPin, chs[1].Address(), chs[2].Address()); !errors.Is(err, ErrPinCapacity) {
+		t.Fatalf("got error %v, want %v", err, ErrPinCapacity)
+	}
+	if err := db.Set(ctx, storage.ModeSetPin, chs[1].Address()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Put(ctx, storage.ModePutUploadPin, chs[2]); !errors.Is(err, ErrPinCapacity) {
+		t.Fatalf("got error %v, want %v", err, ErrPinCapacity)
+	}
+
+	// unpinned chunks make room for the other pins
+	if err := db.Set(ctx, storage.ModeSetUnpin, chs[0].Address()); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set(ctx, storage.ModeSetPin, chs[2].Address()); err != nil {
+		t.Fatal(err)
+	}
+
+	pools, err := db.Pools()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pools.Pinned.Size != 2 || pools.Pinned.Capacity != 2 {
+		t.Fatalf("got pinned pool %+v, want size 2 and capacity 2", pools.Pinned)
+	}
+}
+
+// TestPinCapacityRequest validates that the pins of the chunks which are
+// retrieved from the network are counted in the pin capacity.
+func TestPinCapacityRequest(t *testing.T) {
+	db := newTestDB(t, &Options{PinCapacity: 2})
+	ctx := context.Background()
+
+	chs := generateTestRandomChunks(3)
+	if _, err := db.Put(ctx, storage.ModePutRequestPin, chs[:2]...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Put(ctx, storage.ModePutRequestPin, chs[2]); !errors.Is(err, ErrPinCapacity) {
+		t.Fatalf("got error %v, want %v", err, ErrPinCapacity)
+	}
+	t.Run("pin index count", newItemsCountTest(db.pinIndex, 2))
+
+	// the unpins of the retrieved chunks make room for the other pins
+	if err := db.Set(ctx, storage.ModeSetUnpin, chs[0].Address()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Put(ctx, storage.ModePutRequestPin, chs[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	pools, err := db.Pools()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pools.Pinned.Size != 2 {
+		t.Fatalf("got pinned pool size %d, want 2", pools.Pinned.Size)
+	}
+}
+
+func TestCacheCapacityFromSize(t *testing.T) {
+	capacity, err := CacheCapacity(100*chunkDiskSize, 50, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capacity != 40 {
+		t.Fatalf("got cache capacity %d, want 40", capacity)
+	}
+
+	if _, err := CacheCapacity(60*chunkDiskSize, 50, 10); err == nil {
+		t.Fatal("expected error for the size of the reserve and pinned chunks")
+	}
+}
+
+func TestParseEvictionPolicy(t *testing.T) {
+	for _, p := range []EvictionPolicy{EvictionLRU, EvictionProximity} {
+		got, err := ParseEvictionPolicy(p.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != p {
+			t.Fatalf("got policy %v, want %v", got, p)
+		}
+	}
+	if _, err := ParseEvictionPolicy("fifo"); err == nil {
+		t.Fatal("expected error for unknown policy")
+	}
+}
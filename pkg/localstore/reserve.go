@@ -123,3 +123,18 @@ func withinRadius(db *DB, item shed.Item) bool {
 	po := db.po(swarm.NewAddress(item.Address))
 	return po >= item.Radius
 }
+
+// IterateReserve calls the function with the addresses of the stamped chunks
+// which are preserved in the reserve, ordered by their postage batches.
+func (db *DB) IterateReserve(fn func(addr swarm.Address) (stop bool, err error)) error {
+	return db.postageChunksIndex.Iterate(func(item shed.Item) (stop bool, err error) {
+		has, err := db.pinIndex.Has(item)
+		if err != nil {
+			return true, err
+		}
+		if !has {
+			return false, nil
+		}
+		return fn(swarm.NewAddress(append([]byte(nil), item.Address...)))
+	}, nil)
+}
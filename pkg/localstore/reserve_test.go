@@ -580,3 +580,48 @@ func TestDB_ReserveGC_EvictMaxPO(t *testing.T) {
 		}
 	})
 }
+
+// TestDB_IterateReserve tests that only the chunks preserved in the reserve
+// are iterated.
+func TestDB_IterateReserve(t *testing.T) {
+	db := newTestDB(t, &Options{
+		Capacity:        100,
+		ReserveCapacity: 200,
+	})
+
+	put := func(within bool, count int) map[string]struct{} {
+		t.Helper()
+		reset := setWithinRadiusFunc(func(*DB, shed.Item) bool { return within })
+		defer reset()
+
+		addrs := make(map[string]struct{})
+		for i := 0; i < count; i++ {
+			ch := generateTestRandomChunk().WithBatch(2, 3, 2, false)
+			if _, err := db.Put(context.Background(), storage.ModePutUpload, ch); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Set(context.Background(), storage.ModeSetSync, ch.Address()); err != nil {
+				t.Fatal(err)
+			}
+			addrs[ch.Address().ByteString()] = struct{}{}
+		}
+		return addrs
+	}
+	reserved := put(true, 10)
+	put(false, 5)
+
+	count := 0
+	err := db.IterateReserve(func(addr swarm.Address) (bool, error) {
+		if _, ok := reserved[addr.ByteString()]; !ok {
+			t.Fatalf("chunk %s not in reserve", addr)
+		}
+		count++
+		return false, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(reserved) {
+		t.Fatalf("got %d chunks, want %d", count, len(reserved))
+	}
+}
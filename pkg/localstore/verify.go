@@ -0,0 +1,141 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package localstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethsana/sana/pkg/cac"
+	"github.com/ethsana/sana/pkg/shed"
+	"github.com/ethsana/sana/pkg/soc"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Corruption describes a chunk whose stored record is not consistent with its
+// address.
+type Corruption struct {
+	Address swarm.Address
+	Reason  string
+}
+
+// Verify walks all chunks in the pull index and checks that their data is
+// stored and valid for their addresses, either as content addressed or as
+// single owner chunks. It returns the number of checked chunks and the corrupt
+// ones. If remove is true, the corrupt chunks are removed from the indexes so
+// that they can be synced or uploaded again.
+func (db *DB) Verify(ctx context.Context, remove bool) (count int64, corrupt []Corruption, err error) {
+	var broken []shed.Item // corrupt chunks whose data can not be decoded
+	err = db.pullIndex.Iterate(func(item shed.Item) (stop bool, err error) {
+		if err := ctx.Err(); err != nil {
+			return true, err
+		}
+		count++
+		addr := swarm.NewAddress(append([]byte(nil), item.Address...))
+
+		i, err := db.retrievalDataIndex.Get(item)
+		switch {
+		case errors.Is(err, leveldb.ErrNotFound):
+			corrupt = append(corrupt, Corruption{Address: addr, Reason: "missing data"})
+			broken = append(broken, shed.Item{Address: addr.Bytes(), BinID: item.BinID})
+		case err != nil:
+			corrupt = append(corrupt, Corruption{Address: addr, Reason: fmt.Sprintf("undecodable record: %v", err)})
+			broken = append(broken, shed.Item{Address: addr.Bytes(), BinID: item.BinID})
+		default:
+			ch := swarm.NewChunk(addr, i.Data)
+			if !cac.Valid(ch) && !soc.Valid(ch) {
+				corrupt = append(corrupt, Corruption{Address: addr, Reason: "data does not match address"})
+			}
+		}
+		return false, nil
+	}, nil)
+	if err != nil {
+		return count, corrupt, err
+	}
+	if !remove || len(corrupt) == 0 {
+		return count, corrupt, nil
+	}
+
+	// chunks with decodable records are removed from all indexes
+	isBroken := make(map[string]bool, len(broken))
+	for _, item := range broken {
+		isBroken[string(item.Address)] = true
+	}
+	var addrs []swarm.Address
+	for _, c := range corrupt {
+		if !isBroken[c.Address.ByteString()] {
+			addrs = append(addrs, c.Address)
+		}
+	}
+	if len(addrs) > 0 {
+		if err := db.Set(ctx, storage.ModeSetRemove, addrs...); err != nil {
+			return count, corrupt, fmt.Errorf("remove corrupt chunks: %w", err)
+		}
+	}
+
+	// the records of the other chunks are not known, so the entries which
+	// can not be located by the address and the bin id are found by walking
+	// the push and gc indexes
+	db.batchMu.Lock()
+	defer db.batchMu.Unlock()
+	batch := new(leveldb.Batch)
+	for _, item := range broken {
+		for _, index := range []shed.Index{db.retrievalDataIndex, db.retrievalAccessIndex, db.pinIndex} {
+			if err := index.DeleteInBatch(batch, item); err != nil {
+				return count, corrupt, err
+			}
+		}
+		if err := db.pullIndex.DeleteInBatch(batch, item); err != nil {
+			return count, corrupt, err
+		}
+	}
+
+	// the removal of the chunks with decodable records keeps them in the
+	// push index
+	isCorrupt := make(map[string]bool, len(corrupt))
+	for _, c := range corrupt {
+		isCorrupt[c.Address.ByteString()] = true
+	}
+	err = db.pushIndex.Iterate(func(item shed.Item) (stop bool, err error) {
+		if isCorrupt[string(item.Address)] {
+			return false, db.pushIndex.DeleteInBatch(batch, item)
+		}
+		return false, nil
+	}, nil)
+	if err != nil {
+		return count, corrupt, fmt.Errorf("remove corrupt chunks: %w", err)
+	}
+
+	var gcSizeChange int64
+	if len(broken) > 0 {
+		err = db.gcIndex.Iterate(func(item shed.Item) (stop bool, err error) {
+			if !isBroken[string(item.Address)] {
+				return false, nil
+			}
+			gcSizeChange--
+			return false, db.gcIndex.DeleteInBatch(batch, item)
+		}, nil)
+		if err != nil {
+			return count, corrupt, fmt.Errorf("remove corrupt chunks: %w", err)
+		}
+	}
+	if err := db.incGCSizeInBatch(batch, gcSizeChange); err != nil {
+		return count, corrupt, err
+	}
+
+	if err := db.shed.WriteBatch(batch); err != nil {
+		return count, corrupt, fmt.Errorf("remove corrupt chunks: %w", err)
+	}
+	return count, corrupt, nil
+}
+
+// Compact compacts the underlying database, reclaiming the space of removed
+// chunks.
+func (db *DB) Compact() error {
+	return db.shed.Compact()
+}
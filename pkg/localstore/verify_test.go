@@ -0,0 +1,100 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package localstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/shed"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+func TestDB_Verify(t *testing.T) {
+	t.Cleanup(setWithinRadiusFunc(func(_ *DB, _ shed.Item) bool { return false }))
+	ctx := context.Background()
+	db := newTestDB(t, nil)
+
+	chunks := make([]swarm.Chunk, 5)
+	for i := range chunks {
+		chunks[i] = generateTestRandomChunk()
+	}
+	if _, err := db.Put(ctx, storage.ModePutUpload, chunks[:3]...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Put(ctx, storage.ModePutRequest, chunks[3:]...); err != nil {
+		t.Fatal(err)
+	}
+
+	count, corrupt, err := db.Verify(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 5 || len(corrupt) != 0 {
+		t.Fatalf("got %d chunks, %d corrupt, want 5, 0", count, len(corrupt))
+	}
+
+	// overwrite the data of an uploaded chunk and delete the data of an
+	// uploaded and a requested chunk
+	item, err := db.retrievalDataIndex.Get(addressToItem(chunks[0].Address()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	item.Data = append([]byte(nil), item.Data...)
+	item.Data[swarm.SpanSize] ^= 0xff
+	if err := db.retrievalDataIndex.Put(item); err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range []swarm.Chunk{chunks[1], chunks[3]} {
+		if err := db.retrievalDataIndex.Delete(addressToItem(ch.Address())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	corrupted := []swarm.Chunk{chunks[0], chunks[1], chunks[3]}
+	valid := []swarm.Chunk{chunks[2], chunks[4]}
+
+	count, corrupt, err = db.Verify(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 5 || len(corrupt) != 3 {
+		t.Fatalf("got %d chunks, %d corrupt, want 5, 3", count, len(corrupt))
+	}
+	for _, c := range corrupt {
+		if !c.Address.Equal(chunks[0].Address()) && !c.Address.Equal(chunks[1].Address()) && !c.Address.Equal(chunks[3].Address()) {
+			t.Fatalf("chunk %s reported corrupt: %s", c.Address, c.Reason)
+		}
+	}
+	t.Run("push index count", newItemsCountTest(db.pushIndex, 1))
+	t.Run("gc index count", newItemsCountTest(db.gcIndex, 1))
+	t.Run("gc size", newIndexGCSizeTest(db))
+
+	count, corrupt, err = db.Verify(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || len(corrupt) != 0 {
+		t.Fatalf("got %d chunks, %d corrupt after removal, want 2, 0", count, len(corrupt))
+	}
+	for _, ch := range corrupted {
+		if _, err := db.Get(ctx, storage.ModeGetRequest, ch.Address()); !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+		}
+	}
+	for _, ch := range valid {
+		if _, err := db.Get(ctx, storage.ModeGetRequest, ch.Address()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := db.retrievalDataIndex.Count(); err != nil || n != 2 {
+		t.Fatalf("got %d records after compaction, error %v", n, err)
+	}
+}
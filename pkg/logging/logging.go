@@ -8,10 +8,19 @@ package logging
 
 import (
 	"io"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 )
 
+// Stable names of the fields of the structured log lines.
+const (
+	FieldComponent = "component"
+	FieldPeer      = "peer"
+	FieldChunk     = "chunk"
+	FieldTxHash    = "tx_hash"
+)
+
 type Logger interface {
 	Tracef(format string, args ...interface{})
 	Trace(args ...interface{})
@@ -29,26 +38,153 @@ type Logger interface {
 	NewEntry() *logrus.Entry
 }
 
+// Option configures the logger returned by New.
+type Option func(*logrus.Logger)
+
+// WithJSONFormat formats the log lines as JSON objects instead of text.
+func WithJSONFormat() Option {
+	return func(l *logrus.Logger) {
+		l.Formatter = &logrus.JSONFormatter{}
+	}
+}
+
 type logger struct {
 	*logrus.Logger
 	metrics metrics
+
+	mu         sync.Mutex
+	levels     map[string]logrus.Level // the levels of the components
+	components map[string]*component
 }
 
-func New(w io.Writer, level logrus.Level) Logger {
+func New(w io.Writer, level logrus.Level, opts ...Option) Logger {
 	l := logrus.New()
-	l.SetOutput(w)
+	// the components log through their own logrus loggers to the
+	// same writer
+	l.SetOutput(&syncWriter{w: w})
 	l.SetLevel(level)
 	l.Formatter = &logrus.TextFormatter{
 		FullTimestamp: true,
 	}
+	for _, o := range opts {
+		o(l)
+	}
 	metrics := newMetrics()
 	l.AddHook(metrics)
 	return &logger{
-		Logger:  l,
-		metrics: metrics,
+		Logger:     l,
+		metrics:    metrics,
+		levels:     make(map[string]logrus.Level),
+		components: make(map[string]*component),
 	}
 }
 
 func (l *logger) NewEntry() *logrus.Entry {
 	return logrus.NewEntry(l.Logger)
 }
+
+// SetLevel sets the level of the logger and of its components without their
+// own levels.
+func (l *logger) SetLevel(level logrus.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.Logger.SetLevel(level)
+	for name, c := range l.components {
+		if _, ok := l.levels[name]; !ok {
+			c.SetLevel(level)
+		}
+	}
+}
+
+// SetComponentLevels sets the levels of the components, the other ones log at
+// the level of the logger.
+func (l *logger) SetComponentLevels(levels map[string]logrus.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.levels = make(map[string]logrus.Level, len(levels))
+	for name, level := range levels {
+		l.levels[name] = level
+	}
+	for name, c := range l.components {
+		c.SetLevel(l.componentLevel(name))
+	}
+}
+
+// componentLevel returns the level of the component. It must be called with
+// the mutex locked.
+func (l *logger) componentLevel(name string) logrus.Level {
+	if level, ok := l.levels[name]; ok {
+		return level
+	}
+	return l.Logger.GetLevel()
+}
+
+// component is the logger of a subsystem which adds the component field to
+// the log lines and has its own level.
+type component struct {
+	*logrus.Logger
+}
+
+func (c *component) NewEntry() *logrus.Entry {
+	return logrus.NewEntry(c.Logger)
+}
+
+// Component returns the logger of the named subsystem. Its log lines have the
+// component field and are filtered by the level of the component, if it is
+// set with SetComponentLevels. The logger is returned unchanged if it is not
+// created by New or if it is already the logger of a component.
+func Component(l Logger, name string) Logger {
+	root, ok := l.(*logger)
+	if !ok {
+		return l
+	}
+
+	root.mu.Lock()
+	defer root.mu.Unlock()
+
+	if c, ok := root.components[name]; ok {
+		return c
+	}
+	c := &component{Logger: &logrus.Logger{
+		Out:          root.Out,
+		Hooks:        root.Hooks,
+		Formatter:    &componentFormatter{name: name, formatter: root.Formatter},
+		ReportCaller: root.ReportCaller,
+		Level:        root.componentLevel(name),
+		ExitFunc:     root.ExitFunc,
+	}}
+	root.components[name] = c
+	return c
+}
+
+// componentFormatter adds the component field to the formatted entries.
+type componentFormatter struct {
+	name      string
+	formatter logrus.Formatter
+}
+
+func (f *componentFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	data := make(logrus.Fields, len(e.Data)+1)
+	for k, v := range e.Data {
+		data[k] = v
+	}
+	data[FieldComponent] = f.name
+
+	entry := *e
+	entry.Data = data
+	return f.formatter.Format(&entry)
+}
+
+// syncWriter serializes the writes of the logger and its components.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+func lines(t *testing.T, buf *bytes.Buffer) (entries []map[string]interface{}) {
+	t.Helper()
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var e map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	buf.Reset()
+	return entries
+}
+
+func TestComponentLevels(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := logging.New(buf, logrus.InfoLevel, logging.WithJSONFormat())
+	p2p := logging.Component(logger, "p2p")
+	api := logging.Component(logger, "api")
+
+	logger.(interface {
+		SetComponentLevels(map[string]logrus.Level)
+	}).SetComponentLevels(map[string]logrus.Level{
+		"p2p": logrus.DebugLevel,
+		"api": logrus.WarnLevel,
+	})
+
+	logger.Debug("root debug")
+	logger.Info("root info")
+	p2p.Debug("p2p debug")
+	api.Info("api info")
+	api.WithField(logging.FieldPeer, "abcd").Warning("api warning")
+
+	entries := lines(t, buf)
+	var got []string
+	for _, e := range entries {
+		got = append(got, e["msg"].(string))
+	}
+	want := []string{"root info", "p2p debug", "api warning"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got messages %v, want %v", got, want)
+	}
+	if _, ok := entries[0][logging.FieldComponent]; ok {
+		t.Fatal("component field in the root log line")
+	}
+	if c := entries[1][logging.FieldComponent]; c != "p2p" {
+		t.Fatalf("got component %v, want p2p", c)
+	}
+	if c, p := entries[2][logging.FieldComponent], entries[2][logging.FieldPeer]; c != "api" || p != "abcd" {
+		t.Fatalf("got component %v and peer %v, want api and abcd", c, p)
+	}
+
+	if logging.Component(logger, "p2p") != p2p {
+		t.Fatal("new logger for the same component")
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := logging.New(buf, logrus.InfoLevel, logging.WithJSONFormat())
+	l := logger.(interface {
+		SetLevel(logrus.Level)
+		SetComponentLevels(map[string]logrus.Level)
+	})
+	l.SetComponentLevels(map[string]logrus.Level{"api": logrus.ErrorLevel})
+	p2p := logging.Component(logger, "p2p")
+	api := logging.Component(logger, "api")
+
+	l.SetLevel(logrus.DebugLevel)
+	p2p.Debug("p2p debug")
+	api.Warning("api warning")
+
+	if entries := lines(t, buf); len(entries) != 1 || entries[0]["msg"] != "p2p debug" {
+		t.Fatalf("got log lines %v, want the p2p debug one", entries)
+	}
+}
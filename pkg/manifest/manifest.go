@@ -41,6 +41,10 @@ var (
 // the Store function.
 type StoreSizeFunc func(int64) error
 
+// EntryIterFunc is a callback on every file entry found by the
+// IterateEntries function.
+type EntryIterFunc func(path string, entry Entry) error
+
 // Interface for operations with manifest.
 type Interface interface {
 	// Type returns manifest implementation type information
@@ -58,6 +62,9 @@ type Interface interface {
 	// IterateAddresses is used to iterate over chunks addresses for
 	// the manifest.
 	IterateAddresses(context.Context, swarm.AddressIterFunc) error
+	// IterateEntries is used to iterate over all file entries in the
+	// manifest. The order of iteration is not specified.
+	IterateEntries(context.Context, EntryIterFunc) error
 }
 
 // Entry represents a single manifest entry.
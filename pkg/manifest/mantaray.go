@@ -179,6 +179,40 @@ func (m *mantarayManifest) IterateAddresses(ctx context.Context, fn swarm.Addres
 	return nil
 }
 
+func (m *mantarayManifest) IterateEntries(ctx context.Context, fn EntryIterFunc) error {
+	reference := swarm.NewAddress(m.trie.Reference())
+
+	if swarm.ZeroAddress.Equal(reference) {
+		return ErrMissingReference
+	}
+
+	emptyAddr := swarm.NewAddress([]byte{31: 0})
+	walker := func(path []byte, node *mantaray.Node, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if node == nil || !node.IsValueType() || len(node.Entry()) == 0 {
+			return nil
+		}
+
+		entry := swarm.NewAddress(node.Entry())
+		// the root path holds only metadata and points to an empty address
+		if entry.Equal(emptyAddr) {
+			return nil
+		}
+
+		return fn(string(path), NewEntry(entry, node.Metadata()))
+	}
+
+	err := m.trie.WalkNode(ctx, []byte{}, m.ls, walker)
+	if err != nil {
+		return fmt.Errorf("manifest iterate entries: %w", err)
+	}
+
+	return nil
+}
+
 type mantarayLoadSaver struct {
 	ls          file.LoadSaver
 	storeSizeFn []StoreSizeFunc
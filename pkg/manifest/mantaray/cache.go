@@ -0,0 +1,58 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mantaray
+
+// NodeCache keeps parsed nodes by their reference so that repeated traversals
+// do not have to load and unmarshal the same nodes again. The cached nodes
+// are never modified, loaded nodes receive copies of them.
+type NodeCache interface {
+	// Get returns the node parsed from the reference.
+	Get(reference []byte) (*Node, bool)
+	// Put adds the node parsed from the reference, size is the length of
+	// the serialised node.
+	Put(reference []byte, n *Node, size int)
+}
+
+// CachingLoader is a Loader that keeps the nodes it loads in a NodeCache.
+type CachingLoader interface {
+	Loader
+	NodeCache() NodeCache
+}
+
+// nodeCache returns the node cache of the loader if it has one.
+func nodeCache(l Loader) NodeCache {
+	if cl, ok := l.(CachingLoader); ok {
+		return cl.NodeCache()
+	}
+	return nil
+}
+
+// restore sets the fields of the node as UnmarshalBinary would do for the
+// data the cached node c was unmarshalled from.
+func (n *Node) restore(c *Node) {
+	n.obfuscationKey = append([]byte{}, c.obfuscationKey...)
+	if n.refBytesSize == 0 {
+		n.refBytesSize = c.refBytesSize
+	}
+	n.entry = append([]byte{}, c.entry...)
+	if c.nodeType == nodeTypeEdge {
+		n.nodeType = nodeTypeEdge
+	}
+	n.forks = make(map[byte]*fork, len(c.forks))
+	for b, f := range c.forks {
+		child := NewNodeRef(append([]byte{}, f.Node.ref...))
+		child.nodeType = f.Node.nodeType
+		if f.Node.metadata != nil {
+			child.metadata = make(map[string]string, len(f.Node.metadata))
+			for k, v := range f.Node.metadata {
+				child.metadata[k] = v
+			}
+		}
+		n.forks[b] = &fork{
+			prefix: append([]byte{}, f.prefix...),
+			Node:   child,
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mantaray_test
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/manifest/mantaray"
+)
+
+func TestNodeCache(t *testing.T) {
+	ctx := context.Background()
+	ls := newCachingLoadSaver()
+
+	n := mantaray.New()
+	paths := [][]byte{
+		[]byte("index.html"),
+		[]byte("img/1.png"),
+		[]byte("img/2.png"),
+		[]byte("docs/sub/a.txt"),
+	}
+	for _, c := range paths {
+		var v [32]byte
+		copy(v[:], c)
+		if err := n.Add(ctx, c, v[:], map[string]string{"Filename": string(c)}, ls); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if err := n.Save(ctx, ls); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	ref := n.Reference()
+
+	lookupAll := func(t *testing.T) {
+		t.Helper()
+		n := mantaray.NewNodeRef(ref)
+		for _, c := range paths {
+			node, err := n.LookupNode(ctx, c, ls)
+			if err != nil {
+				t.Fatalf("lookup %s: expected no error, got %v", c, err)
+			}
+			var v [32]byte
+			copy(v[:], c)
+			if !bytes.Equal(node.Entry(), v[:]) {
+				t.Fatalf("lookup %s: expected value %x, got %x", c, v[:], node.Entry())
+			}
+			if got := node.Metadata()["Filename"]; got != string(c) {
+				t.Fatalf("lookup %s: expected filename %s, got %s", c, c, got)
+			}
+		}
+	}
+
+	lookupAll(t)
+	if ls.loads == 0 {
+		t.Fatal("expected nodes to be loaded")
+	}
+	cached := len(ls.nodes)
+
+	ls.loads = 0
+	lookupAll(t)
+	if ls.loads != 0 {
+		t.Fatalf("expected cached nodes, got %d loads", ls.loads)
+	}
+
+	// modifications of a trie loaded from the cache must not change the
+	// cached nodes
+	m := mantaray.NewNodeRef(ref)
+	for _, c := range paths {
+		if err := m.Remove(ctx, c, ls); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if err := m.Save(ctx, ls); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	lookupAll(t)
+	if len(ls.nodes) != cached {
+		t.Fatalf("expected %d cached nodes, got %d", cached, len(ls.nodes))
+	}
+}
+
+// cachingLoadSaver is a mockLoadSaver which keeps the loaded nodes in a map.
+type cachingLoadSaver struct {
+	*mockLoadSaver
+	mtx   sync.Mutex
+	nodes map[string]*mantaray.Node
+	loads int
+}
+
+func newCachingLoadSaver() *cachingLoadSaver {
+	return &cachingLoadSaver{
+		mockLoadSaver: newMockLoadSaver(),
+		nodes:         make(map[string]*mantaray.Node),
+	}
+}
+
+func (c *cachingLoadSaver) Load(ctx context.Context, ref []byte) ([]byte, error) {
+	c.mtx.Lock()
+	c.loads++
+	c.mtx.Unlock()
+	return c.mockLoadSaver.Load(ctx, ref)
+}
+
+func (c *cachingLoadSaver) NodeCache() mantaray.NodeCache {
+	return c
+}
+
+func (c *cachingLoadSaver) Get(ref []byte) (*mantaray.Node, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	n, ok := c.nodes[string(ref)]
+	return n, ok
+}
+
+func (c *cachingLoadSaver) Put(ref []byte, n *mantaray.Node, _ int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.nodes[string(ref)] = n
+}
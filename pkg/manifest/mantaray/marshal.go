@@ -240,6 +240,10 @@ func (n *Node) UnmarshalBinary(data []byte) error {
 	if bytes.Equal(versionHash, version01HashBytes) {
 
 		refBytesSize := int(data[nodeHeaderSize-1])
+		if n.refBytesSize == 0 {
+			// keep the reference size when the node is saved again
+			n.refBytesSize = refBytesSize
+		}
 
 		n.entry = append([]byte{}, data[nodeHeaderSize:nodeHeaderSize+refBytesSize]...)
 		offset := nodeHeaderSize + refBytesSize // skip entry
@@ -267,6 +271,10 @@ func (n *Node) UnmarshalBinary(data []byte) error {
 	} else if bytes.Equal(versionHash, version02HashBytes) {
 
 		refBytesSize := int(data[nodeHeaderSize-1])
+		if n.refBytesSize == 0 {
+			// keep the reference size when the node is saved again
+			n.refBytesSize = refBytesSize
+		}
 
 		n.entry = append([]byte{}, data[nodeHeaderSize:nodeHeaderSize+refBytesSize]...)
 		offset := nodeHeaderSize + refBytesSize // skip entry
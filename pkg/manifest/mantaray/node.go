@@ -314,9 +314,15 @@ func (n *Node) Remove(ctx context.Context, path []byte, ls LoadSaver) error {
 	if len(rest) == 0 {
 		// full path matched
 		delete(n.forks, path[0])
+		n.ref = nil
 		return nil
 	}
-	return f.Node.Remove(ctx, rest, ls)
+	if err := f.Node.Remove(ctx, rest, ls); err != nil {
+		return err
+	}
+	// the node has changed and needs to be saved again
+	n.ref = nil
+	return nil
 }
 
 func common(a, b []byte) (c []byte) {
@@ -45,11 +45,30 @@ func (n *Node) load(ctx context.Context, l Loader) error {
 	if l == nil {
 		return ErrNoLoader
 	}
+	cache := nodeCache(l)
+	if cache == nil {
+		b, err := l.Load(ctx, n.ref)
+		if err != nil {
+			return err
+		}
+		return n.UnmarshalBinary(b)
+	}
+	if c, ok := cache.Get(n.ref); ok {
+		n.restore(c)
+		return nil
+	}
 	b, err := l.Load(ctx, n.ref)
 	if err != nil {
 		return err
 	}
-	return n.UnmarshalBinary(b)
+	// unmarshal into a separate node which is kept unmodified in the cache
+	c := &Node{}
+	if err := c.UnmarshalBinary(b); err != nil {
+		return err
+	}
+	cache.Put(n.ref, c, len(b))
+	n.restore(c)
+	return nil
 }
 
 // Save persists a trie recursively  traversing the nodes
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"errors"
 	"sync"
 	"testing"
 
@@ -60,6 +61,49 @@ func TestPersistIdempotence(t *testing.T) {
 	}
 }
 
+func TestPersistRemove(t *testing.T) {
+	ctx := context.Background()
+	var ls mantaray.LoadSaver = newMockLoadSaver()
+
+	n := mantaray.New()
+	paths := [][]byte{
+		[]byte("a.txt"),
+		[]byte("docs/b.txt"),
+		[]byte("docs/sub/c.txt"),
+	}
+	for _, c := range paths {
+		var v [32]byte
+		copy(v[:], c)
+		if err := n.Add(ctx, c, v[:], nil, ls); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if err := n.Save(ctx, ls); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for i, c := range paths {
+		// load the manifest from its reference on every removal
+		n = mantaray.NewNodeRef(n.Reference())
+		if err := n.Remove(ctx, c, ls); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if err := n.Save(ctx, ls); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		n = mantaray.NewNodeRef(n.Reference())
+		if _, err := n.Lookup(ctx, c, ls); !errors.Is(err, mantaray.ErrNotFound) {
+			t.Fatalf("expected %v, got %v", mantaray.ErrNotFound, err)
+		}
+		for _, rest := range paths[i+1:] {
+			if _, err := n.Lookup(ctx, rest, ls); err != nil {
+				t.Fatalf("lookup %s: expected no error, got %v", rest, err)
+			}
+		}
+	}
+}
+
 type addr [32]byte
 type mockLoadSaver struct {
 	mtx   sync.Mutex
@@ -0,0 +1,66 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodecache
+
+import (
+	m "github.com/ethsana/sana/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	Hits          prometheus.Counter
+	Misses        prometheus.Counter
+	Evictions     prometheus.Counter
+	Invalidations prometheus.Counter
+	Nodes         prometheus.Gauge
+	Bytes         prometheus.Gauge
+}
+
+func newMetrics() metrics {
+	subsystem := "manifest_node_cache"
+
+	return metrics{
+		Hits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "hits",
+			Help:      "Number of manifest nodes found in the cache.",
+		}),
+		Misses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "misses",
+			Help:      "Number of manifest nodes not found in the cache.",
+		}),
+		Evictions: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "evictions",
+			Help:      "Number of manifest nodes evicted to stay within the capacity.",
+		}),
+		Invalidations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "invalidations",
+			Help:      "Number of manifest nodes removed because their content was un-pinned.",
+		}),
+		Nodes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "nodes",
+			Help:      "Number of cached manifest nodes.",
+		}),
+		Bytes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "bytes",
+			Help:      "Serialised size of the cached manifest nodes.",
+		}),
+	}
+}
+
+func (c *Cache) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(c.metrics)
+}
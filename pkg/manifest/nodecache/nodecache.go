@@ -0,0 +1,159 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package nodecache provides a size bounded in-memory cache of parsed
+// manifest nodes. Busy gateways traverse the same intermediate manifest nodes
+// on almost every request and the cache saves loading and unmarshalling them
+// over and over again.
+package nodecache
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/ethsana/sana/pkg/file"
+	"github.com/ethsana/sana/pkg/manifest/mantaray"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+// Cache is a least recently used cache of parsed manifest nodes. The size of
+// the cache is accounted as the length of the serialised nodes.
+type Cache struct {
+	mu       sync.Mutex
+	capacity int64
+	size     int64
+	items    map[string]*list.Element
+	lru      *list.List
+	metrics  metrics
+}
+
+type entry struct {
+	key  string
+	ref  []byte
+	node *mantaray.Node
+	size int64
+}
+
+var _ mantaray.NodeCache = (*Cache)(nil)
+
+// New creates a new cache which keeps at most capacity bytes of nodes.
+func New(capacity int64) *Cache {
+	return &Cache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+		metrics:  newMetrics(),
+	}
+}
+
+// key returns the cache key of the reference, which is the address of the
+// root chunk of the node so that the node can be invalidated by the chunk
+// address also for encrypted references.
+func key(ref []byte) string {
+	if len(ref) > swarm.HashSize {
+		ref = ref[:swarm.HashSize]
+	}
+	return string(ref)
+}
+
+// Get implements mantaray.NodeCache.
+func (c *Cache) Get(ref []byte) (*mantaray.Node, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key(ref)]
+	if !ok || string(el.Value.(*entry).ref) != string(ref) {
+		c.metrics.Misses.Inc()
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	c.metrics.Hits.Inc()
+	return el.Value.(*entry).node, true
+}
+
+// Put implements mantaray.NodeCache. Nodes larger than the capacity are not
+// cached.
+func (c *Cache) Put(ref []byte, n *mantaray.Node, size int) {
+	if int64(size) > c.capacity {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	k := key(ref)
+	if el, ok := c.items[k]; ok {
+		c.remove(el)
+	}
+	c.items[k] = c.lru.PushFront(&entry{
+		key:  k,
+		ref:  append([]byte{}, ref...),
+		node: n,
+		size: int64(size),
+	})
+	c.size += int64(size)
+
+	for c.size > c.capacity {
+		c.remove(c.lru.Back())
+		c.metrics.Evictions.Inc()
+	}
+	c.updateGauges()
+}
+
+// Invalidate removes the node stored under the chunk address. It is called for
+// every chunk of un-pinned content.
+func (c *Cache) Invalidate(addr swarm.Address) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key(addr.Bytes())]
+	if !ok {
+		return
+	}
+	c.remove(el)
+	c.metrics.Invalidations.Inc()
+	c.updateGauges()
+}
+
+// Len returns the number of cached nodes.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// Size returns the sum of the serialised sizes of the cached nodes.
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
+
+func (c *Cache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*entry)
+	delete(c.items, e.key)
+	c.size -= e.size
+}
+
+func (c *Cache) updateGauges() {
+	c.metrics.Nodes.Set(float64(c.lru.Len()))
+	c.metrics.Bytes.Set(float64(c.size))
+}
+
+// LoadSaver returns a load saver which keeps the manifest nodes it loads in
+// the cache.
+func (c *Cache) LoadSaver(ls file.LoadSaver) file.LoadSaver {
+	return &loadSaver{LoadSaver: ls, cache: c}
+}
+
+type loadSaver struct {
+	file.LoadSaver
+	cache *Cache
+}
+
+func (ls *loadSaver) NodeCache() mantaray.NodeCache {
+	return ls.cache
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodecache_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/file/loadsave"
+	"github.com/ethsana/sana/pkg/manifest"
+	"github.com/ethsana/sana/pkg/manifest/mantaray"
+	"github.com/ethsana/sana/pkg/manifest/nodecache"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/swarm/test"
+)
+
+func TestCache(t *testing.T) {
+	c := nodecache.New(100)
+
+	a, b := test.RandomAddress(), test.RandomAddress()
+	na, nb := mantaray.New(), mantaray.New()
+
+	c.Put(a.Bytes(), na, 60)
+	if n, ok := c.Get(a.Bytes()); !ok || n != na {
+		t.Fatal("expected cached node")
+	}
+
+	// adding b exceeds the capacity and evicts a
+	c.Put(b.Bytes(), nb, 60)
+	if _, ok := c.Get(a.Bytes()); ok {
+		t.Fatal("expected evicted node")
+	}
+	if n, ok := c.Get(b.Bytes()); !ok || n != nb {
+		t.Fatal("expected cached node")
+	}
+	if c.Len() != 1 || c.Size() != 60 {
+		t.Fatalf("got %d nodes of %d bytes, want 1 node of 60 bytes", c.Len(), c.Size())
+	}
+
+	// nodes larger than the capacity are not cached
+	c.Put(a.Bytes(), na, 101)
+	if _, ok := c.Get(a.Bytes()); ok {
+		t.Fatal("expected node not to be cached")
+	}
+
+	c.Invalidate(b)
+	if _, ok := c.Get(b.Bytes()); ok {
+		t.Fatal("expected invalidated node")
+	}
+	if c.Len() != 0 || c.Size() != 0 {
+		t.Fatalf("got %d nodes of %d bytes, want empty cache", c.Len(), c.Size())
+	}
+}
+
+func TestCacheEncryptedReference(t *testing.T) {
+	c := nodecache.New(100)
+
+	addr := test.RandomAddress()
+	ref := append(addr.Bytes(), bytes.Repeat([]byte{1}, swarm.HashSize)...)
+	n := mantaray.New()
+
+	c.Put(ref, n, 10)
+	if _, ok := c.Get(addr.Bytes()); ok {
+		t.Fatal("expected no node for a different reference")
+	}
+	if got, ok := c.Get(ref); !ok || got != n {
+		t.Fatal("expected cached node")
+	}
+
+	c.Invalidate(addr)
+	if _, ok := c.Get(ref); ok {
+		t.Fatal("expected invalidated node")
+	}
+}
+
+func TestLoadSaver(t *testing.T) {
+	ctx := context.Background()
+	storer := mock.NewStorer()
+
+	m, err := manifest.NewDefaultManifest(loadsave.New(storer, storage.ModePutUpload, false), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{"index.html", "img/1.png", "img/2.png"}
+	for _, p := range paths {
+		if err := m.Add(ctx, p, manifest.NewEntry(test.RandomAddress(), nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ref, err := m.Store(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := nodecache.New(1 << 20)
+	lookup := func() {
+		t.Helper()
+		m, err := manifest.NewDefaultManifestReference(ref, c.LoadSaver(loadsave.New(storer, storage.ModePutRequest, false)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range paths {
+			if _, err := m.Lookup(ctx, p); err != nil {
+				t.Fatalf("lookup %s: %v", p, err)
+			}
+		}
+	}
+
+	lookup()
+	cached := c.Len()
+	if cached == 0 {
+		t.Fatal("expected cached nodes")
+	}
+	lookup()
+	if c.Len() != cached {
+		t.Fatalf("got %d cached nodes, want %d", c.Len(), cached)
+	}
+
+	c.Invalidate(ref)
+	if c.Len() != cached-1 {
+		t.Fatalf("got %d cached nodes, want %d", c.Len(), cached-1)
+	}
+}
@@ -0,0 +1,554 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package manifestfs exposes the entries of a manifest as a filesystem which
+// can be mounted with FUSE. Files are read lazily from the swarm. With
+// writeback enabled, modifications are kept in temporary files until they
+// are published as a new manifest.
+package manifestfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ethsana/sana/pkg/file/joiner"
+	"github.com/ethsana/sana/pkg/file/loadsave"
+	"github.com/ethsana/sana/pkg/file/pipeline/builder"
+	"github.com/ethsana/sana/pkg/manifest"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+// RootID is the inode number of the filesystem root directory.
+const RootID = 1
+
+// Attr holds the attributes of a file or a directory.
+type Attr struct {
+	Ino     uint64
+	Size    int64
+	Dir     bool
+	Mode    os.FileMode
+	ModTime time.Time
+}
+
+// DirEntry is a single entry of a directory listing.
+type DirEntry struct {
+	Ino  uint64
+	Name string
+	Dir  bool
+}
+
+// Server serves a mounted FS.
+type Server interface {
+	// Wait blocks until the filesystem is unmounted.
+	Wait()
+	// Unmount unmounts the filesystem.
+	Unmount() error
+}
+
+// Options configure the FS.
+type Options struct {
+	// Writeback allows modifications which are published by Publish.
+	Writeback bool
+	// TempDir holds the content of modified files, the default temporary
+	// directory if empty.
+	TempDir string
+}
+
+// FS is a filesystem with the content of a manifest. Its files and
+// directories are identified by their inode numbers, errors of type
+// syscall.Errno are reported by Mount as they are. Modifications fail with
+// EROFS unless writeback is enabled.
+type FS struct {
+	ctx       context.Context
+	storer    loadsave.PutGetter
+	reference swarm.Address
+	writeback bool
+	tempDir   string
+	modTime   time.Time
+
+	mu           sync.Mutex
+	nodes        map[uint64]*node
+	root         *node
+	nextIno      uint64
+	rootMetadata map[string]string
+	modified     bool
+}
+
+type node struct {
+	ino      uint64
+	dir      bool
+	children map[string]*node
+
+	reference swarm.Address
+	metadata  map[string]string
+	size      int64 // -1 until the file is first accessed
+	modTime   time.Time
+	reader    io.ReaderAt
+	local     *os.File // modified content
+}
+
+// New loads the manifest with the reference and creates its filesystem.
+func New(ctx context.Context, storer loadsave.PutGetter, reference swarm.Address, o Options) (*FS, error) {
+	m, err := manifest.NewDefaultManifestReference(reference, loadsave.New(storer, storage.ModePutUpload, false))
+	if err != nil {
+		return nil, fmt.Errorf("load manifest: %w", err)
+	}
+
+	f := &FS{
+		ctx:       ctx,
+		storer:    storer,
+		reference: reference,
+		writeback: o.Writeback,
+		tempDir:   o.TempDir,
+		modTime:   time.Now(),
+		nodes:     make(map[uint64]*node),
+		nextIno:   RootID,
+	}
+	f.root = f.newNode(true)
+
+	rootEntry, err := m.Lookup(ctx, manifest.RootPath)
+	switch {
+	case err == nil:
+		f.rootMetadata = rootEntry.Metadata()
+	case errors.Is(err, manifest.ErrNotFound):
+	default:
+		return nil, fmt.Errorf("lookup root metadata: %w", err)
+	}
+
+	err = m.IterateEntries(ctx, func(p string, entry manifest.Entry) error {
+		dir := f.root
+		parts := strings.Split(p, "/")
+		for i, name := range parts {
+			if name == "" {
+				continue
+			}
+			child, ok := dir.children[name]
+			if i == len(parts)-1 {
+				if ok {
+					return nil
+				}
+				child = f.newNode(false)
+				child.reference = entry.Reference()
+				child.metadata = entry.Metadata()
+				dir.children[name] = child
+				return nil
+			}
+			if !ok {
+				child = f.newNode(true)
+				dir.children[name] = child
+			}
+			if !child.dir {
+				return fmt.Errorf("path %q is both a file and a directory", p)
+			}
+			dir = child
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FS) newNode(dir bool) *node {
+	n := &node{
+		ino:     f.nextIno,
+		dir:     dir,
+		size:    -1,
+		modTime: f.modTime,
+	}
+	if dir {
+		n.children = make(map[string]*node)
+		n.size = 0
+	}
+	f.nextIno++
+	f.nodes[n.ino] = n
+	return n
+}
+
+// Lookup returns the attributes of the named entry in the parent directory.
+func (f *FS) Lookup(parent uint64, name string) (Attr, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	dir, err := f.dir(parent)
+	if err != nil {
+		return Attr{}, err
+	}
+	n, ok := dir.children[name]
+	if !ok {
+		return Attr{}, syscall.ENOENT
+	}
+	return f.attr(n)
+}
+
+// GetAttr returns the attributes of the inode.
+func (f *FS) GetAttr(ino uint64) (Attr, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n, ok := f.nodes[ino]
+	if !ok {
+		return Attr{}, syscall.ENOENT
+	}
+	return f.attr(n)
+}
+
+// ReadDir returns the entries of the directory sorted by name.
+func (f *FS) ReadDir(ino uint64) ([]DirEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	dir, err := f.dir(ino)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]DirEntry, 0, len(dir.children))
+	for name, n := range dir.children {
+		entries = append(entries, DirEntry{Ino: n.ino, Name: name, Dir: n.dir})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// Read reads the file content at the offset.
+func (f *FS) Read(ino uint64, p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n, err := f.file(ino)
+	if err != nil {
+		return 0, err
+	}
+	if n.local != nil {
+		return n.local.ReadAt(p, off)
+	}
+	if err := f.open(n); err != nil {
+		return 0, err
+	}
+	if off >= n.size {
+		return 0, io.EOF
+	}
+	return n.reader.ReadAt(p, off)
+}
+
+// Create creates an empty file in the parent directory.
+func (f *FS) Create(parent uint64, name string) (Attr, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	dir, err := f.create(parent, name)
+	if err != nil {
+		return Attr{}, err
+	}
+	local, err := ioutil.TempFile(f.tempDir, "swarm-mount-")
+	if err != nil {
+		return Attr{}, err
+	}
+	n := f.newNode(false)
+	n.local = local
+	n.size = 0
+	n.modTime = time.Now()
+	n.metadata = map[string]string{
+		manifest.EntryMetadataFilenameKey: name,
+	}
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		n.metadata[manifest.EntryMetadataContentTypeKey] = ct
+	}
+	dir.children[name] = n
+	f.modified = true
+	return f.attr(n)
+}
+
+// Mkdir creates a directory in the parent directory. Directories without
+// files are not preserved by Publish as manifests have no directory entries.
+func (f *FS) Mkdir(parent uint64, name string) (Attr, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	dir, err := f.create(parent, name)
+	if err != nil {
+		return Attr{}, err
+	}
+	n := f.newNode(true)
+	n.modTime = time.Now()
+	dir.children[name] = n
+	return f.attr(n)
+}
+
+// Write writes to the file at the offset.
+func (f *FS) Write(ino uint64, p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.writeback {
+		return 0, syscall.EROFS
+	}
+	n, err := f.file(ino)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.materialize(n); err != nil {
+		return 0, err
+	}
+	written, err := n.local.WriteAt(p, off)
+	if end := off + int64(written); end > n.size {
+		n.size = end
+	}
+	n.modTime = time.Now()
+	f.modified = true
+	return written, err
+}
+
+// Truncate changes the size of the file.
+func (f *FS) Truncate(ino uint64, size int64) (Attr, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.writeback {
+		return Attr{}, syscall.EROFS
+	}
+	n, err := f.file(ino)
+	if err != nil {
+		return Attr{}, err
+	}
+	if err := f.materialize(n); err != nil {
+		return Attr{}, err
+	}
+	if err := n.local.Truncate(size); err != nil {
+		return Attr{}, err
+	}
+	n.size = size
+	n.modTime = time.Now()
+	f.modified = true
+	return f.attr(n)
+}
+
+// Remove removes the named file or empty directory from the parent
+// directory.
+func (f *FS) Remove(parent uint64, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.writeback {
+		return syscall.EROFS
+	}
+	dir, err := f.dir(parent)
+	if err != nil {
+		return err
+	}
+	n, ok := dir.children[name]
+	if !ok {
+		return syscall.ENOENT
+	}
+	if n.dir && len(n.children) > 0 {
+		return syscall.ENOTEMPTY
+	}
+	delete(dir.children, name)
+	delete(f.nodes, n.ino)
+	f.discard(n)
+	f.modified = true
+	return nil
+}
+
+// Modified reports whether there are unpublished modifications.
+func (f *FS) Modified() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.modified
+}
+
+// Publish uploads the modified files and stores a new manifest with all
+// files. The root metadata of the original manifest is preserved. The
+// original reference is returned if nothing was modified.
+func (f *FS) Publish(ctx context.Context) (swarm.Address, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.modified {
+		return f.reference, nil
+	}
+
+	m, err := manifest.NewDefaultManifest(loadsave.New(f.storer, storage.ModePutUpload, false), false)
+	if err != nil {
+		return swarm.ZeroAddress, err
+	}
+	if len(f.rootMetadata) > 0 {
+		if err := m.Add(ctx, manifest.RootPath, manifest.NewEntry(swarm.ZeroAddress, f.rootMetadata)); err != nil {
+			return swarm.ZeroAddress, fmt.Errorf("add root metadata: %w", err)
+		}
+	}
+
+	var walk func(prefix string, dir *node) error
+	walk = func(prefix string, dir *node) error {
+		for name, n := range dir.children {
+			p := prefix + name
+			if n.dir {
+				if err := walk(p+"/", n); err != nil {
+					return err
+				}
+				continue
+			}
+			if n.local != nil {
+				if _, err := n.local.Seek(0, io.SeekStart); err != nil {
+					return err
+				}
+				pipe := builder.NewPipelineBuilder(ctx, f.storer, storage.ModePutUpload, false)
+				reference, err := builder.FeedPipeline(ctx, pipe, n.local)
+				if err != nil {
+					return fmt.Errorf("upload %s: %w", p, err)
+				}
+				n.reference = reference
+			}
+			if err := m.Add(ctx, p, manifest.NewEntry(n.reference, n.metadata)); err != nil {
+				return fmt.Errorf("add %s: %w", p, err)
+			}
+		}
+		return nil
+	}
+	if err := walk("", f.root); err != nil {
+		return swarm.ZeroAddress, err
+	}
+
+	reference, err := m.Store(ctx)
+	if err != nil {
+		return swarm.ZeroAddress, fmt.Errorf("store manifest: %w", err)
+	}
+	f.reference = reference
+	f.modified = false
+	return reference, nil
+}
+
+// Close removes the temporary files of modified files.
+func (f *FS) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, n := range f.nodes {
+		f.discard(n)
+	}
+	return nil
+}
+
+func (f *FS) attr(n *node) (Attr, error) {
+	mode := os.FileMode(0444)
+	if n.dir {
+		mode = 0555
+	}
+	if f.writeback {
+		mode |= 0200
+	}
+	if !n.dir {
+		if err := f.open(n); err != nil {
+			return Attr{}, err
+		}
+	}
+	return Attr{
+		Ino:     n.ino,
+		Size:    n.size,
+		Dir:     n.dir,
+		Mode:    mode,
+		ModTime: n.modTime,
+	}, nil
+}
+
+// open initializes the reader of an unmodified file.
+func (f *FS) open(n *node) error {
+	if n.reader != nil || n.local != nil {
+		return nil
+	}
+	r, size, err := joiner.New(f.ctx, f.storer, n.reference)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return syscall.ENOENT
+		}
+		return err
+	}
+	n.reader = r
+	n.size = size
+	return nil
+}
+
+// materialize copies the content of an unmodified file to a temporary file
+// so that it can be modified.
+func (f *FS) materialize(n *node) error {
+	if n.local != nil {
+		return nil
+	}
+	if err := f.open(n); err != nil {
+		return err
+	}
+	local, err := ioutil.TempFile(f.tempDir, "swarm-mount-")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(local, io.NewSectionReader(n.reader, 0, n.size)); err != nil {
+		local.Close()
+		os.Remove(local.Name())
+		return err
+	}
+	n.local = local
+	n.reader = nil
+	return nil
+}
+
+func (f *FS) discard(n *node) {
+	if n.local != nil {
+		n.local.Close()
+		os.Remove(n.local.Name())
+		n.local = nil
+	}
+}
+
+func (f *FS) dir(ino uint64) (*node, error) {
+	n, ok := f.nodes[ino]
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	if !n.dir {
+		return nil, syscall.ENOTDIR
+	}
+	return n, nil
+}
+
+func (f *FS) file(ino uint64) (*node, error) {
+	n, ok := f.nodes[ino]
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	if n.dir {
+		return nil, syscall.EISDIR
+	}
+	return n, nil
+}
+
+// create returns the parent directory for a new entry.
+func (f *FS) create(parent uint64, name string) (*node, error) {
+	if !f.writeback {
+		return nil, syscall.EROFS
+	}
+	dir, err := f.dir(parent)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := dir.children[name]; ok {
+		return nil, syscall.EEXIST
+	}
+	return dir, nil
+}
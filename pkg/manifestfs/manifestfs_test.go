@@ -0,0 +1,201 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifestfs_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/file/loadsave"
+	"github.com/ethsana/sana/pkg/file/pipeline/builder"
+	"github.com/ethsana/sana/pkg/manifest"
+	"github.com/ethsana/sana/pkg/manifestfs"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+func TestFS(t *testing.T) {
+	ctx := context.Background()
+	storer := mock.NewStorer()
+
+	reference := storeManifest(t, storer, map[string]string{
+		"index.html":   "<html></html>",
+		"docs/a.txt":   "first file",
+		"docs/b/c.txt": "second file",
+	})
+
+	f, err := manifestfs.New(ctx, storer, reference, manifestfs.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	t.Run("read", func(t *testing.T) {
+		expectDir(t, f, manifestfs.RootID, "docs", "index.html")
+		docs := lookup(t, f, manifestfs.RootID, "docs")
+		if !docs.Dir {
+			t.Fatal("docs is not a directory")
+		}
+		expectDir(t, f, docs.Ino, "a.txt", "b")
+		expectContent(t, f, docs.Ino, "a.txt", "first file")
+
+		a := lookup(t, f, docs.Ino, "a.txt")
+		p := make([]byte, 4)
+		n, err := f.Read(a.Ino, p, 6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(p[:n]); got != "file" {
+			t.Fatalf("got %q, want %q", got, "file")
+		}
+
+		if _, err := f.Lookup(docs.Ino, "missing"); !errors.Is(err, syscall.ENOENT) {
+			t.Fatalf("got error %v, want %v", err, syscall.ENOENT)
+		}
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		if _, err := f.Create(manifestfs.RootID, "new.txt"); !errors.Is(err, syscall.EROFS) {
+			t.Fatalf("got error %v, want %v", err, syscall.EROFS)
+		}
+		if err := f.Remove(manifestfs.RootID, "index.html"); !errors.Is(err, syscall.EROFS) {
+			t.Fatalf("got error %v, want %v", err, syscall.EROFS)
+		}
+		got, err := f.Publish(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(reference) {
+			t.Fatal("unmodified filesystem published a new manifest")
+		}
+	})
+
+	t.Run("writeback", func(t *testing.T) {
+		w, err := manifestfs.New(ctx, storer, reference, manifestfs.Options{Writeback: true, TempDir: t.TempDir()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer w.Close()
+
+		docs := lookup(t, w, manifestfs.RootID, "docs")
+		created, err := w.Create(docs.Ino, "new.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(created.Ino, []byte("new file"), 0); err != nil {
+			t.Fatal(err)
+		}
+
+		a := lookup(t, w, docs.Ino, "a.txt")
+		if _, err := w.Write(a.Ino, []byte("FIRST"), 0); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := w.Remove(docs.Ino, "b"); !errors.Is(err, syscall.ENOTEMPTY) {
+			t.Fatalf("got error %v, want %v", err, syscall.ENOTEMPTY)
+		}
+		if err := w.Remove(manifestfs.RootID, "index.html"); err != nil {
+			t.Fatal(err)
+		}
+
+		if !w.Modified() {
+			t.Fatal("filesystem is not modified")
+		}
+		published, err := w.Publish(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if published.Equal(reference) {
+			t.Fatal("modifications were not published")
+		}
+
+		p, err := manifestfs.New(ctx, storer, published, manifestfs.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+
+		expectDir(t, p, manifestfs.RootID, "docs")
+		docs = lookup(t, p, manifestfs.RootID, "docs")
+		expectDir(t, p, docs.Ino, "a.txt", "b", "new.txt")
+		expectContent(t, p, docs.Ino, "a.txt", "FIRST file")
+		expectContent(t, p, docs.Ino, "new.txt", "new file")
+	})
+}
+
+func storeManifest(t *testing.T, storer storage.Storer, files map[string]string) swarm.Address {
+	t.Helper()
+
+	ctx := context.Background()
+	m, err := manifest.NewDefaultManifest(loadsave.New(storer, storage.ModePutUpload, false), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, content := range files {
+		pipe := builder.NewPipelineBuilder(ctx, storer, storage.ModePutUpload, false)
+		reference, err := builder.FeedPipeline(ctx, pipe, strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Add(ctx, p, manifest.NewEntry(reference, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reference, err := m.Store(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reference
+}
+
+func lookup(t *testing.T, f *manifestfs.FS, parent uint64, name string) manifestfs.Attr {
+	t.Helper()
+
+	a, err := f.Lookup(parent, name)
+	if err != nil {
+		t.Fatalf("lookup %s: %v", name, err)
+	}
+	return a
+}
+
+func expectDir(t *testing.T, f *manifestfs.FS, ino uint64, names ...string) {
+	t.Helper()
+
+	entries, err := f.ReadDir(ino)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(names) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(names))
+	}
+	for i, e := range entries {
+		if e.Name != names[i] {
+			t.Fatalf("got entry %q, want %q", e.Name, names[i])
+		}
+	}
+}
+
+func expectContent(t *testing.T, f *manifestfs.FS, parent uint64, name, content string) {
+	t.Helper()
+
+	a := lookup(t, f, parent, name)
+	if a.Size != int64(len(content)) {
+		t.Fatalf("%s: got size %d, want %d", name, a.Size, len(content))
+	}
+	p := make([]byte, len(content)+10)
+	n, err := f.Read(a.Ino, p, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p[:n], []byte(content)) {
+		t.Fatalf("%s: got %q, want %q", name, p[:n], content)
+	}
+}
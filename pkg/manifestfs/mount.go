@@ -0,0 +1,218 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux || darwin
+// +build linux darwin
+
+package manifestfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// attrTimeout is the time for which the kernel caches the entries and the
+// attributes of the files.
+const attrTimeout = time.Second
+
+// Mount mounts the filesystem on the directory with go-fuse and serves it
+// until it is unmounted. Mounting directly is attempted first and for
+// unprivileged users the fusermount helper is used.
+func Mount(dir string, f *FS, logger logging.Logger) (Server, error) {
+	timeout := attrTimeout
+	server, err := fs.Mount(dir, newMountNode(f, logger, RootID), &fs.Options{
+		MountOptions: fuse.MountOptions{
+			FsName:      "swarm",
+			Name:        "swarm",
+			DirectMount: true,
+		},
+		EntryTimeout: &timeout,
+		AttrTimeout:  &timeout,
+		UID:          uint32(os.Getuid()),
+		GID:          uint32(os.Getgid()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// mountNode is the go-fuse inode of the file or the directory of the FS with
+// the inode number.
+type mountNode struct {
+	fs.Inode
+	f      *FS
+	logger logging.Logger
+	ino    uint64
+}
+
+var (
+	_ fs.NodeLookuper  = (*mountNode)(nil)
+	_ fs.NodeGetattrer = (*mountNode)(nil)
+	_ fs.NodeReaddirer = (*mountNode)(nil)
+	_ fs.NodeOpener    = (*mountNode)(nil)
+	_ fs.NodeReader    = (*mountNode)(nil)
+	_ fs.NodeWriter    = (*mountNode)(nil)
+	_ fs.NodeSetattrer = (*mountNode)(nil)
+	_ fs.NodeCreater   = (*mountNode)(nil)
+	_ fs.NodeMkdirer   = (*mountNode)(nil)
+	_ fs.NodeUnlinker  = (*mountNode)(nil)
+	_ fs.NodeRmdirer   = (*mountNode)(nil)
+)
+
+func newMountNode(f *FS, logger logging.Logger, ino uint64) *mountNode {
+	return &mountNode{f: f, logger: logger, ino: ino}
+}
+
+func (n *mountNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	a, err := n.f.Lookup(n.ino, name)
+	if err != nil {
+		return nil, n.errno(err)
+	}
+	return n.child(ctx, a, out), 0
+}
+
+func (n *mountNode) Getattr(ctx context.Context, _ fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	a, err := n.f.GetAttr(n.ino)
+	if err != nil {
+		return n.errno(err)
+	}
+	fillAttr(a, &out.Attr)
+	return 0
+}
+
+func (n *mountNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	entries, err := n.f.ReadDir(n.ino)
+	if err != nil {
+		return nil, n.errno(err)
+	}
+	list := make([]fuse.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		mode := uint32(syscall.S_IFREG)
+		if e.Dir {
+			mode = syscall.S_IFDIR
+		}
+		list = append(list, fuse.DirEntry{Mode: mode, Name: e.Name, Ino: e.Ino})
+	}
+	return fs.NewListDirStream(list), 0
+}
+
+// Open opens the file without a handle, the reads and the writes are served
+// by the inode.
+func (n *mountNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 && !n.f.writeback {
+		return nil, 0, syscall.EROFS
+	}
+	return nil, 0, 0
+}
+
+func (n *mountNode) Read(ctx context.Context, _ fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	read, err := n.f.Read(n.ino, dest, off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, n.errno(err)
+	}
+	return fuse.ReadResultData(dest[:read]), 0
+}
+
+func (n *mountNode) Write(ctx context.Context, _ fs.FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	written, err := n.f.Write(n.ino, data, off)
+	if err != nil {
+		return uint32(written), n.errno(err)
+	}
+	return uint32(written), 0
+}
+
+// Setattr supports only the truncation of the files, the other attributes
+// are not changed.
+func (n *mountNode) Setattr(ctx context.Context, _ fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	var (
+		a   Attr
+		err error
+	)
+	if size, ok := in.GetSize(); ok {
+		a, err = n.f.Truncate(n.ino, int64(size))
+	} else {
+		a, err = n.f.GetAttr(n.ino)
+	}
+	if err != nil {
+		return n.errno(err)
+	}
+	fillAttr(a, &out.Attr)
+	return 0
+}
+
+func (n *mountNode) Create(ctx context.Context, name string, flags, mode uint32, out *fuse.EntryOut) (*fs.Inode, fs.FileHandle, uint32, syscall.Errno) {
+	a, err := n.f.Create(n.ino, name)
+	if err != nil {
+		return nil, nil, 0, n.errno(err)
+	}
+	return n.child(ctx, a, out), nil, 0, 0
+}
+
+func (n *mountNode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	a, err := n.f.Mkdir(n.ino, name)
+	if err != nil {
+		return nil, n.errno(err)
+	}
+	return n.child(ctx, a, out), 0
+}
+
+func (n *mountNode) Unlink(ctx context.Context, name string) syscall.Errno {
+	return n.errno(n.f.Remove(n.ino, name))
+}
+
+func (n *mountNode) Rmdir(ctx context.Context, name string) syscall.Errno {
+	return n.errno(n.f.Remove(n.ino, name))
+}
+
+// child returns the inode of the entry with the attributes.
+func (n *mountNode) child(ctx context.Context, a Attr, out *fuse.EntryOut) *fs.Inode {
+	fillAttr(a, &out.Attr)
+	mode := uint32(syscall.S_IFREG)
+	if a.Dir {
+		mode = syscall.S_IFDIR
+	}
+	return n.NewInode(ctx, newMountNode(n.f, n.logger, a.Ino), fs.StableAttr{Mode: mode, Ino: a.Ino})
+}
+
+// errno returns the errors of type syscall.Errno as they are, ENOENT for
+// os.ErrNotExist and EIO for all other errors.
+func (n *mountNode) errno(err error) syscall.Errno {
+	if err == nil {
+		return 0
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return errno
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return syscall.ENOENT
+	}
+	n.logger.Debugf("manifestfs: inode %d: %v", n.ino, err)
+	n.logger.Error("manifestfs: filesystem operation failed")
+	return syscall.EIO
+}
+
+func fillAttr(a Attr, out *fuse.Attr) {
+	out.Ino = a.Ino
+	out.Size = uint64(a.Size)
+	out.Blocks = (out.Size + 511) / 512
+	out.Mode = uint32(a.Mode.Perm())
+	out.Nlink = 1
+	if a.Dir {
+		out.Mode |= syscall.S_IFDIR
+		out.Nlink = 2
+	} else {
+		out.Mode |= syscall.S_IFREG
+	}
+	out.SetTimes(&a.ModTime, &a.ModTime, &a.ModTime)
+}
@@ -0,0 +1,23 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package manifestfs
+
+import (
+	"errors"
+
+	"github.com/ethsana/sana/pkg/logging"
+)
+
+// ErrUnsupported is returned when mounting on platforms other than linux and
+// darwin.
+var ErrUnsupported = errors.New("fuse mounts are only supported on linux and darwin")
+
+// Mount is not supported on this platform.
+func Mount(dir string, f *FS, logger logging.Logger) (Server, error) {
+	return nil, ErrUnsupported
+}
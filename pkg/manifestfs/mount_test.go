@@ -0,0 +1,111 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package manifestfs_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/manifestfs"
+	"github.com/ethsana/sana/pkg/storage/mock"
+)
+
+func TestMount(t *testing.T) {
+	ctx := context.Background()
+	storer := mock.NewStorer()
+	reference := storeManifest(t, storer, map[string]string{
+		"index.html": "<html></html>",
+		"docs/a.txt": "first file",
+	})
+
+	f, err := manifestfs.New(ctx, storer, reference, manifestfs.Options{Writeback: true, TempDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	dir := t.TempDir()
+	server, err := manifestfs.Mount(dir, f, logging.New(ioutil.Discard, 0))
+	if err != nil {
+		// mounting requires the fuse device and the permission to mount
+		t.Skipf("mount: %v", err)
+	}
+	unmounted := false
+	defer func() {
+		if !unmounted {
+			_ = server.Unmount()
+		}
+	}()
+
+	names := func(p string) string {
+		t.Helper()
+		infos, err := ioutil.ReadDir(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		sort.Strings(names)
+		return strings.Join(names, ",")
+	}
+	if got := names(dir); got != "docs,index.html" {
+		t.Fatalf("got entries %s", got)
+	}
+	got, err := ioutil.ReadFile(filepath.Join(dir, "docs", "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "first file" {
+		t.Fatalf("got content %q", got)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "docs", "a.txt"), []byte("FIRST"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "docs", "new.txt"), []byte("new file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "index.html")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "docs")); !errors.Is(err, syscall.ENOTEMPTY) {
+		t.Fatalf("got error %v, want %v", err, syscall.ENOTEMPTY)
+	}
+
+	if err := server.Unmount(); err != nil {
+		t.Fatal(err)
+	}
+	unmounted = true
+	server.Wait()
+
+	published, err := f.Publish(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := manifestfs.New(ctx, storer, published, manifestfs.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	expectDir(t, p, manifestfs.RootID, "docs")
+	docs := lookup(t, p, manifestfs.RootID, "docs")
+	expectDir(t, p, docs.Ino, "a.txt", "new.txt")
+	expectContent(t, p, docs.Ino, "a.txt", "FIRST")
+	expectContent(t, p, docs.Ino, "new.txt", "new file")
+}
@@ -0,0 +1,13 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mine
+
+import "time"
+
+var NewMetrics = newMetrics
+
+func TEEStatusValue(check func() bool, interval time.Duration) func() float64 {
+	return newTEEStatus(check, interval).value
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mine
+
+import (
+	"sync"
+	"time"
+
+	tee "github.com/ethsana/sana-tee"
+	m "github.com/ethsana/sana/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	// all metrics fields must be exported
+	// to be able to return them by Metrics()
+	// using reflection
+	Rounds          prometheus.Counter
+	RoundFailures   prometheus.Counter
+	Activations     prometheus.Counter
+	ProofsSubmitted prometheus.Counter
+	ProofFailures   prometheus.Counter
+	Working         prometheus.Gauge
+	Trust           prometheus.Gauge
+	Reward          prometheus.Gauge
+}
+
+func newMetrics() metrics {
+	subsystem := "mine"
+
+	return metrics{
+		Rounds: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "rounds",
+			Help:      "Total mining rounds attempted.",
+		}),
+		RoundFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "round_failures",
+			Help:      "Total mining rounds which failed to confirm that the node is working.",
+		}),
+		Activations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "activations",
+			Help:      "Total activations of the node in the mine contract.",
+		}),
+		ProofsSubmitted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "proofs_submitted",
+			Help:      "Total signed roll call proofs submitted to the trust nodes.",
+		}),
+		ProofFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "proof_failures",
+			Help:      "Total roll call proofs which could not be submitted.",
+		}),
+		Working: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "working",
+			Help:      "Whether the node is working in the mine contract (1) or not (0).",
+		}),
+		Trust: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "trust",
+			Help:      "Whether the node is a trust node (1) or not (0).",
+		}),
+		Reward: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "reward",
+			Help:      "Reward earned by the node in the mine contract, in the smallest token unit.",
+		}),
+	}
+}
+
+func (s *service) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(s.metrics)
+}
+
+// TEEMetrics returns the collectors of the TEE environment status. They are
+// reported whether the miner is enabled or not, as a node without a working
+// TEE environment cannot run on the main network.
+func TEEMetrics() []prometheus.Collector {
+	status := newTEEStatus(tee.Ok, teeStatusInterval)
+	return []prometheus.Collector{
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: "tee",
+			Name:      "ok",
+			Help:      "Whether the TEE environment is prepared (1) or not (0).",
+		}, status.value),
+	}
+}
+
+// teeStatusInterval is the interval in which the status of the TEE
+// environment is checked for the metrics, instead of on every scrape.
+const teeStatusInterval = time.Minute
+
+// teeStatus caches the status of the TEE environment.
+type teeStatus struct {
+	check    func() bool
+	interval time.Duration
+
+	mu      sync.Mutex
+	ok      bool
+	checked time.Time
+}
+
+func newTEEStatus(check func() bool, interval time.Duration) *teeStatus {
+	return &teeStatus{check: check, interval: interval}
+}
+
+func (s *teeStatus) value() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.checked.IsZero() || time.Since(s.checked) >= s.interval {
+		s.ok = s.check()
+		s.checked = time.Now()
+	}
+	return boolToFloat(s.ok)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
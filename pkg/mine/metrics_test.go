@@ -0,0 +1,49 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mine_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	m "github.com/ethsana/sana/pkg/metrics"
+	"github.com/ethsana/sana/pkg/mine"
+)
+
+func TestMetricsExported(t *testing.T) {
+	metrics := mine.NewMetrics()
+	if got, want := len(m.PrometheusCollectorsFromFields(metrics)), reflect.TypeOf(metrics).NumField(); got != want {
+		t.Fatalf("got %d collectors, want %d", got, want)
+	}
+}
+
+func TestTEEStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		interval  time.Duration
+		wantCalls int
+	}{
+		{name: "cached", interval: time.Hour, wantCalls: 1},
+		{name: "expired", interval: 0, wantCalls: 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			value := mine.TEEStatusValue(func() bool {
+				calls++
+				return true
+			}, tc.interval)
+
+			for i := 0; i < 3; i++ {
+				if got := value(); got != 1 {
+					t.Fatalf("got %v, want 1", got)
+				}
+			}
+			if calls != tc.wantCalls {
+				t.Fatalf("got %d checks, want %d", calls, tc.wantCalls)
+			}
+		})
+	}
+}
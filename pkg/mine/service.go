@@ -16,6 +16,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	tee "github.com/ethsana/sana-tee"
 	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/events"
 	"github.com/ethsana/sana/pkg/logging"
 	"github.com/ethsana/sana/pkg/sctx"
 	"github.com/ethsana/sana/pkg/settlement/swap/erc20"
@@ -65,13 +66,17 @@ type service struct {
 	oracle   Oracle
 	logger   logging.Logger
 
-	opt *Options
+	opt     *Options
+	metrics metrics
 
 	device *tee.Device
 
 	height    uint64
 	heightMtx sync.Mutex
 
+	// reward is the last reward reported by the mine contract
+	reward *big.Int
+
 	rcnc chan rcn
 	quit chan struct{}
 	wg   sync.WaitGroup
@@ -83,6 +88,8 @@ type Options struct {
 	TransactionService transaction.Service
 	OverlayEthAddress  common.Address
 	DeployGasPrice     string
+	// Events, if set, is notified when the reward increases.
+	Events events.Publisher
 }
 
 type rcn struct {
@@ -125,6 +132,7 @@ func NewService(
 		logger:   logger,
 		device:   device,
 		opt:      &opt,
+		metrics:  newMetrics(),
 		rcnc:     make(chan rcn, 1024),
 		quit:     make(chan struct{}),
 	}
@@ -485,7 +493,34 @@ func (s *service) checkWorkingWorker() (bool, error) {
 	}
 
 	err = s.contract.WaitForActive(ctx, hash)
-	return err == nil, err
+	if err != nil {
+		return false, err
+	}
+	s.metrics.Activations.Inc()
+	return true, nil
+}
+
+// updateRewardMetrics reports the reward earned by the node and publishes the
+// increase since the last update.
+func (s *service) updateRewardMetrics() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*20)
+	defer cancel()
+
+	reward, err := s.contract.Reward(ctx, common.BytesToHash(s.base.Bytes()))
+	if err != nil {
+		return err
+	}
+	f, _ := new(big.Float).SetInt(reward).Float64()
+	s.metrics.Reward.Set(f)
+
+	if s.reward != nil && reward.Cmp(s.reward) > 0 && s.opt.Events != nil {
+		s.opt.Events.Publish(events.MineReward, events.RewardData{
+			Reward:   reward.String(),
+			Increase: new(big.Int).Sub(reward, s.reward).String(),
+		})
+	}
+	s.reward = reward
+	return nil
 }
 
 func (s *service) checkExpireMiners() error {
@@ -632,17 +667,27 @@ func (s *service) manange() {
 
 			err := s.signRollCallToTrust(rc.Expire, rc.Height, rc.Address)
 			if err != nil {
+				s.metrics.ProofFailures.Inc()
 				s.logger.Errorf("sign rollcall to trust %s fail: %v", rc.Address.String(), err.Error())
 				s.rcnc <- rc
+			} else {
+				s.metrics.ProofsSubmitted.Inc()
 			}
 
 		case <-timer.C:
+			s.metrics.Rounds.Inc()
+			s.metrics.Trust.Set(boolToFloat(s.nodes.TrustOf(s.base)))
 			ok, err := s.checkWorkingWorker()
 			if err != nil {
+				s.metrics.RoundFailures.Inc()
 				s.logger.Infof("check mine working fail: %v", err)
 			}
+			s.metrics.Working.Set(boolToFloat(ok))
 
 			if ok {
+				if err := s.updateRewardMetrics(); err != nil {
+					s.logger.Debugf("mine: update reward metrics: %v", err)
+				}
 				timer.Reset(time.Minute * 30)
 				s.logger.Infof("the overlay address %v mining", s.base.String())
 			} else if errors.Is(err, errNodeIsCashout) || errors.Is(err, errNodeIsFreeze) {
@@ -14,11 +14,13 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethsana/sana/pkg/config"
 	"github.com/ethsana/sana/pkg/crypto"
 	"github.com/ethsana/sana/pkg/logging"
 	"github.com/ethsana/sana/pkg/p2p/libp2p"
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/postage/batchservice"
+	"github.com/ethsana/sana/pkg/postage/postagecontract"
 	"github.com/ethsana/sana/pkg/sctx"
 	"github.com/ethsana/sana/pkg/settlement"
 	"github.com/ethsana/sana/pkg/settlement/swap"
@@ -26,37 +28,50 @@ import (
 	"github.com/ethsana/sana/pkg/settlement/swap/priceoracle"
 	"github.com/ethsana/sana/pkg/settlement/swap/swapprotocol"
 	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/syncer"
 	"github.com/ethsana/sana/pkg/transaction"
+	"github.com/ethsana/sana/pkg/transaction/backendmux"
+	"golang.org/x/crypto/sha3"
 )
 
 const (
 	maxDelay          = 1 * time.Minute
 	cancellationDepth = 6
+	// maxBlockLag is the number of blocks a swap endpoint may be behind the
+	// others before the requests fail over.
+	maxBlockLag = 10
 )
 
-// InitChain will initialize the Ethereum backend at the given endpoint and
-// set up the Transaction Service to interact with it using the provided signer.
+// InitChain will initialize the Ethereum backend failing over between the
+// given endpoints and set up the Transaction Service to interact with it using
+// the provided signer.
 func InitChain(
 	ctx context.Context,
 	logger logging.Logger,
 	stateStore storage.StateStorer,
-	endpoint string,
+	endpoints []string,
+	latencySelection bool,
 	signer crypto.Signer,
 	pollingInterval time.Duration,
-) (*ethclient.Client, common.Address, int64, transaction.Monitor, transaction.Service, error) {
-	backend, err := ethclient.Dial(endpoint)
+) (*backendmux.Backend, common.Address, int64, transaction.Monitor, transaction.Service, error) {
+	backend, err := backendmux.New(ctx, endpoints, logger, backendmux.Options{
+		MaxBlockLag:      maxBlockLag,
+		LatencySelection: latencySelection,
+	})
 	if err != nil {
-		return nil, common.Address{}, 0, nil, nil, fmt.Errorf("dial eth client: %w", err)
+		logger.Infof("could not connect to backend at %v. In a swap-enabled network a working blockchain node (for goerli network in production) is required. Check your node or specify other nodes using --swap-endpoint.", strings.Join(endpoints, ", "))
+		return nil, common.Address{}, 0, nil, nil, fmt.Errorf("connect eth backend: %w", err)
 	}
 
 	chainID, err := backend.ChainID(ctx)
 	if err != nil {
-		logger.Infof("could not connect to backend at %v. In a swap-enabled network a working blockchain node (for goerli network in production) is required. Check your node or specify another node using --swap-endpoint.", endpoint)
+		backend.Close()
 		return nil, common.Address{}, 0, nil, nil, fmt.Errorf("get chain id: %w", err)
 	}
 
 	overlayEthAddress, err := signer.EthereumAddress()
 	if err != nil {
+		backend.Close()
 		return nil, common.Address{}, 0, nil, nil, fmt.Errorf("eth address: %w", err)
 	}
 
@@ -64,6 +79,7 @@ func InitChain(
 
 	transactionService, err := transaction.NewService(logger, backend, signer, stateStore, chainID, transactionMonitor)
 	if err != nil {
+		backend.Close()
 		return nil, common.Address{}, 0, nil, nil, fmt.Errorf("new transaction service: %w", err)
 	}
 
@@ -74,7 +90,7 @@ func InitChain(
 // chain backend.
 func InitChequebookFactory(
 	logger logging.Logger,
-	backend *ethclient.Client,
+	backend transaction.Backend,
 	chainID int64,
 	transactionService transaction.Service,
 	factoryAddress string,
@@ -128,7 +144,7 @@ func InitChequebookService(
 	stateStore storage.StateStorer,
 	signer crypto.Signer,
 	chainID int64,
-	backend *ethclient.Client,
+	backend transaction.Backend,
 	overlayEthAddress common.Address,
 	transactionService transaction.Service,
 	chequebookFactory chequebook.Factory,
@@ -317,3 +333,45 @@ func GetTxNextBlock(ctx context.Context, logger logging.Logger, backend transact
 
 	return hashBytes, nil
 }
+
+// initPostageContract makes the syncer update the batch store with the events
+// of the postage contract and returns the service of the contract.
+func initPostageContract(ctx context.Context, b *Ant, logger logging.Logger, stateStore storage.StateStorer, batchStore postage.Storer, post postage.Service, syncSvc syncer.Service, chainCfg *config.ChainConfig, found bool, overlayEthAddress common.Address, transactionService transaction.Service, o *Options) (postagecontract.Interface, error) {
+	postageContractAddress, startBlock := chainCfg.PostageStamp, chainCfg.StartBlock
+	if o.PostageContractAddress != "" {
+		if !common.IsHexAddress(o.PostageContractAddress) {
+			return nil, errors.New("malformed postage stamp address")
+		}
+		postageContractAddress = common.HexToAddress(o.PostageContractAddress)
+	} else if !found {
+		return nil, errors.New("no known postage stamp addresses for this network")
+	}
+
+	if cs := batchStore.GetChainState(); cs.Block == 0 {
+		cs.Block = startBlock
+		err := batchStore.PutChainState(cs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	batchSvc, err := batchservice.New(stateStore, batchStore, logging.Component(logger, "postage"), postageContractAddress, overlayEthAddress.Bytes(), startBlock, &batchEventsListener{BatchCreationListener: post, events: b.events}, sha3.New256)
+	if err != nil {
+		return nil, err
+	}
+	syncSvc.AddSync(batchSvc.Sync())
+
+	erc20Address, err := postagecontract.LookupERC20Address(ctx, transactionService, postageContractAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return postagecontract.New(
+		overlayEthAddress,
+		postageContractAddress,
+		erc20Address,
+		transactionService,
+		post,
+		batchStore,
+	), nil
+}
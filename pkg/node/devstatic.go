@@ -0,0 +1,66 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+// NetworkDevStatic is the network which runs without a chain backend. Postage
+// validation accepts only the configured static batch, used by every node.
+const NetworkDevStatic = "dev-static"
+
+// staticBlockHash is the block hash from which overlays are derived in
+// networks without a chain backend.
+var staticBlockHash = make([]byte, 32)
+
+// staticSenderMatcher verifies peers of networks without a chain backend,
+// where overlays are derived from the static block hash instead of the block
+// following the chequebook deployment transaction.
+type staticSenderMatcher struct{}
+
+func (staticSenderMatcher) Matches(context.Context, []byte, uint64, swarm.Address) ([]byte, error) {
+	return staticBlockHash, nil
+}
+
+// InitStaticPostage stores the static batch in the batch store and makes it
+// usable for uploads through the postage service.
+func InitStaticPostage(logger logging.Logger, batchStore postage.Storer, post postage.Service, id []byte, depth uint8) error {
+	if len(id) != 32 {
+		return errors.New("malformed static postage batch id")
+	}
+	if depth <= postage.BucketDepth {
+		return fmt.Errorf("static postage batch depth must be greater than %d", postage.BucketDepth)
+	}
+
+	b, err := batchStore.Get(id)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		b = postage.NewStaticBatch(id, depth, postage.BucketDepth)
+		// the batch is put as a newly created one, so that its value
+		// is accounted in the reserve state
+		value := b.Value
+		b.Value = big.NewInt(0)
+		if err := batchStore.Put(b, value, depth); err != nil {
+			return fmt.Errorf("put static postage batch: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("get static postage batch: %w", err)
+	case b.Depth != depth:
+		return fmt.Errorf("static postage batch depth %d does not match the stored depth %d", depth, b.Depth)
+	}
+
+	post.Add(postage.NewStampIssuer("static", "", b.ID, b.Value, b.Depth, b.BucketDepth, 0, false))
+	logger.Infof("using static postage batch %x", b.ID)
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethsana/sana/pkg/debugapi"
+	"github.com/ethsana/sana/pkg/events"
+)
+
+// drainPollInterval is the interval in which the pending work is checked
+// while the node is drained.
+var drainPollInterval = 500 * time.Millisecond
+
+// drainCloseReserve is the part of the shutdown timeout which is left for
+// the servers and the components to be closed after the drain, at most half
+// of the timeout.
+var drainCloseReserve = 5 * time.Second
+
+// drainContext returns the context of the drain, which is done before the
+// shutdown context so that the closing after the drain is not cut short.
+func drainContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	reserve := drainCloseReserve
+	if half := time.Until(deadline) / 2; reserve > half {
+		reserve = half
+	}
+	return context.WithDeadline(ctx, deadline.Add(-reserve))
+}
+
+// DrainStatus returns the progress of the drain phase of the shutdown.
+func (b *Ant) DrainStatus() debugapi.DrainStatus {
+	b.drainMu.Lock()
+	defer b.drainMu.Unlock()
+	return b.drainStatus
+}
+
+func (b *Ant) updateDrainStatus(f func(s *debugapi.DrainStatus)) {
+	b.drainMu.Lock()
+	defer b.drainMu.Unlock()
+	f(&b.drainStatus)
+}
+
+// drain waits until the locally uploaded chunks are pushed to the network
+// and the monetary settlements in progress are completed, so that neither
+// the chunks nor the settlements are lost when the connections are closed.
+// The api must not accept new uploads anymore. It returns an error with the
+// pending work if the context is done before.
+func (b *Ant) drain(ctx context.Context) error {
+	startedAt := time.Now()
+	b.updateDrainStatus(func(s *debugapi.DrainStatus) {
+		s.Draining = true
+		s.StartedAt = &startedAt
+	})
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	lastChunks, lastPayments := -1, -1
+	for {
+		chunks, payments, err := b.pendingWork()
+		if err != nil {
+			b.updateDrainStatus(func(s *debugapi.DrainStatus) {
+				s.Draining = false
+				s.Error = err.Error()
+			})
+			b.events.Publish(events.Shutdown, events.ShutdownData{Phase: events.ShutdownDrained, Error: err.Error()})
+			return err
+		}
+		b.updateDrainStatus(func(s *debugapi.DrainStatus) {
+			s.PendingChunks = chunks
+			s.PendingPayments = payments
+		})
+		if chunks != lastChunks || payments != lastPayments {
+			lastChunks, lastPayments = chunks, payments
+			b.events.Publish(events.Shutdown, events.ShutdownData{
+				Phase:           events.ShutdownDraining,
+				PendingChunks:   chunks,
+				PendingPayments: payments,
+			})
+		}
+
+		// chunks can not be pushed without peers
+		if chunks > 0 && (b.p2p == nil || len(b.p2p.Peers()) == 0) {
+			b.logger.Warningf("drain: no connected peers to push %d pending chunks to", chunks)
+			chunks = 0
+		}
+		if chunks == 0 && payments == 0 {
+			b.updateDrainStatus(func(s *debugapi.DrainStatus) {
+				s.Draining = false
+				s.Done = true
+			})
+			b.events.Publish(events.Shutdown, events.ShutdownData{Phase: events.ShutdownDrained})
+			b.logger.Debugf("drain: done in %s", time.Since(startedAt))
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			err := fmt.Errorf("%d chunks and %d payments pending: %w", chunks, payments, ctx.Err())
+			b.updateDrainStatus(func(s *debugapi.DrainStatus) {
+				s.Draining = false
+				s.Error = err.Error()
+			})
+			b.events.Publish(events.Shutdown, events.ShutdownData{
+				Phase:           events.ShutdownDrained,
+				PendingChunks:   chunks,
+				PendingPayments: payments,
+				Error:           err.Error(),
+			})
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+// pendingWork returns the number of chunks which are not yet pushed to the
+// network and the number of monetary settlements in progress.
+func (b *Ant) pendingWork() (chunks, payments int, err error) {
+	if b.storer != nil {
+		chunks, err = b.storer.PushIndexSize()
+		if err != nil {
+			return 0, 0, fmt.Errorf("push index size: %w", err)
+		}
+	}
+	if b.accounting != nil {
+		payments = b.accounting.PendingPayments()
+	}
+	return chunks, payments, nil
+}
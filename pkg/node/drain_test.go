@@ -0,0 +1,110 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/localstore"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/node"
+	"github.com/ethsana/sana/pkg/p2p"
+	"github.com/ethsana/sana/pkg/p2p/mock"
+	"github.com/ethsana/sana/pkg/storage"
+	testingc "github.com/ethsana/sana/pkg/storage/testing"
+	"github.com/ethsana/sana/pkg/swarm/test"
+)
+
+func TestDrainContext(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		timeout time.Duration
+		reserve time.Duration
+	}{
+		{name: "long timeout", timeout: time.Minute, reserve: 5 * time.Second},
+		{name: "short timeout", timeout: 2 * time.Second, reserve: time.Second},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+			deadline, _ := ctx.Deadline()
+
+			drainCtx, cancelDrain := node.DrainContext(ctx)
+			defer cancelDrain()
+			drainDeadline, ok := drainCtx.Deadline()
+			if !ok {
+				t.Fatal("drain without a deadline")
+			}
+			if got := deadline.Sub(drainDeadline); got < tc.reserve-100*time.Millisecond || got > tc.reserve {
+				t.Errorf("got %s reserved for closing, want %s", got, tc.reserve)
+			}
+		})
+	}
+
+	drainCtx, cancelDrain := node.DrainContext(context.Background())
+	defer cancelDrain()
+	if _, ok := drainCtx.Deadline(); ok {
+		t.Error("got a drain deadline without a shutdown deadline")
+	}
+}
+
+func TestShutdownDrainTimeout(t *testing.T) {
+	storer, err := localstore.New("", test.RandomAddress().Bytes(), nil, nil, logging.New(ioutil.Discard, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storer.Close()
+	// the uploaded chunk is never pushed to the connected peer
+	if _, err := storer.Put(context.Background(), storage.ModePutUpload, testingc.GenerateTestRandomChunk()); err != nil {
+		t.Fatal(err)
+	}
+	p2ps := mock.New(mock.WithPeersFunc(func() []p2p.Peer {
+		return []p2p.Peer{{Address: test.RandomAddress()}}
+	}))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b *node.Ant
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		// the request is served until shortly after the drain
+		for b.DrainStatus().Error == "" {
+			time.Sleep(10 * time.Millisecond)
+		}
+		time.Sleep(100 * time.Millisecond)
+	})}
+	go func() { _ = server.Serve(ln) }()
+	b = node.NewDrainingAnt(storer, p2ps, server)
+
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err = b.Shutdown(ctx)
+	if err == nil || !strings.Contains(err.Error(), "drain") {
+		t.Fatalf("got error %v, want drain error", err)
+	}
+	if strings.Contains(err.Error(), "debug api server") {
+		t.Fatalf("debug api server not shut down gracefully: %v", err)
+	}
+	if !strings.Contains(b.DrainStatus().Error, "1 chunks and 0 payments pending") {
+		t.Errorf("got drain error %q", b.DrainStatus().Error)
+	}
+}
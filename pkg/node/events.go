@@ -0,0 +1,81 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node
+
+import (
+	"context"
+	"encoding/hex"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/events"
+	"github.com/ethsana/sana/pkg/p2p"
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/topology"
+)
+
+// peerEventsNotifier publishes the peer events of the notifications which are
+// accepted by the wrapped notifier.
+type peerEventsNotifier struct {
+	p2p.PickyNotifier
+	events events.Publisher
+}
+
+func (n *peerEventsNotifier) Connected(ctx context.Context, peer p2p.Peer, forceConnection bool) error {
+	if err := n.PickyNotifier.Connected(ctx, peer, forceConnection); err != nil {
+		return err
+	}
+	n.events.Publish(events.PeerConnected, events.PeerData{
+		Address:  peer.Address.String(),
+		FullNode: peer.FullNode,
+	})
+	return nil
+}
+
+func (n *peerEventsNotifier) Disconnected(peer p2p.Peer) {
+	n.PickyNotifier.Disconnected(peer)
+	n.events.Publish(events.PeerDisconnected, events.PeerData{
+		Address: peer.Address.String(),
+	})
+}
+
+// batchEventsListener publishes the top-ups of the batches owned by the node.
+type batchEventsListener struct {
+	postage.BatchCreationListener
+	events events.Publisher
+}
+
+func (l *batchEventsListener) HandleTopUp(b *postage.Batch, txHash []byte) {
+	l.events.Publish(events.BatchTopUp, events.BatchData{
+		BatchID:         hex.EncodeToString(b.ID),
+		Value:           b.Value.String(),
+		TransactionHash: common.BytesToHash(txHash).String(),
+	})
+}
+
+func (l *batchEventsListener) HandleDepthIncrease(id []byte, depth uint8) {
+	if dl, ok := l.BatchCreationListener.(postage.BatchDepthIncreaseListener); ok {
+		dl.HandleDepthIncrease(id, depth)
+	}
+}
+
+// publishDepthChanges publishes the changes of the neighbourhood depth until
+// the context is done.
+func publishDepthChanges(ctx context.Context, topologyDriver topology.Driver, publisher events.Publisher) {
+	c, unsubscribe := topologyDriver.SubscribePeersChange()
+	defer unsubscribe()
+
+	depth := topologyDriver.NeighborhoodDepth()
+	for {
+		select {
+		case <-c:
+			if d := topologyDriver.NeighborhoodDepth(); d != depth {
+				depth = d
+				publisher.Publish(events.SyncDepth, events.DepthData{Depth: depth})
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
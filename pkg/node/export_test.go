@@ -0,0 +1,70 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/events"
+	"github.com/ethsana/sana/pkg/localstore"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/p2p"
+	mockstate "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/supervisor"
+	"github.com/sirupsen/logrus"
+)
+
+type ShutdownRecorder = shutdownRecorder
+
+var DrainContext = drainContext
+
+var NewShutdownRecorder = newShutdownRecorder
+
+func (s *shutdownRecorder) Track(name string) func(err error) {
+	return s.track(name)
+}
+
+func (s *shutdownRecorder) Report() *ShutdownReport {
+	return s.report()
+}
+
+// NewLightRemoteAnt returns the node in the light-remote mode without the
+// chain and the api.
+func NewLightRemoteAnt(o *Options) (*Ant, error) {
+	logger := logging.New(ioutil.Discard, 0)
+	b := &Ant{
+		p2pCancel:      func() {},
+		errorLogWriter: logger.WriterLevel(logrus.ErrorLevel),
+		fatalC:         make(chan error, 1),
+		logger:         logger,
+		events:         events.New(),
+	}
+	supervisorGroup := supervisor.New(logger, supervisor.Options{})
+	b.supervisorCloser = supervisorGroup
+
+	if err := initLightRemote(context.Background(), b, logger, nil, nil, nil, mockstate.NewStateStore(), nil, 1, common.Address{}, nil, nil, supervisorGroup, false, false, o); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// NewDrainingAnt returns the node with the localstore of the chunks to be
+// pushed to the peers and the debug api server.
+func NewDrainingAnt(storer *localstore.DB, p2ps p2p.Service, debugAPIServer *http.Server) *Ant {
+	logger := logging.New(ioutil.Discard, 0)
+	return &Ant{
+		p2pCancel:      func() {},
+		errorLogWriter: logger.WriterLevel(logrus.ErrorLevel),
+		fatalC:         make(chan error, 1),
+		logger:         logger,
+		events:         events.New(),
+		storer:         storer,
+		p2p:            p2ps,
+		debugAPIServer: debugAPIServer,
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethsana/sana/pkg/api"
+)
+
+// parseGatewayLimits parses the limits of the api in the gateway mode.
+func parseGatewayLimits(o *Options) (*api.GatewayLimits, error) {
+	if o.GatewayMaxRequestSize < 0 {
+		return nil, errors.New("negative maximal request size")
+	}
+	requests, period, err := api.ParseRateLimit(o.GatewayRateLimit)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := api.ParseEndpointRules(o.GatewayAllowedEndpoints)
+	if err != nil {
+		return nil, fmt.Errorf("allowed endpoints: %w", err)
+	}
+	denied, err := api.ParseEndpointRules(o.GatewayDeniedEndpoints)
+	if err != nil {
+		return nil, fmt.Errorf("denied endpoints: %w", err)
+	}
+	return &api.GatewayLimits{
+		RateLimit:        requests,
+		RateLimitPeriod:  period,
+		MaxRequestSize:   o.GatewayMaxRequestSize,
+		AllowedEndpoints: allowed,
+		DeniedEndpoints:  denied,
+	}, nil
+}
@@ -0,0 +1,26 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node
+
+import (
+	tee "github.com/ethsana/sana-tee"
+	"github.com/ethsana/sana/pkg/identityclaim"
+)
+
+// teeDevice returns the TEE device of the node for the identity claims.
+func teeDevice() (identityclaim.Device, error) {
+	device, err := tee.DeviceID()
+	if err != nil {
+		return identityclaim.Device{}, err
+	}
+	platform := "unknown"
+	switch device.Platform {
+	case tee.AMD:
+		platform = "amd"
+	case tee.Intel:
+		platform = "intel"
+	}
+	return identityclaim.Device{Platform: platform, ID: device.Bytes()}, nil
+}
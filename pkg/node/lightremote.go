@@ -0,0 +1,170 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/api"
+	"github.com/ethsana/sana/pkg/apiclient"
+	"github.com/ethsana/sana/pkg/config"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/debugapi"
+	"github.com/ethsana/sana/pkg/events"
+	"github.com/ethsana/sana/pkg/feeds/factory"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/pinning"
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/postage/batchstore"
+	"github.com/ethsana/sana/pkg/postage/postagecontract"
+	"github.com/ethsana/sana/pkg/postage/postagemanager"
+	"github.com/ethsana/sana/pkg/pss"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/supervisor"
+	"github.com/ethsana/sana/pkg/syncer"
+	"github.com/ethsana/sana/pkg/tags"
+	"github.com/ethsana/sana/pkg/tracing"
+	"github.com/ethsana/sana/pkg/transaction"
+	"github.com/ethsana/sana/pkg/traversal"
+)
+
+// ModeLightRemote is the mode of the nodes which open neither the localstore
+// nor the p2p network. The chunks of the api are stamped with the postage
+// batches of the node and got and put through the api of a trusted full node.
+const ModeLightRemote = "light-remote"
+
+// remoteAPITimeout limits the requests to the api of the remote node.
+const remoteAPITimeout = time.Minute
+
+// initLightRemote serves the api of the node in the light-remote mode, with
+// the chunks stored by the node with the api at o.RemoteAPIURL.
+func initLightRemote(ctx context.Context, b *Ant, logger logging.Logger, tracer *tracing.Tracer, signer crypto.Signer, pssPrivateKey *ecdsa.PrivateKey, stateStore storage.StateStorer, swapBackend transaction.Backend, chainID int64, overlayEthAddress common.Address, transactionService transaction.Service, debugAPIService *debugapi.Service, supervisorGroup *supervisor.Group, devStatic, chainEnabled bool, o *Options) error {
+	if o.RemoteAPIURL == "" {
+		return errors.New("light-remote mode requires the api url of the remote node")
+	}
+	client := apiclient.New(o.RemoteAPIURL, apiclient.Options{
+		HTTPClient: &http.Client{Timeout: remoteAPITimeout},
+	})
+	store := apiclient.NewStore(client)
+	logger.Infof("starting in light-remote mode with the api of %s", o.RemoteAPIURL)
+
+	// there is no reserve to evict the chunks of the expired batches from
+	batchStore, err := batchstore.New(stateStore, func([]byte) error { return nil }, logging.Component(logger, "postage"))
+	if err != nil {
+		return fmt.Errorf("batchstore: %w", err)
+	}
+	post, err := postage.NewService(stateStore, batchStore, chainID)
+	if err != nil {
+		return fmt.Errorf("postage service load: %w", err)
+	}
+	b.postageServiceCloser = post
+
+	if devStatic {
+		staticBatch, err := hex.DecodeString(o.StaticPostageBatch)
+		if err != nil {
+			return errors.New("malformed static postage batch id")
+		}
+		if err := InitStaticPostage(logger, batchStore, post, staticBatch, o.StaticPostageDepth); err != nil {
+			return err
+		}
+	}
+
+	var postageContractService postagecontract.Interface
+	if chainEnabled {
+		syncSvc := syncer.New(logger, swapBackend, time.Duration(o.BlockTime)*time.Second, &pidKiller{node: b})
+		b.syncerCloser = syncSvc
+
+		chainCfg, found := config.GetChainConfig(chainID)
+		postageContractService, err = initPostageContract(ctx, b, logger, stateStore, batchStore, post, syncSvc, chainCfg, found, overlayEthAddress, transactionService, o)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("waiting to sync contract data, this may take a while... more info available in Debug loglevel")
+		syncStart := time.Now()
+		<-syncSvc.Worker()
+		b.events.Publish(events.SyncContracts, events.ChainSyncData{Duration: time.Since(syncStart).String()})
+	}
+
+	var topUpLimit *big.Int
+	if o.PostageAutoTopUpLimit != "" {
+		limit, ok := new(big.Int).SetString(o.PostageAutoTopUpLimit, 10)
+		if !ok || limit.Sign() < 0 {
+			return errors.New("malformed postage auto top-up limit")
+		}
+		topUpLimit = limit
+	}
+	postageManager := postagemanager.New(post, batchStore, postageContractService, stateStore, logging.Component(logger, "postage"), postagemanager.Options{
+		TopUpLimit:        topUpLimit,
+		WarnTTL:           o.PostageExpiryWarning,
+		TargetTTL:         o.PostageTopUpTTL,
+		DiluteUtilization: o.PostageDiluteUtilization,
+		BlockTime:         time.Duration(o.BlockTime) * time.Second,
+	})
+	postageManager.Start()
+	b.postageManagerCloser = postageManager
+
+	if debugAPIService != nil {
+		debugAPIService.ConfigurePostage(batchStore, post, postageContractService, postageManager)
+	}
+
+	if o.APIAddr == "" {
+		return nil
+	}
+
+	tagService := tags.NewTags(stateStore, logger)
+	b.tagsCloser = tagService
+
+	// pss messages are sent as the chunks pushed by the remote node
+	pssService := pss.New(pssPrivateKey, logging.Component(logger, "pss"))
+	pssService.SetPushSyncer(client)
+	b.pssCloser = pssService
+
+	traversalService := traversal.New(store)
+	pinningService := pinning.NewService(store, stateStore, traversalService)
+
+	multiResolver := newReloadableResolver(o.ResolverConnectionCfgs, o.Logger)
+	b.resolverCloser = multiResolver
+	b.resolver = multiResolver
+	nameResolver := &supervisedResolver{Interface: multiResolver, group: supervisorGroup}
+
+	apiService := api.New(tagService, store, nameResolver, pssService, traversalService, pinningService, factory.New(store), post, postageContractService, client, signer, logging.Component(logger, "api"), tracer, api.Options{
+		CORSAllowedOrigins: o.CORSAllowedOrigins,
+		Authorization:      o.DashboardAuthorization,
+		WsPingPeriod:       60 * time.Second,
+	})
+	apiListener, err := net.Listen("tcp", o.APIAddr)
+	if err != nil {
+		return fmt.Errorf("api listener: %w", err)
+	}
+
+	apiServer := &http.Server{
+		IdleTimeout:       30 * time.Second,
+		ReadHeaderTimeout: 3 * time.Second,
+		Handler:           apiService,
+		ErrorLog:          log.New(b.errorLogWriter, "", 0),
+	}
+
+	serveSupervised(supervisorGroup, "api", logger, apiServer, apiListener)
+
+	b.apiServer = apiServer
+	b.apiCloser = apiService
+	b.apiService = apiService
+
+	if debugAPIService != nil {
+		debugAPIService.MustRegisterMetrics(apiService.Metrics()...)
+	}
+	return nil
+}
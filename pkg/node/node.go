@@ -10,6 +10,7 @@ package node
 import (
 	"context"
 	"crypto/ecdsa"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -17,25 +18,29 @@ import (
 	"math/big"
 	"net"
 	"net/http"
-	"os"
 	"path/filepath"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethsana/sana/pkg/accounting"
 	"github.com/ethsana/sana/pkg/addressbook"
 	"github.com/ethsana/sana/pkg/api"
+	"github.com/ethsana/sana/pkg/commitment"
 	"github.com/ethsana/sana/pkg/config"
 	"github.com/ethsana/sana/pkg/crypto"
 	"github.com/ethsana/sana/pkg/debugapi"
+	"github.com/ethsana/sana/pkg/dedup"
+	"github.com/ethsana/sana/pkg/events"
 	"github.com/ethsana/sana/pkg/feeds/factory"
 	"github.com/ethsana/sana/pkg/hive"
+	"github.com/ethsana/sana/pkg/identityclaim"
+	"github.com/ethsana/sana/pkg/keystore"
+	"github.com/ethsana/sana/pkg/keystore/backup"
 	"github.com/ethsana/sana/pkg/localstore"
 	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/manifest/nodecache"
 	"github.com/ethsana/sana/pkg/metrics"
 	"github.com/ethsana/sana/pkg/mine"
 	"github.com/ethsana/sana/pkg/mine/minecontract"
@@ -49,40 +54,57 @@ import (
 	"github.com/ethsana/sana/pkg/pingpong"
 	"github.com/ethsana/sana/pkg/pinning"
 	"github.com/ethsana/sana/pkg/postage"
-	"github.com/ethsana/sana/pkg/postage/batchservice"
 	"github.com/ethsana/sana/pkg/postage/batchstore"
 	"github.com/ethsana/sana/pkg/postage/postagecontract"
+	"github.com/ethsana/sana/pkg/postage/postagemanager"
 	"github.com/ethsana/sana/pkg/pricer"
 	"github.com/ethsana/sana/pkg/pricing"
 	"github.com/ethsana/sana/pkg/pss"
+	"github.com/ethsana/sana/pkg/pss/mailbox"
 	"github.com/ethsana/sana/pkg/puller"
 	"github.com/ethsana/sana/pkg/pullsync"
 	"github.com/ethsana/sana/pkg/pullsync/pullstorage"
 	"github.com/ethsana/sana/pkg/pusher"
 	"github.com/ethsana/sana/pkg/pushsync"
 	"github.com/ethsana/sana/pkg/recovery"
+	"github.com/ethsana/sana/pkg/reload"
+	"github.com/ethsana/sana/pkg/repair"
+	"github.com/ethsana/sana/pkg/report"
+	"github.com/ethsana/sana/pkg/reputation"
 	"github.com/ethsana/sana/pkg/resolver/multiresolver"
 	"github.com/ethsana/sana/pkg/retrieval"
+	"github.com/ethsana/sana/pkg/s3"
+	"github.com/ethsana/sana/pkg/scheduler"
+	"github.com/ethsana/sana/pkg/settlement"
 	"github.com/ethsana/sana/pkg/settlement/pseudosettle"
 	"github.com/ethsana/sana/pkg/settlement/swap"
 	"github.com/ethsana/sana/pkg/settlement/swap/chequebook"
 	"github.com/ethsana/sana/pkg/settlement/swap/priceoracle"
 	"github.com/ethsana/sana/pkg/shed"
+	"github.com/ethsana/sana/pkg/snapshot"
+	"github.com/ethsana/sana/pkg/stamping"
+	"github.com/ethsana/sana/pkg/standby"
 	"github.com/ethsana/sana/pkg/steward"
 	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/supervisor"
 	"github.com/ethsana/sana/pkg/swarm"
 	"github.com/ethsana/sana/pkg/syncer"
 	"github.com/ethsana/sana/pkg/tags"
+	"github.com/ethsana/sana/pkg/takedown"
 	"github.com/ethsana/sana/pkg/topology"
 	"github.com/ethsana/sana/pkg/topology/kademlia"
 	"github.com/ethsana/sana/pkg/topology/lightnode"
 	"github.com/ethsana/sana/pkg/tracing"
 	"github.com/ethsana/sana/pkg/transaction"
+	"github.com/ethsana/sana/pkg/transaction/backendmux"
 	"github.com/ethsana/sana/pkg/traversal"
+	"github.com/ethsana/sana/pkg/uploadpolicy"
+	"github.com/ethsana/sana/pkg/uploadscan"
+	"github.com/ethsana/sana/pkg/uploadsession"
 	"github.com/hashicorp/go-multierror"
 	ma "github.com/multiformats/go-multiaddr"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/sha3"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -92,17 +114,21 @@ type Ant struct {
 	p2pCancel                context.CancelFunc
 	apiCloser                io.Closer
 	apiServer                *http.Server
+	s3APIServer              *http.Server
 	debugAPIServer           *http.Server
+	standbyServer            *http.Server
 	resolverCloser           io.Closer
 	errorLogWriter           *io.PipeWriter
 	tracerCloser             io.Closer
 	tagsCloser               io.Closer
 	stateStoreCloser         io.Closer
+	reputationCloser         io.Closer
 	localstoreCloser         io.Closer
 	topologyCloser           io.Closer
 	topologyHalter           topology.Halter
 	pusherCloser             io.Closer
 	pullerCloser             io.Closer
+	snapshotCloser           io.Closer
 	accountingCloser         io.Closer
 	pullSyncCloser           io.Closer
 	pssCloser                io.Closer
@@ -114,8 +140,31 @@ type Ant struct {
 	postageServiceCloser     io.Closer
 	priceOracleCloser        io.Closer
 	mineCloser               io.Closer
+	reportCloser             io.Closer
+	schedulerCloser          io.Closer
+	commitmentCloser         io.Closer
+	repairCloser             io.Closer
+	postageManagerCloser     io.Closer
+	supervisorCloser         io.Closer
+	pssMailboxCloser         io.Closer
 	shutdownInProgress       bool
 	shutdownMutex            sync.Mutex
+	shutdownRecorder         *shutdownRecorder
+	fatalC                   chan error
+	fatalOnce                sync.Once
+	storer                   *localstore.DB
+	drainMu                  sync.Mutex
+	drainStatus              debugapi.DrainStatus
+	events                   *events.Bus
+
+	// services which are reconfigured by the reloads
+	logger          logging.Logger
+	p2p             p2p.Service
+	accounting      *accounting.Accounting
+	pricing         *pricing.Service
+	resolver        *reloadableResolver
+	apiService      api.Service
+	debugAPIService *debugapi.Service
 }
 
 type Options struct {
@@ -131,6 +180,8 @@ type Options struct {
 	NATAddr                    string
 	EnableWS                   bool
 	EnableQUIC                 bool
+	P2PProxy                   string
+	P2POnionAddr               string
 	WelcomeMessage             string
 	Bootnodes                  []string
 	CORSAllowedOrigins         []string
@@ -140,14 +191,21 @@ type Options struct {
 	TracingEnabled             bool
 	TracingEndpoint            string
 	TracingServiceName         string
+	TracingExporter            string
+	TracingInsecure            bool
 	GlobalPinningEnabled       bool
 	PaymentThreshold           string
 	PaymentTolerance           string
 	PaymentEarly               string
 	ResolverConnectionCfgs     []multiresolver.ConnectionConfig
 	GatewayMode                bool
+	GatewayRateLimit           string
+	GatewayMaxRequestSize      int64
+	GatewayAllowedEndpoints    []string
+	GatewayDeniedEndpoints     []string
 	BootnodeMode               bool
-	SwapEndpoint               string
+	SwapEndpoints              []string
+	SwapLatencySelection       bool
 	SwapFactoryAddress         string
 	SwapLegacyFactoryAddresses []string
 	SwapInitialDeposit         string
@@ -168,18 +226,105 @@ type Options struct {
 	UniswapEnable              bool
 	UniswapEndpoint            string
 	UniswapValidTime           time.Duration
+	RetrievalCaching           bool
+	RetrievalHopTimeout        time.Duration
+	RetrievalRequestTimeout    time.Duration
+	RetrievalRetryInterval     time.Duration
+	UploadDedup                bool
+	S3Enable                   bool
+	S3PostageBatch             string
+	UploadScanEndpoint         string
+	UploadScanTimeout          time.Duration
+	ManifestCacheCapacity      uint64
+	StampingClients            string
+	StampingBatch              string
+	KeepaliveTCP               time.Duration
+	KeepaliveWS                time.Duration
+	KeepaliveQUIC              time.Duration
+	KeepaliveTimeout           time.Duration
+	KeepaliveMaxFailures       int
+	Network                    string
+	StaticPostageBatch         string
+	StaticPostageDepth         uint8
+	UploadPolicies             string
+	StandbyAddr                string
+	StandbyPeer                string
+	StandbyToken               string
+	ReportPeriods              []string
+	ReportWebhooks             []string
+	ReportSMTPAddr             string
+	ReportSMTPUsername         string
+	ReportSMTPPassword         string
+	ReportSMTPFrom             string
+	ReportSMTPTo               []string
+	CommitmentInterval         time.Duration
+	CommitmentPostageBatch     string
+	TakedownSigners            []string
+	RepairInterval             time.Duration
+	RepairBudget               int
+	RepairPostageBatch         string
+	PostageAutoTopUpLimit      string
+	PostageExpiryWarning       time.Duration
+	PostageTopUpTTL            time.Duration
+	PostageDiluteUtilization   float64
+	PssMailbox                 bool
+	PssMailboxCapacity         int
+	PssMailboxMaxTTL           time.Duration
+	Mode                       string
+	RemoteAPIURL               string
+	DBCapacityGB               uint64
+	DBReserveCapacity          uint64
+	DBPinCapacity              uint64
+	DBEvictionPolicy           string
+	S3APIAddr                  string
+	S3Credentials              string
+	ReputationBanThreshold     float64
+	ReputationBanDuration      time.Duration
+	ReputationPreferredPeers   []string
+	ReputationBlockedCIDRs     []string
+	SnapshotSources            []string
+	SnapshotSigners            []string
+	// Reloader, if set, is exposed by the debug api to reload the
+	// configuration of the running node.
+	Reloader reload.Interface
+	// Keystore holds the node keys. If it is set and KeysBackupEnable is
+	// true, the debug api exposes encrypted backups of the keys to the
+	// requests with the password of the keystore.
+	Keystore         keystore.Service
+	KeysBackupEnable bool
+	// Clef is the configuration of the clef signer included in the key
+	// backups, if clef is enabled.
+	Clef *backup.Clef
 }
 
 const (
-	refreshRate = int64(4500000)
-	basePrice   = 10000
+	refreshRate          = int64(4500000)
+	basePrice            = 10000
+	standbyWatchInterval = 15 * time.Second
 )
 
 func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, networkID uint64, logger logging.Logger, libp2pPrivateKey, pssPrivateKey *ecdsa.PrivateKey, o *Options) (b *Ant, err error) {
+	devStatic := o.Network == NetworkDevStatic
+	switch {
+	case o.Network != "" && !devStatic:
+		return nil, fmt.Errorf("unknown network %q", o.Network)
+	case devStatic && (o.SwapEnable || o.MineEnabled):
+		return nil, errors.New("swap and mining require a chain backend, which is not used in the dev-static network")
+	case o.Mode != "" && o.Mode != ModeLightRemote:
+		return nil, fmt.Errorf("unknown mode %q", o.Mode)
+	case o.Mode == ModeLightRemote && (o.FullNodeMode || o.SwapEnable || o.MineEnabled):
+		return nil, errors.New("full node, swap and mining require the p2p network, which is not used in the light-remote mode")
+	}
+	// the chain backend is not used in standalone mode and networks with
+	// static postage
+	chainEnabled := !o.Standalone && !devStatic
+
 	tracer, tracerCloser, err := tracing.NewTracer(&tracing.Options{
 		Enabled:     o.TracingEnabled,
 		Endpoint:    o.TracingEndpoint,
 		ServiceName: o.TracingServiceName,
+		Exporter:    o.TracingExporter,
+		Insecure:    o.TracingInsecure,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("tracer: %w", err)
@@ -205,18 +350,68 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		p2pCancel:      p2pCancel,
 		errorLogWriter: logger.WriterLevel(logrus.ErrorLevel),
 		tracerCloser:   tracerCloser,
+		fatalC:         make(chan error, 1),
+		logger:         logger,
+		events:         events.New(),
 	}
 
+	// non-critical subsystems are supervised, so that a panic in one of them
+	// does not take down the node
+	supervisorGroup := supervisor.New(logger, supervisor.Options{})
+	b.supervisorCloser = supervisorGroup
+
 	stateStore, err := InitStateStore(logger, o.DataDir)
 	if err != nil {
 		return nil, err
 	}
 	b.stateStoreCloser = stateStore
 
+	if o.StandbyAddr != "" || o.StandbyPeer != "" {
+		standbyService, err := standby.New(stateStore, logger, o.StandbyToken, standby.Options{})
+		if err != nil {
+			return nil, err
+		}
+		var peer *standby.Client
+		if o.StandbyPeer != "" {
+			peer = standby.NewClient(o.StandbyPeer, o.StandbyToken)
+		}
+		if err := standbyService.Activate(p2pCtx, peer); err != nil {
+			return nil, err
+		}
+		logger.Infof("active in standby epoch %d", standbyService.State().Epoch)
+
+		if o.StandbyAddr != "" {
+			standbyListener, err := net.Listen("tcp", o.StandbyAddr)
+			if err != nil {
+				return nil, fmt.Errorf("standby listener: %w", err)
+			}
+			standbyServer := &http.Server{
+				IdleTimeout:       30 * time.Second,
+				ReadHeaderTimeout: 3 * time.Second,
+				Handler:           standbyService.Handler(nil),
+				ErrorLog:          log.New(b.errorLogWriter, "", 0),
+			}
+			serveSupervised(supervisorGroup, "standby", logger, standbyServer, standbyListener)
+			b.standbyServer = standbyServer
+		}
+		if peer != nil {
+			go func() {
+				// the node stops once its standby was promoted
+				if err := standbyService.Watch(p2pCtx, peer, standbyWatchInterval); err != nil {
+					b.fatalOnce.Do(func() {
+						b.fatalC <- err
+					})
+				}
+			}()
+		}
+	} else if err := standby.Startable(stateStore); err != nil {
+		return nil, err
+	}
+
 	addressbook := addressbook.New(stateStore)
 
 	var (
-		swapBackend        *ethclient.Client
+		swapBackend        *backendmux.Backend
 		overlayEthAddress  common.Address
 		chainID            int64
 		transactionService transaction.Service
@@ -227,12 +422,13 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		cashoutService     chequebook.CashoutService
 		pollingInterval    = time.Duration(o.BlockTime) * time.Second
 	)
-	if !o.Standalone {
+	if chainEnabled {
 		swapBackend, overlayEthAddress, chainID, transactionMonitor, transactionService, err = InitChain(
 			p2pCtx,
-			logger,
+			logging.Component(logger, "transaction"),
 			stateStore,
-			o.SwapEndpoint,
+			o.SwapEndpoints,
+			o.SwapLatencySelection,
 			signer,
 			pollingInterval,
 		)
@@ -255,7 +451,7 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 			return nil, fmt.Errorf("eth address: %w", err)
 		}
 		// set up basic debug api endpoints for debugging and /health endpoint
-		debugAPIService = debugapi.New(*publicKey, pssPrivateKey.PublicKey, overlayEthAddress, addressbook, logger, tracer, o.CORSAllowedOrigins, o.DashboardAuthorization, transactionService)
+		debugAPIService = debugapi.New(*publicKey, pssPrivateKey.PublicKey, overlayEthAddress, addressbook, logging.Component(logger, "debugapi"), tracer, o.CORSAllowedOrigins, o.DashboardAuthorization, transactionService, b.events)
 
 		debugAPIListener, err := net.Listen("tcp", o.DebugAPIAddr)
 		if err != nil {
@@ -269,19 +465,13 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 			ErrorLog:          log.New(b.errorLogWriter, "", 0),
 		}
 
-		go func() {
-			logger.Infof("debug api address: %s", debugAPIListener.Addr())
-
-			if err := debugAPIServer.Serve(debugAPIListener); err != nil && err != http.ErrServerClosed {
-				logger.Debugf("debug api server: %v", err)
-				logger.Error("unable to serve debug api")
-			}
-		}()
+		serveSupervised(supervisorGroup, "debug api", logger, debugAPIServer, debugAPIListener)
 
 		b.debugAPIServer = debugAPIServer
+		b.debugAPIService = debugAPIService
 	}
 
-	if !o.Standalone {
+	if chainEnabled {
 		// Sync the with the given Ethereum backend:
 		isSynced, _, err := transaction.IsSynced(p2pCtx, swapBackend, maxDelay)
 		if err != nil {
@@ -297,6 +487,13 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		}
 	}
 
+	if o.Mode == ModeLightRemote {
+		if err := initLightRemote(p2pCtx, b, logger, tracer, signer, pssPrivateKey, stateStore, swapBackend, chainID, overlayEthAddress, transactionService, debugAPIService, supervisorGroup, devStatic, chainEnabled, o); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+
 	if o.SwapEnable {
 		chequebookFactory, err = InitChequebookFactory(
 			logger,
@@ -314,6 +511,12 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 			return nil, fmt.Errorf("factory fail: %w", err)
 		}
 
+		var deployed common.Hash
+		deployedErr := stateStore.Get(chequebook.ChequebookDeploymentKey, &deployed)
+		if deployedErr != nil && !errors.Is(deployedErr, storage.ErrNotFound) {
+			return nil, deployedErr
+		}
+
 		chequebookService, err = InitChequebookService(
 			p2pCtx,
 			logger,
@@ -330,6 +533,14 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		if err != nil {
 			return nil, err
 		}
+		if errors.Is(deployedErr, storage.ErrNotFound) {
+			if err := stateStore.Get(chequebook.ChequebookDeploymentKey, &deployed); err == nil {
+				b.events.Publish(events.ChequebookDeployed, events.ChequebookData{
+					Address:         chequebookService.Address().String(),
+					TransactionHash: deployed.String(),
+				})
+			}
+		}
 
 		chequeStore, cashoutService = initChequeStoreCashout(
 			stateStore,
@@ -351,14 +562,19 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		txHash    []byte
 	)
 
-	txHash, err = GetTxHash(stateStore, logger, o.Transaction)
-	if err != nil {
-		return nil, fmt.Errorf("invalid transaction hash: %w", err)
-	}
+	if devStatic {
+		// there is no chequebook deployment transaction
+		txHash, blockHash = make([]byte, 32), staticBlockHash
+	} else {
+		txHash, err = GetTxHash(stateStore, logger, o.Transaction)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transaction hash: %w", err)
+		}
 
-	blockHash, err = GetTxNextBlock(p2pCtx, logger, swapBackend, transactionMonitor, pollingInterval, txHash, o.BlockHash)
-	if err != nil {
-		return nil, fmt.Errorf("invalid block hash: %w", err)
+		blockHash, err = GetTxNextBlock(p2pCtx, logger, swapBackend, transactionMonitor, pollingInterval, txHash, o.BlockHash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block hash: %w", err)
+		}
 	}
 
 	swarmAddress, err := crypto.NewOverlayAddress(*pubKey, networkID, blockHash)
@@ -371,23 +587,50 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 
 	lightNodes := lightnode.NewContainer(swarmAddress)
 
-	senderMatcher := transaction.NewMatcher(swapBackend, types.NewEIP155Signer(big.NewInt(chainID)), stateStore)
+	var senderMatcher interface {
+		Matches(ctx context.Context, tx []byte, networkID uint64, senderOverlay swarm.Address) ([]byte, error)
+	} = transaction.NewMatcher(swapBackend, types.NewEIP155Signer(big.NewInt(chainID)), stateStore)
+	if devStatic {
+		senderMatcher = staticSenderMatcher{}
+	}
+
+	policy, err := reputationPolicy(o)
+	if err != nil {
+		return nil, fmt.Errorf("reputation policy: %w", err)
+	}
+	reputationService, err := reputation.New(stateStore, logging.Component(logger, "reputation"), policy)
+	if err != nil {
+		return nil, fmt.Errorf("reputation: %w", err)
+	}
+	b.reputationCloser = reputationService
 
-	p2ps, err := libp2p.New(p2pCtx, signer, networkID, swarmAddress, addr, addressbook, stateStore, lightNodes, senderMatcher, logger, tracer, libp2p.Options{
+	p2ps, err := libp2p.New(p2pCtx, signer, networkID, swarmAddress, addr, addressbook, stateStore, lightNodes, senderMatcher, logging.Component(logger, "p2p"), tracer, libp2p.Options{
 		PrivateKey:     libp2pPrivateKey,
 		NATAddr:        o.NATAddr,
 		EnableWS:       o.EnableWS,
 		EnableQUIC:     o.EnableQUIC,
+		ProxyURL:       o.P2PProxy,
+		OnionAddr:      o.P2POnionAddr,
 		Standalone:     o.Standalone,
 		WelcomeMessage: o.WelcomeMessage,
 		FullNode:       o.FullNodeMode,
 		Transaction:    txHash,
+		KeepaliveIntervals: map[string]time.Duration{
+			"tcp":  o.KeepaliveTCP,
+			"ws":   o.KeepaliveWS,
+			"quic": o.KeepaliveQUIC,
+		},
+		KeepaliveTimeout:     o.KeepaliveTimeout,
+		KeepaliveMaxFailures: o.KeepaliveMaxFailures,
+		AllowAddress:         reputationService.AllowAddress,
+		Reputation:           reputationService,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("p2p service: %w", err)
 	}
 	b.p2pService = p2ps
 	b.p2pHalter = p2ps
+	reputationService.SetDisconnecter(p2ps)
 
 	var unreserveFn func([]byte, uint8) (uint64, error)
 	var evictFn = func(b []byte) error {
@@ -395,7 +638,7 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		return err
 	}
 
-	batchStore, err := batchstore.New(stateStore, evictFn, logger)
+	batchStore, err := batchstore.New(stateStore, evictFn, logging.Component(logger, "postage"))
 	if err != nil {
 		return nil, fmt.Errorf("batchstore: %w", err)
 	}
@@ -407,21 +650,39 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		logger.Infof("using datadir in: '%s'", o.DataDir)
 		path = filepath.Join(o.DataDir, "localstore")
 	}
+	cacheCapacity, reserveCapacity := o.CacheCapacity, uint64(batchstore.Capacity)
+	if o.DBReserveCapacity > 0 {
+		reserveCapacity = o.DBReserveCapacity
+	}
+	if o.DBCapacityGB > 0 {
+		// the cache takes the space left by the reserve and the pins
+		cacheCapacity, err = localstore.CacheCapacity(o.DBCapacityGB<<30, reserveCapacity, o.DBPinCapacity)
+		if err != nil {
+			return nil, fmt.Errorf("db capacity: %w", err)
+		}
+	}
+	evictionPolicy, err := localstore.ParseEvictionPolicy(o.DBEvictionPolicy)
+	if err != nil {
+		return nil, err
+	}
 	lo := &localstore.Options{
-		Capacity:               o.CacheCapacity,
-		ReserveCapacity:        uint64(batchstore.Capacity),
+		Capacity:               cacheCapacity,
+		ReserveCapacity:        reserveCapacity,
 		UnreserveFunc:          batchStore.Unreserve,
+		PinCapacity:            o.DBPinCapacity,
+		EvictionPolicy:         evictionPolicy,
 		OpenFilesLimit:         o.DBOpenFilesLimit,
 		BlockCacheCapacity:     o.DBBlockCacheCapacity,
 		WriteBufferSize:        o.DBWriteBufferSize,
 		DisableSeeksCompaction: o.DBDisableSeeksCompaction,
 	}
 
-	storer, err := localstore.New(path, swarmAddress.Bytes(), stateStore, lo, logger)
+	storer, err := localstore.New(path, swarmAddress.Bytes(), stateStore, lo, logging.Component(logger, "localstore"))
 	if err != nil {
 		return nil, fmt.Errorf("localstore: %w", err)
 	}
 	b.localstoreCloser = storer
+	b.storer = storer
 	unreserveFn = storer.UnreserveBatch
 
 	validStamp := postage.ValidStamp(batchStore)
@@ -431,57 +692,36 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 	}
 	b.postageServiceCloser = post
 
+	if devStatic {
+		staticBatch, err := hex.DecodeString(o.StaticPostageBatch)
+		if err != nil {
+			return nil, errors.New("malformed static postage batch id")
+		}
+		if err := InitStaticPostage(logger, batchStore, post, staticBatch, o.StaticPostageDepth); err != nil {
+			return nil, err
+		}
+		validStamp = postage.ValidStaticStamp(batchStore, staticBatch)
+	}
+
 	var (
 		postageContractService postagecontract.Interface
-		batchSvc               postage.EventUpdater
 		// eventListener          postage.Listener
 		syncSvc   syncer.Service
 		mineSvr   mine.Service
 		oracleSvr mine.Oracle
 	)
 
-	if !o.Standalone {
-		syncSvc = syncer.New(logger, swapBackend, o.BlockTime, &pidKiller{node: b})
+	if chainEnabled {
+		syncSvc = syncer.New(logger, swapBackend, time.Duration(o.BlockTime)*time.Second, &pidKiller{node: b})
 		b.syncerCloser = syncSvc
 
 		chainCfg, found := config.GetChainConfig(chainID)
-		postageContractAddress, startBlock := chainCfg.PostageStamp, chainCfg.StartBlock
-		if o.PostageContractAddress != "" {
-			if !common.IsHexAddress(o.PostageContractAddress) {
-				return nil, errors.New("malformed postage stamp address")
-			}
-			postageContractAddress = common.HexToAddress(o.PostageContractAddress)
-		} else if !found {
-			return nil, errors.New("no known postage stamp addresses for this network")
-		}
-
-		if cs := batchStore.GetChainState(); cs.Block == 0 {
-			cs.Block = startBlock
-			err := batchStore.PutChainState(cs)
-			if err != nil {
-				return nil, err
-			}
-		}
-
-		batchSvc, err = batchservice.New(stateStore, batchStore, logger, postageContractAddress, overlayEthAddress.Bytes(), startBlock, post, sha3.New256)
-		if err != nil {
-			return nil, err
-		}
-		syncSvc.AddSync(batchSvc.Sync())
-
-		erc20Address, err := postagecontract.LookupERC20Address(p2pCtx, transactionService, postageContractAddress)
+		startBlock := chainCfg.StartBlock
+		postageContractService, err = initPostageContract(p2pCtx, b, logger, stateStore, batchStore, post, syncSvc, chainCfg, found, overlayEthAddress, transactionService, o)
 		if err != nil {
 			return nil, err
 		}
 
-		postageContractService = postagecontract.New(
-			overlayEthAddress,
-			postageContractAddress,
-			erc20Address,
-			transactionService,
-			post,
-		)
-
 		if o.MineEnabled {
 			mineContractAddress := chainCfg.MinerAddress
 			if o.MineContractAddress != "" {
@@ -506,25 +746,45 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 			syncSvc.AddSync(nodeSvc.Sync())
 
 			if o.UniswapEnable {
-				oracleSvr, err = oracle.New(o.UniswapEndpoint, chainCfg.UniV2PairAddress, o.UniswapValidTime, logger)
+				uniswapOracle, err := oracle.New(o.UniswapEndpoint, chainCfg.UniV2PairAddress, o.UniswapValidTime, logger)
 				if err != nil {
 					return nil, err
 				}
+				oracleSvr = &supervisedOracle{Oracle: uniswapOracle, group: supervisorGroup}
 
 			}
 
-			mineSvr = mine.NewService(swarmAddress, mineService, nodeSvc, signer, oracleSvr, logger, mine.Options{
+			mineSvr = mine.NewService(swarmAddress, mineService, nodeSvc, signer, oracleSvr, logging.Component(logger, "mine"), mine.Options{
 				Store:              stateStore,
 				Backend:            swapBackend,
 				TransactionService: transactionService,
 				OverlayEthAddress:  overlayEthAddress,
 				DeployGasPrice:     o.DeployGasPrice,
+				Events:             b.events,
 			})
 
 			b.mineCloser = mineSvr
 		}
 	}
 
+	var topUpLimit *big.Int
+	if o.PostageAutoTopUpLimit != "" {
+		limit, ok := new(big.Int).SetString(o.PostageAutoTopUpLimit, 10)
+		if !ok || limit.Sign() < 0 {
+			return nil, errors.New("malformed postage auto top-up limit")
+		}
+		topUpLimit = limit
+	}
+	postageManager := postagemanager.New(post, batchStore, postageContractService, stateStore, logging.Component(logger, "postage"), postagemanager.Options{
+		TopUpLimit:        topUpLimit,
+		WarnTTL:           o.PostageExpiryWarning,
+		TargetTTL:         o.PostageTopUpTTL,
+		DiluteUtilization: o.PostageDiluteUtilization,
+		BlockTime:         time.Duration(o.BlockTime) * time.Second,
+	})
+	postageManager.Start()
+	b.postageManagerCloser = postageManager
+
 	if !o.Standalone {
 		if natManager := p2ps.NATManager(); natManager != nil {
 			// wait for nat manager to init
@@ -548,7 +808,7 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		return nil, fmt.Errorf("pingpong service: %w", err)
 	}
 
-	hive := hive.New(p2ps, addressbook, networkID, logger)
+	hive := hive.New(p2ps, addressbook, networkID, logging.Component(logger, "hive"))
 	if err = p2ps.AddProtocol(hive.Protocol()); err != nil {
 		return nil, fmt.Errorf("hive service: %w", err)
 	}
@@ -576,11 +836,11 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		return nil, fmt.Errorf("unable to create metrics storage for kademlia: %w", err)
 	}
 
-	kad := kademlia.New(swarmAddress, addressbook, hive, p2ps, metricsDB, logger, kademlia.Options{Bootnodes: bootnodes, StandaloneMode: o.Standalone, BootnodeMode: o.BootnodeMode})
+	kad := kademlia.New(swarmAddress, addressbook, hive, p2ps, metricsDB, logging.Component(logger, "kademlia"), kademlia.Options{Bootnodes: bootnodes, StandaloneMode: o.Standalone, BootnodeMode: o.BootnodeMode})
 	b.topologyCloser = kad
 	b.topologyHalter = kad
 	hive.SetAddPeersHandler(kad.AddPeers)
-	p2ps.SetPickyNotifier(kad)
+	p2ps.SetPickyNotifier(&peerEventsNotifier{PickyNotifier: &preferredPeersNotifier{PickyNotifier: kad, reputation: reputationService}, events: b.events})
 	batchStore.SetRadiusSetter(kad)
 	if o.MineEnabled {
 		trust := trust.New(p2ps, logger, swarmAddress)
@@ -601,7 +861,9 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		// interrupts at this stage of the application lifecycle. some changes
 		// would be needed on the cmd level to support context cancellation at
 		// this stage
+		syncStart := time.Now()
 		<-syncedChan
+		b.events.Publish(events.SyncContracts, events.ChainSyncData{Duration: time.Since(syncStart).String()})
 	}
 
 	// if batchSvc != nil {
@@ -625,24 +887,17 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 	}
 
 	minThreshold := big.NewInt(2 * refreshRate)
-	maxThreshold := big.NewInt(24 * refreshRate)
 
-	paymentThreshold, ok := new(big.Int).SetString(o.PaymentThreshold, 10)
-	if !ok {
-		return nil, fmt.Errorf("invalid payment threshold: %s", paymentThreshold)
+	paymentThreshold, paymentTolerance, paymentEarly, err := parsePaymentThresholds(o.PaymentThreshold, o.PaymentTolerance, o.PaymentEarly)
+	if err != nil {
+		return nil, err
 	}
 
 	pricer := pricer.NewFixedPricer(swarmAddress, basePrice)
 
-	if paymentThreshold.Cmp(minThreshold) < 0 {
-		return nil, fmt.Errorf("payment threshold below minimum generally accepted value, need at least %s", minThreshold)
-	}
-
-	if paymentThreshold.Cmp(maxThreshold) > 0 {
-		return nil, fmt.Errorf("payment threshold above maximum generally accepted value, needs to be reduced to at most %s", maxThreshold)
-	}
-
 	pricing := pricing.New(p2ps, logger, paymentThreshold, minThreshold)
+	b.pricing = pricing
+	b.p2p = p2ps
 
 	if err = p2ps.AddProtocol(pricing.Protocol()); err != nil {
 		return nil, fmt.Errorf("pricing service: %w", err)
@@ -657,15 +912,6 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		logger.Debugf("p2p address: %s", addr)
 	}
 
-	paymentTolerance, ok := new(big.Int).SetString(o.PaymentTolerance, 10)
-	if !ok {
-		return nil, fmt.Errorf("invalid payment tolerance: %s", paymentTolerance)
-	}
-	paymentEarly, ok := new(big.Int).SetString(o.PaymentEarly, 10)
-	if !ok {
-		return nil, fmt.Errorf("invalid payment early: %s", paymentEarly)
-	}
-
 	acc, err := accounting.NewAccounting(
 		paymentThreshold,
 		paymentTolerance,
@@ -680,8 +926,10 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		return nil, fmt.Errorf("accounting: %w", err)
 	}
 	b.accountingCloser = acc
+	b.accounting = acc
+	acc.SetReputation(reputationService)
 
-	pseudosettleService := pseudosettle.New(p2ps, logger, stateStore, acc, big.NewInt(refreshRate), p2ps)
+	pseudosettleService := pseudosettle.New(p2ps, logging.Component(logger, "settlement"), stateStore, acc, big.NewInt(refreshRate), p2ps)
 	if err = p2ps.AddProtocol(pseudosettleService.Protocol()); err != nil {
 		return nil, fmt.Errorf("pseudosettle service: %w", err)
 	}
@@ -692,7 +940,7 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		var priceOracle priceoracle.Service
 		swapService, priceOracle, err = InitSwap(
 			p2ps,
-			logger,
+			logging.Component(logger, "settlement"),
 			stateStore,
 			networkID,
 			overlayEthAddress,
@@ -713,11 +961,16 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 
 	pricing.SetPaymentThresholdObserver(acc)
 
-	retrieve := retrieval.New(swarmAddress, storer, p2ps, kad, logger, acc, pricer, tracer)
+	retrieve := retrieval.New(swarmAddress, storer, p2ps, kad, logging.Component(logger, "retrieval"), acc, pricer, tracer, o.RetrievalCaching, retrieval.Timeouts{
+		Hop:     o.RetrievalHopTimeout,
+		Request: o.RetrievalRequestTimeout,
+		Retry:   o.RetrievalRetryInterval,
+	})
+	retrieve.SetReputation(reputationService)
 	tagService := tags.NewTags(stateStore, logger)
 	b.tagsCloser = tagService
 
-	pssService := pss.New(pssPrivateKey, logger)
+	pssService := pss.New(pssPrivateKey, logging.Component(logger, "pss"))
 	b.pssCloser = pssService
 
 	var ns storage.Storer
@@ -731,9 +984,21 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 
 	traversalService := traversal.New(ns)
 
-	pinningService := pinning.NewService(storer, stateStore, traversalService)
+	var (
+		manifestCache       *nodecache.Cache
+		pinningInvalidators []pinning.Invalidator
+	)
+	if o.ManifestCacheCapacity > 0 {
+		manifestCache = nodecache.New(int64(o.ManifestCacheCapacity))
+		// drop the cached manifest nodes of un-pinned content
+		pinningInvalidators = append(pinningInvalidators, manifestCache)
+	}
+
+	pinningService := pinning.NewService(storer, stateStore, traversalService, pinningInvalidators...)
+
+	pushSyncProtocol := pushsync.New(swarmAddress, blockHash, p2ps, storer, kad, tagService, o.FullNodeMode, pssService.TryUnwrap, validStamp, logging.Component(logger, "pushsync"), acc, pricer, signer, tracer, warmupTime)
 
-	pushSyncProtocol := pushsync.New(swarmAddress, blockHash, p2ps, storer, kad, tagService, o.FullNodeMode, pssService.TryUnwrap, validStamp, logger, acc, pricer, signer, tracer, warmupTime)
+	pushSyncProtocol.SetReputation(reputationService)
 
 	// set the pushSyncer in the PSS
 	pssService.SetPushSyncer(pushSyncProtocol)
@@ -744,17 +1009,36 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		b.recoveryHandleCleanup = pssService.Register(recovery.Topic, chunkRepairHandler)
 	}
 
-	pusherService := pusher.New(networkID, storer, kad, pushSyncProtocol, tagService, logger, tracer, warmupTime)
+	pusherService := pusher.New(networkID, storer, kad, pushSyncProtocol, tagService, logging.Component(logger, "pusher"), tracer, warmupTime)
 	b.pusherCloser = pusherService
 
 	pullStorage := pullstorage.New(storer)
 
-	pullSyncProtocol := pullsync.New(p2ps, pullStorage, pssService.TryUnwrap, validStamp, logger)
+	pullSyncProtocol := pullsync.New(p2ps, pullStorage, pssService.TryUnwrap, validStamp, logging.Component(logger, "pullsync"))
 	b.pullSyncCloser = pullSyncProtocol
 
-	var pullerService *puller.Puller
+	var (
+		snapshotService *snapshot.Service
+		pullerService   *puller.Puller
+	)
 	if o.FullNodeMode {
-		pullerService := puller.New(stateStore, kad, pullSyncProtocol, logger, puller.Options{}, warmupTime)
+		signers := make([]common.Address, 0, len(o.SnapshotSigners))
+		for _, a := range o.SnapshotSigners {
+			if !common.IsHexAddress(a) {
+				return nil, fmt.Errorf("malformed snapshot signer address %q", a)
+			}
+			signers = append(signers, common.HexToAddress(a))
+		}
+		snapshotService = snapshot.New(p2ps, storer, stateStore, signer, swarmAddress, kad.NeighborhoodDepth, validStamp, logging.Component(logger, "snapshot"), snapshot.Options{
+			Sources: o.SnapshotSources,
+			Signers: signers,
+			TempDir: o.DataDir,
+			MaxSize: snapshot.MaxSize(reserveCapacity),
+		})
+		b.snapshotCloser = snapshotService
+
+		// the pull sync starts after the snapshot is imported
+		pullerService = puller.New(stateStore, kad, pullSyncProtocol, logging.Component(logger, "puller"), puller.Options{Wait: snapshotService.Done()}, warmupTime)
 		b.pullerCloser = pullerService
 	}
 
@@ -780,23 +1064,153 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 	if err = p2ps.AddProtocol(pullSyncProtocolSpec); err != nil {
 		return nil, fmt.Errorf("pullsync protocol: %w", err)
 	}
+	if snapshotService != nil {
+		if err = p2ps.AddProtocol(snapshotService.Protocol()); err != nil {
+			return nil, fmt.Errorf("snapshot service: %w", err)
+		}
+		snapshotService.Start(warmupTime)
+	}
 
-	multiResolver := multiresolver.NewMultiResolver(
-		multiresolver.WithConnectionConfigs(o.ResolverConnectionCfgs),
-		multiresolver.WithLogger(o.Logger),
-	)
+	pssMailbox, err := mailbox.New(p2ps, kad, stateStore, pssPrivateKey, validStamp, logging.Component(logger, "pssmailbox"), mailbox.Options{
+		Holder:   o.PssMailbox && o.FullNodeMode,
+		MaxTTL:   o.PssMailboxMaxTTL,
+		Capacity: o.PssMailboxCapacity,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pss mailbox: %w", err)
+	}
+	b.pssMailboxCloser = pssMailbox
+	if o.PssMailbox && o.FullNodeMode {
+		if err = p2ps.AddProtocol(pssMailbox.Protocol()); err != nil {
+			return nil, fmt.Errorf("pss mailbox service: %w", err)
+		}
+	}
+
+	multiResolver := newReloadableResolver(o.ResolverConnectionCfgs, o.Logger)
 	b.resolverCloser = multiResolver
+	b.resolver = multiResolver
+	nameResolver := &supervisedResolver{Interface: multiResolver, group: supervisorGroup}
+
+	var (
+		uploadScanner   uploadscan.Scanner
+		uploadScanAudit uploadscan.Audit
+	)
+	if o.UploadScanEndpoint != "" {
+		uploadScanner, err = uploadscan.New(o.UploadScanEndpoint, o.UploadScanTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("upload scanner: %w", err)
+		}
+		uploadScanAudit = uploadscan.NewAudit(stateStore)
+	}
+
+	var takedownService *takedown.Service
+	if len(o.TakedownSigners) > 0 {
+		signers := make([]common.Address, 0, len(o.TakedownSigners))
+		for _, a := range o.TakedownSigners {
+			if !common.IsHexAddress(a) {
+				return nil, fmt.Errorf("malformed takedown signer address %q", a)
+			}
+			signers = append(signers, common.HexToAddress(a))
+		}
+		if takedownService, err = takedown.New(stateStore, pinningService, logger, signers); err != nil {
+			return nil, fmt.Errorf("takedown: %w", err)
+		}
+	}
+
+	var repairBatch []byte
+	if o.RepairPostageBatch != "" {
+		if repairBatch, err = hex.DecodeString(o.RepairPostageBatch); err != nil || len(repairBatch) != 32 {
+			return nil, errors.New("malformed repair postage batch id")
+		}
+	}
+	// the repaired chunks are traversed in the local store only
+	repairService := repair.New(pinningService, storer, traversal.New(storer), retrieve, pushSyncProtocol, post, signer, logging.Component(logger, "repair"), repair.Options{
+		Interval:     o.RepairInterval,
+		Budget:       o.RepairBudget,
+		PostageBatch: repairBatch,
+	})
+	repairService.Start()
+	b.repairCloser = repairService
 
 	var apiService api.Service
 	if o.APIAddr != "" {
 		// API server
 		feedFactory := factory.New(ns)
 		steward := steward.New(storer, traversalService, pushSyncProtocol)
-		apiService = api.New(tagService, ns, multiResolver, pssService, traversalService, pinningService, feedFactory, post, postageContractService, steward, signer, logger, tracer, api.Options{
+		var dedupIndex dedup.Index
+		if o.UploadDedup {
+			dedupIndex = dedup.New(stateStore)
+		}
+		var (
+			s3Buckets      s3.Buckets
+			s3PostageBatch []byte
+			s3Credentials  s3.Credentials
+		)
+		if o.S3Enable || o.S3APIAddr != "" {
+			s3Buckets = s3.NewBuckets(stateStore)
+			if o.S3PostageBatch != "" {
+				if s3PostageBatch, err = hex.DecodeString(o.S3PostageBatch); err != nil || len(s3PostageBatch) != 32 {
+					return nil, errors.New("malformed s3 postage batch id")
+				}
+			}
+		}
+		if o.S3APIAddr != "" {
+			if o.S3Credentials == "" {
+				return nil, errors.New("s3 api requires the s3 credentials")
+			}
+			if s3Credentials, err = s3.LoadCredentials(o.S3Credentials); err != nil {
+				return nil, fmt.Errorf("s3 credentials: %w", err)
+			}
+		}
+		var (
+			stampingService stamping.Service
+			stampingBatch   []byte
+		)
+		if o.StampingClients != "" {
+			if stampingBatch, err = hex.DecodeString(o.StampingBatch); err != nil || len(stampingBatch) != 32 {
+				return nil, errors.New("malformed stamping batch id")
+			}
+			clients, err := stamping.LoadClients(o.StampingClients)
+			if err != nil {
+				return nil, fmt.Errorf("stamping clients: %w", err)
+			}
+			if stampingService, err = stamping.New(stateStore, clients); err != nil {
+				return nil, err
+			}
+		}
+		var uploadPolicies *uploadpolicy.Policies
+		if o.UploadPolicies != "" {
+			if uploadPolicies, err = uploadpolicy.Load(o.UploadPolicies); err != nil {
+				return nil, fmt.Errorf("upload policies: %w", err)
+			}
+		}
+		var gatewayLimits *api.GatewayLimits
+		if o.GatewayMode {
+			if gatewayLimits, err = parseGatewayLimits(o); err != nil {
+				return nil, fmt.Errorf("gateway limits: %w", err)
+			}
+		}
+		apiService = api.New(tagService, ns, nameResolver, pssService, traversalService, pinningService, feedFactory, post, postageContractService, steward, signer, logging.Component(logger, "api"), tracer, api.Options{
 			CORSAllowedOrigins: o.CORSAllowedOrigins,
 			Authorization:      o.DashboardAuthorization,
 			GatewayMode:        o.GatewayMode,
 			WsPingPeriod:       60 * time.Second,
+			DedupIndex:         dedupIndex,
+			S3Buckets:          s3Buckets,
+			S3PostageBatch:     s3PostageBatch,
+			S3Credentials:      s3Credentials,
+			UploadScanner:      uploadScanner,
+			UploadScanAudit:    uploadScanAudit,
+			ManifestCache:      manifestCache,
+			Stamping:           stampingService,
+			StampingBatch:      stampingBatch,
+			UploadPolicies:     uploadPolicies,
+			Takedown:           takedownService,
+			GatewayLimits:      gatewayLimits,
+			Repair:             repairService,
+			UploadSessions:     uploadsession.New(stateStore, ns),
+			PssMailbox:         pssMailbox,
+			ValidStamp:         validStamp,
 		})
 		apiListener, err := net.Listen("tcp", o.APIAddr)
 		if err != nil {
@@ -810,22 +1224,61 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 			ErrorLog:          log.New(b.errorLogWriter, "", 0),
 		}
 
-		go func() {
-			logger.Infof("api address: %s", apiListener.Addr())
-
-			if err := apiServer.Serve(apiListener); err != nil && err != http.ErrServerClosed {
-				logger.Debugf("api server: %v", err)
-				logger.Error("unable to serve api")
-			}
-		}()
+		serveSupervised(supervisorGroup, "api", logger, apiServer, apiListener)
 
 		b.apiServer = apiServer
 		b.apiCloser = apiService
+		b.apiService = apiService
+
+		if o.S3APIAddr != "" {
+			s3APIListener, err := net.Listen("tcp", o.S3APIAddr)
+			if err != nil {
+				return nil, fmt.Errorf("s3 api listener: %w", err)
+			}
+
+			s3APIServer := &http.Server{
+				IdleTimeout:       30 * time.Second,
+				ReadHeaderTimeout: 3 * time.Second,
+				Handler:           apiService.S3Handler(),
+				ErrorLog:          log.New(b.errorLogWriter, "", 0),
+			}
+
+			serveSupervised(supervisorGroup, "s3 api", logger, s3APIServer, s3APIListener)
+
+			b.s3APIServer = s3APIServer
+		}
+	}
+
+	taskScheduler, err := scheduler.New(stateStore, logger)
+	if err != nil {
+		return nil, err
+	}
+	if swapService != nil {
+		taskScheduler.Register(swap.CashoutTaskType, swap.CashoutTask{Swap: swapService})
+	}
+	taskScheduler.Start()
+	b.schedulerCloser = taskScheduler
+
+	var commitmentService *commitment.Service
+	if o.CommitmentInterval > 0 {
+		var batch []byte
+		if o.CommitmentPostageBatch != "" {
+			if batch, err = hex.DecodeString(o.CommitmentPostageBatch); err != nil || len(batch) != 32 {
+				return nil, errors.New("malformed commitment postage batch id")
+			}
+		}
+		commitmentService = commitment.New(storer, storer, post, signer, stateStore, logger, commitment.Options{
+			Interval:     o.CommitmentInterval,
+			PostageBatch: batch,
+		})
+		commitmentService.Start()
+		b.commitmentCloser = commitmentService
 	}
 
 	if debugAPIService != nil {
 		// register metrics from components
 		debugAPIService.MustRegisterMetrics(p2ps.Metrics()...)
+		debugAPIService.MustRegisterMetrics(supervisorGroup.Metrics()...)
 		debugAPIService.MustRegisterMetrics(pingPong.Metrics()...)
 		debugAPIService.MustRegisterMetrics(acc.Metrics()...)
 		debugAPIService.MustRegisterMetrics(storer.Metrics()...)
@@ -834,13 +1287,19 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		if pullerService != nil {
 			debugAPIService.MustRegisterMetrics(pullerService.Metrics()...)
 		}
+		if snapshotService != nil {
+			debugAPIService.MustRegisterMetrics(snapshotService.Metrics()...)
+		}
 
 		debugAPIService.MustRegisterMetrics(pushSyncProtocol.Metrics()...)
 		debugAPIService.MustRegisterMetrics(pusherService.Metrics()...)
 		debugAPIService.MustRegisterMetrics(pullSyncProtocol.Metrics()...)
 		debugAPIService.MustRegisterMetrics(pullStorage.Metrics()...)
 		debugAPIService.MustRegisterMetrics(retrieve.Metrics()...)
+		debugAPIService.MustRegisterMetrics(repairService.Metrics()...)
+		debugAPIService.MustRegisterMetrics(postageManager.Metrics()...)
 		debugAPIService.MustRegisterMetrics(lightNodes.Metrics()...)
+		debugAPIService.MustRegisterMetrics(reputationService.Metrics()...)
 
 		if bs, ok := batchStore.(metrics.Collector); ok {
 			debugAPIService.MustRegisterMetrics(bs.Metrics()...)
@@ -855,34 +1314,130 @@ func NewAnt(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		if pssServiceMetrics, ok := pssService.(metrics.Collector); ok {
 			debugAPIService.MustRegisterMetrics(pssServiceMetrics.Metrics()...)
 		}
+		debugAPIService.MustRegisterMetrics(pssMailbox.Metrics()...)
 
 		if apiService != nil {
 			debugAPIService.MustRegisterMetrics(apiService.Metrics()...)
 		}
+		if manifestCache != nil {
+			debugAPIService.MustRegisterMetrics(manifestCache.Metrics()...)
+		}
 		if l, ok := logger.(metrics.Collector); ok {
 			debugAPIService.MustRegisterMetrics(l.Metrics()...)
 		}
+		if s, ok := signer.(metrics.Collector); ok {
+			debugAPIService.MustRegisterMetrics(s.Metrics()...)
+		}
 
 		debugAPIService.MustRegisterMetrics(pseudosettleService.Metrics()...)
+		debugAPIService.MustRegisterMetrics(mine.TEEMetrics()...)
+		if m, ok := mineSvr.(metrics.Collector); ok {
+			debugAPIService.MustRegisterMetrics(m.Metrics()...)
+		}
 
+		if swapBackend != nil {
+			debugAPIService.MustRegisterMetrics(swapBackend.Metrics()...)
+		}
 		if swapService != nil {
 			debugAPIService.MustRegisterMetrics(swapService.Metrics()...)
 		}
 
+		var keyBackup *backup.Exporter
+		if o.Keystore != nil && o.KeysBackupEnable {
+			keyBackup = backup.NewExporter(o.Keystore, stateStore, o.Clef)
+		}
+
+		identityClaimService, err := identityclaim.New(swarmAddress, signer, teeDevice)
+		if err != nil {
+			return nil, err
+		}
+
 		// inject dependencies and configure full debug api http path routes
-		debugAPIService.Configure(swarmAddress, p2ps, pingPong, kad, lightNodes, storer, tagService, acc, pseudosettleService, o.SwapEnable, swapService, chequebookService, batchStore, post, postageContractService, o.MineEnabled, mineSvr)
+		debugOptions := debugapi.Options{
+			UploadScanAudit: uploadScanAudit,
+			Scheduler:       taskScheduler,
+			Commitment:      commitmentService,
+			Takedown:        takedownService,
+			Reloader:        o.Reloader,
+			Drainer:         b,
+			KeyBackup:       keyBackup,
+			PostageManager:  postageManager,
+			PeerReputation:  reputationService,
+			IdentityClaim:   identityClaimService,
+		}
+		if snapshotService != nil {
+			debugOptions.Snapshots = snapshotService
+		}
+		if pullerService != nil {
+			debugOptions.PullSync = pullerService
+		}
+		debugAPIService.Configure(swarmAddress, p2ps, pingPong, kad, lightNodes, storer, tagService, acc, pseudosettleService, o.SwapEnable, swapService, chequebookService, batchStore, post, postageContractService, o.MineEnabled, mineSvr, debugOptions)
+	}
+
+	if len(o.ReportPeriods) > 0 {
+		var ro report.Options
+		for _, name := range o.ReportPeriods {
+			p, err := report.ParsePeriod(name)
+			if err != nil {
+				return nil, err
+			}
+			ro.Periods = append(ro.Periods, p)
+		}
+		for _, url := range o.ReportWebhooks {
+			ro.Deliverers = append(ro.Deliverers, report.NewWebhook(url))
+		}
+		if o.ReportSMTPAddr != "" {
+			mailer, err := report.NewSMTP(report.SMTPOptions{
+				Addr:     o.ReportSMTPAddr,
+				Username: o.ReportSMTPUsername,
+				Password: o.ReportSMTPPassword,
+				From:     o.ReportSMTPFrom,
+				To:       o.ReportSMTPTo,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("report: %w", err)
+			}
+			ro.Deliverers = append(ro.Deliverers, mailer)
+		}
+		if len(ro.Deliverers) == 0 {
+			return nil, errors.New("report: no webhook or smtp server to deliver the reports to")
+		}
+
+		// the report metrics are gathered independently of the debug api
+		reportRegistry := prometheus.NewRegistry()
+		reportRegistry.MustRegister(storer.Metrics()...)
+		reportRegistry.MustRegister(retrieve.Metrics()...)
+		reportRegistry.MustRegister(pushSyncProtocol.Metrics()...)
+		reportRegistry.MustRegister(pullSyncProtocol.Metrics()...)
+
+		sources := report.Sources{
+			Settlements: []settlement.Interface{pseudosettleService},
+			Gatherer:    reportRegistry,
+			Post:        post,
+			Tags:        tagService,
+		}
+		if swapService != nil {
+			sources.Settlements = append(sources.Settlements, swapService)
+		}
+		reportService := report.New(stateStore, logger, sources, ro)
+		reportService.Start()
+		b.reportCloser = reportService
 	}
 
 	if err := kad.Start(p2pCtx); err != nil {
 		return nil, err
 	}
+	go publishDepthChanges(p2pCtx, kad, b.events)
 	p2ps.Ready()
 
 	return b, nil
 }
 
 func (b *Ant) Shutdown(ctx context.Context) error {
-	var mErr error
+	var (
+		mErr error
+		mu   sync.Mutex
+	)
 
 	// if a shutdown is already in process, return here
 	b.shutdownMutex.Lock()
@@ -891,55 +1446,117 @@ func (b *Ant) Shutdown(ctx context.Context) error {
 		return ErrShutdownInProgress
 	}
 	b.shutdownInProgress = true
+	recorder := newShutdownRecorder()
+	b.shutdownRecorder = recorder
 	b.shutdownMutex.Unlock()
 
+	b.events.Publish(events.Shutdown, events.ShutdownData{Phase: events.ShutdownStarted})
+
+	// appendErr is safe to be called from multiple goroutines.
+	appendErr := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		mErr = multierror.Append(mErr, err)
+	}
+
 	// halt kademlia while shutting down other
 	// components.
-	b.topologyHalter.Halt()
+	if b.topologyHalter != nil {
+		b.topologyHalter.Halt()
+	}
 
 	// halt p2p layer from accepting new connections
 	// while shutting down other components
-	b.p2pHalter.Halt()
+	if b.p2pHalter != nil {
+		b.p2pHalter.Halt()
+	}
 	// tryClose is a convenient closure which decrease
 	// repetitive io.Closer tryClose procedure.
 	tryClose := func(c io.Closer, errMsg string) {
 		if c == nil {
 			return
 		}
-		if err := c.Close(); err != nil {
-			mErr = multierror.Append(mErr, fmt.Errorf("%s: %w", errMsg, err))
+		done := recorder.track(errMsg)
+		err := c.Close()
+		done(err)
+		if err != nil {
+			appendErr(fmt.Errorf("%s: %w", errMsg, err))
 		}
 	}
 
 	tryClose(b.apiCloser, "api")
 
-	var eg errgroup.Group
+	// stop accepting new uploads and wait for the in-flight ones
 	if b.apiServer != nil {
+		done := recorder.track("api server")
+		err := b.apiServer.Shutdown(ctx)
+		done(err)
+		if err != nil {
+			appendErr(fmt.Errorf("api server: %w", err))
+		}
+	}
+	if b.s3APIServer != nil {
+		done := recorder.track("s3 api server")
+		err := b.s3APIServer.Shutdown(ctx)
+		done(err)
+		if err != nil {
+			appendErr(fmt.Errorf("s3 api server: %w", err))
+		}
+	}
+
+	// the debug api stays available to report the drain progress
+	done := recorder.track("drain")
+	drainCtx, cancelDrain := drainContext(ctx)
+	err := b.drain(drainCtx)
+	cancelDrain()
+	done(err)
+	if err != nil {
+		appendErr(fmt.Errorf("drain: %w", err))
+	}
+	// the event streams end before the debug api server shuts down
+	tryClose(b.events, "events")
+
+	var eg errgroup.Group
+	if b.debugAPIServer != nil {
 		eg.Go(func() error {
-			if err := b.apiServer.Shutdown(ctx); err != nil {
-				return fmt.Errorf("api server: %w", err)
+			done := recorder.track("debug api server")
+			err := b.debugAPIServer.Shutdown(ctx)
+			done(err)
+			if err != nil {
+				return fmt.Errorf("debug api server: %w", err)
 			}
 			return nil
 		})
 	}
-	if b.debugAPIServer != nil {
+	if b.standbyServer != nil {
 		eg.Go(func() error {
-			if err := b.debugAPIServer.Shutdown(ctx); err != nil {
-				return fmt.Errorf("debug api server: %w", err)
+			done := recorder.track("standby server")
+			err := b.standbyServer.Shutdown(ctx)
+			done(err)
+			if err != nil {
+				return fmt.Errorf("standby server: %w", err)
 			}
 			return nil
 		})
 	}
 
 	if err := eg.Wait(); err != nil {
-		mErr = multierror.Append(mErr, err)
+		appendErr(err)
 	}
 
+	tryClose(b.supervisorCloser, "supervisor")
+	tryClose(b.reportCloser, "report")
+	tryClose(b.schedulerCloser, "scheduler")
+	tryClose(b.commitmentCloser, "commitment")
+	tryClose(b.repairCloser, "repair")
+	tryClose(b.postageManagerCloser, "postage manager")
+	tryClose(b.pssMailboxCloser, "pss mailbox")
+
 	if b.recoveryHandleCleanup != nil {
 		b.recoveryHandleCleanup()
 	}
 	var wg sync.WaitGroup
-	wg.Add(6)
+	wg.Add(7)
 	go func() {
 		defer wg.Done()
 		tryClose(b.pssCloser, "pss")
@@ -952,6 +1569,10 @@ func (b *Ant) Shutdown(ctx context.Context) error {
 		defer wg.Done()
 		tryClose(b.pullerCloser, "puller")
 	}()
+	go func() {
+		defer wg.Done()
+		tryClose(b.snapshotCloser, "snapshot")
+	}()
 	go func() {
 		defer wg.Done()
 		tryClose(b.accountingCloser, "accounting")
@@ -971,6 +1592,7 @@ func (b *Ant) Shutdown(ctx context.Context) error {
 	wg.Wait()
 
 	tryClose(b.p2pService, "p2p server")
+	tryClose(b.reputationCloser, "reputation")
 	tryClose(b.priceOracleCloser, "price oracle service")
 
 	wg.Add(3)
@@ -991,7 +1613,9 @@ func (b *Ant) Shutdown(ctx context.Context) error {
 	wg.Wait()
 
 	if c := b.ethClientCloser; c != nil {
+		done := recorder.track("eth client")
 		c()
+		done(nil)
 	}
 
 	tryClose(b.tracerCloser, "tracer")
@@ -1005,27 +1629,45 @@ func (b *Ant) Shutdown(ctx context.Context) error {
 	return mErr
 }
 
+// ShutdownReport returns a snapshot of the shutdown progress. Components that
+// have not yet stopped are listed as forced. It returns nil if the shutdown
+// has not been started.
+func (b *Ant) ShutdownReport() *ShutdownReport {
+	b.shutdownMutex.Lock()
+	recorder := b.shutdownRecorder
+	b.shutdownMutex.Unlock()
+
+	if recorder == nil {
+		return nil
+	}
+	return recorder.report()
+}
+
+// Fatal returns a channel that receives an error if a node component
+// encountered an unrecoverable error and the node needs to be shut down.
+func (b *Ant) Fatal() <-chan error {
+	return b.fatalC
+}
+
 // pidKiller is used to issue a forced shut down of the node from sub modules. The issue with using the
 // node's Shutdown method is that it only shuts down the node and does not exit the start process
 // which is waiting on the os.Signals. This is not desirable, but currently bee node cannot handle
 // rate-limiting blockchain API calls properly. We will shut down the node in this case to allow the
 // user to rectify the API issues (by adjusting limits or using a different one). There is no platform
-// agnostic way to trigger os.Signals in go unfortunately. Which is why we will use the process.Kill
-// approach which works on windows as well.
+// agnostic way to trigger os.Signals in go unfortunately. Which is why the start process is notified
+// through the node's Fatal channel instead, so that it can record the shutdown and exit.
 type pidKiller struct {
 	node *Ant
 }
 
-var ErrShutdownInProgress error = errors.New("shutdown in progress")
+var (
+	ErrShutdownInProgress error = errors.New("shutdown in progress")
+	errFatal                    = errors.New("fatal error in node component")
+)
 
 func (p *pidKiller) Shutdown(ctx context.Context) error {
-	err := p.node.Shutdown(ctx)
-	if err != nil {
-		return err
-	}
-	ps, err := os.FindProcess(syscall.Getpid())
-	if err != nil {
-		return err
-	}
-	return ps.Kill()
+	p.node.fatalOnce.Do(func() {
+		p.node.fatalC <- errFatal
+	})
+	return nil
 }
@@ -0,0 +1,139 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/resolver"
+	"github.com/ethsana/sana/pkg/resolver/multiresolver"
+)
+
+// ErrPaymentThresholdsLightRemote is returned if the payment thresholds are
+// changed in the light-remote mode, in which the node has no peers to account
+// with.
+var ErrPaymentThresholdsLightRemote = errors.New("payment thresholds can't be reloaded in the light-remote mode")
+
+// parsePaymentThresholds parses and validates the payment threshold, the
+// payment tolerance and the early payment.
+func parsePaymentThresholds(threshold, tolerance, early string) (paymentThreshold, paymentTolerance, paymentEarly *big.Int, err error) {
+	minThreshold := big.NewInt(2 * refreshRate)
+	maxThreshold := big.NewInt(24 * refreshRate)
+
+	paymentThreshold, ok := new(big.Int).SetString(threshold, 10)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("invalid payment threshold: %s", threshold)
+	}
+	if paymentThreshold.Cmp(minThreshold) < 0 {
+		return nil, nil, nil, fmt.Errorf("payment threshold below minimum generally accepted value, need at least %s", minThreshold)
+	}
+	if paymentThreshold.Cmp(maxThreshold) > 0 {
+		return nil, nil, nil, fmt.Errorf("payment threshold above maximum generally accepted value, needs to be reduced to at most %s", maxThreshold)
+	}
+	paymentTolerance, ok = new(big.Int).SetString(tolerance, 10)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("invalid payment tolerance: %s", tolerance)
+	}
+	paymentEarly, ok = new(big.Int).SetString(early, 10)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("invalid payment early: %s", early)
+	}
+	return paymentThreshold, paymentTolerance, paymentEarly, nil
+}
+
+// SetPaymentThresholds changes the payment thresholds of the running node. The
+// new payment threshold is announced to the connected peers.
+func (b *Ant) SetPaymentThresholds(threshold, tolerance, early string) error {
+	paymentThreshold, paymentTolerance, paymentEarly, err := parsePaymentThresholds(threshold, tolerance, early)
+	if err != nil {
+		return err
+	}
+	if b.accounting == nil || b.pricing == nil || b.p2p == nil {
+		return ErrPaymentThresholdsLightRemote
+	}
+	b.accounting.SetPaymentThresholds(paymentThreshold, paymentTolerance, paymentEarly)
+	b.pricing.SetPaymentThreshold(paymentThreshold)
+
+	go func() {
+		for _, p := range b.p2p.Peers() {
+			if err := b.pricing.AnnouncePaymentThreshold(context.Background(), p.Address, paymentThreshold); err != nil {
+				b.logger.Debugf("reload: announce payment threshold to peer %s: %v", p.Address, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// SetCORSAllowedOrigins changes the origins which are allowed to make
+// cross-origin requests to the api and the debug api.
+func (b *Ant) SetCORSAllowedOrigins(origins []string) {
+	if b.apiService != nil {
+		b.apiService.SetCORSAllowedOrigins(origins)
+	}
+	if b.debugAPIService != nil {
+		b.debugAPIService.SetCORSAllowedOrigins(origins)
+	}
+}
+
+// SetResolverConnectionConfigs replaces the name resolvers of the running
+// node.
+func (b *Ant) SetResolverConnectionConfigs(cfgs []multiresolver.ConnectionConfig) error {
+	return b.resolver.set(cfgs)
+}
+
+// reloadableResolver resolves names with the multiresolver which is replaced
+// when the resolver endpoints change.
+type reloadableResolver struct {
+	logger logging.Logger
+
+	mu       sync.RWMutex
+	resolver *multiresolver.MultiResolver
+}
+
+var _ resolver.Interface = (*reloadableResolver)(nil)
+
+func newReloadableResolver(cfgs []multiresolver.ConnectionConfig, logger logging.Logger) *reloadableResolver {
+	r := &reloadableResolver{logger: logger}
+	r.resolver = r.newResolver(cfgs)
+	return r
+}
+
+func (r *reloadableResolver) newResolver(cfgs []multiresolver.ConnectionConfig) *multiresolver.MultiResolver {
+	return multiresolver.NewMultiResolver(
+		multiresolver.WithConnectionConfigs(cfgs),
+		multiresolver.WithLogger(r.logger),
+	)
+}
+
+func (r *reloadableResolver) Resolve(name string) (resolver.Address, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.resolver.Resolve(name)
+}
+
+func (r *reloadableResolver) set(cfgs []multiresolver.ConnectionConfig) error {
+	mr := r.newResolver(cfgs)
+
+	r.mu.Lock()
+	old := r.resolver
+	r.resolver = mr
+	r.mu.Unlock()
+
+	if err := old.Close(); err != nil {
+		return fmt.Errorf("close resolver: %w", err)
+	}
+	return nil
+}
+
+func (r *reloadableResolver) Close() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.resolver.Close()
+}
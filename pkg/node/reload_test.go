@@ -0,0 +1,27 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/node"
+)
+
+func TestSetPaymentThresholdsLightRemote(t *testing.T) {
+	b, err := node.NewLightRemoteAnt(&node.Options{
+		RemoteAPIURL: "http://127.0.0.1:1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Shutdown(context.Background())
+
+	if err := b.SetPaymentThresholds("10000000", "25", "50"); !errors.Is(err, node.ErrPaymentThresholdsLightRemote) {
+		t.Fatalf("got error %v, want %v", err, node.ErrPaymentThresholdsLightRemote)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethsana/sana/pkg/p2p"
+	"github.com/ethsana/sana/pkg/reputation"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+// reputationPolicy returns the connection policy of the options.
+func reputationPolicy(o *Options) (reputation.Policy, error) {
+	if o.ReputationBanThreshold > 0 {
+		return reputation.Policy{}, fmt.Errorf("positive reputation ban threshold %v", o.ReputationBanThreshold)
+	}
+	p := reputation.Policy{
+		BanThreshold: o.ReputationBanThreshold,
+		BanDuration:  o.ReputationBanDuration,
+	}
+	for _, v := range o.ReputationPreferredPeers {
+		addr, err := swarm.ParseHexAddress(v)
+		if err != nil {
+			return reputation.Policy{}, fmt.Errorf("preferred peer %q: %w", v, err)
+		}
+		p.PreferredPeers = append(p.PreferredPeers, addr)
+	}
+	var err error
+	if p.BlockedNetworks, err = reputation.ParseCIDRs(o.ReputationBlockedCIDRs); err != nil {
+		return reputation.Policy{}, err
+	}
+	return p, nil
+}
+
+// preferredPeersNotifier accepts the connections of the preferred peers of
+// the connection policy which the wrapped notifier would reject because it
+// does not want more peers.
+type preferredPeersNotifier struct {
+	p2p.PickyNotifier
+	reputation *reputation.Service
+}
+
+func (n *preferredPeersNotifier) Pick(peer p2p.Peer) bool {
+	return n.reputation.Preferred(peer.Address) || n.PickyNotifier.Pick(peer)
+}
+
+func (n *preferredPeersNotifier) Connected(ctx context.Context, peer p2p.Peer, forceConnection bool) error {
+	return n.PickyNotifier.Connected(ctx, peer, forceConnection || n.reputation.Preferred(peer.Address))
+}
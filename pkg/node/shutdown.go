@@ -0,0 +1,119 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ShutdownReason describes why the node has been shut down.
+type ShutdownReason string
+
+const (
+	// ShutdownReasonSignal is used when the process received an interrupt
+	// or termination signal.
+	ShutdownReasonSignal ShutdownReason = "signal"
+	// ShutdownReasonTimebomb is used when the node version has exceeded its
+	// supported lifetime and the node is shut down for it. The timebomb of
+	// the start command only warns about outdated versions.
+	ShutdownReasonTimebomb ShutdownReason = "timebomb"
+	// ShutdownReasonFatal is used when a node component encountered an
+	// unrecoverable error.
+	ShutdownReasonFatal ShutdownReason = "fatal"
+	// ShutdownReasonMaintenance is used when the node is stopped by an
+	// operator or a service manager for maintenance.
+	ShutdownReasonMaintenance ShutdownReason = "maintenance"
+)
+
+// ComponentShutdown holds the stop information of a single node component.
+type ComponentShutdown struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// ShutdownReport is a structured summary of the node shutdown.
+type ShutdownReport struct {
+	Reason     ShutdownReason      `json:"reason"`
+	Error      string              `json:"error,omitempty"`
+	StartedAt  time.Time           `json:"startedAt"`
+	Duration   time.Duration       `json:"duration"`
+	Components []ComponentShutdown `json:"components"`
+	// Forced lists the components that did not stop before the shutdown was
+	// forcefully terminated.
+	Forced []string `json:"forced,omitempty"`
+}
+
+// WriteFile writes the report as JSON to the file at the given path.
+func (r *ShutdownReport) WriteFile(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// shutdownRecorder records component stop durations while the node is
+// shutting down. It is safe for concurrent use.
+type shutdownRecorder struct {
+	mu         sync.Mutex
+	startedAt  time.Time
+	components []ComponentShutdown
+	pending    map[string]struct{}
+}
+
+func newShutdownRecorder() *shutdownRecorder {
+	return &shutdownRecorder{
+		startedAt: time.Now(),
+		pending:   make(map[string]struct{}),
+	}
+}
+
+// track marks the component as stopping and returns a function that records
+// its stop duration and error.
+func (s *shutdownRecorder) track(name string) func(err error) {
+	start := time.Now()
+
+	s.mu.Lock()
+	s.pending[name] = struct{}{}
+	s.mu.Unlock()
+
+	return func(err error) {
+		c := ComponentShutdown{
+			Name:     name,
+			Duration: time.Since(start),
+		}
+		if err != nil {
+			c.Error = err.Error()
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.pending, name)
+		s.components = append(s.components, c)
+	}
+}
+
+// report returns a snapshot of the recorded shutdown. Components that are
+// still stopping are reported as forced.
+func (s *shutdownRecorder) report() *ShutdownReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := &ShutdownReport{
+		StartedAt:  s.startedAt,
+		Duration:   time.Since(s.startedAt),
+		Components: append([]ComponentShutdown(nil), s.components...),
+	}
+	for name := range s.pending {
+		r.Forced = append(r.Forced, name)
+	}
+	sort.Strings(r.Forced)
+	return r
+}
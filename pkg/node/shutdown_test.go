@@ -0,0 +1,63 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/node"
+)
+
+func TestShutdownRecorder(t *testing.T) {
+	r := node.NewShutdownRecorder()
+
+	r.Track("api")(nil)
+	r.Track("pusher")(errors.New("pusher error"))
+	r.Track("localstore")
+	r.Track("puller")
+
+	report := r.Report()
+	if len(report.Components) != 2 {
+		t.Fatalf("got %d components, want 2", len(report.Components))
+	}
+	if c := report.Components[0]; c.Name != "api" || c.Error != "" {
+		t.Errorf("got component %+v, want api without error", c)
+	}
+	if c := report.Components[1]; c.Name != "pusher" || c.Error != "pusher error" {
+		t.Errorf("got component %+v, want pusher with error", c)
+	}
+	if want := []string{"localstore", "puller"}; !reflect.DeepEqual(report.Forced, want) {
+		t.Errorf("got forced %v, want %v", report.Forced, want)
+	}
+	if report.StartedAt.IsZero() {
+		t.Error("got no start time")
+	}
+}
+
+func TestShutdownReportWriteFile(t *testing.T) {
+	report := node.NewShutdownRecorder().Report()
+	report.Reason = node.ShutdownReasonTimebomb
+
+	path := filepath.Join(t.TempDir(), "shutdown-report.json")
+	if err := report.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got node.ShutdownReport
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Reason != node.ShutdownReasonTimebomb {
+		t.Errorf("got reason %q, want %q", got.Reason, node.ShutdownReasonTimebomb)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/mine"
+	"github.com/ethsana/sana/pkg/resolver"
+	"github.com/ethsana/sana/pkg/supervisor"
+)
+
+// serveSupervised serves the http server on the listener in a supervised
+// goroutine. If serving fails, the server is restarted on a new listener
+// bound to the same address.
+func serveSupervised(g *supervisor.Group, name string, logger logging.Logger, server *http.Server, l net.Listener) {
+	addr := l.Addr().String()
+	g.Go(name, func(ctx context.Context) error {
+		if l == nil {
+			var err error
+			if l, err = net.Listen("tcp", addr); err != nil {
+				return fmt.Errorf("listen: %w", err)
+			}
+		}
+		logger.Infof("%s address: %s", name, l.Addr())
+
+		// the listener is closed by Serve when it returns
+		err := server.Serve(l)
+		l = nil
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		logger.Debugf("%s server: %v", name, err)
+		logger.Errorf("unable to serve %s", name)
+		return err
+	})
+}
+
+// supervisedResolver recovers panics of the name resolution.
+type supervisedResolver struct {
+	resolver.Interface
+	group *supervisor.Group
+}
+
+func (r *supervisedResolver) Resolve(url string) (addr resolver.Address, err error) {
+	err = r.group.Do("resolver", func() error {
+		addr, err = r.Interface.Resolve(url)
+		return err
+	})
+	return addr, err
+}
+
+// supervisedOracle recovers panics of the price oracle.
+type supervisedOracle struct {
+	mine.Oracle
+	group *supervisor.Group
+}
+
+func (o *supervisedOracle) Price(ctx context.Context) (price *big.Int, err error) {
+	err = o.group.Do("uniswap", func() error {
+		price, err = o.Oracle.Price(ctx)
+		return err
+	})
+	return price, err
+}
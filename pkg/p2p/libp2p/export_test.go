@@ -23,3 +23,8 @@ func (s *Service) NewStreamForPeerID(peerID libp2ppeer.ID, protocolName, protoco
 type StaticAddressResolver = staticAddressResolver
 
 var NewStaticAddressResolver = newStaticAddressResolver
+
+var (
+	NewOnionAddressResolver = newOnionAddressResolver
+	ProxyDialAddress        = proxyDialAddress
+)
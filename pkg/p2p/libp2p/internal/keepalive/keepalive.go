@@ -0,0 +1,220 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package keepalive pings connected peers periodically so that idle
+// connections are not silently dropped by NATs and firewalls. Peers which stop
+// answering the pings are on half-open connections and are reaped.
+package keepalive
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ethsana/sana/pkg/swarm"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// Transport names used as keys of the ping intervals.
+const (
+	TransportTCP  = "tcp"
+	TransportWS   = "ws"
+	TransportQUIC = "quic"
+)
+
+const (
+	// defaults
+	timeout     = 10 * time.Second
+	maxFailures = 2
+)
+
+// PingFunc sends a single ping to the peer and waits for the answer.
+type PingFunc func(ctx context.Context, overlay swarm.Address) error
+
+// ReapFunc is called for a peer which did not answer the configured number of
+// consecutive pings.
+type ReapFunc func(overlay swarm.Address)
+
+type Options struct {
+	// Intervals between pings per transport, transports without an interval
+	// are not pinged.
+	Intervals map[string]time.Duration
+	// Timeout of a single ping.
+	Timeout time.Duration
+	// MaxFailures is the number of consecutive failed pings after which the
+	// connection is considered half-open.
+	MaxFailures int
+}
+
+type Service struct {
+	ping        PingFunc
+	reap        ReapFunc
+	intervals   map[string]time.Duration
+	timeout     time.Duration
+	maxFailures int
+	metrics     metrics
+
+	mu    sync.Mutex
+	peers map[string]chan struct{} // quit channels of the peer ping loops
+	quit  chan struct{}
+	wg    sync.WaitGroup
+}
+
+func New(ping PingFunc, reap ReapFunc, o Options) *Service {
+	s := &Service{
+		ping:        ping,
+		reap:        reap,
+		intervals:   o.Intervals,
+		timeout:     o.Timeout,
+		maxFailures: o.MaxFailures,
+		metrics:     newMetrics(),
+		peers:       make(map[string]chan struct{}),
+		quit:        make(chan struct{}),
+	}
+	if s.timeout <= 0 {
+		s.timeout = timeout
+	}
+	if s.maxFailures <= 0 {
+		s.maxFailures = maxFailures
+	}
+	return s
+}
+
+// Transport returns the transport name of the connection address.
+func Transport(addr ma.Multiaddr) string {
+	for _, p := range addr.Protocols() {
+		switch p.Code {
+		case ma.P_QUIC:
+			return TransportQUIC
+		case ma.P_WS, ma.P_WSS:
+			return TransportWS
+		}
+	}
+	return TransportTCP
+}
+
+// Add starts pinging the peer connected over the transport. Peers which are
+// already pinged are restarted with the interval of the new transport.
+func (s *Service) Add(overlay swarm.Address, transport string) {
+	interval := s.intervals[transport]
+	if interval <= 0 {
+		s.Remove(overlay)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.quit:
+		return
+	default:
+	}
+
+	key := overlay.ByteString()
+	if stop, ok := s.peers[key]; ok {
+		close(stop)
+	}
+	stop := make(chan struct{})
+	s.peers[key] = stop
+	s.metrics.Peers.Set(float64(len(s.peers)))
+
+	s.wg.Add(1)
+	go s.run(overlay, transport, interval, stop)
+}
+
+// Remove stops pinging the peer.
+func (s *Service) Remove(overlay swarm.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := overlay.ByteString()
+	if stop, ok := s.peers[key]; ok {
+		close(stop)
+		delete(s.peers, key)
+		s.metrics.Peers.Set(float64(len(s.peers)))
+	}
+}
+
+// run pings the peer until it is removed. Failed pings are retried after the
+// ping timeout instead of the full interval so that half-open connections are
+// reaped promptly.
+func (s *Service) run(overlay swarm.Address, transport string, interval time.Duration, stop chan struct{}) {
+	defer s.wg.Done()
+
+	retry := s.timeout
+	if retry > interval {
+		retry = interval
+	}
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	var failures int
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		go func() {
+			select {
+			case <-s.quit:
+			case <-stop:
+			case <-ctx.Done():
+			}
+			cancel()
+		}()
+		err := s.ping(ctx, overlay)
+		cancel()
+
+		s.metrics.Pings.WithLabelValues(transport).Inc()
+		if err == nil {
+			failures = 0
+			timer.Reset(interval)
+			continue
+		}
+
+		s.metrics.FailedPings.WithLabelValues(transport).Inc()
+		failures++
+		if failures < s.maxFailures {
+			timer.Reset(retry)
+			continue
+		}
+
+		s.mu.Lock()
+		select {
+		case <-stop:
+			// removed while pinging
+			s.mu.Unlock()
+			return
+		default:
+		}
+		delete(s.peers, overlay.ByteString())
+		s.metrics.Peers.Set(float64(len(s.peers)))
+		s.mu.Unlock()
+
+		s.metrics.ReapedConnections.WithLabelValues(transport).Inc()
+		s.reap(overlay)
+		return
+	}
+}
+
+// Close stops pinging all peers.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	select {
+	case <-s.quit:
+	default:
+		close(s.quit)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keepalive_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/p2p/libp2p/internal/keepalive"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/swarm/test"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestKeepalive(t *testing.T) {
+	var (
+		alive    = test.RandomAddress()
+		halfOpen = test.RandomAddress()
+		quic     = test.RandomAddress()
+
+		mu     sync.Mutex
+		pinged = make(map[string]int)
+		reaped = make(chan swarm.Address, 3)
+	)
+
+	s := keepalive.New(
+		func(ctx context.Context, overlay swarm.Address) error {
+			mu.Lock()
+			pinged[overlay.ByteString()]++
+			mu.Unlock()
+			if overlay.Equal(halfOpen) {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		},
+		func(overlay swarm.Address) {
+			reaped <- overlay
+		},
+		keepalive.Options{
+			Intervals: map[string]time.Duration{
+				keepalive.TransportTCP: 10 * time.Millisecond,
+			},
+			Timeout:     20 * time.Millisecond,
+			MaxFailures: 2,
+		},
+	)
+	defer s.Close()
+
+	s.Add(alive, keepalive.TransportTCP)
+	s.Add(halfOpen, keepalive.TransportTCP)
+	s.Add(quic, keepalive.TransportQUIC)
+
+	select {
+	case overlay := <-reaped:
+		if !overlay.Equal(halfOpen) {
+			t.Fatalf("reaped %s, want %s", overlay, halfOpen)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("half-open connection not reaped")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	s.Remove(alive)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := pinged[halfOpen.ByteString()]; got != 2 {
+		t.Errorf("got %d pings of the half-open peer, want 2", got)
+	}
+	if got := pinged[alive.ByteString()]; got < 2 {
+		t.Errorf("got %d pings of the alive peer, want at least 2", got)
+	}
+	if got := pinged[quic.ByteString()]; got != 0 {
+		t.Errorf("got %d pings of the peer without interval, want none", got)
+	}
+	select {
+	case overlay := <-reaped:
+		t.Errorf("unexpectedly reaped %s", overlay)
+	default:
+	}
+}
+
+func TestKeepaliveRemove(t *testing.T) {
+	var (
+		overlay = test.RandomAddress()
+		pings   = make(chan struct{}, 10)
+	)
+
+	s := keepalive.New(
+		func(ctx context.Context, _ swarm.Address) error {
+			pings <- struct{}{}
+			return errors.New("failed")
+		},
+		func(overlay swarm.Address) {
+			t.Errorf("unexpectedly reaped %s", overlay)
+		},
+		keepalive.Options{
+			Intervals: map[string]time.Duration{
+				keepalive.TransportTCP: 10 * time.Millisecond,
+			},
+			MaxFailures: 3,
+		},
+	)
+	defer s.Close()
+
+	s.Add(overlay, keepalive.TransportTCP)
+	<-pings
+	s.Remove(overlay)
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestTransport(t *testing.T) {
+	for addr, want := range map[string]string{
+		"/ip4/127.0.0.1/tcp/1634":       keepalive.TransportTCP,
+		"/ip4/127.0.0.1/tcp/1634/ws":    keepalive.TransportWS,
+		"/ip6/::1/udp/1634/quic":        keepalive.TransportQUIC,
+		"/dns4/example.com/tcp/443/wss": keepalive.TransportWS,
+	} {
+		a, err := ma.NewMultiaddr(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := keepalive.Transport(a); got != want {
+			t.Errorf("%s: got transport %s, want %s", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keepalive
+
+import (
+	m "github.com/ethsana/sana/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	Pings             *prometheus.CounterVec
+	FailedPings       *prometheus.CounterVec
+	ReapedConnections *prometheus.CounterVec
+	Peers             prometheus.Gauge
+}
+
+func newMetrics() metrics {
+	subsystem := "libp2p_keepalive"
+
+	return metrics{
+		Pings: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: m.Namespace,
+				Subsystem: subsystem,
+				Name:      "pings",
+				Help:      "Number of keepalive pings sent to peers.",
+			},
+			[]string{"transport"},
+		),
+		FailedPings: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: m.Namespace,
+				Subsystem: subsystem,
+				Name:      "failed_pings",
+				Help:      "Number of keepalive pings which were not answered in time.",
+			},
+			[]string{"transport"},
+		),
+		ReapedConnections: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: m.Namespace,
+				Subsystem: subsystem,
+				Name:      "reaped_connections",
+				Help:      "Number of half-open peer connections which were closed.",
+			},
+			[]string{"transport"},
+		),
+		Peers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "peers",
+			Help:      "Number of peers which are pinged.",
+		}),
+	}
+}
+
+func (s *Service) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(s.metrics)
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libp2p
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+// The legacy protocol versions are scaffolding only, none of the protocols
+// of the node sets a p2p.LegacyProtocolSpec yet.
+
+// legacyPeers keeps track of the connected peers which use the legacy
+// version of a protocol.
+type legacyPeers struct {
+	peers map[string]map[string]struct{} // protocol name -> overlay
+	mu    sync.Mutex
+}
+
+func newLegacyPeers() *legacyPeers {
+	return &legacyPeers{peers: make(map[string]map[string]struct{})}
+}
+
+// add records the peer as a user of the legacy protocol version and returns
+// the number of such peers.
+func (l *legacyPeers) add(protocolName string, overlay swarm.Address) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	m, ok := l.peers[protocolName]
+	if !ok {
+		m = make(map[string]struct{})
+		l.peers[protocolName] = m
+	}
+	m[overlay.ByteString()] = struct{}{}
+	return len(m)
+}
+
+// remove forgets the peer for all protocols and returns the number of
+// remaining legacy peers of the protocols the peer used.
+func (l *legacyPeers) remove(overlay swarm.Address) map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var counts map[string]int
+	for name, m := range l.peers {
+		if _, ok := m[overlay.ByteString()]; !ok {
+			continue
+		}
+		delete(m, overlay.ByteString())
+		if counts == nil {
+			counts = make(map[string]int)
+		}
+		counts[name] = len(m)
+	}
+	return counts
+}
+
+// legacyVersion returns the legacy version of the protocol which is served
+// alongside the given version, if it is still within its deprecation window.
+func (s *Service) legacyVersion(protocolName, protocolVersion string) (string, bool) {
+	s.protocolsmu.RLock()
+	defer s.protocolsmu.RUnlock()
+
+	for _, p := range s.protocols {
+		if p.Name == protocolName && p.Version == protocolVersion && p.Legacy != nil && p.Legacy.Served(time.Now()) {
+			return p.Legacy.Version, true
+		}
+	}
+	return "", false
+}
+
+// addLegacyPeer records that the peer uses the legacy version of the
+// protocol.
+func (s *Service) addLegacyPeer(protocolName string, overlay swarm.Address) {
+	s.metrics.LegacyPeers.WithLabelValues(protocolName).Set(float64(s.legacyPeers.add(protocolName, overlay)))
+}
+
+// removeLegacyPeer forgets the legacy protocol versions of the peer.
+func (s *Service) removeLegacyPeer(overlay swarm.Address) {
+	for name, count := range s.legacyPeers.remove(overlay) {
+		s.metrics.LegacyPeers.WithLabelValues(name).Set(float64(count))
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethsana/sana"
 	"github.com/ethsana/sana/pkg/addressbook"
 	"github.com/ethsana/sana/pkg/bzz"
 	beecrypto "github.com/ethsana/sana/pkg/crypto"
@@ -21,6 +22,8 @@ import (
 	"github.com/ethsana/sana/pkg/p2p/libp2p/internal/blocklist"
 	"github.com/ethsana/sana/pkg/p2p/libp2p/internal/breaker"
 	handshake "github.com/ethsana/sana/pkg/p2p/libp2p/internal/handshake"
+	"github.com/ethsana/sana/pkg/p2p/libp2p/internal/keepalive"
+	"github.com/ethsana/sana/pkg/reputation"
 	"github.com/ethsana/sana/pkg/storage"
 	"github.com/ethsana/sana/pkg/swarm"
 	"github.com/ethsana/sana/pkg/topology/lightnode"
@@ -37,15 +40,23 @@ import (
 	libp2pquic "github.com/libp2p/go-libp2p-quic-transport"
 	tptu "github.com/libp2p/go-libp2p-transport-upgrader"
 	basichost "github.com/libp2p/go-libp2p/p2p/host/basic"
+	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
 	"github.com/libp2p/go-tcp-transport"
 	ws "github.com/libp2p/go-ws-transport"
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/multiformats/go-multistream"
+	"golang.org/x/net/proxy"
 )
 
 var (
 	_ p2p.Service      = (*Service)(nil)
 	_ p2p.DebugService = (*Service)(nil)
+	_ p2p.Versioner    = (*Service)(nil)
+
+	// userAgent is announced to peers in the identify protocol.
+	userAgent = "ant/" + sana.Version
+
+	errAddressBlocked = errors.New("underlay address blocked by the connection policy")
 )
 
 const defaultLightNodeLimit = 100
@@ -54,7 +65,7 @@ type Service struct {
 	ctx               context.Context
 	host              host.Host
 	natManager        basichost.NATManager
-	natAddrResolver   *staticAddressResolver
+	natAddrResolver   handshake.AdvertisableAddressResolver
 	autonatDialer     host.Host
 	libp2pPeerstore   peerstore.Peerstore
 	metrics           metrics
@@ -72,7 +83,11 @@ type Service struct {
 	halt              chan struct{}
 	lightNodes        lightnodes
 	lightNodeLimit    int
+	keepalive         *keepalive.Service
+	legacyPeers       *legacyPeers
 	protocolsmu       sync.RWMutex
+	allowAddress      func(ma.Multiaddr) bool
+	reputation        reputation.Recorder
 }
 
 type lightnodes interface {
@@ -92,6 +107,27 @@ type Options struct {
 	LightNodeLimit int
 	WelcomeMessage string
 	Transaction    []byte
+	// KeepaliveIntervals are the intervals between keepalive pings of
+	// connected peers per transport (tcp, ws and quic), peers connected over
+	// transports without an interval are not pinged.
+	KeepaliveIntervals map[string]time.Duration
+	// KeepaliveTimeout is the timeout of a single keepalive ping.
+	KeepaliveTimeout time.Duration
+	// KeepaliveMaxFailures is the number of consecutive failed keepalive
+	// pings after which the peer connection is closed as half-open.
+	KeepaliveMaxFailures int
+	// ProxyURL is the socks5:// url of the SOCKS5 proxy through which the
+	// outbound connections are dialed. Only the tcp transport can be proxied.
+	ProxyURL string
+	// OnionAddr is the "<onion service>.onion:<port>" address of the Tor
+	// onion service of the node which is advertised instead of the observed
+	// address.
+	OnionAddr string
+	// AllowAddress, if set, rejects the connections with the underlay
+	// addresses for which it returns false.
+	AllowAddress func(ma.Multiaddr) bool
+	// Reputation, if set, records the protocol errors of the peers.
+	Reputation reputation.Recorder
 }
 
 func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay swarm.Address, addr string, ab addressbook.Putter, storer storage.StateStorer, lightNodes *lightnode.Container, swapBackend handshake.SenderMatcher, logger logging.Logger, tracer *tracing.Tracer, o Options) (*Service, error) {
@@ -100,6 +136,25 @@ func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay
 		return nil, fmt.Errorf("address: %w", err)
 	}
 
+	var proxyDialer proxy.ContextDialer
+	if o.ProxyURL != "" {
+		if o.EnableWS || o.EnableQUIC {
+			return nil, errors.New("proxy: only the tcp transport can be proxied, disable the ws and quic transports")
+		}
+		proxyDialer, err = newProxyDialer(o.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("proxy: %w", err)
+		}
+	}
+
+	var onionAddrResolver *onionAddressResolver
+	if o.OnionAddr != "" {
+		onionAddrResolver, err = newOnionAddressResolver(o.OnionAddr)
+		if err != nil {
+			return nil, fmt.Errorf("onion address: %w", err)
+		}
+	}
+
 	ip4Addr := "0.0.0.0"
 	ip6Addr := "::"
 
@@ -145,9 +200,11 @@ func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay
 		security,
 		// Use dedicated peerstore instead the global DefaultPeerstore
 		libp2p.Peerstore(libp2pPeerstore),
+		libp2p.UserAgent(userAgent),
 	}
 
-	if o.NATAddr == "" {
+	// the port mappings are not needed for the onion service
+	if o.NATAddr == "" && onionAddrResolver == nil {
 		opts = append(opts,
 			libp2p.NATManager(func(n network.Network) basichost.NATManager {
 				natManager = basichost.NewNATManager(n)
@@ -162,12 +219,17 @@ func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay
 		)
 	}
 
-	transports := []libp2p.Option{
-		libp2p.Transport(func(u *tptu.Upgrader) *tcp.TcpTransport {
+	var transports []libp2p.Option
+	if proxyDialer != nil {
+		transports = append(transports, libp2p.Transport(func(u *tptu.Upgrader) *proxyTransport {
+			return newProxyTransport(u, proxyDialer)
+		}))
+	} else {
+		transports = append(transports, libp2p.Transport(func(u *tptu.Upgrader) *tcp.TcpTransport {
 			t := tcp.NewTCPTransport(u)
 			t.DisableReuseport = true
 			return t
-		}),
+		}))
 	}
 
 	if o.EnableWS {
@@ -204,17 +266,22 @@ func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay
 	}
 
 	var advertisableAddresser handshake.AdvertisableAddressResolver
-	var natAddrResolver *staticAddressResolver
-	if o.NATAddr == "" {
+	var natAddrResolver handshake.AdvertisableAddressResolver
+	switch {
+	case onionAddrResolver != nil:
+		natAddrResolver = onionAddrResolver
+		advertisableAddresser = onionAddrResolver
+	case o.NATAddr == "":
 		advertisableAddresser = &UpnpAddressResolver{
 			host: h,
 		}
-	} else {
-		natAddrResolver, err = newStaticAddressResolver(o.NATAddr, net.LookupIP)
+	default:
+		staticResolver, err := newStaticAddressResolver(o.NATAddr, net.LookupIP)
 		if err != nil {
 			return nil, fmt.Errorf("static nat: %w", err)
 		}
-		advertisableAddresser = natAddrResolver
+		natAddrResolver = staticResolver
+		advertisableAddresser = staticResolver
 	}
 
 	handshakeService, err := handshake.New(signer, advertisableAddresser, swapBackend, overlay, networkID, o.FullNode, o.Transaction, o.WelcomeMessage, logger)
@@ -242,6 +309,9 @@ func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay
 		ready:             make(chan struct{}),
 		halt:              make(chan struct{}),
 		lightNodes:        lightNodes,
+		legacyPeers:       newLegacyPeers(),
+		allowAddress:      o.AllowAddress,
+		reputation:        o.Reputation,
 	}
 
 	peerRegistry.setDisconnecter(s)
@@ -251,6 +321,17 @@ func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay
 		s.lightNodeLimit = o.LightNodeLimit
 	}
 
+	for _, interval := range o.KeepaliveIntervals {
+		if interval > 0 {
+			s.keepalive = keepalive.New(s.keepalivePing, s.reapHalfOpen, keepalive.Options{
+				Intervals:   o.KeepaliveIntervals,
+				Timeout:     o.KeepaliveTimeout,
+				MaxFailures: o.KeepaliveMaxFailures,
+			})
+			break
+		}
+	}
+
 	// Construct protocols.
 	id := protocol.ID(p2p.NewSwarmStreamName(handshake.ProtocolName, handshake.ProtocolVersion, handshake.StreamName))
 	matcher, err := s.protocolSemverMatcher(id)
@@ -281,6 +362,13 @@ func (s *Service) handleIncoming(stream network.Stream) {
 	}
 
 	peerID := stream.Conn().RemotePeer()
+	if s.allowAddress != nil && !s.allowAddress(stream.Conn().RemoteMultiaddr()) {
+		s.logger.Debugf("stream handler: blocked connection from address %s of peer id %s", stream.Conn().RemoteMultiaddr(), peerID)
+		_ = stream.Reset()
+		_ = s.host.Network().ClosePeer(peerID)
+		return
+	}
+
 	handshakeStream := NewStream(stream)
 	i, err := s.handshakeService.Handle(s.ctx, handshakeStream, stream.Conn().RemoteMultiaddr(), peerID)
 	if err != nil {
@@ -406,6 +494,10 @@ func (s *Service) handleIncoming(stream network.Stream) {
 		return
 	}
 
+	if s.keepalive != nil {
+		s.keepalive.Add(overlay, keepalive.Transport(stream.Conn().RemoteMultiaddr()))
+	}
+
 	s.logger.Debugf("stream handler: successfully connected to peer %s%s (inbound)", i.BzzAddress.ShortString(), i.LightString())
 	s.logger.Infof("stream handler: successfully connected to peer %s%s (inbound)", i.BzzAddress.Overlay, i.LightString())
 }
@@ -423,77 +515,109 @@ func (s *Service) AddProtocol(p p2p.ProtocolSpec) (err error) {
 			return fmt.Errorf("protocol version match %s: %w", id, err)
 		}
 
-		s.host.SetStreamHandlerMatch(id, matcher, func(streamlibp2p network.Stream) {
-			peerID := streamlibp2p.Conn().RemotePeer()
-			overlay, found := s.peers.overlay(peerID)
-			if !found {
-				_ = streamlibp2p.Reset()
-				s.logger.Debugf("overlay address for peer %q not found", peerID)
-				return
+		if l := p.Legacy; l != nil {
+			legacyID := protocol.ID(p2p.NewSwarmStreamName(p.Name, l.Version, ss.Name))
+			legacyMatcher, err := s.protocolSemverMatcher(legacyID)
+			if err != nil {
+				return fmt.Errorf("protocol version match %s: %w", legacyID, err)
 			}
-			full, found := s.peers.fullnode(peerID)
-			if !found {
-				_ = streamlibp2p.Reset()
-				s.logger.Debugf("fullnode info for peer %q not found", peerID)
-				return
+
+			// versions matched by the legacy handler are not served by the
+			// current handler, not even after the deprecation window
+			currentMatcher := matcher
+			matcher = func(check string) bool {
+				return currentMatcher(check) && !legacyMatcher(check)
 			}
 
-			stream := newStream(streamlibp2p)
+			s.host.SetStreamHandlerMatch(legacyID, func(check string) bool {
+				return l.Served(time.Now()) && legacyMatcher(check)
+			}, s.streamHandler(p, l.Version, l.StreamSpec(ss), true))
+		}
 
-			// exchange headers
-			if err := handleHeaders(ss.Headler, stream, overlay); err != nil {
-				s.logger.Debugf("handle protocol %s/%s: stream %s: peer %s: handle headers: %v", p.Name, p.Version, ss.Name, overlay, err)
-				_ = stream.Reset()
-				return
-			}
+		s.host.SetStreamHandlerMatch(id, matcher, s.streamHandler(p, p.Version, ss, false))
+	}
 
-			ctx, cancel := context.WithCancel(s.ctx)
+	s.protocolsmu.Lock()
+	s.protocols = append(s.protocols, p)
+	s.protocolsmu.Unlock()
+	return nil
+}
 
-			s.peers.addStream(peerID, streamlibp2p, cancel)
-			defer s.peers.removeStream(peerID, streamlibp2p)
+// streamHandler returns the handler of the protocol stream of the given
+// version, which is a legacy version of the protocol if legacy is true.
+func (s *Service) streamHandler(p p2p.ProtocolSpec, version string, ss p2p.StreamSpec, legacy bool) network.StreamHandler {
+	return func(streamlibp2p network.Stream) {
+		peerID := streamlibp2p.Conn().RemotePeer()
+		overlay, found := s.peers.overlay(peerID)
+		if !found {
+			_ = streamlibp2p.Reset()
+			s.logger.Debugf("overlay address for peer %q not found", peerID)
+			return
+		}
+		full, found := s.peers.fullnode(peerID)
+		if !found {
+			_ = streamlibp2p.Reset()
+			s.logger.Debugf("fullnode info for peer %q not found", peerID)
+			return
+		}
 
-			// tracing: get span tracing context and add it to the context
-			// silently ignore if the peer is not providing tracing
-			ctx, err := s.tracer.WithContextFromHeaders(ctx, stream.Headers())
-			if err != nil && !errors.Is(err, tracing.ErrContextNotFound) {
-				s.logger.Debugf("handle protocol %s/%s: stream %s: peer %s: get tracing context: %v", p.Name, p.Version, ss.Name, overlay, err)
-				_ = stream.Reset()
-				return
-			}
+		stream := newStream(streamlibp2p)
 
-			logger := tracing.NewLoggerWithTraceID(ctx, s.logger)
+		// exchange headers
+		if err := handleHeaders(ss.Headler, stream, overlay); err != nil {
+			s.logger.Debugf("handle protocol %s/%s: stream %s: peer %s: handle headers: %v", p.Name, version, ss.Name, overlay, err)
+			_ = stream.Reset()
+			return
+		}
 
-			s.metrics.HandledStreamCount.Inc()
-			if err := ss.Handler(ctx, p2p.Peer{Address: overlay, FullNode: full}, stream); err != nil {
-				var de *p2p.DisconnectError
-				if errors.As(err, &de) {
-					_ = stream.Reset()
-					_ = s.Disconnect(overlay)
-				}
+		ctx, cancel := context.WithCancel(s.ctx)
 
-				var bpe *p2p.BlockPeerError
-				if errors.As(err, &bpe) {
-					_ = stream.Reset()
-					if err := s.Blocklist(overlay, bpe.Duration()); err != nil {
-						logger.Debugf("blocklist: could not blocklist peer %s: %v", peerID, err)
-						logger.Errorf("unable to blocklist peer %v", peerID)
-					}
-					logger.Tracef("blocklisted a peer %s", peerID)
-				}
-				// count unexpected requests
-				if errors.Is(err, p2p.ErrUnexpected) {
-					s.metrics.UnexpectedProtocolReqCount.Inc()
+		s.peers.addStream(peerID, streamlibp2p, cancel)
+		defer s.peers.removeStream(peerID, streamlibp2p)
+
+		// tracing: get span tracing context and add it to the context
+		// silently ignore if the peer is not providing tracing
+		ctx, err := s.tracer.WithContextFromHeaders(ctx, stream.Headers())
+		if err != nil && !errors.Is(err, tracing.ErrContextNotFound) {
+			s.logger.Debugf("handle protocol %s/%s: stream %s: peer %s: get tracing context: %v", p.Name, version, ss.Name, overlay, err)
+			_ = stream.Reset()
+			return
+		}
+
+		logger := tracing.NewLoggerWithTraceID(ctx, s.logger)
+
+		s.metrics.HandledStreamCount.Inc()
+		if legacy {
+			s.metrics.LegacyHandledStreamCount.WithLabelValues(p.Name).Inc()
+			s.addLegacyPeer(p.Name, overlay)
+		}
+		if err := ss.Handler(ctx, p2p.Peer{Address: overlay, FullNode: full}, stream); err != nil {
+			var de *p2p.DisconnectError
+			if errors.As(err, &de) {
+				_ = stream.Reset()
+				_ = s.Disconnect(overlay)
+			}
+			if s.reputation != nil && (de != nil || errors.Is(err, p2p.ErrUnexpected)) {
+				s.reputation.RecordFailure(overlay, reputation.FailureProtocol)
+			}
+
+			var bpe *p2p.BlockPeerError
+			if errors.As(err, &bpe) {
+				_ = stream.Reset()
+				if err := s.Blocklist(overlay, bpe.Duration()); err != nil {
+					logger.Debugf("blocklist: could not blocklist peer %s: %v", peerID, err)
+					logger.Errorf("unable to blocklist peer %v", peerID)
 				}
-				logger.Debugf("could not handle protocol %s/%s: stream %s: peer %s: error: %v", p.Name, p.Version, ss.Name, overlay, err)
-				return
+				logger.Tracef("blocklisted a peer %s", peerID)
+			}
+			// count unexpected requests
+			if errors.Is(err, p2p.ErrUnexpected) {
+				s.metrics.UnexpectedProtocolReqCount.Inc()
 			}
-		})
+			logger.Debugf("could not handle protocol %s/%s: stream %s: peer %s: error: %v", p.Name, version, ss.Name, overlay, err)
+			return
+		}
 	}
-
-	s.protocolsmu.Lock()
-	s.protocols = append(s.protocols, p)
-	s.protocolsmu.Unlock()
-	return nil
 }
 
 func (s *Service) Addresses() (addreses []ma.Multiaddr, err error) {
@@ -553,6 +677,10 @@ func (s *Service) Connect(ctx context.Context, addr ma.Multiaddr) (address *bzz.
 		return nil, fmt.Errorf("addr from p2p: %w", err)
 	}
 
+	if s.allowAddress != nil && !s.allowAddress(addr) {
+		return nil, errAddressBlocked
+	}
+
 	hostAddr, err := buildHostAddress(info.ID)
 	if err != nil {
 		return nil, fmt.Errorf("build host address: %w", err)
@@ -655,6 +783,9 @@ func (s *Service) Connect(ctx context.Context, addr ma.Multiaddr) (address *bzz.
 	}
 
 	s.metrics.CreatedConnectionCount.Inc()
+	if s.keepalive != nil {
+		s.keepalive.Add(overlay, keepalive.Transport(stream.Conn().RemoteMultiaddr()))
+	}
 
 	s.logger.Debugf("successfully connected to peer %s%s (outbound)", i.BzzAddress.ShortString(), i.LightString())
 	s.logger.Infof("successfully connected to peer %s%s (outbound)", overlay, i.LightString())
@@ -669,6 +800,11 @@ func (s *Service) Disconnect(overlay swarm.Address) error {
 	// found is checked at the bottom of the function
 	found, full, peerID := s.peers.remove(overlay)
 
+	if s.keepalive != nil {
+		s.keepalive.Remove(overlay)
+	}
+	s.removeLegacyPeer(overlay)
+
 	_ = s.host.Network().ClosePeer(peerID)
 
 	peer := p2p.Peer{Address: overlay, FullNode: full}
@@ -701,6 +837,10 @@ func (s *Service) Disconnect(overlay swarm.Address) error {
 // disconnected is a registered peer registry event
 func (s *Service) disconnected(address swarm.Address) {
 	peer := p2p.Peer{Address: address}
+	if s.keepalive != nil {
+		s.keepalive.Remove(address)
+	}
+	s.removeLegacyPeer(address)
 	peerID, found := s.peers.peerID(address)
 	if found {
 		// peerID might not always be found on shutdown
@@ -732,6 +872,21 @@ func (s *Service) Peers() []p2p.Peer {
 	return s.peers.peers()
 }
 
+// PeerVersion returns the software version which the connected peer
+// announced in the identify protocol.
+func (s *Service) PeerVersion(overlay swarm.Address) (string, bool) {
+	peerID, found := s.peers.peerID(overlay)
+	if !found {
+		return "", false
+	}
+	v, err := s.host.Peerstore().Get(peerID, "AgentVersion")
+	if err != nil {
+		return "", false
+	}
+	version, ok := v.(string)
+	return version, ok
+}
+
 func (s *Service) BlocklistedPeers() ([]p2p.Peer, error) {
 	return s.blocklist.Peers()
 }
@@ -775,6 +930,9 @@ func (s *Service) newStreamForPeerID(ctx context.Context, peerID libp2ppeer.ID,
 			_ = st.Close()
 		}
 		if err == multistream.ErrNotSupported || err == multistream.ErrIncorrectVersion {
+			if legacyVersion, ok := s.legacyVersion(protocolName, protocolVersion); ok {
+				return s.newLegacyStreamForPeerID(ctx, peerID, protocolName, legacyVersion, streamName, err)
+			}
 			return nil, p2p.NewIncompatibleStreamError(err)
 		}
 		return nil, fmt.Errorf("create stream %q to %q: %w", swarmStreamName, peerID, err)
@@ -783,7 +941,62 @@ func (s *Service) newStreamForPeerID(ctx context.Context, peerID libp2ppeer.ID,
 	return st, nil
 }
 
+// newLegacyStreamForPeerID creates a stream of the legacy protocol version
+// to the peer which does not support the current version. The caller keeps
+// speaking the current version, which is compatible with the previous minor
+// version.
+func (s *Service) newLegacyStreamForPeerID(ctx context.Context, peerID libp2ppeer.ID, protocolName, legacyVersion, streamName string, currentErr error) (network.Stream, error) {
+	swarmStreamName := p2p.NewSwarmStreamName(protocolName, legacyVersion, streamName)
+	st, err := s.host.NewStream(ctx, peerID, protocol.ID(swarmStreamName))
+	if err != nil {
+		if st != nil {
+			_ = st.Close()
+		}
+		if err == multistream.ErrNotSupported || err == multistream.ErrIncorrectVersion {
+			return nil, p2p.NewIncompatibleStreamError(currentErr)
+		}
+		return nil, fmt.Errorf("create stream %q to %q: %w", swarmStreamName, peerID, err)
+	}
+	s.metrics.CreatedStreamCount.Inc()
+	s.metrics.LegacyCreatedStreamCount.WithLabelValues(protocolName).Inc()
+	if overlay, found := s.peers.overlay(peerID); found {
+		s.addLegacyPeer(protocolName, overlay)
+	}
+	return st, nil
+}
+
+// keepalivePing pings the peer over its existing connections.
+func (s *Service) keepalivePing(ctx context.Context, overlay swarm.Address) error {
+	peerID, found := s.peers.peerID(overlay)
+	if !found {
+		return p2p.ErrPeerNotFound
+	}
+
+	ctx, cancel := context.WithCancel(network.WithNoDial(ctx, "keepalive"))
+	defer cancel()
+
+	r, ok := <-ping.Ping(ctx, s.host, peerID)
+	if !ok {
+		// the result channel is closed without a result when the context
+		// is done
+		return ctx.Err()
+	}
+	return r.Error
+}
+
+// reapHalfOpen disconnects the peer which stopped answering keepalive pings.
+func (s *Service) reapHalfOpen(overlay swarm.Address) {
+	s.logger.Debugf("libp2p keepalive: peer %s not answering pings, closing half-open connection", overlay)
+	s.logger.Infof("libp2p keepalive: closing half-open connection to peer %s", overlay)
+	_ = s.Disconnect(overlay)
+}
+
 func (s *Service) Close() error {
+	if s.keepalive != nil {
+		if err := s.keepalive.Close(); err != nil {
+			return err
+		}
+	}
 	if err := s.libp2pPeerstore.Close(); err != nil {
 		return err
 	}
@@ -23,6 +23,9 @@ type metrics struct {
 	ConnectBreakerCount        prometheus.Counter
 	UnexpectedProtocolReqCount prometheus.Counter
 	KickedOutPeersCount        prometheus.Counter
+	LegacyHandledStreamCount   *prometheus.CounterVec
+	LegacyCreatedStreamCount   *prometheus.CounterVec
+	LegacyPeers                *prometheus.GaugeVec
 }
 
 func newMetrics() metrics {
@@ -89,9 +92,31 @@ func newMetrics() metrics {
 			Name:      "kickedout_peers_count",
 			Help:      "Number of total kicked-out peers.",
 		}),
+		LegacyHandledStreamCount: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "legacy_handled_stream_count",
+			Help:      "Number of handled incoming streams of legacy protocol versions.",
+		}, []string{"protocol"}),
+		LegacyCreatedStreamCount: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "legacy_created_stream_count",
+			Help:      "Number of outgoing streams which fell back to legacy protocol versions.",
+		}, []string{"protocol"}),
+		LegacyPeers: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "legacy_peers",
+			Help:      "Number of connected peers which use legacy protocol versions.",
+		}, []string{"protocol"}),
 	}
 }
 
 func (s *Service) Metrics() []prometheus.Collector {
-	return m.PrometheusCollectorsFromFields(s.metrics)
+	collectors := m.PrometheusCollectorsFromFields(s.metrics)
+	if s.keepalive != nil {
+		collectors = append(collectors, s.keepalive.Metrics()...)
+	}
+	return collectors
 }
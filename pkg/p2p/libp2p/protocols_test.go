@@ -271,6 +271,98 @@ func TestNewStream_semanticVersioning(t *testing.T) {
 	}
 }
 
+// TestNewStream_legacyVersion tests that the legacy protocol version is served
+// to and used with peers which do not support the current version.
+func TestNewStream_legacyVersion(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode: true,
+	}})
+
+	s2, _ := newService(t, 1, libp2pServiceOpts{})
+
+	addr := serviceUnderlayAddress(t, s1)
+
+	if _, err := s2.Connect(ctx, addr); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mtx                       sync.Mutex
+		currentCalls, legacyCalls int
+	)
+	p := newTestProtocol(func(_ context.Context, _ p2p.Peer, s p2p.Stream) error {
+		defer s.Close()
+		mtx.Lock()
+		defer mtx.Unlock()
+		currentCalls++
+		return nil
+	})
+	p.Legacy = &p2p.LegacyProtocolSpec{
+		Version: "2.2.0",
+		StreamSpecs: []p2p.StreamSpec{
+			{
+				Name: testStreamName,
+				Handler: func(_ context.Context, _ p2p.Peer, s p2p.Stream) error {
+					defer s.Close()
+					mtx.Lock()
+					defer mtx.Unlock()
+					legacyCalls++
+					return nil
+				},
+			},
+		},
+	}
+	if err := s1.AddProtocol(p); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range []string{"2.1.0", "2.2.0"} {
+		stream, err := s2.NewStream(ctx, overlay1, nil, testProtocolName, version, testStreamName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := stream.FullClose(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectCounter(t, &legacyCalls, 2, &mtx)
+
+	stream, err := s2.NewStream(ctx, overlay1, nil, testProtocolName, "2.3.0", testStreamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.FullClose(); err != nil {
+		t.Fatal(err)
+	}
+	expectCounter(t, &currentCalls, 1, &mtx)
+
+	// the newer version falls back to the version supported by s1
+	if err := s2.AddProtocol(p2p.ProtocolSpec{
+		Name:        testProtocolName,
+		Version:     "2.4.0",
+		StreamSpecs: []p2p.StreamSpec{{Name: testStreamName}},
+		Legacy:      &p2p.LegacyProtocolSpec{Version: testProtocolVersion},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	stream, err = s2.NewStream(ctx, overlay1, nil, testProtocolName, "2.4.0", testStreamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.FullClose(); err != nil {
+		t.Fatal(err)
+	}
+	expectCounter(t, &currentCalls, 2, &mtx)
+
+	// the legacy version is not served after the deprecation window
+	p.Legacy.Until = time.Now().Add(-time.Second)
+	_, err = s2.NewStream(ctx, overlay1, nil, testProtocolName, "2.2.0", testStreamName)
+	expectErrNotSupported(t, err)
+}
+
 func TestDisconnectError(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -0,0 +1,196 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libp2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	libp2ppeer "github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/transport"
+	tptu "github.com/libp2p/go-libp2p-transport-upgrader"
+	"github.com/libp2p/go-tcp-transport"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"golang.org/x/net/proxy"
+)
+
+// proxyConnectTimeout is the connect timeout of the proxied dials, longer
+// than the one of the tcp transport as building the Tor circuits takes time.
+const proxyConnectTimeout = 30 * time.Second
+
+// proxyTransport is the tcp transport which dials the peers through a SOCKS5
+// proxy. Listening is not proxied. The onion addresses can be dialed if the
+// proxy is the one of a Tor client.
+type proxyTransport struct {
+	*tcp.TcpTransport
+	dialer proxy.ContextDialer
+}
+
+func newProxyDialer(proxyURL string) (proxy.ContextDialer, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse proxy url: %w", err)
+	}
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, errors.New("proxy address not provided")
+	}
+	d, err := proxy.FromURL(u, proxy.Direct)
+	if err != nil {
+		return nil, err
+	}
+	cd, ok := d.(proxy.ContextDialer)
+	if !ok {
+		return nil, errors.New("proxy dialer does not support contexts")
+	}
+	return cd, nil
+}
+
+func newProxyTransport(u *tptu.Upgrader, dialer proxy.ContextDialer) *proxyTransport {
+	t := tcp.NewTCPTransport(u)
+	t.DisableReuseport = true
+	t.ConnectTimeout = proxyConnectTimeout
+	return &proxyTransport{
+		TcpTransport: t,
+		dialer:       dialer,
+	}
+}
+
+// CanDial returns true for the tcp addresses of the ip addresses and host
+// names and for the onion addresses.
+func (t *proxyTransport) CanDial(addr ma.Multiaddr) bool {
+	_, err := proxyDialAddress(addr)
+	return err == nil
+}
+
+// Dial dials the peer at the remote address through the proxy.
+func (t *proxyTransport) Dial(ctx context.Context, raddr ma.Multiaddr, p libp2ppeer.ID) (transport.CapableConn, error) {
+	address, err := proxyDialAddress(raddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.ConnectTimeout)
+		defer cancel()
+	}
+
+	conn, err := t.dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("proxy dial %s: %w", address, err)
+	}
+
+	// the local address is the one of the connection to the proxy
+	laddr, err := manet.FromNetAddr(conn.LocalAddr())
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return t.Upgrader.UpgradeOutbound(ctx, t, &proxyConn{
+		Conn:  conn,
+		laddr: laddr,
+		raddr: raddr,
+	}, p)
+}
+
+// Protocols returns the tcp and onion3 protocols, so that the onion addresses
+// are dialed with this transport.
+func (t *proxyTransport) Protocols() []int {
+	return []int{ma.P_TCP, ma.P_ONION3}
+}
+
+func (t *proxyTransport) String() string {
+	return "TCP over SOCKS5"
+}
+
+// proxyConn is the connection dialed through the proxy. Its remote address is
+// the address of the peer instead of the one of the proxy.
+type proxyConn struct {
+	net.Conn
+	laddr ma.Multiaddr
+	raddr ma.Multiaddr
+}
+
+func (c *proxyConn) LocalMultiaddr() ma.Multiaddr {
+	return c.laddr
+}
+
+func (c *proxyConn) RemoteMultiaddr() ma.Multiaddr {
+	return c.raddr
+}
+
+// proxyDialAddress returns the host:port address which the proxy is asked to
+// connect to. The host names are resolved by the proxy.
+func proxyDialAddress(addr ma.Multiaddr) (string, error) {
+	protocols := addr.Protocols()
+	switch {
+	case len(protocols) == 1 && protocols[0].Code == ma.P_ONION3:
+		v, err := addr.ValueForProtocol(ma.P_ONION3)
+		if err != nil {
+			return "", err
+		}
+		i := strings.LastIndex(v, ":")
+		if i < 0 {
+			return "", fmt.Errorf("invalid onion address %s", addr)
+		}
+		return net.JoinHostPort(v[:i]+".onion", v[i+1:]), nil
+	case len(protocols) == 2 && protocols[1].Code == ma.P_TCP:
+		switch protocols[0].Code {
+		case ma.P_IP4, ma.P_IP6, ma.P_DNS, ma.P_DNS4, ma.P_DNS6:
+		default:
+			return "", fmt.Errorf("unsupported proxy address %s", addr)
+		}
+		host, err := addr.ValueForProtocol(protocols[0].Code)
+		if err != nil {
+			return "", err
+		}
+		port, err := addr.ValueForProtocol(ma.P_TCP)
+		if err != nil {
+			return "", err
+		}
+		return net.JoinHostPort(host, port), nil
+	}
+	return "", fmt.Errorf("unsupported proxy address %s", addr)
+}
+
+// onionAddressResolver advertises the onion address of the node instead of
+// the observed one.
+type onionAddressResolver struct {
+	addr ma.Multiaddr
+}
+
+// newOnionAddressResolver returns the resolver of the onion address in the
+// "<onion service>.onion:<port>" format.
+func newOnionAddressResolver(addr string) (*onionAddressResolver, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(host, ".onion") {
+		return nil, fmt.Errorf("invalid onion address %q", addr)
+	}
+	a, err := ma.NewMultiaddr("/onion3/" + strings.TrimSuffix(host, ".onion") + ":" + port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid onion address %q: %w", addr, err)
+	}
+	return &onionAddressResolver{addr: a}, nil
+}
+
+func (r *onionAddressResolver) Resolve(observedAddress ma.Multiaddr) (ma.Multiaddr, error) {
+	observableAddrInfo, err := libp2ppeer.AddrInfoFromP2pAddr(observedAddress)
+	if err != nil {
+		return nil, err
+	}
+	return buildUnderlayAddress(r.addr, observableAddrInfo.ID)
+}
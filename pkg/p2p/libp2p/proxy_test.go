@@ -0,0 +1,97 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libp2p_test
+
+import (
+	"testing"
+
+	"github.com/ethsana/sana/pkg/p2p/libp2p"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const testOnionService = "vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd"
+
+func TestOnionAddressResolver(t *testing.T) {
+	r, err := libp2p.NewOnionAddressResolver(testOnionService + ".onion:1634")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	observableAddress, err := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/7071/p2p/16Uiu2HAkyyGKpjBiCkVqCKoJa6RzzZw9Nr7hGogsMPcdad1KyMmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Resolve(observableAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "/onion3/" + testOnionService + ":1634/p2p/16Uiu2HAkyyGKpjBiCkVqCKoJa6RzzZw9Nr7hGogsMPcdad1KyMmd"
+	if got.String() != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	for _, addr := range []string{
+		"example.com:1634",
+		"invalid.onion:1634",
+		testOnionService + ".onion",
+	} {
+		if _, err := libp2p.NewOnionAddressResolver(addr); err == nil {
+			t.Errorf("%s: expected error", addr)
+		}
+	}
+}
+
+func TestProxyDialAddress(t *testing.T) {
+	for _, tc := range []struct {
+		addr    string
+		want    string
+		wantErr bool
+	}{
+		{
+			addr: "/ip4/192.168.1.34/tcp/1634",
+			want: "192.168.1.34:1634",
+		},
+		{
+			addr: "/ip6/2001:db8::8a2e:370:7334/tcp/1634",
+			want: "[2001:db8::8a2e:370:7334]:1634",
+		},
+		{
+			addr: "/dns4/example.com/tcp/1634",
+			want: "example.com:1634",
+		},
+		{
+			addr: "/onion3/" + testOnionService + ":1634",
+			want: testOnionService + ".onion:1634",
+		},
+		{
+			addr:    "/ip4/192.168.1.34/udp/1634/quic",
+			wantErr: true,
+		},
+		{
+			addr:    "/ip4/192.168.1.34/tcp/1634/ws",
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.addr, func(t *testing.T) {
+			a, err := ma.NewMultiaddr(tc.addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := libp2p.ProxyDialAddress(a)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -6,13 +6,13 @@ package libp2p_test
 
 import (
 	"context"
-	"fmt"
 	"testing"
 	"time"
 
 	"github.com/ethsana/sana/pkg/p2p"
 	"github.com/ethsana/sana/pkg/p2p/libp2p"
 	"github.com/ethsana/sana/pkg/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestTracing(t *testing.T) {
@@ -40,14 +40,14 @@ func TestTracing(t *testing.T) {
 
 	s2, _ := newService(t, 1, libp2pServiceOpts{})
 
-	var handledTracingSpan string
+	var handledTraceID trace.TraceID
 	handled := make(chan struct{})
 	if err := s1.AddProtocol(newTestProtocol(func(ctx context.Context, _ p2p.Peer, _ p2p.Stream) error {
 
 		span, _, _ := tracer1.StartSpanFromContext(ctx, "test-p2p-handler", nil)
-		defer span.Finish()
+		defer span.End()
 
-		handledTracingSpan = fmt.Sprint(span.Context())
+		handledTraceID = span.SpanContext().TraceID()
 		close(handled)
 		return nil
 	})); err != nil {
@@ -67,9 +67,9 @@ func TestTracing(t *testing.T) {
 	defer cancel()
 
 	span, _, ctx := tracer2.StartSpanFromContext(ctx, "test-p2p-client", nil)
-	defer span.Finish()
+	defer span.End()
 
-	if fmt.Sprint(span.Context()) == "" {
+	if !span.SpanContext().IsValid() {
 		t.Error("not tracing span context to send")
 	}
 
@@ -85,7 +85,7 @@ func TestTracing(t *testing.T) {
 		t.Fatal("timeout waiting for handler")
 	}
 
-	if handledTracingSpan == "" {
-		t.Error("got not tracing span context in handler")
+	if handledTraceID != span.SpanContext().TraceID() {
+		t.Errorf("got trace id %s in handler, want %s", handledTraceID, span.SpanContext().TraceID())
 	}
 }
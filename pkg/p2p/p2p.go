@@ -60,6 +60,11 @@ type DebugService interface {
 	GetWelcomeMessage() string
 }
 
+// Versioner reports the software versions of the connected peers.
+type Versioner interface {
+	PeerVersion(overlay swarm.Address) (version string, ok bool)
+}
+
 // Streamer is able to create a new Stream.
 type Streamer interface {
 	NewStream(ctx context.Context, address swarm.Address, h Headers, protocol, version, stream string) (Stream, error)
@@ -89,6 +94,44 @@ type ProtocolSpec struct {
 	ConnectOut    func(context.Context, Peer) error
 	DisconnectIn  func(Peer) error
 	DisconnectOut func(Peer) error
+	// Legacy, if set, is the previous version of the protocol which is
+	// still served to peers that do not support Version.
+	Legacy *LegacyProtocolSpec
+}
+
+// LegacyProtocolSpec defines the previous version of a protocol which is
+// served during a deprecation window, so that peers which were not upgraded
+// yet are not dropped when the protocol version is bumped.
+//
+// It is scaffolding only. All the protocols of the node are at their first
+// version, so none of them sets a legacy spec, no legacy version is served
+// and the legacy metrics stay at zero. The first protocol whose version is
+// bumped sets the spec with its previous version and the end of its
+// deprecation window.
+type LegacyProtocolSpec struct {
+	Version string
+	// StreamSpecs handle the streams of the legacy version. Streams without a
+	// legacy spec are handled by the stream spec of the current version.
+	StreamSpecs []StreamSpec
+	// Until is the end of the deprecation window. The legacy version is
+	// served indefinitely if it is zero.
+	Until time.Time
+}
+
+// Served reports whether the legacy version is still served at time t.
+func (l *LegacyProtocolSpec) Served(t time.Time) bool {
+	return l.Until.IsZero() || t.Before(l.Until)
+}
+
+// StreamSpec returns the legacy spec of the stream, falling back to the spec
+// of the current version.
+func (l *LegacyProtocolSpec) StreamSpec(ss StreamSpec) StreamSpec {
+	for _, ls := range l.StreamSpecs {
+		if ls.Name == ss.Name {
+			return ls
+		}
+	}
+	return ss
 }
 
 // StreamSpec defines a Stream handling within the protocol.
@@ -5,7 +5,10 @@
 package p2p_test
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/ethsana/sana/pkg/p2p"
 )
@@ -18,3 +21,41 @@ func TestNewSwarmStreamName(t *testing.T) {
 		t.Errorf("got %s, want %s", got, want)
 	}
 }
+
+func TestLegacyProtocolSpec(t *testing.T) {
+	now := time.Now()
+	l := &p2p.LegacyProtocolSpec{
+		Version: "1.0.0",
+		StreamSpecs: []p2p.StreamSpec{
+			{Name: "peers"},
+		},
+		Until: now.Add(time.Hour),
+	}
+
+	if !l.Served(now) {
+		t.Error("legacy version not served within the deprecation window")
+	}
+	if l.Served(now.Add(2 * time.Hour)) {
+		t.Error("legacy version served after the deprecation window")
+	}
+	if !(&p2p.LegacyProtocolSpec{}).Served(now.Add(24 * time.Hour)) {
+		t.Error("legacy version without deprecation window not served")
+	}
+
+	errCurrent := errors.New("current")
+	errLegacy := errors.New("legacy")
+	current := p2p.StreamSpec{Name: "peers", Handler: func(context.Context, p2p.Peer, p2p.Stream) error {
+		return errCurrent
+	}}
+	l.StreamSpecs[0].Handler = func(context.Context, p2p.Peer, p2p.Stream) error {
+		return errLegacy
+	}
+	if err := l.StreamSpec(current).Handler(context.Background(), p2p.Peer{}, nil); !errors.Is(err, errLegacy) {
+		t.Errorf("got %v stream spec, want legacy", err)
+	}
+	other := current
+	other.Name = "other"
+	if err := l.StreamSpec(other).Handler(context.Background(), p2p.Peer{}, nil); !errors.Is(err, errCurrent) {
+		t.Errorf("got %v stream spec, want current", err)
+	}
+}
@@ -19,6 +19,13 @@ func WithDisconnectStreams(spec ProtocolSpec) {
 			return NewDisconnectError(ErrUnexpected)
 		}
 	}
+	if spec.Legacy != nil {
+		for i := range spec.Legacy.StreamSpecs {
+			spec.Legacy.StreamSpecs[i].Handler = func(c context.Context, p Peer, s Stream) error {
+				return NewDisconnectError(ErrUnexpected)
+			}
+		}
+	}
 }
 
 // WithBlocklistStreams will mutate the given spec and replace the handler with a always erroring one.
@@ -28,4 +35,11 @@ func WithBlocklistStreams(dur time.Duration, spec ProtocolSpec) {
 			return NewBlockPeerError(dur, ErrUnexpected)
 		}
 	}
+	if spec.Legacy != nil {
+		for i := range spec.Legacy.StreamSpecs {
+			spec.Legacy.StreamSpecs[i].Handler = func(c context.Context, p Peer, s Stream) error {
+				return NewBlockPeerError(dur, ErrUnexpected)
+			}
+		}
+	}
 }
@@ -314,7 +314,7 @@ func (r *record) Read(p []byte) (n int, err error) {
 	}
 	n = copy(p, r.b[r.c:end])
 	r.c += n
-	if r.Closed() {
+	if r.c == len(r.b) && r.Closed() {
 		err = io.EOF
 	}
 
@@ -42,24 +42,33 @@ func rootPinKey(ref swarm.Address) string {
 	return fmt.Sprintf("%s-%s", storePrefix, ref)
 }
 
+// Invalidator is notified about every chunk that is un-pinned so that state
+// derived from the chunk can be dropped.
+type Invalidator interface {
+	Invalidate(swarm.Address)
+}
+
 // NewService is a convenient constructor for Service.
 func NewService(
 	pinStorage storage.Storer,
 	rhStorage storage.StateStorer,
 	traverser traversal.Traverser,
+	invalidators ...Invalidator,
 ) *Service {
 	return &Service{
-		pinStorage: pinStorage,
-		rhStorage:  rhStorage,
-		traverser:  traverser,
+		pinStorage:   pinStorage,
+		rhStorage:    rhStorage,
+		traverser:    traverser,
+		invalidators: invalidators,
 	}
 }
 
 // Service is implementation of the pinning.Interface.
 type Service struct {
-	pinStorage storage.Storer
-	rhStorage  storage.StateStorer
-	traverser  traversal.Traverser
+	pinStorage   storage.Storer
+	rhStorage    storage.StateStorer
+	traverser    traversal.Traverser
+	invalidators []Invalidator
 }
 
 // CreatePin implements Interface.CreatePin method.
@@ -103,6 +112,9 @@ func (s *Service) DeletePin(ctx context.Context, ref swarm.Address) error {
 	var iterErr error
 	// iterFn is a unpinning iterator function over the leaves of the root.
 	iterFn := func(leaf swarm.Address) error {
+		for _, i := range s.invalidators {
+			i.Invalidate(leaf)
+		}
 		err := s.pinStorage.Set(ctx, storage.ModeSetUnpin, leaf)
 		if err != nil {
 			iterErr = multierror.Append(err, fmt.Errorf("unable to unpin the chunk for leaf %q of root %q: %w", leaf, ref, err))
@@ -14,6 +14,7 @@ import (
 	statestorem "github.com/ethsana/sana/pkg/statestore/mock"
 	"github.com/ethsana/sana/pkg/storage"
 	storagem "github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/swarm"
 	"github.com/ethsana/sana/pkg/traversal"
 )
 
@@ -96,3 +97,47 @@ func TestPinningService(t *testing.T) {
 		}
 	})
 }
+
+// invalidatorFunc adapts a function to the pinning.Invalidator interface.
+type invalidatorFunc func(swarm.Address)
+
+func (f invalidatorFunc) Invalidate(addr swarm.Address) { f(addr) }
+
+func TestPinningServiceInvalidators(t *testing.T) {
+	var (
+		ctx         = context.Background()
+		storerMock  = storagem.NewStorer()
+		invalidated []swarm.Address
+		service     = pinning.NewService(
+			storerMock,
+			statestorem.NewStateStore(),
+			traversal.New(storerMock),
+			invalidatorFunc(func(addr swarm.Address) {
+				invalidated = append(invalidated, addr)
+			}),
+		)
+	)
+
+	pipe := builder.NewPipelineBuilder(ctx, storerMock, storage.ModePutUpload, false)
+	ref, err := builder.FeedPipeline(ctx, pipe, strings.NewReader("Hello, Bee!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.CreatePin(ctx, ref, true); err != nil {
+		t.Fatalf("CreatePin(...): unexpected error: %v", err)
+	}
+	if have, want := len(invalidated), 0; have != want {
+		t.Fatalf("CreatePin(...): have %d invalidated chunks; want %d", have, want)
+	}
+
+	if err := service.DeletePin(ctx, ref); err != nil {
+		t.Fatalf("DeletePin(...): unexpected error: %v", err)
+	}
+	if have, want := len(invalidated), 1; have != want {
+		t.Fatalf("DeletePin(...): have %d invalidated chunks; want %d", have, want)
+	}
+	if have, want := invalidated[0], ref; !have.Equal(want) {
+		t.Fatalf("reference mismatch: have %q; want %q", have, want)
+	}
+}
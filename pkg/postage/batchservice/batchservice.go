@@ -264,6 +264,10 @@ func (svc *batchService) TopUp(id []byte, normalisedBalance *big.Int, txHash []b
 	if err != nil {
 		return fmt.Errorf("put: %w", err)
 	}
+
+	if l, ok := svc.batchListener.(postage.BatchTopUpListener); ok && bytes.Equal(svc.owner, b.Owner) {
+		l.HandleTopUp(b, txHash)
+	}
 	cs, err := svc.updateChecksum(txHash)
 	if err != nil {
 		return fmt.Errorf("update checksum: %w", err)
@@ -280,16 +284,21 @@ func (svc *batchService) UpdateDepth(id []byte, depth uint8, normalisedBalance *
 	if err != nil {
 		return fmt.Errorf("get: %w", err)
 	}
+	oldDepth := b.Depth
 	err = svc.storer.Put(b, normalisedBalance, depth)
 	if err != nil {
 		return fmt.Errorf("put: %w", err)
 	}
+
+	if l, ok := svc.batchListener.(postage.BatchDepthIncreaseListener); ok && bytes.Equal(svc.owner, b.Owner) {
+		l.HandleDepthIncrease(b.ID, depth)
+	}
 	cs, err := svc.updateChecksum(txHash)
 	if err != nil {
 		return fmt.Errorf("update checksum: %w", err)
 	}
 
-	svc.logger.Debugf("batch service: updated depth of batch id %s from %d to %d, tx %x, checksum %x", hex.EncodeToString(b.ID), b.Depth, depth, txHash, cs)
+	svc.logger.Debugf("batch service: updated depth of batch id %s from %d to %d, tx %x, checksum %x", hex.EncodeToString(b.ID), oldDepth, depth, txHash, cs)
 	return nil
 }
 
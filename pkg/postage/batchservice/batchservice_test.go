@@ -37,6 +37,24 @@ func (m *mockBatchCreationHandler) Handle(b *postage.Batch) {
 	m.count++
 }
 
+type mockBatchTopUpHandler struct {
+	mockBatchCreationHandler
+	topUps int
+}
+
+func (m *mockBatchTopUpHandler) HandleTopUp(b *postage.Batch, txHash []byte) {
+	m.topUps++
+}
+
+type mockBatchDepthIncreaseHandler struct {
+	mockBatchCreationHandler
+	depths []uint8
+}
+
+func (m *mockBatchDepthIncreaseHandler) HandleDepthIncrease(id []byte, depth uint8) {
+	m.depths = append(m.depths, depth)
+}
+
 func TestBatchServiceCreate(t *testing.T) {
 	testChainState := postagetesting.NewChainState()
 
@@ -203,6 +221,30 @@ func TestBatchServiceTopUp(t *testing.T) {
 			t.Fatalf("topped up amount: got %v, want %v", got.Value, want)
 		}
 	})
+
+	t.Run("notifies listener of owned batches", func(t *testing.T) {
+		for _, tc := range []struct {
+			name  string
+			owner []byte
+			want  int
+		}{
+			{name: "owned", owner: testBatch.Owner, want: 1},
+			{name: "not owned", owner: make([]byte, 32), want: 0},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				listener := &mockBatchTopUpHandler{}
+				svc, batchStore, _ := newTestStoreAndServiceWithListener(t, tc.owner, listener)
+				putBatch(t, batchStore, testBatch)
+
+				if err := svc.TopUp(testBatch.ID, testNormalisedBalance, testTxHash); err != nil {
+					t.Fatalf("top up: %v", err)
+				}
+				if listener.topUps != tc.want {
+					t.Fatalf("got %d top up notifications, want %d", listener.topUps, tc.want)
+				}
+			})
+		}
+	})
 }
 
 func TestBatchServiceUpdateDepth(t *testing.T) {
@@ -250,6 +292,33 @@ func TestBatchServiceUpdateDepth(t *testing.T) {
 			t.Fatalf("wrong batch depth set: want %v, got %v", testNewDepth, val.Depth)
 		}
 	})
+
+	t.Run("notifies listener of owned batches", func(t *testing.T) {
+		for _, tc := range []struct {
+			name  string
+			owner []byte
+			want  int
+		}{
+			{name: "owned", owner: testBatch.Owner, want: 1},
+			{name: "not owned", owner: make([]byte, 32), want: 0},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				listener := &mockBatchDepthIncreaseHandler{}
+				svc, batchStore, _ := newTestStoreAndServiceWithListener(t, tc.owner, listener)
+				putBatch(t, batchStore, testBatch)
+
+				if err := svc.UpdateDepth(testBatch.ID, testNewDepth, testNormalisedBalance, testTxHash); err != nil {
+					t.Fatalf("update depth: %v", err)
+				}
+				if len(listener.depths) != tc.want {
+					t.Fatalf("got %d depth increase notifications, want %d", len(listener.depths), tc.want)
+				}
+				if tc.want > 0 && listener.depths[0] != testNewDepth {
+					t.Fatalf("got depth %d, want %d", listener.depths[0], testNewDepth)
+				}
+			})
+		}
+	})
 }
 
 func TestBatchServiceUpdatePrice(t *testing.T) {
@@ -55,3 +55,15 @@ type Listener interface {
 type BatchCreationListener interface {
 	Handle(*Batch)
 }
+
+// BatchTopUpListener is notified of the top-ups of the batches owned by the
+// node. It is optionally implemented by the BatchCreationListener.
+type BatchTopUpListener interface {
+	HandleTopUp(b *Batch, txHash []byte)
+}
+
+// BatchDepthIncreaseListener is notified of the depth increases of the batches
+// owned by the node. It is optionally implemented by the BatchCreationListener.
+type BatchDepthIncreaseListener interface {
+	HandleDepthIncrease(id []byte, depth uint8)
+}
@@ -31,10 +31,16 @@ var (
 	ErrBatchCreate       = errors.New("batch creation failed")
 	ErrInsufficientFunds = errors.New("insufficient token balance")
 	ErrInvalidDepth      = errors.New("invalid depth")
+	ErrBatchNotFound     = errors.New("batch not found")
 )
 
 type Interface interface {
 	CreateBatch(ctx context.Context, initialBalance *big.Int, depth uint8, immutable bool, label string) ([]byte, error)
+	// TopUpBatch adds the amount to the balance of every chunk of the batch.
+	TopUpBatch(ctx context.Context, batchID []byte, topUpAmount *big.Int) error
+	// DiluteBatch increases the depth of the batch. The remaining balance of
+	// the batch is spread over the chunks of the new depth.
+	DiluteBatch(ctx context.Context, batchID []byte, newDepth uint8) error
 }
 
 type postageContract struct {
@@ -43,6 +49,7 @@ type postageContract struct {
 	bzzTokenAddress        common.Address
 	transactionService     transaction.Service
 	postageService         postage.Service
+	postageStorer          postage.Storer
 }
 
 func New(
@@ -51,6 +58,7 @@ func New(
 	bzzTokenAddress common.Address,
 	transactionService transaction.Service,
 	postageService postage.Service,
+	postageStorer postage.Storer,
 ) Interface {
 	return &postageContract{
 		owner:                  owner,
@@ -58,6 +66,7 @@ func New(
 		bzzTokenAddress:        bzzTokenAddress,
 		transactionService:     transactionService,
 		postageService:         postageService,
+		postageStorer:          postageStorer,
 	}
 }
 
@@ -122,6 +131,37 @@ func (c *postageContract) sendCreateBatchTransaction(ctx context.Context, owner
 	return receipt, nil
 }
 
+func (c *postageContract) sendBatchTransaction(ctx context.Context, method string, args ...interface{}) (*types.Receipt, error) {
+	callData, err := postageStampABI.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &transaction.TxRequest{
+		To:       &c.postageContractAddress,
+		Data:     callData,
+		GasPrice: sctx.GetGasPrice(ctx),
+		GasLimit: 160000,
+		Value:    big.NewInt(0),
+	}
+
+	txHash, err := c.transactionService.Send(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("send %s: %w", method, err)
+	}
+
+	receipt, err := c.transactionService.WaitForReceipt(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if receipt.Status == 0 {
+		return nil, transaction.ErrTransactionReverted
+	}
+
+	return receipt, nil
+}
+
 func (c *postageContract) getBalance(ctx context.Context) (*big.Int, error) {
 	callData, err := erc20ABI.Pack("balanceOf", c.owner)
 	if err != nil {
@@ -203,6 +243,45 @@ func (c *postageContract) CreateBatch(ctx context.Context, initialBalance *big.I
 	return nil, ErrBatchCreate
 }
 
+func (c *postageContract) TopUpBatch(ctx context.Context, batchID []byte, topUpAmount *big.Int) error {
+	batch, err := c.postageStorer.Get(batchID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBatchNotFound, err)
+	}
+
+	totalAmount := big.NewInt(0).Mul(topUpAmount, big.NewInt(int64(1<<batch.Depth)))
+	balance, err := c.getBalance(ctx)
+	if err != nil {
+		return err
+	}
+
+	if balance.Cmp(totalAmount) < 0 {
+		return ErrInsufficientFunds
+	}
+
+	_, err = c.sendApproveTransaction(ctx, totalAmount)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.sendBatchTransaction(ctx, "topUp", common.BytesToHash(batchID), topUpAmount)
+	return err
+}
+
+func (c *postageContract) DiluteBatch(ctx context.Context, batchID []byte, newDepth uint8) error {
+	batch, err := c.postageStorer.Get(batchID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBatchNotFound, err)
+	}
+
+	if newDepth <= batch.Depth {
+		return ErrInvalidDepth
+	}
+
+	_, err = c.sendBatchTransaction(ctx, "increaseDepth", common.BytesToHash(batchID), newDepth)
+	return err
+}
+
 type batchCreatedEvent struct {
 	BatchId           [32]byte
 	TotalAmount       *big.Int
@@ -14,6 +14,8 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethsana/sana/pkg/postage"
+	batchstoreMock "github.com/ethsana/sana/pkg/postage/batchstore/mock"
 	postageMock "github.com/ethsana/sana/pkg/postage/mock"
 	"github.com/ethsana/sana/pkg/postage/postagecontract"
 	"github.com/ethsana/sana/pkg/transaction"
@@ -85,6 +87,7 @@ func TestCreateBatch(t *testing.T) {
 				}),
 			),
 			postageMock,
+			batchstoreMock.New(),
 		)
 
 		returnedID, err := contract.CreateBatch(ctx, initialBalance, depth, false, label)
@@ -115,6 +118,7 @@ func TestCreateBatch(t *testing.T) {
 			bzzTokenAddress,
 			transactionMock.New(),
 			postageMock.New(),
+			batchstoreMock.New(),
 		)
 
 		_, err := contract.CreateBatch(ctx, initialBalance, depth, false, label)
@@ -140,6 +144,7 @@ func TestCreateBatch(t *testing.T) {
 				}),
 			),
 			postageMock.New(),
+			batchstoreMock.New(),
 		)
 
 		_, err := contract.CreateBatch(ctx, initialBalance, depth, false, label)
@@ -149,6 +154,168 @@ func TestCreateBatch(t *testing.T) {
 	})
 }
 
+func TestTopUpBatch(t *testing.T) {
+	owner := common.HexToAddress("abcd")
+	postageStampAddress := common.HexToAddress("ffff")
+	bzzTokenAddress := common.HexToAddress("eeee")
+	ctx := context.Background()
+	topUpAmount := big.NewInt(100)
+	batch := &postage.Batch{ID: common.HexToHash("dddd").Bytes(), Value: big.NewInt(0), Depth: 10}
+
+	t.Run("ok", func(t *testing.T) {
+		txHashApprove := common.HexToHash("abb0")
+		txHashTopUp := common.HexToHash("c3a7")
+
+		expectedCallData, err := postagecontract.PostageStampABI.Pack("topUp", common.BytesToHash(batch.ID), topUpAmount)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contract := postagecontract.New(
+			owner,
+			postageStampAddress,
+			bzzTokenAddress,
+			transactionMock.New(
+				transactionMock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (txHash common.Hash, err error) {
+					if *request.To == bzzTokenAddress {
+						return txHashApprove, nil
+					} else if *request.To == postageStampAddress {
+						if !bytes.Equal(expectedCallData, request.Data) {
+							return common.Hash{}, fmt.Errorf("got wrong call data. wanted %x, got %x", expectedCallData, request.Data)
+						}
+						return txHashTopUp, nil
+					}
+					return common.Hash{}, errors.New("sent to wrong contract")
+				}),
+				transactionMock.WithWaitForReceiptFunc(func(ctx context.Context, txHash common.Hash) (receipt *types.Receipt, err error) {
+					if txHash == txHashApprove || txHash == txHashTopUp {
+						return &types.Receipt{
+							Status: 1,
+						}, nil
+					}
+					return nil, errors.New("unknown tx hash")
+				}),
+				transactionMock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) (result []byte, err error) {
+					if *request.To == bzzTokenAddress {
+						return big.NewInt(102400).FillBytes(make([]byte, 32)), nil
+					}
+					return nil, errors.New("unexpected call")
+				}),
+			),
+			postageMock.New(),
+			batchstoreMock.New(batchstoreMock.WithBatch(batch)),
+		)
+
+		if err := contract.TopUpBatch(ctx, batch.ID, topUpAmount); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("batch not found", func(t *testing.T) {
+		contract := postagecontract.New(
+			owner,
+			postageStampAddress,
+			bzzTokenAddress,
+			transactionMock.New(),
+			postageMock.New(),
+			batchstoreMock.New(),
+		)
+
+		err := contract.TopUpBatch(ctx, batch.ID, topUpAmount)
+		if !errors.Is(err, postagecontract.ErrBatchNotFound) {
+			t.Fatalf("expected error %v. got %v", postagecontract.ErrBatchNotFound, err)
+		}
+	})
+
+	t.Run("insufficient funds", func(t *testing.T) {
+		contract := postagecontract.New(
+			owner,
+			postageStampAddress,
+			bzzTokenAddress,
+			transactionMock.New(
+				transactionMock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) (result []byte, err error) {
+					if *request.To == bzzTokenAddress {
+						return big.NewInt(102399).FillBytes(make([]byte, 32)), nil
+					}
+					return nil, errors.New("unexpected call")
+				}),
+			),
+			postageMock.New(),
+			batchstoreMock.New(batchstoreMock.WithBatch(batch)),
+		)
+
+		err := contract.TopUpBatch(ctx, batch.ID, topUpAmount)
+		if !errors.Is(err, postagecontract.ErrInsufficientFunds) {
+			t.Fatalf("expected error %v. got %v", postagecontract.ErrInsufficientFunds, err)
+		}
+	})
+}
+
+func TestDiluteBatch(t *testing.T) {
+	owner := common.HexToAddress("abcd")
+	postageStampAddress := common.HexToAddress("ffff")
+	bzzTokenAddress := common.HexToAddress("eeee")
+	ctx := context.Background()
+	batch := &postage.Batch{ID: common.HexToHash("dddd").Bytes(), Value: big.NewInt(0), Depth: 10}
+
+	t.Run("ok", func(t *testing.T) {
+		txHashDilute := common.HexToHash("c3a7")
+		newDepth := uint8(11)
+
+		expectedCallData, err := postagecontract.PostageStampABI.Pack("increaseDepth", common.BytesToHash(batch.ID), newDepth)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contract := postagecontract.New(
+			owner,
+			postageStampAddress,
+			bzzTokenAddress,
+			transactionMock.New(
+				transactionMock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (txHash common.Hash, err error) {
+					if *request.To == postageStampAddress {
+						if !bytes.Equal(expectedCallData, request.Data) {
+							return common.Hash{}, fmt.Errorf("got wrong call data. wanted %x, got %x", expectedCallData, request.Data)
+						}
+						return txHashDilute, nil
+					}
+					return common.Hash{}, errors.New("sent to wrong contract")
+				}),
+				transactionMock.WithWaitForReceiptFunc(func(ctx context.Context, txHash common.Hash) (receipt *types.Receipt, err error) {
+					if txHash == txHashDilute {
+						return &types.Receipt{
+							Status: 1,
+						}, nil
+					}
+					return nil, errors.New("unknown tx hash")
+				}),
+			),
+			postageMock.New(),
+			batchstoreMock.New(batchstoreMock.WithBatch(batch)),
+		)
+
+		if err := contract.DiluteBatch(ctx, batch.ID, newDepth); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("invalid depth", func(t *testing.T) {
+		contract := postagecontract.New(
+			owner,
+			postageStampAddress,
+			bzzTokenAddress,
+			transactionMock.New(),
+			postageMock.New(),
+			batchstoreMock.New(batchstoreMock.WithBatch(batch)),
+		)
+
+		err := contract.DiluteBatch(ctx, batch.ID, batch.Depth)
+		if !errors.Is(err, postagecontract.ErrInvalidDepth) {
+			t.Fatalf("expected error %v. got %v", postagecontract.ErrInvalidDepth, err)
+		}
+	})
+}
+
 func newCreateEvent(postageContractAddress common.Address, batchId common.Hash) *types.Log {
 	b, err := postagecontract.PostageStampABI.Events["BatchCreated"].Inputs.NonIndexed().Pack(
 		big.NewInt(0),
@@ -13,12 +13,22 @@ import (
 
 type contractMock struct {
 	createBatch func(ctx context.Context, initialBalance *big.Int, depth uint8, immutable bool, label string) ([]byte, error)
+	topUpBatch  func(ctx context.Context, batchID []byte, topUpAmount *big.Int) error
+	diluteBatch func(ctx context.Context, batchID []byte, newDepth uint8) error
 }
 
 func (c *contractMock) CreateBatch(ctx context.Context, initialBalance *big.Int, depth uint8, immutable bool, label string) ([]byte, error) {
 	return c.createBatch(ctx, initialBalance, depth, immutable, label)
 }
 
+func (c *contractMock) TopUpBatch(ctx context.Context, batchID []byte, topUpAmount *big.Int) error {
+	return c.topUpBatch(ctx, batchID, topUpAmount)
+}
+
+func (c *contractMock) DiluteBatch(ctx context.Context, batchID []byte, newDepth uint8) error {
+	return c.diluteBatch(ctx, batchID, newDepth)
+}
+
 // Option is a an option passed to New
 type Option func(*contractMock)
 
@@ -38,3 +48,15 @@ func WithCreateBatchFunc(f func(ctx context.Context, initialBalance *big.Int, de
 		m.createBatch = f
 	}
 }
+
+func WithTopUpBatchFunc(f func(ctx context.Context, batchID []byte, topUpAmount *big.Int) error) Option {
+	return func(m *contractMock) {
+		m.topUpBatch = f
+	}
+}
+
+func WithDiluteBatchFunc(f func(ctx context.Context, batchID []byte, newDepth uint8) error) Option {
+	return func(m *contractMock) {
+		m.diluteBatch = f
+	}
+}
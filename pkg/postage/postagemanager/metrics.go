@@ -0,0 +1,52 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postagemanager
+
+import (
+	m "github.com/ethsana/sana/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	ExpiringBatches prometheus.Gauge
+	TopUps          prometheus.Counter
+	Dilutions       prometheus.Counter
+	FailedTopUps    prometheus.Counter
+}
+
+func newMetrics() metrics {
+	subsystem := "postage_manager"
+
+	return metrics{
+		ExpiringBatches: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "expiring_batches",
+			Help:      "Number of owned batches which expire within the warning time to live.",
+		}),
+		TopUps: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "top_ups",
+			Help:      "Total automatic top-ups of the owned batches.",
+		}),
+		Dilutions: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "dilutions",
+			Help:      "Total automatic dilutions of the owned batches.",
+		}),
+		FailedTopUps: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "failed_top_ups",
+			Help:      "Total automatic top-ups and dilutions which failed.",
+		}),
+	}
+}
+
+func (s *Service) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(s.metrics)
+}
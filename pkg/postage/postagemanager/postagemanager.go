@@ -0,0 +1,432 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package postagemanager manages the lifecycle of the postage batches owned by
+// the node. It periodically estimates the remaining time to live of the
+// batches from their balances and the current price of the postage oracle,
+// warns about the batches which are about to expire and, within the budget
+// set by the operator, tops them up and dilutes the ones which are almost
+// fully utilized.
+package postagemanager
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/postage/postagecontract"
+	"github.com/ethsana/sana/pkg/storage"
+)
+
+const (
+	// DefaultInterval is the default time between the checks of the batches.
+	DefaultInterval = 10 * time.Minute
+	// DefaultWarnTTL is the default time to live under which the batches are
+	// reported as expiring.
+	DefaultWarnTTL = 7 * 24 * time.Hour
+
+	spentKey = "postagemanager_spent"
+)
+
+// TTLUnknown is the time to live of the batches while the price of the
+// postage oracle is not known.
+const TTLUnknown time.Duration = -1
+
+// Options configure the Service.
+type Options struct {
+	// TopUpLimit is the total amount of tokens the manager may spend on the
+	// top-ups of the batches. The batches are only watched if it is nil or
+	// zero.
+	TopUpLimit *big.Int
+	// WarnTTL is the time to live under which a batch is reported as
+	// expiring and topped up, DefaultWarnTTL if it is zero.
+	WarnTTL time.Duration
+	// TargetTTL is the time to live the expiring batches are topped up to,
+	// twice the WarnTTL if it is not greater than it.
+	TargetTTL time.Duration
+	// DiluteUtilization is the fill of the fullest bucket of a mutable batch,
+	// as a fraction of the bucket capacity, from which the batch is diluted
+	// to the next depth. The batches are not diluted if it is zero.
+	DiluteUtilization float64
+	// BlockTime is the time between the blocks of the chain.
+	BlockTime time.Duration
+	// Interval is the time between the checks of the batches,
+	// DefaultInterval if it is zero.
+	Interval time.Duration
+}
+
+// BatchStatus is the estimated lifetime of an owned batch.
+type BatchStatus struct {
+	BatchID string `json:"batchID"`
+	Label   string `json:"label"`
+	Depth   uint8  `json:"depth"`
+	// Utilization is the fill of the fullest bucket as a fraction of the
+	// bucket capacity.
+	Utilization float64 `json:"utilization"`
+	// TTL is the estimated remaining time to live in seconds, -1 if the
+	// price is not known.
+	TTL      int64 `json:"batchTTL"`
+	Expiring bool  `json:"expiring"`
+	Expired  bool  `json:"expired"`
+}
+
+// Status is the state of the owned batches and of the top-up budget.
+type Status struct {
+	Batches []BatchStatus
+	Limit   *big.Int
+	Spent   *big.Int
+}
+
+// pending is the change of a batch which is sent to the chain, but not yet
+// seen in the batch store.
+type pending struct {
+	value *big.Int
+	depth uint8
+}
+
+// Service watches and maintains the owned batches.
+type Service struct {
+	post      postage.Service
+	batches   postage.Storer
+	contract  postagecontract.Interface
+	store     storage.StateStorer
+	logger    logging.Logger
+	metrics   metrics
+	limit     *big.Int
+	warnTTL   time.Duration
+	targetTTL time.Duration
+	dilute    float64
+	blockTime time.Duration
+	interval  time.Duration
+
+	mu      sync.Mutex // serializes the checks
+	pending map[string]pending
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New returns a new Service. The contract may be nil if the node has no
+// chain backend, in which case the batches are only watched.
+func New(post postage.Service, batches postage.Storer, contract postagecontract.Interface, store storage.StateStorer, logger logging.Logger, o Options) *Service {
+	s := &Service{
+		post:      post,
+		batches:   batches,
+		contract:  contract,
+		store:     store,
+		logger:    logger,
+		metrics:   newMetrics(),
+		limit:     new(big.Int),
+		warnTTL:   o.WarnTTL,
+		targetTTL: o.TargetTTL,
+		dilute:    o.DiluteUtilization,
+		blockTime: o.BlockTime,
+		interval:  o.Interval,
+		pending:   make(map[string]pending),
+		quit:      make(chan struct{}),
+	}
+	if o.TopUpLimit != nil && contract != nil {
+		s.limit.Set(o.TopUpLimit)
+	}
+	if s.warnTTL <= 0 {
+		s.warnTTL = DefaultWarnTTL
+	}
+	if s.targetTTL <= s.warnTTL {
+		s.targetTTL = 2 * s.warnTTL
+	}
+	if s.interval <= 0 {
+		s.interval = DefaultInterval
+	}
+	return s
+}
+
+// Start begins the periodic checks of the batches.
+func (s *Service) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			<-s.quit
+			cancel()
+		}()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			if err := s.Check(ctx); err != nil {
+				s.logger.Debugf("postage manager: check: %v", err)
+				s.logger.Error("postage manager: unable to check the postage batches")
+			}
+			select {
+			case <-s.quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Status returns the estimated lifetime of the owned batches and the spent
+// part of the top-up budget.
+func (s *Service) Status() (*Status, error) {
+	spent, err := s.spent()
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{
+		Batches: []BatchStatus{},
+		Limit:   new(big.Int).Set(s.limit),
+		Spent:   spent,
+	}
+	for _, issuer := range s.post.StampIssuers() {
+		if issuer == nil {
+			continue
+		}
+		st, _, _ := s.batchStatus(issuer)
+		status.Batches = append(status.Batches, st)
+	}
+	return status, nil
+}
+
+// Check estimates the lifetime of the owned batches, warns about the
+// expiring ones and dilutes and tops them up within the budget.
+func (s *Service) Check(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiring := 0
+	for _, issuer := range s.post.StampIssuers() {
+		if issuer == nil {
+			continue
+		}
+		st, batch, remaining := s.batchStatus(issuer)
+		if st.Expiring {
+			expiring++
+		}
+		switch {
+		case st.Expired:
+			s.logger.Warningf("postage manager: batch %s expired", st.BatchID)
+			continue
+		case st.Expiring:
+			s.logger.Warningf("postage manager: batch %s expires in %v", st.BatchID, time.Duration(st.TTL)*time.Second)
+		}
+		if batch == nil || st.TTL < 0 || s.limit.Sign() == 0 || !s.settled(batch) {
+			continue
+		}
+
+		if s.dilute > 0 && !issuer.ImmutableFlag() && st.Utilization >= s.dilute {
+			if err := s.diluteBatch(ctx, batch, remaining); err != nil {
+				return err
+			}
+			continue
+		}
+		if st.Expiring {
+			if err := s.topUpBatch(ctx, batch, remaining); err != nil {
+				return err
+			}
+		}
+	}
+	s.metrics.ExpiringBatches.Set(float64(expiring))
+	return nil
+}
+
+// batchStatus returns the status of the batch of the issuer, the batch and
+// its remaining balance per chunk. The batch is nil if it is not in the
+// batch store.
+func (s *Service) batchStatus(issuer *postage.StampIssuer) (BatchStatus, *postage.Batch, *big.Int) {
+	st := BatchStatus{
+		BatchID: hex.EncodeToString(issuer.ID()),
+		Label:   issuer.Label(),
+		Depth:   issuer.Depth(),
+		TTL:     int64(TTLUnknown),
+	}
+	if issuer.Depth() > issuer.BucketDepth() {
+		capacity := uint64(1) << (issuer.Depth() - issuer.BucketDepth())
+		st.Utilization = float64(issuer.Utilization()) / float64(capacity)
+	}
+
+	batch, err := s.batches.Get(issuer.ID())
+	if err != nil {
+		// the expired batches are evicted from the batch store
+		st.Expired = true
+		st.TTL = 0
+		return st, nil, nil
+	}
+	st.Depth = batch.Depth
+
+	cs := s.batches.GetChainState()
+	remaining := new(big.Int).Set(batch.Value)
+	if cs.TotalAmount != nil {
+		remaining.Sub(remaining, cs.TotalAmount)
+	}
+	if remaining.Sign() <= 0 {
+		st.Expired = true
+		st.TTL = 0
+		return st, nil, nil
+	}
+	if cs.CurrentPrice == nil || cs.CurrentPrice.Sign() <= 0 {
+		return st, batch, remaining
+	}
+
+	ttl := ttlOf(remaining, cs.CurrentPrice, s.blockTime)
+	st.TTL = int64(ttl / time.Second)
+	st.Expiring = ttl < s.warnTTL
+	return st, batch, remaining
+}
+
+// ttlOf returns the time in which the remaining balance is paid at the price
+// per block.
+func ttlOf(remaining, price *big.Int, blockTime time.Duration) time.Duration {
+	blocks := new(big.Int).Div(remaining, price)
+	ttl := blocks.Mul(blocks, big.NewInt(int64(blockTime)))
+	if !ttl.IsInt64() {
+		return math.MaxInt64
+	}
+	return time.Duration(ttl.Int64())
+}
+
+// settled returns true if the last change of the batch sent by the manager
+// is seen in the batch store, so that it is not repeated.
+func (s *Service) settled(batch *postage.Batch) bool {
+	key := string(batch.ID)
+	p, ok := s.pending[key]
+	if !ok {
+		return true
+	}
+	if batch.Value.Cmp(p.value) < 0 || batch.Depth < p.depth {
+		return false
+	}
+	delete(s.pending, key)
+	return true
+}
+
+// topUpBatch tops up the batch to the target time to live, or with the
+// remaining budget if it is not enough.
+func (s *Service) topUpBatch(ctx context.Context, batch *postage.Batch, remaining *big.Int) error {
+	if s.blockTime <= 0 {
+		return nil
+	}
+	price := s.batches.GetChainState().CurrentPrice
+	targetBlocks := int64(s.targetTTL / s.blockTime)
+	amount := new(big.Int).Mul(price, big.NewInt(targetBlocks))
+	amount.Sub(amount, remaining)
+	if amount.Sign() <= 0 {
+		return nil
+	}
+
+	chunks := new(big.Int).Lsh(big.NewInt(1), uint(batch.Depth))
+	available, err := s.available()
+	if err != nil {
+		return err
+	}
+	if max := new(big.Int).Div(available, chunks); amount.Cmp(max) > 0 {
+		amount = max
+	}
+	if amount.Sign() == 0 {
+		s.logger.Warningf("postage manager: top-up budget exhausted, batch %x is not topped up", batch.ID)
+		return nil
+	}
+
+	cost := new(big.Int).Mul(amount, chunks)
+	if err := s.contract.TopUpBatch(ctx, batch.ID, amount); err != nil {
+		s.metrics.FailedTopUps.Inc()
+		if errors.Is(err, postagecontract.ErrInsufficientFunds) {
+			s.logger.Warningf("postage manager: insufficient funds to top up batch %x with %v per chunk", batch.ID, amount)
+			return nil
+		}
+		return fmt.Errorf("top up batch %x: %w", batch.ID, err)
+	}
+	s.metrics.TopUps.Inc()
+	s.pending[string(batch.ID)] = pending{
+		value: new(big.Int).Add(batch.Value, amount),
+		depth: batch.Depth,
+	}
+	s.logger.Infof("postage manager: topped up batch %x with %v per chunk", batch.ID, amount)
+	return s.spend(cost)
+}
+
+// diluteBatch increases the depth of the batch and tops it up with the
+// balance which keeps its time to live, if the budget allows it.
+func (s *Service) diluteBatch(ctx context.Context, batch *postage.Batch, remaining *big.Int) error {
+	// the remaining balance is halved by the dilution, the same amount over
+	// the doubled number of chunks restores it
+	cost := new(big.Int).Lsh(remaining, uint(batch.Depth))
+	available, err := s.available()
+	if err != nil {
+		return err
+	}
+	if cost.Cmp(available) > 0 {
+		s.logger.Warningf("postage manager: top-up budget exhausted, batch %x is not diluted", batch.ID)
+		return nil
+	}
+
+	depth := batch.Depth + 1
+	if err := s.contract.DiluteBatch(ctx, batch.ID, depth); err != nil {
+		s.metrics.FailedTopUps.Inc()
+		return fmt.Errorf("dilute batch %x: %w", batch.ID, err)
+	}
+	s.metrics.Dilutions.Inc()
+	s.logger.Infof("postage manager: diluted batch %x to depth %d", batch.ID, depth)
+
+	amount := new(big.Int).Rsh(remaining, 1)
+	value := new(big.Int).Sub(batch.Value, amount)
+	s.pending[string(batch.ID)] = pending{value: value, depth: depth}
+	if amount.Sign() == 0 {
+		return nil
+	}
+	// the batch store is not updated before the depth increase is synced
+	if err := s.contract.TopUpBatch(ctx, batch.ID, amount); err != nil {
+		s.metrics.FailedTopUps.Inc()
+		return fmt.Errorf("top up diluted batch %x: %w", batch.ID, err)
+	}
+	s.metrics.TopUps.Inc()
+	s.pending[string(batch.ID)] = pending{value: new(big.Int).Set(batch.Value), depth: depth}
+	return s.spend(cost)
+}
+
+func (s *Service) spent() (*big.Int, error) {
+	spent := new(big.Int)
+	if err := s.store.Get(spentKey, spent); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	return spent, nil
+}
+
+// available returns the part of the budget which is not spent.
+func (s *Service) available() (*big.Int, error) {
+	spent, err := s.spent()
+	if err != nil {
+		return nil, err
+	}
+	available := new(big.Int).Sub(s.limit, spent)
+	if available.Sign() < 0 {
+		available.SetInt64(0)
+	}
+	return available, nil
+}
+
+func (s *Service) spend(amount *big.Int) error {
+	spent, err := s.spent()
+	if err != nil {
+		return err
+	}
+	return s.store.Put(spentKey, spent.Add(spent, amount))
+}
+
+// Close stops the checks of the batches.
+func (s *Service) Close() error {
+	close(s.quit)
+	s.wg.Wait()
+	return nil
+}
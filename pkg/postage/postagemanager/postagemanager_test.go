@@ -0,0 +1,229 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postagemanager_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"io/ioutil"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/postage"
+	batchstoreMock "github.com/ethsana/sana/pkg/postage/batchstore/mock"
+	postageMock "github.com/ethsana/sana/pkg/postage/mock"
+	"github.com/ethsana/sana/pkg/postage/postagecontract"
+	contractMock "github.com/ethsana/sana/pkg/postage/postagecontract/mock"
+	"github.com/ethsana/sana/pkg/postage/postagemanager"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/swarm/test"
+)
+
+const (
+	depth       = 17
+	bucketDepth = 16
+)
+
+var (
+	batchID = make([]byte, 32)
+	chunks  = big.NewInt(1 << depth)
+)
+
+type topUp struct {
+	id     []byte
+	amount *big.Int
+}
+
+// newTestService returns the service of a batch with the balance of 10
+// blocks at the price of 10 per block, which expires in 10 seconds.
+func newTestService(t *testing.T, o postagemanager.Options, issuer *postage.StampIssuer, opts ...contractMock.Option) (*postagemanager.Service, *batchstoreMock.BatchStore) {
+	t.Helper()
+
+	if issuer == nil {
+		issuer = postage.NewStampIssuer("label", "keyID", batchID, big.NewInt(100), depth, bucketDepth, 0, false)
+	}
+	batches := batchstoreMock.New(
+		batchstoreMock.WithBatch(&postage.Batch{ID: batchID, Value: big.NewInt(100), Depth: depth, BucketDepth: bucketDepth}),
+		batchstoreMock.WithChainState(&postage.ChainState{TotalAmount: big.NewInt(0), CurrentPrice: big.NewInt(10)}),
+	)
+	o.BlockTime = time.Second
+	o.WarnTTL = time.Minute
+	o.TargetTTL = 2 * time.Minute
+	s := postagemanager.New(
+		postageMock.New(postageMock.WithIssuer(issuer)),
+		batches,
+		contractMock.New(opts...),
+		statestore.NewStateStore(),
+		logging.New(ioutil.Discard, 0),
+		o,
+	)
+	return s, batches
+}
+
+func withTopUps(topUps *[]topUp) contractMock.Option {
+	return contractMock.WithTopUpBatchFunc(func(_ context.Context, id []byte, amount *big.Int) error {
+		*topUps = append(*topUps, topUp{id: id, amount: amount})
+		return nil
+	})
+}
+
+func TestStatus(t *testing.T) {
+	s, _ := newTestService(t, postagemanager.Options{}, nil)
+
+	status, err := s.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Batches) != 1 {
+		t.Fatalf("got %d batches, want 1", len(status.Batches))
+	}
+	b := status.Batches[0]
+	if b.TTL != 10 {
+		t.Fatalf("got ttl %d, want %d", b.TTL, 10)
+	}
+	if !b.Expiring || b.Expired {
+		t.Fatalf("got expiring %t, expired %t, want expiring", b.Expiring, b.Expired)
+	}
+	if status.Limit.Sign() != 0 || status.Spent.Sign() != 0 {
+		t.Fatalf("got limit %v, spent %v, want no budget", status.Limit, status.Spent)
+	}
+}
+
+func TestTopUp(t *testing.T) {
+	t.Run("to target ttl", func(t *testing.T) {
+		var topUps []topUp
+		s, _ := newTestService(t, postagemanager.Options{TopUpLimit: big.NewInt(1e12)}, nil, withTopUps(&topUps))
+
+		if err := s.Check(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		// the target of 120 blocks at the price of 10 less the balance
+		want := big.NewInt(1100)
+		if len(topUps) != 1 || topUps[0].amount.Cmp(want) != 0 {
+			t.Fatalf("got top-ups %v, want one of %v", topUps, want)
+		}
+
+		// the top-up is not yet seen in the batch store
+		if err := s.Check(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if len(topUps) != 1 {
+			t.Fatalf("got %d top-ups, want 1", len(topUps))
+		}
+
+		status, err := s.Status()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if spent := new(big.Int).Mul(want, chunks); status.Spent.Cmp(spent) != 0 {
+			t.Fatalf("got spent %v, want %v", status.Spent, spent)
+		}
+	})
+
+	t.Run("within budget", func(t *testing.T) {
+		var topUps []topUp
+		limit := new(big.Int).Mul(big.NewInt(500), chunks)
+		s, batches := newTestService(t, postagemanager.Options{TopUpLimit: limit}, nil, withTopUps(&topUps))
+
+		if err := s.Check(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if len(topUps) != 1 || topUps[0].amount.Cmp(big.NewInt(500)) != 0 {
+			t.Fatalf("got top-ups %v, want one of 500", topUps)
+		}
+
+		// the budget is exhausted once the top-up is synced
+		b, err := batches.Get(batchID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := batches.Put(b, big.NewInt(600), depth); err != nil {
+			t.Fatal(err)
+		}
+		if err := batches.PutChainState(&postage.ChainState{TotalAmount: big.NewInt(0), CurrentPrice: big.NewInt(20)}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Check(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if len(topUps) != 1 {
+			t.Fatalf("got %d top-ups, want 1", len(topUps))
+		}
+	})
+
+	t.Run("without budget", func(t *testing.T) {
+		var topUps []topUp
+		s, _ := newTestService(t, postagemanager.Options{}, nil, withTopUps(&topUps))
+
+		if err := s.Check(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if len(topUps) != 0 {
+			t.Fatalf("got %d top-ups, want none", len(topUps))
+		}
+	})
+
+	t.Run("insufficient funds", func(t *testing.T) {
+		s, _ := newTestService(t, postagemanager.Options{TopUpLimit: big.NewInt(1e12)}, nil,
+			contractMock.WithTopUpBatchFunc(func(context.Context, []byte, *big.Int) error {
+				return postagecontract.ErrInsufficientFunds
+			}),
+		)
+
+		if err := s.Check(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		status, err := s.Status()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Spent.Sign() != 0 {
+			t.Fatalf("got spent %v, want none", status.Spent)
+		}
+	})
+}
+
+func TestDilute(t *testing.T) {
+	issuer := postage.NewStampIssuer("label", "keyID", batchID, big.NewInt(100), depth, bucketDepth, 0, false)
+	signer := crypto.NewDefaultSigner(mustGenerateKey(t))
+	if _, err := postage.NewStamper(issuer, signer).Stamp(test.RandomAddress()); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		topUps   []topUp
+		newDepth uint8
+	)
+	s, _ := newTestService(t, postagemanager.Options{TopUpLimit: big.NewInt(1e12), DiluteUtilization: 0.5}, issuer,
+		withTopUps(&topUps),
+		contractMock.WithDiluteBatchFunc(func(_ context.Context, _ []byte, d uint8) error {
+			newDepth = d
+			return nil
+		}),
+	)
+
+	if err := s.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if newDepth != depth+1 {
+		t.Fatalf("got new depth %d, want %d", newDepth, depth+1)
+	}
+	// the halved balance is restored
+	if len(topUps) != 1 || topUps[0].amount.Cmp(big.NewInt(50)) != 0 {
+		t.Fatalf("got top-ups %v, want one of 50", topUps)
+	}
+}
+
+func mustGenerateKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
@@ -103,6 +103,25 @@ func (ps *service) Handle(b *Batch) {
 	))
 }
 
+// HandleDepthIncrease implements the BatchDepthIncreaseListener interface. It
+// sets the new depth of the diluted batch in its stamp issuer, extending the
+// capacity of its buckets.
+func (ps *service) HandleDepthIncrease(id []byte, depth uint8) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	for _, st := range ps.issuers {
+		if bytes.Equal(id, st.data.BatchID) {
+			st.bucketMu.Lock()
+			if depth > st.data.BatchDepth {
+				st.data.BatchDepth = depth
+			}
+			st.bucketMu.Unlock()
+			return
+		}
+	}
+}
+
 // StampIssuers returns the currently active stamp issuers.
 func (ps *service) StampIssuers() []*StampIssuer {
 	ps.lock.Lock()
@@ -121,3 +121,25 @@ func TestGetStampIssuer(t *testing.T) {
 		}
 	})
 }
+
+func TestHandleDepthIncrease(t *testing.T) {
+	ps, err := postage.NewService(storemock.NewStateStore(), pstoremock.New(), int64(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 32)
+	if _, err := io.ReadFull(crand.Reader, id); err != nil {
+		t.Fatal(err)
+	}
+	ps.Add(postage.NewStampIssuer("label", "keyID", id, big.NewInt(3), 16, 8, 0, false))
+
+	l, ok := ps.(postage.BatchDepthIncreaseListener)
+	if !ok {
+		t.Fatal("service is not a depth increase listener")
+	}
+	l.HandleDepthIncrease(id, 17)
+
+	if got := ps.StampIssuers()[0].Depth(); got != 17 {
+		t.Fatalf("got depth %d, want %d", got, 17)
+	}
+}
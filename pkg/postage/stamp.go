@@ -141,27 +141,37 @@ func ValidStamp(batchStore Storer) func(chunk swarm.Chunk, stampBytes []byte) (s
 // the validity  check is only meaningful in its association of a chunk
 // this chunk address needs to be given as argument
 func (s *Stamp) Valid(chunkAddr swarm.Address, ownerAddr []byte, depth, bucketDepth uint8, immutable bool) error {
-	toSign, err := toSignDigest(chunkAddr.Bytes(), s.batchID, s.index, s.timestamp)
+	signerAddr, err := s.signer(chunkAddr, depth, bucketDepth)
 	if err != nil {
 		return err
 	}
+	if !bytes.Equal(signerAddr, ownerAddr) {
+		return ErrOwnerMismatch
+	}
+	return nil
+}
+
+// signer returns the ethereum address of the stamp signer after checking that
+// the stamp index is valid for the chunk.
+func (s *Stamp) signer(chunkAddr swarm.Address, depth, bucketDepth uint8) ([]byte, error) {
+	toSign, err := toSignDigest(chunkAddr.Bytes(), s.batchID, s.index, s.timestamp)
+	if err != nil {
+		return nil, err
+	}
 	signerPubkey, err := crypto.Recover(s.sig, toSign)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	signerAddr, err := crypto.NewEthereumAddress(*signerPubkey)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	bucket, index := bytesToIndex(s.index)
 	if toBucket(bucketDepth, chunkAddr) != bucket {
-		return ErrBucketMismatch
+		return nil, ErrBucketMismatch
 	}
 	if index >= 1<<int(depth-bucketDepth) {
-		return ErrInvalidIndex
+		return nil, ErrInvalidIndex
 	}
-	if !bytes.Equal(signerAddr, ownerAddr) {
-		return ErrOwnerMismatch
-	}
-	return nil
+	return signerAddr, nil
 }
@@ -0,0 +1,60 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+// StaticBatchValue is the normalised balance of a static batch. There is no
+// chain which increases the total amount paid out, so it never expires.
+var StaticBatchValue = new(big.Int).Lsh(big.NewInt(1), 128)
+
+// NewStaticBatch returns a batch which is not created on the blockchain but
+// configured on every node of a network without a chain backend.
+func NewStaticBatch(id []byte, depth, bucketDepth uint8) *Batch {
+	return &Batch{
+		ID:          id,
+		Value:       new(big.Int).Set(StaticBatchValue),
+		Owner:       make([]byte, 20),
+		Depth:       depth,
+		BucketDepth: bucketDepth,
+	}
+}
+
+// ValidStaticStamp returns a stampvalidator function for networks with static
+// postage. Every node stamps the chunks of the static batch with its own key,
+// so its stamps are accepted regardless of their signer. Stamps of other
+// batches are validated as with ValidStamp.
+func ValidStaticStamp(batchStore Storer, staticID []byte) func(chunk swarm.Chunk, stampBytes []byte) (swarm.Chunk, error) {
+	validStamp := ValidStamp(batchStore)
+	return func(chunk swarm.Chunk, stampBytes []byte) (swarm.Chunk, error) {
+		stamp := new(Stamp)
+		err := stamp.UnmarshalBinary(stampBytes)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(stamp.BatchID(), staticID) {
+			return validStamp(chunk, stampBytes)
+		}
+		b, err := batchStore.Get(stamp.BatchID())
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return nil, fmt.Errorf("batchstore get: %v, %w", err, ErrNotFound)
+			}
+			return nil, err
+		}
+		if _, err := stamp.signer(chunk.Address(), b.Depth, b.BucketDepth); err != nil {
+			return nil, err
+		}
+		return chunk.WithStamp(stamp).WithBatch(b.Radius, b.Depth, b.BucketDepth, b.Immutable), nil
+	}
+}
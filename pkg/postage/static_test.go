@@ -0,0 +1,76 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postage_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/postage/batchstore/mock"
+	postagetesting "github.com/ethsana/sana/pkg/postage/testing"
+	chunktesting "github.com/ethsana/sana/pkg/storage/testing"
+)
+
+func TestValidStaticStamp(t *testing.T) {
+	b := postage.NewStaticBatch(postagetesting.MustNewID(), 20, 16)
+	other := postagetesting.MustNewBatch()
+	bs := mock.New(mock.WithBatch(b))
+	validStamp := postage.ValidStaticStamp(bs, b.ID)
+
+	// stamps of every node are accepted for the static batch
+	for i := 0; i < 2; i++ {
+		privKey, err := crypto.GenerateSecp256k1Key()
+		if err != nil {
+			t.Fatal(err)
+		}
+		issuer := postage.NewStampIssuer("static", "", b.ID, b.Value, b.Depth, b.BucketDepth, 0, false)
+		stamper := postage.NewStamper(issuer, crypto.NewDefaultSigner(privKey))
+
+		ch := chunktesting.GenerateTestRandomChunk()
+		st, err := stamper.Stamp(ch.Address())
+		if err != nil {
+			t.Fatal(err)
+		}
+		stBytes, err := st.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch, err = validStamp(ch, stBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compareStamps(t, st, ch.Stamp().(*postage.Stamp))
+		if ch.Depth() != b.Depth || ch.BucketDepth() != b.BucketDepth {
+			t.Fatalf("got depth %d and bucket depth %d, want %d and %d", ch.Depth(), ch.BucketDepth(), b.Depth, b.BucketDepth)
+		}
+
+		// the static batch owner is not the signer
+		if _, err := postage.ValidStamp(bs)(ch, stBytes); !errors.Is(err, postage.ErrOwnerMismatch) {
+			t.Fatalf("got error %v, want %v", err, postage.ErrOwnerMismatch)
+		}
+	}
+
+	// stamps of other batches are not accepted
+	privKey, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := postage.NewStampIssuer("other", "", other.ID, other.Value, other.Depth, other.BucketDepth, 0, false)
+	stamper := postage.NewStamper(issuer, crypto.NewDefaultSigner(privKey))
+	ch := chunktesting.GenerateTestRandomChunk()
+	st, err := stamper.Stamp(ch.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stBytes, err := st.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := validStamp(ch, stBytes); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,15 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mailbox
+
+import "time"
+
+func (s *Service) SetTimeNow(f func() time.Time) {
+	s.timeNow = f
+}
+
+func (s *Service) Prune() error {
+	return s.prune()
+}
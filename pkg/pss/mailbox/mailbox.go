@@ -0,0 +1,683 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mailbox keeps pss messages for recipients which are offline when
+// the messages are sent.
+//
+// The messages are deposited as stamped trojan chunks in the mailbox of the
+// pss target they are mined for. The mailbox of a target is held by the full
+// node closest to the target padded to an address, to which the deposits and
+// the fetches of the recipients are forwarded. The holders keep the envelopes
+// for a bounded time to live, and the recipients fetch and unwrap the
+// envelopes stored since their last fetch.
+package mailbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethsana/sana/pkg/cac"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/p2p"
+	"github.com/ethsana/sana/pkg/p2p/protobuf"
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/pss"
+	"github.com/ethsana/sana/pkg/pss/mailbox/pb"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/topology"
+)
+
+const (
+	protocolName    = "pssmailbox"
+	protocolVersion = "1.0.0"
+	streamDeposit   = "deposit"
+	streamFetch     = "fetch"
+
+	keyPrefix = "pss_mailbox_"
+
+	// DefaultMaxTTL is the default maximal time to live of the envelopes.
+	DefaultMaxTTL = 7 * 24 * time.Hour
+	// DefaultCapacity is the default maximal number of envelopes held.
+	DefaultCapacity = 10000
+	// MaxTargetLength is the maximal length of the targets of the mailboxes,
+	// the one of the pss api, which bounds the mining of the envelopes.
+	MaxTargetLength = 2
+	// MaxFetchEnvelopes is the maximal number of envelopes returned by a
+	// fetch, the rest is returned by the fetches since the last one.
+	MaxFetchEnvelopes = 256
+
+	fetchBatchSize = 16 // envelopes per message, within the protobuf size limit
+	maxAttempts    = 3  // peers tried before the request fails
+	requestTimeout = 30 * time.Second
+	pruneInterval  = 10 * time.Minute
+)
+
+var (
+	// ErrNoMailbox is returned if no node holding the mailbox of the target
+	// is reachable.
+	ErrNoMailbox = errors.New("no mailbox")
+	// ErrFull is returned if the holder of the mailbox is at its capacity.
+	ErrFull = errors.New("mailbox full")
+	// ErrInvalidTarget is returned for targets which are empty, too long or
+	// not matching the address of the envelope.
+	ErrInvalidTarget = errors.New("invalid mailbox target")
+	// ErrInvalidEnvelope is returned if the envelope is not a content
+	// addressed chunk.
+	ErrInvalidEnvelope = errors.New("invalid mailbox envelope")
+)
+
+// remoteErrors are the errors of the holders reported to the nodes which
+// forwarded the requests.
+var remoteErrors = []error{ErrNoMailbox, ErrFull, ErrInvalidTarget, ErrInvalidEnvelope, postage.ErrNotFound}
+
+// Options configure the Service.
+type Options struct {
+	// Holder, if set, makes the node hold the mailboxes it is the closest
+	// node to and forward the requests of other nodes. Otherwise the node
+	// only deposits in and fetches from the mailboxes of other nodes.
+	Holder bool
+	// MaxTTL bounds the time to live of the held envelopes, DefaultMaxTTL if
+	// it is zero.
+	MaxTTL time.Duration
+	// Capacity is the maximal number of held envelopes, DefaultCapacity if
+	// it is zero.
+	Capacity int
+}
+
+// Message is a pss message fetched from a mailbox.
+type Message struct {
+	Payload []byte
+	// Stored is the time the holder stored the envelope of the message at,
+	// after which the next fetch continues.
+	Stored time.Time
+}
+
+// record is the envelope held in the state store.
+type record struct {
+	Address []byte `json:"address"`
+	Data    []byte `json:"data"`
+	Stamp   []byte `json:"stamp"`
+	Stored  int64  `json:"stored"` // unix nanoseconds
+	Expiry  int64  `json:"expiry"` // unix nanoseconds
+}
+
+// Service deposits pss messages in and fetches them from the mailboxes, and
+// holds the mailboxes of the Holder nodes.
+type Service struct {
+	streamer   p2p.Streamer
+	topology   topology.ClosestPeerer
+	store      storage.StateStorer
+	key        *ecdsa.PrivateKey
+	validStamp func(swarm.Chunk, []byte) (swarm.Chunk, error)
+	logger     logging.Logger
+	metrics    metrics
+	holder     bool
+	maxTTL     time.Duration
+	capacity   int
+	timeNow    func() time.Time
+
+	mu    sync.Mutex // serializes the changes of the held envelopes
+	count int
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New returns the mailbox service of the node with the pss key. The stamps
+// of the held envelopes are validated with the validStamp function.
+func New(streamer p2p.Streamer, topology topology.ClosestPeerer, store storage.StateStorer, key *ecdsa.PrivateKey, validStamp func(swarm.Chunk, []byte) (swarm.Chunk, error), logger logging.Logger, o Options) (*Service, error) {
+	s := &Service{
+		streamer:   streamer,
+		topology:   topology,
+		store:      store,
+		key:        key,
+		validStamp: validStamp,
+		logger:     logger,
+		metrics:    newMetrics(),
+		holder:     o.Holder,
+		maxTTL:     o.MaxTTL,
+		capacity:   o.Capacity,
+		timeNow:    time.Now,
+		quit:       make(chan struct{}),
+	}
+	if s.maxTTL <= 0 {
+		s.maxTTL = DefaultMaxTTL
+	}
+	if s.capacity <= 0 {
+		s.capacity = DefaultCapacity
+	}
+
+	if s.holder {
+		if err := s.prune(); err != nil {
+			return nil, fmt.Errorf("prune mailbox: %w", err)
+		}
+		s.wg.Add(1)
+		go s.pruneLoop()
+	}
+	return s, nil
+}
+
+// Protocol returns the protocol of the holders.
+func (s *Service) Protocol() p2p.ProtocolSpec {
+	return p2p.ProtocolSpec{
+		Name:    protocolName,
+		Version: protocolVersion,
+		StreamSpecs: []p2p.StreamSpec{
+			{
+				Name:    streamDeposit,
+				Handler: s.depositHandler,
+			},
+			{
+				Name:    streamFetch,
+				Handler: s.fetchHandler,
+			},
+		},
+	}
+}
+
+// Send wraps the payload for the recipient like pss does and deposits the
+// trojan chunk stamped by the stamper in the mailbox of the target which its
+// address is mined for, to be kept for the ttl.
+func (s *Service) Send(ctx context.Context, topic pss.Topic, payload []byte, stamper postage.Stamper, recipient *ecdsa.PublicKey, targets pss.Targets, ttl time.Duration) error {
+	for _, t := range targets {
+		if len(t) == 0 || len(t) > MaxTargetLength {
+			return ErrInvalidTarget
+		}
+	}
+	chunk, err := pss.Wrap(ctx, topic, payload, recipient, targets)
+	if err != nil {
+		return err
+	}
+	stamp, err := stamper.Stamp(chunk.Address())
+	if err != nil {
+		return err
+	}
+	stampBytes, err := stamp.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var target []byte
+	for _, t := range targets {
+		if bytes.HasPrefix(chunk.Address().Bytes(), t) {
+			target = t
+			break
+		}
+	}
+	if target == nil {
+		return ErrInvalidTarget
+	}
+
+	return s.deposit(ctx, &pb.Deposit{
+		Target:  target,
+		Address: chunk.Address().Bytes(),
+		Data:    chunk.Data(),
+		Stamp:   stampBytes,
+		TTL:     int64(ttl / time.Second),
+	})
+}
+
+// Fetch returns the messages with the topic to the node in the mailboxes of
+// the targets, which were stored after the since time, and the time the next
+// fetch continues at. The mailboxes return at most MaxFetchEnvelopes
+// envelopes each, the later ones are returned by the next fetch.
+func (s *Service) Fetch(ctx context.Context, topic pss.Topic, targets pss.Targets, since time.Time) (messages []Message, next time.Time, err error) {
+	var sinceNanos int64
+	if !since.IsZero() {
+		sinceNanos = since.UnixNano()
+	}
+	latest, truncated := sinceNanos, int64(math.MaxInt64)
+	for _, t := range targets {
+		if len(t) == 0 || len(t) > MaxTargetLength {
+			return nil, time.Time{}, ErrInvalidTarget
+		}
+		envelopes, err := s.fetch(ctx, &pb.Fetch{Target: t, Since: sinceNanos})
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("fetch mailbox %x: %w", t, err)
+		}
+		for _, e := range envelopes {
+			if e.Stored > latest {
+				latest = e.Stored
+			}
+			chunk := swarm.NewChunk(swarm.NewAddress(e.Address), e.Data)
+			if !cac.Valid(chunk) {
+				continue
+			}
+			if _, msg, err := pss.Unwrap(ctx, s.key, chunk, []pss.Topic{topic}); err == nil && msg != nil {
+				messages = append(messages, Message{Payload: msg, Stored: time.Unix(0, e.Stored)})
+			}
+		}
+		if len(envelopes) >= MaxFetchEnvelopes && envelopes[len(envelopes)-1].Stored < truncated {
+			truncated = envelopes[len(envelopes)-1].Stored
+		}
+	}
+	if latest > truncated {
+		// the later messages of the other mailboxes are returned again by
+		// the next fetch
+		latest = truncated
+		n := 0
+		for _, m := range messages {
+			if m.Stored.UnixNano() <= latest {
+				messages[n] = m
+				n++
+			}
+		}
+		messages = messages[:n]
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Stored.Before(messages[j].Stored)
+	})
+	if latest == sinceNanos {
+		return messages, since, nil
+	}
+	return messages, time.Unix(0, latest), nil
+}
+
+// rendezvous returns the address of the mailbox of the target.
+func rendezvous(target []byte) swarm.Address {
+	b := make([]byte, swarm.HashSize)
+	copy(b, target)
+	return swarm.NewAddress(b)
+}
+
+// route calls the function with the closest peers to the mailbox of the
+// target which supports the protocol, skipping the peers. It returns
+// topology.ErrWantSelf if this node is the closest one.
+func (s *Service) route(ctx context.Context, target []byte, f func(peer swarm.Address) error, skip ...swarm.Address) error {
+	addr := rendezvous(target)
+	var lastErr error
+	for i := 0; i < maxAttempts; i++ {
+		peer, err := s.topology.ClosestPeer(addr, s.holder, skip...)
+		if err != nil {
+			if errors.Is(err, topology.ErrWantSelf) {
+				return err
+			}
+			if errors.Is(err, topology.ErrNotFound) {
+				if lastErr != nil {
+					return fmt.Errorf("%w: %v", ErrNoMailbox, lastErr)
+				}
+				return ErrNoMailbox
+			}
+			return err
+		}
+		if err := f(peer); err != nil {
+			var remote *remoteError
+			if errors.As(err, &remote) {
+				return remote.err
+			}
+			s.logger.Debugf("pss mailbox: request to peer %s: %v", peer, err)
+			lastErr = err
+			skip = append(skip, peer)
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %v", ErrNoMailbox, lastErr)
+}
+
+// remoteError is the error reported by the peer.
+type remoteError struct {
+	err error
+}
+
+func (e *remoteError) Error() string {
+	return e.err.Error()
+}
+
+func newRemoteError(msg string) *remoteError {
+	for _, err := range remoteErrors {
+		if msg == err.Error() {
+			return &remoteError{err: err}
+		}
+	}
+	return &remoteError{err: errors.New(msg)}
+}
+
+// errorString returns the error reported to the peers, for the remoteErrors
+// without their context.
+func errorString(err error) string {
+	for _, e := range remoteErrors {
+		if errors.Is(err, e) {
+			return e.Error()
+		}
+	}
+	return err.Error()
+}
+
+// deposit stores the envelope in the mailbox of its target, on this node or
+// on the closest peer.
+func (s *Service) deposit(ctx context.Context, d *pb.Deposit, skip ...swarm.Address) error {
+	err := s.route(ctx, d.Target, func(peer swarm.Address) error {
+		return s.depositPeer(ctx, peer, d)
+	}, skip...)
+	if errors.Is(err, topology.ErrWantSelf) {
+		return s.hold(d)
+	}
+	if err == nil {
+		s.metrics.ForwardedDeposits.Inc()
+	}
+	return err
+}
+
+func (s *Service) depositPeer(ctx context.Context, peer swarm.Address, d *pb.Deposit) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	stream, err := s.streamer.NewStream(ctx, peer, nil, protocolName, protocolVersion, streamDeposit)
+	if err != nil {
+		return fmt.Errorf("new stream: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = stream.Reset()
+		} else {
+			go stream.FullClose()
+		}
+	}()
+
+	w, r := protobuf.NewWriterAndReader(stream)
+	if err := w.WriteMsgWithContext(ctx, d); err != nil {
+		return fmt.Errorf("write deposit: %w", err)
+	}
+	var receipt pb.Receipt
+	if err := r.ReadMsgWithContext(ctx, &receipt); err != nil {
+		return fmt.Errorf("read receipt: %w", err)
+	}
+	if receipt.Err != "" {
+		return newRemoteError(receipt.Err)
+	}
+	return nil
+}
+
+func (s *Service) depositHandler(ctx context.Context, p p2p.Peer, stream p2p.Stream) (err error) {
+	w, r := protobuf.NewWriterAndReader(stream)
+	defer func() {
+		if err != nil {
+			_ = stream.Reset()
+		} else {
+			_ = stream.FullClose()
+		}
+	}()
+
+	var d pb.Deposit
+	if err := r.ReadMsgWithContext(ctx, &d); err != nil {
+		return fmt.Errorf("read deposit: %w", err)
+	}
+
+	var receipt pb.Receipt
+	if err := s.deposit(ctx, &d, p.Address); err != nil {
+		s.logger.Debugf("pss mailbox: deposit of peer %s: %v", p.Address, err)
+		receipt.Err = errorString(err)
+	}
+	if err := w.WriteMsgWithContext(ctx, &receipt); err != nil {
+		return fmt.Errorf("write receipt: %w", err)
+	}
+	return nil
+}
+
+// hold validates the envelope and stores it in the mailbox on this node.
+func (s *Service) hold(d *pb.Deposit) error {
+	if len(d.Target) == 0 || len(d.Target) > MaxTargetLength || !bytes.HasPrefix(d.Address, d.Target) {
+		return ErrInvalidTarget
+	}
+	chunk := swarm.NewChunk(swarm.NewAddress(d.Address), d.Data)
+	if !cac.Valid(chunk) {
+		return ErrInvalidEnvelope
+	}
+	if _, err := s.validStamp(chunk, d.Stamp); err != nil {
+		return fmt.Errorf("invalid stamp: %w", err)
+	}
+
+	ttl := time.Duration(d.TTL) * time.Second
+	if ttl <= 0 || ttl > s.maxTTL {
+		ttl = s.maxTTL
+	}
+	now := s.timeNow()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := recordKey(d.Target, d.Address)
+	var existing record
+	switch err := s.store.Get(key, &existing); {
+	case errors.Is(err, storage.ErrNotFound):
+		if s.count >= s.capacity {
+			return ErrFull
+		}
+		s.count++
+	case err != nil:
+		return err
+	}
+	if err := s.store.Put(key, record{
+		Address: d.Address,
+		Data:    d.Data,
+		Stamp:   d.Stamp,
+		Stored:  now.UnixNano(),
+		Expiry:  now.Add(ttl).UnixNano(),
+	}); err != nil {
+		return err
+	}
+	s.metrics.HeldDeposits.Inc()
+	s.metrics.HeldEnvelopes.Set(float64(s.count))
+	return nil
+}
+
+func recordKey(target, address []byte) string {
+	return fmt.Sprintf("%s%x_%x", keyPrefix, target, address)
+}
+
+// fetch returns the envelopes of the mailbox of the target, on this node or
+// on the closest peer.
+func (s *Service) fetch(ctx context.Context, f *pb.Fetch, skip ...swarm.Address) (envelopes []*pb.Envelope, err error) {
+	err = s.route(ctx, f.Target, func(peer swarm.Address) (err error) {
+		envelopes, err = s.fetchPeer(ctx, peer, f)
+		return err
+	}, skip...)
+	if errors.Is(err, topology.ErrWantSelf) {
+		return s.held(f)
+	}
+	return envelopes, err
+}
+
+func (s *Service) fetchPeer(ctx context.Context, peer swarm.Address, f *pb.Fetch) (envelopes []*pb.Envelope, err error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	stream, err := s.streamer.NewStream(ctx, peer, nil, protocolName, protocolVersion, streamFetch)
+	if err != nil {
+		return nil, fmt.Errorf("new stream: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = stream.Reset()
+		} else {
+			go stream.FullClose()
+		}
+	}()
+
+	w, r := protobuf.NewWriterAndReader(stream)
+	if err := w.WriteMsgWithContext(ctx, f); err != nil {
+		return nil, fmt.Errorf("write fetch: %w", err)
+	}
+	for {
+		var batch pb.Envelopes
+		if err := r.ReadMsgWithContext(ctx, &batch); err != nil {
+			return nil, fmt.Errorf("read envelopes: %w", err)
+		}
+		if batch.Err != "" {
+			return nil, newRemoteError(batch.Err)
+		}
+		if len(batch.Envelopes) == 0 {
+			return envelopes, nil
+		}
+		envelopes = append(envelopes, batch.Envelopes...)
+		if len(envelopes) > MaxFetchEnvelopes {
+			return nil, errors.New("too many envelopes")
+		}
+	}
+}
+
+// fetchHandler writes the envelopes in batches terminated by an empty one.
+func (s *Service) fetchHandler(ctx context.Context, p p2p.Peer, stream p2p.Stream) (err error) {
+	w, r := protobuf.NewWriterAndReader(stream)
+	defer func() {
+		if err != nil {
+			_ = stream.Reset()
+		} else {
+			_ = stream.FullClose()
+		}
+	}()
+
+	var f pb.Fetch
+	if err := r.ReadMsgWithContext(ctx, &f); err != nil {
+		return fmt.Errorf("read fetch: %w", err)
+	}
+
+	envelopes, err := s.fetch(ctx, &f, p.Address)
+	if err != nil {
+		s.logger.Debugf("pss mailbox: fetch of peer %s: %v", p.Address, err)
+		if err := w.WriteMsgWithContext(ctx, &pb.Envelopes{Err: errorString(err)}); err != nil {
+			return fmt.Errorf("write envelopes: %w", err)
+		}
+		return nil
+	}
+	for len(envelopes) > 0 {
+		n := fetchBatchSize
+		if n > len(envelopes) {
+			n = len(envelopes)
+		}
+		if err := w.WriteMsgWithContext(ctx, &pb.Envelopes{Envelopes: envelopes[:n]}); err != nil {
+			return fmt.Errorf("write envelopes: %w", err)
+		}
+		envelopes = envelopes[n:]
+	}
+	if err := w.WriteMsgWithContext(ctx, &pb.Envelopes{}); err != nil {
+		return fmt.Errorf("write envelopes: %w", err)
+	}
+	return nil
+}
+
+// held returns the envelopes of the mailbox on this node which expire in
+// the future and were stored after the since time, at most
+// MaxFetchEnvelopes of the earliest ones.
+func (s *Service) held(f *pb.Fetch) ([]*pb.Envelope, error) {
+	if len(f.Target) == 0 || len(f.Target) > MaxTargetLength {
+		return nil, ErrInvalidTarget
+	}
+	now := s.timeNow().UnixNano()
+	prefix := fmt.Sprintf("%s%x_", keyPrefix, f.Target)
+
+	var envelopes []*pb.Envelope
+	if err := s.store.Iterate(prefix, func(key, value []byte) (bool, error) {
+		if !strings.HasPrefix(string(key), prefix) {
+			return true, nil
+		}
+		var r record
+		if err := json.Unmarshal(value, &r); err != nil {
+			return true, fmt.Errorf("invalid envelope %s: %w", key, err)
+		}
+		if r.Stored > f.Since && r.Expiry > now {
+			envelopes = append(envelopes, &pb.Envelope{Address: r.Address, Data: r.Data, Stored: r.Stored})
+		}
+		return false, nil
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(envelopes, func(i, j int) bool {
+		return envelopes[i].Stored < envelopes[j].Stored
+	})
+	if len(envelopes) > MaxFetchEnvelopes {
+		envelopes = envelopes[:MaxFetchEnvelopes]
+	}
+	s.metrics.Fetches.Inc()
+	return envelopes, nil
+}
+
+func (s *Service) pruneLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+		}
+		if err := s.prune(); err != nil {
+			s.logger.Debugf("pss mailbox: prune: %v", err)
+			s.logger.Error("pss mailbox: prune")
+		}
+	}
+}
+
+// prune removes the expired envelopes and counts the held ones.
+func (s *Service) prune() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timeNow().UnixNano()
+	var (
+		expired []string
+		count   int
+	)
+	if err := s.store.Iterate(keyPrefix, func(key, value []byte) (bool, error) {
+		if !strings.HasPrefix(string(key), keyPrefix) {
+			return true, nil
+		}
+		var r record
+		if err := json.Unmarshal(value, &r); err != nil || r.Expiry <= now {
+			expired = append(expired, string(key))
+			return false, nil
+		}
+		count++
+		return false, nil
+	}); err != nil {
+		return err
+	}
+	for _, key := range expired {
+		if err := s.store.Delete(key); err != nil {
+			return err
+		}
+	}
+	if len(expired) > 0 {
+		s.logger.Debugf("pss mailbox: removed %d expired envelopes", len(expired))
+	}
+	s.count = count
+	s.metrics.ExpiredEnvelopes.Add(float64(len(expired)))
+	s.metrics.HeldEnvelopes.Set(float64(count))
+	return nil
+}
+
+// ParseTargets parses the comma separated hex encoded targets.
+func ParseTargets(s string) (pss.Targets, error) {
+	var targets pss.Targets
+	for _, v := range strings.Split(s, ",") {
+		target, err := hex.DecodeString(v)
+		if err != nil || len(target) == 0 || len(target) > MaxTargetLength {
+			return nil, ErrInvalidTarget
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// Close stops the removal of the expired envelopes.
+func (s *Service) Close() error {
+	close(s.quit)
+	s.wg.Wait()
+	return nil
+}
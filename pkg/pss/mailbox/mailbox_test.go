@@ -0,0 +1,225 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mailbox_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/p2p/streamtest"
+	"github.com/ethsana/sana/pkg/postage"
+	postagemock "github.com/ethsana/sana/pkg/postage/mock"
+	"github.com/ethsana/sana/pkg/pss"
+	"github.com/ethsana/sana/pkg/pss/mailbox"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/topology"
+)
+
+var (
+	holderAddress = swarm.MustParseHexAddress("0100000000000000000000000000000000000000000000000000000000000000")
+	targets       = pss.Targets{{1}}
+	topic         = pss.NewTopic("mailbox")
+)
+
+// closestPeer returns the peer, or topology.ErrNotFound if it is skipped.
+type closestPeer swarm.Address
+
+func (c closestPeer) ClosestPeer(_ swarm.Address, _ bool, skipPeers ...swarm.Address) (swarm.Address, error) {
+	for _, p := range skipPeers {
+		if p.Equal(swarm.Address(c)) {
+			return swarm.ZeroAddress, topology.ErrNotFound
+		}
+	}
+	return swarm.Address(c), nil
+}
+
+// self is the topology of the closest node.
+type self struct{}
+
+func (self) ClosestPeer(swarm.Address, bool, ...swarm.Address) (swarm.Address, error) {
+	return swarm.ZeroAddress, topology.ErrWantSelf
+}
+
+func validStamp(chunk swarm.Chunk, _ []byte) (swarm.Chunk, error) {
+	return chunk, nil
+}
+
+func newHolder(t *testing.T, o mailbox.Options, valid func(swarm.Chunk, []byte) (swarm.Chunk, error)) *mailbox.Service {
+	t.Helper()
+
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Holder = true
+	holder, err := mailbox.New(streamtest.New(), self{}, statestore.NewStateStore(), key, valid, logging.New(ioutil.Discard, 0), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = holder.Close() })
+	return holder
+}
+
+// newClient returns the client of the holder and the public key of the
+// messages it can fetch.
+func newClient(t *testing.T, holder *mailbox.Service) (*mailbox.Service, *ecdsa.PublicKey) {
+	t.Helper()
+
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := streamtest.New(streamtest.WithProtocols(holder.Protocol()), streamtest.WithBaseAddr(swarm.MustParseHexAddress("ff")))
+	client, err := mailbox.New(recorder, closestPeer(holderAddress), statestore.NewStateStore(), key, validStamp, logging.New(ioutil.Discard, 0), mailbox.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client, &key.PublicKey
+}
+
+func TestSendFetch(t *testing.T) {
+	holder := newHolder(t, mailbox.Options{}, validStamp)
+	client, recipient := newClient(t, holder)
+	ctx := context.Background()
+
+	for _, payload := range []string{"first", "second"} {
+		if err := client.Send(ctx, topic, []byte(payload), postagemock.NewStamper(), recipient, targets, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// of another recipient
+	other, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Send(ctx, topic, []byte("other"), postagemock.NewStamper(), &other.PublicKey, targets, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, next, err := client.Fetch(ctx, topic, targets, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || !bytes.Equal(messages[0].Payload, []byte("first")) || !bytes.Equal(messages[1].Payload, []byte("second")) {
+		t.Fatalf("got messages %v, want first and second", messages)
+	}
+
+	// the messages of other topics are not unwrapped
+	if messages, _, err := client.Fetch(ctx, pss.NewTopic("other"), targets, time.Time{}); err != nil || len(messages) != 0 {
+		t.Fatalf("got messages %v, error %v, want none", messages, err)
+	}
+
+	// the next fetch continues after the last envelope, the one of the other
+	// recipient
+	if !next.After(messages[1].Stored) {
+		t.Fatalf("got next %v, want after %v", next, messages[1].Stored)
+	}
+	if messages, _, err := client.Fetch(ctx, topic, targets, next); err != nil || len(messages) != 0 {
+		t.Fatalf("got messages %v, error %v, want none", messages, err)
+	}
+}
+
+func TestHeldLocally(t *testing.T) {
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the holder is the recipient of its own mailbox
+	recipient, err := mailbox.New(streamtest.New(), self{}, statestore.NewStateStore(), key, validStamp, logging.New(ioutil.Discard, 0), mailbox.Options{Holder: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recipient.Close()
+
+	ctx := context.Background()
+	if err := recipient.Send(ctx, topic, []byte("self"), postagemock.NewStamper(), &key.PublicKey, targets, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	messages, _, err := recipient.Fetch(ctx, topic, targets, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || !bytes.Equal(messages[0].Payload, []byte("self")) {
+		t.Fatalf("got messages %v, want self", messages)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	holder := newHolder(t, mailbox.Options{MaxTTL: 2 * time.Hour, Capacity: 1}, validStamp)
+	client, recipient := newClient(t, holder)
+	ctx := context.Background()
+
+	now := time.Now()
+	holder.SetTimeNow(func() time.Time { return now })
+	// the ttl is bounded by the one of the holder
+	if err := client.Send(ctx, topic, []byte("message"), postagemock.NewStamper(), recipient, targets, 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Send(ctx, topic, []byte("full"), postagemock.NewStamper(), recipient, targets, time.Hour); !errors.Is(err, mailbox.ErrFull) {
+		t.Fatalf("got error %v, want %v", err, mailbox.ErrFull)
+	}
+
+	holder.SetTimeNow(func() time.Time { return now.Add(3 * time.Hour) })
+	if messages, _, err := client.Fetch(ctx, topic, targets, time.Time{}); err != nil || len(messages) != 0 {
+		t.Fatalf("got messages %v, error %v, want none", messages, err)
+	}
+
+	// the capacity is freed once the expired envelopes are removed
+	if err := holder.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Send(ctx, topic, []byte("message"), postagemock.NewStamper(), recipient, targets, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInvalidDeposits(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stamp", func(t *testing.T) {
+		holder := newHolder(t, mailbox.Options{}, func(swarm.Chunk, []byte) (swarm.Chunk, error) {
+			return nil, postage.ErrNotFound
+		})
+		client, recipient := newClient(t, holder)
+
+		if err := client.Send(ctx, topic, []byte("message"), postagemock.NewStamper(), recipient, targets, time.Hour); !errors.Is(err, postage.ErrNotFound) {
+			t.Fatalf("got error %v, want %v", err, postage.ErrNotFound)
+		}
+	})
+
+	t.Run("target", func(t *testing.T) {
+		holder := newHolder(t, mailbox.Options{}, validStamp)
+		client, recipient := newClient(t, holder)
+
+		if err := client.Send(ctx, topic, []byte("message"), postagemock.NewStamper(), recipient, pss.Targets{{1, 2, 3}}, time.Hour); !errors.Is(err, mailbox.ErrInvalidTarget) {
+			t.Fatalf("got error %v, want %v", err, mailbox.ErrInvalidTarget)
+		}
+	})
+
+	t.Run("no mailbox", func(t *testing.T) {
+		key, err := crypto.GenerateSecp256k1Key()
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, err := mailbox.New(streamtest.New(), closestPeer(holderAddress), statestore.NewStateStore(), key, validStamp, logging.New(ioutil.Discard, 0), mailbox.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+
+		if _, _, err := client.Fetch(ctx, topic, targets, time.Time{}); !errors.Is(err, mailbox.ErrNoMailbox) {
+			t.Fatalf("got error %v, want %v", err, mailbox.ErrNoMailbox)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mailbox
+
+import (
+	m "github.com/ethsana/sana/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	HeldDeposits      prometheus.Counter
+	ForwardedDeposits prometheus.Counter
+	Fetches           prometheus.Counter
+	HeldEnvelopes     prometheus.Gauge
+	ExpiredEnvelopes  prometheus.Counter
+}
+
+func newMetrics() metrics {
+	subsystem := "pss_mailbox"
+
+	return metrics{
+		HeldDeposits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "held_deposits",
+			Help:      "Total envelopes deposited in the mailboxes held by the node.",
+		}),
+		ForwardedDeposits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "forwarded_deposits",
+			Help:      "Total envelopes deposited in the mailboxes of peers.",
+		}),
+		Fetches: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "fetches",
+			Help:      "Total fetches from the mailboxes held by the node.",
+		}),
+		HeldEnvelopes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "held_envelopes",
+			Help:      "Number of the envelopes held by the node.",
+		}),
+		ExpiredEnvelopes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "expired_envelopes",
+			Help:      "Total held envelopes removed after their time to live.",
+		}),
+	}
+}
+
+func (s *Service) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(s.metrics)
+}
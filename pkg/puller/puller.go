@@ -28,6 +28,17 @@ var (
 
 type Options struct {
 	Bins uint8
+	// Wait delays the syncing after the warmup until it is closed, such as
+	// until the snapshot of the neighborhood is imported.
+	Wait <-chan struct{}
+}
+
+// Status is the status of the pull syncing.
+type Status struct {
+	// Started reports whether the syncing started after the warmup.
+	Started bool
+	// Peers is the number of the peers which the node syncs with.
+	Peers int
 }
 
 type Puller struct {
@@ -47,7 +58,9 @@ type Puller struct {
 	quit chan struct{}
 	wg   sync.WaitGroup
 
-	bins uint8 // how many bins do we support
+	bins    uint8 // how many bins do we support
+	wait    <-chan struct{}
+	started bool // guarded by syncPeersMtx
 }
 
 func New(stateStore storage.StateStorer, topology topology.Driver, pullSync pullsync.Interface, logger logging.Logger, o Options, warmupTime time.Duration) *Puller {
@@ -71,6 +84,7 @@ func New(stateStore storage.StateStorer, topology topology.Driver, pullSync pull
 		wg:        sync.WaitGroup{},
 
 		bins: bins,
+		wait: o.Wait,
 	}
 
 	for i := uint8(0); i < bins; i++ {
@@ -103,6 +117,17 @@ func (p *Puller) manage(warmupTime time.Duration) {
 	case <-p.quit:
 		return
 	}
+	if p.wait != nil {
+		select {
+		case <-p.wait:
+		case <-p.quit:
+			return
+		}
+	}
+
+	p.syncPeersMtx.Lock()
+	p.started = true
+	p.syncPeersMtx.Unlock()
 
 	p.logger.Info("puller: warmup period complete, worker starting.")
 
@@ -429,6 +454,23 @@ func (p *Puller) liveSyncWorker(ctx context.Context, peer swarm.Address, bin uin
 	}
 }
 
+// Status returns the status of the pull syncing.
+func (p *Puller) Status() Status {
+	p.syncPeersMtx.Lock()
+	defer p.syncPeersMtx.Unlock()
+
+	peers := make(map[string]struct{})
+	for _, bin := range p.syncPeers {
+		for peer := range bin {
+			peers[peer] = struct{}{}
+		}
+	}
+	return Status{
+		Started: p.started,
+		Peers:   len(peers),
+	}
+}
+
 func (p *Puller) Close() error {
 	p.logger.Info("puller shutting down")
 	close(p.quit)
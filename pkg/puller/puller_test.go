@@ -32,6 +32,45 @@ var (
 	reply = mockps.NewReply // alias to make code more readable
 )
 
+// test that the syncing starts once the wait channel is closed
+func TestWait(t *testing.T) {
+	var (
+		addr        = test.RandomAddress()
+		cursors     = []uint64{1000, 1000, 1000}
+		liveReplies = []uint64{1001}
+		wait        = make(chan struct{})
+	)
+
+	puller, _, kad, pullsync := newPuller(opts{
+		kad: []mockk.Option{
+			mockk.WithEachPeerRevCalls(
+				mockk.AddrTuple{Addr: addr, PO: 1},
+			), mockk.WithDepth(1),
+		},
+		pullSync: []mockps.Option{mockps.WithCursors(cursors), mockps.WithLiveSyncReplies(liveReplies...)},
+		bins:     3,
+		wait:     wait,
+	})
+	defer puller.Close()
+	defer pullsync.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	kad.Trigger()
+
+	waitCursorsCalled(t, pullsync, addr, true)
+	if s := puller.Status(); s.Started || s.Peers != 0 {
+		t.Fatalf("got status %+v before the wait", s)
+	}
+
+	close(wait)
+
+	waitCursorsCalled(t, pullsync, addr, false)
+	waitSyncCalled(t, pullsync, addr, false)
+	if s := puller.Status(); !s.Started || s.Peers != 1 {
+		t.Fatalf("got status %+v, want started with 1 peer", s)
+	}
+}
+
 // test that adding one peer starts syncing
 func TestOneSync(t *testing.T) {
 	var (
@@ -586,6 +625,7 @@ type opts struct {
 	pullSync []mockps.Option
 	kad      []mockk.Option
 	bins     uint8
+	wait     chan struct{}
 }
 
 func newPuller(ops opts) (*puller.Puller, storage.StateStorer, *mockk.Mock, *mockps.PullSyncMock) {
@@ -596,6 +636,7 @@ func newPuller(ops opts) (*puller.Puller, storage.StateStorer, *mockk.Mock, *moc
 
 	o := puller.Options{
 		Bins: ops.bins,
+		Wait: ops.wait,
 	}
 	return puller.New(s, kad, ps, logger, o, 0), s, kad, ps
 }
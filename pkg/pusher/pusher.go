@@ -25,7 +25,7 @@ import (
 	"github.com/ethsana/sana/pkg/tags"
 	"github.com/ethsana/sana/pkg/topology"
 	"github.com/ethsana/sana/pkg/tracing"
-	"github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/sirupsen/logrus"
 )
@@ -84,7 +84,7 @@ func (s *Service) chunksWorker(warmupTime time.Duration) {
 		sem           = make(chan struct{}, concurrentJobs)
 		inflight      = make(map[string]struct{})
 		mtx           sync.Mutex
-		span          opentracing.Span
+		span          trace.Span
 		logger        *logrus.Entry
 		retryCounter  = make(map[string]int)
 	)
@@ -139,7 +139,7 @@ LOOP:
 					unsubscribe()
 				}
 				if span != nil {
-					span.Finish()
+					span.End()
 				}
 
 				return
@@ -273,7 +273,7 @@ LOOP:
 			s.metrics.MarkAndSweepTime.Observe(time.Since(startTime).Seconds())
 
 			if span != nil {
-				span.Finish()
+				span.End()
 				span = nil
 			}
 
@@ -282,7 +282,7 @@ LOOP:
 				unsubscribe()
 			}
 			if span != nil {
-				span.Finish()
+				span.End()
 			}
 
 			break LOOP
@@ -22,13 +22,16 @@ import (
 	"github.com/ethsana/sana/pkg/postage"
 	"github.com/ethsana/sana/pkg/pricer"
 	"github.com/ethsana/sana/pkg/pushsync/pb"
+	"github.com/ethsana/sana/pkg/reputation"
 	"github.com/ethsana/sana/pkg/soc"
 	"github.com/ethsana/sana/pkg/storage"
 	"github.com/ethsana/sana/pkg/swarm"
 	"github.com/ethsana/sana/pkg/tags"
 	"github.com/ethsana/sana/pkg/topology"
 	"github.com/ethsana/sana/pkg/tracing"
-	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -77,6 +80,7 @@ type PushSync struct {
 	isFullNode     bool
 	warmupPeriod   time.Time
 	skipList       *peerSkipList
+	reputation     reputation.Recorder
 }
 
 var defaultTTL = 20 * time.Second                     // request time to live
@@ -217,8 +221,11 @@ func (ps *PushSync) handler(ctx context.Context, p p2p.Peer, stream p2p.Stream)
 		}
 	}
 
-	span, _, ctx := ps.tracer.StartSpanFromContext(ctx, "pushsync-handler", ps.logger, opentracing.Tag{Key: "address", Value: chunkAddress.String()})
-	defer span.Finish()
+	span, _, ctx := ps.tracer.StartSpanFromContext(ctx, "pushsync-handler", ps.logger, trace.WithAttributes(
+		attribute.String("address", chunkAddress.String()),
+		attribute.String("peer", p.Address.String()),
+	))
+	defer func() { tracing.EndSpan(span, err) }()
 
 	receipt, err := ps.pushToClosest(ctx, chunk, false, p.Address)
 	if err != nil {
@@ -253,7 +260,7 @@ func (ps *PushSync) handler(ctx context.Context, p p2p.Peer, stream p2p.Stream)
 				return fmt.Errorf("send receipt to peer %s: %w", p.Address.String(), err)
 			}
 
-			return debit.Apply()
+			return ps.applyDebit(ctx, debit, p.Address, price)
 		}
 		return fmt.Errorf("handler: push to closest: %w", err)
 
@@ -270,7 +277,15 @@ func (ps *PushSync) handler(ctx context.Context, p p2p.Peer, stream p2p.Stream)
 		return fmt.Errorf("send receipt to peer %s: %w", p.Address.String(), err)
 	}
 
-	return debit.Apply()
+	return ps.applyDebit(ctx, debit, p.Address, price)
+}
+
+// applyDebit applies the debit of the peer in a span of the context.
+func (ps *PushSync) applyDebit(ctx context.Context, debit accounting.Action, peer swarm.Address, price uint64) error {
+	span, _, _ := ps.tracer.StartSpanFromContext(ctx, "accounting-debit", nil, tracing.AccountingAttributes(peer, price))
+	err := debit.Apply()
+	tracing.EndSpan(span, err)
+	return err
 }
 
 // PushChunkToClosest sends chunk to the closest peer by opening a stream. It then waits for
@@ -294,8 +309,11 @@ type pushResult struct {
 }
 
 func (ps *PushSync) pushToClosest(ctx context.Context, ch swarm.Chunk, retryAllowed bool, origin swarm.Address) (*pb.Receipt, error) {
-	span, logger, ctx := ps.tracer.StartSpanFromContext(ctx, "push-closest", ps.logger, opentracing.Tag{Key: "address", Value: ch.Address().String()})
-	defer span.Finish()
+	span, logger, ctx := ps.tracer.StartSpanFromContext(ctx, "push-closest", ps.logger, trace.WithAttributes(
+		attribute.String("address", ch.Address().String()),
+		attribute.Bool("originated", retryAllowed),
+	))
+	defer span.End()
 	defer ps.skipList.PruneExpired()
 
 	var (
@@ -366,7 +384,13 @@ func (ps *PushSync) pushToClosest(ctx context.Context, ch swarm.Chunk, retryAllo
 			ctxd, canceld := context.WithTimeout(ctx, defaultTTL)
 			defer canceld()
 
+			span, _, ctxd := ps.tracer.StartSpanFromContext(ctxd, "push-peer", nil, trace.WithAttributes(
+				attribute.String("address", ch.Address().String()),
+				attribute.String("peer", peer.String()),
+			))
 			r, attempted, err := ps.pushPeer(ctxd, peer, ch, retryAllowed)
+			span.SetAttributes(attribute.Bool("attempted", attempted))
+			tracing.EndSpan(span, err)
 			// attempted is true if we get past accounting and actually attempt
 			// to send the request to the peer. If we dont get past accounting, we
 			// should not count the retry and try with a different peer again
@@ -374,7 +398,10 @@ func (ps *PushSync) pushToClosest(ctx context.Context, ch swarm.Chunk, retryAllo
 				allowedRetries--
 			}
 			if err != nil {
-				logger.Debugf("could not push to peer %s: %v", peer, err)
+				logger.WithFields(logrus.Fields{
+					logging.FieldChunk: ch.Address().String(),
+					logging.FieldPeer:  peer.String(),
+				}).Debugf("could not push to peer %s: %v", peer, err)
 				resultC <- &pushResult{err: err, attempted: attempted}
 				return
 			}
@@ -450,18 +477,30 @@ func (ps *PushSync) pushPeer(ctx context.Context, peer swarm.Address, ch swarm.C
 		}
 	}
 
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent("chunk sent")
+
 	var receipt pb.Receipt
 	if err := r.ReadMsgWithContext(ctx, &receipt); err != nil {
 		_ = streamer.Reset()
+		// the pushes canceled after a receipt from another peer are not
+		// failures of the peer
+		if !errors.Is(ctx.Err(), context.Canceled) {
+			ps.recordReceiptFailure(peer)
+		}
 		return nil, true, fmt.Errorf("chunk %s receive receipt from peer %s: %w", ch.Address(), peer, err)
 	}
 
 	if !ch.Address().Equal(swarm.NewAddress(receipt.Address)) {
 		// if the receipt is invalid, try to push to the next peer
+		ps.recordReceiptFailure(peer)
 		return nil, true, fmt.Errorf("invalid receipt. chunk %s, peer %s", ch.Address(), peer)
 	}
+	span.AddEvent("receipt received")
 
+	creditSpan, _, _ := ps.tracer.StartSpanFromContext(ctx, "accounting-credit", nil, tracing.AccountingAttributes(peer, receiptPrice))
 	err = ps.accounting.Credit(peer, receiptPrice, originated)
+	tracing.EndSpan(creditSpan, err)
 	if err != nil {
 		return nil, true, err
 	}
@@ -469,6 +508,17 @@ func (ps *PushSync) pushPeer(ctx context.Context, peer swarm.Address, ch swarm.C
 	return &receipt, true, nil
 }
 
+// SetReputation sets the recorder of the failed receipts of the peers.
+func (ps *PushSync) SetReputation(r reputation.Recorder) {
+	ps.reputation = r
+}
+
+func (ps *PushSync) recordReceiptFailure(peer swarm.Address) {
+	if ps.reputation != nil {
+		ps.reputation.RecordFailure(peer, reputation.FailureReceipt)
+	}
+}
+
 // pushToNeighbour handles in-neighborhood replication for a single peer.
 func (ps *PushSync) pushToNeighbour(peer swarm.Address, ch swarm.Chunk, origin bool) {
 	var err error
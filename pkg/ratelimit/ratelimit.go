@@ -15,18 +15,20 @@ import (
 )
 
 type Limiter struct {
-	mtx     sync.Mutex
-	limiter map[string]*rate.Limiter
-	rate    rate.Limit
-	burst   int
+	mtx      sync.Mutex
+	limiter  map[string]*rate.Limiter
+	lastSeen map[string]time.Time
+	rate     rate.Limit
+	burst    int
 }
 
 // New returns a new Limiter object with refresh rate and burst amount
 func New(r time.Duration, burst int) *Limiter {
 	return &Limiter{
-		limiter: make(map[string]*rate.Limiter),
-		rate:    rate.Every(r),
-		burst:   burst,
+		limiter:  make(map[string]*rate.Limiter),
+		lastSeen: make(map[string]time.Time),
+		rate:     rate.Every(r),
+		burst:    burst,
 	}
 }
 
@@ -42,7 +44,9 @@ func (l *Limiter) Allow(key string, count int) bool {
 		l.limiter[key] = limiter
 	}
 
-	return limiter.AllowN(time.Now(), count)
+	now := time.Now()
+	l.lastSeen[key] = now
+	return limiter.AllowN(now, count)
 }
 
 // Clear deletes the limiter that belongs to 'key'
@@ -52,4 +56,22 @@ func (l *Limiter) Clear(key string) {
 	defer l.mtx.Unlock()
 
 	delete(l.limiter, key)
+	delete(l.lastSeen, key)
+}
+
+// ClearIdle deletes the limiters that were not used for the idle duration.
+// Clearing the limiters which are idle for at least the time it takes to
+// refill the burst amount does not change the limits.
+func (l *Limiter) ClearIdle(idle time.Duration) {
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := time.Now()
+	for key, seen := range l.lastSeen {
+		if now.Sub(seen) >= idle {
+			delete(l.limiter, key)
+			delete(l.lastSeen, key)
+		}
+	}
 }
@@ -40,3 +40,35 @@ func TestRateLimit(t *testing.T) {
 		t.Fatal("want allowed")
 	}
 }
+
+func TestClearIdle(t *testing.T) {
+
+	var (
+		key1  = "test1"
+		key2  = "test2"
+		rate  = time.Hour
+		burst = 1
+	)
+
+	limiter := ratelimit.New(rate, burst)
+
+	if !limiter.Allow(key1, burst) {
+		t.Fatal("want allowed")
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if !limiter.Allow(key2, burst) {
+		t.Fatal("want allowed")
+	}
+
+	limiter.ClearIdle(100 * time.Millisecond)
+
+	if !limiter.Allow(key1, burst) {
+		t.Fatal("want allowed after idle limiter is cleared")
+	}
+
+	if limiter.Allow(key2, burst) {
+		t.Fatal("want not allowed")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package reload applies configuration changes to a running node. The
+// settings are loaded again on every reload and the options which changed are
+// passed to the registered handlers. Changed options without a handler
+// require a restart of the node.
+package reload
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethsana/sana/pkg/logging"
+)
+
+// Interface reloads the configuration.
+type Interface interface {
+	Reload() (*Result, error)
+}
+
+// Result reports the changed options of a reload.
+type Result struct {
+	// Applied are the options which were applied to the running node.
+	Applied []string `json:"applied"`
+	// Restart are the options which require a restart to take effect.
+	Restart []string `json:"restart"`
+	// Failed maps the options which could not be applied to the errors.
+	Failed map[string]string `json:"failed"`
+}
+
+type handler struct {
+	options []string
+	apply   func() error
+}
+
+// Reloader keeps the effective settings of the node and applies the changes
+// to them.
+type Reloader struct {
+	load   func() (map[string]interface{}, error)
+	logger logging.Logger
+
+	mu       sync.Mutex
+	settings map[string]string
+	handlers map[string]*handler
+}
+
+var _ Interface = (*Reloader)(nil)
+
+// New returns a new Reloader with the settings returned by load as the
+// effective ones.
+func New(load func() (map[string]interface{}, error), logger logging.Logger) (*Reloader, error) {
+	settings, err := load()
+	if err != nil {
+		return nil, err
+	}
+	return &Reloader{
+		load:     load,
+		logger:   logger,
+		settings: format(settings),
+		handlers: make(map[string]*handler),
+	}, nil
+}
+
+// Handle registers the function which applies the options. It is called once
+// for every reload which changes any of the options, after the new settings
+// are loaded.
+func (r *Reloader) Handle(apply func() error, options ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h := &handler{options: options, apply: apply}
+	for _, o := range options {
+		r.handlers[o] = h
+	}
+}
+
+// Reload loads the settings and applies the changed options. Only the options
+// which are applied become effective, so the others are reported again by the
+// following reloads until the node is restarted.
+func (r *Reloader) Reload() (*Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	loaded, err := r.load()
+	if err != nil {
+		return nil, fmt.Errorf("load settings: %w", err)
+	}
+	settings := format(loaded)
+
+	var changed []string
+	for o, v := range settings {
+		if old, ok := r.settings[o]; !ok || old != v {
+			changed = append(changed, o)
+		}
+	}
+	for o := range r.settings {
+		if _, ok := settings[o]; !ok {
+			changed = append(changed, o)
+		}
+	}
+	sort.Strings(changed)
+
+	result := &Result{
+		Applied: make([]string, 0),
+		Restart: make([]string, 0),
+		Failed:  make(map[string]string),
+	}
+	applied := make(map[*handler]error)
+	for _, o := range changed {
+		h, ok := r.handlers[o]
+		if !ok {
+			result.Restart = append(result.Restart, o)
+			continue
+		}
+		err, ok := applied[h]
+		if !ok {
+			err = h.apply()
+			applied[h] = err
+		}
+		if err != nil {
+			result.Failed[o] = err.Error()
+			continue
+		}
+		result.Applied = append(result.Applied, o)
+		if v, ok := settings[o]; ok {
+			r.settings[o] = v
+		} else {
+			delete(r.settings, o)
+		}
+	}
+
+	for _, o := range result.Applied {
+		r.logger.Infof("reload: applied option %s", o)
+	}
+	for _, o := range result.Restart {
+		r.logger.Warningf("reload: option %s changed, restart required", o)
+	}
+	for o, err := range result.Failed {
+		r.logger.Errorf("reload: unable to apply option %s: %s", o, err)
+	}
+	return result, nil
+}
+
+// format returns the settings in a comparable form, regardless of the types
+// in which the values of different sources are decoded.
+func format(settings map[string]interface{}) map[string]string {
+	f := make(map[string]string, len(settings))
+	for o, v := range settings {
+		f[o] = fmt.Sprint(v)
+	}
+	return f
+}
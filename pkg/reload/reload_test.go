@@ -0,0 +1,97 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reload_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/reload"
+)
+
+func TestReload(t *testing.T) {
+	settings := map[string]interface{}{
+		"verbosity":         "info",
+		"cors":              []string{"a"},
+		"payment-threshold": "100",
+		"payment-tolerance": "10",
+		"data-dir":          "/data",
+	}
+	load := func() (map[string]interface{}, error) {
+		s := make(map[string]interface{}, len(settings))
+		for k, v := range settings {
+			s[k] = v
+		}
+		return s, nil
+	}
+	r, err := reload.New(load, logging.New(ioutil.Discard, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := make(map[string]int)
+	var paymentErr error
+	r.Handle(func() error { calls["verbosity"]++; return nil }, "verbosity")
+	r.Handle(func() error { calls["cors"]++; return nil }, "cors")
+	r.Handle(func() error { calls["payment"]++; return paymentErr }, "payment-threshold", "payment-tolerance")
+
+	check := func(t *testing.T, want *reload.Result) {
+		t.Helper()
+		got, err := r.Reload()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got result %+v, want %+v", got, want)
+		}
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		check(t, &reload.Result{Applied: []string{}, Restart: []string{}, Failed: map[string]string{}})
+		if len(calls) != 0 {
+			t.Fatalf("got calls %v", calls)
+		}
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		// values of other types from another source are equal
+		settings["cors"] = []interface{}{"a"}
+		settings["verbosity"] = "debug"
+		settings["payment-threshold"] = "200"
+		settings["payment-tolerance"] = "20"
+		settings["data-dir"] = "/other"
+		check(t, &reload.Result{
+			Applied: []string{"payment-threshold", "payment-tolerance", "verbosity"},
+			Restart: []string{"data-dir"},
+			Failed:  map[string]string{},
+		})
+		if !reflect.DeepEqual(calls, map[string]int{"verbosity": 1, "payment": 1}) {
+			t.Fatalf("got calls %v", calls)
+		}
+	})
+
+	t.Run("restart still required", func(t *testing.T) {
+		check(t, &reload.Result{Applied: []string{}, Restart: []string{"data-dir"}, Failed: map[string]string{}})
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		paymentErr = errors.New("invalid threshold")
+		settings["payment-threshold"] = "1"
+		want := &reload.Result{
+			Applied: []string{},
+			Restart: []string{"data-dir"},
+			Failed:  map[string]string{"payment-threshold": "invalid threshold"},
+		}
+		check(t, want)
+		// the failed option is not effective and is applied again
+		check(t, want)
+		if calls["payment"] != 3 {
+			t.Fatalf("got %d payment calls, want 3", calls["payment"])
+		}
+	})
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package repair
+
+import (
+	m "github.com/ethsana/sana/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	Rounds         prometheus.Counter
+	ProbedChunks   prometheus.Counter
+	MissingChunks  prometheus.Counter
+	RepairedChunks prometheus.Counter
+	FailedChunks   prometheus.Counter
+}
+
+func newMetrics() metrics {
+	subsystem := "repair"
+
+	return metrics{
+		Rounds: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "rounds",
+			Help:      "Total rounds of the sampled repairs.",
+		}),
+		ProbedChunks: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "probed_chunks",
+			Help:      "Total chunks probed in the network.",
+		}),
+		MissingChunks: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "missing_chunks",
+			Help:      "Total probed chunks which were not retrieved from the network.",
+		}),
+		RepairedChunks: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "repaired_chunks",
+			Help:      "Total missing chunks which were pushed again.",
+		}),
+		FailedChunks: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "failed_chunks",
+			Help:      "Total missing chunks which could not be pushed.",
+		}),
+	}
+}
+
+func (s *Service) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(s.metrics)
+}
@@ -0,0 +1,313 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package repair recovers the content which is stored by the node but lost in
+// the network. It periodically samples the chunks of the pinned content,
+// probes the network for them and pushes the local copies of the missing
+// chunks to their neighbourhoods again, optionally with fresh postage stamps.
+package repair
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/pushsync"
+	"github.com/ethsana/sana/pkg/retrieval"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/topology"
+	"github.com/ethsana/sana/pkg/traversal"
+)
+
+const (
+	// DefaultBudget is the default number of chunks probed in a round.
+	DefaultBudget = 1000
+	// parallelProbes is the number of chunks probed concurrently.
+	parallelProbes = 5
+)
+
+// Pins lists the pinned content.
+type Pins interface {
+	Pins() ([]swarm.Address, error)
+}
+
+// Report is the outcome of the repair of the content.
+type Report struct {
+	// Chunks is the number of probed chunks.
+	Chunks int `json:"chunks"`
+	// Missing is the number of chunks which were not retrieved from the
+	// network.
+	Missing int `json:"missing"`
+	// Repaired is the number of missing chunks which were pushed again.
+	Repaired int `json:"repaired"`
+	// Failed is the number of missing chunks which could not be pushed.
+	Failed int `json:"failed"`
+}
+
+func (r *Report) add(o Report) {
+	r.Chunks += o.Chunks
+	r.Missing += o.Missing
+	r.Repaired += o.Repaired
+	r.Failed += o.Failed
+}
+
+// Options configure the Service.
+type Options struct {
+	// Interval is the time between the rounds of the sampled repairs. The
+	// content is repaired only on demand if it is zero.
+	Interval time.Duration
+	// Budget is the maximal number of chunks probed in a round,
+	// DefaultBudget if it is zero.
+	Budget int
+	// PostageBatch, if set, stamps the pushed chunks with fresh stamps.
+	// Otherwise the chunks are pushed with their stored stamps, which may
+	// have expired.
+	PostageBatch []byte
+}
+
+// Service repairs the content in rounds and on demand.
+type Service struct {
+	pins      Pins
+	getter    storage.Getter
+	traverser traversal.Traverser
+	retrieval retrieval.Interface
+	push      pushsync.PushSyncer
+	stamper   func() (postage.Stamper, error)
+	logger    logging.Logger
+	metrics   metrics
+	interval  time.Duration
+	budget    int
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New returns a new Service. The getter and the traverser have to access
+// only the local store, so that probing the network is left to the
+// retrieval.
+func New(pins Pins, getter storage.Getter, traverser traversal.Traverser, r retrieval.Interface, push pushsync.PushSyncer, post postage.Service, signer crypto.Signer, logger logging.Logger, o Options) *Service {
+	s := &Service{
+		pins:      pins,
+		getter:    getter,
+		traverser: traverser,
+		retrieval: r,
+		push:      push,
+		logger:    logger,
+		metrics:   newMetrics(),
+		interval:  o.Interval,
+		budget:    o.Budget,
+		quit:      make(chan struct{}),
+	}
+	if s.budget <= 0 {
+		s.budget = DefaultBudget
+	}
+	if o.PostageBatch != nil {
+		batch := o.PostageBatch
+		s.stamper = func() (postage.Stamper, error) {
+			issuer, err := post.GetStampIssuer(batch)
+			if err != nil {
+				return nil, fmt.Errorf("stamp issuer: %w", err)
+			}
+			return postage.NewStamper(issuer, signer), nil
+		}
+	}
+	return s
+}
+
+// Start begins the rounds of the sampled repairs if the interval is set.
+func (s *Service) Start() {
+	if s.interval <= 0 {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			<-s.quit
+			cancel()
+		}()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.quit:
+				return
+			case <-ticker.C:
+			}
+			r, err := s.Round(ctx)
+			if err != nil {
+				s.logger.Debugf("repair: round: %v", err)
+				s.logger.Error("repair: unable to repair the pinned content")
+				continue
+			}
+			if r.Missing > 0 {
+				s.logger.Infof("repair: %d of %d sampled chunks missing, %d repaired", r.Missing, r.Chunks, r.Repaired)
+			}
+		}
+	}()
+}
+
+// Round probes a random sample of the chunks of the pinned content, up to
+// the budget, and repairs the missing ones.
+func (s *Service) Round(ctx context.Context) (*Report, error) {
+	s.metrics.Rounds.Inc()
+
+	roots, err := s.pins.Pins()
+	if err != nil {
+		return nil, fmt.Errorf("pins: %w", err)
+	}
+	rand.Shuffle(len(roots), func(i, j int) { roots[i], roots[j] = roots[j], roots[i] })
+
+	report := new(Report)
+	for _, root := range roots {
+		remaining := s.budget - report.Chunks
+		if remaining <= 0 {
+			break
+		}
+		addrs, err := s.chunks(ctx, root)
+		if err != nil {
+			// the content may be unpinned in the meantime
+			s.logger.Debugf("repair: traverse %s: %v", root, err)
+			continue
+		}
+		if len(addrs) > remaining {
+			rand.Shuffle(len(addrs), func(i, j int) { addrs[i], addrs[j] = addrs[j], addrs[i] })
+			addrs = addrs[:remaining]
+		}
+		r, err := s.repair(ctx, addrs)
+		report.add(r)
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// Repair probes all chunks of the content with the root and repairs the
+// missing ones. The content has to be stored locally.
+func (s *Service) Repair(ctx context.Context, root swarm.Address) (*Report, error) {
+	addrs, err := s.chunks(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.repair(ctx, addrs)
+	return &r, err
+}
+
+// chunks returns the addresses of the chunks of the content with the root.
+func (s *Service) chunks(ctx context.Context, root swarm.Address) ([]swarm.Address, error) {
+	var addrs []swarm.Address
+	seen := make(map[string]struct{})
+	err := s.traverser.Traverse(ctx, root, func(addr swarm.Address) error {
+		if _, ok := seen[addr.ByteString()]; ok {
+			return nil
+		}
+		seen[addr.ByteString()] = struct{}{}
+		addrs = append(addrs, addr)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return addrs, nil
+}
+
+// repair probes the chunks and pushes the missing ones.
+func (s *Service) repair(ctx context.Context, addrs []swarm.Address) (Report, error) {
+	var stamper postage.Stamper
+	if s.stamper != nil {
+		var err error
+		if stamper, err = s.stamper(); err != nil {
+			return Report{}, err
+		}
+	}
+
+	var (
+		mu     sync.Mutex
+		report Report
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, parallelProbes)
+	)
+	for _, addr := range addrs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return report, ctx.Err()
+		}
+		wg.Add(1)
+		go func(addr swarm.Address) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			r := s.probe(ctx, addr, stamper)
+			mu.Lock()
+			report.add(r)
+			mu.Unlock()
+		}(addr)
+	}
+	wg.Wait()
+	return report, ctx.Err()
+}
+
+// probe retrieves the chunk from the network and pushes the local copy if
+// it is missing.
+func (s *Service) probe(ctx context.Context, addr swarm.Address, stamper postage.Stamper) (r Report) {
+	r.Chunks = 1
+	s.metrics.ProbedChunks.Inc()
+
+	if _, err := s.retrieval.RetrieveChunk(ctx, addr, true); err == nil || ctx.Err() != nil {
+		return r
+	}
+	r.Missing = 1
+	s.metrics.MissingChunks.Inc()
+
+	if err := s.pushChunk(ctx, addr, stamper); err != nil {
+		s.logger.Debugf("repair: push chunk %s: %v", addr, err)
+		r.Failed = 1
+		s.metrics.FailedChunks.Inc()
+		return r
+	}
+	r.Repaired = 1
+	s.metrics.RepairedChunks.Inc()
+	return r
+}
+
+func (s *Service) pushChunk(ctx context.Context, addr swarm.Address, stamper postage.Stamper) error {
+	ch, err := s.getter.Get(ctx, storage.ModeGetSync, addr)
+	if err != nil {
+		return err
+	}
+	if stamper != nil {
+		stamp, err := stamper.Stamp(addr)
+		if err != nil {
+			return fmt.Errorf("stamp: %w", err)
+		}
+		ch = ch.WithStamp(stamp)
+	}
+	if _, err := s.push.PushChunkToClosest(ctx, ch); err != nil && !errors.Is(err, topology.ErrWantSelf) {
+		return err
+	}
+	// the node is the closest one if the chunk is wanted by itself
+	return nil
+}
+
+// Close stops the rounds of the sampled repairs.
+func (s *Service) Close() error {
+	close(s.quit)
+	s.wg.Wait()
+	return nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package repair_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/file/pipeline/builder"
+	"github.com/ethsana/sana/pkg/logging"
+	pinning "github.com/ethsana/sana/pkg/pinning/mock"
+	"github.com/ethsana/sana/pkg/postage"
+	mockpost "github.com/ethsana/sana/pkg/postage/mock"
+	"github.com/ethsana/sana/pkg/pushsync"
+	pushsyncmock "github.com/ethsana/sana/pkg/pushsync/mock"
+	"github.com/ethsana/sana/pkg/repair"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/traversal"
+)
+
+// network retrieves the chunks which were not lost.
+type network struct {
+	mu   sync.Mutex
+	lost map[string]bool
+}
+
+func (n *network) RetrieveChunk(_ context.Context, addr swarm.Address, _ bool) (swarm.Chunk, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.lost[addr.ByteString()] {
+		return nil, storage.ErrNotFound
+	}
+	return swarm.NewChunk(addr, nil), nil
+}
+
+// pushed records the pushed chunks.
+type pushed struct {
+	mu     sync.Mutex
+	chunks []swarm.Chunk
+}
+
+func (p *pushed) push(_ context.Context, ch swarm.Chunk) (*pushsync.Receipt, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.chunks = append(p.chunks, ch)
+	return &pushsync.Receipt{Address: ch.Address()}, nil
+}
+
+func upload(t *testing.T, storer storage.Storer, size int) (root swarm.Address, chunks []swarm.Address) {
+	t.Helper()
+
+	ctx := context.Background()
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	pipe := builder.NewPipelineBuilder(ctx, storer, storage.ModePutUpload, false)
+	root, err := builder.FeedPipeline(ctx, pipe, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = traversal.New(storer).Traverse(ctx, root, func(addr swarm.Address) error {
+		chunks = append(chunks, addr)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root, chunks
+}
+
+func TestRepair(t *testing.T) {
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := make([]byte, 32)
+	issuer := postage.NewStampIssuer("label", "", batch, big.NewInt(1), 17, 16, 0, false)
+
+	storer := mock.NewStorer()
+	root, chunks := upload(t, storer, 3*swarm.ChunkSize)
+	lost := chunks[1]
+	n := &network{lost: map[string]bool{lost.ByteString(): true}}
+	p := new(pushed)
+
+	s := repair.New(pinning.NewServiceMock(), storer, traversal.New(storer), n, pushsyncmock.New(p.push), mockpost.New(mockpost.WithIssuer(issuer)), crypto.NewDefaultSigner(key), logging.New(ioutil.Discard, 0), repair.Options{
+		PostageBatch: batch,
+	})
+	defer s.Close()
+
+	r, err := s.Repair(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := repair.Report{Chunks: len(chunks), Missing: 1, Repaired: 1}
+	if *r != want {
+		t.Fatalf("got report %+v, want %+v", *r, want)
+	}
+	if len(p.chunks) != 1 || !p.chunks[0].Address().Equal(lost) {
+		t.Fatalf("got pushed chunks %v, want %s", p.chunks, lost)
+	}
+	if stamp := p.chunks[0].Stamp(); stamp == nil || !bytes.Equal(stamp.BatchID(), batch) {
+		t.Fatal("pushed chunk not stamped with the batch")
+	}
+}
+
+func TestRepairFailed(t *testing.T) {
+	storer := mock.NewStorer()
+	root, chunks := upload(t, storer, swarm.ChunkSize)
+	n := &network{lost: map[string]bool{chunks[0].ByteString(): true}}
+	push := pushsyncmock.New(func(context.Context, swarm.Chunk) (*pushsync.Receipt, error) {
+		return nil, errors.New("no push")
+	})
+
+	s := repair.New(pinning.NewServiceMock(), storer, traversal.New(storer), n, push, nil, nil, logging.New(ioutil.Discard, 0), repair.Options{})
+	defer s.Close()
+
+	r, err := s.Repair(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := repair.Report{Chunks: 1, Missing: 1, Failed: 1}
+	if *r != want {
+		t.Fatalf("got report %+v, want %+v", *r, want)
+	}
+}
+
+func TestRound(t *testing.T) {
+	ctx := context.Background()
+	storer := mock.NewStorer()
+	pins := pinning.NewServiceMock()
+	lost := make(map[string]bool)
+	var total int
+	for _, size := range []int{3 * swarm.ChunkSize, 5*swarm.ChunkSize + 1} {
+		root, chunks := upload(t, storer, size)
+		if err := pins.CreatePin(ctx, root, true); err != nil {
+			t.Fatal(err)
+		}
+		for _, addr := range chunks {
+			lost[addr.ByteString()] = true
+		}
+		total += len(chunks)
+	}
+	p := new(pushed)
+
+	const budget = 5
+	s := repair.New(pins, storer, traversal.New(storer), &network{lost: lost}, pushsyncmock.New(p.push), nil, nil, logging.New(ioutil.Discard, 0), repair.Options{
+		Budget: budget,
+	})
+	defer s.Close()
+
+	r, err := s.Round(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := repair.Report{Chunks: budget, Missing: budget, Repaired: budget}
+	if *r != want {
+		t.Fatalf("got report %+v, want %+v", *r, want)
+	}
+	if len(p.chunks) != budget {
+		t.Fatalf("got %d pushed chunks, want %d", len(p.chunks), budget)
+	}
+	if total <= budget {
+		t.Fatalf("content of %d chunks within the budget", total)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package report
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Deliverer sends a generated report to its destination.
+type Deliverer interface {
+	Deliver(ctx context.Context, r *Report) error
+}
+
+type webhook struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewWebhook returns a Deliverer which posts the JSON encoded reports to the
+// URL.
+func NewWebhook(url string) Deliverer {
+	return &webhook{
+		url:        url,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (w *webhook) Deliver(ctx context.Context, r *Report) error {
+	body, err := r.JSON()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: unexpected response status %s", resp.Status)
+	}
+	return nil
+}
+
+// SMTPOptions configure the delivery of reports by email.
+type SMTPOptions struct {
+	// Addr is the host:port of the mail server.
+	Addr     string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+type mailer struct {
+	o SMTPOptions
+	// sendMail is replaced in tests
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTP returns a Deliverer which sends the HTML rendered reports by email.
+// The server is authenticated with PLAIN authentication if a username is set.
+func NewSMTP(o SMTPOptions) (Deliverer, error) {
+	if _, _, err := net.SplitHostPort(o.Addr); err != nil {
+		return nil, fmt.Errorf("smtp: invalid address %q: %w", o.Addr, err)
+	}
+	if o.From == "" || len(o.To) == 0 {
+		return nil, fmt.Errorf("smtp: sender and recipients are required")
+	}
+	return &mailer{o: o, sendMail: smtp.SendMail}, nil
+}
+
+func (m *mailer) Deliver(_ context.Context, r *Report) error {
+	body, err := r.HTML()
+	if err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.o.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(m.o.To, ", "))
+	fmt.Fprintf(&msg, "Subject: Sana %s report %s\r\n", r.Period, r.To.Format("2006-01-02"))
+	fmt.Fprintf(&msg, "Date: %s\r\n", r.To.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n\r\n")
+	msg.Write(body)
+
+	var auth smtp.Auth
+	if m.o.Username != "" {
+		host, _, _ := net.SplitHostPort(m.o.Addr)
+		auth = smtp.PlainAuth("", m.o.Username, m.o.Password, host)
+	}
+	return m.sendMail(m.o.Addr, auth, m.o.From, m.o.To, msg.Bytes())
+}
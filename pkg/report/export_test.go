@@ -0,0 +1,23 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package report
+
+import (
+	"context"
+	"net/smtp"
+	"time"
+)
+
+func (s *Service) SetNow(now func() time.Time) {
+	s.now = now
+}
+
+func (s *Service) Check(ctx context.Context) {
+	s.check(ctx)
+}
+
+func SetSendMail(d Deliverer, f func(addr string, a smtp.Auth, from string, to []string, msg []byte) error) {
+	d.(*mailer).sendMail = f
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+)
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Sana {{.Period}} report</title></head>
+<body>
+<h1>Sana {{.Period}} report</h1>
+<p>{{.From.Format "2006-01-02 15:04 MST"}} &ndash; {{.To.Format "2006-01-02 15:04 MST"}}</p>
+
+<h2>Earnings</h2>
+<table>
+<tr><td>Received</td><td>{{.Earnings.Received}}</td></tr>
+<tr><td>Sent</td><td>{{.Earnings.Sent}}</td></tr>
+<tr><td>Net</td><td>{{.Earnings.Net}}</td></tr>
+</table>
+
+<h2>Bandwidth</h2>
+<table>
+<tr><td>Retrieved chunks</td><td>{{.Bandwidth.RetrievedChunks}}</td></tr>
+<tr><td>Pushed chunks</td><td>{{.Bandwidth.PushedChunks}}</td></tr>
+<tr><td>Received chunks</td><td>{{.Bandwidth.ReceivedChunks}}</td></tr>
+<tr><td>Synced chunks</td><td>{{.Bandwidth.SyncedChunks}}</td></tr>
+</table>
+
+<h2>Storage</h2>
+<table>
+<tr><td>Reserve chunks</td><td>{{.Storage.ReserveChunks}} ({{.Storage.ReserveGrowth}})</td></tr>
+<tr><td>Cache chunks</td><td>{{.Storage.CacheChunks}} ({{.Storage.CacheGrowth}})</td></tr>
+</table>
+
+<h2>Postage batches</h2>
+{{if .Batches}}<table>
+<tr><th>Batch</th><th>Label</th><th>Depth</th><th>Utilization</th></tr>
+{{range .Batches}}<tr><td>{{.BatchID}}</td><td>{{.Label}}</td><td>{{.Depth}}</td><td>{{.Utilization}}/{{.Capacity}} ({{.Percent}}%){{if .NearlyFull}} nearly full{{end}}</td></tr>
+{{end}}</table>{{else}}<p>No usable batches.</p>{{end}}
+
+<h2>Top content</h2>
+{{if .TopContent}}<table>
+<tr><th>Address</th><th>Chunks</th><th>Started</th></tr>
+{{range .TopContent}}<tr><td>{{.Address}}</td><td>{{.Chunks}}</td><td>{{.StartedAt.Format "2006-01-02 15:04 MST"}}</td></tr>
+{{end}}</table>{{else}}<p>No uploads.</p>{{end}}
+</body>
+</html>
+`))
+
+// JSON returns the report encoded as JSON.
+func (r *Report) JSON() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// HTML returns the report rendered as an HTML document.
+func (r *Report) HTML() ([]byte, error) {
+	var b bytes.Buffer
+	if err := htmlTemplate.Execute(&b, r); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
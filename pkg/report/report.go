@@ -0,0 +1,275 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package report generates periodic summaries of the node operation, such as
+// earnings, bandwidth, storage growth, postage batch health and the largest
+// uploads, and delivers them to webhooks or by email. It gives visibility to
+// operators which do not run a monitoring stack.
+package report
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethsana/sana/pkg/bigint"
+	m "github.com/ethsana/sana/pkg/metrics"
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/settlement"
+	"github.com/ethsana/sana/pkg/tags"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Period is the interval which a report summarizes.
+type Period string
+
+const (
+	Daily  Period = "daily"
+	Weekly Period = "weekly"
+)
+
+// Duration returns the length of the period.
+func (p Period) Duration() time.Duration {
+	if p == Weekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// ParsePeriod returns the period with the name.
+func ParsePeriod(s string) (Period, error) {
+	switch p := Period(s); p {
+	case Daily, Weekly:
+		return p, nil
+	}
+	return "", fmt.Errorf("report: unknown period %q", s)
+}
+
+// batchNearlyFull is the utilization in percent from which a batch is
+// reported as nearly full.
+const batchNearlyFull = 90
+
+// topContentCount is the number of uploads listed in a report.
+const topContentCount = 10
+
+// Report is a summary of the node operation in a period.
+type Report struct {
+	Period     Period        `json:"period"`
+	From       time.Time     `json:"from"`
+	To         time.Time     `json:"to"`
+	Earnings   Earnings      `json:"earnings"`
+	Bandwidth  Bandwidth     `json:"bandwidth"`
+	Storage    Storage       `json:"storage"`
+	Batches    []BatchHealth `json:"batches"`
+	TopContent []Content     `json:"topContent"`
+}
+
+// Earnings are the settlements with peers in the period.
+type Earnings struct {
+	Received *bigint.BigInt `json:"received"`
+	Sent     *bigint.BigInt `json:"sent"`
+	Net      *bigint.BigInt `json:"net"`
+}
+
+// Bandwidth is the number of chunks transferred with peers in the period.
+type Bandwidth struct {
+	RetrievedChunks uint64 `json:"retrievedChunks"`
+	PushedChunks    uint64 `json:"pushedChunks"`
+	ReceivedChunks  uint64 `json:"receivedChunks"`
+	SyncedChunks    uint64 `json:"syncedChunks"`
+}
+
+// Storage is the number of chunks stored at the end of the period and their
+// growth during the period.
+type Storage struct {
+	ReserveChunks uint64 `json:"reserveChunks"`
+	CacheChunks   uint64 `json:"cacheChunks"`
+	ReserveGrowth int64  `json:"reserveGrowth"`
+	CacheGrowth   int64  `json:"cacheGrowth"`
+}
+
+// BatchHealth is the utilization of a usable postage batch.
+type BatchHealth struct {
+	BatchID     string `json:"batchID"`
+	Label       string `json:"label"`
+	Depth       uint8  `json:"depth"`
+	Utilization uint32 `json:"utilization"`
+	Capacity    uint32 `json:"capacity"`
+	Percent     uint32 `json:"percent"`
+	NearlyFull  bool   `json:"nearlyFull"`
+}
+
+// Content is an upload started in the period.
+type Content struct {
+	Address   string    `json:"address"`
+	Chunks    int64     `json:"chunks"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// Sources are the node components from which the reports are generated. All
+// of them are optional.
+type Sources struct {
+	Settlements []settlement.Interface
+	Gatherer    prometheus.Gatherer
+	Post        postage.Service
+	Tags        *tags.Tags
+}
+
+// counters are the cumulative values from which the changes in a period are
+// calculated.
+type counters struct {
+	Received      *big.Int `json:"received"`
+	Sent          *big.Int `json:"sent"`
+	Retrieved     uint64   `json:"retrieved"`
+	Pushed        uint64   `json:"pushed"`
+	PushReceived  uint64   `json:"pushReceived"`
+	PullDelivered uint64   `json:"pullDelivered"`
+	ReserveSize   uint64   `json:"reserveSize"`
+	CacheSize     uint64   `json:"cacheSize"`
+}
+
+var metricNames = struct {
+	retrieved, pushed, pushReceived, pullDelivered, reserveSize, cacheSize string
+}{
+	retrieved:     m.Namespace + "_retrieval_total_retrieved",
+	pushed:        m.Namespace + "_pushsync_total_sent",
+	pushReceived:  m.Namespace + "_pushsync_total_received",
+	pullDelivered: m.Namespace + "_pullsync_chunks_delivered",
+	reserveSize:   m.Namespace + "_localstore_reserve_size",
+	cacheSize:     m.Namespace + "_localstore_gc_size",
+}
+
+func (s Sources) counters() (*counters, error) {
+	c := &counters{Received: big.NewInt(0), Sent: big.NewInt(0)}
+	for _, settlements := range s.Settlements {
+		received, err := settlements.SettlementsReceived()
+		if err != nil {
+			return nil, fmt.Errorf("settlements received: %w", err)
+		}
+		for _, v := range received {
+			c.Received.Add(c.Received, v)
+		}
+		sent, err := settlements.SettlementsSent()
+		if err != nil {
+			return nil, fmt.Errorf("settlements sent: %w", err)
+		}
+		for _, v := range sent {
+			c.Sent.Add(c.Sent, v)
+		}
+	}
+
+	if s.Gatherer != nil {
+		families, err := s.Gatherer.Gather()
+		if err != nil {
+			return nil, fmt.Errorf("gather metrics: %w", err)
+		}
+		values := make(map[string]uint64)
+		for _, f := range families {
+			for _, metric := range f.GetMetric() {
+				var v float64
+				switch {
+				case metric.Counter != nil:
+					v = metric.Counter.GetValue()
+				case metric.Gauge != nil:
+					v = metric.Gauge.GetValue()
+				}
+				values[f.GetName()] += uint64(v)
+			}
+		}
+		c.Retrieved = values[metricNames.retrieved]
+		c.Pushed = values[metricNames.pushed]
+		c.PushReceived = values[metricNames.pushReceived]
+		c.PullDelivered = values[metricNames.pullDelivered]
+		c.ReserveSize = values[metricNames.reserveSize]
+		c.CacheSize = values[metricNames.cacheSize]
+	}
+	return c, nil
+}
+
+// generate creates the report of the period ending now from the current
+// counters and the counters at the start of the period.
+func (s Sources) generate(ctx context.Context, period Period, from, now time.Time, prev, cur *counters) (*Report, error) {
+	received := new(big.Int).Sub(cur.Received, prev.Received)
+	sent := new(big.Int).Sub(cur.Sent, prev.Sent)
+
+	r := &Report{
+		Period: period,
+		From:   from,
+		To:     now,
+		Earnings: Earnings{
+			Received: bigint.Wrap(received),
+			Sent:     bigint.Wrap(sent),
+			Net:      bigint.Wrap(new(big.Int).Sub(received, sent)),
+		},
+		Bandwidth: Bandwidth{
+			RetrievedChunks: delta(prev.Retrieved, cur.Retrieved),
+			PushedChunks:    delta(prev.Pushed, cur.Pushed),
+			ReceivedChunks:  delta(prev.PushReceived, cur.PushReceived),
+			SyncedChunks:    delta(prev.PullDelivered, cur.PullDelivered),
+		},
+		Storage: Storage{
+			ReserveChunks: cur.ReserveSize,
+			CacheChunks:   cur.CacheSize,
+			ReserveGrowth: int64(cur.ReserveSize) - int64(prev.ReserveSize),
+			CacheGrowth:   int64(cur.CacheSize) - int64(prev.CacheSize),
+		},
+		Batches:    make([]BatchHealth, 0),
+		TopContent: make([]Content, 0),
+	}
+
+	if s.Post != nil {
+		for _, issuer := range s.Post.StampIssuers() {
+			capacity := uint32(1)
+			if issuer.Depth() > issuer.BucketDepth() {
+				capacity <<= issuer.Depth() - issuer.BucketDepth()
+			}
+			percent := issuer.Utilization() * 100 / capacity
+			r.Batches = append(r.Batches, BatchHealth{
+				BatchID:     hex.EncodeToString(issuer.ID()),
+				Label:       issuer.Label(),
+				Depth:       issuer.Depth(),
+				Utilization: issuer.Utilization(),
+				Capacity:    capacity,
+				Percent:     percent,
+				NearlyFull:  percent >= batchNearlyFull,
+			})
+		}
+	}
+
+	if s.Tags != nil {
+		all, err := s.Tags.ListAll(ctx, 0, 1000)
+		if err != nil {
+			return nil, fmt.Errorf("list tags: %w", err)
+		}
+		for _, t := range all {
+			if t.StartedAt.Before(from) || t.Address.IsZero() {
+				continue
+			}
+			r.TopContent = append(r.TopContent, Content{
+				Address:   t.Address.String(),
+				Chunks:    t.Split,
+				StartedAt: t.StartedAt,
+			})
+		}
+		sort.Slice(r.TopContent, func(i, j int) bool {
+			return r.TopContent[i].Chunks > r.TopContent[j].Chunks
+		})
+		if len(r.TopContent) > topContentCount {
+			r.TopContent = r.TopContent[:topContentCount]
+		}
+	}
+	return r, nil
+}
+
+// delta is the increase of a counter, which starts over from zero if the node
+// was restarted.
+func delta(prev, cur uint64) uint64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
@@ -0,0 +1,205 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package report_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/postage"
+	mockpost "github.com/ethsana/sana/pkg/postage/mock"
+	"github.com/ethsana/sana/pkg/report"
+	"github.com/ethsana/sana/pkg/settlement"
+	"github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/swarm/test"
+	"github.com/ethsana/sana/pkg/tags"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type settlementMock struct {
+	settlement.Interface
+	received, sent int64
+}
+
+func (s *settlementMock) SettlementsReceived() (map[string]*big.Int, error) {
+	return map[string]*big.Int{"a": big.NewInt(s.received)}, nil
+}
+
+func (s *settlementMock) SettlementsSent() (map[string]*big.Int, error) {
+	return map[string]*big.Int{"a": big.NewInt(s.sent)}, nil
+}
+
+type recorder struct {
+	mu      sync.Mutex
+	reports []*report.Report
+}
+
+func (r *recorder) Deliver(_ context.Context, rep *report.Report) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reports = append(r.reports, rep)
+	return nil
+}
+
+func TestService(t *testing.T) {
+	var (
+		logger      = logging.New(ioutil.Discard, 0)
+		store       = mock.NewStateStore()
+		settlements = &settlementMock{received: 100, sent: 40}
+		registry    = prometheus.NewRegistry()
+		retrieved   = prometheus.NewCounter(prometheus.CounterOpts{Name: "bee_retrieval_total_retrieved"})
+		reserve     = prometheus.NewGauge(prometheus.GaugeOpts{Name: "bee_localstore_reserve_size"})
+		tagService  = tags.NewTags(store, logger)
+		issuer      = postage.NewStampIssuer("label", "", make([]byte, 32), big.NewInt(1), 17, 16, 0, false)
+		rec         = &recorder{}
+		now         = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	)
+	registry.MustRegister(retrieved, reserve)
+	retrieved.Add(10)
+	reserve.Set(1000)
+
+	s := report.New(store, logger, report.Sources{
+		Settlements: []settlement.Interface{settlements},
+		Gatherer:    registry,
+		Post:        mockpost.New(mockpost.WithIssuer(issuer)),
+		Tags:        tagService,
+	}, report.Options{
+		Periods:    []report.Period{report.Daily, report.Weekly},
+		Deliverers: []report.Deliverer{rec},
+	})
+	s.SetNow(func() time.Time { return now })
+	ctx := context.Background()
+
+	// the first check starts the periods
+	s.Check(ctx)
+	if len(rec.reports) != 0 {
+		t.Fatalf("got %d reports, want none", len(rec.reports))
+	}
+
+	settlements.received, settlements.sent = 250, 50
+	retrieved.Add(5)
+	reserve.Set(1200)
+	for i, chunks := range []int64{3, 7} {
+		tag, err := tagService.Create(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tag.Address = test.RandomAddress()
+		tag.Split = chunks
+		if i == 0 {
+			tag.StartedAt = now.Add(-time.Hour)
+		} else {
+			tag.StartedAt = now.Add(time.Hour)
+		}
+	}
+
+	now = now.Add(25 * time.Hour)
+	s.Check(ctx)
+	if len(rec.reports) != 1 {
+		t.Fatalf("got %d reports, want 1", len(rec.reports))
+	}
+	r := rec.reports[0]
+	if r.Period != report.Daily || !r.To.Equal(now) || !r.From.Equal(now.Add(-25*time.Hour)) {
+		t.Fatalf("got period %s from %s to %s", r.Period, r.From, r.To)
+	}
+	if r.Earnings.Received.Int64() != 150 || r.Earnings.Sent.Int64() != 10 || r.Earnings.Net.Int64() != 140 {
+		t.Fatalf("got earnings %+v", r.Earnings)
+	}
+	if r.Bandwidth.RetrievedChunks != 5 {
+		t.Fatalf("got retrieved chunks %d, want 5", r.Bandwidth.RetrievedChunks)
+	}
+	if r.Storage.ReserveChunks != 1200 || r.Storage.ReserveGrowth != 200 {
+		t.Fatalf("got storage %+v", r.Storage)
+	}
+	if len(r.Batches) != 1 || r.Batches[0].Capacity != 2 || r.Batches[0].Label != "label" {
+		t.Fatalf("got batches %+v", r.Batches)
+	}
+	// only the upload started in the period is listed
+	if len(r.TopContent) != 1 || r.TopContent[0].Chunks != 7 {
+		t.Fatalf("got top content %+v", r.TopContent)
+	}
+
+	html, err := r.HTML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(html, []byte(r.TopContent[0].Address)) {
+		t.Fatal("top content not rendered")
+	}
+
+	// the weekly report is generated once a week
+	now = now.Add(6 * 24 * time.Hour)
+	s.Check(ctx)
+	if len(rec.reports) != 3 || rec.reports[1].Period != report.Daily || rec.reports[2].Period != report.Weekly {
+		t.Fatalf("got %d reports", len(rec.reports))
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var got report.Report
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	if err := report.NewWebhook(srv.URL).Deliver(context.Background(), &report.Report{Period: report.Weekly}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Period != report.Weekly {
+		t.Fatalf("got period %s", got.Period)
+	}
+}
+
+func TestSMTP(t *testing.T) {
+	d, err := report.NewSMTP(report.SMTPOptions{
+		Addr:     "mail.example.org:587",
+		Username: "user",
+		Password: "pass",
+		From:     "node@example.org",
+		To:       []string{"ops@example.org"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg string
+	report.SetSendMail(d, func(addr string, a smtp.Auth, from string, to []string, m []byte) error {
+		if addr != "mail.example.org:587" || a == nil || from != "node@example.org" || len(to) != 1 {
+			t.Errorf("got addr %s from %s to %v", addr, from, to)
+		}
+		msg = string(m)
+		return nil
+	})
+	r := &report.Report{
+		Period: report.Daily,
+		To:     time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		TopContent: []report.Content{
+			{Address: swarm.MustParseHexAddress("ca").String()},
+		},
+	}
+	if err := d.Deliver(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(msg, "Subject: Sana daily report 2021-06-01\r\n") || !strings.Contains(msg, "Content-Type: text/html") {
+		t.Fatalf("got message %q", msg)
+	}
+
+	if _, err := report.NewSMTP(report.SMTPOptions{Addr: "mail.example.org"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
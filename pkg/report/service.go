@@ -0,0 +1,141 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/storage"
+)
+
+const (
+	stateKeyPrefix = "report_state_"
+	checkInterval  = time.Minute
+)
+
+// periodState is the start of the current period of a schedule.
+type periodState struct {
+	Start    time.Time `json:"start"`
+	Counters *counters `json:"counters"`
+}
+
+// Options configure the Service.
+type Options struct {
+	Periods    []Period
+	Deliverers []Deliverer
+}
+
+// Service generates the reports of the configured periods and delivers
+// them. The start of the current periods is persisted, so that the schedule
+// survives restarts of the node.
+type Service struct {
+	store      storage.StateStorer
+	logger     logging.Logger
+	sources    Sources
+	periods    []Period
+	deliverers []Deliverer
+	now        func() time.Time // now is replaced in tests
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// New returns a new Service.
+func New(store storage.StateStorer, logger logging.Logger, sources Sources, o Options) *Service {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Service{
+		store:      store,
+		logger:     logger,
+		sources:    sources,
+		periods:    o.Periods,
+		deliverers: o.Deliverers,
+		now:        time.Now,
+		ctx:        ctx,
+		cancel:     cancel,
+		quit:       make(chan struct{}),
+	}
+}
+
+// Start begins the scheduling of the reports.
+func (s *Service) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			s.check(s.ctx)
+			select {
+			case <-s.quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *Service) check(ctx context.Context) {
+	for _, p := range s.periods {
+		if err := s.checkPeriod(ctx, p); err != nil {
+			s.logger.Debugf("report: %s: %v", p, err)
+			s.logger.Errorf("report: unable to generate %s report", p)
+		}
+	}
+}
+
+// checkPeriod generates and delivers the report of the period if it ended.
+// The first check only starts the period.
+func (s *Service) checkPeriod(ctx context.Context, p Period) error {
+	now := s.now()
+	key := stateKeyPrefix + string(p)
+
+	var state periodState
+	err := s.store.Get(key, &state)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("get state: %w", err)
+	}
+	found := err == nil
+	if found && now.Sub(state.Start) < p.Duration() {
+		return nil
+	}
+
+	cur, err := s.sources.counters()
+	if err != nil {
+		return err
+	}
+	if found {
+		r, err := s.sources.generate(ctx, p, state.Start, now, state.Counters, cur)
+		if err != nil {
+			return err
+		}
+		// a failed delivery is not retried, so that the destinations which
+		// received the report do not receive it again
+		for _, d := range s.deliverers {
+			if err := d.Deliver(ctx, r); err != nil {
+				s.logger.Debugf("report: deliver %s report: %v", p, err)
+				s.logger.Errorf("report: unable to deliver %s report", p)
+			}
+		}
+		s.logger.Infof("report: %s report generated", p)
+	}
+
+	return s.store.Put(key, periodState{Start: now, Counters: cur})
+}
+
+// Close stops the scheduling of the reports.
+func (s *Service) Close() error {
+	s.cancel()
+	close(s.quit)
+	s.wg.Wait()
+	return nil
+}
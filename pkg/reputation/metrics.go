@@ -0,0 +1,46 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reputation
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	m "github.com/ethsana/sana/pkg/metrics"
+)
+
+type metrics struct {
+	// all metrics fields must be exported
+	// to be able to return them by Metrics()
+	// using reflection
+
+	Failures prometheus.CounterVec
+	Bans     prometheus.Counter
+}
+
+func newMetrics() metrics {
+	subsystem := "reputation"
+
+	return metrics{
+		Failures: *prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: m.Namespace,
+				Subsystem: subsystem,
+				Name:      "failures_count",
+				Help:      "Number of recorded peer failures by kind.",
+			},
+			[]string{"failure"},
+		),
+		Bans: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "bans_count",
+			Help:      "Number of peers banned for their low score.",
+		}),
+	}
+}
+
+func (s *Service) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(s.metrics)
+}
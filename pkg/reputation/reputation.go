@@ -0,0 +1,379 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package reputation scores the peers on the latency of their retrieval
+// responses and on their protocol errors, failed pushsync receipts and
+// settlement misbehavior, and enforces the connection policy of the node:
+// the peers which score below the ban threshold are blocklisted, the
+// connections of the preferred peers are always accepted and they are never
+// banned, and the
+// connections with the underlay addresses in the blocked networks are
+// rejected.
+package reputation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/p2p"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+const (
+	keyPrefix = "reputation_peer_"
+
+	// MaxScore and MinScore bound the scores of the peers.
+	MaxScore = 100
+	MinScore = -100
+
+	// latencyTarget is the retrieval latency at and above which the
+	// responses of a peer no longer improve its score.
+	latencyTarget = time.Second
+	// latencyWeight is the weight of the last latency in the moving average
+	// of the latencies of a peer.
+	latencyWeight = 0.1
+	// flushInterval is the interval at which the changed scores are
+	// persisted.
+	flushInterval = time.Minute
+)
+
+// Failure is the misbehavior of a peer which lowers its score.
+type Failure int
+
+const (
+	// FailureProtocol is a protocol violation, such as an invalid chunk or
+	// request.
+	FailureProtocol Failure = iota
+	// FailureReceipt is a pushsync push without a valid receipt.
+	FailureReceipt
+	// FailureSettlement is a settlement misbehavior, such as a refused
+	// refreshment or an overdraft.
+	FailureSettlement
+)
+
+// failurePenalties are the score penalties of the failures.
+var failurePenalties = map[Failure]float64{
+	FailureProtocol:   2,
+	FailureReceipt:    5,
+	FailureSettlement: 10,
+}
+
+func (f Failure) String() string {
+	switch f {
+	case FailureProtocol:
+		return "protocol"
+	case FailureReceipt:
+		return "receipt"
+	case FailureSettlement:
+		return "settlement"
+	default:
+		return "unknown"
+	}
+}
+
+// Recorder records the events which change the reputation of the peers.
+type Recorder interface {
+	// RecordLatency records the latency of a response of the peer.
+	RecordLatency(peer swarm.Address, d time.Duration)
+	// RecordFailure records the misbehavior of the peer.
+	RecordFailure(peer swarm.Address, f Failure)
+}
+
+// Policy is the connection policy of the node.
+type Policy struct {
+	// BanThreshold is the negative score at or below which the peers are
+	// blocklisted for the BanDuration. Zero disables the bans.
+	BanThreshold float64
+	// BanDuration is the duration of the bans, zero bans the peers forever.
+	BanDuration time.Duration
+	// PreferredPeers are never banned and their connections are accepted
+	// even if the topology does not want more peers.
+	PreferredPeers []swarm.Address
+	// BlockedNetworks are the networks of the underlay addresses which the
+	// connections are rejected with.
+	BlockedNetworks []*net.IPNet
+}
+
+// ParseCIDRs parses the CIDR notations of the networks.
+func ParseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(strings.TrimSpace(c))
+		if err != nil {
+			return nil, fmt.Errorf("reputation: parse cidr %q: %w", c, err)
+		}
+		networks = append(networks, n)
+	}
+	return networks, nil
+}
+
+// Score is the reputation of a peer.
+type Score struct {
+	// Score is between MinScore and MaxScore, the peers start at zero.
+	Score float64 `json:"score"`
+	// Latency is the moving average of the retrieval latencies.
+	Latency            time.Duration `json:"latency"`
+	ProtocolErrors     uint64        `json:"protocolErrors"`
+	ReceiptFailures    uint64        `json:"receiptFailures"`
+	SettlementFailures uint64        `json:"settlementFailures"`
+	Bans               uint64        `json:"bans"`
+	UpdatedAt          time.Time     `json:"updatedAt"`
+}
+
+var _ Recorder = (*Service)(nil)
+
+// Service keeps the scores of the peers, persisted in the state store, and
+// enforces the Policy.
+type Service struct {
+	store   storage.StateStorer
+	logger  logging.Logger
+	metrics metrics
+
+	mu           sync.Mutex
+	policy       Policy
+	disconnecter p2p.Disconnecter
+	scores       map[string]*Score
+	dirty        map[string]struct{}
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates the Service with the scores persisted in the state store.
+func New(store storage.StateStorer, logger logging.Logger, policy Policy) (*Service, error) {
+	s := &Service{
+		store:   store,
+		logger:  logger,
+		metrics: newMetrics(),
+		policy:  policy,
+		scores:  make(map[string]*Score),
+		dirty:   make(map[string]struct{}),
+		quit:    make(chan struct{}),
+	}
+
+	err := store.Iterate(keyPrefix, func(key, value []byte) (bool, error) {
+		var sc Score
+		if err := json.Unmarshal(value, &sc); err != nil {
+			return true, fmt.Errorf("reputation: score %s: %w", key, err)
+		}
+		s.scores[strings.TrimPrefix(string(key), keyPrefix)] = &sc
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.wg.Add(1)
+	go s.flushLoop()
+	return s, nil
+}
+
+// SetDisconnecter sets the p2p service which blocklists the banned peers.
+func (s *Service) SetDisconnecter(d p2p.Disconnecter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disconnecter = d
+}
+
+// RecordLatency improves the score of the peer by up to one point for the
+// responses faster than the latency target.
+func (s *Service) RecordLatency(peer swarm.Address, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc := s.score(peer)
+	if sc.Latency == 0 {
+		sc.Latency = d
+	} else {
+		sc.Latency = time.Duration(latencyWeight*float64(d) + (1-latencyWeight)*float64(sc.Latency))
+	}
+	if d < latencyTarget {
+		sc.add(1 - float64(d)/float64(latencyTarget))
+	}
+	s.dirty[peer.String()] = struct{}{}
+}
+
+// RecordFailure lowers the score of the peer by the penalty of the failure
+// and bans the peer if the score reaches the ban threshold.
+func (s *Service) RecordFailure(peer swarm.Address, f Failure) {
+	s.metrics.Failures.WithLabelValues(f.String()).Inc()
+
+	s.mu.Lock()
+	sc := s.score(peer)
+	switch f {
+	case FailureProtocol:
+		sc.ProtocolErrors++
+	case FailureReceipt:
+		sc.ReceiptFailures++
+	case FailureSettlement:
+		sc.SettlementFailures++
+	}
+	sc.add(-failurePenalties[f])
+	s.dirty[peer.String()] = struct{}{}
+
+	ban := s.policy.BanThreshold < 0 && sc.Score <= s.policy.BanThreshold && !s.preferred(peer)
+	if ban {
+		// the peers start anew after the ban
+		sc.Score = 0
+		sc.Bans++
+	}
+	d, duration := s.disconnecter, s.policy.BanDuration
+	s.mu.Unlock()
+
+	if ban && d != nil {
+		s.metrics.Bans.Inc()
+		s.logger.Infof("reputation: banning peer %s for %s after %s failure", peer, duration, f)
+		if err := d.Blocklist(peer, duration); err != nil {
+			s.logger.Debugf("reputation: blocklist peer %s: %v", peer, err)
+			s.logger.Errorf("reputation: unable to ban peer %s", peer)
+		}
+	}
+}
+
+// Score returns the score of the peer.
+func (s *Service) Score(peer swarm.Address) Score {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sc, ok := s.scores[peer.String()]; ok {
+		return *sc
+	}
+	return Score{}
+}
+
+// Scores returns the scores of all peers.
+func (s *Service) Scores() map[string]Score {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scores := make(map[string]Score, len(s.scores))
+	for k, sc := range s.scores {
+		scores[k] = *sc
+	}
+	return scores
+}
+
+// Policy returns the connection policy.
+func (s *Service) Policy() Policy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.policy
+}
+
+// SetPolicy replaces the connection policy. The connected peers in the
+// blocked networks are not disconnected.
+func (s *Service) SetPolicy(p Policy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = p
+}
+
+// Preferred reports whether the peer is one of the preferred peers.
+func (s *Service) Preferred(peer swarm.Address) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.preferred(peer)
+}
+
+func (s *Service) preferred(peer swarm.Address) bool {
+	for _, p := range s.policy.PreferredPeers {
+		if p.Equal(peer) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowAddress reports whether the connections with the underlay address are
+// allowed, which they are unless its ip is in one of the blocked networks.
+func (s *Service) AllowAddress(addr ma.Multiaddr) bool {
+	ip, err := manet.ToIP(addr)
+	if err != nil {
+		// the addresses without an ip, such as dns ones, are not blocked
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range s.policy.BlockedNetworks {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// score returns the score of the peer, creating it if it does not exist.
+// This function must be called under the mu lock.
+func (s *Service) score(peer swarm.Address) *Score {
+	sc, ok := s.scores[peer.String()]
+	if !ok {
+		sc = new(Score)
+		s.scores[peer.String()] = sc
+	}
+	sc.UpdatedAt = time.Now()
+	return sc
+}
+
+func (sc *Score) add(v float64) {
+	sc.Score += v
+	if sc.Score > MaxScore {
+		sc.Score = MaxScore
+	}
+	if sc.Score < MinScore {
+		sc.Score = MinScore
+	}
+}
+
+func (s *Service) flushLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.flush(); err != nil {
+				s.logger.Debugf("reputation: persist scores: %v", err)
+				s.logger.Error("reputation: unable to persist scores")
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// flush persists the changed scores.
+func (s *Service) flush() error {
+	s.mu.Lock()
+	scores := make(map[string]Score, len(s.dirty))
+	for k := range s.dirty {
+		scores[k] = *s.scores[k]
+	}
+	s.dirty = make(map[string]struct{})
+	s.mu.Unlock()
+
+	var errs []string
+	for k, sc := range scores {
+		if err := s.store.Put(keyPrefix+k, sc); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Close persists the changed scores.
+func (s *Service) Close() error {
+	close(s.quit)
+	s.wg.Wait()
+	return s.flush()
+}
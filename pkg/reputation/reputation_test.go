@@ -0,0 +1,150 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reputation_test
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/logging"
+	p2pmock "github.com/ethsana/sana/pkg/p2p/mock"
+	"github.com/ethsana/sana/pkg/reputation"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/swarm/test"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func newService(t *testing.T, policy reputation.Policy) *reputation.Service {
+	t.Helper()
+	s, err := reputation.New(statestore.NewStateStore(), logging.New(ioutil.Discard, 0), policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return s
+}
+
+func TestScore(t *testing.T) {
+	s := newService(t, reputation.Policy{})
+	peer := test.RandomAddress()
+
+	s.RecordLatency(peer, 100*time.Millisecond)
+	s.RecordLatency(peer, 2*time.Second)
+	s.RecordFailure(peer, reputation.FailureReceipt)
+
+	sc := s.Score(peer)
+	if want := 0.9 - 5; sc.Score < want-1e-9 || sc.Score > want+1e-9 {
+		t.Fatalf("got score %v, want %v", sc.Score, want)
+	}
+	if want := 290 * time.Millisecond; sc.Latency != want {
+		t.Fatalf("got latency %v, want %v", sc.Latency, want)
+	}
+	if sc.ReceiptFailures != 1 {
+		t.Fatalf("got %d receipt failures, want 1", sc.ReceiptFailures)
+	}
+
+	for i := 0; i < 20; i++ {
+		s.RecordFailure(peer, reputation.FailureSettlement)
+	}
+	if sc := s.Score(peer); sc.Score != reputation.MinScore {
+		t.Fatalf("got score %v, want %v", sc.Score, reputation.MinScore)
+	}
+	if _, ok := s.Scores()[peer.String()]; !ok {
+		t.Fatal("peer not in the scores")
+	}
+}
+
+func TestBan(t *testing.T) {
+	var banned []swarm.Address
+	preferred := test.RandomAddress()
+	s := newService(t, reputation.Policy{
+		BanThreshold:   -10,
+		BanDuration:    time.Hour,
+		PreferredPeers: []swarm.Address{preferred},
+	})
+	s.SetDisconnecter(p2pmock.New(p2pmock.WithBlocklistFunc(func(peer swarm.Address, d time.Duration) error {
+		if d != time.Hour {
+			t.Errorf("got ban duration %v, want %v", d, time.Hour)
+		}
+		banned = append(banned, peer)
+		return nil
+	})))
+
+	peer := test.RandomAddress()
+	s.RecordFailure(peer, reputation.FailureReceipt)
+	if len(banned) != 0 {
+		t.Fatal("peer banned above the threshold")
+	}
+	s.RecordFailure(peer, reputation.FailureReceipt)
+	if len(banned) != 1 || !banned[0].Equal(peer) {
+		t.Fatalf("got banned peers %v, want %v", banned, peer)
+	}
+	if sc := s.Score(peer); sc.Score != 0 || sc.Bans != 1 {
+		t.Fatalf("got score %v and %d bans, want 0 and 1 ban", sc.Score, sc.Bans)
+	}
+
+	for i := 0; i < 5; i++ {
+		s.RecordFailure(preferred, reputation.FailureSettlement)
+	}
+	if len(banned) != 1 {
+		t.Fatal("preferred peer banned")
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	store := statestore.NewStateStore()
+	logger := logging.New(ioutil.Discard, 0)
+	peer := test.RandomAddress()
+
+	s, err := reputation.New(store, logger, reputation.Policy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.RecordFailure(peer, reputation.FailureProtocol)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = reputation.New(store, logger, reputation.Policy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if sc := s.Score(peer); sc.Score != -2 || sc.ProtocolErrors != 1 {
+		t.Fatalf("got score %v and %d protocol errors, want -2 and 1 error", sc.Score, sc.ProtocolErrors)
+	}
+}
+
+func TestAllowAddress(t *testing.T) {
+	networks, err := reputation.ParseCIDRs([]string{"10.0.0.0/8", " 2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newService(t, reputation.Policy{BlockedNetworks: networks})
+
+	for _, tc := range []struct {
+		addr  string
+		allow bool
+	}{
+		{addr: "/ip4/10.1.2.3/tcp/1634", allow: false},
+		{addr: "/ip4/192.168.0.1/tcp/1634", allow: true},
+		{addr: "/ip6/2001:db8::1/tcp/1634", allow: false},
+		{addr: "/dns4/example.com/tcp/1634", allow: true},
+	} {
+		if got := s.AllowAddress(ma.StringCast(tc.addr)); got != tc.allow {
+			t.Errorf("%s: got allowed %v, want %v", tc.addr, got, tc.allow)
+		}
+	}
+
+	if _, err := reputation.ParseCIDRs([]string{"10.0.0.0"}); err == nil {
+		t.Fatal("expected error for the invalid cidr")
+	}
+}
@@ -6,10 +6,25 @@ package retrieval
 
 import (
 	"context"
+	"time"
 
 	"github.com/ethsana/sana/pkg/p2p"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func (s *Service) Handler(ctx context.Context, p p2p.Peer, stream p2p.Stream) error {
 	return s.handler(ctx, p, stream)
 }
+
+func (s *Service) HopTimeout(peer swarm.Address) time.Duration {
+	return s.hopTimeout(peer)
+}
+
+func (s *Service) ObserveRTT(peer swarm.Address, d time.Duration) {
+	s.rtts.observe(peer, d)
+}
+
+func (s *Service) ForwardCacheHits() float64 {
+	return testutil.ToFloat64(s.metrics.ForwardCacheHits)
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package retrieval
+
+import (
+	"sync"
+
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+// forwardedSetSize is the number of the addresses of the cached forwarded
+// chunks which are remembered for the forward cache hits.
+const forwardedSetSize = 100000
+
+// forwardedSet remembers the addresses of the chunks which are cached after
+// they have been forwarded, the oldest addresses are forgotten first.
+type forwardedSet struct {
+	mu        sync.Mutex
+	addresses map[string]struct{}
+	ring      []string
+	next      int
+}
+
+func newForwardedSet(size int) *forwardedSet {
+	return &forwardedSet{
+		addresses: make(map[string]struct{}),
+		ring:      make([]string, size),
+	}
+}
+
+func (s *forwardedSet) add(addr swarm.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := addr.ByteString()
+	if _, ok := s.addresses[key]; ok {
+		return
+	}
+	delete(s.addresses, s.ring[s.next])
+	s.ring[s.next] = key
+	s.next = (s.next + 1) % len(s.ring)
+	s.addresses[key] = struct{}{}
+}
+
+func (s *forwardedSet) has(addr swarm.Address) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.addresses[addr.ByteString()]
+	return ok
+}
@@ -23,6 +23,11 @@ type metrics struct {
 	RetrieveChunkPOGainCounter prometheus.CounterVec
 	ChunkPrice                 prometheus.Summary
 	TotalErrors                prometheus.Counter
+	LocalHits                  prometheus.Counter
+	ForwardedRequests          prometheus.Counter
+	ForwardCached              prometheus.Counter
+	ForwardCacheErrors         prometheus.Counter
+	ForwardCacheHits           prometheus.Counter
 }
 
 func newMetrics() metrics {
@@ -84,6 +89,36 @@ func newMetrics() metrics {
 			Name:      "total_errors",
 			Help:      "Total number of errors while retrieving chunk.",
 		}),
+		LocalHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "local_hits",
+			Help:      "Number of peer requests served from the local store.",
+		}),
+		ForwardedRequests: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "forwarded_requests",
+			Help:      "Number of peer requests forwarded to other peers.",
+		}),
+		ForwardCached: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "forward_cached_chunks",
+			Help:      "Number of forwarded chunks stored in the local cache.",
+		}),
+		ForwardCacheErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "forward_cache_errors",
+			Help:      "Number of errors while storing forwarded chunks in the local cache.",
+		}),
+		ForwardCacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "forward_cache_hits",
+			Help:      "Number of peer requests served from the forwarded chunks in the local cache.",
+		}),
 	}
 }
 
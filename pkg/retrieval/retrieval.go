@@ -22,13 +22,16 @@ import (
 	"github.com/ethsana/sana/pkg/p2p/protobuf"
 	"github.com/ethsana/sana/pkg/postage"
 	"github.com/ethsana/sana/pkg/pricer"
+	"github.com/ethsana/sana/pkg/reputation"
 	pb "github.com/ethsana/sana/pkg/retrieval/pb"
 	"github.com/ethsana/sana/pkg/soc"
 	"github.com/ethsana/sana/pkg/storage"
 	"github.com/ethsana/sana/pkg/swarm"
 	"github.com/ethsana/sana/pkg/topology"
 	"github.com/ethsana/sana/pkg/tracing"
-	"github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"resenje.org/singleflight"
 )
 
@@ -64,9 +67,19 @@ type Service struct {
 	metrics       metrics
 	pricer        pricer.Interface
 	tracer        *tracing.Tracer
+	caching       bool
+	forwarded     *forwardedSet
+	timeouts      Timeouts
+	rtts          *rtts
+	reputation    reputation.Recorder
 }
 
-func New(addr swarm.Address, storer storage.Storer, streamer p2p.Streamer, chunkPeerer topology.EachPeerer, logger logging.Logger, accounting accounting.Interface, pricer pricer.Interface, tracer *tracing.Tracer) *Service {
+// New creates a new retrieval service. If caching is enabled, chunks that
+// are forwarded for other peers are stored in the local cache.
+func New(addr swarm.Address, storer storage.Storer, streamer p2p.Streamer, chunkPeerer topology.EachPeerer, logger logging.Logger, accounting accounting.Interface, pricer pricer.Interface, tracer *tracing.Tracer, caching bool, timeouts Timeouts) *Service {
+	if timeouts.Retry <= 0 {
+		timeouts.Retry = defaultRetryInterval
+	}
 	return &Service{
 		addr:          addr,
 		streamer:      streamer,
@@ -77,9 +90,19 @@ func New(addr swarm.Address, storer storage.Storer, streamer p2p.Streamer, chunk
 		pricer:        pricer,
 		metrics:       newMetrics(),
 		tracer:        tracer,
+		caching:       caching,
+		forwarded:     newForwardedSet(forwardedSetSize),
+		timeouts:      timeouts,
+		rtts:          newRTTs(),
 	}
 }
 
+// SetReputation sets the recorder of the latencies and the invalid
+// deliveries of the peers.
+func (s *Service) SetReputation(r reputation.Recorder) {
+	s.reputation = r
+}
+
 func (s *Service) Protocol() p2p.ProtocolSpec {
 	return p2p.ProtocolSpec{
 		Name:    protocolName,
@@ -94,16 +117,20 @@ func (s *Service) Protocol() p2p.ProtocolSpec {
 }
 
 const (
-	retrieveChunkTimeout          = 10 * time.Second
-	retrieveRetryIntervalDuration = 5 * time.Second
-	maxRequestRounds              = 5
-	maxSelects                    = 8
-	originSuffix                  = "_origin"
+	maxRequestRounds = 5
+	maxSelects       = 8
+	originSuffix     = "_origin"
 )
 
-func (s *Service) RetrieveChunk(ctx context.Context, addr swarm.Address, origin bool) (swarm.Chunk, error) {
+func (s *Service) RetrieveChunk(ctx context.Context, addr swarm.Address, origin bool) (_ swarm.Chunk, err error) {
 	s.metrics.RequestCounter.Inc()
 
+	span, _, ctx := s.tracer.StartSpanFromContext(ctx, "retrieve-chunk", s.logger, trace.WithAttributes(
+		attribute.String("address", addr.String()),
+		attribute.Bool("origin", origin),
+	))
+	defer func() { tracing.EndSpan(span, err) }()
+
 	flightRoute := addr.String()
 	if origin {
 		flightRoute = addr.String() + originSuffix
@@ -113,6 +140,12 @@ func (s *Service) RetrieveChunk(ctx context.Context, addr swarm.Address, origin
 	topCtx := ctx
 
 	v, _, err := s.singleflight.Do(ctx, flightRoute, func(ctx context.Context) (interface{}, error) {
+		if s.timeouts.Request > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.timeouts.Request)
+			defer cancel()
+		}
+
 		maxPeers := 1
 		if origin {
 			maxPeers = maxSelects
@@ -120,7 +153,7 @@ func (s *Service) RetrieveChunk(ctx context.Context, addr swarm.Address, origin
 
 		sp := newSkipPeers()
 
-		ticker := time.NewTicker(retrieveRetryIntervalDuration)
+		ticker := time.NewTicker(s.timeouts.Retry)
 		defer ticker.Stop()
 
 		var (
@@ -141,27 +174,30 @@ func (s *Service) RetrieveChunk(ctx context.Context, addr swarm.Address, origin
 				// set the tracing span to the new context from the context of the first caller
 				ctx := tracing.WithContext(context.Background(), tracing.FromContext(topCtx))
 
-				// get the tracing span
-				span, _, ctx := s.tracer.StartSpanFromContext(ctx, "retrieve-chunk", s.logger, opentracing.Tag{Key: "address", Value: addr.String()})
-				defer span.Finish()
-
 				peerAttempt++
 				s.metrics.PeerRequestCounter.Inc()
-				go func() {
-
-					// cancel the goroutine just with the timeout
-					ctx, cancel := context.WithTimeout(ctx, retrieveChunkTimeout)
+				go func(round, attempt int) {
+					span, _, ctx := s.tracer.StartSpanFromContext(ctx, "retrieve-chunk-attempt", s.logger, trace.WithAttributes(
+						attribute.String("address", addr.String()),
+						attribute.Int("round", round),
+						attribute.Int("attempt", attempt),
+					))
+
+					// cancel the goroutine just with the timeout, the
+					// request to the selected peer may be limited further
+					ctx, cancel := context.WithTimeout(ctx, s.maxHop())
 					defer cancel()
 
 					chunk, peer, requested, err := s.retrieveChunk(ctx, addr, sp, origin)
+					span.SetAttributes(attribute.String("peer", peer.String()), attribute.Bool("requested", requested))
+					tracing.EndSpan(span, err)
 					resultC <- retrievalResult{
 						chunk:     chunk,
 						peer:      peer,
 						err:       err,
 						retrieved: requested,
 					}
-
-				}()
+				}(requestAttempt, peerAttempt)
 			} else {
 				resultC <- retrievalResult{}
 			}
@@ -173,7 +209,10 @@ func (s *Service) RetrieveChunk(ctx context.Context, addr swarm.Address, origin
 				if res.retrieved {
 					if res.err != nil {
 						if !res.peer.IsZero() {
-							s.logger.Debugf("retrieval: failed to get chunk %s from peer %s: %v", addr, res.peer, res.err)
+							s.logger.WithFields(logrus.Fields{
+								logging.FieldChunk: addr.String(),
+								logging.FieldPeer:  res.peer.String(),
+							}).Debugf("retrieval: failed to get chunk %s from peer %s: %v", addr, res.peer, res.err)
 						}
 						peersResults++
 					} else {
@@ -244,13 +283,14 @@ func (s *Service) retrieveChunk(ctx context.Context, addr swarm.Address, sp *ski
 		allowUpstream = false
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, retrieveChunkTimeout)
-	defer cancel()
 	peer, err = s.closestPeer(addr, sp.All(), allowUpstream)
 	if err != nil {
 		return nil, peer, false, fmt.Errorf("get closest for address %s, allow upstream %v: %w", addr.String(), allowUpstream, err)
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, s.hopTimeout(peer))
+	defer cancel()
+
 	peerPO := swarm.Proximity(s.addr.Bytes(), peer.Bytes())
 
 	if !sourcePeerAddr.IsZero() {
@@ -278,8 +318,15 @@ func (s *Service) retrieveChunk(ctx context.Context, addr swarm.Address, sp *ski
 
 	sp.Add(peer)
 
-	s.logger.Tracef("retrieval: requesting chunk %s from peer %s", addr, peer)
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("peer", peer.String()))
 
+	s.logger.WithFields(logrus.Fields{
+		logging.FieldChunk: addr.String(),
+		logging.FieldPeer:  peer.String(),
+	}).Tracef("retrieval: requesting chunk %s from peer %s", addr, peer)
+
+	requestStart := time.Now()
 	stream, err := s.streamer.NewStream(ctx, peer, nil, protocolName, protocolVersion, streamName)
 	if err != nil {
 		s.metrics.TotalErrors.Inc()
@@ -302,11 +349,18 @@ func (s *Service) retrieveChunk(ctx context.Context, addr swarm.Address, sp *ski
 		return nil, peer, false, fmt.Errorf("write request: %w peer %s", err, peer.String())
 	}
 
+	span.AddEvent("request sent")
+
 	var d pb.Delivery
 	if err := r.ReadMsgWithContext(ctx, &d); err != nil {
 		s.metrics.TotalErrors.Inc()
 		return nil, peer, true, fmt.Errorf("read delivery: %w peer %s", err, peer.String())
 	}
+	span.AddEvent("delivery received")
+	s.rtts.observe(peer, time.Since(requestStart))
+	if s.reputation != nil {
+		s.reputation.RecordLatency(peer, time.Since(requestStart))
+	}
 	s.metrics.RetrieveChunkPeerPOTimer.
 		WithLabelValues(strconv.Itoa(int(peerPO))).
 		Observe(time.Since(startTimer).Seconds())
@@ -315,6 +369,9 @@ func (s *Service) retrieveChunk(ctx context.Context, addr swarm.Address, sp *ski
 	stamp := new(postage.Stamp)
 	err = stamp.UnmarshalBinary(d.Stamp)
 	if err != nil {
+		if s.reputation != nil {
+			s.reputation.RecordFailure(peer, reputation.FailureProtocol)
+		}
 		return nil, peer, true, fmt.Errorf("stamp unmarshal: %w", err)
 	}
 	chunk = swarm.NewChunk(addr, d.Data).WithStamp(stamp)
@@ -322,12 +379,17 @@ func (s *Service) retrieveChunk(ctx context.Context, addr swarm.Address, sp *ski
 		if !soc.Valid(chunk) {
 			s.metrics.InvalidChunkRetrieved.Inc()
 			s.metrics.TotalErrors.Inc()
+			if s.reputation != nil {
+				s.reputation.RecordFailure(peer, reputation.FailureProtocol)
+			}
 			return nil, peer, true, swarm.ErrInvalidChunk
 		}
 	}
 
 	// credit the peer after successful delivery
+	creditSpan, _, _ := s.tracer.StartSpanFromContext(ctx, "accounting-credit", nil, tracing.AccountingAttributes(peer, chunkPrice))
 	err = s.accounting.Credit(peer, chunkPrice, originated)
+	tracing.EndSpan(creditSpan, err)
 	if err != nil {
 		return nil, peer, true, err
 	}
@@ -405,22 +467,33 @@ func (s *Service) handler(ctx context.Context, p p2p.Peer, stream p2p.Stream) (e
 		return fmt.Errorf("read request: %w peer %s", err, p.Address.String())
 	}
 
-	span, _, ctx := s.tracer.StartSpanFromContext(ctx, "handle-retrieve-chunk", s.logger, opentracing.Tag{Key: "address", Value: swarm.NewAddress(req.Addr).String()})
-	defer span.Finish()
+	span, _, ctx := s.tracer.StartSpanFromContext(ctx, "handle-retrieve-chunk", s.logger, trace.WithAttributes(
+		attribute.String("address", swarm.NewAddress(req.Addr).String()),
+		attribute.String("peer", p.Address.String()),
+	))
+	defer func() { tracing.EndSpan(span, err) }()
 
 	ctx = context.WithValue(ctx, requestSourceContextKey{}, p.Address.String())
 	addr := swarm.NewAddress(req.Addr)
+	forwarded := false
 	chunk, err := s.storer.Get(ctx, storage.ModeGetRequest, addr)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
+			s.metrics.ForwardedRequests.Inc()
 			// forward the request
 			chunk, err = s.RetrieveChunk(ctx, addr, false)
 			if err != nil {
 				return fmt.Errorf("retrieve chunk: %w", err)
 			}
+			forwarded = true
 		} else {
 			return fmt.Errorf("get from store: %w", err)
 		}
+	} else {
+		s.metrics.LocalHits.Inc()
+		if s.forwarded.has(addr) {
+			s.metrics.ForwardCacheHits.Inc()
+		}
 	}
 
 	stamp, err := chunk.Stamp().MarshalBinary()
@@ -442,7 +515,28 @@ func (s *Service) handler(ctx context.Context, p p2p.Peer, stream p2p.Stream) (e
 		return fmt.Errorf("write delivery: %w peer %s", err, p.Address.String())
 	}
 
+	// the chunk is cached once it is delivered, not to delay the delivery
+	if forwarded && s.caching {
+		s.cacheForwarded(ctx, chunk)
+	}
+
 	s.logger.Tracef("retrieval protocol debiting peer %s", p.Address.String())
 	// debit price from p's balance
-	return debit.Apply()
+	debitSpan, _, _ := s.tracer.StartSpanFromContext(ctx, "accounting-debit", nil, tracing.AccountingAttributes(p.Address, chunkPrice))
+	err = debit.Apply()
+	tracing.EndSpan(debitSpan, err)
+	return err
+}
+
+// cacheForwarded stores the chunk that has been forwarded for another peer in
+// the local cache. The cache capacity is enforced by the storer garbage
+// collection. Failing to cache the chunk does not fail the delivery.
+func (s *Service) cacheForwarded(ctx context.Context, chunk swarm.Chunk) {
+	if _, err := s.storer.Put(ctx, storage.ModePutRequestCache, chunk); err != nil {
+		s.metrics.ForwardCacheErrors.Inc()
+		s.logger.Debugf("retrieval: cache forwarded chunk %s: %v", chunk.Address(), err)
+		return
+	}
+	s.forwarded.add(chunk.Address())
+	s.metrics.ForwardCached.Inc()
 }
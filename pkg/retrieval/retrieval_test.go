@@ -61,7 +61,7 @@ func TestDelivery(t *testing.T) {
 	}
 
 	// create the server that will handle the request and will serve the response
-	server := retrieval.New(swarm.MustParseHexAddress("0034"), mockStorer, nil, nil, logger, serverMockAccounting, pricerMock, nil)
+	server := retrieval.New(swarm.MustParseHexAddress("0034"), mockStorer, nil, nil, logger, serverMockAccounting, pricerMock, nil, false, retrieval.Timeouts{})
 	recorder := streamtest.New(
 		streamtest.WithProtocols(server.Protocol()),
 		streamtest.WithBaseAddr(clientAddr),
@@ -78,7 +78,7 @@ func TestDelivery(t *testing.T) {
 		return nil
 	}}
 
-	client := retrieval.New(clientAddr, clientMockStorer, recorder, ps, logger, clientMockAccounting, pricerMock, nil)
+	client := retrieval.New(clientAddr, clientMockStorer, recorder, ps, logger, clientMockAccounting, pricerMock, nil, false, retrieval.Timeouts{})
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
 	defer cancel()
 	v, err := client.RetrieveChunk(ctx, chunk.Address(), true)
@@ -167,14 +167,14 @@ func TestRetrieveChunk(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		server := retrieval.New(serverAddress, serverStorer, nil, nil, logger, accountingmock.NewAccounting(), pricer, nil)
+		server := retrieval.New(serverAddress, serverStorer, nil, nil, logger, accountingmock.NewAccounting(), pricer, nil, false, retrieval.Timeouts{})
 		recorder := streamtest.New(streamtest.WithProtocols(server.Protocol()))
 
 		clientSuggester := mockPeerSuggester{eachPeerRevFunc: func(f topology.EachPeerFunc) error {
 			_, _, _ = f(serverAddress, 0)
 			return nil
 		}}
-		client := retrieval.New(clientAddress, nil, recorder, clientSuggester, logger, accountingmock.NewAccounting(), pricer, nil)
+		client := retrieval.New(clientAddress, nil, recorder, clientSuggester, logger, accountingmock.NewAccounting(), pricer, nil, false, retrieval.Timeouts{})
 
 		got, err := client.RetrieveChunk(context.Background(), chunk.Address(), true)
 		if err != nil {
@@ -207,6 +207,8 @@ func TestRetrieveChunk(t *testing.T) {
 			accountingmock.NewAccounting(),
 			pricer,
 			nil,
+			false,
+			retrieval.Timeouts{},
 		)
 
 		forwarder := retrieval.New(
@@ -221,6 +223,8 @@ func TestRetrieveChunk(t *testing.T) {
 			accountingmock.NewAccounting(),
 			pricer,
 			nil,
+			false,
+			retrieval.Timeouts{},
 		)
 
 		client := retrieval.New(
@@ -235,6 +239,8 @@ func TestRetrieveChunk(t *testing.T) {
 			accountingmock.NewAccounting(),
 			pricer,
 			nil,
+			false,
+			retrieval.Timeouts{},
 		)
 
 		got, err := client.RetrieveChunk(context.Background(), chunk.Address(), true)
@@ -245,6 +251,99 @@ func TestRetrieveChunk(t *testing.T) {
 			t.Fatalf("got data %x, want %x", got.Data(), chunk.Data())
 		}
 	})
+
+	t.Run("forward caching", func(t *testing.T) {
+		chunk := testingc.FixtureChunk("0025")
+
+		serverAddress := swarm.MustParseHexAddress("0100000000000000000000000000000000000000000000000000000000000000")
+		forwarderAddress := swarm.MustParseHexAddress("0200000000000000000000000000000000000000000000000000000000000000")
+		clientAddress := swarm.MustParseHexAddress("030000000000000000000000000000000000000000000000000000000000000000")
+
+		serverStorer := storemock.NewStorer()
+		_, err := serverStorer.Put(context.Background(), storage.ModePutUpload, chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		server := retrieval.New(
+			serverAddress,
+			serverStorer, // chunk is in server's store
+			nil,
+			nil,
+			logger,
+			accountingmock.NewAccounting(),
+			pricer,
+			nil,
+			false,
+			retrieval.Timeouts{},
+		)
+
+		forwarderStorer := storemock.NewStorer()
+		forwarder := retrieval.New(
+			forwarderAddress,
+			forwarderStorer, // no chunk in forwarder's store
+			streamtest.New(streamtest.WithProtocols(server.Protocol())), // connect to server
+			mockPeerSuggester{eachPeerRevFunc: func(f topology.EachPeerFunc) error {
+				_, _, _ = f(serverAddress, 0) // suggest server's address
+				return nil
+			}},
+			logger,
+			accountingmock.NewAccounting(),
+			pricer,
+			nil,
+			true, // cache forwarded chunks
+			retrieval.Timeouts{},
+		)
+
+		client := retrieval.New(
+			clientAddress,
+			storemock.NewStorer(), // no chunk in clients's store
+			streamtest.New(streamtest.WithProtocols(forwarder.Protocol())), // connect to forwarder
+			mockPeerSuggester{eachPeerRevFunc: func(f topology.EachPeerFunc) error {
+				_, _, _ = f(forwarderAddress, 0) // suggest forwarder's address
+				return nil
+			}},
+			logger,
+			accountingmock.NewAccounting(),
+			pricer,
+			nil,
+			false,
+			retrieval.Timeouts{},
+		)
+
+		got, err := client.RetrieveChunk(context.Background(), chunk.Address(), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Data(), chunk.Data()) {
+			t.Fatalf("got data %x, want %x", got.Data(), chunk.Data())
+		}
+
+		// the chunk is cached after it is delivered
+		for i := 0; ; i++ {
+			has, err := forwarderStorer.Has(context.Background(), chunk.Address())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if has {
+				break
+			}
+			if i == 50 {
+				t.Fatal("forwarded chunk not cached")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if hits := forwarder.ForwardCacheHits(); hits != 0 {
+			t.Fatalf("got %v forward cache hits, want 0", hits)
+		}
+
+		if _, err := client.RetrieveChunk(context.Background(), chunk.Address(), true); err != nil {
+			t.Fatal(err)
+		}
+		if hits := forwarder.ForwardCacheHits(); hits != 1 {
+			t.Fatalf("got %v forward cache hits, want 1", hits)
+		}
+	})
 }
 
 func TestRetrievePreemptiveRetry(t *testing.T) {
@@ -301,8 +400,8 @@ func TestRetrievePreemptiveRetry(t *testing.T) {
 		return peerSuggester
 	}
 
-	server1 := retrieval.New(serverAddress1, serverStorer1, nil, noPeerSuggester, logger, accountingmock.NewAccounting(), pricerMock, nil)
-	server2 := retrieval.New(serverAddress2, serverStorer2, nil, noPeerSuggester, logger, accountingmock.NewAccounting(), pricerMock, nil)
+	server1 := retrieval.New(serverAddress1, serverStorer1, nil, noPeerSuggester, logger, accountingmock.NewAccounting(), pricerMock, nil, false, retrieval.Timeouts{})
+	server2 := retrieval.New(serverAddress2, serverStorer2, nil, noPeerSuggester, logger, accountingmock.NewAccounting(), pricerMock, nil, false, retrieval.Timeouts{})
 
 	t.Run("peer not reachable", func(t *testing.T) {
 		ranOnce := true
@@ -330,7 +429,7 @@ func TestRetrievePreemptiveRetry(t *testing.T) {
 			streamtest.WithBaseAddr(clientAddress),
 		)
 
-		client := retrieval.New(clientAddress, nil, recorder, peerSuggesterFn(peers...), logger, accountingmock.NewAccounting(), pricerMock, nil)
+		client := retrieval.New(clientAddress, nil, recorder, peerSuggesterFn(peers...), logger, accountingmock.NewAccounting(), pricerMock, nil, false, retrieval.Timeouts{})
 
 		got, err := client.RetrieveChunk(context.Background(), chunk.Address(), true)
 		if err != nil {
@@ -366,7 +465,7 @@ func TestRetrievePreemptiveRetry(t *testing.T) {
 			),
 		)
 
-		client := retrieval.New(clientAddress, nil, recorder, peerSuggesterFn(peers...), logger, accountingmock.NewAccounting(), pricerMock, nil)
+		client := retrieval.New(clientAddress, nil, recorder, peerSuggesterFn(peers...), logger, accountingmock.NewAccounting(), pricerMock, nil, false, retrieval.Timeouts{})
 
 		got, err := client.RetrieveChunk(context.Background(), chunk.Address(), true)
 		if err != nil {
@@ -395,8 +494,8 @@ func TestRetrievePreemptiveRetry(t *testing.T) {
 		server1MockAccounting := accountingmock.NewAccounting()
 		server2MockAccounting := accountingmock.NewAccounting()
 
-		server1 := retrieval.New(serverAddress1, serverStorer1, nil, noPeerSuggester, logger, server1MockAccounting, pricerMock, nil)
-		server2 := retrieval.New(serverAddress2, serverStorer2, nil, noPeerSuggester, logger, server2MockAccounting, pricerMock, nil)
+		server1 := retrieval.New(serverAddress1, serverStorer1, nil, noPeerSuggester, logger, server1MockAccounting, pricerMock, nil, false, retrieval.Timeouts{})
+		server2 := retrieval.New(serverAddress2, serverStorer2, nil, noPeerSuggester, logger, server2MockAccounting, pricerMock, nil, false, retrieval.Timeouts{})
 
 		// NOTE: must be more than retry duration
 		// (here one second more)
@@ -430,7 +529,7 @@ func TestRetrievePreemptiveRetry(t *testing.T) {
 
 		clientMockAccounting := accountingmock.NewAccounting()
 
-		client := retrieval.New(clientAddress, nil, recorder, peerSuggesterFn(peers...), logger, clientMockAccounting, pricerMock, nil)
+		client := retrieval.New(clientAddress, nil, recorder, peerSuggesterFn(peers...), logger, clientMockAccounting, pricerMock, nil, false, retrieval.Timeouts{})
 
 		got, err := client.RetrieveChunk(context.Background(), chunk.Address(), true)
 		if err != nil {
@@ -468,21 +567,21 @@ func TestRetrievePreemptiveRetry(t *testing.T) {
 
 	t.Run("peer forwards request", func(t *testing.T) {
 		// server 2 has the chunk
-		server2 := retrieval.New(serverAddress2, serverStorer2, nil, noPeerSuggester, logger, accountingmock.NewAccounting(), pricerMock, nil)
+		server2 := retrieval.New(serverAddress2, serverStorer2, nil, noPeerSuggester, logger, accountingmock.NewAccounting(), pricerMock, nil, false, retrieval.Timeouts{})
 
 		server1Recorder := streamtest.New(
 			streamtest.WithProtocols(server2.Protocol()),
 		)
 
 		// server 1 will forward request to server 2
-		server1 := retrieval.New(serverAddress1, serverStorer1, server1Recorder, peerSuggesterFn(serverAddress2), logger, accountingmock.NewAccounting(), pricerMock, nil)
+		server1 := retrieval.New(serverAddress1, serverStorer1, server1Recorder, peerSuggesterFn(serverAddress2), logger, accountingmock.NewAccounting(), pricerMock, nil, false, retrieval.Timeouts{})
 
 		clientRecorder := streamtest.New(
 			streamtest.WithProtocols(server1.Protocol()),
 		)
 
 		// client only knows about server 1
-		client := retrieval.New(clientAddress, nil, clientRecorder, peerSuggesterFn(serverAddress1), logger, accountingmock.NewAccounting(), pricerMock, nil)
+		client := retrieval.New(clientAddress, nil, clientRecorder, peerSuggesterFn(serverAddress1), logger, accountingmock.NewAccounting(), pricerMock, nil, false, retrieval.Timeouts{})
 
 		got, err := client.RetrieveChunk(context.Background(), chunk.Address(), true)
 		if err != nil {
@@ -505,3 +604,75 @@ func (s mockPeerSuggester) EachPeer(topology.EachPeerFunc) error {
 func (s mockPeerSuggester) EachPeerRev(f topology.EachPeerFunc) error {
 	return s.eachPeerRevFunc(f)
 }
+
+func TestHopTimeout(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	pricerMock := pricermock.NewMockService(defaultPrice, defaultPrice)
+	fast := swarm.MustParseHexAddress("01")
+	slow := swarm.MustParseHexAddress("02")
+	unknown := swarm.MustParseHexAddress("03")
+
+	s := retrieval.New(swarm.ZeroAddress, nil, nil, nil, logger, accountingmock.NewAccounting(), pricerMock, nil, false, retrieval.Timeouts{})
+	if got := s.HopTimeout(fast); got != 10*time.Second {
+		t.Fatalf("got timeout %s without measurements, want %s", got, 10*time.Second)
+	}
+
+	s.ObserveRTT(fast, 100*time.Millisecond)
+	s.ObserveRTT(slow, 5*time.Second)
+	for peer, want := range map[string]time.Duration{
+		fast.String(): 3 * time.Second,  // bounded by the minimum
+		slow.String(): 20 * time.Second, // adapted to the measured round trips
+	} {
+		if got := s.HopTimeout(swarm.MustParseHexAddress(peer)); got != want {
+			t.Errorf("peer %s: got timeout %s, want %s", peer, got, want)
+		}
+	}
+	// peers without measurements get the timeout derived from all peers
+	if got := s.HopTimeout(unknown); got <= 3*time.Second || got >= 20*time.Second {
+		t.Errorf("got timeout %s for unknown peer", got)
+	}
+
+	s = retrieval.New(swarm.ZeroAddress, nil, nil, nil, logger, accountingmock.NewAccounting(), pricerMock, nil, false, retrieval.Timeouts{Hop: time.Minute})
+	s.ObserveRTT(fast, 100*time.Millisecond)
+	if got := s.HopTimeout(fast); got != time.Minute {
+		t.Fatalf("got timeout %s, want configured %s", got, time.Minute)
+	}
+}
+
+func TestRetrieveChunk_requestTimeout(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	pricerMock := pricermock.NewMockService(defaultPrice, defaultPrice)
+	serverAddress := swarm.MustParseHexAddress("01")
+
+	// the server never delivers the chunk in time
+	server := p2p.ProtocolSpec{
+		Name:    "retrieval",
+		Version: "1.0.0",
+		StreamSpecs: []p2p.StreamSpec{
+			{
+				Name: "retrieval",
+				Handler: func(ctx context.Context, p p2p.Peer, stream p2p.Stream) error {
+					time.Sleep(time.Second)
+					return nil
+				},
+			},
+		},
+	}
+	recorder := streamtest.New(streamtest.WithProtocols(server))
+	suggester := mockPeerSuggester{eachPeerRevFunc: func(f topology.EachPeerFunc) error {
+		_, _, _ = f(serverAddress, 0)
+		return nil
+	}}
+	client := retrieval.New(swarm.MustParseHexAddress("02"), nil, recorder, suggester, logger, accountingmock.NewAccounting(), pricerMock, nil, false, retrieval.Timeouts{
+		Request: 100 * time.Millisecond,
+	})
+
+	start := time.Now()
+	_, err := client.RetrieveChunk(context.Background(), testingc.FixtureChunk("0025").Address(), true)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > 900*time.Millisecond {
+		t.Fatalf("retrieval took %s", d)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package retrieval
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+const (
+	defaultHopTimeout    = 10 * time.Second
+	defaultRetryInterval = 5 * time.Second
+	minHopTimeout        = 3 * time.Second
+	maxHopTimeout        = 60 * time.Second
+	// hopTimeoutFactor is the multiple of the measured round trip time of a
+	// peer which is waited for its delivery.
+	hopTimeoutFactor = 4
+	// rttWeight is the weight of a new measurement in the moving average of
+	// the round trip times.
+	rttWeight = 0.2
+)
+
+// Timeouts configure how long chunks are waited for. Zero values select the
+// defaults.
+type Timeouts struct {
+	// Hop is the maximal duration of a request to a single peer. By default
+	// it is adapted to the measured round trip times of the peer.
+	Hop time.Duration
+	// Request is the maximal duration of retrieving a chunk, including the
+	// requests to multiple peers. By default it is only limited by the
+	// context of the caller.
+	Request time.Duration
+	// Retry is the duration after which the chunk is requested from another
+	// peer while a request is in flight.
+	Retry time.Duration
+}
+
+// rtts holds the moving averages of the round trip times of the requests to
+// peers, which include the forwarding by the peers.
+type rtts struct {
+	mu    sync.Mutex
+	peers map[string]time.Duration
+	all   time.Duration
+}
+
+func newRTTs() *rtts {
+	return &rtts{peers: make(map[string]time.Duration)}
+}
+
+func (r *rtts) observe(peer swarm.Address, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.peers[peer.ByteString()] = average(r.peers[peer.ByteString()], d)
+	r.all = average(r.all, d)
+}
+
+func (r *rtts) get(peer swarm.Address) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if d, ok := r.peers[peer.ByteString()]; ok {
+		return d
+	}
+	return r.all
+}
+
+func average(avg, d time.Duration) time.Duration {
+	if avg == 0 {
+		return d
+	}
+	return time.Duration((1-rttWeight)*float64(avg) + rttWeight*float64(d))
+}
+
+// hopTimeout returns the duration which a request to the peer is waited for.
+// Without a configured timeout, it is a multiple of the round trip time of
+// the peer, or of all peers if the peer was not measured yet.
+func (s *Service) hopTimeout(peer swarm.Address) time.Duration {
+	if s.timeouts.Hop > 0 {
+		return s.timeouts.Hop
+	}
+	rtt := s.rtts.get(peer)
+	if rtt == 0 {
+		return defaultHopTimeout
+	}
+	timeout := hopTimeoutFactor * rtt
+	switch {
+	case timeout < minHopTimeout:
+		return minHopTimeout
+	case timeout > maxHopTimeout:
+		return maxHopTimeout
+	}
+	return timeout
+}
+
+// maxHop is the longest duration which a request to any peer is waited for.
+func (s *Service) maxHop() time.Duration {
+	if s.timeouts.Hop > 0 {
+		return s.timeouts.Hop
+	}
+	return maxHopTimeout
+}
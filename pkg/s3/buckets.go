@@ -0,0 +1,88 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package s3
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+const bucketKeyPrefix = "s3_bucket_"
+
+// ErrBucketNotFound is returned when the bucket does not exist.
+var ErrBucketNotFound = errors.New("s3: bucket not found")
+
+// Bucket maps a bucket name to the manifest holding its objects.
+type Bucket struct {
+	Name string `json:"name"`
+	// Reference is the root of the bucket manifest. It is the zero
+	// address for a bucket without objects.
+	Reference swarm.Address `json:"reference"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+// Buckets persists the bucket to manifest mapping.
+type Buckets interface {
+	Get(name string) (Bucket, error)
+	Put(b Bucket) error
+	Delete(name string) error
+	// List returns all buckets sorted by name.
+	List() ([]Bucket, error)
+}
+
+type buckets struct {
+	store storage.StateStorer
+}
+
+// NewBuckets returns bucket persistence backed by the state store.
+func NewBuckets(store storage.StateStorer) Buckets {
+	return &buckets{store: store}
+}
+
+func (s *buckets) Get(name string) (b Bucket, err error) {
+	if err := s.store.Get(bucketKeyPrefix+name, &b); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return Bucket{}, ErrBucketNotFound
+		}
+		return Bucket{}, err
+	}
+	return b, nil
+}
+
+func (s *buckets) Put(b Bucket) error {
+	return s.store.Put(bucketKeyPrefix+b.Name, b)
+}
+
+func (s *buckets) Delete(name string) error {
+	return s.store.Delete(bucketKeyPrefix + name)
+}
+
+func (s *buckets) List() ([]Bucket, error) {
+	var list []Bucket
+	err := s.store.Iterate(bucketKeyPrefix, func(key, value []byte) (bool, error) {
+		if !strings.HasPrefix(string(key), bucketKeyPrefix) {
+			return true, nil
+		}
+		var b Bucket
+		if err := json.Unmarshal(value, &b); err != nil {
+			return true, err
+		}
+		list = append(list, b)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package s3 contains the types needed to expose swarm manifests through a
+// minimal S3-compatible object storage API. Every bucket is backed by a
+// manifest whose root reference changes with every object modification.
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+const (
+	// Manifest entry metadata keys used to answer object requests without
+	// reading the object data.
+	EntryMetadataETagKey         = "S3-ETag"
+	EntryMetadataSizeKey         = "S3-Size"
+	EntryMetadataLastModifiedKey = "S3-Last-Modified"
+
+	// TimeFormat is the format of timestamps in S3 responses.
+	TimeFormat = "2006-01-02T15:04:05.000Z"
+
+	// MaxKeys is the maximal number of keys returned by a single listing.
+	MaxKeys = 1000
+
+	// XMLNamespace is the namespace of S3 response documents.
+	XMLNamespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+)
+
+var bucketNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// ValidBucketName reports whether the name is a valid S3 bucket name.
+func ValidBucketName(name string) bool {
+	return bucketNameRegexp.MatchString(name)
+}
+
+// Owner is the bucket owner. The node has a single owner for all buckets.
+type Owner struct {
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName"`
+}
+
+// BucketInfo describes a single bucket in the ListBuckets response.
+type BucketInfo struct {
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"`
+}
+
+// ListAllMyBucketsResult is the ListBuckets response.
+type ListAllMyBucketsResult struct {
+	XMLName xml.Name     `xml:"ListAllMyBucketsResult"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Owner   Owner        `xml:"Owner"`
+	Buckets []BucketInfo `xml:"Buckets>Bucket"`
+}
+
+// NewListAllMyBucketsResult creates the ListBuckets response for the
+// buckets.
+func NewListAllMyBucketsResult(owner string, buckets []Bucket) ListAllMyBucketsResult {
+	r := ListAllMyBucketsResult{
+		Xmlns:   XMLNamespace,
+		Owner:   Owner{ID: owner, DisplayName: owner},
+		Buckets: make([]BucketInfo, 0, len(buckets)),
+	}
+	for _, b := range buckets {
+		r.Buckets = append(r.Buckets, BucketInfo{
+			Name:         b.Name,
+			CreationDate: b.CreatedAt.UTC().Format(TimeFormat),
+		})
+	}
+	return r
+}
+
+// Object describes a single object in the ListObjects response.
+type Object struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+// CommonPrefix is a key prefix grouped by the listing delimiter.
+type CommonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+// ListBucketResult is the ListObjects response.
+type ListBucketResult struct {
+	XMLName        xml.Name       `xml:"ListBucketResult"`
+	Xmlns          string         `xml:"xmlns,attr"`
+	Name           string         `xml:"Name"`
+	Prefix         string         `xml:"Prefix"`
+	Marker         string         `xml:"Marker"`
+	NextMarker     string         `xml:"NextMarker,omitempty"`
+	MaxKeys        int            `xml:"MaxKeys"`
+	Delimiter      string         `xml:"Delimiter,omitempty"`
+	IsTruncated    bool           `xml:"IsTruncated"`
+	Contents       []Object       `xml:"Contents"`
+	CommonPrefixes []CommonPrefix `xml:"CommonPrefixes"`
+}
+
+// ListBucketResultV2 is the ListObjectsV2 response.
+type ListBucketResultV2 struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	Xmlns                 string         `xml:"xmlns,attr"`
+	Name                  string         `xml:"Name"`
+	Prefix                string         `xml:"Prefix"`
+	StartAfter            string         `xml:"StartAfter,omitempty"`
+	ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+	KeyCount              int            `xml:"KeyCount"`
+	MaxKeys               int            `xml:"MaxKeys"`
+	Delimiter             string         `xml:"Delimiter,omitempty"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	Contents              []Object       `xml:"Contents"`
+	CommonPrefixes        []CommonPrefix `xml:"CommonPrefixes"`
+}
+
+// Error codes used in error responses.
+const (
+	ErrCodeAccessDenied                 = "AccessDenied"
+	ErrCodeAuthorizationHeaderMalformed = "AuthorizationHeaderMalformed"
+	ErrCodeBucketAlreadyExists          = "BucketAlreadyOwnedByYou"
+	ErrCodeBucketNotEmpty               = "BucketNotEmpty"
+	ErrCodeContentSHA256Mismatch        = "XAmzContentSHA256Mismatch"
+	ErrCodeInternalError                = "InternalError"
+	ErrCodeInvalidAccessKeyID           = "InvalidAccessKeyId"
+	ErrCodeInvalidArgument              = "InvalidArgument"
+	ErrCodeInvalidBucketName            = "InvalidBucketName"
+	ErrCodeInvalidRequest               = "InvalidRequest"
+	ErrCodeNoSuchBucket                 = "NoSuchBucket"
+	ErrCodeNoSuchKey                    = "NoSuchKey"
+	ErrCodeNotImplemented               = "NotImplemented"
+	ErrCodeRequestTimeTooSkewed         = "RequestTimeTooSkewed"
+	ErrCodeSignatureDoesNotMatch        = "SignatureDoesNotMatch"
+)
+
+var errCodeStatus = map[string]int{
+	ErrCodeAccessDenied:                 http.StatusForbidden,
+	ErrCodeAuthorizationHeaderMalformed: http.StatusBadRequest,
+	ErrCodeBucketAlreadyExists:          http.StatusConflict,
+	ErrCodeBucketNotEmpty:               http.StatusConflict,
+	ErrCodeContentSHA256Mismatch:        http.StatusBadRequest,
+	ErrCodeInternalError:                http.StatusInternalServerError,
+	ErrCodeInvalidAccessKeyID:           http.StatusForbidden,
+	ErrCodeInvalidArgument:              http.StatusBadRequest,
+	ErrCodeInvalidBucketName:            http.StatusBadRequest,
+	ErrCodeInvalidRequest:               http.StatusBadRequest,
+	ErrCodeNoSuchBucket:                 http.StatusNotFound,
+	ErrCodeNoSuchKey:                    http.StatusNotFound,
+	ErrCodeNotImplemented:               http.StatusNotImplemented,
+	ErrCodeRequestTimeTooSkewed:         http.StatusForbidden,
+	ErrCodeSignatureDoesNotMatch:        http.StatusForbidden,
+}
+
+// Error is the error response.
+type Error struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource,omitempty"`
+}
+
+// StatusCode returns the HTTP status code of the error.
+func (e Error) StatusCode() int {
+	if code, ok := errCodeStatus[e.Code]; ok {
+		return code
+	}
+	return http.StatusInternalServerError
+}
+
+// FormatTime formats the timestamp for S3 responses.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
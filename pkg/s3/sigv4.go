@@ -0,0 +1,401 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package s3
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SigV4Algorithm is the algorithm of the AWS Signature Version 4.
+	SigV4Algorithm = "AWS4-HMAC-SHA256"
+	// UnsignedPayload is the payload hash of the requests which body is not
+	// signed.
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+	sigV4DateFormat   = "20060102T150405Z"
+	sigV4Service      = "s3"
+	sigV4Terminator   = "aws4_request"
+	sigV4MaxSkew      = 15 * time.Minute
+	sigV4MaxExpires   = 7 * 24 * time.Hour
+	streamingPrefix   = "STREAMING-"
+	contentSHA256Name = "X-Amz-Content-Sha256"
+)
+
+var (
+	// ErrMissingAuthorization is returned for the requests which are not
+	// signed.
+	ErrMissingAuthorization = errors.New("s3: missing authorization")
+	// ErrMalformedAuthorization is returned for the requests which signature
+	// can not be parsed.
+	ErrMalformedAuthorization = errors.New("s3: malformed authorization")
+	// ErrInvalidAccessKeyID is returned for the requests signed with an
+	// unknown access key.
+	ErrInvalidAccessKeyID = errors.New("s3: invalid access key id")
+	// ErrSignatureMismatch is returned for the requests which signature does
+	// not match the one computed with the secret key.
+	ErrSignatureMismatch = errors.New("s3: signature does not match")
+	// ErrRequestTimeSkewed is returned for the requests signed too far from
+	// the current time.
+	ErrRequestTimeSkewed = errors.New("s3: request time too skewed")
+	// ErrRequestExpired is returned for the presigned requests which are
+	// made after they expire.
+	ErrRequestExpired = errors.New("s3: request expired")
+	// ErrUnsupportedPayload is returned for the requests which body is
+	// signed in chunks.
+	ErrUnsupportedPayload = errors.New("s3: streaming payload not supported")
+	// ErrUnsignedHeader is returned for the requests which signature does
+	// not cover the host header or one of the x-amz-* headers.
+	ErrUnsignedHeader = errors.New("s3: header not signed")
+	// ErrContentSHA256Mismatch is returned while reading the body of the
+	// request which hash does not match the signed one.
+	ErrContentSHA256Mismatch = errors.New("s3: content sha256 mismatch")
+)
+
+// Credential is an access key and its secret key which sign the requests.
+type Credential struct {
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+}
+
+// Credentials are the secret keys by their access keys.
+type Credentials map[string]string
+
+// LoadCredentials reads the credentials from a JSON file containing an array
+// of credential definitions.
+func LoadCredentials(path string) (Credentials, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []Credential
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("s3: parse %s: %w", path, err)
+	}
+	c := make(Credentials, len(list))
+	for _, v := range list {
+		if v.AccessKey == "" || v.SecretKey == "" {
+			return nil, fmt.Errorf("s3: parse %s: empty access or secret key", path)
+		}
+		c[v.AccessKey] = v.SecretKey
+	}
+	return c, nil
+}
+
+// sigV4Request holds the signature of a request from the Authorization header
+// or the query of a presigned url.
+type sigV4Request struct {
+	accessKey     string
+	scope         string
+	date          time.Time
+	signedHeaders []string
+	signature     []byte
+	payloadHash   string
+	presigned     bool
+}
+
+// Verify checks the AWS Signature Version 4 of the request, from either the
+// Authorization header or the query of a presigned url, and returns the
+// access key which signed it. The body of a request with a signed payload
+// hash is replaced with the one which returns ErrContentSHA256Mismatch when
+// the read body does not match the hash.
+func (c Credentials) Verify(r *http.Request, now time.Time) (string, error) {
+	var (
+		req *sigV4Request
+		err error
+	)
+	if r.URL.Query().Get("X-Amz-Algorithm") != "" {
+		req, err = parsePresigned(r, now)
+	} else {
+		req, err = parseAuthorization(r, now)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := req.checkSignedHeaders(r); err != nil {
+		return "", err
+	}
+
+	secretKey, ok := c[req.accessKey]
+	if !ok {
+		return "", ErrInvalidAccessKeyID
+	}
+
+	if subtle.ConstantTimeCompare(req.sign(r, secretKey), req.signature) != 1 {
+		return "", ErrSignatureMismatch
+	}
+
+	if req.payloadHash != UnsignedPayload && r.Body != nil {
+		want, err := hex.DecodeString(req.payloadHash)
+		if err != nil || len(want) != sha256.Size {
+			return "", ErrMalformedAuthorization
+		}
+		r.Body = &sha256VerifyReader{ReadCloser: r.Body, h: sha256.New(), want: want}
+	}
+	return req.accessKey, nil
+}
+
+// parseAuthorization parses the signature of the Authorization header which
+// has the form:
+//
+//	AWS4-HMAC-SHA256 Credential=<key>/<scope>, SignedHeaders=<h1;h2>, Signature=<hex>
+func parseAuthorization(r *http.Request, now time.Time) (*sigV4Request, error) {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return nil, ErrMissingAuthorization
+	}
+	if !strings.HasPrefix(auth, SigV4Algorithm+" ") {
+		return nil, ErrMalformedAuthorization
+	}
+
+	fields := make(map[string]string)
+	for _, f := range strings.Split(strings.TrimPrefix(auth, SigV4Algorithm+" "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(f), "=", 2)
+		if len(kv) != 2 {
+			return nil, ErrMalformedAuthorization
+		}
+		fields[kv[0]] = kv[1]
+	}
+
+	req, err := newSigV4Request(fields["Credential"], fields["SignedHeaders"], fields["Signature"], r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, err
+	}
+	if d := now.Sub(req.date); d > sigV4MaxSkew || d < -sigV4MaxSkew {
+		return nil, ErrRequestTimeSkewed
+	}
+
+	req.payloadHash = r.Header.Get(contentSHA256Name)
+	switch {
+	case req.payloadHash == "":
+		return nil, ErrMalformedAuthorization
+	case strings.HasPrefix(req.payloadHash, streamingPrefix):
+		return nil, ErrUnsupportedPayload
+	}
+	return req, nil
+}
+
+// parsePresigned parses the signature of a presigned url.
+func parsePresigned(r *http.Request, now time.Time) (*sigV4Request, error) {
+	q := r.URL.Query()
+	if q.Get("X-Amz-Algorithm") != SigV4Algorithm {
+		return nil, ErrMalformedAuthorization
+	}
+	req, err := newSigV4Request(q.Get("X-Amz-Credential"), q.Get("X-Amz-SignedHeaders"), q.Get("X-Amz-Signature"), q.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, err
+	}
+
+	expires, err := strconv.Atoi(q.Get("X-Amz-Expires"))
+	if err != nil || expires < 0 || time.Duration(expires)*time.Second > sigV4MaxExpires {
+		return nil, ErrMalformedAuthorization
+	}
+	if now.Before(req.date.Add(-sigV4MaxSkew)) {
+		return nil, ErrRequestTimeSkewed
+	}
+	if now.After(req.date.Add(time.Duration(expires) * time.Second)) {
+		return nil, ErrRequestExpired
+	}
+
+	req.payloadHash = UnsignedPayload
+	req.presigned = true
+	return req, nil
+}
+
+func newSigV4Request(credential, signedHeaders, signature, date string) (*sigV4Request, error) {
+	parts := strings.SplitN(credential, "/", 2)
+	if len(parts) != 2 || signedHeaders == "" {
+		return nil, ErrMalformedAuthorization
+	}
+	scope := strings.Split(parts[1], "/")
+	if len(scope) != 4 || scope[2] != sigV4Service || scope[3] != sigV4Terminator {
+		return nil, ErrMalformedAuthorization
+	}
+
+	t, err := time.Parse(sigV4DateFormat, date)
+	if err != nil || t.Format("20060102") != scope[0] {
+		return nil, ErrMalformedAuthorization
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return nil, ErrMalformedAuthorization
+	}
+
+	return &sigV4Request{
+		accessKey:     parts[0],
+		scope:         parts[1],
+		date:          t,
+		signedHeaders: strings.Split(signedHeaders, ";"),
+		signature:     sig,
+	}, nil
+}
+
+// checkSignedHeaders checks that the host header and all the x-amz-* headers
+// of the request are signed.
+func (req *sigV4Request) checkSignedHeaders(r *http.Request) error {
+	signed := make(map[string]struct{}, len(req.signedHeaders))
+	for _, name := range req.signedHeaders {
+		signed[name] = struct{}{}
+	}
+	if _, ok := signed["host"]; !ok {
+		return fmt.Errorf("%w: host", ErrUnsignedHeader)
+	}
+	for name := range r.Header {
+		name = strings.ToLower(name)
+		if !strings.HasPrefix(name, "x-amz-") {
+			continue
+		}
+		if _, ok := signed[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnsignedHeader, name)
+		}
+	}
+	return nil
+}
+
+// sign returns the signature of the request with the secret key.
+func (req *sigV4Request) sign(r *http.Request, secretKey string) []byte {
+	stringToSign := strings.Join([]string{
+		SigV4Algorithm,
+		req.date.Format(sigV4DateFormat),
+		req.scope,
+		hexSHA256([]byte(canonicalRequest(r, req))),
+	}, "\n")
+
+	key := []byte("AWS4" + secretKey)
+	for _, s := range strings.Split(req.scope, "/") {
+		key = hmacSHA256(key, s)
+	}
+	return hmacSHA256(key, stringToSign)
+}
+
+// canonicalRequest returns the canonical form of the request which hash is
+// signed.
+func canonicalRequest(r *http.Request, req *sigV4Request) string {
+	var query []string
+	for k, vs := range r.URL.Query() {
+		if req.presigned && k == "X-Amz-Signature" {
+			continue
+		}
+		for _, v := range vs {
+			query = append(query, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	sort.Strings(query)
+
+	var headers strings.Builder
+	for _, name := range req.signedHeaders {
+		var value string
+		switch name {
+		case "host":
+			value = r.Host
+		case "content-length":
+			value = r.Header.Get("Content-Length")
+			if value == "" && r.ContentLength >= 0 {
+				value = strconv.FormatInt(r.ContentLength, 10)
+			}
+		default:
+			var vs []string
+			for _, v := range r.Header.Values(name) {
+				vs = append(vs, strings.Join(strings.Fields(v), " "))
+			}
+			value = strings.Join(vs, ",")
+		}
+		headers.WriteString(name + ":" + value + "\n")
+	}
+
+	return strings.Join([]string{
+		r.Method,
+		uriEncode(r.URL.Path, false),
+		strings.Join(query, "&"),
+		headers.String(),
+		strings.Join(req.signedHeaders, ";"),
+		req.payloadHash,
+	}, "\n")
+}
+
+// uriEncode encodes every byte of s but the unreserved characters, and the
+// slashes of the paths.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// sha256VerifyReader returns ErrContentSHA256Mismatch instead of io.EOF if
+// the read data does not match the signed hash.
+type sha256VerifyReader struct {
+	io.ReadCloser
+	h    hash.Hash
+	want []byte
+}
+
+func (v *sha256VerifyReader) Read(p []byte) (int, error) {
+	n, err := v.ReadCloser.Read(p)
+	_, _ = v.h.Write(p[:n])
+	if errors.Is(err, io.EOF) && !bytes.Equal(v.h.Sum(nil), v.want) {
+		return n, ErrContentSHA256Mismatch
+	}
+	return n, err
+}
+
+// SignV4 signs the request with the AWS Signature Version 4 of the
+// credential in the region and sets its Authorization header. The payload
+// hash is taken from the X-Amz-Content-Sha256 header, which is set to
+// UnsignedPayload if it is empty.
+func SignV4(r *http.Request, c Credential, region string, now time.Time) {
+	now = now.UTC()
+	if r.Header.Get(contentSHA256Name) == "" {
+		r.Header.Set(contentSHA256Name, UnsignedPayload)
+	}
+	r.Header.Set("X-Amz-Date", now.Format(sigV4DateFormat))
+	if r.Host == "" {
+		r.Host = r.URL.Host
+	}
+
+	req := &sigV4Request{
+		scope:         strings.Join([]string{now.Format("20060102"), region, sigV4Service, sigV4Terminator}, "/"),
+		date:          now,
+		signedHeaders: []string{"host", "x-amz-content-sha256", "x-amz-date"},
+		payloadHash:   r.Header.Get(contentSHA256Name),
+	}
+	r.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		SigV4Algorithm, c.AccessKey, req.scope, strings.Join(req.signedHeaders, ";"), hex.EncodeToString(req.sign(r, c.SecretKey))))
+}
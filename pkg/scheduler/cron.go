@@ -0,0 +1,171 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearch limits the search for the next activation of an expression which
+// matches very rarely or never, such as the 31st of February.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+var errNoActivation = errors.New("scheduler: expression has no activation")
+
+var macros = map[string]string{
+	"@yearly":  "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly":  "0 0 * * 0",
+	"@daily":   "0 0 * * *",
+	"@hourly":  "0 * * * *",
+}
+
+// Expression is a parsed cron expression with the fields minute, hour, day of
+// month, month and day of week. Fields support lists, ranges and steps, for
+// example "*/15 9-17 * * 1-5". The macros @yearly, @monthly, @weekly,
+// @daily and @hourly are supported as well.
+type Expression struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record unrestricted day fields, as a day matches
+	// either of the restricted ones
+	domAny, dowAny bool
+}
+
+type field struct {
+	min, max int
+}
+
+var fields = [5]field{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 6},  // day of week
+}
+
+// ParseExpression parses a cron expression.
+func ParseExpression(s string) (*Expression, error) {
+	s = strings.TrimSpace(s)
+	if m, ok := macros[s]; ok {
+		s = m
+	}
+	parts := strings.Fields(s)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("scheduler: expression %q: want %d fields", s, len(fields))
+	}
+	var bits [5]uint64
+	for i, p := range parts {
+		b, err := parseField(p, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("scheduler: expression %q: %w", s, err)
+		}
+		bits[i] = b
+	}
+	// sunday may be written as 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &Expression{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+func parseField(s string, f field) (uint64, error) {
+	max := f.max
+	if f == fields[4] {
+		max = 7
+	}
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		step := 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", item)
+			}
+			step = n
+			item = item[:i]
+		}
+		lo, hi := f.min, max
+		switch {
+		case item == "*":
+		case strings.Contains(item, "-"):
+			i := strings.Index(item, "-")
+			var err error
+			if lo, err = strconv.Atoi(item[:i]); err != nil {
+				return 0, fmt.Errorf("invalid range %q", item)
+			}
+			if hi, err = strconv.Atoi(item[i+1:]); err != nil {
+				return 0, fmt.Errorf("invalid range %q", item)
+			}
+		default:
+			n, err := strconv.Atoi(item)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", item)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < f.min || hi > max || lo > hi {
+			return 0, fmt.Errorf("value out of range %d-%d in %q", f.min, max, s)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first activation after t, with minute precision.
+func (e *Expression) Next(t time.Time) (time.Time, error) {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.Add(maxSearch)
+	for t.Before(end) {
+		if e.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !e.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if e.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if e.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t, nil
+	}
+	return time.Time{}, errNoActivation
+}
+
+func (e *Expression) dayMatches(t time.Time) bool {
+	dom := e.dom&(1<<uint(t.Day())) != 0
+	dow := e.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case e.domAny && e.dowAny:
+		return true
+	case e.domAny:
+		return dow
+	case e.dowAny:
+		return dom
+	}
+	return dom || dow
+}
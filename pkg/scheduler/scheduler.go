@@ -0,0 +1,457 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package scheduler runs deferred and recurring operations of the node. Other
+// modules register the types of tasks which they implement, and schedules of
+// these tasks are created with cron expressions. Schedules and their
+// execution history are persisted in the state store.
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/storage"
+)
+
+const (
+	scheduleKeyPrefix = "scheduler_schedule_"
+	historyKeyPrefix  = "scheduler_history_"
+	// historySize is the number of executions kept for each schedule.
+	historySize = 50
+)
+
+var (
+	// ErrNotFound is returned if a schedule does not exist.
+	ErrNotFound = errors.New("scheduler: schedule not found")
+	// ErrUnknownType is returned if a schedule refers to a task type which
+	// is not registered.
+	ErrUnknownType = errors.New("scheduler: unknown task type")
+	// ErrInvalid is returned if the expression or the parameters of a
+	// schedule are not valid.
+	ErrInvalid = errors.New("scheduler: invalid schedule")
+)
+
+// Task is a type of operation which can be scheduled.
+type Task interface {
+	// Validate checks the parameters of a schedule before it is stored.
+	Validate(params json.RawMessage) error
+	// Run executes the operation with the parameters of a schedule.
+	Run(ctx context.Context, params json.RawMessage) error
+}
+
+// TaskFunc is a Task accepting any parameters.
+type TaskFunc func(ctx context.Context, params json.RawMessage) error
+
+// Validate implements the Task interface.
+func (TaskFunc) Validate(json.RawMessage) error { return nil }
+
+// Run implements the Task interface.
+func (f TaskFunc) Run(ctx context.Context, params json.RawMessage) error {
+	return f(ctx, params)
+}
+
+// Schedule is a task executed at the activations of a cron expression.
+type Schedule struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Expression string          `json:"expression"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	Enabled    bool            `json:"enabled"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	NextRun    time.Time       `json:"nextRun"`
+	LastRun    *Execution      `json:"lastRun,omitempty"`
+}
+
+// Execution is a record of a run of a schedule.
+type Execution struct {
+	StartedAt time.Time     `json:"startedAt"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Update holds the changes to a schedule. Nil fields are left unchanged.
+type Update struct {
+	Expression *string          `json:"expression"`
+	Params     *json.RawMessage `json:"params"`
+	Enabled    *bool            `json:"enabled"`
+}
+
+// Scheduler executes the schedules at their activation times. Activations
+// which were missed while the node was not running are not executed
+// afterwards.
+type Scheduler struct {
+	store  storage.StateStorer
+	logger logging.Logger
+	now    func() time.Time // now is replaced in tests
+
+	mu        sync.Mutex
+	tasks     map[string]Task
+	schedules map[string]*Schedule
+	running   map[string]struct{}
+
+	wake   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// New returns a Scheduler with the schedules loaded from the state store.
+func New(store storage.StateStorer, logger logging.Logger) (*Scheduler, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		store:     store,
+		logger:    logger,
+		now:       time.Now,
+		tasks:     make(map[string]Task),
+		schedules: make(map[string]*Schedule),
+		running:   make(map[string]struct{}),
+		wake:      make(chan struct{}, 1),
+		ctx:       ctx,
+		cancel:    cancel,
+		quit:      make(chan struct{}),
+	}
+
+	err := store.Iterate(scheduleKeyPrefix, func(key, value []byte) (bool, error) {
+		if !strings.HasPrefix(string(key), scheduleKeyPrefix) {
+			return true, nil
+		}
+		var sc Schedule
+		if err := json.Unmarshal(value, &sc); err != nil {
+			return true, fmt.Errorf("decode schedule %s: %w", key, err)
+		}
+		s.schedules[sc.ID] = &sc
+		return false, nil
+	})
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("scheduler: load schedules: %w", err)
+	}
+
+	now := s.now()
+	for _, sc := range s.schedules {
+		if sc.NextRun.Before(now) {
+			if err := s.reschedule(sc, now); err != nil {
+				s.logger.Debugf("scheduler: reschedule %s: %v", sc.ID, err)
+				sc.Enabled = false
+			}
+		}
+	}
+	return s, nil
+}
+
+// Register adds a type of task which can be scheduled. It must be called
+// before Start.
+func (s *Scheduler) Register(taskType string, t Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[taskType] = t
+}
+
+// Types returns the names of the registered task types.
+func (s *Scheduler) Types() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	types := make([]string, 0, len(s.tasks))
+	for t := range s.tasks {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Create stores a new enabled schedule.
+func (s *Scheduler) Create(taskType, expression string, params json.RawMessage) (*Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tasks[taskType]
+	if !ok {
+		return nil, ErrUnknownType
+	}
+	if err := t.Validate(params); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	now := s.now()
+	sc := &Schedule{
+		ID:         hex.EncodeToString(id),
+		Type:       taskType,
+		Expression: expression,
+		Params:     params,
+		Enabled:    true,
+		CreatedAt:  now,
+	}
+	if err := s.reschedule(sc, now); err != nil {
+		return nil, err
+	}
+	s.schedules[sc.ID] = sc
+	s.notify()
+	c := *sc
+	return &c, nil
+}
+
+// Get returns the schedule with the ID.
+func (s *Scheduler) Get(id string) (*Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc, ok := s.schedules[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *sc
+	return &c, nil
+}
+
+// List returns all schedules ordered by their creation.
+func (s *Scheduler) List() []Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Schedule, 0, len(s.schedules))
+	for _, sc := range s.schedules {
+		list = append(list, *sc)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Update changes the schedule with the ID.
+func (s *Scheduler) Update(id string, u Update) (*Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc, ok := s.schedules[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *sc
+	if u.Expression != nil {
+		c.Expression = *u.Expression
+	}
+	if u.Params != nil {
+		t, ok := s.tasks[c.Type]
+		if !ok {
+			return nil, ErrUnknownType
+		}
+		if err := t.Validate(*u.Params); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		c.Params = *u.Params
+	}
+	if u.Enabled != nil {
+		c.Enabled = *u.Enabled
+	}
+	if err := s.reschedule(&c, s.now()); err != nil {
+		return nil, err
+	}
+	*sc = c
+	s.notify()
+	return &c, nil
+}
+
+// Delete removes the schedule with the ID and its history.
+func (s *Scheduler) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.schedules[id]; !ok {
+		return ErrNotFound
+	}
+	if err := s.store.Delete(scheduleKeyPrefix + id); err != nil {
+		return err
+	}
+	if err := s.store.Delete(historyKeyPrefix + id); err != nil {
+		return err
+	}
+	delete(s.schedules, id)
+	return nil
+}
+
+// History returns the recent executions of the schedule with the ID, the
+// latest first.
+func (s *Scheduler) History(id string) ([]Execution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.schedules[id]; !ok {
+		return nil, ErrNotFound
+	}
+	return s.history(id)
+}
+
+func (s *Scheduler) history(id string) ([]Execution, error) {
+	var h []Execution
+	if err := s.store.Get(historyKeyPrefix+id, &h); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	return h, nil
+}
+
+// reschedule sets the next activation of the schedule after now and stores
+// it. It must be called with the lock held.
+func (s *Scheduler) reschedule(sc *Schedule, now time.Time) error {
+	e, err := ParseExpression(sc.Expression)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	next, err := e.Next(now)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	sc.NextRun = next
+	return s.store.Put(scheduleKeyPrefix+sc.ID, sc)
+}
+
+// notify wakes the scheduling loop to recompute the next activation.
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start begins the execution of the schedules.
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for {
+			select {
+			case <-s.quit:
+				return
+			case <-s.wake:
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+			case <-timer.C:
+			}
+			timer.Reset(s.runDue())
+		}
+	}()
+}
+
+// runDue starts the schedules which are due and returns the duration until
+// the next activation.
+func (s *Scheduler) runDue() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	wait := time.Hour
+	for _, sc := range s.schedules {
+		if !sc.Enabled {
+			continue
+		}
+		if !sc.NextRun.After(now) {
+			s.start(sc, now)
+		}
+		if d := sc.NextRun.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// start executes the schedule in the background and sets its next
+// activation. An activation is skipped if the previous execution of the
+// schedule is still running. It must be called with the lock held.
+func (s *Scheduler) start(sc *Schedule, now time.Time) {
+	if err := s.reschedule(sc, now); err != nil {
+		s.logger.Debugf("scheduler: reschedule %s: %v", sc.ID, err)
+		s.logger.Errorf("scheduler: unable to reschedule %s", sc.ID)
+		sc.Enabled = false
+		return
+	}
+	if _, ok := s.running[sc.ID]; ok {
+		s.logger.Debugf("scheduler: schedule %s still running, activation skipped", sc.ID)
+		return
+	}
+	t, ok := s.tasks[sc.Type]
+	if !ok {
+		s.logger.Debugf("scheduler: schedule %s: unknown task type %s", sc.ID, sc.Type)
+		return
+	}
+
+	s.running[sc.ID] = struct{}{}
+	id, params := sc.ID, sc.Params
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(id, t, params)
+	}()
+}
+
+func (s *Scheduler) run(id string, t Task, params json.RawMessage) {
+	started := s.now()
+	err := t.Run(s.ctx, params)
+	e := Execution{StartedAt: started, Duration: s.now().Sub(started)}
+	if err != nil {
+		e.Error = err.Error()
+		s.logger.Debugf("scheduler: schedule %s: %v", id, err)
+		s.logger.Errorf("scheduler: schedule %s failed", id)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, id)
+
+	sc, ok := s.schedules[id]
+	if !ok {
+		// deleted while running
+		return
+	}
+	h, err := s.history(id)
+	if err != nil {
+		s.logger.Debugf("scheduler: get history %s: %v", id, err)
+	}
+	h = append([]Execution{e}, h...)
+	if len(h) > historySize {
+		h = h[:historySize]
+	}
+	if err := s.store.Put(historyKeyPrefix+id, h); err != nil {
+		s.logger.Debugf("scheduler: put history %s: %v", id, err)
+		s.logger.Errorf("scheduler: unable to record execution of %s", id)
+	}
+	sc.LastRun = &e
+	if err := s.store.Put(scheduleKeyPrefix+id, sc); err != nil {
+		s.logger.Debugf("scheduler: put schedule %s: %v", id, err)
+	}
+}
+
+// Close stops the scheduling and waits for the running tasks, which are
+// cancelled.
+func (s *Scheduler) Close() error {
+	s.cancel()
+	close(s.quit)
+	s.wg.Wait()
+	return nil
+}
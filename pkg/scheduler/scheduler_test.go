@@ -0,0 +1,194 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scheduler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/scheduler"
+	"github.com/ethsana/sana/pkg/statestore/mock"
+)
+
+func TestExpression(t *testing.T) {
+	from := time.Date(2021, 6, 1, 10, 7, 30, 0, time.UTC) // a tuesday
+	for _, tc := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2021, 6, 1, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2021, 6, 1, 10, 15, 0, 0, time.UTC)},
+		{"0 9-17 * * *", time.Date(2021, 6, 1, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2021, 6, 2, 2, 30, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2021, 6, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2021, 6, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2021, 6, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * 5", time.Date(2021, 6, 4, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+	} {
+		e, err := scheduler.ParseExpression(tc.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.expr, err)
+		}
+		got, err := e.Next(from)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.expr, err)
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("%s: got %s, want %s", tc.expr, got, tc.want)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := scheduler.ParseExpression(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+
+	e, err := scheduler.ParseExpression("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Next(from); err == nil {
+		t.Fatal("expected error for expression without activation")
+	}
+}
+
+type validatingTask struct {
+	scheduler.TaskFunc
+}
+
+func (validatingTask) Validate(params json.RawMessage) error {
+	var p struct{ Peer string }
+	if err := json.Unmarshal(params, &p); err != nil || p.Peer == "" {
+		return errors.New("peer required")
+	}
+	return nil
+}
+
+func TestScheduler(t *testing.T) {
+	var (
+		logger  = logging.New(ioutil.Discard, 0)
+		store   = mock.NewStateStore()
+		now     = time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+		mu      sync.Mutex
+		runs    = make(chan string, 10)
+		failing = errors.New("failing")
+	)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+
+	s, err := scheduler.New(store, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetNow(clock)
+	s.Register("cashout", validatingTask{scheduler.TaskFunc(func(_ context.Context, params json.RawMessage) error {
+		runs <- string(params)
+		return failing
+	})})
+
+	if _, err := s.Create("unknown", "* * * * *", nil); !errors.Is(err, scheduler.ErrUnknownType) {
+		t.Fatalf("got error %v, want %v", err, scheduler.ErrUnknownType)
+	}
+	if _, err := s.Create("cashout", "* * * * *", json.RawMessage(`{}`)); !errors.Is(err, scheduler.ErrInvalid) {
+		t.Fatalf("got error %v, want %v", err, scheduler.ErrInvalid)
+	}
+	if _, err := s.Create("cashout", "* *", json.RawMessage(`{"peer":"ab"}`)); !errors.Is(err, scheduler.ErrInvalid) {
+		t.Fatalf("got error %v, want %v", err, scheduler.ErrInvalid)
+	}
+
+	sc, err := s.Create("cashout", "*/5 * * * *", json.RawMessage(`{"peer":"ab"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := now.Add(5 * time.Minute); !sc.NextRun.Equal(want) {
+		t.Fatalf("got next run %s, want %s", sc.NextRun, want)
+	}
+	if d := s.RunDue(); d != 5*time.Minute {
+		t.Fatalf("got wait %s, want 5m", d)
+	}
+
+	advance(5 * time.Minute)
+	s.RunDue()
+	select {
+	case p := <-runs:
+		if p != `{"peer":"ab"}` {
+			t.Fatalf("got params %s", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("task not run")
+	}
+
+	var h []scheduler.Execution
+	for i := 0; i < 100; i++ {
+		if h, err = s.History(sc.ID); err != nil {
+			t.Fatal(err)
+		}
+		if len(h) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(h) != 1 || h[0].Error != failing.Error() {
+		t.Fatalf("got history %+v", h)
+	}
+
+	// disabled schedules are not run
+	disabled := false
+	if _, err := s.Update(sc.ID, scheduler.Update{Enabled: &disabled}); err != nil {
+		t.Fatal(err)
+	}
+	advance(5 * time.Minute)
+	s.RunDue()
+	select {
+	case <-runs:
+		t.Fatal("disabled schedule run")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// schedules survive restarts
+	s, err = scheduler.New(store, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	list := s.List()
+	if len(list) != 1 || list[0].ID != sc.ID || list[0].Enabled || list[0].LastRun == nil {
+		t.Fatalf("got schedules %+v", list)
+	}
+	if h, err := s.History(sc.ID); err != nil || len(h) != 1 {
+		t.Fatalf("got history %+v, error %v", h, err)
+	}
+
+	if err := s.Delete(sc.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(sc.ID); !errors.Is(err, scheduler.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, scheduler.ErrNotFound)
+	}
+	if _, err := s.History(sc.ID); !errors.Is(err, scheduler.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, scheduler.ErrNotFound)
+	}
+}
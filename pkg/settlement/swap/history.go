@@ -0,0 +1,191 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package swap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+const (
+	historyBucketKeyPrefix = "swap_history_bucket_"
+	historyPeerKeyPrefix   = "swap_history_peer_"
+	// HistoryBucketDuration is the duration of the time buckets in which
+	// the settlement history is persisted.
+	HistoryBucketDuration = time.Hour
+)
+
+// HistoryBucket is the settlement activity with a peer in a time bucket. The
+// amounts are in tokens.
+type HistoryBucket struct {
+	Peer  swarm.Address `json:"peer"`
+	Start time.Time     `json:"start"`
+	// ChequesSent is the number of cheques issued to the peer.
+	ChequesSent uint64 `json:"chequesSent"`
+	// Sent is the amount of the cheques issued to the peer.
+	Sent *big.Int `json:"sent"`
+	// ChequesReceived is the number of cheques received from the peer.
+	ChequesReceived uint64 `json:"chequesReceived"`
+	// Received is the amount of the cheques received from the peer.
+	Received *big.Int `json:"received"`
+	// Cashouts is the number of cashouts of the cheques of the peer.
+	Cashouts uint64 `json:"cashouts"`
+	// Uncashed is the received amount which was not cashed out at the end of
+	// the bucket.
+	Uncashed *big.Int `json:"uncashed"`
+}
+
+// merge adds the activity of the later bucket o.
+func (b *HistoryBucket) merge(o HistoryBucket) {
+	b.ChequesSent += o.ChequesSent
+	b.Sent = new(big.Int).Add(b.Sent, o.Sent)
+	b.ChequesReceived += o.ChequesReceived
+	b.Received = new(big.Int).Add(b.Received, o.Received)
+	b.Cashouts += o.Cashouts
+	b.Uncashed = o.Uncashed
+}
+
+// historyPeer is the cumulative settlement state of a peer from which the
+// amounts of the buckets are derived.
+type historyPeer struct {
+	Sent     *big.Int `json:"sent"`
+	Received *big.Int `json:"received"`
+	Cashed   *big.Int `json:"cashed"`
+}
+
+func historyBucketKey(peer swarm.Address, start time.Time) string {
+	return fmt.Sprintf("%s%s_%020d", historyBucketKeyPrefix, peer, start.Unix())
+}
+
+func historyPeerKey(peer swarm.Address) string {
+	return historyPeerKeyPrefix + peer.String()
+}
+
+// recordHistory applies the update to the cumulative state of the peer and
+// to its current bucket.
+func (s *Service) recordHistory(peer swarm.Address, update func(p *historyPeer, b *HistoryBucket)) error {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	p := historyPeer{Sent: new(big.Int), Received: new(big.Int), Cashed: new(big.Int)}
+	if err := s.store.Get(historyPeerKey(peer), &p); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	start := s.timeNow().UTC().Truncate(HistoryBucketDuration)
+	key := historyBucketKey(peer, start)
+	b := HistoryBucket{Peer: peer, Start: start, Sent: new(big.Int), Received: new(big.Int)}
+	if err := s.store.Get(key, &b); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	update(&p, &b)
+	b.Uncashed = new(big.Int).Sub(p.Received, p.Cashed)
+	if b.Uncashed.Sign() < 0 {
+		b.Uncashed.SetInt64(0)
+	}
+
+	if err := s.store.Put(historyPeerKey(peer), p); err != nil {
+		return err
+	}
+	return s.store.Put(key, b)
+}
+
+// recordSent records the cheque issued to the peer with the cumulative
+// payout.
+func (s *Service) recordSent(peer swarm.Address, cumulativePayout *big.Int) error {
+	return s.recordHistory(peer, func(p *historyPeer, b *HistoryBucket) {
+		if amount := new(big.Int).Sub(cumulativePayout, p.Sent); amount.Sign() > 0 {
+			b.Sent.Add(b.Sent, amount)
+		}
+		b.ChequesSent++
+		p.Sent = new(big.Int).Set(cumulativePayout)
+	})
+}
+
+// recordReceived records the cheque with the cumulative payout received
+// from the peer.
+func (s *Service) recordReceived(peer swarm.Address, amount, cumulativePayout *big.Int) error {
+	return s.recordHistory(peer, func(p *historyPeer, b *HistoryBucket) {
+		b.Received.Add(b.Received, amount)
+		b.ChequesReceived++
+		p.Received = new(big.Int).Set(cumulativePayout)
+	})
+}
+
+// recordCashout records the cashout of the cheques received from the peer.
+// The cashed amount is the one of the cheques received up to the cashout, as
+// the last received cheque is cashed.
+func (s *Service) recordCashout(peer swarm.Address) error {
+	return s.recordHistory(peer, func(p *historyPeer, b *HistoryBucket) {
+		b.Cashouts++
+		p.Cashed = new(big.Int).Set(p.Received)
+	})
+}
+
+// SettlementHistory returns the settlement history with the peer, or with
+// all peers if it is the zero address, in the buckets which start in the
+// [from, to) interval. The persisted buckets are merged into buckets of the
+// period, a multiple of HistoryBucketDuration. The buckets are ordered by
+// their start and by the peers.
+func (s *Service) SettlementHistory(peer swarm.Address, from, to time.Time, period time.Duration) ([]HistoryBucket, error) {
+	if period < HistoryBucketDuration || period%HistoryBucketDuration != 0 {
+		return nil, fmt.Errorf("invalid history period %v", period)
+	}
+
+	prefix := historyBucketKeyPrefix
+	if !peer.IsZero() {
+		prefix += peer.String() + "_"
+	}
+
+	var stored []HistoryBucket
+	err := s.store.Iterate(prefix, func(key, value []byte) (bool, error) {
+		if !strings.HasPrefix(string(key), prefix) {
+			return true, nil
+		}
+		var b HistoryBucket
+		if err := json.Unmarshal(value, &b); err != nil {
+			return true, fmt.Errorf("invalid history bucket %s: %w", key, err)
+		}
+		if !b.Start.Before(from) && b.Start.Before(to) {
+			stored = append(stored, b)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].Start.Before(stored[j].Start)
+	})
+
+	var buckets []HistoryBucket
+	index := make(map[string]int)
+	for _, b := range stored {
+		b.Start = b.Start.Truncate(period)
+		k := fmt.Sprintf("%s_%d", b.Peer, b.Start.Unix())
+		if i, ok := index[k]; ok {
+			buckets[i].merge(b)
+			continue
+		}
+		index[k] = len(buckets)
+		buckets = append(buckets, b)
+	}
+	sort.SliceStable(buckets, func(i, j int) bool {
+		if !buckets[i].Start.Equal(buckets[j].Start) {
+			return buckets[i].Start.Before(buckets[j].Start)
+		}
+		return buckets[i].Peer.String() < buckets[j].Peer.String()
+	})
+	return buckets, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package swap_test
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/settlement/swap"
+	"github.com/ethsana/sana/pkg/settlement/swap/chequebook"
+	mockchequebook "github.com/ethsana/sana/pkg/settlement/swap/chequebook/mock"
+	mockchequestore "github.com/ethsana/sana/pkg/settlement/swap/chequestore/mock"
+	"github.com/ethsana/sana/pkg/settlement/swap/swapprotocol"
+	mockstore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+func TestSettlementHistory(t *testing.T) {
+	peer := swarm.MustParseHexAddress("abcd")
+	otherPeer := swarm.MustParseHexAddress("bcde")
+	chequebookAddress := common.HexToAddress("0xcd")
+	beneficiary := common.HexToAddress("0xab")
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	var (
+		received *big.Int // the amount of the received cheque
+		sent     *big.Int // the cumulative payout of the issued cheque
+	)
+	addressbook := &addressbookMock{
+		chequebook: func(swarm.Address) (common.Address, bool, error) {
+			return chequebookAddress, true, nil
+		},
+		beneficiary: func(swarm.Address) (common.Address, bool, error) {
+			return beneficiary, true, nil
+		},
+	}
+	observer := newTestObserver()
+	swapService := swap.New(
+		&swapProtocolMock{
+			emitCheque: func(context.Context, swarm.Address, common.Address, *big.Int, swapprotocol.IssueFunc) (*big.Int, error) {
+				return big.NewInt(0), nil
+			},
+		},
+		logging.New(ioutil.Discard, 0),
+		mockstore.NewStateStore(),
+		mockchequebook.NewChequebook(
+			mockchequebook.WithLastChequeFunc(func(common.Address) (*chequebook.SignedCheque, error) {
+				return &chequebook.SignedCheque{Cheque: chequebook.Cheque{CumulativePayout: sent}}, nil
+			}),
+		),
+		mockchequestore.NewChequeStore(
+			mockchequestore.WithReceiveChequeFunc(func(context.Context, *chequebook.SignedCheque, *big.Int, *big.Int) (*big.Int, error) {
+				return received, nil
+			}),
+		),
+		addressbook,
+		uint64(1),
+		&cashoutMock{
+			cashCheque: func(context.Context, common.Address, common.Address) (common.Hash, error) {
+				return common.HexToHash("eeee"), nil
+			},
+		},
+		observer,
+	)
+
+	receive := func(p swarm.Address, at time.Duration, amount, cumulativePayout int64) {
+		t.Helper()
+		swapService.SetTimeNow(func() time.Time { return start.Add(at) })
+		received = big.NewInt(amount)
+		cheque := &chequebook.SignedCheque{Cheque: chequebook.Cheque{Chequebook: chequebookAddress, CumulativePayout: big.NewInt(cumulativePayout)}}
+		if err := swapService.ReceiveCheque(context.Background(), p, cheque, big.NewInt(1), big.NewInt(0)); err != nil {
+			t.Fatal(err)
+		}
+		<-observer.receivedCalled
+	}
+	pay := func(p swarm.Address, at time.Duration, cumulativePayout int64) {
+		t.Helper()
+		swapService.SetTimeNow(func() time.Time { return start.Add(at) })
+		sent = big.NewInt(cumulativePayout)
+		swapService.Pay(context.Background(), p, big.NewInt(1))
+		<-observer.sentCalled
+	}
+
+	receive(peer, 0, 100, 100)
+	receive(peer, 30*time.Minute, 50, 150)
+	pay(peer, time.Hour, 70)
+	swapService.SetTimeNow(func() time.Time { return start.Add(time.Hour) })
+	if _, err := swapService.CashCheque(context.Background(), peer); err != nil {
+		t.Fatal(err)
+	}
+	receive(peer, 2*time.Hour, 50, 200)
+	receive(otherPeer, 2*time.Hour, 10, 10)
+
+	t.Run("hourly", func(t *testing.T) {
+		buckets, err := swapService.SettlementHistory(peer, start, start.Add(24*time.Hour), swap.HistoryBucketDuration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []swap.HistoryBucket{
+			{Peer: peer, Start: start, Sent: big.NewInt(0), ChequesReceived: 2, Received: big.NewInt(150), Uncashed: big.NewInt(150)},
+			{Peer: peer, Start: start.Add(time.Hour), ChequesSent: 1, Sent: big.NewInt(70), Received: big.NewInt(0), Cashouts: 1, Uncashed: big.NewInt(0)},
+			{Peer: peer, Start: start.Add(2 * time.Hour), Sent: big.NewInt(0), ChequesReceived: 1, Received: big.NewInt(50), Uncashed: big.NewInt(50)},
+		}
+		assertHistory(t, buckets, want)
+	})
+
+	t.Run("daily of all peers", func(t *testing.T) {
+		buckets, err := swapService.SettlementHistory(swarm.ZeroAddress, start, start.Add(24*time.Hour), 24*time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []swap.HistoryBucket{
+			{Peer: peer, Start: start, ChequesSent: 1, Sent: big.NewInt(70), ChequesReceived: 3, Received: big.NewInt(200), Cashouts: 1, Uncashed: big.NewInt(50)},
+			{Peer: otherPeer, Start: start, Sent: big.NewInt(0), ChequesReceived: 1, Received: big.NewInt(10), Uncashed: big.NewInt(10)},
+		}
+		assertHistory(t, buckets, want)
+	})
+
+	t.Run("interval", func(t *testing.T) {
+		buckets, err := swapService.SettlementHistory(peer, start.Add(time.Hour), start.Add(2*time.Hour), swap.HistoryBucketDuration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(buckets) != 1 || !buckets[0].Start.Equal(start.Add(time.Hour)) {
+			t.Fatalf("got buckets %v, want the one of the second hour", buckets)
+		}
+	})
+
+	t.Run("invalid period", func(t *testing.T) {
+		if _, err := swapService.SettlementHistory(peer, start, start.Add(time.Hour), time.Minute); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func assertHistory(t *testing.T, got, want []swap.HistoryBucket) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d buckets, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		if !g.Peer.Equal(w.Peer) || !g.Start.Equal(w.Start) ||
+			g.ChequesSent != w.ChequesSent || g.Sent.Cmp(w.Sent) != 0 ||
+			g.ChequesReceived != w.ChequesReceived || g.Received.Cmp(w.Received) != 0 ||
+			g.Cashouts != w.Cashouts || g.Uncashed.Cmp(w.Uncashed) != 0 {
+			t.Fatalf("bucket %d: got %+v, want %+v", i, g, w)
+		}
+	}
+}
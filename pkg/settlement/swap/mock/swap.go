@@ -7,6 +7,7 @@ package mock
 import (
 	"context"
 	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 
@@ -40,6 +41,8 @@ type Service struct {
 
 	cashChequeFunc    func(ctx context.Context, peer swarm.Address) (common.Hash, error)
 	cashoutStatusFunc func(ctx context.Context, peer swarm.Address) (*chequebook.CashoutStatus, error)
+
+	settlementHistoryFunc func(peer swarm.Address, from, to time.Time, period time.Duration) ([]swap.HistoryBucket, error)
 }
 
 // WithsettlementFunc sets the mock settlement function
@@ -122,6 +125,12 @@ func WithCashoutStatusFunc(f func(ctx context.Context, peer swarm.Address) (*che
 	})
 }
 
+func WithSettlementHistoryFunc(f func(peer swarm.Address, from, to time.Time, period time.Duration) ([]swap.HistoryBucket, error)) Option {
+	return optionFunc(func(s *Service) {
+		s.settlementHistoryFunc = f
+	})
+}
+
 // New creates the mock swap implementation
 func New(opts ...Option) swap.Interface {
 	mock := new(Service)
@@ -247,6 +256,13 @@ func (s *Service) CashoutStatus(ctx context.Context, peer swarm.Address) (*chequ
 	return nil, nil
 }
 
+func (s *Service) SettlementHistory(peer swarm.Address, from, to time.Time, period time.Duration) ([]swap.HistoryBucket, error) {
+	if s.settlementHistoryFunc != nil {
+		return s.settlementHistoryFunc(peer, from, to, period)
+	}
+	return nil, nil
+}
+
 func (s *Service) ReceiveCheque(ctx context.Context, peer swarm.Address, cheque *chequebook.SignedCheque, exchangeRate, deduction *big.Int) (err error) {
 	defer func() {
 		if err == nil {
@@ -9,6 +9,8 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethsana/sana/pkg/logging"
@@ -42,6 +44,8 @@ type Interface interface {
 	CashCheque(ctx context.Context, peer swarm.Address) (common.Hash, error)
 	// CashoutStatus gets the status of the latest cashout transaction for the peers chequebook
 	CashoutStatus(ctx context.Context, peer swarm.Address) (*chequebook.CashoutStatus, error)
+	// SettlementHistory returns the settlement history with the peer, or with all peers if it is the zero address
+	SettlementHistory(peer swarm.Address, from, to time.Time, period time.Duration) ([]HistoryBucket, error)
 }
 
 // Service is the implementation of the swap settlement layer.
@@ -56,6 +60,8 @@ type Service struct {
 	cashout     chequebook.CashoutService
 	addressbook Addressbook
 	networkID   uint64
+	historyMu   sync.Mutex // serializes the updates of the settlement history
+	timeNow     func() time.Time
 }
 
 // New creates a new swap Service.
@@ -71,6 +77,7 @@ func New(proto swapprotocol.Interface, logger logging.Logger, store storage.Stat
 		networkID:   networkID,
 		cashout:     cashout,
 		accounting:  accounting,
+		timeNow:     time.Now,
 	}
 }
 
@@ -112,6 +119,10 @@ func (s *Service) ReceiveCheque(ctx context.Context, peer swarm.Address, cheque
 	s.metrics.TotalReceived.Add(tot)
 	s.metrics.ChequesReceived.Inc()
 
+	if err := s.recordReceived(peer, receivedAmount, cheque.CumulativePayout); err != nil {
+		s.logger.Debugf("swap: record settlement history of peer %s: %v", peer, err)
+	}
+
 	return s.accounting.NotifyPaymentReceived(peer, amount)
 }
 
@@ -144,6 +155,12 @@ func (s *Service) Pay(ctx context.Context, peer swarm.Address, amount *big.Int)
 	amountFloat, _ := big.NewFloat(0).SetInt(amount).Float64()
 	s.metrics.TotalSent.Add(amountFloat)
 	s.metrics.ChequesSent.Inc()
+
+	if cheque, err := s.chequebook.LastCheque(beneficiary); err == nil {
+		if err := s.recordSent(peer, cheque.CumulativePayout); err != nil {
+			s.logger.Debugf("swap: record settlement history of peer %s: %v", peer, err)
+		}
+	}
 }
 
 func (s *Service) SetAccounting(accounting settlement.Accounting) {
@@ -334,7 +351,14 @@ func (s *Service) CashCheque(ctx context.Context, peer swarm.Address) (common.Ha
 	if !known {
 		return common.Hash{}, chequebook.ErrNoCheque
 	}
-	return s.cashout.CashCheque(ctx, chequebookAddress, s.chequebook.Address())
+	txHash, err := s.cashout.CashCheque(ctx, chequebookAddress, s.chequebook.Address())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := s.recordCashout(peer); err != nil {
+		s.logger.Debugf("swap: record settlement history of peer %s: %v", peer, err)
+	}
+	return txHash, nil
 }
 
 // CashoutStatus gets the status of the latest cashout transaction for the peers chequebook
@@ -0,0 +1,59 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package swap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+// CashoutTaskType is the scheduler task type which cashes the last cheque
+// received from a peer.
+const CashoutTaskType = "chequebook-cashout"
+
+type cashoutParams struct {
+	Peer string `json:"peer"`
+}
+
+// CashoutTask is a scheduler task cashing the last cheque of the peer in its
+// parameters, for example {"peer": "<overlay>"}.
+type CashoutTask struct {
+	Swap Interface
+}
+
+func parseCashoutParams(params json.RawMessage) (swarm.Address, error) {
+	var p cashoutParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return swarm.ZeroAddress, fmt.Errorf("cashout: invalid parameters: %w", err)
+	}
+	if p.Peer == "" {
+		return swarm.ZeroAddress, errors.New("cashout: peer is required")
+	}
+	peer, err := swarm.ParseHexAddress(p.Peer)
+	if err != nil {
+		return swarm.ZeroAddress, fmt.Errorf("cashout: invalid peer: %w", err)
+	}
+	return peer, nil
+}
+
+// Validate implements the scheduler.Task interface.
+func (t CashoutTask) Validate(params json.RawMessage) error {
+	_, err := parseCashoutParams(params)
+	return err
+}
+
+// Run implements the scheduler.Task interface.
+func (t CashoutTask) Run(ctx context.Context, params json.RawMessage) error {
+	peer, err := parseCashoutParams(params)
+	if err != nil {
+		return err
+	}
+	_, err = t.Swap.CashCheque(ctx, peer)
+	return err
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package snapshot
+
+import (
+	m "github.com/ethsana/sana/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	ImportedChunks prometheus.Counter
+	RejectedChunks prometheus.Counter
+	FailedImports  prometheus.Counter
+	Exports        prometheus.Counter
+	ExportedChunks prometheus.Counter
+}
+
+func newMetrics() metrics {
+	subsystem := "snapshot"
+
+	return metrics{
+		ImportedChunks: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "imported_chunks",
+			Help:      "Total chunks imported from the snapshots.",
+		}),
+		RejectedChunks: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "rejected_chunks",
+			Help:      "Total chunks of the snapshots not imported for their invalid stamps.",
+		}),
+		FailedImports: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "failed_imports",
+			Help:      "Total imports of the snapshots which failed from all the sources.",
+		}),
+		Exports: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "exports",
+			Help:      "Total snapshots exported.",
+		}),
+		ExportedChunks: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "exported_chunks",
+			Help:      "Total chunks of the exported snapshots.",
+		}),
+	}
+}
+
+func (s *Service) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(s.metrics)
+}
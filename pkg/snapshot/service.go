@@ -0,0 +1,745 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package snapshot
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/p2p"
+	"github.com/ethsana/sana/pkg/p2p/protobuf"
+	"github.com/ethsana/sana/pkg/snapshot/pb"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+const (
+	protocolName    = "snapshot"
+	protocolVersion = "1.0.0"
+	streamName      = "snapshot"
+
+	keyImported = "snapshot_imported"
+
+	dataMessageSize = 64 * 1024 // snapshot bytes per message, within the protobuf size limit
+	putBatchSize    = 64
+	maxAttempts     = 3 // attempts per source before the next one is tried
+	retryInterval   = 10 * time.Second
+)
+
+// The states of the import of the snapshot.
+const (
+	StateDisabled  = "disabled"
+	StatePending   = "pending"
+	StateImporting = "importing"
+	StateCompleted = "completed"
+	StateSkipped   = "skipped"
+	StateFailed    = "failed"
+)
+
+var (
+	// ErrUntrustedSigner is returned if the snapshot downloaded over HTTP(S)
+	// is not signed by one of the trusted signers.
+	ErrUntrustedSigner = errors.New("snapshot: untrusted signer")
+	// ErrNeighborhood is returned if the snapshot is of a neighborhood which
+	// the node is not in, or is requested by a peer outside the neighborhood
+	// of the node.
+	ErrNeighborhood = errors.New("snapshot: other neighborhood")
+	// ErrBusy is returned if a snapshot is already being exported.
+	ErrBusy = errors.New("snapshot: export in progress")
+	// ErrTooLarge is returned if the snapshot is larger than the maximum
+	// size of the snapshots.
+	ErrTooLarge = errors.New("snapshot: too large")
+)
+
+// Storer is the localstore which the snapshots are exported from and
+// imported into.
+type Storer interface {
+	storage.Getter
+	storage.Putter
+	storage.PullSubscriber
+	LastPullSubscriptionBinID(bin uint8) (id uint64, err error)
+}
+
+// Progress is the progress of the import of the snapshot.
+type Progress struct {
+	State string `json:"state"`
+	// Source is the source of the snapshot which is or was imported.
+	Source string         `json:"source,omitempty"`
+	Signer common.Address `json:"signer"`
+	// Chunks are the imported chunks, Rejected are the chunks with invalid
+	// stamps which are not imported, such as the ones of expired batches.
+	Chunks   uint64    `json:"chunks"`
+	Rejected uint64    `json:"rejected"`
+	Bytes    uint64    `json:"bytes"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Options are the options of the Service.
+type Options struct {
+	// Sources are the HTTP(S) URLs of the snapshots or the overlay
+	// addresses of the peers which serve them, tried in order.
+	Sources []string
+	// Signers are trusted to sign the snapshots downloaded over HTTP(S).
+	// The snapshots of the peers are trusted as the ones of the sources.
+	Signers []common.Address
+	// TempDir is the directory into which the snapshots are downloaded
+	// before they are imported, the default directory for temporary files
+	// if empty.
+	TempDir string
+	// MaxSize is the maximum size of the snapshots which are downloaded into
+	// the temporary directory, unlimited if 0.
+	MaxSize int64
+}
+
+// Service serves the snapshots of the neighborhoods of the peers and imports
+// the snapshot of the neighborhood of the node from the sources.
+type Service struct {
+	streamer   p2p.Streamer
+	storer     Storer
+	store      storage.StateStorer
+	signer     crypto.Signer
+	overlay    swarm.Address
+	depth      func() uint8
+	validStamp func(swarm.Chunk, []byte) (swarm.Chunk, error)
+	logger     logging.Logger
+	metrics    metrics
+	client     *http.Client
+
+	sources []string
+	signers map[common.Address]struct{}
+	tempDir string
+	maxSize int64
+
+	exporting chan struct{} // holds a token while a snapshot is exported
+
+	mu       sync.Mutex
+	progress Progress
+
+	done chan struct{}
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New returns a new Service. The depth is the neighborhood depth of the node,
+// within which the snapshot is imported and the snapshots are served to the
+// peers.
+func New(streamer p2p.Streamer, storer Storer, store storage.StateStorer, signer crypto.Signer, overlay swarm.Address, depth func() uint8, validStamp func(swarm.Chunk, []byte) (swarm.Chunk, error), logger logging.Logger, o Options) *Service {
+	s := &Service{
+		streamer:   streamer,
+		storer:     storer,
+		store:      store,
+		signer:     signer,
+		overlay:    overlay,
+		depth:      depth,
+		validStamp: validStamp,
+		logger:     logger,
+		metrics:    newMetrics(),
+		client:     &http.Client{},
+		sources:    o.Sources,
+		signers:    make(map[common.Address]struct{}, len(o.Signers)),
+		tempDir:    o.TempDir,
+		maxSize:    o.MaxSize,
+		exporting:  make(chan struct{}, 1),
+		progress:   Progress{State: StateDisabled},
+		done:       make(chan struct{}),
+		quit:       make(chan struct{}),
+	}
+	for _, a := range o.Signers {
+		s.signers[a] = struct{}{}
+	}
+	if len(s.sources) > 0 {
+		s.progress.State = StatePending
+	}
+	return s
+}
+
+// Protocol returns the protocol serving the snapshots.
+func (s *Service) Protocol() p2p.ProtocolSpec {
+	return p2p.ProtocolSpec{
+		Name:    protocolName,
+		Version: protocolVersion,
+		StreamSpecs: []p2p.StreamSpec{
+			{
+				Name:    streamName,
+				Handler: s.handler,
+			},
+		},
+	}
+}
+
+// Start imports the snapshot after the warmup, in the neighborhood at the
+// depth then, if the node has no chunks and has not imported a snapshot
+// before. The Done channel is closed once the import is finished, whether
+// it succeeded or not.
+func (s *Service) Start(warmup time.Duration) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(s.done)
+
+		if len(s.sources) == 0 {
+			return
+		}
+		fresh, err := s.fresh()
+		if err != nil {
+			s.logger.Debugf("snapshot: check localstore: %v", err)
+			s.logger.Error("snapshot: unable to check the localstore, fast sync skipped")
+			s.finish(StateFailed, err)
+			return
+		}
+		if !fresh {
+			s.logger.Info("snapshot: localstore not empty, fast sync skipped")
+			s.finish(StateSkipped, nil)
+			return
+		}
+
+		select {
+		case <-time.After(warmup):
+		case <-s.quit:
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-s.quit:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		s.mu.Lock()
+		s.progress.State = StateImporting
+		s.progress.Started = time.Now()
+		s.mu.Unlock()
+
+		if err := s.importSources(ctx, s.depth()); err != nil {
+			s.metrics.FailedImports.Inc()
+			s.logger.Debugf("snapshot: import: %v", err)
+			s.logger.Warning("snapshot: fast sync failed, continuing with the pull sync")
+			s.finish(StateFailed, err)
+			return
+		}
+		if err := s.store.Put(keyImported, time.Now().Unix()); err != nil {
+			s.logger.Debugf("snapshot: mark imported: %v", err)
+		}
+		p := s.finish(StateCompleted, nil)
+		s.logger.Infof("snapshot: imported %d chunks from %s, continuing with the pull sync", p.Chunks, p.Source)
+	}()
+}
+
+// Done returns the channel which is closed once the import of the snapshot
+// is finished.
+func (s *Service) Done() <-chan struct{} {
+	return s.done
+}
+
+// Progress returns the progress of the import of the snapshot.
+func (s *Service) Progress() Progress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.progress
+}
+
+func (s *Service) finish(state string, err error) Progress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progress.State = state
+	s.progress.Finished = time.Now()
+	if err != nil {
+		s.progress.Error = err.Error()
+	}
+	return s.progress
+}
+
+// fresh reports whether the node has not imported a snapshot before and has
+// no chunks in its localstore.
+func (s *Service) fresh() (bool, error) {
+	var imported int64
+	switch err := s.store.Get(keyImported, &imported); {
+	case err == nil:
+		return false, nil
+	case !errors.Is(err, storage.ErrNotFound):
+		return false, err
+	}
+	for bin := uint8(0); bin <= swarm.MaxPO; bin++ {
+		id, err := s.storer.LastPullSubscriptionBinID(bin)
+		if err != nil {
+			return false, err
+		}
+		if id > 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// importSources imports the snapshot from the first source which serves a
+// valid one.
+func (s *Service) importSources(ctx context.Context, depth uint8) (err error) {
+	for _, source := range s.sources {
+		for attempt := 1; attempt <= maxAttempts; attempt++ {
+			s.mu.Lock()
+			s.progress.Source = source
+			s.progress.Error = ""
+			s.mu.Unlock()
+
+			err = s.importSource(ctx, source, depth)
+			if err == nil {
+				return nil
+			}
+			s.logger.Debugf("snapshot: import from %s, attempt %d: %v", source, attempt, err)
+			if errors.Is(err, context.Canceled) {
+				return err
+			}
+			if permanent(err) {
+				break
+			}
+			select {
+			case <-time.After(retryInterval):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		s.logger.Warningf("snapshot: unable to import from %s: %v", source, err)
+	}
+	return err
+}
+
+// permanent reports whether the import from the source fails with the error
+// in further attempts too.
+func permanent(err error) bool {
+	for _, e := range []error{ErrUntrustedSigner, ErrInvalidSignature, ErrNeighborhood, ErrUnsupportedVersion, ErrInvalidChunk, ErrTooLarge} {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// importSource imports the snapshot from the HTTP(S) URL or the overlay
+// address of the peer. The snapshot is downloaded into a temporary file and
+// verified in full, up to the signature over all the chunks, before any of
+// its chunks is imported. The snapshots larger than the maximum size are not
+// downloaded further.
+func (s *Service) importSource(ctx context.Context, source string, depth uint8) error {
+	var (
+		r    io.ReadCloser
+		peer bool
+		err  error
+	)
+	if strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://") {
+		r, err = s.download(ctx, source)
+	} else {
+		a, perr := swarm.ParseHexAddress(source)
+		if perr != nil {
+			return fmt.Errorf("invalid source %q", source)
+		}
+		r, err = s.request(ctx, a, depth)
+		peer = true
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	f, err := ioutil.TempFile(s.tempDir, "snapshot-*.gz")
+	if err != nil {
+		return fmt.Errorf("temporary file: %w", err)
+	}
+	defer func() {
+		f.Close()
+		if err := os.Remove(f.Name()); err != nil {
+			s.logger.Debugf("snapshot: remove %s: %v", f.Name(), err)
+		}
+	}()
+
+	var src io.Reader = r
+	limited := &io.LimitedReader{R: r, N: s.maxSize + 1}
+	if s.maxSize > 0 {
+		src = limited
+	}
+	if err := s.verifySnapshot(io.TeeReader(&countingReader{r: src, n: func(n int) {
+		s.mu.Lock()
+		s.progress.Bytes += uint64(n)
+		s.mu.Unlock()
+	}}, f), peer); err != nil {
+		if s.maxSize > 0 && limited.N == 0 {
+			return fmt.Errorf("%w: over %d bytes", ErrTooLarge, s.maxSize)
+		}
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("temporary file: %w", err)
+	}
+	return s.importSnapshot(ctx, bufio.NewReader(f))
+}
+
+// verifySnapshot reads the whole snapshot and verifies its signer, its
+// neighborhood, its chunks, which must be within the neighborhood, and the
+// signature over them. The signers of the
+// snapshots of the peers, which are authenticated by the p2p connection, are
+// not verified.
+func (s *Service) verifySnapshot(r io.Reader, peer bool) error {
+	sr, err := NewReader(r)
+	if err != nil {
+		return err
+	}
+	signer := sr.Signer()
+	if _, ok := s.signers[signer]; !ok && !peer {
+		return fmt.Errorf("%w %s", ErrUntrustedSigner, signer)
+	}
+	h := sr.Header()
+	if swarm.Proximity(h.Overlay.Bytes(), s.overlay.Bytes()) < h.Depth {
+		return fmt.Errorf("%w %s at depth %d", ErrNeighborhood, h.Overlay, h.Depth)
+	}
+
+	s.mu.Lock()
+	s.progress.Signer = signer
+	s.mu.Unlock()
+
+	for {
+		ch, err := sr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkNeighborhood(h, ch); err != nil {
+			return err
+		}
+	}
+	// the rest of the stream, the end of the compression, is read into the
+	// temporary file too
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	return nil
+}
+
+// importSnapshot imports the chunks of the verified snapshot with valid
+// stamps into the localstore.
+func (s *Service) importSnapshot(ctx context.Context, r io.Reader) error {
+	sr, err := NewReader(r)
+	if err != nil {
+		return err
+	}
+
+	batch := make([]swarm.Chunk, 0, putBatchSize)
+	put := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := s.storer.Put(ctx, storage.ModePutSync, batch...); err != nil {
+			return fmt.Errorf("put chunks: %w", err)
+		}
+		s.metrics.ImportedChunks.Add(float64(len(batch)))
+		s.mu.Lock()
+		s.progress.Chunks += uint64(len(batch))
+		s.mu.Unlock()
+		batch = batch[:0]
+		return nil
+	}
+	for {
+		ch, err := sr.Next()
+		if errors.Is(err, io.EOF) {
+			return put()
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkNeighborhood(sr.Header(), ch); err != nil {
+			return err
+		}
+		stamp, err := ch.Stamp().MarshalBinary()
+		if err != nil {
+			return err
+		}
+		valid, err := s.validStamp(ch, stamp)
+		if err != nil {
+			s.logger.Debugf("snapshot: chunk %s: invalid stamp: %v", ch.Address(), err)
+			s.metrics.RejectedChunks.Inc()
+			s.mu.Lock()
+			s.progress.Rejected++
+			s.mu.Unlock()
+			continue
+		}
+		if batch = append(batch, valid); len(batch) == putBatchSize {
+			if err := put(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkNeighborhood returns ErrInvalidChunk if the chunk is outside the
+// neighborhood of the snapshot.
+func checkNeighborhood(h Header, ch swarm.Chunk) error {
+	if swarm.Proximity(ch.Address().Bytes(), h.Overlay.Bytes()) < h.Depth {
+		return fmt.Errorf("%w %s outside the neighborhood", ErrInvalidChunk, ch.Address())
+	}
+	return nil
+}
+
+// download returns the snapshot at the url.
+func (s *Service) download(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download: %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// request returns the snapshot of the neighborhood of the node at the depth
+// served by the peer.
+func (s *Service) request(ctx context.Context, peer swarm.Address, depth uint8) (io.ReadCloser, error) {
+	stream, err := s.streamer.NewStream(ctx, peer, nil, protocolName, protocolVersion, streamName)
+	if err != nil {
+		return nil, fmt.Errorf("new stream: %w", err)
+	}
+	w, r := protobuf.NewWriterAndReader(stream)
+	if err := w.WriteMsgWithContext(ctx, &pb.Request{Depth: uint32(depth)}); err != nil {
+		_ = stream.Reset()
+		return nil, fmt.Errorf("write request: %w", err)
+	}
+	// the peer refuses the request in the first message
+	dr := &dataReader{ctx: ctx, stream: stream, r: r}
+	if _, err := dr.Read(nil); err != nil && !errors.Is(err, io.EOF) {
+		_ = stream.Reset()
+		return nil, err
+	}
+	return dr, nil
+}
+
+// handler writes the snapshot of the neighborhood of the peer, if the peer is
+// in the neighborhood of the node, at least at the depth of the node.
+func (s *Service) handler(ctx context.Context, p p2p.Peer, stream p2p.Stream) (err error) {
+	w, r := protobuf.NewWriterAndReader(stream)
+	defer func() {
+		if err != nil {
+			_ = stream.Reset()
+		} else {
+			_ = stream.FullClose()
+		}
+	}()
+
+	var req pb.Request
+	if err := r.ReadMsgWithContext(ctx, &req); err != nil {
+		return fmt.Errorf("read request: %w", err)
+	}
+	if req.Depth > uint32(swarm.MaxPO) {
+		return fmt.Errorf("invalid depth %d", req.Depth)
+	}
+	depth := uint8(req.Depth)
+	if d := s.depth(); depth < d {
+		depth = d
+	}
+	if swarm.Proximity(p.Address.Bytes(), s.overlay.Bytes()) < depth {
+		s.logger.Debugf("snapshot: peer %s outside the neighborhood at depth %d", p.Address, depth)
+		if err := w.WriteMsgWithContext(ctx, &pb.Data{Err: ErrNeighborhood.Error()}); err != nil {
+			return fmt.Errorf("write data: %w", err)
+		}
+		return nil
+	}
+
+	dw := &dataWriter{ctx: ctx, w: w}
+	bw := bufio.NewWriterSize(dw, dataMessageSize)
+	count, err := s.Export(ctx, bw, p.Address, depth)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		s.logger.Debugf("snapshot: export for peer %s: %v", p.Address, err)
+		if dw.err != nil {
+			return dw.err
+		}
+		if err := w.WriteMsgWithContext(ctx, &pb.Data{Err: err.Error()}); err != nil {
+			return fmt.Errorf("write data: %w", err)
+		}
+		return nil
+	}
+	if err := w.WriteMsgWithContext(ctx, &pb.Data{}); err != nil {
+		return fmt.Errorf("write data: %w", err)
+	}
+	s.logger.Debugf("snapshot: exported %d chunks for peer %s at depth %d", count, p.Address, depth)
+	return nil
+}
+
+// Export writes the snapshot of the chunks within the depth of the overlay
+// address and returns the number of the chunks. Only one snapshot is
+// exported at a time, ErrBusy is returned for the others.
+func (s *Service) Export(ctx context.Context, w io.Writer, overlay swarm.Address, depth uint8) (count uint64, err error) {
+	select {
+	case s.exporting <- struct{}{}:
+		defer func() { <-s.exporting }()
+	default:
+		return 0, ErrBusy
+	}
+
+	sw, err := NewWriter(w, s.signer, Header{
+		Overlay: overlay,
+		Depth:   depth,
+		Created: time.Now().Unix(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	for bin := uint8(0); bin <= swarm.MaxPO; bin++ {
+		if err := s.exportBin(ctx, sw, bin, overlay, depth); err != nil {
+			return 0, fmt.Errorf("bin %d: %w", bin, err)
+		}
+	}
+	if err := sw.Close(); err != nil {
+		return 0, err
+	}
+	s.metrics.Exports.Inc()
+	s.metrics.ExportedChunks.Add(float64(sw.Count()))
+	return sw.Count(), nil
+}
+
+// exportBin writes the chunks of the bin of the localstore which are within
+// the depth of the overlay address.
+func (s *Service) exportBin(ctx context.Context, sw *Writer, bin uint8, overlay swarm.Address, depth uint8) error {
+	until, err := s.storer.LastPullSubscriptionBinID(bin)
+	if err != nil {
+		return err
+	}
+	if until == 0 {
+		return nil
+	}
+	descriptors, _, stop := s.storer.SubscribePull(ctx, bin, 0, until)
+	defer stop()
+	for {
+		select {
+		case d, ok := <-descriptors:
+			if !ok {
+				return ctx.Err()
+			}
+			if swarm.Proximity(d.Address.Bytes(), overlay.Bytes()) < depth {
+				continue
+			}
+			ch, err := s.storer.Get(ctx, storage.ModeGetSync, d.Address)
+			if errors.Is(err, storage.ErrNotFound) {
+				// garbage collected since the descriptor was read
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := sw.Write(ch); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close stops the import of the snapshot.
+func (s *Service) Close() error {
+	close(s.quit)
+	s.wg.Wait()
+	return nil
+}
+
+// dataWriter writes the snapshot in the data messages.
+type dataWriter struct {
+	ctx context.Context
+	w   protobuf.Writer
+	err error
+}
+
+func (w *dataWriter) Write(p []byte) (int, error) {
+	for n := 0; n < len(p); {
+		m := len(p) - n
+		if m > dataMessageSize {
+			m = dataMessageSize
+		}
+		if err := w.w.WriteMsgWithContext(w.ctx, &pb.Data{Data: p[n : n+m]}); err != nil {
+			w.err = fmt.Errorf("write data: %w", err)
+			return n, w.err
+		}
+		n += m
+	}
+	return len(p), nil
+}
+
+// dataReader reads the snapshot from the data messages, terminated by an
+// empty one.
+type dataReader struct {
+	ctx    context.Context
+	stream p2p.Stream
+	r      protobuf.Reader
+	buf    []byte
+	err    error
+}
+
+func (r *dataReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		var d pb.Data
+		if err := r.r.ReadMsgWithContext(r.ctx, &d); err != nil {
+			r.err = fmt.Errorf("read data: %w", err)
+			return 0, r.err
+		}
+		switch {
+		case d.Err == ErrNeighborhood.Error():
+			r.err = ErrNeighborhood
+		case d.Err != "":
+			r.err = fmt.Errorf("peer: %s", d.Err)
+		case len(d.Data) == 0:
+			r.err = io.EOF
+		}
+		r.buf = d.Data
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *dataReader) Close() error {
+	if r.err == io.EOF {
+		go r.stream.FullClose()
+		return nil
+	}
+	return r.stream.Reset()
+}
+
+type countingReader struct {
+	r io.Reader
+	n func(int)
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n(n)
+	return n, err
+}
@@ -0,0 +1,441 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package snapshot_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/localstore"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/p2p/streamtest"
+	"github.com/ethsana/sana/pkg/snapshot"
+	mockstate "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/storage"
+	testingc "github.com/ethsana/sana/pkg/storage/testing"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/swarm/test"
+)
+
+var validStamp = func(ch swarm.Chunk, _ []byte) (swarm.Chunk, error) { return ch, nil }
+
+func newStorer(t *testing.T, overlay swarm.Address, chunks ...swarm.Chunk) *localstore.DB {
+	t.Helper()
+	db, err := localstore.New("", overlay.Bytes(), nil, nil, logging.New(ioutil.Discard, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if len(chunks) > 0 {
+		if _, err := db.Put(context.Background(), storage.ModePutUpload, chunks...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// newServer returns the service of the node at the base address and the
+// neighborhood depth, with the chunks and the chunks within the depth of the
+// overlay address.
+func newServer(t *testing.T, base swarm.Address, nodeDepth uint8, overlay swarm.Address, depth uint8) (*snapshot.Service, common.Address, []swarm.Chunk) {
+	t.Helper()
+	var chunks, neighborhood []swarm.Chunk
+	for i := 0; i < 20; i++ {
+		ch := testingc.GenerateTestRandomChunk()
+		chunks = append(chunks, ch)
+		if swarm.Proximity(ch.Address().Bytes(), overlay.Bytes()) >= depth {
+			neighborhood = append(neighborhood, ch)
+		}
+	}
+	signer, signerAddress := newSigner(t)
+	s := snapshot.New(nil, newStorer(t, base, chunks...), mockstate.NewStateStore(), signer, base, func() uint8 { return nodeDepth }, validStamp, logging.New(ioutil.Discard, 0), snapshot.Options{})
+	t.Cleanup(func() { s.Close() })
+	return s, signerAddress, neighborhood
+}
+
+func newClient(t *testing.T, streamer *streamtest.Recorder, storer *localstore.DB, overlay swarm.Address, depth uint8, validStamp func(swarm.Chunk, []byte) (swarm.Chunk, error), o snapshot.Options) *snapshot.Service {
+	t.Helper()
+	signer, _ := newSigner(t)
+	s := snapshot.New(streamer, storer, mockstate.NewStateStore(), signer, overlay, func() uint8 { return depth }, validStamp, logging.New(ioutil.Discard, 0), o)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func checkImported(t *testing.T, storer *localstore.DB, chunks []swarm.Chunk) {
+	t.Helper()
+	for _, ch := range chunks {
+		got, err := storer.Get(context.Background(), storage.ModeGetRequest, ch.Address())
+		if err != nil {
+			t.Fatalf("chunk %s: %v", ch.Address(), err)
+		}
+		if !bytes.Equal(got.Data(), ch.Data()) {
+			t.Fatalf("chunk %s: got other data", ch.Address())
+		}
+	}
+}
+
+func checkNotImported(t *testing.T, storer *localstore.DB, chunks []swarm.Chunk) {
+	t.Helper()
+	for _, ch := range chunks {
+		if has, _ := storer.Has(context.Background(), ch.Address()); has {
+			t.Fatalf("chunk %s imported", ch.Address())
+		}
+	}
+}
+
+func TestImportPeer(t *testing.T) {
+	overlay := test.RandomAddress()
+	server, _, chunks := newServer(t, test.RandomAddress(), 0, overlay, 0)
+	rejected := chunks[0]
+
+	recorder := streamtest.New(
+		streamtest.WithProtocols(server.Protocol()),
+		streamtest.WithBaseAddr(overlay),
+	)
+	storer := newStorer(t, overlay)
+	client := newClient(t, recorder, storer, overlay, 0, func(ch swarm.Chunk, stamp []byte) (swarm.Chunk, error) {
+		if ch.Address().Equal(rejected.Address()) {
+			return nil, errors.New("expired batch")
+		}
+		return ch, nil
+	}, snapshot.Options{
+		Sources: []string{test.RandomAddress().String()},
+	})
+
+	// the snapshots of the peers are served for the overlay address of the
+	// requesting peer, so the source peer is whichever the recorder serves
+	client.Start(0)
+	<-client.Done()
+
+	p := client.Progress()
+	if p.State != snapshot.StateCompleted {
+		t.Fatalf("got state %s, want %s: %s", p.State, snapshot.StateCompleted, p.Error)
+	}
+	if p.Chunks != uint64(len(chunks)-1) {
+		t.Errorf("got %d chunks, want %d", p.Chunks, len(chunks)-1)
+	}
+	if p.Rejected != 1 {
+		t.Errorf("got %d rejected chunks, want 1", p.Rejected)
+	}
+	if p.Bytes == 0 {
+		t.Error("got no bytes")
+	}
+	checkImported(t, storer, chunks[1:])
+	checkNotImported(t, storer, []swarm.Chunk{rejected})
+}
+
+func TestImportPeerDepth(t *testing.T) {
+	overlay := test.RandomAddress()
+	var depth uint8 = 1
+
+	t.Run("node depth", func(t *testing.T) {
+		// the peer requests the whole reserve, it gets the neighborhood
+		// at the depth of the serving node
+		server, _, chunks := newServer(t, overlay, depth, overlay, depth)
+		recorder := streamtest.New(
+			streamtest.WithProtocols(server.Protocol()),
+			streamtest.WithBaseAddr(overlay),
+		)
+		storer := newStorer(t, overlay)
+		client := newClient(t, recorder, storer, overlay, 0, validStamp, snapshot.Options{
+			Sources: []string{test.RandomAddress().String()},
+		})
+		client.Start(0)
+		<-client.Done()
+
+		p := client.Progress()
+		if p.State != snapshot.StateCompleted {
+			t.Fatalf("got state %s, want %s: %s", p.State, snapshot.StateCompleted, p.Error)
+		}
+		if p.Chunks != uint64(len(chunks)) {
+			t.Errorf("got %d chunks, want %d", p.Chunks, len(chunks))
+		}
+		checkImported(t, storer, chunks)
+	})
+
+	t.Run("outside neighborhood", func(t *testing.T) {
+		other := swarm.NewAddress(append([]byte{overlay.Bytes()[0] ^ 0x80}, overlay.Bytes()[1:]...))
+		server, _, chunks := newServer(t, other, depth, overlay, 0)
+		recorder := streamtest.New(
+			streamtest.WithProtocols(server.Protocol()),
+			streamtest.WithBaseAddr(overlay),
+		)
+		storer := newStorer(t, overlay)
+		client := newClient(t, recorder, storer, overlay, 0, validStamp, snapshot.Options{
+			Sources: []string{other.String()},
+		})
+		client.Start(0)
+		<-client.Done()
+
+		p := client.Progress()
+		if p.State != snapshot.StateFailed {
+			t.Fatalf("got state %s, want %s", p.State, snapshot.StateFailed)
+		}
+		if !strings.Contains(p.Error, snapshot.ErrNeighborhood.Error()) {
+			t.Errorf("got error %q, want %q", p.Error, snapshot.ErrNeighborhood)
+		}
+		checkNotImported(t, storer, chunks)
+	})
+}
+
+func TestImportHTTP(t *testing.T) {
+	overlay := test.RandomAddress()
+	var depth uint8 = 1
+	server, serverSigner, chunks := newServer(t, test.RandomAddress(), 0, overlay, depth)
+
+	var snapshotData bytes.Buffer
+	if _, err := server.Export(context.Background(), &snapshotData, overlay, depth); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(snapshotData.Bytes())
+	}))
+	defer ts.Close()
+	tampered := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the signature over the chunks is the last part of the snapshot
+		_, _ = w.Write(modify(t, snapshotData.Bytes(), 2))
+	}))
+	defer tampered.Close()
+
+	t.Run("trusted", func(t *testing.T) {
+		storer := newStorer(t, overlay)
+		client := newClient(t, streamtest.New(), storer, overlay, depth, validStamp, snapshot.Options{
+			Sources: []string{ts.URL},
+			Signers: []common.Address{serverSigner},
+		})
+		client.Start(0)
+		<-client.Done()
+
+		p := client.Progress()
+		if p.State != snapshot.StateCompleted {
+			t.Fatalf("got state %s, want %s: %s", p.State, snapshot.StateCompleted, p.Error)
+		}
+		if p.Signer != serverSigner {
+			t.Errorf("got signer %s, want %s", p.Signer, serverSigner)
+		}
+		if p.Chunks != uint64(len(chunks)) {
+			t.Errorf("got %d chunks, want %d", p.Chunks, len(chunks))
+		}
+		checkImported(t, storer, chunks)
+	})
+
+	t.Run("untrusted", func(t *testing.T) {
+		storer := newStorer(t, overlay)
+		client := newClient(t, streamtest.New(), storer, overlay, depth, validStamp, snapshot.Options{
+			Sources: []string{ts.URL},
+		})
+		client.Start(0)
+		<-client.Done()
+
+		p := client.Progress()
+		if p.State != snapshot.StateFailed {
+			t.Fatalf("got state %s, want %s", p.State, snapshot.StateFailed)
+		}
+		if !strings.Contains(p.Error, snapshot.ErrUntrustedSigner.Error()) {
+			t.Errorf("got error %q, want %q", p.Error, snapshot.ErrUntrustedSigner)
+		}
+		if p.Chunks != 0 {
+			t.Errorf("got %d chunks, want none", p.Chunks)
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		storer := newStorer(t, overlay)
+		client := newClient(t, streamtest.New(), storer, overlay, depth, validStamp, snapshot.Options{
+			Sources: []string{tampered.URL},
+			Signers: []common.Address{serverSigner},
+		})
+		client.Start(0)
+		<-client.Done()
+
+		p := client.Progress()
+		if p.State != snapshot.StateFailed {
+			t.Fatalf("got state %s, want %s", p.State, snapshot.StateFailed)
+		}
+		if !strings.Contains(p.Error, snapshot.ErrInvalidSignature.Error()) {
+			t.Errorf("got error %q, want %q", p.Error, snapshot.ErrInvalidSignature)
+		}
+		if p.Chunks != 0 {
+			t.Errorf("got %d chunks, want none", p.Chunks)
+		}
+		checkNotImported(t, storer, chunks)
+	})
+
+	t.Run("other neighborhood", func(t *testing.T) {
+		other := swarm.NewAddress(append([]byte{overlay.Bytes()[0] ^ 0x80}, overlay.Bytes()[1:]...))
+		storer := newStorer(t, other)
+		client := newClient(t, streamtest.New(), storer, other, depth, validStamp, snapshot.Options{
+			Sources: []string{ts.URL},
+			Signers: []common.Address{serverSigner},
+		})
+		client.Start(0)
+		<-client.Done()
+
+		if p := client.Progress(); !strings.Contains(p.Error, snapshot.ErrNeighborhood.Error()) {
+			t.Errorf("got error %q, want %q", p.Error, snapshot.ErrNeighborhood)
+		}
+	})
+}
+
+func TestImportHTTPInvalid(t *testing.T) {
+	overlay := test.RandomAddress()
+	var depth uint8 = 1
+	signer, signerAddress := newSigner(t)
+
+	// the chunks outside the neighborhood in the header are not imported,
+	// even if the snapshot is signed by a trusted signer
+	var inside, outside []swarm.Chunk
+	for len(inside) == 0 || len(outside) == 0 {
+		ch := testingc.GenerateTestRandomChunk()
+		if swarm.Proximity(ch.Address().Bytes(), overlay.Bytes()) >= depth {
+			inside = append(inside, ch)
+		} else {
+			outside = append(outside, ch)
+		}
+	}
+	snapshotData := writeSnapshot(t, signer, snapshot.Header{Overlay: overlay, Depth: depth}, append(inside, outside...))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(snapshotData)
+	}))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		name    string
+		maxSize int64
+		wantErr error
+	}{
+		{
+			name:    "outside neighborhood",
+			wantErr: snapshot.ErrInvalidChunk,
+		},
+		{
+			name:    "too large",
+			maxSize: int64(len(snapshotData) / 2),
+			wantErr: snapshot.ErrTooLarge,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			storer := newStorer(t, overlay)
+			client := newClient(t, streamtest.New(), storer, overlay, depth, validStamp, snapshot.Options{
+				Sources: []string{ts.URL},
+				Signers: []common.Address{signerAddress},
+				MaxSize: tc.maxSize,
+			})
+			client.Start(0)
+			<-client.Done()
+
+			p := client.Progress()
+			if p.State != snapshot.StateFailed {
+				t.Fatalf("got state %s, want %s", p.State, snapshot.StateFailed)
+			}
+			if !strings.Contains(p.Error, tc.wantErr.Error()) {
+				t.Errorf("got error %q, want %q", p.Error, tc.wantErr)
+			}
+			if p.Chunks != 0 {
+				t.Errorf("got %d chunks, want none", p.Chunks)
+			}
+			checkNotImported(t, storer, append(inside, outside...))
+		})
+	}
+
+	// the snapshot of the size of the limit is imported
+	storer := newStorer(t, overlay)
+	valid := writeSnapshot(t, signer, snapshot.Header{Overlay: overlay, Depth: depth}, inside)
+	vs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(valid)
+	}))
+	defer vs.Close()
+	client := newClient(t, streamtest.New(), storer, overlay, depth, validStamp, snapshot.Options{
+		Sources: []string{vs.URL},
+		Signers: []common.Address{signerAddress},
+		MaxSize: int64(len(valid)),
+	})
+	client.Start(0)
+	<-client.Done()
+	if p := client.Progress(); p.State != snapshot.StateCompleted {
+		t.Fatalf("got state %s, want %s: %s", p.State, snapshot.StateCompleted, p.Error)
+	}
+	checkImported(t, storer, inside)
+}
+
+func TestMaxSize(t *testing.T) {
+	signer, _ := newSigner(t)
+	var chunks []swarm.Chunk
+	for i := 0; i < 10; i++ {
+		chunks = append(chunks, testingc.GenerateTestRandomChunk())
+	}
+	if size, max := len(writeSnapshot(t, signer, snapshot.Header{}, chunks)), snapshot.MaxSize(uint64(len(chunks))); int64(size) > max {
+		t.Fatalf("got snapshot of %d bytes, want at most %d", size, max)
+	}
+}
+
+func TestImportSkipped(t *testing.T) {
+	overlay := test.RandomAddress()
+	storer := newStorer(t, overlay, testingc.GenerateTestRandomChunk())
+	client := newClient(t, streamtest.New(), storer, overlay, 0, validStamp, snapshot.Options{
+		Sources: []string{"https://snapshots.example.com/snapshot.gz"},
+	})
+	client.Start(0)
+	<-client.Done()
+
+	if p := client.Progress(); p.State != snapshot.StateSkipped {
+		t.Fatalf("got state %s, want %s", p.State, snapshot.StateSkipped)
+	}
+}
+
+func TestImportDisabled(t *testing.T) {
+	overlay := test.RandomAddress()
+	client := newClient(t, streamtest.New(), newStorer(t, overlay), overlay, 0, validStamp, snapshot.Options{})
+	client.Start(0)
+	<-client.Done()
+
+	if p := client.Progress(); p.State != snapshot.StateDisabled {
+		t.Fatalf("got state %s, want %s", p.State, snapshot.StateDisabled)
+	}
+}
+
+func TestExportBusy(t *testing.T) {
+	overlay := test.RandomAddress()
+	server, _, _ := newServer(t, test.RandomAddress(), 0, overlay, 0)
+
+	w := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	errC := make(chan error, 1)
+	go func() {
+		_, err := server.Export(context.Background(), w, overlay, 0)
+		errC <- err
+	}()
+	<-w.started
+
+	if _, err := server.Export(context.Background(), ioutil.Discard, overlay, 0); !errors.Is(err, snapshot.ErrBusy) {
+		t.Fatalf("got error %v, want %v", err, snapshot.ErrBusy)
+	}
+	close(w.release)
+	if err := <-errC; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// blockingWriter blocks the writes until it is released.
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+	once    bool
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	if !w.once {
+		w.once = true
+		close(w.started)
+	}
+	<-w.release
+	return len(p), nil
+}
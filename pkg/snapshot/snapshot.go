@@ -0,0 +1,339 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package snapshot bootstraps the reserve of new full nodes from signed,
+// compressed snapshots of their neighborhood.
+//
+// A snapshot holds the stamped chunks of a node which are within the depth
+// of the overlay address of the neighborhood. It is served by full nodes
+// over the snapshot protocol, to the neighborhood of the requesting peer, and
+// by the debug api, from which the operators can publish it to be downloaded
+// over HTTPS. A fresh full node downloads the snapshot from the configured
+// sources before it starts the pull syncing. The snapshots downloaded over
+// HTTPS must be signed by one of the trusted signers, which is verified
+// before any chunk is imported, the ones of the peers are trusted as the
+// peers are. The snapshot is downloaded into a temporary file and verified
+// in full, the content addresses of the chunks and the signature over all
+// of them at the end of the snapshot, before any chunk is imported. The stamp
+// of every chunk is verified as it is imported into the localstore. The pull
+// sync then completes the reserve with the chunks stored since the snapshot.
+package snapshot
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/cac"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/postage"
+	"github.com/ethsana/sana/pkg/soc"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+const (
+	// Version is the version of the snapshot format.
+	Version = 1
+
+	magic = "sana-snapshot"
+
+	recordEnd   byte = 0
+	recordChunk byte = 1
+
+	maxHeaderSize    = 4 * 1024
+	maxChunkDataSize = swarm.ChunkWithSpanSize + soc.IdSize + soc.SignatureSize
+	signatureSize    = 65
+)
+
+var (
+	// ErrInvalidSnapshot is returned if the snapshot is malformed or
+	// truncated.
+	ErrInvalidSnapshot = errors.New("snapshot: invalid snapshot")
+	// ErrUnsupportedVersion is returned for the snapshots of an unknown
+	// version of the format.
+	ErrUnsupportedVersion = errors.New("snapshot: unsupported version")
+	// ErrInvalidChunk is returned if a chunk of the snapshot does not match
+	// its address.
+	ErrInvalidChunk = errors.New("snapshot: invalid chunk")
+	// ErrInvalidSignature is returned if the signature of the header or the
+	// one of the chunks is not made by the signer of the snapshot.
+	ErrInvalidSignature = errors.New("snapshot: invalid signature")
+)
+
+// MaxSize returns the maximum size of a snapshot of the number of the chunks,
+// with the overhead of the compression of the incompressible chunks.
+func MaxSize(chunks uint64) int64 {
+	record := int64(1 + swarm.HashSize + postage.StampSize + 4 + maxChunkDataSize)
+	size := int64(len(magic)+4+maxHeaderSize+signatureSize) + int64(chunks)*record + 1 + 8 + signatureSize
+	// deflate stores the incompressible data in blocks of up to 64KiB with
+	// a 5 byte header, gzip adds its own header and trailer
+	return size + size/(64*1024)*5 + 5 + 1024
+}
+
+// Header describes the neighborhood of the snapshot.
+type Header struct {
+	Version int `json:"version"`
+	// Overlay and Depth are the neighborhood of the chunks, the chunks
+	// within the depth of the overlay address.
+	Overlay swarm.Address `json:"overlay"`
+	Depth   uint8         `json:"depth"`
+	// Created is the unix time at which the snapshot was started.
+	Created int64 `json:"created"`
+}
+
+// Writer writes the chunks of a snapshot. The snapshot is a gzip compressed
+// stream of the magic, the header with its signature, the records of the
+// chunks with their stamps and the number of the chunks with the signature
+// over the header and the chunks.
+type Writer struct {
+	gz     *gzip.Writer
+	w      *bufio.Writer
+	signer crypto.Signer
+	digest hash.Hash
+	count  uint64
+}
+
+// NewWriter writes the header of the snapshot signed by the signer and
+// returns the Writer of its chunks. The snapshot is complete once the Writer
+// is closed.
+func NewWriter(w io.Writer, signer crypto.Signer, h Header) (*Writer, error) {
+	h.Version = Version
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signer.Sign(data)
+	if err != nil {
+		return nil, fmt.Errorf("sign header: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	sw := &Writer{
+		gz:     gz,
+		w:      bufio.NewWriter(gz),
+		signer: signer,
+		digest: swarm.NewHasher(),
+	}
+	sw.digest.Write(data)
+
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(data)))
+	for _, b := range [][]byte{[]byte(magic), size[:], data, sig} {
+		if _, err := sw.w.Write(b); err != nil {
+			return nil, err
+		}
+	}
+	return sw, nil
+}
+
+// Write writes the chunk with its stamp.
+func (w *Writer) Write(ch swarm.Chunk) error {
+	if ch.Stamp() == nil {
+		return fmt.Errorf("chunk %s: no stamp", ch.Address())
+	}
+	stamp, err := ch.Stamp().MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("chunk %s: stamp: %w", ch.Address(), err)
+	}
+	w.digest.Write(ch.Address().Bytes())
+	w.digest.Write(stamp)
+
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(ch.Data())))
+	for _, b := range [][]byte{{recordChunk}, ch.Address().Bytes(), stamp, size[:], ch.Data()} {
+		if _, err := w.w.Write(b); err != nil {
+			return err
+		}
+	}
+	w.count++
+	return nil
+}
+
+// Count returns the number of the written chunks.
+func (w *Writer) Count() uint64 {
+	return w.count
+}
+
+// Close writes the number of the chunks and the signature of the snapshot.
+func (w *Writer) Close() error {
+	var count [8]byte
+	binary.BigEndian.PutUint64(count[:], w.count)
+	w.digest.Write(count[:])
+	sig, err := w.signer.Sign(w.digest.Sum(nil))
+	if err != nil {
+		return fmt.Errorf("sign chunks: %w", err)
+	}
+	for _, b := range [][]byte{{recordEnd}, count[:], sig} {
+		if _, err := w.w.Write(b); err != nil {
+			return err
+		}
+	}
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+// Reader reads and verifies the chunks of a snapshot.
+type Reader struct {
+	r      *bufio.Reader
+	header Header
+	signer common.Address
+	digest hash.Hash
+	count  uint64
+	err    error
+}
+
+// NewReader reads the header of the snapshot and verifies its signature.
+func NewReader(r io.Reader) (*Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	br := bufio.NewReader(gz)
+
+	prefix := make([]byte, len(magic)+4)
+	if _, err := io.ReadFull(br, prefix); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if string(prefix[:len(magic)]) != magic {
+		return nil, ErrInvalidSnapshot
+	}
+	size := binary.BigEndian.Uint32(prefix[len(magic):])
+	if size > maxHeaderSize {
+		return nil, fmt.Errorf("%w: header too large", ErrInvalidSnapshot)
+	}
+	data := make([]byte, int(size)+signatureSize)
+	if _, err := io.ReadFull(br, data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	data, sig := data[:size], data[size:]
+
+	var h Header
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidSnapshot, err)
+	}
+	if h.Version != Version {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedVersion, h.Version)
+	}
+	signer, err := recoverSigner(sig, data)
+	if err != nil {
+		return nil, err
+	}
+
+	sr := &Reader{
+		r:      br,
+		header: h,
+		signer: signer,
+		digest: swarm.NewHasher(),
+	}
+	sr.digest.Write(data)
+	return sr, nil
+}
+
+// Header returns the header of the snapshot.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Signer returns the ethereum address of the signer of the snapshot.
+func (r *Reader) Signer() common.Address {
+	return r.signer
+}
+
+// Next returns the next chunk of the snapshot with its stamp, if the chunk
+// matches its address. The signature over the chunks is verified at the end
+// of the snapshot, after which io.EOF is returned.
+func (r *Reader) Next() (swarm.Chunk, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	ch, err := r.next()
+	if err != nil {
+		r.err = err
+		return nil, err
+	}
+	return ch, nil
+}
+
+func (r *Reader) next() (swarm.Chunk, error) {
+	kind, err := r.r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	switch kind {
+	case recordEnd:
+		return nil, r.verify()
+	case recordChunk:
+	default:
+		return nil, fmt.Errorf("%w: unknown record %d", ErrInvalidSnapshot, kind)
+	}
+
+	head := make([]byte, swarm.HashSize+postage.StampSize+4)
+	if _, err := io.ReadFull(r.r, head); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	addr := swarm.NewAddress(head[:swarm.HashSize])
+	stampBytes := head[swarm.HashSize : swarm.HashSize+postage.StampSize]
+	size := binary.BigEndian.Uint32(head[swarm.HashSize+postage.StampSize:])
+	if size > maxChunkDataSize {
+		return nil, fmt.Errorf("%w: chunk %s too large", ErrInvalidSnapshot, addr)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	r.digest.Write(addr.Bytes())
+	r.digest.Write(stampBytes)
+	r.count++
+
+	stamp := new(postage.Stamp)
+	if err := stamp.UnmarshalBinary(stampBytes); err != nil {
+		return nil, fmt.Errorf("%w: chunk %s: stamp: %v", ErrInvalidSnapshot, addr, err)
+	}
+	ch := swarm.NewChunk(addr, data).WithStamp(stamp)
+	if !cac.Valid(ch) && !soc.Valid(ch) {
+		return nil, fmt.Errorf("%w %s", ErrInvalidChunk, addr)
+	}
+	return ch, nil
+}
+
+// verify verifies the number of the chunks and the signature over them.
+func (r *Reader) verify() error {
+	trailer := make([]byte, 8+signatureSize)
+	if _, err := io.ReadFull(r.r, trailer); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	count, sig := trailer[:8], trailer[8:]
+	if binary.BigEndian.Uint64(count) != r.count {
+		return fmt.Errorf("%w: %d chunks, want %d", ErrInvalidSnapshot, r.count, binary.BigEndian.Uint64(count))
+	}
+	r.digest.Write(count)
+	signer, err := recoverSigner(sig, r.digest.Sum(nil))
+	if err != nil {
+		return err
+	}
+	if signer != r.signer {
+		return ErrInvalidSignature
+	}
+	return io.EOF
+}
+
+func recoverSigner(sig, data []byte) (common.Address, error) {
+	pub, err := crypto.Recover(sig, data)
+	if err != nil {
+		return common.Address{}, ErrInvalidSignature
+	}
+	a, err := crypto.NewEthereumAddress(*pub)
+	if err != nil {
+		return common.Address{}, ErrInvalidSignature
+	}
+	return common.BytesToAddress(a), nil
+}
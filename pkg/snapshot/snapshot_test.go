@@ -0,0 +1,187 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package snapshot_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/snapshot"
+	testingc "github.com/ethsana/sana/pkg/storage/testing"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+func newSigner(t *testing.T) (crypto.Signer, common.Address) {
+	t.Helper()
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.NewDefaultSigner(key)
+	a, err := signer.EthereumAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, a
+}
+
+func writeSnapshot(t *testing.T, signer crypto.Signer, h snapshot.Header, chunks []swarm.Chunk) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := snapshot.NewWriter(&buf, signer, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range chunks {
+		if err := w.Write(ch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// readAll reads the chunks of the snapshot until the first error.
+func readAll(data []byte) ([]swarm.Chunk, error) {
+	r, err := snapshot.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var chunks []swarm.Chunk
+	for {
+		ch, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return chunks, nil
+		}
+		if err != nil {
+			return chunks, err
+		}
+		chunks = append(chunks, ch)
+	}
+}
+
+// modify returns the snapshot with the uncompressed byte at the offset from
+// the end of the snapshot flipped.
+func modify(t *testing.T, data []byte, offset int) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[len(raw)-offset] ^= 0xff
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriterReader(t *testing.T) {
+	signer, signerAddress := newSigner(t)
+	header := snapshot.Header{
+		Overlay: swarm.MustParseHexAddress("ca1e000000000000000000000000000000000000000000000000000000000000"),
+		Depth:   2,
+		Created: 1634000000,
+	}
+	chunks := []swarm.Chunk{
+		testingc.GenerateTestRandomChunk(),
+		testingc.GenerateTestRandomChunk(),
+		testingc.GenerateTestRandomChunk(),
+	}
+	data := writeSnapshot(t, signer, header, chunks)
+
+	r, err := snapshot.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header.Version = snapshot.Version
+	if got := r.Header(); got.Version != header.Version || !got.Overlay.Equal(header.Overlay) || got.Depth != header.Depth || got.Created != header.Created {
+		t.Errorf("got header %+v, want %+v", got, header)
+	}
+	if r.Signer() != signerAddress {
+		t.Errorf("got signer %s, want %s", r.Signer(), signerAddress)
+	}
+
+	got, err := readAll(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(chunks) {
+		t.Fatalf("got %d chunks, want %d", len(got), len(chunks))
+	}
+	for i, ch := range got {
+		if !ch.Equal(chunks[i]) {
+			t.Errorf("chunk %d: got %s, want %s", i, ch.Address(), chunks[i].Address())
+		}
+		if !bytes.Equal(ch.Stamp().BatchID(), chunks[i].Stamp().BatchID()) {
+			t.Errorf("chunk %d: got batch %x, want %x", i, ch.Stamp().BatchID(), chunks[i].Stamp().BatchID())
+		}
+	}
+}
+
+func TestReaderInvalid(t *testing.T) {
+	signer, _ := newSigner(t)
+	ch := testingc.GenerateTestRandomChunk()
+	data := writeSnapshot(t, signer, snapshot.Header{}, []swarm.Chunk{ch})
+
+	// the last record is the end marker, the number of the chunks and the
+	// signature, preceded by the data of the chunk
+	trailerSize := 1 + 8 + 65
+
+	for _, tc := range []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{
+			name:    "truncated",
+			data:    data[:len(data)/2],
+			wantErr: snapshot.ErrInvalidSnapshot,
+		},
+		{
+			name:    "modified chunk",
+			data:    modify(t, data, trailerSize+1),
+			wantErr: snapshot.ErrInvalidChunk,
+		},
+		{
+			name:    "modified signature",
+			data:    modify(t, data, 2),
+			wantErr: snapshot.ErrInvalidSignature,
+		},
+		{
+			name:    "modified count",
+			data:    modify(t, data, 65+1),
+			wantErr: snapshot.ErrInvalidSnapshot,
+		},
+		{
+			name:    "not a snapshot",
+			data:    []byte("not a snapshot"),
+			wantErr: snapshot.ErrInvalidSnapshot,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := readAll(tc.data); !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package stamping keeps track of the trusted light clients which are allowed
+// to have their chunks stamped by the node with a designated postage batch,
+// and of the number of chunks each client has already had stamped.
+package stamping
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/ethsana/sana/pkg/storage"
+)
+
+const keyPrefix = "stamping_usage_"
+
+var (
+	// ErrUnauthorized is returned when the token does not belong to any client.
+	ErrUnauthorized = errors.New("stamping: unauthorized")
+	// ErrQuotaExceeded is returned when the client has used up its quota.
+	ErrQuotaExceeded = errors.New("stamping: quota exceeded")
+)
+
+// Client is a light client which is allowed to have chunks stamped.
+type Client struct {
+	// Name identifies the client in logs and usage records.
+	Name string `json:"name"`
+	// TokenHash is the hex encoded SHA-256 hash of the client token.
+	TokenHash string `json:"tokenHash"`
+	// Quota is the number of chunks the client may have stamped, zero
+	// means unlimited.
+	Quota uint64 `json:"quota"`
+}
+
+// Usage is the number of chunks stamped for a client.
+type Usage struct {
+	Client  string `json:"client"`
+	Stamped uint64 `json:"stamped"`
+	Quota   uint64 `json:"quota"`
+}
+
+// Service authenticates clients and accounts the chunks stamped for them.
+type Service interface {
+	// Authenticate returns the client which the token belongs to.
+	Authenticate(token string) (Client, error)
+	// Reserve accounts n chunks to the client, returning ErrQuotaExceeded
+	// if that would exceed its quota.
+	Reserve(c Client, n uint64) error
+	// Release returns n previously reserved chunks to the client.
+	Release(c Client, n uint64) error
+	// Usage returns the number of chunks stamped for the client.
+	Usage(c Client) (Usage, error)
+}
+
+type service struct {
+	store   storage.StateStorer
+	clients []client
+	mu      sync.Mutex
+}
+
+type client struct {
+	Client
+	hash []byte
+}
+
+// New creates a new Service for the clients, persisting their usage in the
+// state store.
+func New(store storage.StateStorer, clients []Client) (Service, error) {
+	s := &service{store: store}
+	names := make(map[string]struct{}, len(clients))
+	for _, c := range clients {
+		if c.Name == "" {
+			return nil, errors.New("stamping: client without name")
+		}
+		if _, ok := names[c.Name]; ok {
+			return nil, fmt.Errorf("stamping: duplicate client %s", c.Name)
+		}
+		names[c.Name] = struct{}{}
+		hash, err := hex.DecodeString(c.TokenHash)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("stamping: client %s: malformed token hash", c.Name)
+		}
+		s.clients = append(s.clients, client{Client: c, hash: hash})
+	}
+	return s, nil
+}
+
+// LoadClients reads the clients from a JSON file containing an array of
+// client definitions.
+func LoadClients(path string) ([]Client, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var clients []Client
+	if err := json.Unmarshal(b, &clients); err != nil {
+		return nil, fmt.Errorf("stamping: parse %s: %w", path, err)
+	}
+	return clients, nil
+}
+
+// TokenHash returns the hex encoded hash of the token which is stored in the
+// client definition.
+func TokenHash(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+func (s *service) Authenticate(token string) (Client, error) {
+	if token == "" {
+		return Client{}, ErrUnauthorized
+	}
+	h := sha256.Sum256([]byte(token))
+	for _, c := range s.clients {
+		if subtle.ConstantTimeCompare(c.hash, h[:]) == 1 {
+			return c.Client, nil
+		}
+	}
+	return Client{}, ErrUnauthorized
+}
+
+func (s *service) Reserve(c Client, n uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stamped, err := s.stamped(c.Name)
+	if err != nil {
+		return err
+	}
+	if c.Quota > 0 && stamped+n > c.Quota {
+		return ErrQuotaExceeded
+	}
+	return s.store.Put(storeKey(c.Name), stamped+n)
+}
+
+func (s *service) Release(c Client, n uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stamped, err := s.stamped(c.Name)
+	if err != nil {
+		return err
+	}
+	if n > stamped {
+		n = stamped
+	}
+	return s.store.Put(storeKey(c.Name), stamped-n)
+}
+
+func (s *service) Usage(c Client) (Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stamped, err := s.stamped(c.Name)
+	if err != nil {
+		return Usage{}, err
+	}
+	return Usage{Client: c.Name, Stamped: stamped, Quota: c.Quota}, nil
+}
+
+func (s *service) stamped(name string) (uint64, error) {
+	var stamped uint64
+	if err := s.store.Get(storeKey(name), &stamped); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return 0, err
+	}
+	return stamped, nil
+}
+
+func storeKey(name string) string {
+	return keyPrefix + name
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stamping_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/stamping"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+)
+
+func TestService(t *testing.T) {
+	store := statestore.NewStateStore()
+	clients := []stamping.Client{
+		{Name: "mobile", TokenHash: stamping.TokenHash("mobile-token"), Quota: 2},
+		{Name: "browser", TokenHash: stamping.TokenHash("browser-token")},
+	}
+	s, err := stamping.New(store, clients)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Authenticate("wrong-token"); !errors.Is(err, stamping.ErrUnauthorized) {
+		t.Fatalf("got error %v, want %v", err, stamping.ErrUnauthorized)
+	}
+	if _, err := s.Authenticate(""); !errors.Is(err, stamping.ErrUnauthorized) {
+		t.Fatalf("got error %v, want %v", err, stamping.ErrUnauthorized)
+	}
+
+	c, err := s.Authenticate("mobile-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "mobile" {
+		t.Fatalf("got client %s, want mobile", c.Name)
+	}
+
+	if err := s.Reserve(c, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reserve(c, 1); !errors.Is(err, stamping.ErrQuotaExceeded) {
+		t.Fatalf("got error %v, want %v", err, stamping.ErrQuotaExceeded)
+	}
+	if err := s.Release(c, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reserve(c, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// usage survives a restart
+	s, err = stamping.New(store, clients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := s.Usage(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != (stamping.Usage{Client: "mobile", Stamped: 2, Quota: 2}) {
+		t.Fatalf("got usage %+v", u)
+	}
+
+	b, err := s.Authenticate("browser-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reserve(b, 1000); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		clients []stamping.Client
+	}{
+		{
+			name:    "no name",
+			clients: []stamping.Client{{TokenHash: stamping.TokenHash("token")}},
+		},
+		{
+			name: "duplicate",
+			clients: []stamping.Client{
+				{Name: "a", TokenHash: stamping.TokenHash("token-1")},
+				{Name: "a", TokenHash: stamping.TokenHash("token-2")},
+			},
+		},
+		{
+			name:    "malformed hash",
+			clients: []stamping.Client{{Name: "a", TokenHash: "abcd"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := stamping.New(statestore.NewStateStore(), tc.clients); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestLoadClients(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clients.json")
+	data := `[{"name":"mobile","tokenHash":"` + stamping.TokenHash("token") + `","quota":10}]`
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	clients, err := stamping.LoadClients(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 1 || clients[0].Name != "mobile" || clients[0].Quota != 10 {
+		t.Fatalf("got clients %+v", clients)
+	}
+}
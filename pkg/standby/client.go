@@ -0,0 +1,73 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package standby
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TokenHeader is the header which carries the token shared by the pair.
+const TokenHeader = "Swarm-Standby-Token"
+
+// Client accesses the pairing endpoint of the other node of the pair.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient returns a new Client for the pairing endpoint at the base URL.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Status returns the state of the paired node.
+func (c *Client) Status(ctx context.Context) (State, error) {
+	var state State
+	err := c.get(ctx, "/standby/status", &state)
+	return state, err
+}
+
+// Snapshot returns the replicated data of the paired node.
+func (c *Client) Snapshot(ctx context.Context) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := c.get(ctx, "/standby/snapshot", &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, v interface{}) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	r.Header.Set(TokenHeader, c.token)
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusConflict:
+		return ErrNotActive
+	default:
+		return fmt.Errorf("standby: %s: unexpected response status %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
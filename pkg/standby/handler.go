@@ -0,0 +1,76 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package standby
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ethsana/sana/pkg/jsonhttp"
+	"github.com/gorilla/mux"
+)
+
+// Handler returns the pairing endpoint of the node. The promote route is
+// served only if the peer, the active node replicated by the standby, is
+// given.
+func (s *Service) Handler(peer *Client) http.Handler {
+	router := mux.NewRouter()
+	router.NotFoundHandler = http.HandlerFunc(jsonhttp.NotFoundHandler)
+
+	router.Handle("/standby/status", jsonhttp.MethodHandler{
+		"GET": http.HandlerFunc(s.statusHandler),
+	})
+	router.Handle("/standby/snapshot", jsonhttp.MethodHandler{
+		"GET": http.HandlerFunc(s.snapshotHandler),
+	})
+	if peer != nil {
+		router.Handle("/standby/promote", jsonhttp.MethodHandler{
+			"POST": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				s.promoteHandler(w, r, peer)
+			}),
+		})
+	}
+
+	return s.authorizationHandler(router)
+}
+
+func (s *Service) authorizationHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.Authorized(r.Header.Get(TokenHeader)) {
+			jsonhttp.Unauthorized(w, "invalid standby token")
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (s *Service) statusHandler(w http.ResponseWriter, r *http.Request) {
+	jsonhttp.OK(w, s.State())
+}
+
+func (s *Service) snapshotHandler(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := s.Snapshot()
+	if err != nil {
+		if errors.Is(err, ErrNotActive) {
+			jsonhttp.Conflict(w, "node is not active")
+			return
+		}
+		s.logger.Debugf("standby: snapshot: %v", err)
+		s.logger.Error("standby: snapshot")
+		jsonhttp.InternalServerError(w, "cannot create snapshot")
+		return
+	}
+	jsonhttp.OK(w, snapshot)
+}
+
+func (s *Service) promoteHandler(w http.ResponseWriter, r *http.Request, peer *Client) {
+	if err := s.Promote(r.Context(), peer); err != nil {
+		s.logger.Debugf("standby: promote: %v", err)
+		s.logger.Error("standby: promote")
+		jsonhttp.Conflict(w, err.Error())
+		return
+	}
+	jsonhttp.OK(w, s.State())
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package standby
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrPrimaryAlive is returned by Promote if the active node may still be
+// running.
+var ErrPrimaryAlive = errors.New("standby: active node may still be running")
+
+// rawValue stores the replicated value in the state store without encoding.
+type rawValue []byte
+
+func (v rawValue) MarshalBinary() ([]byte, error) {
+	return v, nil
+}
+
+// Replicate copies the snapshot of the active peer into the state store and
+// marks the node as its standby. Replicated keys which were removed on the
+// active node are removed as well.
+func (s *Service) Replicate(ctx context.Context, peer *Client) error {
+	snapshot, err := peer.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state.Role == RoleActive && s.state.Epoch > 0 {
+		return fmt.Errorf("%w: node is active in epoch %d", ErrPeerActive, s.state.Epoch)
+	}
+	if snapshot.State.Epoch < s.state.Epoch {
+		return fmt.Errorf("%w: got epoch %d, want at least %d", ErrStaleEpoch, snapshot.State.Epoch, s.state.Epoch)
+	}
+
+	keep := make(map[string]struct{}, len(snapshot.Entries))
+	for _, e := range snapshot.Entries {
+		if !s.replicated(e.Key) {
+			continue
+		}
+		keep[e.Key] = struct{}{}
+		if err := s.store.Put(e.Key, rawValue(e.Value)); err != nil {
+			return fmt.Errorf("standby: put %s: %w", e.Key, err)
+		}
+	}
+
+	var stale []string
+	for _, prefix := range s.prefixes {
+		if err := s.store.Iterate(prefix, func(key, _ []byte) (bool, error) {
+			if _, ok := keep[string(key)]; !ok {
+				stale = append(stale, string(key))
+			}
+			return false, nil
+		}); err != nil {
+			return fmt.Errorf("standby: iterate %s: %w", prefix, err)
+		}
+	}
+	for _, key := range stale {
+		if err := s.store.Delete(key); err != nil {
+			return fmt.Errorf("standby: delete %s: %w", key, err)
+		}
+	}
+
+	if err := s.setState(State{Role: RoleStandby, Epoch: snapshot.State.Epoch}); err != nil {
+		return err
+	}
+	s.lastSync = time.Now()
+	s.logger.Debugf("standby: replicated %d entries in epoch %d", len(snapshot.Entries), snapshot.State.Epoch)
+	return nil
+}
+
+// Run replicates the active peer at the given interval until the context is
+// done or the node is promoted.
+func (s *Service) Run(ctx context.Context, peer *Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Replicate(ctx, peer); err != nil {
+			s.logger.Debugf("standby: replicate: %v", err)
+			s.logger.Error("standby: unable to replicate active node")
+
+			if s.autoPromote {
+				if err := s.Promote(ctx, peer); err == nil {
+					return
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.promoted:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Promote marks the standby node as active in the next epoch. It refuses to
+// do so while the active peer answers or was replicated within the failover
+// timeout.
+func (s *Service) Promote(ctx context.Context, peer *Client) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state.Role != RoleStandby {
+		return fmt.Errorf("standby: node is %s, not a standby", s.state.Role)
+	}
+	if since := time.Since(s.lastSync); since < s.failoverTimeout {
+		return fmt.Errorf("%w: replicated %s ago", ErrPrimaryAlive, since.Round(time.Second))
+	}
+	if ps, err := peer.Status(ctx); err == nil && ps.Role == RoleActive {
+		return fmt.Errorf("%w: active in epoch %d", ErrPrimaryAlive, ps.Epoch)
+	}
+
+	if err := s.setState(State{Role: RoleActive, Epoch: s.state.Epoch + 1}); err != nil {
+		return err
+	}
+	close(s.promoted)
+	s.logger.Infof("standby: promoted to active in epoch %d", s.state.Epoch)
+	return nil
+}
+
+// Promoted returns a channel which is closed when the node is promoted.
+func (s *Service) Promoted() <-chan struct{} {
+	return s.promoted
+}
+
+func (s *Service) replicated(key string) bool {
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
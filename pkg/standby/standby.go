@@ -0,0 +1,282 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package standby implements the warm standby pairing of two nodes. The
+// standby node continuously replicates the critical state store data of the
+// active node, such as pins, postage batches and chequebook metadata, and can
+// be promoted when the active node is lost. Every promotion increments the
+// epoch of the pair, which lets the nodes detect that the other one took over
+// and prevents both of them from being active at the same time.
+//
+// The key material is not replicated, it needs to be provisioned on the
+// standby host separately.
+package standby
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/storage"
+)
+
+const (
+	// RoleActive is the role of the node which runs the network services.
+	RoleActive = "active"
+	// RoleStandby is the role of the node which replicates the active node.
+	RoleStandby = "standby"
+	// RoleFenced is the role of a formerly active node which has detected
+	// that its standby was promoted.
+	RoleFenced = "fenced"
+)
+
+const stateKey = "standby_state"
+
+// DefaultPrefixes are the prefixes of the state store keys which are
+// replicated to the standby node.
+var DefaultPrefixes = []string{
+	"root-pin",
+	"postage",
+	"batchstore_",
+	"batchservice_",
+	"swap_",
+	"pseudosettle_",
+	"transaction_",
+}
+
+var (
+	// ErrUnauthorized is returned by the paired node if the token is invalid.
+	ErrUnauthorized = errors.New("standby: unauthorized")
+	// ErrNotActive is returned by the paired node if it is not active and
+	// therefore can not be replicated.
+	ErrNotActive = errors.New("standby: node is not active")
+	// ErrPeerActive is returned if the paired node is active.
+	ErrPeerActive = errors.New("standby: paired node is active")
+	// ErrStandby is returned on an attempt to start the node with the state
+	// store of a standby node which was not promoted.
+	ErrStandby = errors.New("standby: state store belongs to a standby node which was not promoted")
+	// ErrFenced is returned if the node was superseded by its promoted
+	// standby.
+	ErrFenced = errors.New("standby: node was fenced by its promoted standby")
+	// ErrStaleEpoch is returned if the paired node has a lower epoch than
+	// the replicated one.
+	ErrStaleEpoch = errors.New("standby: paired node has a stale epoch")
+)
+
+// State is the persisted role of the node in the pair.
+type State struct {
+	Role  string `json:"role"`
+	Epoch uint64 `json:"epoch"`
+}
+
+// Entry is a replicated state store key and its raw value.
+type Entry struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Snapshot is the replicated data of the active node.
+type Snapshot struct {
+	State   State   `json:"state"`
+	Entries []Entry `json:"entries"`
+}
+
+// Options are the optional parameters of the Service.
+type Options struct {
+	// Prefixes override the DefaultPrefixes.
+	Prefixes []string
+	// FailoverTimeout is the minimal time since the last successful
+	// replication, or since the start, before the standby can be promoted.
+	FailoverTimeout time.Duration
+	// AutoPromote promotes the standby once the active node can not be
+	// replicated for the failover timeout.
+	AutoPromote bool
+}
+
+// Service holds the role of the node in the pair and serves the data of
+// the active node to its standby.
+type Service struct {
+	store           storage.StateStorer
+	logger          logging.Logger
+	tokenHash       [sha256.Size]byte
+	prefixes        []string
+	failoverTimeout time.Duration
+	autoPromote     bool
+
+	mu       sync.Mutex
+	state    State
+	lastSync time.Time
+	promoted chan struct{}
+}
+
+// New returns a new Service which authenticates the paired node with the
+// shared token.
+func New(store storage.StateStorer, logger logging.Logger, token string, o Options) (*Service, error) {
+	if token == "" {
+		return nil, errors.New("standby: no token provided")
+	}
+	s := &Service{
+		store:           store,
+		logger:          logger,
+		tokenHash:       sha256.Sum256([]byte(token)),
+		prefixes:        o.Prefixes,
+		failoverTimeout: o.FailoverTimeout,
+		autoPromote:     o.AutoPromote,
+		lastSync:        time.Now(),
+		promoted:        make(chan struct{}),
+	}
+	if s.prefixes == nil {
+		s.prefixes = DefaultPrefixes
+	}
+	state, err := GetState(store)
+	if err != nil {
+		return nil, err
+	}
+	s.state = state
+	return s, nil
+}
+
+// GetState returns the persisted state of the node. Nodes which were never
+// paired are active in epoch zero.
+func GetState(store storage.StateStorer) (State, error) {
+	var state State
+	err := store.Get(stateKey, &state)
+	if errors.Is(err, storage.ErrNotFound) {
+		return State{Role: RoleActive}, nil
+	}
+	if err != nil {
+		return State{}, fmt.Errorf("standby: get state: %w", err)
+	}
+	return state, nil
+}
+
+// Startable returns an error if the state store belongs to a node which must
+// not be started as an active one.
+func Startable(store storage.StateStorer) error {
+	state, err := GetState(store)
+	if err != nil {
+		return err
+	}
+	switch state.Role {
+	case RoleStandby:
+		return ErrStandby
+	case RoleFenced:
+		return ErrFenced
+	}
+	return nil
+}
+
+// State returns the current state of the node.
+func (s *Service) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+func (s *Service) setState(state State) error {
+	if err := s.store.Put(stateKey, state); err != nil {
+		return fmt.Errorf("standby: put state: %w", err)
+	}
+	s.state = state
+	return nil
+}
+
+// Authorized reports whether the token is the one shared by the pair.
+func (s *Service) Authorized(token string) bool {
+	h := sha256.Sum256([]byte(token))
+	return subtle.ConstantTimeCompare(s.tokenHash[:], h[:]) == 1
+}
+
+// Activate marks the node as active. It fails if the node must not be
+// started or if the paired node, when reachable, is active.
+func (s *Service) Activate(ctx context.Context, peer *Client) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.state.Role {
+	case RoleStandby:
+		return ErrStandby
+	case RoleFenced:
+		return ErrFenced
+	}
+	if peer != nil {
+		ps, err := peer.Status(ctx)
+		switch {
+		case err != nil:
+			s.logger.Warningf("standby: paired node unreachable: %v", err)
+		case ps.Role == RoleActive:
+			return fmt.Errorf("%w in epoch %d", ErrPeerActive, ps.Epoch)
+		}
+	}
+	if s.state.Epoch == 0 {
+		return s.setState(State{Role: RoleActive, Epoch: 1})
+	}
+	return nil
+}
+
+// Snapshot returns the replicated entries of the active node.
+func (s *Service) Snapshot() (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state.Role != RoleActive {
+		return nil, ErrNotActive
+	}
+	snapshot := &Snapshot{State: s.state, Entries: make([]Entry, 0)}
+	for _, prefix := range s.prefixes {
+		if err := s.store.Iterate(prefix, func(key, value []byte) (bool, error) {
+			snapshot.Entries = append(snapshot.Entries, Entry{Key: string(key), Value: value})
+			return false, nil
+		}); err != nil {
+			return nil, fmt.Errorf("standby: iterate %s: %w", prefix, err)
+		}
+	}
+	return snapshot, nil
+}
+
+// Watch periodically checks the paired node until the context is done. It
+// fences the node and returns ErrFenced once it detects that the paired
+// node was promoted in a later epoch.
+func (s *Service) Watch(ctx context.Context, peer *Client, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		ps, err := peer.Status(ctx)
+		if err != nil {
+			s.logger.Debugf("standby: paired node status: %v", err)
+			continue
+		}
+		if ps.Role != RoleActive {
+			continue
+		}
+
+		s.mu.Lock()
+		state := s.state
+		if ps.Epoch > state.Epoch {
+			err = s.setState(State{Role: RoleFenced, Epoch: state.Epoch})
+		}
+		s.mu.Unlock()
+
+		if ps.Epoch <= state.Epoch {
+			s.logger.Warningf("standby: paired node is active in stale epoch %d", ps.Epoch)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		s.logger.Errorf("standby: paired node was promoted in epoch %d, fencing this node", ps.Epoch)
+		return ErrFenced
+	}
+}
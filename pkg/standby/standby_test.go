@@ -0,0 +1,185 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package standby_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/standby"
+	"github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/storage"
+)
+
+const token = "secret"
+
+type node struct {
+	store   storage.StateStorer
+	service *standby.Service
+	client  *standby.Client
+	url     string
+}
+
+func newNode(t *testing.T, peer *standby.Client, o standby.Options) *node {
+	t.Helper()
+	store := mock.NewStateStore()
+	s, err := standby.New(store, logging.New(ioutil.Discard, 0), token, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s.Handler(peer))
+	t.Cleanup(ts.Close)
+	return &node{
+		store:   store,
+		service: s,
+		client:  standby.NewClient(ts.URL, token),
+		url:     ts.URL,
+	}
+}
+
+func TestReplicate(t *testing.T) {
+	ctx := context.Background()
+	primary := newNode(t, nil, standby.Options{})
+	if err := primary.service.Activate(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.store.Put("root-pin-a", "pin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.store.Put("swap_chequebook", "chequebook"); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.store.Put("non-mineable-overlay", "overlay"); err != nil {
+		t.Fatal(err)
+	}
+
+	secondary := newNode(t, primary.client, standby.Options{})
+	if err := secondary.store.Put("root-pin-stale", "pin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := secondary.service.Replicate(ctx, primary.client); err != nil {
+		t.Fatal(err)
+	}
+
+	var v string
+	for key, want := range map[string]string{
+		"root-pin-a":      "pin",
+		"swap_chequebook": "chequebook",
+	} {
+		if err := secondary.store.Get(key, &v); err != nil {
+			t.Fatalf("get %s: %v", key, err)
+		}
+		if v != want {
+			t.Fatalf("got %s %q, want %q", key, v, want)
+		}
+	}
+	for _, key := range []string{"root-pin-stale", "non-mineable-overlay"} {
+		if err := secondary.store.Get(key, &v); !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("get %s: got error %v, want %v", key, err, storage.ErrNotFound)
+		}
+	}
+	if got := secondary.service.State(); got != (standby.State{Role: standby.RoleStandby, Epoch: 1}) {
+		t.Fatalf("got state %+v", got)
+	}
+
+	// a standby node must not be started and can not be replicated
+	if err := standby.Startable(secondary.store); !errors.Is(err, standby.ErrStandby) {
+		t.Fatalf("got error %v, want %v", err, standby.ErrStandby)
+	}
+	if _, err := secondary.client.Snapshot(ctx); !errors.Is(err, standby.ErrNotActive) {
+		t.Fatalf("got error %v, want %v", err, standby.ErrNotActive)
+	}
+	// the active node does not replicate
+	if err := primary.service.Replicate(ctx, primary.client); !errors.Is(err, standby.ErrPeerActive) {
+		t.Fatalf("got error %v, want %v", err, standby.ErrPeerActive)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	ctx := context.Background()
+	primary := newNode(t, nil, standby.Options{})
+	if err := primary.service.Activate(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	secondary := newNode(t, primary.client, standby.Options{FailoverTimeout: time.Hour})
+	if err := secondary.service.Replicate(ctx, primary.client); err != nil {
+		t.Fatal(err)
+	}
+
+	// the primary was replicated within the failover timeout
+	if err := secondary.service.Promote(ctx, primary.client); !errors.Is(err, standby.ErrPrimaryAlive) {
+		t.Fatalf("got error %v, want %v", err, standby.ErrPrimaryAlive)
+	}
+
+	secondary = newNode(t, primary.client, standby.Options{})
+	if err := secondary.service.Replicate(ctx, primary.client); err != nil {
+		t.Fatal(err)
+	}
+	// the primary still answers
+	if err := secondary.service.Promote(ctx, primary.client); !errors.Is(err, standby.ErrPrimaryAlive) {
+		t.Fatalf("got error %v, want %v", err, standby.ErrPrimaryAlive)
+	}
+
+	unreachable := standby.NewClient("http://127.0.0.1:1", token)
+	if err := secondary.service.Promote(ctx, unreachable); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-secondary.service.Promoted():
+	default:
+		t.Fatal("not promoted")
+	}
+	if got := secondary.service.State(); got != (standby.State{Role: standby.RoleActive, Epoch: 2}) {
+		t.Fatalf("got state %+v", got)
+	}
+
+	// the former primary fences itself once it sees the promoted node
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := primary.service.Watch(ctx, secondary.client, 10*time.Millisecond); !errors.Is(err, standby.ErrFenced) {
+		t.Fatalf("got error %v, want %v", err, standby.ErrFenced)
+	}
+	if err := standby.Startable(primary.store); !errors.Is(err, standby.ErrFenced) {
+		t.Fatalf("got error %v, want %v", err, standby.ErrFenced)
+	}
+
+	// a node does not activate while its paired node is active
+	other := newNode(t, nil, standby.Options{})
+	if err := other.service.Activate(ctx, secondary.client); !errors.Is(err, standby.ErrPeerActive) {
+		t.Fatalf("got error %v, want %v", err, standby.ErrPeerActive)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	n := newNode(t, nil, standby.Options{})
+	c := standby.NewClient(n.url, "wrong")
+	if _, err := c.Status(context.Background()); !errors.Is(err, standby.ErrUnauthorized) {
+		t.Fatalf("got error %v, want %v", err, standby.ErrUnauthorized)
+	}
+}
+
+func TestRunAutoPromote(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	primary := newNode(t, nil, standby.Options{})
+	if err := primary.service.Activate(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	secondary := newNode(t, primary.client, standby.Options{AutoPromote: true})
+	if err := secondary.service.Replicate(ctx, primary.client); err != nil {
+		t.Fatal(err)
+	}
+
+	// the active node is lost
+	secondary.service.Run(ctx, standby.NewClient("http://127.0.0.1:1", token), 10*time.Millisecond)
+	if got := secondary.service.State(); got != (standby.State{Role: standby.RoleActive, Epoch: 2}) {
+		t.Fatalf("got state %+v", got)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package supervisor
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	m "github.com/ethsana/sana/pkg/metrics"
+)
+
+type metrics struct {
+	// all metrics fields must be exported
+	// to be able to return them by Metrics()
+	// using reflection
+
+	Panics   *prometheus.CounterVec
+	Restarts *prometheus.CounterVec
+}
+
+func newMetrics() metrics {
+	subsystem := "supervisor"
+
+	return metrics{
+		Panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "panics_count",
+			Help:      "Number of recovered panics per subsystem.",
+		}, []string{"subsystem"}),
+		Restarts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "restarts_count",
+			Help:      "Number of subsystem restarts.",
+		}, []string{"subsystem"}),
+	}
+}
+
+func (g *Group) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(g.metrics)
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package supervisor provides supervised goroutine groups which isolate
+// panics of non-critical node subsystems and restart the failed subsystems
+// with a backoff.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/ethsana/sana/pkg/logging"
+)
+
+const (
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = 5 * time.Minute
+	defaultResetAfter = 10 * time.Minute
+)
+
+// ErrPanic is returned when a supervised function panicked.
+var ErrPanic = errors.New("panic")
+
+// Options configure the restart backoff of a Group. Zero values are replaced
+// with defaults.
+type Options struct {
+	// MinBackoff is the delay before the first restart.
+	MinBackoff time.Duration
+	// MaxBackoff is the upper limit of the exponentially growing delay.
+	MaxBackoff time.Duration
+	// ResetAfter is the run time after which a subsystem is considered
+	// stable and the backoff is reset.
+	ResetAfter time.Duration
+}
+
+// Group runs subsystems in supervised goroutines.
+type Group struct {
+	logger     logging.Logger
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	resetAfter time.Duration
+	metrics    metrics
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a new Group.
+func New(logger logging.Logger, o Options) *Group {
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = defaultMinBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = defaultMaxBackoff
+	}
+	if o.MaxBackoff < o.MinBackoff {
+		o.MaxBackoff = o.MinBackoff
+	}
+	if o.ResetAfter <= 0 {
+		o.ResetAfter = defaultResetAfter
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{
+		logger:     logger,
+		minBackoff: o.MinBackoff,
+		maxBackoff: o.MaxBackoff,
+		resetAfter: o.ResetAfter,
+		metrics:    newMetrics(),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Go runs fn in a new goroutine. If fn panics or returns an error, it is
+// restarted after a backoff. It is not restarted if it returns nil or if the
+// group is closed. The context passed to fn is cancelled on Close.
+func (g *Group) Go(name string, fn func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+
+		backoff := g.minBackoff
+		for {
+			start := time.Now()
+			err := g.run(name, fn)
+			if err == nil || g.ctx.Err() != nil {
+				return
+			}
+			if time.Since(start) >= g.resetAfter {
+				backoff = g.minBackoff
+			}
+
+			g.logger.Debugf("supervisor: %s: %v", name, err)
+			g.logger.Warningf("supervisor: %s failed, restarting in %s", name, backoff)
+
+			select {
+			case <-time.After(backoff):
+			case <-g.ctx.Done():
+				return
+			}
+			g.metrics.Restarts.WithLabelValues(name).Inc()
+
+			backoff *= 2
+			if backoff > g.maxBackoff {
+				backoff = g.maxBackoff
+			}
+		}
+	}()
+}
+
+// Do calls fn and returns a recovered panic as an error wrapping ErrPanic,
+// so that a panic in a synchronously called subsystem does not take down
+// the caller.
+func (g *Group) Do(name string, fn func() error) (err error) {
+	defer g.recover(name, &err)
+	return fn()
+}
+
+func (g *Group) run(name string, fn func(ctx context.Context) error) (err error) {
+	defer g.recover(name, &err)
+	return fn(g.ctx)
+}
+
+func (g *Group) recover(name string, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	g.metrics.Panics.WithLabelValues(name).Inc()
+	g.logger.Errorf("supervisor: %s panic: %v\n%s", name, r, debug.Stack())
+	*err = fmt.Errorf("%s: %w: %v", name, ErrPanic, r)
+}
+
+// Close cancels the context of the supervised functions and waits for them
+// to return.
+func (g *Group) Close() error {
+	g.cancel()
+	g.wg.Wait()
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package supervisor_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/supervisor"
+)
+
+func newGroup(t *testing.T) *supervisor.Group {
+	t.Helper()
+
+	g := supervisor.New(logging.New(ioutil.Discard, 0), supervisor.Options{
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond,
+	})
+	t.Cleanup(func() { _ = g.Close() })
+	return g
+}
+
+func TestGoRestartsOnPanic(t *testing.T) {
+	g := newGroup(t)
+
+	var runs int32
+	done := make(chan struct{})
+	g.Go("test", func(ctx context.Context) error {
+		if atomic.AddInt32(&runs, 1) < 3 {
+			panic("boom")
+		}
+		close(done)
+		return nil
+	})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for restart")
+	}
+	if got := atomic.LoadInt32(&runs); got != 3 {
+		t.Fatalf("got %d runs, want 3", got)
+	}
+}
+
+func TestGoRestartsOnError(t *testing.T) {
+	g := newGroup(t)
+
+	var runs int32
+	done := make(chan struct{})
+	g.Go("test", func(ctx context.Context) error {
+		if atomic.AddInt32(&runs, 1) < 2 {
+			return errors.New("failed")
+		}
+		close(done)
+		return nil
+	})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for restart")
+	}
+}
+
+func TestGoStopsOnClose(t *testing.T) {
+	g := supervisor.New(logging.New(ioutil.Discard, 0), supervisor.Options{})
+
+	started := make(chan struct{})
+	g.Go("test", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+
+	closed := make(chan struct{})
+	go func() {
+		_ = g.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for close")
+	}
+}
+
+func TestDo(t *testing.T) {
+	g := newGroup(t)
+
+	err := g.Do("test", func() error {
+		panic("boom")
+	})
+	if !errors.Is(err, supervisor.ErrPanic) {
+		t.Fatalf("got error %v, want %v", err, supervisor.ErrPanic)
+	}
+
+	errTest := errors.New("test")
+	if err := g.Do("test", func() error { return errTest }); !errors.Is(err, errTest) {
+		t.Fatalf("got error %v, want %v", err, errTest)
+	}
+}
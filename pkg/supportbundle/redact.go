@@ -0,0 +1,118 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package supportbundle
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Redacted replaces secret values in the bundle.
+const Redacted = "REDACTED"
+
+// secretKeys are the parts of configuration keys whose values are secrets.
+var secretKeys = []string{"password", "authorization", "secret", "token", "private-key"}
+
+// urlPattern matches URLs in configuration values and texts.
+var urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s,;"']+`)
+
+// redactor replaces the secret values of the configuration in the collected
+// texts.
+type redactor struct {
+	replacements map[string]string
+}
+
+func newRedactor() *redactor {
+	return &redactor{replacements: make(map[string]string)}
+}
+
+// config returns a copy of the configuration settings with the values of
+// secret keys redacted and credentials, paths and queries removed from URLs,
+// as endpoint URLs often contain API keys.
+func (r *redactor) config(settings map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		redacted[k] = r.value(k, v)
+	}
+	return redacted
+}
+
+func (r *redactor) value(key string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return r.config(v)
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, e := range v {
+			values[i] = r.value(key, e)
+		}
+		return values
+	case []string:
+		values := make([]string, len(v))
+		for i, e := range v {
+			values[i] = r.string(key, e)
+		}
+		return values
+	case string:
+		return r.string(key, v)
+	}
+	return v
+}
+
+func (r *redactor) string(key, v string) string {
+	if v == "" || strings.HasSuffix(key, "-file") {
+		// empty values and paths of files with secrets are not secret
+		return v
+	}
+	for _, s := range secretKeys {
+		if strings.Contains(strings.ToLower(key), s) {
+			r.replacements[v] = Redacted
+			return Redacted
+		}
+	}
+	return urlPattern.ReplaceAllStringFunc(v, func(rawURL string) string {
+		redacted := redactURL(rawURL)
+		if redacted != rawURL {
+			r.replacements[rawURL] = redacted
+		}
+		return redacted
+	})
+}
+
+// redactURL keeps only the scheme and the host of the URL.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return Redacted
+	}
+	if u.User == nil && (u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.Fragment == "" {
+		return rawURL
+	}
+	return fmt.Sprintf("%s://%s/%s", u.Scheme, u.Host, Redacted)
+}
+
+// text replaces all secret values found in the configuration in the text.
+func (r *redactor) text(s string) string {
+	if len(r.replacements) == 0 {
+		return s
+	}
+	// replace longer values first so that values containing other values
+	// are replaced completely
+	secrets := make([]string, 0, len(r.replacements))
+	for secret := range r.replacements {
+		secrets = append(secrets, secret)
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+	oldnew := make([]string, 0, 2*len(secrets))
+	for _, secret := range secrets {
+		oldnew = append(oldnew, secret, r.replacements[secret])
+	}
+	return strings.NewReplacer(oldnew...).Replace(s)
+}
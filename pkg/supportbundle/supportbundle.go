@@ -0,0 +1,264 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package supportbundle collects the diagnostic information of a node into a
+// single gzipped tar archive which can be attached to bug reports. The bundle
+// contains the version information, the effective configuration, the tail of
+// the node log and snapshots of the debug API endpoints which describe the
+// metrics, the peer topology and the chain state. Secrets from the
+// configuration are redacted in all collected files.
+package supportbundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// defaults
+	logLines = 1000
+	timeout  = 10 * time.Second
+
+	// maxResponseSize limits the size of collected debug API responses.
+	maxResponseSize = 64 * 1024 * 1024
+)
+
+// debugEndpoints are the debug API endpoints whose responses are added to
+// the bundle.
+var debugEndpoints = []struct {
+	path string
+	name string
+}{
+	{path: "/health", name: "health.json"},
+	{path: "/addresses", name: "addresses.json"},
+	{path: "/topology", name: "topology.json"},
+	{path: "/peers", name: "peers.json"},
+	{path: "/blocklist", name: "blocklist.json"},
+	{path: "/chainstate", name: "chainstate.json"},
+	{path: "/reservestate", name: "reservestate.json"},
+	{path: "/mine/status", name: "mine_status.json"},
+	{path: "/metrics", name: "metrics.txt"},
+}
+
+type Options struct {
+	// Version of the node binary.
+	Version string
+	// Config are the effective configuration settings.
+	Config map[string]interface{}
+	// DebugAPIURL is the URL of the debug API, endpoint snapshots are not
+	// collected if it is empty.
+	DebugAPIURL string
+	// LogFile is the path of the node log, which is not collected if it is
+	// empty.
+	LogFile string
+	// LogLines is the number of the last lines of the log which are
+	// collected.
+	LogLines int
+	// Timeout of a single debug API request.
+	Timeout time.Duration
+	// Client is the HTTP client used for the debug API requests.
+	Client *http.Client
+	// Now returns the time of the bundle creation.
+	Now func() time.Time
+}
+
+// File describes a file added to the bundle.
+type File struct {
+	Name string
+	Size int
+}
+
+// Write writes the bundle as a gzipped tar archive to w. Parts of the bundle
+// which can not be collected, for example because the node is not running,
+// are listed with the reason in the errors.txt file of the bundle instead of
+// failing the whole bundle. The returned files are in the order in which they
+// were written.
+func Write(ctx context.Context, w io.Writer, o Options) ([]File, error) {
+	if o.LogLines <= 0 {
+		o.LogLines = logLines
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = timeout
+	}
+	if o.Client == nil {
+		o.Client = http.DefaultClient
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+
+	now := o.Now().UTC()
+	dir := Name(now)
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	b := &bundle{
+		tw:       tw,
+		dir:      dir,
+		modTime:  now,
+		redactor: newRedactor(),
+	}
+
+	config := b.redactor.config(o.Config)
+	configData, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("marshal config: %w", err)
+	}
+
+	version := fmt.Sprintf("version: %s\ngo: %s\nos: %s\narch: %s\ncreated: %s\n", o.Version, runtime.Version(), runtime.GOOS, runtime.GOARCH, now.Format(time.RFC3339))
+	if err := b.add("version.txt", []byte(version)); err != nil {
+		return nil, err
+	}
+	if err := b.add("config.yaml", configData); err != nil {
+		return nil, err
+	}
+
+	if o.LogFile == "" {
+		b.failed("logs.txt", errors.New("no log file provided"))
+	} else if logs, err := tail(o.LogFile, o.LogLines); err != nil {
+		b.failed("logs.txt", err)
+	} else if err := b.add("logs.txt", logs); err != nil {
+		return nil, err
+	}
+
+	if o.DebugAPIURL == "" {
+		b.failed("debug API", errors.New("no debug API URL provided"))
+	} else {
+		base := strings.TrimRight(o.DebugAPIURL, "/")
+		for _, e := range debugEndpoints {
+			data, err := get(ctx, o.Client, base+e.path, o.Timeout)
+			if err != nil {
+				b.failed(e.name, err)
+				continue
+			}
+			if err := b.add(e.name, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(b.errors) > 0 {
+		if err := b.add("errors.txt", []byte(strings.Join(b.errors, "\n")+"\n")); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return b.files, nil
+}
+
+// Name returns the name of the bundle created at the time, which is the name
+// of the directory with the files in the archive.
+func Name(t time.Time) string {
+	return "sana-support-" + t.UTC().Format("20060102-150405")
+}
+
+type bundle struct {
+	tw       *tar.Writer
+	dir      string
+	modTime  time.Time
+	redactor *redactor
+	files    []File
+	errors   []string
+}
+
+// add redacts the data and adds it to the archive.
+func (b *bundle) add(name string, data []byte) error {
+	data = []byte(b.redactor.text(string(data)))
+	err := b.tw.WriteHeader(&tar.Header{
+		Name:    b.dir + "/" + name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: b.modTime,
+	})
+	if err != nil {
+		return fmt.Errorf("write %s header: %w", name, err)
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	b.files = append(b.files, File{Name: name, Size: len(data)})
+	return nil
+}
+
+// failed records the part of the bundle which could not be collected.
+func (b *bundle) failed(name string, err error) {
+	b.errors = append(b.errors, fmt.Sprintf("%s: %v", name, err))
+}
+
+// tail returns at most the last n lines of the file.
+func tail(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// ring of the last n lines
+	lines := make([]string, n)
+	var count int
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for s.Scan() {
+		lines[count%n] = s.Text()
+		count++
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	start := 0
+	if count > n {
+		start = count - n
+	}
+	var buf bytes.Buffer
+	for i := start; i < count; i++ {
+		buf.WriteString(lines[i%n])
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func get(ctx context.Context, client *http.Client, url string, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
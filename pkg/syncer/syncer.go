@@ -49,7 +49,7 @@ type Service interface {
 type service struct {
 	logger    logging.Logger
 	ev        BlockHeightContractFilterer
-	blockTime uint64
+	blockTime time.Duration
 
 	syncMtx sync.Mutex
 	syncs   []*Sync
@@ -62,7 +62,7 @@ type service struct {
 func New(
 	logger logging.Logger,
 	ev BlockHeightContractFilterer,
-	blockTime uint64,
+	blockTime time.Duration,
 	shutdowner Shutdowner,
 ) *service {
 	return &service{
@@ -87,7 +87,7 @@ func (s *service) Worker() <-chan struct{} {
 		cancel()
 	}()
 
-	chainUpdateInterval := time.Duration(s.blockTime) / 2
+	chainUpdateInterval := s.blockTime / 2
 
 	synced := make(chan struct{})
 	closeOnce := new(sync.Once)
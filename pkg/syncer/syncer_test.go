@@ -0,0 +1,40 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package syncer_test
+
+import (
+	"context"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/syncer"
+	"github.com/ethsana/sana/pkg/transaction/backendmock"
+)
+
+func TestChainUpdateInterval(t *testing.T) {
+	var calls int64
+	backend := backendmock.New(
+		backendmock.WithBlockNumberFunc(func(context.Context) (uint64, error) {
+			atomic.AddInt64(&calls, 1)
+			return 0, nil
+		}),
+	)
+
+	s := syncer.New(logging.New(ioutil.Discard, 0), backend, 2*time.Second, nil)
+	s.Worker()
+	time.Sleep(200 * time.Millisecond)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the block number is requested once at the start and then every
+	// half of the block time
+	if got := atomic.LoadInt64(&calls); got > 1 {
+		t.Fatalf("got %d block number requests, want at most 1", got)
+	}
+}
@@ -29,7 +29,7 @@ import (
 	"github.com/ethsana/sana/pkg/storage"
 	"github.com/ethsana/sana/pkg/swarm"
 	"github.com/ethsana/sana/pkg/tracing"
-	"github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -65,7 +65,7 @@ type Tag struct {
 
 	// end-to-end tag tracing
 	ctx        context.Context     // tracing context
-	span       opentracing.Span    // tracing root span
+	span       trace.Span          // tracing root span
 	spanOnce   sync.Once           // make sure we close root span only once
 	stateStore storage.StateStorer // to persist the tag
 	logger     logging.Logger      // logger instance for logging
@@ -95,7 +95,7 @@ func (t *Tag) Context() context.Context {
 // FinishRootSpan closes the pushsync span of the tags
 func (t *Tag) FinishRootSpan() {
 	t.spanOnce.Do(func() {
-		t.span.Finish()
+		t.span.End()
 	})
 }
 
@@ -0,0 +1,380 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package takedown implements the content takedown workflow of gateway
+// operators. Takedown notices signed by one of the trusted signers deny-list
+// the referenced content and unpin it, signed counter-notices restore it.
+// Every notice and the actions taken on it are recorded in an audit log.
+package takedown
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/pinning"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+const (
+	stateKeyPrefix = "takedown_state_"
+	auditKeyPrefix = "takedown_audit_"
+	// maxClockSkew is how far in the future the timestamp of a notice may be.
+	maxClockSkew = 5 * time.Minute
+)
+
+// Kind is the kind of a notice.
+type Kind string
+
+// Kinds of the notices.
+const (
+	KindNotice        Kind = "notice"
+	KindCounterNotice Kind = "counter-notice"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionDenyList = "deny-list"
+	ActionUnpin    = "unpin"
+	ActionRestore  = "restore"
+	ActionRepin    = "repin"
+)
+
+var (
+	// ErrInvalidSignature is returned if the signature of a notice can not be
+	// recovered.
+	ErrInvalidSignature = errors.New("takedown: invalid signature")
+	// ErrUntrustedSigner is returned if a notice is not signed by one of the
+	// trusted signers.
+	ErrUntrustedSigner = errors.New("takedown: untrusted signer")
+	// ErrStaleNotice is returned if a notice is not newer than the last notice
+	// for the same reference, or if its timestamp is in the future.
+	ErrStaleNotice = errors.New("takedown: stale notice")
+	// ErrTakenDown is returned for a notice about content which is already
+	// taken down.
+	ErrTakenDown = errors.New("takedown: content already taken down")
+	// ErrNotTakenDown is returned for a counter-notice about content which is
+	// not taken down.
+	ErrNotTakenDown = errors.New("takedown: content not taken down")
+)
+
+// Notice is a takedown notice or a counter-notice about the content with the
+// reference.
+type Notice struct {
+	Kind      Kind          `json:"kind"`
+	Reference swarm.Address `json:"reference"`
+	Claimant  string        `json:"claimant"`
+	Reason    string        `json:"reason"`
+	Timestamp int64         `json:"timestamp"`
+}
+
+// Data returns the data which is signed by the signers of the notice.
+func (n *Notice) Data() []byte {
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(n.Timestamp))
+	h, _ := crypto.LegacyKeccak256([]byte(fmt.Sprintf("%s\x00%s\x00%s", n.Kind, n.Claimant, n.Reason)))
+	data := make([]byte, 0, len(n.Kind)+swarm.HashSize+len(ts)+len(h))
+	data = append(data, n.Kind...)
+	data = append(data, n.Reference.Bytes()...)
+	data = append(data, ts[:]...)
+	return append(data, h...)
+}
+
+// Sign signs the notice with the signer.
+func (n *Notice) Sign(signer crypto.Signer) ([]byte, error) {
+	return signer.Sign(n.Data())
+}
+
+// Takedown is the content which is currently taken down.
+type Takedown struct {
+	Reference swarm.Address  `json:"reference"`
+	Signer    common.Address `json:"signer"`
+	Claimant  string         `json:"claimant"`
+	Reason    string         `json:"reason"`
+	Time      time.Time      `json:"time"`
+	Unpinned  bool           `json:"unpinned"`
+}
+
+// Entry is the record of a notice in the audit log.
+type Entry struct {
+	Time      time.Time      `json:"time"`
+	Notice    Notice         `json:"notice"`
+	Signer    common.Address `json:"signer"`
+	Signature string         `json:"signature"`
+	Actions   []string       `json:"actions"`
+}
+
+// state is the persisted state of the notices about a reference. The timestamp
+// of the latest notice is kept after a restoration so that the notices can not
+// be replayed.
+type state struct {
+	Takedown  *Takedown `json:"takedown,omitempty"`
+	Timestamp int64     `json:"timestamp"`
+}
+
+// Service processes the notices and keeps the deny-list.
+type Service struct {
+	store   storage.StateStorer
+	pinning pinning.Interface
+	logger  logging.Logger
+	signers map[common.Address]struct{}
+	now     func() time.Time
+
+	noticeMu sync.Mutex // serializes the processing of notices
+	mu       sync.RWMutex
+	denied   map[string]struct{}
+}
+
+// New returns a new Service which accepts the notices of the trusted signers.
+// The deny-list is loaded from the state store.
+func New(store storage.StateStorer, pinning pinning.Interface, logger logging.Logger, signers []common.Address) (*Service, error) {
+	s := &Service{
+		store:   store,
+		pinning: pinning,
+		logger:  logger,
+		signers: make(map[common.Address]struct{}, len(signers)),
+		now:     time.Now,
+		denied:  make(map[string]struct{}),
+	}
+	for _, a := range signers {
+		s.signers[a] = struct{}{}
+	}
+	err := store.Iterate(stateKeyPrefix, func(key, value []byte) (bool, error) {
+		if !strings.HasPrefix(string(key), stateKeyPrefix) {
+			return true, nil
+		}
+		var st state
+		if err := json.Unmarshal(value, &st); err != nil {
+			return true, err
+		}
+		if st.Takedown != nil {
+			s.denied[st.Takedown.Reference.ByteString()] = struct{}{}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load deny-list: %w", err)
+	}
+	return s, nil
+}
+
+// Denied reports whether the content with the reference is taken down.
+func (s *Service) Denied(addr swarm.Address) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.denied[addr.ByteString()]
+	return ok
+}
+
+// Notice processes a signed takedown notice. The referenced content is added
+// to the deny-list and its pin is removed.
+func (s *Service) Notice(ctx context.Context, n Notice, signature []byte) (*Entry, error) {
+	s.noticeMu.Lock()
+	defer s.noticeMu.Unlock()
+
+	n.Kind = KindNotice
+	signer, st, err := s.verify(&n, signature)
+	if err != nil {
+		return nil, err
+	}
+	if st.Takedown != nil {
+		return nil, ErrTakenDown
+	}
+
+	e := &Entry{
+		Time:      s.now(),
+		Notice:    n,
+		Signer:    signer,
+		Signature: hex.EncodeToString(signature),
+		Actions:   []string{ActionDenyList},
+	}
+	t := &Takedown{
+		Reference: n.Reference,
+		Signer:    signer,
+		Claimant:  n.Claimant,
+		Reason:    n.Reason,
+		Time:      e.Time,
+	}
+
+	s.setDenied(n.Reference, true)
+
+	pinned, err := s.pinning.HasPin(n.Reference)
+	if err != nil {
+		s.logger.Debugf("takedown: has pin %s: %v", n.Reference, err)
+		s.logger.Error("takedown: unable to check pin")
+	}
+	if pinned {
+		if err := s.pinning.DeletePin(ctx, n.Reference); err != nil {
+			s.logger.Debugf("takedown: unpin %s: %v", n.Reference, err)
+			s.logger.Error("takedown: unable to unpin content")
+		} else {
+			t.Unpinned = true
+			e.Actions = append(e.Actions, ActionUnpin)
+		}
+	}
+
+	if err := s.save(n.Reference, state{Takedown: t, Timestamp: n.Timestamp}, e); err != nil {
+		s.setDenied(n.Reference, false)
+		return nil, err
+	}
+	return e, nil
+}
+
+// CounterNotice processes a signed counter-notice. The referenced content is
+// removed from the deny-list and pinned again if its pin was removed by the
+// takedown.
+func (s *Service) CounterNotice(ctx context.Context, n Notice, signature []byte) (*Entry, error) {
+	s.noticeMu.Lock()
+	defer s.noticeMu.Unlock()
+
+	n.Kind = KindCounterNotice
+	signer, st, err := s.verify(&n, signature)
+	if err != nil {
+		return nil, err
+	}
+	if st.Takedown == nil {
+		return nil, ErrNotTakenDown
+	}
+
+	e := &Entry{
+		Time:      s.now(),
+		Notice:    n,
+		Signer:    signer,
+		Signature: hex.EncodeToString(signature),
+		Actions:   []string{ActionRestore},
+	}
+
+	if st.Takedown.Unpinned {
+		if err := s.pinning.CreatePin(ctx, n.Reference, true); err != nil {
+			s.logger.Debugf("takedown: repin %s: %v", n.Reference, err)
+			s.logger.Error("takedown: unable to pin restored content")
+		} else {
+			e.Actions = append(e.Actions, ActionRepin)
+		}
+	}
+
+	if err := s.save(n.Reference, state{Timestamp: n.Timestamp}, e); err != nil {
+		return nil, err
+	}
+	s.setDenied(n.Reference, false)
+	return e, nil
+}
+
+// Takedowns returns the content which is currently taken down, ordered by the
+// time of the takedown.
+func (s *Service) Takedowns() ([]Takedown, error) {
+	var takedowns []Takedown
+	err := s.store.Iterate(stateKeyPrefix, func(key, value []byte) (bool, error) {
+		if !strings.HasPrefix(string(key), stateKeyPrefix) {
+			return true, nil
+		}
+		var st state
+		if err := json.Unmarshal(value, &st); err != nil {
+			return true, err
+		}
+		if st.Takedown != nil {
+			takedowns = append(takedowns, *st.Takedown)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(takedowns, func(i, j int) bool {
+		return takedowns[i].Time.Before(takedowns[j].Time)
+	})
+	return takedowns, nil
+}
+
+// Audit returns the entries of the audit log ordered by time. If the
+// reference is not the zero address, only the entries of the notices about
+// the reference are returned. Entries before since are skipped.
+func (s *Service) Audit(reference swarm.Address, since time.Time) ([]Entry, error) {
+	var entries []Entry
+	err := s.store.Iterate(auditKeyPrefix, func(key, value []byte) (bool, error) {
+		if !strings.HasPrefix(string(key), auditKeyPrefix) {
+			return true, nil
+		}
+		var e Entry
+		if err := json.Unmarshal(value, &e); err != nil {
+			return true, err
+		}
+		if !reference.IsZero() && !e.Notice.Reference.Equal(reference) {
+			return false, nil
+		}
+		if e.Time.Before(since) {
+			return false, nil
+		}
+		entries = append(entries, e)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries, nil
+}
+
+// verify checks the signature and the timestamp of the notice and returns its
+// signer with the current state of the reference.
+func (s *Service) verify(n *Notice, signature []byte) (common.Address, *state, error) {
+	pub, err := crypto.Recover(signature, n.Data())
+	if err != nil {
+		return common.Address{}, nil, ErrInvalidSignature
+	}
+	a, err := crypto.NewEthereumAddress(*pub)
+	if err != nil {
+		return common.Address{}, nil, ErrInvalidSignature
+	}
+	signer := common.BytesToAddress(a)
+	if _, ok := s.signers[signer]; !ok {
+		return common.Address{}, nil, ErrUntrustedSigner
+	}
+
+	if time.Unix(n.Timestamp, 0).After(s.now().Add(maxClockSkew)) {
+		return common.Address{}, nil, ErrStaleNotice
+	}
+	st := new(state)
+	if err := s.store.Get(stateKey(n.Reference), st); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return common.Address{}, nil, err
+	}
+	if n.Timestamp <= st.Timestamp {
+		return common.Address{}, nil, ErrStaleNotice
+	}
+	return signer, st, nil
+}
+
+func (s *Service) setDenied(reference swarm.Address, denied bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if denied {
+		s.denied[reference.ByteString()] = struct{}{}
+	} else {
+		delete(s.denied, reference.ByteString())
+	}
+}
+
+func (s *Service) save(reference swarm.Address, st state, e *Entry) error {
+	if err := s.store.Put(stateKey(reference), st); err != nil {
+		return err
+	}
+	return s.store.Put(fmt.Sprintf("%s%020d_%s", auditKeyPrefix, e.Time.UnixNano(), reference), e)
+}
+
+func stateKey(reference swarm.Address) string {
+	return stateKeyPrefix + reference.String()
+}
@@ -0,0 +1,177 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package takedown_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/crypto"
+	"github.com/ethsana/sana/pkg/logging"
+	pinning "github.com/ethsana/sana/pkg/pinning/mock"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/swarm/test"
+	"github.com/ethsana/sana/pkg/takedown"
+)
+
+func newSigner(t *testing.T) (crypto.Signer, common.Address) {
+	t.Helper()
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.NewDefaultSigner(key)
+	a, err := signer.EthereumAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, a
+}
+
+func sign(t *testing.T, signer crypto.Signer, kind takedown.Kind, ref swarm.Address, ts int64) (takedown.Notice, []byte) {
+	t.Helper()
+	n := takedown.Notice{
+		Kind:      kind,
+		Reference: ref,
+		Claimant:  "rights holder",
+		Reason:    "infringement",
+		Timestamp: ts,
+	}
+	sig, err := n.Sign(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n, sig
+}
+
+func TestTakedown(t *testing.T) {
+	ctx := context.Background()
+	trusted, trustedAddr := newSigner(t)
+	untrusted, _ := newSigner(t)
+	store := statestore.NewStateStore()
+	pins := pinning.NewServiceMock()
+	logger := logging.New(ioutil.Discard, 0)
+
+	s, err := takedown.New(store, pins, logger, []common.Address{trustedAddr})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref := test.RandomAddress()
+	if err := pins.CreatePin(ctx, ref, true); err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Now().Unix()
+
+	n, sig := sign(t, untrusted, takedown.KindNotice, ref, ts)
+	if _, err := s.Notice(ctx, n, sig); !errors.Is(err, takedown.ErrUntrustedSigner) {
+		t.Fatalf("got error %v, want %v", err, takedown.ErrUntrustedSigner)
+	}
+	n, sig = sign(t, trusted, takedown.KindCounterNotice, ref, ts)
+	if _, err := s.Notice(ctx, n, sig); !errors.Is(err, takedown.ErrUntrustedSigner) {
+		t.Fatalf("counter-notice signature accepted as notice: %v", err)
+	}
+	n, sig = sign(t, trusted, takedown.KindNotice, ref, time.Now().Add(time.Hour).Unix())
+	if _, err := s.Notice(ctx, n, sig); !errors.Is(err, takedown.ErrStaleNotice) {
+		t.Fatalf("got error %v, want %v", err, takedown.ErrStaleNotice)
+	}
+
+	n, sig = sign(t, trusted, takedown.KindNotice, ref, ts)
+	e, err := s.Notice(ctx, n, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Signer != trustedAddr || !reflect.DeepEqual(e.Actions, []string{takedown.ActionDenyList, takedown.ActionUnpin}) {
+		t.Fatalf("got entry %+v", e)
+	}
+	if !s.Denied(ref) || s.Denied(test.RandomAddress()) {
+		t.Fatal("deny-list not updated")
+	}
+	if has, _ := pins.HasPin(ref); has {
+		t.Fatal("content not unpinned")
+	}
+	if _, err := s.Notice(ctx, n, sig); !errors.Is(err, takedown.ErrStaleNotice) {
+		t.Fatalf("got error %v, want %v", err, takedown.ErrStaleNotice)
+	}
+	n, sig = sign(t, trusted, takedown.KindNotice, ref, ts+1)
+	if _, err := s.Notice(ctx, n, sig); !errors.Is(err, takedown.ErrTakenDown) {
+		t.Fatalf("got error %v, want %v", err, takedown.ErrTakenDown)
+	}
+
+	// the deny-list is restored from the state store
+	s, err = takedown.New(store, pins, logger, []common.Address{trustedAddr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Denied(ref) {
+		t.Fatal("deny-list not loaded")
+	}
+	takedowns, err := s.Takedowns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(takedowns) != 1 || !takedowns[0].Reference.Equal(ref) || !takedowns[0].Unpinned {
+		t.Fatalf("got takedowns %+v", takedowns)
+	}
+
+	n, sig = sign(t, trusted, takedown.KindCounterNotice, ref, ts)
+	if _, err := s.CounterNotice(ctx, n, sig); !errors.Is(err, takedown.ErrStaleNotice) {
+		t.Fatalf("got error %v, want %v", err, takedown.ErrStaleNotice)
+	}
+	n, sig = sign(t, trusted, takedown.KindCounterNotice, ref, ts+2)
+	e, err = s.CounterNotice(ctx, n, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(e.Actions, []string{takedown.ActionRestore, takedown.ActionRepin}) {
+		t.Fatalf("got entry %+v", e)
+	}
+	if s.Denied(ref) {
+		t.Fatal("content not restored")
+	}
+	if has, _ := pins.HasPin(ref); !has {
+		t.Fatal("content not pinned again")
+	}
+	if _, err := s.CounterNotice(ctx, n, sig); !errors.Is(err, takedown.ErrStaleNotice) {
+		t.Fatalf("got error %v, want %v", err, takedown.ErrStaleNotice)
+	}
+	n, sig = sign(t, trusted, takedown.KindCounterNotice, ref, ts+3)
+	if _, err := s.CounterNotice(ctx, n, sig); !errors.Is(err, takedown.ErrNotTakenDown) {
+		t.Fatalf("got error %v, want %v", err, takedown.ErrNotTakenDown)
+	}
+	if takedowns, err := s.Takedowns(); err != nil || len(takedowns) != 0 {
+		t.Fatalf("got takedowns %+v, error %v", takedowns, err)
+	}
+
+	other := test.RandomAddress()
+	n, sig = sign(t, trusted, takedown.KindNotice, other, ts)
+	if _, err := s.Notice(ctx, n, sig); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := s.Audit(swarm.ZeroAddress, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d audit entries, want 3", len(entries))
+	}
+	entries, err = s.Audit(ref, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Notice.Kind != takedown.KindNotice || entries[1].Notice.Kind != takedown.KindCounterNotice {
+		t.Fatalf("got audit entries %+v", entries)
+	}
+	if entries, err := s.Audit(swarm.ZeroAddress, time.Now().Add(time.Hour)); err != nil || len(entries) != 0 {
+		t.Fatalf("got audit entries %+v, error %v", entries, err)
+	}
+}
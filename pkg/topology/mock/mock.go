@@ -22,6 +22,7 @@ type mock struct {
 	addPeersErr     error
 	isWithinFunc    func(c swarm.Address) bool
 	marshalJSONFunc func() ([]byte, error)
+	snapshot        *topology.KadParams
 	mtx             sync.Mutex
 }
 
@@ -61,6 +62,12 @@ func WithMarshalJSONFunc(f func() ([]byte, error)) Option {
 	})
 }
 
+func WithSnapshot(s *topology.KadParams) Option {
+	return optionFunc(func(d *mock) {
+		d.snapshot = s
+	})
+}
+
 func WithIsWithinFunc(f func(swarm.Address) bool) Option {
 	return optionFunc(func(d *mock) {
 		d.isWithinFunc = f
@@ -210,6 +217,9 @@ func (d *mock) EachPeerRev(f topology.EachPeerFunc) (err error) {
 }
 
 func (d *mock) Snapshot() *topology.KadParams {
+	if d.snapshot != nil {
+		return d.snapshot
+	}
 	return new(topology.KadParams)
 }
 
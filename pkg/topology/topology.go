@@ -123,6 +123,16 @@ type KadBins struct {
 	Bin31 BinInfo `json:"bin_31"`
 }
 
+// List returns the bins ordered by their proximity order.
+func (b *KadBins) List() []BinInfo {
+	return []BinInfo{
+		b.Bin0, b.Bin1, b.Bin2, b.Bin3, b.Bin4, b.Bin5, b.Bin6, b.Bin7,
+		b.Bin8, b.Bin9, b.Bin10, b.Bin11, b.Bin12, b.Bin13, b.Bin14, b.Bin15,
+		b.Bin16, b.Bin17, b.Bin18, b.Bin19, b.Bin20, b.Bin21, b.Bin22, b.Bin23,
+		b.Bin24, b.Bin25, b.Bin26, b.Bin27, b.Bin28, b.Bin29, b.Bin30, b.Bin31,
+	}
+}
+
 type KadParams struct {
 	Base           string    `json:"baseAddr"`       // base address string
 	Population     int       `json:"population"`     // known
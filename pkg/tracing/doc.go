@@ -5,7 +5,9 @@
 /*
 Package tracing helps with the propagation of the tracing span through context
 in the system. It does this for operations contained to single node, as well as
-across nodes, by injecting special headers.
+across nodes, by injecting special headers. The spans are created with
+OpenTelemetry and exported to a Jaeger agent or, with the OTLP over gRPC or
+HTTP, to an OpenTelemetry collector.
 
 To use the tracing package, a Tracer instance must be created, which contains
 functions for starting new span contexts, injecting them in other data, and
@@ -15,8 +17,9 @@ To use the tracing package a Tracer instance must be created:
 
 	tracer, tracerCloser, err := tracing.NewTracer(&tracing.Options{
 		Enabled:     true,
-		Endpoint:    "127.0.0.1:6831",
+		Endpoint:    "127.0.0.1:4317",
 		ServiceName: "bee",
+		Exporter:    tracing.ExporterOTLPGRPC,
 	})
 	if err != nil {
 		// handle error
@@ -29,9 +32,12 @@ them in other data, and extracting the active span them from the context:
 
 	span, _, ctx := tracer.StartSpanFromContext(ctx, "operation-name", nil)
 
-Once the operation is finished, the open span should be finished:
+Once the operation is finished, the open span should be ended, with the error
+of the operation if there is one:
 
-	span.Finish()
+	span.End()
+	// or
+	tracing.EndSpan(span, err)
 
 The tracing package also provides a function for creating a logger which will
 inject a "traceid" field entry to the log line, which helps in finding out which
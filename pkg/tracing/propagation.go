@@ -0,0 +1,147 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The span contexts are propagated in the formats of the Jaeger tracer which
+// the nodes used before OpenTelemetry, so that the traces continue across
+// the nodes of both versions.
+
+// binarySize is the size of the binary encoding of a span context without
+// baggage: trace id, span id, parent span id, flags and baggage count.
+const binarySize = 16 + 8 + 8 + 1 + 4
+
+const (
+	jaegerFlagSampled = 1
+)
+
+var errMalformedContext = errors.New("malformed tracing context")
+
+// marshalBinary encodes the span context in the binary format of the Jaeger
+// tracer.
+func marshalBinary(c trace.SpanContext) []byte {
+	b := make([]byte, binarySize)
+	traceID, spanID := c.TraceID(), c.SpanID()
+	copy(b[:16], traceID[:])
+	copy(b[16:24], spanID[:])
+	// the parent span id at b[24:32] is not used by the receivers
+	if c.IsSampled() {
+		b[32] = jaegerFlagSampled
+	}
+	// no baggage
+	return b
+}
+
+// unmarshalBinary decodes the span context from the binary format of the
+// Jaeger tracer, ignoring its baggage.
+func unmarshalBinary(b []byte) (trace.SpanContext, error) {
+	if len(b) < binarySize {
+		return trace.SpanContext{}, errMalformedContext
+	}
+	var (
+		traceID trace.TraceID
+		spanID  trace.SpanID
+	)
+	copy(traceID[:], b[:16])
+	copy(spanID[:], b[16:24])
+	if int32(binary.BigEndian.Uint32(b[33:37])) < 0 {
+		return trace.SpanContext{}, errMalformedContext
+	}
+	return newRemoteSpanContext(traceID, spanID, b[32]&jaegerFlagSampled != 0)
+}
+
+// jaegerHeaderPropagator propagates the span contexts in the
+// TraceContextHeaderName header, in the text format of the Jaeger tracer
+// {trace-id}:{span-id}:{parent-span-id}:{flags}.
+type jaegerHeaderPropagator struct{}
+
+var _ propagation.TextMapPropagator = jaegerHeaderPropagator{}
+
+func (jaegerHeaderPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	c := trace.SpanContextFromContext(ctx)
+	if !c.IsValid() {
+		return
+	}
+	var flags int
+	if c.IsSampled() {
+		flags = jaegerFlagSampled
+	}
+	carrier.Set(TraceContextHeaderName, fmt.Sprintf("%s:%s:0:%x", c.TraceID(), c.SpanID(), flags))
+}
+
+func (jaegerHeaderPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	v := carrier.Get(TraceContextHeaderName)
+	if v == "" {
+		return ctx
+	}
+	parts := strings.Split(v, ":")
+	if len(parts) != 4 || len(parts[0]) > 32 || len(parts[1]) > 16 {
+		return ctx
+	}
+
+	var (
+		traceID trace.TraceID
+		spanID  trace.SpanID
+	)
+	// the leading zeros of the ids may be omitted
+	t, err := hex.DecodeString(leftPad(parts[0], 32))
+	if err != nil {
+		return ctx
+	}
+	copy(traceID[:], t)
+	s, err := hex.DecodeString(leftPad(parts[1], 16))
+	if err != nil {
+		return ctx
+	}
+	copy(spanID[:], s)
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return ctx
+	}
+
+	c, err := newRemoteSpanContext(traceID, spanID, flags&jaegerFlagSampled != 0)
+	if err != nil {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, c)
+}
+
+func (jaegerHeaderPropagator) Fields() []string {
+	return []string{TraceContextHeaderName}
+}
+
+func newRemoteSpanContext(traceID trace.TraceID, spanID trace.SpanID, sampled bool) (trace.SpanContext, error) {
+	var flags trace.TraceFlags
+	if sampled {
+		flags = trace.FlagsSampled
+	}
+	c := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+		Remote:     true,
+	})
+	if !c.IsValid() {
+		return trace.SpanContext{}, errMalformedContext
+	}
+	return c, nil
+}
+
+// leftPad pads the hex encoded id with the omitted leading zeros.
+func leftPad(id string, size int) string {
+	return strings.Repeat("0", size-len(id)) + id
+}
@@ -5,20 +5,29 @@
 package tracing
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/ethsana/sana/pkg/logging"
 	"github.com/ethsana/sana/pkg/p2p"
-	"github.com/opentracing/opentracing-go"
+	"github.com/ethsana/sana/pkg/swarm"
 	"github.com/sirupsen/logrus"
-	"github.com/uber/jaeger-client-go"
-	"github.com/uber/jaeger-client-go/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -27,27 +36,43 @@ var (
 	ErrContextNotFound = errors.New("tracing context not found")
 
 	// noopTracer is the tracer that does nothing to handle a nil Tracer usage.
-	noopTracer = &Tracer{tracer: new(opentracing.NoopTracer)}
-)
+	noopTracer = &Tracer{tracer: trace.NewNoopTracerProvider().Tracer("")}
 
-// contextKey is used to reference a tracing context span as context value.
-type contextKey struct{}
+	// httpPropagator propagates the span contexts in the W3C trace context
+	// headers and in the header of the former Jaeger tracer.
+	httpPropagator = propagation.NewCompositeTextMapPropagator(jaegerHeaderPropagator{}, propagation.TraceContext{})
+)
 
 // LogField is the key in log message field that holds tracing id value.
 const LogField = "traceid"
 
 const (
-	// TraceContextHeaderName is the http header name used to propagate tracing context.
+	// TraceContextHeaderName is the http header name used to propagate
+	// tracing context in the format of the Jaeger tracer, in addition to the
+	// W3C traceparent header.
 	TraceContextHeaderName = "swarm-trace-id"
+)
 
-	// TraceBaggageHeaderPrefix is the prefix for http headers used to propagate baggage.
-	TraceBaggageHeaderPrefix = "swarmctx-"
+// Exporters of the spans.
+const (
+	// ExporterJaeger exports the spans to a Jaeger agent.
+	ExporterJaeger = "jaeger"
+	// ExporterOTLPGRPC exports the spans to an OpenTelemetry collector with
+	// the OTLP over gRPC.
+	ExporterOTLPGRPC = "otlp-grpc"
+	// ExporterOTLPHTTP exports the spans to an OpenTelemetry collector with
+	// the OTLP over HTTP.
+	ExporterOTLPHTTP = "otlp-http"
 )
 
+// shutdownTimeout is the time in which the remaining spans are exported
+// when the tracer is closed.
+const shutdownTimeout = 5 * time.Second
+
 // Tracer connect to a tracing server and handles tracing spans and contexts
-// by using opentracing Tracer.
+// by using OpenTelemetry Tracer.
 type Tracer struct {
-	tracer opentracing.Tracer
+	tracer trace.Tracer
 }
 
 // Options are optional parameters for Tracer constructor.
@@ -55,6 +80,11 @@ type Options struct {
 	Enabled     bool
 	Endpoint    string
 	ServiceName string
+	// Exporter is one of the ExporterJaeger, ExporterOTLPGRPC and
+	// ExporterOTLPHTTP, ExporterJaeger if empty.
+	Exporter string
+	// Insecure disables the TLS of the connections of the OTLP exporters.
+	Insecure bool
 }
 
 // NewTracer creates a new Tracer and returns a closer which needs to be closed
@@ -63,108 +93,125 @@ func NewTracer(o *Options) (*Tracer, io.Closer, error) {
 	if o == nil {
 		o = new(Options)
 	}
-
-	cfg := config.Configuration{
-		Disabled:    !o.Enabled,
-		ServiceName: o.ServiceName,
-		Sampler: &config.SamplerConfig{
-			Type:  jaeger.SamplerTypeConst,
-			Param: 1,
-		},
-		Reporter: &config.ReporterConfig{
-			LogSpans:            true,
-			BufferFlushInterval: 1 * time.Second,
-			LocalAgentHostPort:  o.Endpoint,
-		},
-		Headers: &jaeger.HeadersConfig{
-			TraceContextHeaderName:   TraceContextHeaderName,
-			TraceBaggageHeaderPrefix: TraceBaggageHeaderPrefix,
-		},
+	if !o.Enabled {
+		return noopTracer, closerFunc(func() error { return nil }), nil
 	}
 
-	t, closer, err := cfg.NewTracer()
+	exporter, err := newExporter(o)
 	if err != nil {
 		return nil, nil, err
 	}
-	return &Tracer{tracer: t}, closer, nil
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(time.Second)),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(o.ServiceName))),
+	)
+	closer := closerFunc(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return provider.Shutdown(ctx)
+	})
+	return &Tracer{tracer: provider.Tracer("github.com/ethsana/sana")}, closer, nil
+}
+
+func newExporter(o *Options) (sdktrace.SpanExporter, error) {
+	switch o.Exporter {
+	case "", ExporterJaeger:
+		var opts []jaeger.AgentEndpointOption
+		if o.Endpoint != "" {
+			host, port, err := net.SplitHostPort(o.Endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("jaeger agent endpoint: %w", err)
+			}
+			opts = append(opts, jaeger.WithAgentHost(host), jaeger.WithAgentPort(port))
+		}
+		return jaeger.New(jaeger.WithAgentEndpoint(opts...))
+	case ExporterOTLPGRPC:
+		var opts []otlptracegrpc.Option
+		if o.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(o.Endpoint))
+		}
+		if o.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptrace.New(context.Background(), otlptracegrpc.NewClient(opts...))
+	case ExporterOTLPHTTP:
+		var opts []otlptracehttp.Option
+		if o.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(o.Endpoint))
+		}
+		if o.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptrace.New(context.Background(), otlptracehttp.NewClient(opts...))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", o.Exporter)
+	}
 }
 
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
 // StartSpanFromContext starts a new tracing span that is either a root one or a
 // child of existing one from the provided Context. If logger is provided, a new
 // log Entry will be returned with "traceid" log field.
-func (t *Tracer) StartSpanFromContext(ctx context.Context, operationName string, l logging.Logger, opts ...opentracing.StartSpanOption) (opentracing.Span, *logrus.Entry, context.Context) {
+func (t *Tracer) StartSpanFromContext(ctx context.Context, operationName string, l logging.Logger, opts ...trace.SpanStartOption) (trace.Span, *logrus.Entry, context.Context) {
 	if t == nil {
 		t = noopTracer
 	}
 
-	var span opentracing.Span
-	if parentContext := FromContext(ctx); parentContext != nil {
-		opts = append(opts, opentracing.ChildOf(parentContext))
-		span = t.tracer.StartSpan(operationName, opts...)
-	} else {
-		span = t.tracer.StartSpan(operationName, opts...)
+	ctx, span := t.tracer.Start(ctx, operationName, opts...)
+	return span, loggerWithTraceID(span.SpanContext(), l), ctx
+}
+
+// EndSpan ends the span, setting its status to the error if it is not nil.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 	}
-	sc := span.Context()
-	return span, loggerWithTraceID(sc, l), WithContext(ctx, sc)
+	span.End()
+}
+
+// AccountingAttributes are the attributes of the spans of the accounting with
+// the peer.
+func AccountingAttributes(peer swarm.Address, price uint64) trace.SpanStartOption {
+	return trace.WithAttributes(
+		attribute.String("peer", peer.String()),
+		attribute.Int64("price", int64(price)),
+	)
 }
 
 // AddContextHeader adds a tracing span context to provided p2p Headers from
 // the go context. If the tracing span context is not present in go context,
 // ErrContextNotFound is returned.
 func (t *Tracer) AddContextHeader(ctx context.Context, headers p2p.Headers) error {
-	if t == nil {
-		t = noopTracer
-	}
-
 	c := FromContext(ctx)
-	if c == nil {
+	if !c.IsValid() {
 		return ErrContextNotFound
 	}
 
-	var b bytes.Buffer
-	w := bufio.NewWriter(&b)
-	if err := t.tracer.Inject(c, opentracing.Binary, w); err != nil {
-		return err
-	}
-	if err := w.Flush(); err != nil {
-		return err
-	}
-
-	headers[p2p.HeaderNameTracingSpanContext] = b.Bytes()
+	headers[p2p.HeaderNameTracingSpanContext] = marshalBinary(c)
 
 	return nil
 }
 
 // FromHeaders returns tracing span context from p2p Headers. If the tracing
 // span context is not present in go context, ErrContextNotFound is returned.
-func (t *Tracer) FromHeaders(headers p2p.Headers) (opentracing.SpanContext, error) {
-	if t == nil {
-		t = noopTracer
-	}
-
+func (t *Tracer) FromHeaders(headers p2p.Headers) (trace.SpanContext, error) {
 	v := headers[p2p.HeaderNameTracingSpanContext]
 	if v == nil {
-		return nil, ErrContextNotFound
-	}
-	c, err := t.tracer.Extract(opentracing.Binary, bytes.NewReader(v))
-	if err != nil {
-		if errors.Is(err, opentracing.ErrSpanContextNotFound) {
-			return nil, ErrContextNotFound
-		}
-		return nil, err
+		return trace.SpanContext{}, ErrContextNotFound
 	}
-
-	return c, nil
+	return unmarshalBinary(v)
 }
 
 // WithContextFromHeaders returns a new context with injected tracing span
 // context if they are found in p2p Headers. If the tracing span context is not
 // present in go context, ErrContextNotFound is returned.
 func (t *Tracer) WithContextFromHeaders(ctx context.Context, headers p2p.Headers) (context.Context, error) {
-	if t == nil {
-		t = noopTracer
-	}
-
 	c, err := t.FromHeaders(headers)
 	if err != nil {
 		return ctx, err
@@ -176,33 +223,21 @@ func (t *Tracer) WithContextFromHeaders(ctx context.Context, headers p2p.Headers
 // from the go context. If the tracing span context is not present in
 // go context, ErrContextNotFound is returned.
 func (t *Tracer) AddContextHTTPHeader(ctx context.Context, headers http.Header) error {
-	if t == nil {
-		t = noopTracer
-	}
-
-	c := FromContext(ctx)
-	if c == nil {
+	if !FromContext(ctx).IsValid() {
 		return ErrContextNotFound
 	}
 
-	carrier := opentracing.HTTPHeadersCarrier(headers)
-	return t.tracer.Inject(c, opentracing.HTTPHeaders, carrier)
+	httpPropagator.Inject(ctx, propagation.HeaderCarrier(headers))
+	return nil
 }
 
 // FromHTTPHeaders returns tracing span context from HTTP headers. If the tracing
 // span context is not present in go context, ErrContextNotFound is returned.
-func (t *Tracer) FromHTTPHeaders(headers http.Header) (opentracing.SpanContext, error) {
-	if t == nil {
-		t = noopTracer
-	}
-
-	carrier := opentracing.HTTPHeadersCarrier(headers)
-	c, err := t.tracer.Extract(opentracing.HTTPHeaders, carrier)
-	if err != nil {
-		if errors.Is(err, opentracing.ErrSpanContextNotFound) {
-			return nil, ErrContextNotFound
-		}
-		return nil, err
+func (t *Tracer) FromHTTPHeaders(headers http.Header) (trace.SpanContext, error) {
+	ctx := httpPropagator.Extract(context.Background(), propagation.HeaderCarrier(headers))
+	c := trace.SpanContextFromContext(ctx)
+	if !c.IsValid() {
+		return trace.SpanContext{}, ErrContextNotFound
 	}
 
 	return c, nil
@@ -212,10 +247,6 @@ func (t *Tracer) FromHTTPHeaders(headers http.Header) (opentracing.SpanContext,
 // context if they are found in HTTP headers. If the tracing span context is not
 // present in go context, ErrContextNotFound is returned.
 func (t *Tracer) WithContextFromHTTPHeaders(ctx context.Context, headers http.Header) (context.Context, error) {
-	if t == nil {
-		t = noopTracer
-	}
-
 	c, err := t.FromHTTPHeaders(headers)
 	if err != nil {
 		return ctx, err
@@ -225,18 +256,14 @@ func (t *Tracer) WithContextFromHTTPHeaders(ctx context.Context, headers http.He
 }
 
 // WithContext adds tracing span context to go context.
-func WithContext(ctx context.Context, c opentracing.SpanContext) context.Context {
-	return context.WithValue(ctx, contextKey{}, c)
+func WithContext(ctx context.Context, c trace.SpanContext) context.Context {
+	return trace.ContextWithSpanContext(ctx, c)
 }
 
 // FromContext return tracing span context from go context. If the tracing span
-// context is not present in go context, nil is returned.
-func FromContext(ctx context.Context) opentracing.SpanContext {
-	c, ok := ctx.Value(contextKey{}).(opentracing.SpanContext)
-	if !ok {
-		return nil
-	}
-	return c
+// context is not present in go context, an invalid span context is returned.
+func FromContext(ctx context.Context) trace.SpanContext {
+	return trace.SpanContextFromContext(ctx)
 }
 
 // NewLoggerWithTraceID creates a new log Entry with "traceid" field added if it
@@ -245,17 +272,12 @@ func NewLoggerWithTraceID(ctx context.Context, l logging.Logger) *logrus.Entry {
 	return loggerWithTraceID(FromContext(ctx), l)
 }
 
-func loggerWithTraceID(sc opentracing.SpanContext, l logging.Logger) *logrus.Entry {
+func loggerWithTraceID(sc trace.SpanContext, l logging.Logger) *logrus.Entry {
 	if l == nil {
 		return nil
 	}
-	jsc, ok := sc.(jaeger.SpanContext)
-	if !ok {
-		return l.NewEntry()
-	}
-	traceID := jsc.TraceID()
-	if !traceID.IsValid() {
+	if !sc.HasTraceID() {
 		return l.NewEntry()
 	}
-	return l.WithField(LogField, traceID.String())
+	return l.WithField(LogField, sc.TraceID().String())
 }
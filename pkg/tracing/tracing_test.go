@@ -5,16 +5,22 @@
 package tracing_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"reflect"
 	"testing"
 
 	"github.com/ethsana/sana/pkg/logging"
 	"github.com/ethsana/sana/pkg/p2p"
+	"github.com/ethsana/sana/pkg/swarm"
 	"github.com/ethsana/sana/pkg/tracing"
-	"github.com/uber/jaeger-client-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestSpanFromHeaders(t *testing.T) {
@@ -22,7 +28,7 @@ func TestSpanFromHeaders(t *testing.T) {
 	defer closer.Close()
 
 	span, _, ctx := tracer.StartSpanFromContext(context.Background(), "some-operation", nil)
-	defer span.Finish()
+	defer span.End()
 
 	headers := make(p2p.Headers)
 	if err := tracer.AddContextHeader(ctx, headers); err != nil {
@@ -38,7 +44,8 @@ func TestSpanFromHeaders(t *testing.T) {
 		t.Fatal("got empty span context")
 	}
 
-	wantSpanContext := span.Context()
+	// the span contexts from the headers are the ones of the remote spans
+	wantSpanContext := span.SpanContext().WithRemote(true)
 	if fmt.Sprint(wantSpanContext) == "" {
 		t.Fatal("got empty start span context")
 	}
@@ -53,7 +60,7 @@ func TestSpanWithContextFromHeaders(t *testing.T) {
 	defer closer.Close()
 
 	span, _, ctx := tracer.StartSpanFromContext(context.Background(), "some-operation", nil)
-	defer span.Finish()
+	defer span.End()
 
 	headers := make(p2p.Headers)
 	if err := tracer.AddContextHeader(ctx, headers); err != nil {
@@ -70,7 +77,7 @@ func TestSpanWithContextFromHeaders(t *testing.T) {
 		t.Fatal("got empty span context")
 	}
 
-	wantSpanContext := span.Context()
+	wantSpanContext := span.SpanContext().WithRemote(true)
 	if fmt.Sprint(wantSpanContext) == "" {
 		t.Fatal("got empty start span context")
 	}
@@ -85,9 +92,9 @@ func TestFromContext(t *testing.T) {
 	defer closer.Close()
 
 	span, _, ctx := tracer.StartSpanFromContext(context.Background(), "some-operation", nil)
-	defer span.Finish()
+	defer span.End()
 
-	wantSpanContext := span.Context()
+	wantSpanContext := span.SpanContext()
 	if fmt.Sprint(wantSpanContext) == "" {
 		t.Fatal("got empty start span context")
 	}
@@ -107,14 +114,14 @@ func TestWithContext(t *testing.T) {
 	defer closer.Close()
 
 	span, _, _ := tracer.StartSpanFromContext(context.Background(), "some-operation", nil)
-	defer span.Finish()
+	defer span.End()
 
-	wantSpanContext := span.Context()
+	wantSpanContext := span.SpanContext()
 	if fmt.Sprint(wantSpanContext) == "" {
 		t.Fatal("got empty start span context")
 	}
 
-	ctx := tracing.WithContext(context.Background(), span.Context())
+	ctx := tracing.WithContext(context.Background(), span.SpanContext())
 
 	gotSpanContext := tracing.FromContext(ctx)
 	if fmt.Sprint(gotSpanContext) == "" {
@@ -131,9 +138,9 @@ func TestStartSpanFromContext_logger(t *testing.T) {
 	defer closer.Close()
 
 	span, logger, _ := tracer.StartSpanFromContext(context.Background(), "some-operation", logging.New(ioutil.Discard, 0))
-	defer span.Finish()
+	defer span.End()
 
-	wantTraceID := span.Context().(jaeger.SpanContext).TraceID()
+	wantTraceID := span.SpanContext().TraceID()
 
 	v, ok := logger.Data[tracing.LogField]
 	if !ok {
@@ -155,7 +162,7 @@ func TestStartSpanFromContext_nilLogger(t *testing.T) {
 	defer closer.Close()
 
 	span, logger, _ := tracer.StartSpanFromContext(context.Background(), "some-operation", nil)
-	defer span.Finish()
+	defer span.End()
 
 	if logger != nil {
 		t.Error("logger is not nil")
@@ -167,11 +174,11 @@ func TestNewLoggerWithTraceID(t *testing.T) {
 	defer closer.Close()
 
 	span, _, ctx := tracer.StartSpanFromContext(context.Background(), "some-operation", nil)
-	defer span.Finish()
+	defer span.End()
 
 	logger := tracing.NewLoggerWithTraceID(ctx, logging.New(ioutil.Discard, 0))
 
-	wantTraceID := span.Context().(jaeger.SpanContext).TraceID()
+	wantTraceID := span.SpanContext().TraceID()
 
 	v, ok := logger.Data[tracing.LogField]
 	if !ok {
@@ -193,7 +200,7 @@ func TestNewLoggerWithTraceID_nilLogger(t *testing.T) {
 	defer closer.Close()
 
 	span, _, ctx := tracer.StartSpanFromContext(context.Background(), "some-operation", nil)
-	defer span.Finish()
+	defer span.End()
 
 	logger := tracing.NewLoggerWithTraceID(ctx, nil)
 
@@ -202,6 +209,142 @@ func TestNewLoggerWithTraceID_nilLogger(t *testing.T) {
 	}
 }
 
+func TestSpanFromHTTPHeaders(t *testing.T) {
+	tracer, closer := newTracer(t)
+	defer closer.Close()
+
+	span, _, ctx := tracer.StartSpanFromContext(context.Background(), "some-operation", nil)
+	defer span.End()
+
+	headers := make(http.Header)
+	if err := tracer.AddContextHTTPHeader(ctx, headers); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"traceparent", tracing.TraceContextHeaderName} {
+		if headers.Get(name) == "" {
+			t.Errorf("header %q not set", name)
+		}
+
+		// each of the headers carries the span context
+		h := make(http.Header)
+		h.Set(name, headers.Get(name))
+		got, err := tracer.FromHTTPHeaders(h)
+		if err != nil {
+			t.Fatalf("header %q: %v", name, err)
+		}
+		if want := span.SpanContext().WithRemote(true); !got.Equal(want) {
+			t.Errorf("header %q: got span context %+v, want %+v", name, got, want)
+		}
+	}
+
+	if _, err := tracer.FromHTTPHeaders(make(http.Header)); !errors.Is(err, tracing.ErrContextNotFound) {
+		t.Fatalf("got error %v, want %v", err, tracing.ErrContextNotFound)
+	}
+}
+
+// TestJaegerSpanContext validates that the span contexts are exchanged in the
+// formats of the Jaeger tracer used by the former nodes.
+func TestJaegerSpanContext(t *testing.T) {
+	tracer, closer := newTracer(t)
+	defer closer.Close()
+
+	// trace id 0102...10, span id 1112...18, no parent, sampled, no baggage
+	binary := []byte{
+		1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16,
+		17, 18, 19, 20, 21, 22, 23, 24,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		1,
+		0, 0, 0, 0,
+	}
+	c, err := tracer.FromHeaders(p2p.Headers{p2p.HeaderNameTracingSpanContext: binary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.TraceID().String(), "0102030405060708090a0b0c0d0e0f10"; got != want {
+		t.Errorf("got trace id %s, want %s", got, want)
+	}
+	if got, want := c.SpanID().String(), "1112131415161718"; got != want {
+		t.Errorf("got span id %s, want %s", got, want)
+	}
+	if !c.IsSampled() {
+		t.Error("span context not sampled")
+	}
+
+	headers := make(p2p.Headers)
+	if err := tracer.AddContextHeader(tracing.WithContext(context.Background(), c), headers); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(headers[p2p.HeaderNameTracingSpanContext], binary) {
+		t.Errorf("got header %v, want %v", headers[p2p.HeaderNameTracingSpanContext], binary)
+	}
+
+	// the leading zeros of the ids are omitted by the Jaeger tracer
+	h := make(http.Header)
+	h.Set(tracing.TraceContextHeaderName, "a0b0c0d0e0f10:1112131415161718:0:1")
+	c, err = tracer.FromHTTPHeaders(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.TraceID().String(), "0000000000000000000a0b0c0d0e0f10"; got != want {
+		t.Errorf("got trace id %s, want %s", got, want)
+	}
+}
+
+func TestNewTracer(t *testing.T) {
+	for _, exporter := range []string{tracing.ExporterJaeger, tracing.ExporterOTLPGRPC, tracing.ExporterOTLPHTTP} {
+		t.Run(exporter, func(t *testing.T) {
+			tracer, closer, err := tracing.NewTracer(&tracing.Options{
+				Enabled:     true,
+				Endpoint:    "127.0.0.1:1",
+				ServiceName: "test",
+				Exporter:    exporter,
+				Insecure:    true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the span is not ended so that it is not exported to the
+			// unreachable endpoint
+			span, _, _ := tracer.StartSpanFromContext(context.Background(), "some-operation", nil)
+			if !span.SpanContext().IsValid() {
+				t.Error("got invalid span context")
+			}
+			if err := closer.Close(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	if _, _, err := tracing.NewTracer(&tracing.Options{Enabled: true, Exporter: "zipkin"}); err == nil {
+		t.Fatal("expected error for the unknown exporter")
+	}
+
+	tracer, closer, err := tracing.NewTracer(&tracing.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	span, _, ctx := tracer.StartSpanFromContext(context.Background(), "some-operation", nil)
+	defer span.End()
+	if err := tracer.AddContextHeader(ctx, make(p2p.Headers)); !errors.Is(err, tracing.ErrContextNotFound) {
+		t.Fatalf("got error %v, want %v", err, tracing.ErrContextNotFound)
+	}
+}
+
+func TestAccountingAttributes(t *testing.T) {
+	peer := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+
+	cfg := trace.NewSpanStartConfig(tracing.AccountingAttributes(peer, 10))
+	got := cfg.Attributes()
+	want := []attribute.KeyValue{
+		attribute.String("peer", peer.String()),
+		attribute.Int64("price", 10),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got attributes %v, want %v", got, want)
+	}
+}
+
 func newTracer(t *testing.T) (*tracing.Tracer, io.Closer) {
 	t.Helper()
 
@@ -0,0 +1,572 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package backendmux provides the blockchain backend which multiplexes the
+// requests over several endpoints, failing over to the next healthy endpoint
+// when one is unavailable.
+package backendmux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/transaction"
+)
+
+var (
+	// ErrNoEndpoints is returned if the backend is created without endpoints.
+	ErrNoEndpoints = errors.New("no blockchain endpoints")
+	// ErrNoHealthyEndpoint is returned if none of the endpoints is available.
+	ErrNoHealthyEndpoint = errors.New("no healthy blockchain endpoint")
+)
+
+const (
+	defaultHealthCheckInterval = 30 * time.Second
+	defaultHealthCheckTimeout  = 10 * time.Second
+)
+
+// Client is the blockchain backend at an endpoint.
+type Client interface {
+	transaction.Backend
+	ChainID(ctx context.Context) (*big.Int, error)
+	Close()
+}
+
+// DialFunc connects to the blockchain backend at the endpoint.
+type DialFunc func(ctx context.Context, endpoint string) (Client, error)
+
+// Dial connects to the endpoint with the ethereum rpc client.
+func Dial(ctx context.Context, endpoint string) (Client, error) {
+	return ethclient.DialContext(ctx, endpoint)
+}
+
+// Options configure the Backend.
+type Options struct {
+	// Dial connects to the endpoints, Dial if it is nil.
+	Dial DialFunc
+	// HealthCheckInterval is the interval of the health checks of the
+	// endpoints, 30 seconds if it is zero.
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout bounds a health check of an endpoint, 10 seconds if
+	// it is zero.
+	HealthCheckTimeout time.Duration
+	// MaxBlockLag is the number of blocks an endpoint may be behind the
+	// highest block of the endpoints before it is unhealthy, no limit if it
+	// is zero.
+	MaxBlockLag uint64
+	// LatencySelection prefers the healthy endpoints with the lowest latency
+	// of the last health check instead of the order of the endpoints.
+	LatencySelection bool
+}
+
+// EndpointStatus is the health of an endpoint.
+type EndpointStatus struct {
+	Host    string
+	Healthy bool
+	Latency time.Duration
+	Block   uint64
+}
+
+type endpoint struct {
+	index   int
+	url     string
+	host    string
+	client  Client // nil while not connected
+	healthy bool
+	latency time.Duration
+	block   uint64
+}
+
+type candidate struct {
+	endpoint *endpoint
+	client   Client
+}
+
+// Backend is the transaction.Backend which sends the requests to the first
+// healthy endpoint, in the order of the endpoints or by their latency, and
+// retries the requests which failed as the endpoint was unavailable on the
+// next one. The endpoints are checked periodically and dialed again once they
+// become available. All endpoints have to be on the same chain as the first
+// available one.
+type Backend struct {
+	dial     DialFunc
+	interval time.Duration
+	timeout  time.Duration
+	maxLag   uint64
+	latency  bool
+	logger   logging.Logger
+	metrics  metrics
+
+	mu        sync.RWMutex
+	endpoints []*endpoint
+	chainID   *big.Int
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New connects to the endpoints and starts their health checks. It fails if
+// none of the endpoints is available.
+func New(ctx context.Context, endpoints []string, logger logging.Logger, o Options) (*Backend, error) {
+	if len(endpoints) == 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	b := &Backend{
+		dial:     o.Dial,
+		interval: o.HealthCheckInterval,
+		timeout:  o.HealthCheckTimeout,
+		maxLag:   o.MaxBlockLag,
+		latency:  o.LatencySelection,
+		logger:   logger,
+		metrics:  newMetrics(),
+		quit:     make(chan struct{}),
+	}
+	if b.dial == nil {
+		b.dial = Dial
+	}
+	if b.interval <= 0 {
+		b.interval = defaultHealthCheckInterval
+	}
+	if b.timeout <= 0 {
+		b.timeout = defaultHealthCheckTimeout
+	}
+	for i, u := range endpoints {
+		b.endpoints = append(b.endpoints, &endpoint{index: i, url: u, host: endpointHost(u)})
+	}
+
+	if err := b.check(ctx); err != nil {
+		b.closeClients()
+		return nil, err
+	}
+	for _, s := range b.Status() {
+		if !s.Healthy {
+			b.logger.Warningf("chain backend: endpoint %s is unavailable", s.Host)
+		}
+	}
+
+	b.wg.Add(1)
+	go b.checkLoop()
+
+	return b, nil
+}
+
+// endpointHost returns the host of the endpoint for the logs, as the rest of
+// the url may contain credentials.
+func endpointHost(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "endpoint"
+	}
+	return u.Host
+}
+
+func (b *Backend) checkLoop() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-b.quit
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-b.quit:
+			return
+		case <-ticker.C:
+		}
+		if err := b.check(ctx); err != nil {
+			b.logger.Errorf("chain backend: %v", err)
+		}
+	}
+}
+
+// check checks the health of the endpoints in their order. It returns
+// ErrNoHealthyEndpoint with the error of the last endpoint if none is
+// healthy.
+func (b *Backend) check(ctx context.Context) error {
+	var lastErr error
+	for _, e := range b.endpoints {
+		if err := b.checkEndpoint(ctx, e); err != nil {
+			lastErr = err
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var highest uint64
+	for _, e := range b.endpoints {
+		if e.healthy && e.block > highest {
+			highest = e.block
+		}
+	}
+	healthy := 0
+	for _, e := range b.endpoints {
+		if e.healthy && b.maxLag > 0 && e.block+b.maxLag < highest {
+			e.healthy = false
+			lastErr = fmt.Errorf("%s is %d blocks behind", e.host, highest-e.block)
+			b.logger.Warningf("chain backend: endpoint %s is unhealthy: at block %d, %d blocks behind", e.host, e.block, highest-e.block)
+		}
+		if e.healthy {
+			healthy++
+		}
+	}
+	b.metrics.HealthyEndpoints.Set(float64(healthy))
+
+	if healthy == 0 {
+		return fmt.Errorf("%w: %v", ErrNoHealthyEndpoint, lastErr)
+	}
+	return nil
+}
+
+// checkEndpoint dials the endpoint if it is not connected and requests its
+// block number.
+func (b *Backend) checkEndpoint(ctx context.Context, e *endpoint) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	b.mu.RLock()
+	client := e.client
+	b.mu.RUnlock()
+
+	defer func() {
+		if err != nil {
+			b.setUnhealthy(e, client, err)
+		}
+	}()
+
+	if client == nil {
+		if client, err = b.connect(ctx, e); err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	block, err := client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("%s block number: %w", e.host, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !e.healthy {
+		b.logger.Infof("chain backend: endpoint %s is healthy", e.host)
+	}
+	e.healthy = true
+	e.latency = time.Since(start)
+	e.block = block
+	return nil
+}
+
+// connect dials the endpoint and verifies that it is on the chain of the
+// backend.
+func (b *Backend) connect(ctx context.Context, e *endpoint) (Client, error) {
+	client, err := b.dial(ctx, e.url)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", e.host, err)
+	}
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("%s chain id: %w", e.host, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.chainID == nil {
+		b.chainID = chainID
+	} else if b.chainID.Cmp(chainID) != 0 {
+		client.Close()
+		return nil, fmt.Errorf("%s is on chain %v, want %v", e.host, chainID, b.chainID)
+	}
+	e.client = client
+	return client, nil
+}
+
+// setUnhealthy marks the endpoint unhealthy after the client failed with the
+// error. The client is closed, to be dialed again by the next health check.
+func (b *Backend) setUnhealthy(e *endpoint, client Client, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if client != nil && e.client == client {
+		e.client.Close()
+		e.client = nil
+	}
+	if e.healthy {
+		b.logger.Warningf("chain backend: endpoint %s is unhealthy: %v", e.host, err)
+	} else {
+		b.logger.Debugf("chain backend: endpoint %s: %v", e.host, err)
+	}
+	e.healthy = false
+}
+
+// candidates returns the connected endpoints in the order in which the
+// requests are sent to them, the healthy ones first.
+func (b *Backend) candidates() []candidate {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	endpoints := make([]*endpoint, 0, len(b.endpoints))
+	for _, e := range b.endpoints {
+		if e.client != nil {
+			endpoints = append(endpoints, e)
+		}
+	}
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		ei, ej := endpoints[i], endpoints[j]
+		if ei.healthy != ej.healthy {
+			return ei.healthy
+		}
+		if b.latency && ei.healthy {
+			return ei.latency < ej.latency
+		}
+		return ei.index < ej.index
+	})
+
+	candidates := make([]candidate, len(endpoints))
+	for i, e := range endpoints {
+		candidates[i] = candidate{endpoint: e, client: e.client}
+	}
+	return candidates
+}
+
+// do calls the function with the clients of the candidates until it succeeds
+// or fails with an error which is not caused by the endpoint.
+func (b *Backend) do(ctx context.Context, f func(Client) error) error {
+	candidates := b.candidates()
+	if len(candidates) == 0 {
+		return ErrNoHealthyEndpoint
+	}
+
+	var err error
+	for i, c := range candidates {
+		if i > 0 {
+			b.metrics.Failovers.Inc()
+		}
+		if err = f(c.client); err == nil || !isEndpointError(ctx, err) {
+			return err
+		}
+		b.metrics.EndpointErrors.Inc()
+		b.logger.Debugf("chain backend: request to endpoint %s: %v", c.endpoint.host, err)
+		b.mu.Lock()
+		if c.endpoint.healthy {
+			b.logger.Warningf("chain backend: endpoint %s is unhealthy: %v", c.endpoint.host, err)
+		}
+		c.endpoint.healthy = false
+		b.mu.Unlock()
+	}
+	return err
+}
+
+// isEndpointError returns whether the request failed as the endpoint was
+// unavailable. The errors returned by the node, like reverted calls or not
+// found transactions, are not endpoint errors, nor are the cancellations of
+// the request.
+func isEndpointError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+	var rpcErr rpc.Error
+	return !errors.As(err, &rpcErr)
+}
+
+// Status returns the health of the endpoints in their order.
+func (b *Backend) Status() []EndpointStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	status := make([]EndpointStatus, len(b.endpoints))
+	for i, e := range b.endpoints {
+		status[i] = EndpointStatus{
+			Host:    e.host,
+			Healthy: e.healthy,
+			Latency: e.latency,
+			Block:   e.block,
+		}
+	}
+	return status
+}
+
+// ChainID returns the chain id of the endpoints.
+func (b *Backend) ChainID(ctx context.Context) (*big.Int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.chainID == nil {
+		return nil, ErrNoHealthyEndpoint
+	}
+	return new(big.Int).Set(b.chainID), nil
+}
+
+func (b *Backend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) (code []byte, err error) {
+	err = b.do(ctx, func(c Client) (err error) {
+		code, err = c.CodeAt(ctx, contract, blockNumber)
+		return err
+	})
+	return code, err
+}
+
+func (b *Backend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) (result []byte, err error) {
+	err = b.do(ctx, func(c Client) (err error) {
+		result, err = c.CallContract(ctx, call, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+func (b *Backend) PendingCodeAt(ctx context.Context, account common.Address) (code []byte, err error) {
+	err = b.do(ctx, func(c Client) (err error) {
+		code, err = c.PendingCodeAt(ctx, account)
+		return err
+	})
+	return code, err
+}
+
+func (b *Backend) PendingNonceAt(ctx context.Context, account common.Address) (nonce uint64, err error) {
+	err = b.do(ctx, func(c Client) (err error) {
+		nonce, err = c.PendingNonceAt(ctx, account)
+		return err
+	})
+	return nonce, err
+}
+
+func (b *Backend) SuggestGasPrice(ctx context.Context) (price *big.Int, err error) {
+	err = b.do(ctx, func(c Client) (err error) {
+		price, err = c.SuggestGasPrice(ctx)
+		return err
+	})
+	return price, err
+}
+
+func (b *Backend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (gas uint64, err error) {
+	err = b.do(ctx, func(c Client) (err error) {
+		gas, err = c.EstimateGas(ctx, call)
+		return err
+	})
+	return gas, err
+}
+
+// SendTransaction sends the signed transaction. Sending it again on the next
+// endpoint is safe, as the nodes deduplicate the transactions by their hash.
+func (b *Backend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	return b.do(ctx, func(c Client) error {
+		return c.SendTransaction(ctx, tx)
+	})
+}
+
+func (b *Backend) FilterLogs(ctx context.Context, query ethereum.FilterQuery) (logs []types.Log, err error) {
+	err = b.do(ctx, func(c Client) (err error) {
+		logs, err = c.FilterLogs(ctx, query)
+		return err
+	})
+	return logs, err
+}
+
+// SubscribeFilterLogs subscribes to the logs on the first available endpoint.
+// The subscription fails if that endpoint fails.
+func (b *Backend) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (sub ethereum.Subscription, err error) {
+	err = b.do(ctx, func(c Client) (err error) {
+		sub, err = c.SubscribeFilterLogs(ctx, query, ch)
+		return err
+	})
+	return sub, err
+}
+
+func (b *Backend) TransactionReceipt(ctx context.Context, txHash common.Hash) (receipt *types.Receipt, err error) {
+	err = b.do(ctx, func(c Client) (err error) {
+		receipt, err = c.TransactionReceipt(ctx, txHash)
+		return err
+	})
+	return receipt, err
+}
+
+func (b *Backend) TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
+	err = b.do(ctx, func(c Client) (err error) {
+		tx, isPending, err = c.TransactionByHash(ctx, hash)
+		return err
+	})
+	return tx, isPending, err
+}
+
+func (b *Backend) BlockNumber(ctx context.Context) (number uint64, err error) {
+	err = b.do(ctx, func(c Client) (err error) {
+		number, err = c.BlockNumber(ctx)
+		return err
+	})
+	return number, err
+}
+
+func (b *Backend) BlockByNumber(ctx context.Context, number *big.Int) (block *types.Block, err error) {
+	err = b.do(ctx, func(c Client) (err error) {
+		block, err = c.BlockByNumber(ctx, number)
+		return err
+	})
+	return block, err
+}
+
+func (b *Backend) HeaderByNumber(ctx context.Context, number *big.Int) (header *types.Header, err error) {
+	err = b.do(ctx, func(c Client) (err error) {
+		header, err = c.HeaderByNumber(ctx, number)
+		return err
+	})
+	return header, err
+}
+
+func (b *Backend) BalanceAt(ctx context.Context, address common.Address, block *big.Int) (balance *big.Int, err error) {
+	err = b.do(ctx, func(c Client) (err error) {
+		balance, err = c.BalanceAt(ctx, address, block)
+		return err
+	})
+	return balance, err
+}
+
+func (b *Backend) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (nonce uint64, err error) {
+	err = b.do(ctx, func(c Client) (err error) {
+		nonce, err = c.NonceAt(ctx, account, blockNumber)
+		return err
+	})
+	return nonce, err
+}
+
+// Close stops the health checks and closes the connections to the endpoints.
+func (b *Backend) Close() {
+	close(b.quit)
+	b.wg.Wait()
+	b.closeClients()
+}
+
+func (b *Backend) closeClients() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, e := range b.endpoints {
+		if e.client != nil {
+			e.client.Close()
+			e.client = nil
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package backendmux_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethsana/sana/pkg/logging"
+	"github.com/ethsana/sana/pkg/transaction"
+	"github.com/ethsana/sana/pkg/transaction/backendmock"
+	"github.com/ethsana/sana/pkg/transaction/backendmux"
+)
+
+type testClient struct {
+	transaction.Backend
+	chainID int64
+	closed  bool
+}
+
+func (c *testClient) ChainID(context.Context) (*big.Int, error) {
+	return big.NewInt(c.chainID), nil
+}
+
+func (c *testClient) Close() {
+	c.closed = true
+}
+
+// rpcError is the error returned by the node.
+type rpcError struct{}
+
+func (rpcError) Error() string  { return "execution reverted" }
+func (rpcError) ErrorCode() int { return 3 }
+
+var errUnavailable = errors.New("connection refused")
+
+// testEndpoint is the endpoint at the block whose nonce requests return the
+// nonce or the error.
+type testEndpoint struct {
+	chainID int64
+	block   uint64
+	delay   time.Duration
+	nonce   uint64
+	err     error
+	dialErr error
+	down    bool
+}
+
+func (e *testEndpoint) client() *testClient {
+	return &testClient{
+		Backend: backendmock.New(
+			backendmock.WithBlockNumberFunc(func(context.Context) (uint64, error) {
+				if e.down {
+					return 0, errUnavailable
+				}
+				time.Sleep(e.delay)
+				return e.block, nil
+			}),
+			backendmock.WithNonceAtFunc(func(context.Context, common.Address, *big.Int) (uint64, error) {
+				if e.down {
+					return 0, errUnavailable
+				}
+				return e.nonce, e.err
+			}),
+		),
+		chainID: e.chainID,
+	}
+}
+
+func newTestBackend(t *testing.T, o backendmux.Options, endpoints ...*testEndpoint) (*backendmux.Backend, error) {
+	t.Helper()
+
+	urls := make([]string, len(endpoints))
+	byURL := make(map[string]*testEndpoint)
+	for i, e := range endpoints {
+		urls[i] = "http://endpoint" + string(rune('a'+i))
+		byURL[urls[i]] = e
+	}
+	o.Dial = func(_ context.Context, u string) (backendmux.Client, error) {
+		e := byURL[u]
+		if e.dialErr != nil {
+			return nil, e.dialErr
+		}
+		return e.client(), nil
+	}
+	o.HealthCheckInterval = time.Hour
+
+	b, err := backendmux.New(context.Background(), urls, logging.New(ioutil.Discard, 0), o)
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(b.Close)
+	return b, nil
+}
+
+func assertNonce(t *testing.T, b *backendmux.Backend, want uint64) {
+	t.Helper()
+
+	nonce, err := b.NonceAt(context.Background(), common.Address{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce != want {
+		t.Fatalf("got nonce %d, want %d", nonce, want)
+	}
+}
+
+func assertHealthy(t *testing.T, b *backendmux.Backend, want ...bool) {
+	t.Helper()
+
+	status := b.Status()
+	for i, s := range status {
+		if s.Healthy != want[i] {
+			t.Fatalf("endpoint %d: got healthy %t, want %t", i, s.Healthy, want[i])
+		}
+	}
+}
+
+func TestFailover(t *testing.T) {
+	first := &testEndpoint{chainID: 1, block: 10, nonce: 1}
+	second := &testEndpoint{chainID: 1, block: 10, nonce: 2}
+	b, err := newTestBackend(t, backendmux.Options{}, first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertNonce(t, b, 1)
+
+	first.down = true
+	assertNonce(t, b, 2)
+	assertHealthy(t, b, false, true)
+
+	// the first endpoint is used again once it recovers
+	first.down = false
+	if err := b.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertHealthy(t, b, true, true)
+	assertNonce(t, b, 1)
+}
+
+func TestNodeError(t *testing.T) {
+	first := &testEndpoint{chainID: 1, block: 10, err: rpcError{}}
+	second := &testEndpoint{chainID: 1, block: 10, nonce: 2}
+	b, err := newTestBackend(t, backendmux.Options{}, first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.NonceAt(context.Background(), common.Address{}, nil); !errors.Is(err, rpcError{}) {
+		t.Fatalf("got error %v, want %v", err, rpcError{})
+	}
+	assertHealthy(t, b, true, true)
+}
+
+func TestUnavailableEndpoints(t *testing.T) {
+	t.Run("first unavailable", func(t *testing.T) {
+		b, err := newTestBackend(t, backendmux.Options{},
+			&testEndpoint{dialErr: errUnavailable},
+			&testEndpoint{chainID: 1, block: 10, nonce: 2},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHealthy(t, b, false, true)
+		assertNonce(t, b, 2)
+	})
+
+	t.Run("all unavailable", func(t *testing.T) {
+		_, err := newTestBackend(t, backendmux.Options{},
+			&testEndpoint{dialErr: errUnavailable},
+			&testEndpoint{chainID: 1, down: true},
+		)
+		if !errors.Is(err, backendmux.ErrNoHealthyEndpoint) {
+			t.Fatalf("got error %v, want %v", err, backendmux.ErrNoHealthyEndpoint)
+		}
+	})
+
+	t.Run("other chain", func(t *testing.T) {
+		b, err := newTestBackend(t, backendmux.Options{},
+			&testEndpoint{chainID: 1, block: 10, nonce: 1},
+			&testEndpoint{chainID: 2, block: 10, nonce: 2},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHealthy(t, b, true, false)
+		chainID, err := b.ChainID(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chainID.Int64() != 1 {
+			t.Fatalf("got chain id %v, want 1", chainID)
+		}
+	})
+
+	t.Run("no endpoints", func(t *testing.T) {
+		if _, err := newTestBackend(t, backendmux.Options{}); !errors.Is(err, backendmux.ErrNoEndpoints) {
+			t.Fatalf("got error %v, want %v", err, backendmux.ErrNoEndpoints)
+		}
+	})
+}
+
+func TestBlockLag(t *testing.T) {
+	b, err := newTestBackend(t, backendmux.Options{MaxBlockLag: 5},
+		&testEndpoint{chainID: 1, block: 10, nonce: 1},
+		&testEndpoint{chainID: 1, block: 20, nonce: 2},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertHealthy(t, b, false, true)
+	assertNonce(t, b, 2)
+}
+
+func TestLatencySelection(t *testing.T) {
+	endpoints := []*testEndpoint{
+		{chainID: 1, block: 10, nonce: 1, delay: 50 * time.Millisecond},
+		{chainID: 1, block: 10, nonce: 2},
+	}
+
+	b, err := newTestBackend(t, backendmux.Options{}, endpoints...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNonce(t, b, 1)
+
+	b, err = newTestBackend(t, backendmux.Options{LatencySelection: true}, endpoints...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNonce(t, b, 2)
+}
@@ -0,0 +1,12 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package backendmux
+
+import "context"
+
+// Check checks the health of the endpoints.
+func (b *Backend) Check(ctx context.Context) error {
+	return b.check(ctx)
+}
@@ -0,0 +1,45 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package backendmux
+
+import (
+	m "github.com/ethsana/sana/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	HealthyEndpoints prometheus.Gauge
+	EndpointErrors   prometheus.Counter
+	Failovers        prometheus.Counter
+}
+
+func newMetrics() metrics {
+	subsystem := "chain_backend"
+
+	return metrics{
+		HealthyEndpoints: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "healthy_endpoints",
+			Help:      "Number of the healthy blockchain endpoints.",
+		}),
+		EndpointErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "endpoint_errors",
+			Help:      "Total requests which failed as a blockchain endpoint was unavailable.",
+		}),
+		Failovers: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "failovers",
+			Help:      "Total requests retried on another blockchain endpoint.",
+		}),
+	}
+}
+
+func (b *Backend) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(b.metrics)
+}
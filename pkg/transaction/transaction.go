@@ -152,7 +152,7 @@ func (t *transactionService) Send(ctx context.Context, request *TxRequest) (txHa
 		return common.Hash{}, err
 	}
 
-	t.logger.Tracef("sending transaction %x with nonce %d", signedTx.Hash(), nonce)
+	t.logger.WithField(logging.FieldTxHash, signedTx.Hash().Hex()).Tracef("sending transaction %x with nonce %d", signedTx.Hash(), nonce)
 
 	err = t.backend.SendTransaction(ctx, signedTx)
 	if err != nil {
@@ -197,13 +197,13 @@ func (t *transactionService) waitForPendingTx(txHash common.Hash) {
 		_, err := t.WaitForReceipt(t.ctx, txHash)
 		if err != nil {
 			if !errors.Is(err, ErrTransactionCancelled) {
-				t.logger.Errorf("error while waiting for pending transaction %x: %v", txHash, err)
+				t.logger.WithField(logging.FieldTxHash, txHash.Hex()).Errorf("error while waiting for pending transaction %x: %v", txHash, err)
 				return
 			} else {
-				t.logger.Warningf("pending transaction %x cancelled", txHash)
+				t.logger.WithField(logging.FieldTxHash, txHash.Hex()).Warningf("pending transaction %x cancelled", txHash)
 			}
 		} else {
-			t.logger.Tracef("pending transaction %x confirmed", txHash)
+			t.logger.WithField(logging.FieldTxHash, txHash.Hex()).Tracef("pending transaction %x confirmed", txHash)
 		}
 
 		err = t.store.Delete(pendingTransactionKey(txHash))
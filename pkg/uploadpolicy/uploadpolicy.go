@@ -0,0 +1,159 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package uploadpolicy defines the policies which a gateway enforces on the
+// uploads of the web applications it serves, selected by the request Origin.
+package uploadpolicy
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"strings"
+)
+
+// AnyOrigin is the origin of the policy which applies to requests with an
+// origin without its own policy, including requests without an origin.
+const AnyOrigin = "*"
+
+// Policy restricts the uploads of requests from an origin.
+type Policy struct {
+	// Origin is the value of the Origin header the policy applies to.
+	Origin string `json:"origin"`
+	// MaxUploadSize is the maximal size in bytes of the request body, zero
+	// means unlimited.
+	MaxUploadSize int64 `json:"maxUploadSize"`
+	// ContentTypes are the allowed media types, which may use a wildcard
+	// subtype, for example image/*. All types are allowed if it is empty.
+	ContentTypes []string `json:"contentTypes"`
+	// APIKeyHash, if set, is the hex encoded SHA-256 hash of the API key
+	// which the requests must provide.
+	APIKeyHash string `json:"apiKeyHash"`
+	// PostageBatch, if set, is the hex encoded id of the postage batch used
+	// for all uploads, regardless of the batch requested.
+	PostageBatch string `json:"postageBatch"`
+
+	apiKeyHash   []byte
+	postageBatch []byte
+}
+
+// Policies is a set of upload policies indexed by origin.
+type Policies struct {
+	origins map[string]*Policy
+}
+
+// New validates the policies and returns them as a set.
+func New(policies []Policy) (*Policies, error) {
+	p := &Policies{origins: make(map[string]*Policy, len(policies))}
+	for i := range policies {
+		policy := policies[i]
+		if policy.Origin == "" {
+			return nil, errors.New("uploadpolicy: policy without origin")
+		}
+		origin := normalizeOrigin(policy.Origin)
+		if _, ok := p.origins[origin]; ok {
+			return nil, fmt.Errorf("uploadpolicy: duplicate policy for origin %s", policy.Origin)
+		}
+		if policy.MaxUploadSize < 0 {
+			return nil, fmt.Errorf("uploadpolicy: origin %s: negative max upload size", policy.Origin)
+		}
+		for _, ct := range policy.ContentTypes {
+			if !strings.Contains(ct, "/") {
+				return nil, fmt.Errorf("uploadpolicy: origin %s: malformed content type %q", policy.Origin, ct)
+			}
+		}
+		if policy.APIKeyHash != "" {
+			h, err := hex.DecodeString(policy.APIKeyHash)
+			if err != nil || len(h) != sha256.Size {
+				return nil, fmt.Errorf("uploadpolicy: origin %s: malformed api key hash", policy.Origin)
+			}
+			policy.apiKeyHash = h
+		}
+		if policy.PostageBatch != "" {
+			b, err := hex.DecodeString(policy.PostageBatch)
+			if err != nil || len(b) != 32 {
+				return nil, fmt.Errorf("uploadpolicy: origin %s: malformed postage batch id", policy.Origin)
+			}
+			policy.postageBatch = b
+		}
+		p.origins[origin] = &policy
+	}
+	return p, nil
+}
+
+// Load reads the policies from a JSON file containing an array of policy
+// definitions.
+func Load(path string) (*Policies, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policies []Policy
+	if err := json.Unmarshal(b, &policies); err != nil {
+		return nil, fmt.Errorf("uploadpolicy: parse %s: %w", path, err)
+	}
+	return New(policies)
+}
+
+// Match returns the policy for the origin, falling back to the policy of
+// AnyOrigin.
+func (p *Policies) Match(origin string) (*Policy, bool) {
+	if policy, ok := p.origins[normalizeOrigin(origin)]; ok {
+		return policy, true
+	}
+	policy, ok := p.origins[AnyOrigin]
+	return policy, ok
+}
+
+// AllowsContentType reports whether the media type of the Content-Type
+// header value is allowed by the policy.
+func (p *Policy) AllowsContentType(contentType string) bool {
+	if len(p.ContentTypes) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, ct := range p.ContentTypes {
+		ct = strings.ToLower(ct)
+		if ct == mediaType {
+			return true
+		}
+		if strings.HasSuffix(ct, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(ct, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorized reports whether the API key satisfies the policy.
+func (p *Policy) Authorized(apiKey string) bool {
+	if p.apiKeyHash == nil {
+		return true
+	}
+	h := sha256.Sum256([]byte(apiKey))
+	return subtle.ConstantTimeCompare(p.apiKeyHash, h[:]) == 1
+}
+
+// Batch returns the postage batch which the policy enforces, or nil.
+func (p *Policy) Batch() []byte {
+	return p.postageBatch
+}
+
+// KeyHash returns the hex encoded hash of the API key which is stored in the
+// policy.
+func KeyHash(apiKey string) string {
+	h := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(h[:])
+}
+
+func normalizeOrigin(origin string) string {
+	return strings.TrimSuffix(strings.ToLower(origin), "/")
+}
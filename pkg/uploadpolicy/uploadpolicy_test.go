@@ -0,0 +1,132 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uploadpolicy_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/uploadpolicy"
+)
+
+func TestPolicies(t *testing.T) {
+	batch := bytes.Repeat([]byte{1}, 32)
+	p, err := uploadpolicy.New([]uploadpolicy.Policy{
+		{
+			Origin:        "https://app.example.org",
+			MaxUploadSize: 1024,
+			ContentTypes:  []string{"image/*", "application/json"},
+			APIKeyHash:    uploadpolicy.KeyHash("secret"),
+			PostageBatch:  hex.EncodeToString(batch),
+		},
+		{
+			Origin: uploadpolicy.AnyOrigin,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy, ok := p.Match("https://APP.example.org")
+	if !ok || policy.Origin != "https://app.example.org" {
+		t.Fatalf("got policy %+v", policy)
+	}
+	if !bytes.Equal(policy.Batch(), batch) {
+		t.Fatalf("got batch %x, want %x", policy.Batch(), batch)
+	}
+	for ct, want := range map[string]bool{
+		"image/png":                       true,
+		"IMAGE/JPEG":                      true,
+		"application/json; charset=utf-8": true,
+		"text/html":                       false,
+		"imagefoo/png":                    false,
+		"":                                false,
+	} {
+		if got := policy.AllowsContentType(ct); got != want {
+			t.Errorf("content type %q: got %v, want %v", ct, got, want)
+		}
+	}
+	if !policy.Authorized("secret") {
+		t.Error("api key not authorized")
+	}
+	if policy.Authorized("wrong") || policy.Authorized("") {
+		t.Error("wrong api key authorized")
+	}
+
+	for _, origin := range []string{"https://other.example.org", ""} {
+		policy, ok = p.Match(origin)
+		if !ok || policy.Origin != uploadpolicy.AnyOrigin {
+			t.Fatalf("origin %q: got policy %+v", origin, policy)
+		}
+		if !policy.AllowsContentType("text/html") || !policy.Authorized("") || policy.Batch() != nil {
+			t.Fatalf("origin %q: any origin policy is restrictive", origin)
+		}
+	}
+
+	p, err = uploadpolicy.New([]uploadpolicy.Policy{{Origin: "https://app.example.org"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.Match("https://other.example.org"); ok {
+		t.Fatal("matched origin without policy")
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		policies []uploadpolicy.Policy
+	}{
+		{
+			name:     "no origin",
+			policies: []uploadpolicy.Policy{{}},
+		},
+		{
+			name:     "duplicate",
+			policies: []uploadpolicy.Policy{{Origin: "https://a.org"}, {Origin: "https://A.org/"}},
+		},
+		{
+			name:     "negative size",
+			policies: []uploadpolicy.Policy{{Origin: "https://a.org", MaxUploadSize: -1}},
+		},
+		{
+			name:     "malformed content type",
+			policies: []uploadpolicy.Policy{{Origin: "https://a.org", ContentTypes: []string{"image"}}},
+		},
+		{
+			name:     "malformed api key hash",
+			policies: []uploadpolicy.Policy{{Origin: "https://a.org", APIKeyHash: "abcd"}},
+		},
+		{
+			name:     "malformed batch",
+			policies: []uploadpolicy.Policy{{Origin: "https://a.org", PostageBatch: "abcd"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := uploadpolicy.New(tc.policies); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.json")
+	data := `[{"origin":"https://app.example.org","maxUploadSize":1024,"contentTypes":["image/png"]}]`
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := uploadpolicy.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, ok := p.Match("https://app.example.org")
+	if !ok || policy.MaxUploadSize != 1024 || !policy.AllowsContentType("image/png") {
+		t.Fatalf("got policy %+v", policy)
+	}
+}
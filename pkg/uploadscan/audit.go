@@ -0,0 +1,69 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uploadscan
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethsana/sana/pkg/storage"
+)
+
+const auditKeyPrefix = "uploadscan_rejection_"
+
+// Rejection is the audit record of an upload rejected by the scanner.
+type Rejection struct {
+	Time        time.Time `json:"time"`
+	Path        string    `json:"path"`
+	RemoteAddr  string    `json:"remoteAddr"`
+	ContentType string    `json:"contentType,omitempty"`
+	Filename    string    `json:"filename,omitempty"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	Reason      string    `json:"reason"`
+}
+
+// Audit persists records of rejected uploads.
+type Audit interface {
+	// Record stores the rejection.
+	Record(Rejection) error
+	// Rejections returns all stored rejections ordered by time.
+	Rejections() ([]Rejection, error)
+}
+
+type audit struct {
+	store storage.StateStorer
+}
+
+// NewAudit creates a new Audit persisted in the state store.
+func NewAudit(store storage.StateStorer) Audit {
+	return &audit{store: store}
+}
+
+func (a *audit) Record(r Rejection) error {
+	key := fmt.Sprintf("%s%020d_%s", auditKeyPrefix, r.Time.UnixNano(), r.SHA256)
+	return a.store.Put(key, r)
+}
+
+func (a *audit) Rejections() ([]Rejection, error) {
+	var rejections []Rejection
+	err := a.store.Iterate(auditKeyPrefix, func(_, value []byte) (bool, error) {
+		var r Rejection
+		if err := json.Unmarshal(value, &r); err != nil {
+			return true, err
+		}
+		rejections = append(rejections, r)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(rejections, func(i, j int) bool {
+		return rejections[i].Time.Before(rejections[j].Time)
+	})
+	return rejections, nil
+}
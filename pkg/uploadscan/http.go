@@ -0,0 +1,74 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uploadscan
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxReasonSize limits the size of the rejection reason read from scanner
+// responses.
+const maxReasonSize = 1024
+
+type httpScanner struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newHTTPScanner(u *url.URL, timeout time.Duration) *httpScanner {
+	return &httpScanner{
+		endpoint: u.String(),
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *httpScanner) Scan(ctx context.Context, r io.Reader, info Info) (Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, r)
+	if err != nil {
+		return Result{}, err
+	}
+	req.ContentLength = info.Size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	if info.ContentType != "" {
+		req.Header.Set("X-Content-Type", info.ContentType)
+	}
+	if info.Filename != "" {
+		req.Header.Set("X-Filename", info.Filename)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Result{}, fmt.Errorf("scan request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return Result{}, nil
+	case resp.StatusCode == http.StatusForbidden,
+		resp.StatusCode == http.StatusNotAcceptable,
+		resp.StatusCode == http.StatusUnavailableForLegalReasons:
+		b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxReasonSize))
+		if err != nil {
+			return Result{}, fmt.Errorf("read scan response: %w", err)
+		}
+		reason := strings.TrimSpace(string(b))
+		if reason == "" {
+			reason = resp.Status
+		}
+		return Result{Rejected: true, Reason: reason}, nil
+	default:
+		return Result{}, fmt.Errorf("unexpected scan response status %s", resp.Status)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uploadscan
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	icapDefaultPort = "1344"
+	icapChunkSize   = 32 * 1024
+)
+
+// icapScanner sends the content in ICAP REQMOD requests, as described in
+// RFC 3507.
+type icapScanner struct {
+	url     *url.URL
+	address string
+	timeout time.Duration
+}
+
+func newICAPScanner(u *url.URL, timeout time.Duration) *icapScanner {
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), icapDefaultPort)
+	}
+	return &icapScanner{
+		url:     u,
+		address: address,
+		timeout: timeout,
+	}
+}
+
+func (s *icapScanner) Scan(ctx context.Context, r io.Reader, info Info) (Result, error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return Result{}, fmt.Errorf("dial icap server: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return Result{}, err
+		}
+	}
+
+	// the encapsulated http request which is modified by the icap server
+	name := info.Filename
+	if name == "" {
+		name = "upload"
+	}
+	var req strings.Builder
+	fmt.Fprintf(&req, "PUT /%s HTTP/1.1\r\n", url.PathEscape(name))
+	fmt.Fprintf(&req, "Host: %s\r\n", s.url.Hostname())
+	if info.ContentType != "" {
+		fmt.Fprintf(&req, "Content-Type: %s\r\n", info.ContentType)
+	}
+	fmt.Fprintf(&req, "Content-Length: %d\r\n\r\n", info.Size)
+
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "REQMOD %s ICAP/1.0\r\n", s.url.String())
+	fmt.Fprintf(w, "Host: %s\r\n", s.url.Host)
+	fmt.Fprintf(w, "Allow: 204\r\n")
+	fmt.Fprintf(w, "Encapsulated: req-hdr=0, req-body=%d\r\n\r\n", req.Len())
+	if _, err := w.WriteString(req.String()); err != nil {
+		return Result{}, fmt.Errorf("write icap request: %w", err)
+	}
+
+	buf := make([]byte, icapChunkSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			fmt.Fprintf(w, "%x\r\n", n)
+			_, _ = w.Write(buf[:n])
+			if _, err := w.WriteString("\r\n"); err != nil {
+				return Result{}, fmt.Errorf("write icap request: %w", err)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Result{}, fmt.Errorf("read content: %w", err)
+		}
+	}
+	if _, err := w.WriteString("0\r\n\r\n"); err != nil {
+		return Result{}, fmt.Errorf("write icap request: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return Result{}, fmt.Errorf("write icap request: %w", err)
+	}
+
+	tp := textproto.NewReader(bufio.NewReader(conn))
+	line, err := tp.ReadLine()
+	if err != nil {
+		return Result{}, fmt.Errorf("read icap response: %w", err)
+	}
+	code, err := icapStatus(line)
+	if err != nil {
+		return Result{}, err
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return Result{}, fmt.Errorf("read icap response header: %w", err)
+	}
+
+	switch code {
+	case 204:
+		return Result{}, nil
+	case 200:
+		reason := header.Get("X-Infection-Found")
+		if reason == "" {
+			reason = header.Get("X-Virus-ID")
+		}
+		if reason == "" {
+			reason = header.Get("X-Violations-Found")
+		}
+		if reason == "" {
+			reason = "blocked by icap server"
+		}
+		return Result{Rejected: true, Reason: reason}, nil
+	default:
+		return Result{}, fmt.Errorf("unexpected icap response %q", line)
+	}
+}
+
+// icapStatus parses the status code of the ICAP response status line.
+func icapStatus(line string) (int, error) {
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], "ICAP/") {
+		return 0, fmt.Errorf("malformed icap response %q", line)
+	}
+	code, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("malformed icap response %q", line)
+	}
+	return code, nil
+}
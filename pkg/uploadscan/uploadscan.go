@@ -0,0 +1,66 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package uploadscan passes uploaded content to an external scanning
+// service, such as an antivirus or a moderation service, before it is
+// stored, and keeps audit records of rejected uploads.
+//
+// Two kinds of scanning services are supported, selected by the scheme of
+// the endpoint URL:
+//
+//  - icap:// endpoints receive an ICAP REQMOD request with the content. A
+//    204 No Content response accepts the upload, a 200 OK response, which
+//    replaces the request with a blocking page, rejects it.
+//  - http:// and https:// endpoints receive the content in the body of a
+//    POST request. A 2xx response accepts the upload, 403, 406 and 451
+//    responses reject it with the response body as the reason.
+package uploadscan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+)
+
+// ErrUnsupportedScheme is returned by New for endpoints which are neither
+// ICAP nor HTTP URLs.
+var ErrUnsupportedScheme = errors.New("uploadscan: unsupported endpoint scheme")
+
+// Info describes the scanned content.
+type Info struct {
+	ContentType string
+	Filename    string
+	Size        int64
+}
+
+// Result is the verdict of the scanning service.
+type Result struct {
+	Rejected bool
+	Reason   string
+}
+
+// Scanner scans uploaded content.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader, info Info) (Result, error)
+}
+
+// New returns the Scanner for the endpoint. Scans which take longer than the
+// timeout fail.
+func New(endpoint string, timeout time.Duration) (Scanner, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse endpoint: %w", err)
+	}
+	switch u.Scheme {
+	case "icap":
+		return newICAPScanner(u, timeout), nil
+	case "http", "https":
+		return newHTTPScanner(u, timeout), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedScheme, u.Scheme)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uploadscan_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/uploadscan"
+)
+
+var (
+	clean    = []byte("clean content")
+	infected = []byte("infected content")
+)
+
+func TestHTTPScanner(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("X-Filename") != "file.txt" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if bytes.Equal(b, infected) {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintln(w, "Eicar-Test-Signature")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	s, err := uploadscan.New(ts.URL, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testScanner(t, s)
+}
+
+func TestICAPScanner(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveICAP(conn)
+		}
+	}()
+
+	s, err := uploadscan.New("icap://"+l.Addr().String()+"/avscan", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testScanner(t, s)
+}
+
+// serveICAP answers a single REQMOD request, rejecting the infected content.
+func serveICAP(conn net.Conn) {
+	defer conn.Close()
+
+	tp := textproto.NewReader(bufio.NewReader(conn))
+	line, err := tp.ReadLine()
+	if err != nil || !strings.HasPrefix(line, "REQMOD icap://") {
+		return
+	}
+	if _, err := tp.ReadMIMEHeader(); err != nil {
+		return
+	}
+	// encapsulated http request header
+	if _, err := tp.ReadLine(); err != nil {
+		return
+	}
+	if _, err := tp.ReadMIMEHeader(); err != nil {
+		return
+	}
+
+	var body []byte
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		n, err := strconv.ParseInt(line, 16, 64)
+		if err != nil {
+			return
+		}
+		if n == 0 {
+			break
+		}
+		chunk := make([]byte, n+2)
+		if _, err := io.ReadFull(tp.R, chunk); err != nil {
+			return
+		}
+		body = append(body, chunk[:n]...)
+	}
+
+	if bytes.Equal(body, infected) {
+		fmt.Fprint(conn, "ICAP/1.0 200 OK\r\nX-Infection-Found: Type=0; Resolution=2; Threat=Eicar-Test-Signature;\r\nEncapsulated: null-body=0\r\n\r\n")
+		return
+	}
+	fmt.Fprint(conn, "ICAP/1.0 204 No Content\r\nEncapsulated: null-body=0\r\n\r\n")
+}
+
+func testScanner(t *testing.T, s uploadscan.Scanner) {
+	t.Helper()
+
+	ctx := context.Background()
+	r, err := s.Scan(ctx, bytes.NewReader(clean), uploadscan.Info{Filename: "file.txt", Size: int64(len(clean))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Rejected {
+		t.Fatalf("clean content rejected: %s", r.Reason)
+	}
+
+	r, err = s.Scan(ctx, bytes.NewReader(infected), uploadscan.Info{Filename: "file.txt", Size: int64(len(infected))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Rejected {
+		t.Fatal("infected content accepted")
+	}
+	if !strings.Contains(r.Reason, "Eicar-Test-Signature") {
+		t.Fatalf("got reason %q", r.Reason)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := uploadscan.New("ftp://localhost/scan", time.Second); !errors.Is(err, uploadscan.ErrUnsupportedScheme) {
+		t.Fatalf("got error %v, want %v", err, uploadscan.ErrUnsupportedScheme)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	a := uploadscan.NewAudit(statestore.NewStateStore())
+	now := time.Now().UTC()
+	for i := 2; i >= 0; i-- {
+		err := a.Record(uploadscan.Rejection{
+			Time:   now.Add(time.Duration(i) * time.Second),
+			Path:   "/bytes",
+			SHA256: strconv.Itoa(i),
+			Reason: "flagged",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rejections, err := a.Rejections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejections) != 3 {
+		t.Fatalf("got %d rejections, want 3", len(rejections))
+	}
+	for i, r := range rejections {
+		if r.SHA256 != strconv.Itoa(i) {
+			t.Fatalf("rejection %d: got %s", i, r.SHA256)
+		}
+	}
+}
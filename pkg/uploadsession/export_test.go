@@ -0,0 +1,10 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uploadsession
+
+// SetPartSize sets the size of the parts, a multiple of the chunk size.
+func (m *Manager) SetPartSize(n int) {
+	m.partSize = n
+}
@@ -0,0 +1,367 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package uploadsession implements the resumable uploads of large content.
+// The data of a session is sent in consecutive ranges which are split into
+// parts, each of them stored as a separate tree of chunks. The roots of the
+// parts and the unaligned tail of the received data are persisted in the
+// state store after every part, so that an interrupted upload is resumed
+// from the last persisted offset, also after a restart of the node. Once all
+// data is received, the data chunks of the parts are joined in the tree of
+// the whole content, which is scanned, if a scan is given, before the session
+// is completed.
+package uploadsession
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ethsana/sana/pkg/file/joiner"
+	"github.com/ethsana/sana/pkg/file/pipeline"
+	"github.com/ethsana/sana/pkg/file/pipeline/builder"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/swarm"
+)
+
+const (
+	keyPrefix = "uploadsession_"
+	// defaultPartSize is the maximal size of the data stored as one part, a
+	// multiple of the chunk size.
+	defaultPartSize = 4096 * swarm.ChunkSize
+)
+
+var (
+	// ErrNotFound is returned if the session does not exist.
+	ErrNotFound = errors.New("upload session: not found")
+	// ErrBusy is returned if the data of the session is already being
+	// written.
+	ErrBusy = errors.New("upload session: busy")
+	// ErrCompleted is returned if data is written to the session which
+	// already received all data.
+	ErrCompleted = errors.New("upload session: completed")
+	// ErrOffsetMismatch is returned if the offset of the written data is not
+	// the offset of the session.
+	ErrOffsetMismatch = errors.New("upload session: offset mismatch")
+	// ErrInterrupted is returned if reading the data failed, the upload is
+	// resumed from the offset of the session.
+	ErrInterrupted = errors.New("upload session: interrupted")
+)
+
+// Scan checks the content of a session, with the length, before the session is
+// completed. The session is not completed if it returns an error.
+type Scan func(ctx context.Context, r io.Reader, length int64) error
+
+// Session is the persisted state of a resumable upload.
+type Session struct {
+	ID string `json:"id"`
+	// Length is the size of the content.
+	Length int64 `json:"length"`
+	// Offset is the number of bytes received.
+	Offset int64 `json:"offset"`
+	// Batch is the postage batch which stamps the chunks.
+	Batch []byte `json:"batch"`
+	// Pin is true if the content is pinned once it is completed.
+	Pin bool `json:"pin"`
+	// Parts are the roots of the stored parts.
+	Parts []swarm.Address `json:"parts"`
+	// Tail is the received data which is not stored in a part yet.
+	Tail []byte `json:"tail"`
+	// StampedChunks is the number of new chunks stored and stamped.
+	StampedChunks int64 `json:"stampedChunks"`
+	// Reference is the root of the content when the session is completed.
+	Reference swarm.Address `json:"reference"`
+	Created   time.Time     `json:"created"`
+}
+
+// Stored returns the number of received bytes which are stored in chunks.
+func (s *Session) Stored() int64 {
+	return s.Offset - int64(len(s.Tail))
+}
+
+// Completed returns true if all data is received and the content is stored.
+func (s *Session) Completed() bool {
+	return !s.Reference.IsZero()
+}
+
+func key(id string) string {
+	return keyPrefix + id
+}
+
+// Manager manages the upload sessions.
+type Manager struct {
+	store    storage.StateStorer
+	getter   storage.Getter
+	partSize int
+
+	mu   sync.Mutex
+	busy map[string]struct{}
+}
+
+// New returns a new Manager. The getter retrieves the chunks of the parts to
+// join them, it should retrieve also the ones which may be already garbage
+// collected locally after they were synced.
+func New(store storage.StateStorer, getter storage.Getter) *Manager {
+	return &Manager{
+		store:    store,
+		getter:   getter,
+		partSize: defaultPartSize,
+		busy:     make(map[string]struct{}),
+	}
+}
+
+// Create creates the session of the content with the length, stamped with the
+// batch.
+func (m *Manager) Create(length int64, batch []byte, pin bool) (*Session, error) {
+	if length < 0 {
+		return nil, fmt.Errorf("upload session: invalid length %d", length)
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	s := &Session{
+		ID:        hex.EncodeToString(b),
+		Length:    length,
+		Batch:     batch,
+		Pin:       pin,
+		Reference: swarm.ZeroAddress,
+		Created:   time.Now(),
+	}
+	if err := m.store.Put(key(s.ID), s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns the session with the id.
+func (m *Manager) Get(id string) (*Session, error) {
+	s := new(Session)
+	if err := m.store.Get(key(id), s); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// Delete removes the session. The already stored chunks are left to the
+// garbage collection.
+func (m *Manager) Delete(id string) error {
+	if err := m.lock(id); err != nil {
+		return err
+	}
+	defer m.unlock(id)
+
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+	return m.store.Delete(key(id))
+}
+
+// Write stores the data of the session which starts at the offset with the
+// putter, which is expected to stamp the chunks with the batch of the
+// session. The session is persisted after every stored part, so if reading
+// the data fails, the returned session is the one with the offset from
+// which the upload is resumed. Once all data is received, the content is
+// joined, scanned if the scan is not nil, and the reference of the session is
+// set.
+func (m *Manager) Write(ctx context.Context, id string, offset int64, putter storage.Putter, r io.Reader, scan Scan) (*Session, error) {
+	if err := m.lock(id); err != nil {
+		return nil, err
+	}
+	defer m.unlock(id)
+
+	s, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Completed() {
+		return s, ErrCompleted
+	}
+	if offset != s.Offset {
+		return s, ErrOffsetMismatch
+	}
+
+	p := &countingPutter{Putter: putter}
+	r = io.LimitReader(r, s.Length-s.Offset)
+	buf := make([]byte, m.partSize)
+	for s.Offset < s.Length {
+		n := copy(buf, s.Tail)
+		k, rerr := io.ReadFull(r, buf[n:])
+		n += k
+		s.Offset += int64(k)
+
+		if aligned := n - n%swarm.ChunkSize; aligned > 0 {
+			root, err := storePart(ctx, p, buf[:aligned])
+			if err != nil {
+				return s, err
+			}
+			s.Parts = append(s.Parts, root)
+			s.Tail = append([]byte(nil), buf[aligned:n]...)
+		} else {
+			s.Tail = append([]byte(nil), buf[:n]...)
+		}
+		s.StampedChunks += p.reset()
+		if err := m.store.Put(key(s.ID), s); err != nil {
+			return s, err
+		}
+
+		if rerr != nil {
+			if errors.Is(rerr, io.EOF) || errors.Is(rerr, io.ErrUnexpectedEOF) {
+				break
+			}
+			return s, fmt.Errorf("%w: %v", ErrInterrupted, rerr)
+		}
+	}
+	if s.Offset < s.Length {
+		return s, nil
+	}
+
+	if err := m.complete(ctx, s, p, scan); err != nil {
+		return s, err
+	}
+	s.StampedChunks += p.reset()
+	if err := m.store.Put(key(s.ID), s); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// complete stores the tail, joins the parts and scans the content.
+func (m *Manager) complete(ctx context.Context, s *Session, p storage.Putter, scan Scan) error {
+	if len(s.Tail) > 0 || len(s.Parts) == 0 {
+		root, err := storePart(ctx, p, s.Tail)
+		if err != nil {
+			return err
+		}
+		s.Parts = append(s.Parts, root)
+		s.Tail = nil
+	}
+
+	ref, err := m.join(ctx, s, p)
+	if err != nil {
+		return err
+	}
+	if scan != nil {
+		r, _, err := joiner.New(ctx, m.getter, ref)
+		if err != nil {
+			return fmt.Errorf("read content: %w", err)
+		}
+		if err := scan(ctx, r, s.Length); err != nil {
+			return err
+		}
+	}
+	s.Reference = ref
+	return nil
+}
+
+// join returns the root of the tree of the content with the data chunks of
+// the parts.
+func (m *Manager) join(ctx context.Context, s *Session, p storage.Putter) (swarm.Address, error) {
+	if len(s.Parts) == 1 {
+		// the tree of the only part is the one of the content
+		return s.Parts[0], nil
+	}
+
+	tw := builder.NewTrieWriter(ctx, p, storage.ModePutUpload)
+	for _, part := range s.Parts {
+		if err := m.leaves(ctx, part, tw); err != nil {
+			return swarm.ZeroAddress, fmt.Errorf("part %s: %w", part, err)
+		}
+	}
+	root, err := tw.Sum()
+	if err != nil {
+		return swarm.ZeroAddress, err
+	}
+	return swarm.NewAddress(root), nil
+}
+
+// leaves writes the references and the spans of the data chunks of the tree
+// with the root in order.
+func (m *Manager) leaves(ctx context.Context, root swarm.Address, w pipeline.ChainWriter) error {
+	ch, err := m.getter.Get(ctx, storage.ModeGetRequest, root)
+	if err != nil {
+		return err
+	}
+	data := ch.Data()
+	if len(data) < swarm.SpanSize {
+		return fmt.Errorf("invalid chunk %s", root)
+	}
+	span := data[:swarm.SpanSize]
+	if binary.LittleEndian.Uint64(span) <= swarm.ChunkSize {
+		return w.ChainWrite(&pipeline.PipeWriteArgs{
+			Ref:  root.Bytes(),
+			Span: append([]byte(nil), span...),
+		})
+	}
+
+	refs := data[swarm.SpanSize:]
+	for i := 0; i+swarm.HashSize <= len(refs); i += swarm.HashSize {
+		ref := swarm.NewAddress(append([]byte(nil), refs[i:i+swarm.HashSize]...))
+		if err := m.leaves(ctx, ref, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) lock(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.busy[id]; ok {
+		return ErrBusy
+	}
+	m.busy[id] = struct{}{}
+	return nil
+}
+
+func (m *Manager) unlock(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.busy, id)
+}
+
+// storePart stores the data as the tree of a part and returns its root.
+func storePart(ctx context.Context, p storage.Putter, data []byte) (swarm.Address, error) {
+	pipe := builder.NewPipelineBuilder(ctx, p, storage.ModePutUpload, false)
+	return builder.FeedPipeline(ctx, pipe, bytes.NewReader(data))
+}
+
+// countingPutter counts the new chunks.
+type countingPutter struct {
+	storage.Putter
+	mu sync.Mutex
+	n  int64
+}
+
+func (p *countingPutter) Put(ctx context.Context, mode storage.ModePut, chs ...swarm.Chunk) ([]bool, error) {
+	exist, err := p.Putter.Put(ctx, mode, chs...)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range exist {
+		if !e {
+			p.n++
+		}
+	}
+	return exist, nil
+}
+
+func (p *countingPutter) reset() (n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n, p.n = p.n, 0
+	return n
+}
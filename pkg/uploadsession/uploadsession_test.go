@@ -0,0 +1,185 @@
+// Copyright 2021 The Sana Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uploadsession_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+
+	"github.com/ethsana/sana/pkg/file/pipeline/builder"
+	statestore "github.com/ethsana/sana/pkg/statestore/mock"
+	"github.com/ethsana/sana/pkg/storage"
+	"github.com/ethsana/sana/pkg/storage/mock"
+	"github.com/ethsana/sana/pkg/swarm"
+	"github.com/ethsana/sana/pkg/uploadsession"
+)
+
+var errDropped = errors.New("connection dropped")
+
+// droppingReader returns the error after reading the data.
+type droppingReader struct {
+	r io.Reader
+}
+
+func (r *droppingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if errors.Is(err, io.EOF) {
+		return n, errDropped
+	}
+	return n, err
+}
+
+func reference(t *testing.T, data []byte) swarm.Address {
+	t.Helper()
+
+	ctx := context.Background()
+	pipe := builder.NewPipelineBuilder(ctx, mock.NewStorer(), storage.ModePutUpload, false)
+	addr, err := builder.FeedPipeline(ctx, pipe, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addr
+}
+
+func TestWrite(t *testing.T) {
+	for _, size := range []int{0, 100, swarm.ChunkSize, 5*swarm.ChunkSize + 17, 300 * swarm.ChunkSize} {
+		data := make([]byte, size)
+		if _, err := rand.Read(data); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx := context.Background()
+		stateStore := statestore.NewStateStore()
+		storer := mock.NewStorer()
+		m := uploadsession.New(stateStore, storer)
+		m.SetPartSize(2 * swarm.ChunkSize)
+
+		s, err := m.Create(int64(size), nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the data is sent in ranges, the first one is interrupted
+		var offset int64
+		for i, end := 0, size/3; offset < int64(size) || i == 0; i, end = i+1, size {
+			var r io.Reader = bytes.NewReader(data[offset:end])
+			if i == 0 {
+				r = &droppingReader{r: r}
+			}
+			s, err = m.Write(ctx, s.ID, offset, storer, r, nil)
+			if i == 0 && size/3 > 0 {
+				if !errors.Is(err, uploadsession.ErrInterrupted) {
+					t.Fatalf("size %d: got error %v, want %v", size, err, uploadsession.ErrInterrupted)
+				}
+			} else if err != nil {
+				t.Fatalf("size %d: %v", size, err)
+			}
+			offset = s.Offset
+
+			// the session is persisted
+			if s, err = m.Get(s.ID); err != nil {
+				t.Fatal(err)
+			}
+			if s.Offset != offset {
+				t.Fatalf("size %d: got persisted offset %d, want %d", size, s.Offset, offset)
+			}
+		}
+
+		if !s.Completed() {
+			t.Fatalf("size %d: session not completed", size)
+		}
+		if want := reference(t, data); !s.Reference.Equal(want) {
+			t.Fatalf("size %d: got reference %s, want %s", size, s.Reference, want)
+		}
+		if _, err := m.Write(ctx, s.ID, s.Offset, storer, bytes.NewReader(nil), nil); !errors.Is(err, uploadsession.ErrCompleted) {
+			t.Fatalf("size %d: got error %v, want %v", size, err, uploadsession.ErrCompleted)
+		}
+	}
+}
+
+func TestWriteScan(t *testing.T) {
+	data := make([]byte, 5*swarm.ChunkSize+17)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	storer := mock.NewStorer()
+	m := uploadsession.New(statestore.NewStateStore(), storer)
+	m.SetPartSize(2 * swarm.ChunkSize)
+
+	s, err := m.Create(int64(len(data)), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errRejected := errors.New("rejected")
+	var scanned []byte
+	scan := func(_ context.Context, r io.Reader, length int64) error {
+		if length != int64(len(data)) {
+			t.Fatalf("got length %d, want %d", length, len(data))
+		}
+		if scanned, err = ioutil.ReadAll(r); err != nil {
+			t.Fatal(err)
+		}
+		return errRejected
+	}
+	if _, err := m.Write(ctx, s.ID, 0, storer, bytes.NewReader(data), scan); !errors.Is(err, errRejected) {
+		t.Fatalf("got error %v, want %v", err, errRejected)
+	}
+	if !bytes.Equal(scanned, data) {
+		t.Fatal("scanned content is not the data of the session")
+	}
+	if s, err = m.Get(s.ID); err != nil {
+		t.Fatal(err)
+	}
+	if s.Completed() {
+		t.Fatal("rejected session completed")
+	}
+
+	// the rejected session is scanned again when it is written to
+	s, err = m.Write(ctx, s.ID, s.Offset, storer, bytes.NewReader(nil), func(context.Context, io.Reader, int64) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := reference(t, data); !s.Reference.Equal(want) {
+		t.Fatalf("got reference %s, want %s", s.Reference, want)
+	}
+}
+
+func TestWriteOffsetMismatch(t *testing.T) {
+	storer := mock.NewStorer()
+	m := uploadsession.New(statestore.NewStateStore(), storer)
+
+	s, err := m.Create(10, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Write(context.Background(), s.ID, 5, storer, bytes.NewReader(make([]byte, 5)), nil); !errors.Is(err, uploadsession.ErrOffsetMismatch) {
+		t.Fatalf("got error %v, want %v", err, uploadsession.ErrOffsetMismatch)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	m := uploadsession.New(statestore.NewStateStore(), mock.NewStorer())
+
+	s, err := m.Create(10, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Delete(s.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Get(s.ID); !errors.Is(err, uploadsession.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, uploadsession.ErrNotFound)
+	}
+}